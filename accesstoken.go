@@ -0,0 +1,112 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// AccessTokenUsageSign indicates the access token secret signing the
+	// tokens only.
+	AccessTokenUsageSign = "sign"
+	// AccessTokenUsageVerify indicates the access token secret verifying
+	// the tokens only.
+	AccessTokenUsageVerify = "verify"
+	// AccessTokenUsageSignVerify indicates the access token secret both
+	// signing and verifying the tokens.
+	AccessTokenUsageSignVerify = "sign-verify"
+)
+
+// AccessToken is the shared secret signing or verifying the access tokens.
+type AccessToken struct {
+	ID    string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Usage string `json:"usage,omitempty" xml:"usage,omitempty" yaml:"usage,omitempty"`
+	Value string `json:"value,omitempty" xml:"value,omitempty" yaml:"value,omitempty"`
+	// NotBefore is the time the secret is valid from. It is zero when the
+	// secret is valid immediately.
+	NotBefore time.Time `json:"not_before,omitempty" xml:"not_before,omitempty" yaml:"not_before,omitempty"`
+	// ExpiresAt is the time the secret is valid until. It is zero when the
+	// secret does not expire.
+	ExpiresAt time.Time `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// CanSign reports whether the secret may sign the tokens.
+func (t *AccessToken) CanSign() bool {
+	return t.Usage == AccessTokenUsageSign || t.Usage == AccessTokenUsageSignVerify
+}
+
+// CanVerify reports whether the secret may verify the tokens.
+func (t *AccessToken) CanVerify() bool {
+	return t.Usage == AccessTokenUsageVerify || t.Usage == AccessTokenUsageSignVerify
+}
+
+// ValidAt reports whether the secret is valid at the time.
+func (t *AccessToken) ValidAt(now time.Time) bool {
+	if !t.NotBefore.IsZero() && now.Before(t.NotBefore) {
+		return false
+	}
+	if !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt) {
+		return false
+	}
+	return true
+}
+
+// GetAccessToken returns the access token secret stored in the id, usage,
+// value, and the optional not_before and expires_at keys of the secret.
+// The usage is either sign, verify, or sign-verify. The times are either
+// RFC 3339 strings or Unix seconds.
+func (c *client) GetAccessToken(ctx context.Context, path string) (*AccessToken, error) {
+	m, err := c.GetSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	token, err := parseAccessToken(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	return token, nil
+}
+
+func parseAccessToken(m map[string]interface{}) (*AccessToken, error) {
+	token := &AccessToken{}
+	var err error
+	if token.ID, err = getStringValue(m, "id", false); err != nil {
+		return nil, err
+	}
+	if token.Usage, err = getStringValue(m, "usage", true); err != nil {
+		return nil, err
+	}
+	switch token.Usage {
+	case AccessTokenUsageSign, AccessTokenUsageVerify, AccessTokenUsageSignVerify:
+	default:
+		return nil, fmt.Errorf("unsupported %q usage", token.Usage)
+	}
+	if token.Value, err = getStringValue(m, "value", true); err != nil {
+		return nil, err
+	}
+	if token.NotBefore, err = getTimeValue(m, "not_before"); err != nil {
+		return nil, err
+	}
+	if token.ExpiresAt, err = getTimeValue(m, "expires_at"); err != nil {
+		return nil, err
+	}
+	if !token.NotBefore.IsZero() && !token.ExpiresAt.IsZero() && !token.NotBefore.Before(token.ExpiresAt) {
+		return nil, fmt.Errorf("key %q value is not before key %q value", "expires_at", "not_before")
+	}
+	return token, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetAccessToken(t *testing.T) {
+	path := "authcrunch/caddy/access_token"
+	testcases := []struct {
+		name      string
+		secret    map[string]interface{}
+		want      *AccessToken
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test access token",
+			secret: map[string]interface{}{
+				"id":    "0",
+				"usage": "sign-verify",
+				"value": "b006d65b-c923-46a1-8da1-7d52558508fe",
+			},
+			want: &AccessToken{
+				ID:    "0",
+				Usage: "sign-verify",
+				Value: "b006d65b-c923-46a1-8da1-7d52558508fe",
+			},
+		},
+		{
+			name: "test access token with validity period",
+			secret: map[string]interface{}{
+				"id":         "1",
+				"usage":      "verify",
+				"value":      "8b8c7b1e-6a2a-4e45-9d1c-1b0b6f0c2c51",
+				"not_before": "2023-01-01T00:00:00Z",
+				"expires_at": 1675209600,
+			},
+			want: &AccessToken{
+				ID:        "1",
+				Usage:     "verify",
+				Value:     "8b8c7b1e-6a2a-4e45-9d1c-1b0b6f0c2c51",
+				NotBefore: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				ExpiresAt: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:      "test access token with unsupported usage",
+			secret:    map[string]interface{}{"usage": "encrypt", "value": "foobar"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: unsupported %q usage", path, "encrypt"),
+		},
+		{
+			name:      "test access token without value",
+			secret:    map[string]interface{}{"usage": "sign"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q not found", path, "value"),
+		},
+		{
+			name:      "test access token with malformed expiration",
+			secret:    map[string]interface{}{"usage": "sign", "value": "foobar", "expires_at": "tomorrow"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value is not a time", path, "expires_at"),
+		},
+		{
+			name: "test access token expiring before validity",
+			secret: map[string]interface{}{
+				"usage":      "sign",
+				"value":      "foobar",
+				"not_before": "2023-02-01T00:00:00Z",
+				"expires_at": "2023-01-01T00:00:00Z",
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value is not before key %q value", path, "expires_at", "not_before"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, map[string]map[string]interface{}{"AWSCURRENT": tc.secret}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetAccessToken(context.TODO(), path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("GetAccessToken() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetAccessToken() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAccessTokenValidity(t *testing.T) {
+	token := &AccessToken{
+		Usage:     AccessTokenUsageVerify,
+		NotBefore: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		ExpiresAt: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if token.CanSign() || !token.CanVerify() {
+		t.Fatalf("unexpected usage of verify token")
+	}
+	for _, tc := range []struct {
+		now  time.Time
+		want bool
+	}{
+		{now: time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC), want: false},
+		{now: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), want: true},
+		{now: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), want: false},
+	} {
+		if got := token.ValidAt(tc.now); got != tc.want {
+			t.Errorf("ValidAt(%s) = %t, want %t", tc.now, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// AccountSecret is the secret fetched in one of the accounts.
+type AccountSecret struct {
+	// RoleARN is the role assumed in the account.
+	RoleARN string `json:"role_arn,omitempty" xml:"role_arn,omitempty" yaml:"role_arn,omitempty"`
+	// Secret is the key-value map of the secret. It is nil on error.
+	Secret map[string]interface{} `json:"-" xml:"-" yaml:"-"`
+	// Err is the error fetching the secret in the account.
+	Err error `json:"-" xml:"-" yaml:"-"`
+}
+
+// GetSecretAcrossAccounts assumes each of the roles, fetches the secret at
+// the same path in every account concurrently, and returns the results
+// keyed by the account IDs of the roles. The failure in one account does
+// not affect the others, see AccountSecret.Err. The error is returned for
+// the malformed or duplicate roles only. The path, tag, and other
+// policies of the client apply in every account, while the SSM fallback
+// and the S3 overflow use the credentials of the client.
+func (c *client) GetSecretAcrossAccounts(ctx context.Context, path string, roleARNs []string) (map[string]*AccountSecret, error) {
+	if c.getConfig().Provider != defaultProvider {
+		return nil, fmt.Errorf("cross-account access is not supported with %q provider", c.getConfig().Provider)
+	}
+	accounts, err := accountIDs(roleARNs)
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]*AccountSecret, len(roleARNs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, roleARN := range roleARNs {
+		wg.Add(1)
+		go func(account, roleARN string) {
+			defer wg.Done()
+			m, err := c.fetchSecret(ctx, &secretRequest{path: path, stage: versionStageCurrent, roleARN: roleARN})
+			mu.Lock()
+			results[account] = &AccountSecret{RoleARN: roleARN, Secret: m, Err: err}
+			mu.Unlock()
+		}(accounts[i], roleARN)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// accountIDs returns the account IDs of the roles.
+func accountIDs(roleARNs []string) ([]string, error) {
+	if len(roleARNs) == 0 {
+		return nil, errors.New("role arns not found")
+	}
+	accounts := make([]string, 0, len(roleARNs))
+	seen := make(map[string]bool)
+	for _, roleARN := range roleARNs {
+		if !roleARNRgx.MatchString(roleARN) {
+			return nil, fmt.Errorf("malformed %q role arn", roleARN)
+		}
+		account := strings.Split(roleARN, ":")[4]
+		if seen[account] {
+			return nil, fmt.Errorf("duplicate %q account of %q role arn", account, roleARN)
+		}
+		seen[account] = true
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// getRoleCredentials returns the cached credentials of the role assumed
+// with the credentials of the client. The caller must hold the lock.
+func (c *client) getRoleCredentials(roleARN string) aws.CredentialsProvider {
+	if p, exists := c.roleCredentials[roleARN]; exists {
+		return p
+	}
+	if c.roleCredentials == nil {
+		c.roleCredentials = make(map[string]aws.CredentialsProvider)
+	}
+	p := aws.NewCredentialsCache(
+		stscreds.NewAssumeRoleProvider(sts.NewFromConfig(c.serviceConfig), roleARN),
+	)
+	c.roleCredentials[roleARN] = p
+	return p
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+var testAccessKeyRgx = regexp.MustCompile(`Credential=([^/]+)/`)
+
+// newAccountsMockClient returns HTTP client serving the secrets of the
+// accounts. The AssumeRole requests return the credentials with the
+// access key "AKID<account>", and the requests for the secrets are
+// served from the store of the account of the access key. The roles of
+// the accounts not in the stores are denied.
+func newAccountsMockClient(t *testing.T, stores map[string]map[string]string, assumed *[]string) smithyhttp.ClientDoFunc {
+	var mu sync.Mutex
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return mockFailure(t, "failed reading request: %v", err)
+		}
+		if r.Header.Get("X-Amz-Target") == "" {
+			form, err := url.ParseQuery(string(b))
+			if err != nil || form.Get("Action") != "AssumeRole" {
+				return mockFailure(t, "unexpected %q request", b)
+			}
+			roleARN := form.Get("RoleArn")
+			mu.Lock()
+			*assumed = append(*assumed, roleARN)
+			mu.Unlock()
+			account := strings.Split(roleARN, ":")[4]
+			if _, found := stores[account]; !found {
+				return &http.Response{
+					StatusCode: http.StatusForbidden,
+					Header:     http.Header{},
+					Body: ioutil.NopCloser(strings.NewReader(`<ErrorResponse><Error><Type>Sender</Type>` +
+						`<Code>AccessDenied</Code><Message>not authorized to assume role</Message></Error></ErrorResponse>`)),
+				}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body: ioutil.NopCloser(strings.NewReader(fmt.Sprintf(`<AssumeRoleResponse><AssumeRoleResult><Credentials>`+
+					`<AccessKeyId>AKID%s</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken>`+
+					`<Expiration>2099-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`, account))),
+			}, nil
+		}
+		m := testAccessKeyRgx.FindStringSubmatch(r.Header.Get("Authorization"))
+		if m == nil {
+			return mockFailure(t, "unsigned request")
+		}
+		store := stores[strings.TrimPrefix(m[1], "AKID")]
+		for name, v := range store {
+			if strings.Contains(string(b), `"`+name+`"`) {
+				return secretsmock.JSONResponse(map[string]interface{}{"SecretString": v}), nil
+			}
+		}
+		return secretsmock.NotFoundResponse(""), nil
+	})
+}
+
+func TestGetSecretAcrossAccounts(t *testing.T) {
+	stores := map[string]map[string]string{
+		"111111111111": {"authcrunch/tenant": `{"name":"acme"}`},
+		"222222222222": {"authcrunch/tenant": `{"name":"globex"}`},
+		"333333333333": {},
+	}
+	roleARNs := []string{
+		"arn:aws:iam::111111111111:role/authcrunch",
+		"arn:aws:iam::222222222222:role/authcrunch",
+		"arn:aws:iam::333333333333:role/authcrunch",
+		"arn:aws:iam::444444444444:role/authcrunch",
+	}
+	var assumed []string
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithBasePrefix("authcrunch/"),
+		WithHTTPClient(newAccountsMockClient(t, stores, &assumed)),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		results, err := c.GetSecretAcrossAccounts(context.TODO(), "tenant", roleARNs)
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		got := make(map[string]interface{})
+		for account, r := range results {
+			switch {
+			case r.Err == nil:
+				got[account] = r.Secret
+			case isNotFound(r.Err):
+				got[account] = "not found"
+			default:
+				got[account] = "error"
+			}
+		}
+		want := map[string]interface{}{
+			"111111111111": map[string]interface{}{"name": "acme"},
+			"222222222222": map[string]interface{}{"name": "globex"},
+			"333333333333": "not found",
+			"444444444444": "error",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("GetSecretAcrossAccounts() mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff(roleARNs[1], results["222222222222"].RoleARN); diff != "" {
+			t.Fatalf("role arn mismatch (-want +got):\n%s", diff)
+		}
+	}
+	// The credentials of the assumed roles are reused, only the denied
+	// role is assumed again.
+	if n := len(assumed); n != 5 {
+		t.Fatalf("unexpected %d assume role requests: %v", n, assumed)
+	}
+	if _, err := c.GetSecret(context.TODO(), "tenant"); !isNotFound(err) {
+		t.Fatalf("expected not found error of the own account, got: %v", err)
+	}
+}
+
+func TestGetSecretAcrossAccountsErrors(t *testing.T) {
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	testcases := []struct {
+		name     string
+		roleARNs []string
+		err      error
+	}{
+		{name: "test no roles", err: errors.New("role arns not found")},
+		{
+			name:     "test malformed role",
+			roleARNs: []string{"arn:aws:iam::111111111111:user/jsmith"},
+			err:      errors.New(`malformed "arn:aws:iam::111111111111:user/jsmith" role arn`),
+		},
+		{
+			name:     "test duplicate account",
+			roleARNs: []string{"arn:aws:iam::111111111111:role/foo", "arn:aws:iam::111111111111:role/bar"},
+			err:      errors.New(`duplicate "111111111111" account of "arn:aws:iam::111111111111:role/bar" role arn`),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := c.GetSecretAcrossAccounts(context.TODO(), "tenant", tc.roleARNs)
+			if err == nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+				t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+			}
+		})
+	}
+}
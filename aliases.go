@@ -0,0 +1,32 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+// applyFieldAliases renames the keys of the secret according to the
+// provided alias-to-field mapping. When the secret already has the
+// field, the alias is left intact.
+func applyFieldAliases(m map[string]interface{}, aliases map[string]string) {
+	for alias, field := range aliases {
+		v, exists := m[alias]
+		if !exists {
+			continue
+		}
+		if _, exists := m[field]; exists {
+			continue
+		}
+		m[field] = v
+		delete(m, alias)
+	}
+}
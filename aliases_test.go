@@ -0,0 +1,128 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestFieldAliases(t *testing.T) {
+	aliases := map[string]string{
+		"user": "username",
+		"pass": "password",
+		"mail": "email",
+	}
+
+	testcases := []struct {
+		name      string
+		aliases   map[string]string
+		secret    map[string]interface{}
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:    "test secret with aliased keys",
+			aliases: aliases,
+			secret: map[string]interface{}{
+				"user": "jsmith",
+				"pass": "bcrypt:10:$2a$10$iqq53VjdCwknBSBrnyLd9OH1Mfh6kqPezMMy6h6F41iLdVDkj13I6",
+				"mail": "jsmith@localhost.localdomain",
+			},
+			want: map[string]interface{}{
+				"username": "jsmith",
+				"password": "bcrypt:10:$2a$10$iqq53VjdCwknBSBrnyLd9OH1Mfh6kqPezMMy6h6F41iLdVDkj13I6",
+				"email":    "jsmith@localhost.localdomain",
+			},
+		},
+		{
+			name:    "test secret with both aliased and expected keys",
+			aliases: aliases,
+			secret: map[string]interface{}{
+				"user":     "john",
+				"username": "jsmith",
+			},
+			want: map[string]interface{}{
+				"user":     "john",
+				"username": "jsmith",
+			},
+		},
+		{
+			name: "test secret without aliases",
+			secret: map[string]interface{}{
+				"user": "jsmith",
+			},
+			want: map[string]interface{}{
+				"user": "jsmith",
+			},
+		},
+		{
+			name:      "test alias pointing to itself",
+			aliases:   map[string]string{"user": "user"},
+			shouldErr: true,
+			err:       fmt.Errorf("field alias %q points to itself", "user"),
+		},
+		{
+			name:      "test empty alias",
+			aliases:   map[string]string{"": "username"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q field alias for %q", "", "username"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClientWithConfig(context.TODO(), &ClientConfig{
+				ID:           "foo",
+				Region:       "us-east-1",
+				Provider:     "aws_secrets_manager",
+				FieldAliases: tc.aliases,
+			})
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("NewClientWithConfig() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			c.SetMockClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+				return secretsmock.SecretStringResponse(tc.secret), nil
+			}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetSecret(context.TODO(), "authcrunch/caddy/users/jsmith")
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrAccessBlocked is returned when the anomaly detection temporarily
+// blocks the reads of the secrets with the path prefix.
+var ErrAccessBlocked = errors.New("secret access blocked by anomaly detection")
+
+// AnomalyConfig is the configuration of the detection of the spikes in
+// the reads of the secrets, e.g. credential stuffing or exfiltration
+// through the client. The reads fetched from the backend are counted per
+// path prefix in fixed windows. The reads served from the cache and the
+// refreshes by the client itself, e.g. the Watch polls, are not counted,
+// but the blocks apply to them too.
+type AnomalyConfig struct {
+	// Window is the duration of the counting window.
+	Window time.Duration
+	// Threshold is the baseline number of the reads of a prefix within a
+	// window. The read exceeding it is an anomaly.
+	Threshold int
+	// PrefixDepth is the number of the leading segments of the path
+	// forming the prefix, e.g. 1 counts the reads of "users/jsmith" and
+	// "users/mjones" together as "users". When zero, the full path is
+	// the prefix.
+	PrefixDepth int
+	// BlockDuration, when set, blocks the reads of the prefix for the
+	// duration after an anomaly, failing them with ErrAccessBlocked,
+	// starting with the read exceeding the threshold.
+	BlockDuration time.Duration
+	// Alert, when set, is called with every detected anomaly. It must not
+	// block. The number of the detected anomalies is also counted in
+	// AnomalyStats.
+	Alert func(*AccessAnomaly)
+}
+
+func (cfg *AnomalyConfig) validate() error {
+	if cfg.Window <= 0 {
+		return fmt.Errorf("malformed %s anomaly window", cfg.Window)
+	}
+	if cfg.Threshold < 1 {
+		return fmt.Errorf("malformed %d anomaly threshold", cfg.Threshold)
+	}
+	if cfg.PrefixDepth < 0 {
+		return fmt.Errorf("malformed %d anomaly prefix depth", cfg.PrefixDepth)
+	}
+	if cfg.BlockDuration < 0 {
+		return fmt.Errorf("malformed %s anomaly block duration", cfg.BlockDuration)
+	}
+	return nil
+}
+
+// AccessAnomaly describes the reads of a prefix exceeding the threshold.
+type AccessAnomaly struct {
+	Prefix string
+	// Path is the path of the read exceeding the threshold.
+	Path string
+	// Count is the number of the reads of the prefix within the window.
+	Count  int
+	Window time.Duration
+	// BlockedUntil is the time the reads of the prefix are blocked until,
+	// or zero, when they are not blocked.
+	BlockedUntil time.Time
+}
+
+// AnomalyStats are the counters of the anomaly detection.
+type AnomalyStats struct {
+	// Reads is the number of the counted reads, i.e. the ones fetched
+	// from the backend.
+	Reads uint64 `json:"reads" xml:"reads" yaml:"reads"`
+	// Anomalies is the number of the detected anomalies.
+	Anomalies uint64 `json:"anomalies" xml:"anomalies" yaml:"anomalies"`
+	// Blocked is the number of the reads failed with ErrAccessBlocked.
+	Blocked uint64 `json:"blocked" xml:"blocked" yaml:"blocked"`
+}
+
+// accessWindow counts the reads of a prefix.
+type accessWindow struct {
+	start time.Time
+	count int
+}
+
+// anomalyDetector tracks the reads of the secrets per prefix.
+type anomalyDetector struct {
+	mu      sync.Mutex
+	cfg     AnomalyConfig
+	windows map[string]*accessWindow
+	blocked map[string]time.Time
+	stats   AnomalyStats
+}
+
+func newAnomalyDetector(cfg *AnomalyConfig) *anomalyDetector {
+	return &anomalyDetector{
+		cfg:     *cfg,
+		windows: make(map[string]*accessWindow),
+		blocked: make(map[string]time.Time),
+	}
+}
+
+func (d *anomalyDetector) prefix(secretPath string) string {
+	if d.cfg.PrefixDepth == 0 {
+		return secretPath
+	}
+	segments := strings.Split(secretPath, "/")
+	if len(segments) > d.cfg.PrefixDepth {
+		segments = segments[:d.cfg.PrefixDepth]
+	}
+	return strings.Join(segments, "/")
+}
+
+// blockedUntil returns the end of the block of the reads of the secret at
+// the path, and the blocked prefix. The zero end blocks until the prefix
+// is unblocked. The expired blocks are removed.
+func (d *anomalyDetector) blockedUntil(secretPath string, now time.Time) (string, time.Time, bool) {
+	for prefix, until := range d.blocked {
+		if secretPath != prefix && !strings.HasPrefix(secretPath, prefix+"/") {
+			continue
+		}
+		if until.IsZero() || now.Before(until) {
+			return prefix, until, true
+		}
+		delete(d.blocked, prefix)
+	}
+	return "", time.Time{}, false
+}
+
+// check returns ErrAccessBlocked when the prefix of the secret at the
+// path is blocked at the time. It does not count the read.
+func (d *anomalyDetector) check(secretPath string, now time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if blocked, until, found := d.blockedUntil(secretPath, now); found {
+		d.stats.Blocked++
+		return blockedError(blocked, until)
+	}
+	return nil
+}
+
+// record counts the read of the secret at the path at the time. It returns
+// ErrAccessBlocked when the prefix is blocked, and the anomaly, when the
+// read exceeds the threshold for the first time in the window. The read
+// exceeding the threshold is blocked, when the blocking is configured.
+func (d *anomalyDetector) record(secretPath string, now time.Time) (*AccessAnomaly, error) {
+	prefix := d.prefix(secretPath)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if blocked, until, found := d.blockedUntil(secretPath, now); found {
+		d.stats.Blocked++
+		return nil, blockedError(blocked, until)
+	}
+	d.stats.Reads++
+	w := d.windows[prefix]
+	if w == nil || now.Sub(w.start) >= d.cfg.Window {
+		for k, v := range d.windows {
+			if now.Sub(v.start) >= d.cfg.Window {
+				delete(d.windows, k)
+			}
+		}
+		w = &accessWindow{start: now}
+		d.windows[prefix] = w
+	}
+	w.count++
+	if w.count != d.cfg.Threshold+1 {
+		return nil, nil
+	}
+	d.stats.Anomalies++
+	anomaly := &AccessAnomaly{
+		Prefix: prefix,
+		Path:   secretPath,
+		Count:  w.count,
+		Window: d.cfg.Window,
+	}
+	if d.cfg.BlockDuration > 0 {
+		anomaly.BlockedUntil = now.Add(d.cfg.BlockDuration)
+		d.blocked[prefix] = anomaly.BlockedUntil
+		delete(d.windows, prefix)
+		d.stats.Blocked++
+		return anomaly, blockedError(prefix, anomaly.BlockedUntil)
+	}
+	return anomaly, nil
+}
+
+// block blocks the reads of the secrets under the prefix until the time,
+// or until unblocked, when the time is zero.
+func (d *anomalyDetector) block(prefix string, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.blocked[prefix] = until
+}
+
+// getStats returns the copy of the counters.
+func (d *anomalyDetector) getStats() AnomalyStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// unblock removes the block of the prefix and restarts its counting.
+func (d *anomalyDetector) unblock(prefix string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.blocked, prefix)
+	delete(d.windows, prefix)
+}
+
+func blockedError(prefix string, until time.Time) error {
+	if until.IsZero() {
+		return fmt.Errorf("%w: %q prefix blocked", ErrAccessBlocked, prefix)
+	}
+	return fmt.Errorf("%w: %q prefix blocked until %s", ErrAccessBlocked, prefix, until.UTC().Format(time.RFC3339))
+}
+
+// BlockAccess blocks the reads of the secrets with the paths under the
+// prefix, e.g. "users" or "users/jsmith", for the duration, failing them
+// with ErrAccessBlocked. The zero duration blocks them until UnblockAccess.
+// It requires the anomaly detection, see WithAnomalyDetection.
+func (c *client) BlockAccess(prefix string, d time.Duration) error {
+	if c.anomalies == nil {
+		return errors.New("anomaly detection is disabled")
+	}
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return errors.New("blocked prefix is empty")
+	}
+	if d < 0 {
+		return fmt.Errorf("malformed %s block duration", d)
+	}
+	var until time.Time
+	if d > 0 {
+		until = c.clock.Now().Add(d)
+	}
+	c.anomalies.block(prefix, until)
+	c.getLogger().Warn(
+		"secret access blocked",
+		zap.String("prefix", prefix),
+		zap.Time("blocked_until", until),
+	)
+	return nil
+}
+
+// UnblockAccess removes the block of the reads of the secrets under the
+// prefix, set by BlockAccess or by the anomaly detection.
+func (c *client) UnblockAccess(prefix string) error {
+	if c.anomalies == nil {
+		return errors.New("anomaly detection is disabled")
+	}
+	prefix = strings.Trim(prefix, "/")
+	c.anomalies.unblock(prefix)
+	c.getLogger().Info("secret access unblocked", zap.String("prefix", prefix))
+	return nil
+}
+
+// AnomalyStats returns the counters of the anomaly detection. It requires
+// the anomaly detection, see WithAnomalyDetection.
+func (c *client) AnomalyStats() (AnomalyStats, error) {
+	if c.anomalies == nil {
+		return AnomalyStats{}, errors.New("anomaly detection is disabled")
+	}
+	return c.anomalies.getStats(), nil
+}
+
+// checkAccess fails the read of the secret at the path, when the anomaly
+// detection is enabled and the prefix of the path is blocked. Unlike
+// recordAccess, it does not count the read.
+func (c *client) checkAccess(secretPath string) error {
+	if c.anomalies == nil {
+		return nil
+	}
+	return c.anomalies.check(secretPath, c.clock.Now())
+}
+
+// recordAccess counts the read of the secret at the path, when the anomaly
+// detection is enabled, and logs and reports the detected anomalies.
+func (c *client) recordAccess(secretPath string) error {
+	if c.anomalies == nil {
+		return nil
+	}
+	anomaly, err := c.anomalies.record(secretPath, c.clock.Now())
+	if anomaly != nil {
+		c.getLogger().Warn(
+			"secret access anomaly",
+			zap.String("prefix", anomaly.Prefix),
+			zap.String("path", anomaly.Path),
+			zap.Int("count", anomaly.Count),
+			zap.Duration("window", anomaly.Window),
+			zap.Time("blocked_until", anomaly.BlockedUntil),
+		)
+		if c.anomalies.cfg.Alert != nil {
+			c.anomalies.cfg.Alert(anomaly)
+		}
+	}
+	return err
+}
@@ -0,0 +1,284 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestAnomalyConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cfg       *AnomalyConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test valid config",
+			cfg:  &AnomalyConfig{Window: time.Minute, Threshold: 100, PrefixDepth: 1, BlockDuration: time.Hour},
+		},
+		{
+			name:      "test config without window",
+			cfg:       &AnomalyConfig{Threshold: 100},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed 0s anomaly window"),
+		},
+		{
+			name:      "test config without threshold",
+			cfg:       &AnomalyConfig{Window: time.Minute},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed 0 anomaly threshold"),
+		},
+		{
+			name:      "test config with negative prefix depth",
+			cfg:       &AnomalyConfig{Window: time.Minute, Threshold: 100, PrefixDepth: -1},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed -1 anomaly prefix depth"),
+		},
+		{
+			name:      "test config with negative block duration",
+			cfg:       &AnomalyConfig{Window: time.Minute, Threshold: 100, BlockDuration: -time.Second},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed -1s anomaly block duration"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.validate()
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestAnomalyDetection(t *testing.T) {
+	clock := newTestClock()
+	var anomalies []*AccessAnomaly
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithClock(clock),
+		WithAnomalyDetection(&AnomalyConfig{
+			Window:        time.Minute,
+			Threshold:     2,
+			PrefixDepth:   1,
+			BlockDuration: 5 * time.Minute,
+			Alert: func(anomaly *AccessAnomaly) {
+				anomalies = append(anomalies, anomaly)
+			},
+		}),
+		WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+		})),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	ctx := context.TODO()
+
+	// The reads below the threshold pass, the window restarting after a
+	// minute.
+	for _, path := range []string{"users/jsmith", "users/mjones"} {
+		if _, err := c.GetSecret(ctx, path); err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+	}
+	clock.Advance(time.Minute)
+	for _, path := range []string{"users/jsmith", "users/mjones", "tokens/github"} {
+		if _, err := c.GetSecret(ctx, path); err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+	}
+	if len(anomalies) != 0 {
+		t.Fatalf("unexpected anomalies: %v", anomalies)
+	}
+
+	// The read exceeding the threshold is reported and blocked, as well as
+	// the following reads of the prefix.
+	if _, err := c.GetSecret(ctx, "users/admin"); !errors.Is(err, ErrAccessBlocked) {
+		t.Fatalf("expected ErrAccessBlocked, got: %v", err)
+	}
+	want := []*AccessAnomaly{
+		{
+			Prefix:       "users",
+			Path:         "users/admin",
+			Count:        3,
+			Window:       time.Minute,
+			BlockedUntil: clock.Now().Add(5 * time.Minute),
+		},
+	}
+	if diff := cmp.Diff(want, anomalies); diff != "" {
+		t.Fatalf("anomalies mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := c.GetSecret(ctx, "users/jsmith"); !errors.Is(err, ErrAccessBlocked) {
+		t.Fatalf("expected ErrAccessBlocked, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "tokens/github"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	// The block expires.
+	clock.Advance(5 * time.Minute)
+	if _, err := c.GetSecret(ctx, "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+}
+
+func TestAnomalyDetectionCounting(t *testing.T) {
+	var anomalies []*AccessAnomaly
+	var requests int
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithClock(newTestClock()),
+		WithCacheTTL(time.Hour),
+		WithAnomalyDetection(&AnomalyConfig{
+			Window:      time.Minute,
+			Threshold:   1,
+			PrefixDepth: 1,
+			Alert: func(anomaly *AccessAnomaly) {
+				anomalies = append(anomalies, anomaly)
+			},
+		}),
+		WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+		})),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	ctx := context.TODO()
+
+	// The cache hits and the Watch polls are not counted.
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetSecret(ctx, "users/jsmith"); err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+	}
+	c.(*client).pollSecret(ctx, "users/jsmith", make(map[string]string))
+	if requests != 2 {
+		t.Fatalf("unexpected %d requests, want: 2", requests)
+	}
+	if len(anomalies) != 0 {
+		t.Fatalf("unexpected anomalies: %v", anomalies)
+	}
+
+	// The second fetch from the backend exceeds the threshold.
+	if _, err := c.GetSecret(ctx, "users/mjones"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if len(anomalies) != 1 {
+		t.Fatalf("unexpected %d anomalies, want: 1", len(anomalies))
+	}
+
+	// The blocks apply to the cache hits.
+	if err := c.BlockAccess("users", 0); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "users/jsmith"); !errors.Is(err, ErrAccessBlocked) {
+		t.Fatalf("expected ErrAccessBlocked, got: %v", err)
+	}
+
+	got, err := c.AnomalyStats()
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(AnomalyStats{Reads: 2, Anomalies: 1, Blocked: 1}, got); diff != "" {
+		t.Fatalf("AnomalyStats() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := c.Scoped("users").AnomalyStats(); !errors.Is(err, ErrScopedClient) {
+		t.Fatalf("expected ErrScopedClient, got: %v", err)
+	}
+}
+
+func TestBlockAccess(t *testing.T) {
+	clock := newTestClock()
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithClock(clock),
+		WithAnomalyDetection(&AnomalyConfig{Window: time.Minute, Threshold: 100}),
+		WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+		})),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	ctx := context.TODO()
+
+	if err := c.BlockAccess("users/jsmith", 0); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if err := c.BlockAccess("tokens/", time.Minute); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	for _, path := range []string{"users/jsmith", "users/jsmith/mfa", "tokens/github"} {
+		if _, err := c.GetSecret(ctx, path); !errors.Is(err, ErrAccessBlocked) {
+			t.Fatalf("expected ErrAccessBlocked for %q, got: %v", path, err)
+		}
+	}
+	for _, path := range []string{"users/jsmith2", "users/mjones"} {
+		if _, err := c.GetSecret(ctx, path); err != nil {
+			t.Fatalf("expected success for %q, got: %v", path, err)
+		}
+	}
+
+	// The timed block expires, and the indefinite one lasts until
+	// unblocked.
+	clock.Advance(time.Hour)
+	if _, err := c.GetSecret(ctx, "tokens/github"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "users/jsmith"); !errors.Is(err, ErrAccessBlocked) {
+		t.Fatalf("expected ErrAccessBlocked, got: %v", err)
+	}
+	if err := c.UnblockAccess("users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	if err := c.BlockAccess("users", -time.Second); err == nil {
+		t.Fatalf("unexpected success for negative duration")
+	}
+	if err := c.Scoped("users").BlockAccess("users", time.Minute); !errors.Is(err, ErrScopedClient) {
+		t.Fatalf("expected ErrScopedClient, got: %v", err)
+	}
+}
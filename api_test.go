@@ -0,0 +1,112 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+// mockSecretsManagerAPI is the in-memory SecretsManagerAPI recording the
+// names of the invoked operations.
+type mockSecretsManagerAPI struct {
+	SecretsManagerAPI
+	secrets map[string]string
+	calls   []string
+}
+
+func (m *mockSecretsManagerAPI) GetSecretValue(_ context.Context, input *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	name := aws.ToString(input.SecretId)
+	m.calls = append(m.calls, "GetSecretValue "+name)
+	value, exists := m.secrets[name]
+	if !exists {
+		return nil, &types.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("secret %s not found", name))}
+	}
+	return &secretsmanager.GetSecretValueOutput{Name: aws.String(name), SecretString: aws.String(value)}, nil
+}
+
+func (m *mockSecretsManagerAPI) PutSecretValue(_ context.Context, input *secretsmanager.PutSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error) {
+	name := aws.ToString(input.SecretId)
+	m.calls = append(m.calls, "PutSecretValue "+name)
+	m.secrets[name] = aws.ToString(input.SecretString)
+	return &secretsmanager.PutSecretValueOutput{Name: aws.String(name)}, nil
+}
+
+func (m *mockSecretsManagerAPI) ListSecrets(_ context.Context, input *secretsmanager.ListSecretsInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.ListSecretsOutput, error) {
+	m.calls = append(m.calls, "ListSecrets")
+	output := &secretsmanager.ListSecretsOutput{}
+	for name := range m.secrets {
+		output.SecretList = append(output.SecretList, types.SecretListEntry{Name: aws.String(name)})
+	}
+	return output, nil
+}
+
+func TestSecretsManagerAPI(t *testing.T) {
+	api := &mockSecretsManagerAPI{
+		secrets: map[string]string{
+			"authcrunch/caddy/access_token": packMapToJSON(t, map[string]interface{}{"secret": "foobar"}),
+		},
+	}
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithBasePrefix("authcrunch/"),
+		WithSecretsManagerAPI(api),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+
+	got, err := c.GetSecretByKey(context.TODO(), "caddy/access_token", "secret", WithRegionOverride("eu-west-1"))
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff("foobar", got); diff != "" {
+		t.Errorf("GetSecretByKey() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := c.PutSecret(context.TODO(), "caddy/access_token", map[string]interface{}{"secret": "barfoo"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	paths, err := c.ListSecrets(context.TODO(), "caddy/")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"caddy/access_token"}, paths); diff != "" {
+		t.Errorf("ListSecrets() mismatch (-want +got):\n%s", diff)
+	}
+
+	wantCalls := []string{
+		"GetSecretValue authcrunch/caddy/access_token",
+		"GetSecretValue authcrunch/caddy/access_token",
+		"PutSecretValue authcrunch/caddy/access_token",
+		"ListSecrets",
+	}
+	if diff := cmp.Diff(wantCalls, api.calls); diff != "" {
+		t.Errorf("SecretsManagerAPI calls mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := NewClient(context.TODO(), WithSecretsManagerAPI(nil)); err == nil {
+		t.Fatalf("expected error for nil api, got success")
+	}
+}
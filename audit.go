@@ -0,0 +1,189 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrAuditLogTampered is returned when the hash chain of an audit log
+// does not verify.
+var ErrAuditLogTampered = errors.New("audit log tampered")
+
+// AuditRecord describes an access to a secret by the client.
+type AuditRecord struct {
+	Time     time.Time `json:"time"`
+	ClientID string    `json:"client_id,omitempty"`
+	Op       string    `json:"op"`
+	Path     string    `json:"path"`
+	Error    string    `json:"error,omitempty"`
+	// PrevHash and Hash link the records of a hash-chained log.
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// AuditSink receives the audit records of the client. The client logs the
+// errors of the sink, but does not fail the access to the secrets.
+type AuditSink interface {
+	WriteAuditRecord(*AuditRecord) error
+}
+
+// AuditLog is the sink writing the audit records as JSON lines. When
+// hash-chained, every record holds the hash of the previous one and its
+// own HMAC-SHA256 hash, keyed with the audit key, over the previous hash
+// and its content. VerifyAuditLog then detects the modified, removed, and
+// reordered records, unless the attacker holds the key. It cannot detect
+// the records removed from the end of the log, so the hash returned by
+// LastHash should be recorded outside of the log, e.g. periodically
+// shipped to a separate system, and compared with the one returned by
+// VerifyAuditLog.
+type AuditLog struct {
+	mu       sync.Mutex
+	w        io.Writer
+	key      []byte
+	chained  bool
+	lastHash string
+}
+
+// NewAuditLog returns the sink writing the plain JSON lines to the writer.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: w}
+}
+
+// NewHashChainAuditLog returns the sink writing the hash-chained JSON lines
+// to the writer. The key is the audit key, kept away from the host writing
+// the log. The lastHash is the hash of the last record already in the log,
+// or empty for a new log.
+func NewHashChainAuditLog(w io.Writer, key []byte, lastHash string) (*AuditLog, error) {
+	if len(key) == 0 {
+		return nil, errors.New("audit log key is empty")
+	}
+	return &AuditLog{w: w, key: key, chained: true, lastHash: lastHash}, nil
+}
+
+// LastHash returns the hash of the last record written to the
+// hash-chained log.
+func (l *AuditLog) LastHash() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastHash
+}
+
+// WriteAuditRecord writes the record as a JSON line.
+func (l *AuditLog) WriteAuditRecord(r *AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec := *r
+	rec.PrevHash, rec.Hash = "", ""
+	if l.chained {
+		rec.PrevHash = l.lastHash
+		hash, err := auditRecordHash(l.key, &rec)
+		if err != nil {
+			return err
+		}
+		rec.Hash = hash
+	}
+	b, err := json.Marshal(&rec)
+	if err != nil {
+		return err
+	}
+	if _, err := l.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if l.chained {
+		l.lastHash = rec.Hash
+	}
+	return nil
+}
+
+// auditRecordHash returns the hex-encoded HMAC-SHA256 of the JSON encoding
+// of the record without its hash, keyed with the audit key. The encoding
+// includes the previous hash.
+func auditRecordHash(key []byte, r *AuditRecord) (string, error) {
+	rec := *r
+	rec.Hash = ""
+	b, err := json.Marshal(&rec)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyAuditLog verifies the hash chain of the log written by the
+// hash-chained AuditLog with the audit key. It returns the hash of the
+// last record, to be compared with the one recorded outside of the log,
+// and ErrAuditLogTampered naming the first line failing the verification.
+func VerifyAuditLog(r io.Reader, key []byte) (string, error) {
+	if len(key) == 0 {
+		return "", errors.New("audit log key is empty")
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lastHash string
+	for line := 1; scanner.Scan(); line++ {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return "", fmt.Errorf("%w: line %d is malformed: %v", ErrAuditLogTampered, line, err)
+		}
+		if rec.PrevHash != lastHash {
+			return "", fmt.Errorf("%w: line %d does not follow the previous record", ErrAuditLogTampered, line)
+		}
+		hash, err := auditRecordHash(key, &rec)
+		if err != nil {
+			return "", err
+		}
+		if !hmac.Equal([]byte(rec.Hash), []byte(hash)) {
+			return "", fmt.Errorf("%w: line %d hash mismatch", ErrAuditLogTampered, line)
+		}
+		lastHash = rec.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return lastHash, nil
+}
+
+// audit sends the record of the operation on the secret at the path to
+// the audit sink, when set.
+func (c *client) audit(op, path string, err error) {
+	if c.auditSink == nil {
+		return
+	}
+	r := &AuditRecord{
+		Time:     c.clock.Now().UTC(),
+		ClientID: c.getConfig().ID,
+		Op:       op,
+		Path:     path,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if err := c.auditSink.WriteAuditRecord(r); err != nil {
+		c.getLogger().Error("failed writing audit record", zap.String("op", op), zap.String("path", path), zap.Error(err))
+	}
+}
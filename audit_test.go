@@ -0,0 +1,125 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	key := []byte("foobar")
+	log, err := NewHashChainAuditLog(&buf, key, "")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	store := make(map[string]string)
+	var requests []string
+	c := newWritesTestClient(t, store, &requests, WithAuditSink(log), WithClock(newTestClock()))
+	ctx := context.TODO()
+
+	if _, err := c.CreateSecret(ctx, "users/jsmith", map[string]interface{}{"username": "jsmith"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "users/mjones"); err == nil {
+		t.Fatalf("unexpected success")
+	}
+
+	lastHash, err := VerifyAuditLog(bytes.NewReader(buf.Bytes()), key)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if lastHash != log.LastHash() {
+		t.Fatalf("unexpected %q last hash, want %q", lastHash, log.LastHash())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var got []*AuditRecord
+	for _, line := range lines {
+		var r AuditRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("failed parsing audit record: %v", err)
+		}
+		got = append(got, &r)
+	}
+	want := []*AuditRecord{
+		{ClientID: "foo", Op: "create", Path: "users/jsmith"},
+		{ClientID: "foo", Op: "get", Path: "users/jsmith"},
+		{ClientID: "foo", Op: "get", Path: "users/mjones"},
+	}
+	opts := []cmp.Option{
+		cmpopts.IgnoreFields(AuditRecord{}, "Time", "Error", "PrevHash", "Hash"),
+	}
+	if diff := cmp.Diff(want, got, opts...); diff != "" {
+		t.Fatalf("audit records mismatch (-want +got):\n%s", diff)
+	}
+	if got[2].Error == "" {
+		t.Errorf("expected error in the record of the failed access")
+	}
+
+	// The modified, removed, and reordered records break the chain.
+	for name, tampered := range map[string][]string{
+		"modified":  {lines[0], strings.Replace(lines[1], "users/jsmith", "users/admin", 1), lines[2]},
+		"removed":   {lines[0], lines[2]},
+		"reordered": {lines[1], lines[0], lines[2]},
+	} {
+		_, err := VerifyAuditLog(strings.NewReader(strings.Join(tampered, "\n")), key)
+		if !errors.Is(err, ErrAuditLogTampered) {
+			t.Errorf("%s log: expected ErrAuditLogTampered, got: %v", name, err)
+		}
+	}
+
+	// The log does not verify with other key, i.e. the records cannot be
+	// rehashed without the key.
+	if _, err := VerifyAuditLog(bytes.NewReader(buf.Bytes()), []byte("other")); !errors.Is(err, ErrAuditLogTampered) {
+		t.Errorf("expected ErrAuditLogTampered, got: %v", err)
+	}
+
+	// The log continues the chain after reopening.
+	reopened, err := NewHashChainAuditLog(&buf, key, lastHash)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if err := reopened.WriteAuditRecord(&AuditRecord{Time: time.Unix(0, 0), Op: "get", Path: "users/jsmith"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := VerifyAuditLog(bytes.NewReader(buf.Bytes()), key); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+}
+
+func TestPlainAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewAuditLog(&buf)
+	if err := log.WriteAuditRecord(&AuditRecord{Time: time.Unix(0, 0).UTC(), Op: "get", Path: "users/jsmith"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	want := `{"time":"1970-01-01T00:00:00Z","op":"get","path":"users/jsmith"}` + "\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("audit log mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// ErrVersionIDNotSupported is returned when the backend of the client
+// cannot select the versions of the secrets by their IDs, e.g. for the
+// version pins.
+var ErrVersionIDNotSupported = errors.New("version id is not supported by the backend")
+
+// Backend stores the secrets of the client. It lets the packages
+// supporting other secret stores, e.g. GCP Secret Manager or Azure Key
+// Vault, reuse the options, the caching, and the policies of the client.
+// The names of the secrets are the paths resolved against the base prefix.
+// The values are the JSON objects encoded as strings. The methods return
+// the errors wrapping ErrSecretNotFound for the missing secrets. The
+// backends holding connections implement io.Closer, and the client closes
+// them on Close and when Reconfigure replaces them.
+type Backend interface {
+	// GetSecretString returns the value of the version of the secret with
+	// the ID or, when the ID is empty, with the stage, e.g. "AWSCURRENT".
+	// The empty stage selects the current version. The backends without
+	// the version IDs return ErrVersionIDNotSupported for non-empty ones.
+	GetSecretString(ctx context.Context, name, versionID, stage string) (string, error)
+	// DescribeSecret returns the metadata of the secret. The path of the
+	// metadata is ignored.
+	DescribeSecret(ctx context.Context, name string) (*SecretMetadata, error)
+	// ListSecrets returns the names of the secrets starting with the
+	// prefix.
+	ListSecrets(ctx context.Context, prefix string) ([]string, error)
+	CreateSecret(ctx context.Context, name, value string) error
+	// PutSecretValue stores the new version of the secret with the
+	// stages. The empty stages make it the current version.
+	PutSecretValue(ctx context.Context, name, value string, stages []string) error
+	DeleteSecret(ctx context.Context, name string) error
+	RotateSecret(ctx context.Context, name string) error
+}
+
+// BackendFactory returns the backend for the client configuration, e.g.
+// using its FilePath or BackendConfig.
+type BackendFactory func(context.Context, *ClientConfig) (Backend, error)
+
+// backends are the registered backends keyed by provider. AWS Secrets
+// Manager is registered first and is served natively, without a factory.
+var backends = struct {
+	mu        sync.RWMutex
+	factories map[string]BackendFactory
+}{
+	factories: map[string]BackendFactory{defaultProvider: nil},
+}
+
+// RegisterBackend makes the backend created by the factory available as
+// the provider of the client configuration. It is usually called from the
+// init function of the package implementing the backend.
+func RegisterBackend(provider string, factory BackendFactory) error {
+	if provider == "" {
+		return errors.New("backend provider is empty")
+	}
+	if factory == nil {
+		return fmt.Errorf("backend factory of %q provider is nil", provider)
+	}
+	backends.mu.Lock()
+	defer backends.mu.Unlock()
+	if _, exists := backends.factories[provider]; exists {
+		return fmt.Errorf("backend %q already registered", provider)
+	}
+	backends.factories[provider] = factory
+	return nil
+}
+
+// Backends returns the sorted providers of the registered backends.
+func Backends() []string {
+	backends.mu.RLock()
+	defer backends.mu.RUnlock()
+	providers := make([]string, 0, len(backends.factories))
+	for provider := range backends.factories {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// lookupBackend returns the factory of the provider, which is nil for AWS
+// Secrets Manager.
+func lookupBackend(provider string) (BackendFactory, error) {
+	backends.mu.RLock()
+	defer backends.mu.RUnlock()
+	factory, exists := backends.factories[provider]
+	if !exists {
+		return nil, fmt.Errorf("unsupported %q provider", provider)
+	}
+	return factory, nil
+}
+
+// newBackendAPI returns the API serving the requests with the backend of
+// the configured provider, or nil for AWS Secrets Manager.
+func newBackendAPI(ctx context.Context, cfg *ClientConfig) (SecretsManagerAPI, error) {
+	factory, err := lookupBackend(cfg.Provider)
+	if err != nil || factory == nil {
+		return nil, err
+	}
+	b, err := factory(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &backendAPI{b: b}, nil
+}
+
+// backendAPI adapts Backend to SecretsManagerAPI used by the client.
+type backendAPI struct {
+	b Backend
+}
+
+// closeBackend closes the backend of the API, when it implements
+// io.Closer.
+func closeBackend(api SecretsManagerAPI) error {
+	if api, ok := api.(*backendAPI); ok {
+		if closer, ok := api.b.(io.Closer); ok {
+			return closer.Close()
+		}
+	}
+	return nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// GetSecretValue implements SecretsManagerAPI.
+func (api *backendAPI) GetSecretValue(ctx context.Context, input *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	s, err := api.b.GetSecretString(ctx, aws.ToString(input.SecretId), aws.ToString(input.VersionId), aws.ToString(input.VersionStage))
+	if err != nil {
+		return nil, err
+	}
+	return &secretsmanager.GetSecretValueOutput{
+		Name:         input.SecretId,
+		SecretString: aws.String(s),
+	}, nil
+}
+
+// DescribeSecret implements SecretsManagerAPI.
+func (api *backendAPI) DescribeSecret(ctx context.Context, input *secretsmanager.DescribeSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error) {
+	m, err := api.b.DescribeSecret(ctx, aws.ToString(input.SecretId))
+	if err != nil {
+		return nil, err
+	}
+	output := &secretsmanager.DescribeSecretOutput{
+		Name:               input.SecretId,
+		RotationEnabled:    aws.Bool(m.RotationEnabled),
+		CreatedDate:        timePtr(m.CreatedDate),
+		LastChangedDate:    timePtr(m.LastChangedDate),
+		LastRotatedDate:    timePtr(m.LastRotatedDate),
+		NextRotationDate:   timePtr(m.NextRotationDate),
+		DeletedDate:        timePtr(m.DeletedDate),
+		VersionIdsToStages: m.VersionIdsToStages,
+	}
+	if m.ARN != "" {
+		output.ARN = aws.String(m.ARN)
+	}
+	if m.Description != "" {
+		output.Description = aws.String(m.Description)
+	}
+	if m.KMSKeyID != "" {
+		output.KmsKeyId = aws.String(m.KMSKeyID)
+	}
+	for k, v := range m.Tags {
+		output.Tags = append(output.Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return output, nil
+}
+
+// ListSecrets implements SecretsManagerAPI. It returns the secrets
+// matching any of the name filters in a single page.
+func (api *backendAPI) ListSecrets(ctx context.Context, input *secretsmanager.ListSecretsInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.ListSecretsOutput, error) {
+	prefixes := []string{""}
+	for _, filter := range input.Filters {
+		if filter.Key == types.FilterNameStringTypeName {
+			prefixes = filter.Values
+		}
+	}
+	seen := make(map[string]bool)
+	output := &secretsmanager.ListSecretsOutput{}
+	for _, prefix := range prefixes {
+		names, err := api.b.ListSecrets(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				output.SecretList = append(output.SecretList, types.SecretListEntry{Name: aws.String(name)})
+			}
+		}
+	}
+	return output, nil
+}
+
+// CreateSecret implements SecretsManagerAPI.
+func (api *backendAPI) CreateSecret(ctx context.Context, input *secretsmanager.CreateSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error) {
+	if err := api.b.CreateSecret(ctx, aws.ToString(input.Name), aws.ToString(input.SecretString)); err != nil {
+		return nil, err
+	}
+	return &secretsmanager.CreateSecretOutput{Name: input.Name}, nil
+}
+
+// PutSecretValue implements SecretsManagerAPI.
+func (api *backendAPI) PutSecretValue(ctx context.Context, input *secretsmanager.PutSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error) {
+	if err := api.b.PutSecretValue(ctx, aws.ToString(input.SecretId), aws.ToString(input.SecretString), input.VersionStages); err != nil {
+		return nil, err
+	}
+	return &secretsmanager.PutSecretValueOutput{Name: input.SecretId}, nil
+}
+
+// DeleteSecret implements SecretsManagerAPI.
+func (api *backendAPI) DeleteSecret(ctx context.Context, input *secretsmanager.DeleteSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.DeleteSecretOutput, error) {
+	if err := api.b.DeleteSecret(ctx, aws.ToString(input.SecretId)); err != nil {
+		return nil, err
+	}
+	return &secretsmanager.DeleteSecretOutput{Name: input.SecretId}, nil
+}
+
+// RotateSecret implements SecretsManagerAPI.
+func (api *backendAPI) RotateSecret(ctx context.Context, input *secretsmanager.RotateSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.RotateSecretOutput, error) {
+	if err := api.b.RotateSecret(ctx, aws.ToString(input.SecretId)); err != nil {
+		return nil, err
+	}
+	return &secretsmanager.RotateSecretOutput{Name: input.SecretId}, nil
+}
+
+var _ SecretsManagerAPI = (*backendAPI)(nil)
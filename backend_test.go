@@ -0,0 +1,221 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// memoryBackend is the backend keeping the secrets in memory.
+type memoryBackend struct {
+	mu      sync.Mutex
+	secrets map[string]string
+	tags    map[string]map[string]string
+}
+
+func (b *memoryBackend) GetSecretString(_ context.Context, name, versionID, stage string) (string, error) {
+	if versionID != "" {
+		return "", ErrVersionIDNotSupported
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, found := b.secrets[name]
+	if !found || (stage != "" && stage != versionStageCurrent) {
+		return "", fmt.Errorf("%w: %q", ErrSecretNotFound, name)
+	}
+	return v, nil
+}
+
+func (b *memoryBackend) DescribeSecret(ctx context.Context, name string) (*SecretMetadata, error) {
+	if _, err := b.GetSecretString(ctx, name, "", ""); err != nil {
+		return nil, err
+	}
+	return &SecretMetadata{Tags: b.tags[name]}, nil
+}
+
+func (b *memoryBackend) ListSecrets(_ context.Context, prefix string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var names []string
+	for name := range b.secrets {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (b *memoryBackend) CreateSecret(_ context.Context, name, value string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.secrets[name] = value
+	return nil
+}
+
+func (b *memoryBackend) PutSecretValue(ctx context.Context, name, value string, _ []string) error {
+	return b.CreateSecret(ctx, name, value)
+}
+
+func (b *memoryBackend) DeleteSecret(_ context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.secrets, name)
+	return nil
+}
+
+func (b *memoryBackend) RotateSecret(context.Context, string) error {
+	return nil
+}
+
+var testMemoryBackend = &memoryBackend{
+	secrets: map[string]string{
+		"authcrunch/users/jsmith": `{"username":"jsmith"}`,
+		"authcrunch/users/admin":  `{"username":"admin"}`,
+	},
+	tags: map[string]map[string]string{
+		"authcrunch/users/admin": {"classification": "restricted"},
+	},
+}
+
+// closingBackend is the memory backend recording whether it was closed.
+type closingBackend struct {
+	*memoryBackend
+	closed bool
+}
+
+func (b *closingBackend) Close() error {
+	b.closed = true
+	return nil
+}
+
+// testClosingBackends are the closable backends created by the "memory"
+// provider.
+var testClosingBackends []*closingBackend
+
+func init() {
+	if err := RegisterBackend("memory", func(_ context.Context, cfg *ClientConfig) (Backend, error) {
+		if cfg.BackendConfig["fail"] != "" {
+			return nil, errors.New(cfg.BackendConfig["fail"])
+		}
+		if cfg.BackendConfig["closable"] != "" {
+			b := &closingBackend{memoryBackend: testMemoryBackend}
+			testClosingBackends = append(testClosingBackends, b)
+			return b, nil
+		}
+		return testMemoryBackend, nil
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisteredBackend(t *testing.T) {
+	c, err := NewClient(context.TODO(),
+		WithConfig(&ClientConfig{ID: "foo", Provider: "memory", BasePrefix: "authcrunch/"}),
+		WithTagPolicy(&TagPolicyConfig{Deny: []string{"classification=restricted"}}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	ctx := context.TODO()
+
+	got, err := c.GetSecret(ctx, "users/jsmith")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"username": "jsmith"}, got); diff != "" {
+		t.Fatalf("GetSecret() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := c.GetSecret(ctx, "users/admin"); !errors.Is(err, ErrTagDenied) {
+		t.Fatalf("expected ErrTagDenied, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "users/mjones"); !isNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+	if _, err := c.CreateSecret(ctx, "users/mjones", map[string]interface{}{"username": "mjones"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	ch, err := c.PutSecret(ctx, "users/mjones", map[string]interface{}{"username": "mjones", "email": "mjones@localhost"})
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(&Change{Op: "put", Path: "users/mjones", AddedKeys: []string{"email"}}, ch); diff != "" {
+		t.Fatalf("PutSecret() mismatch (-want +got):\n%s", diff)
+	}
+	paths, err := c.ListSecrets(ctx, "users/")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"users/admin", "users/jsmith", "users/mjones"}, paths); diff != "" {
+		t.Fatalf("ListSecrets() mismatch (-want +got):\n%s", diff)
+	}
+
+	pinned, err := NewClient(ctx,
+		WithConfig(&ClientConfig{ID: "foo", Provider: "memory", BasePrefix: "authcrunch/"}),
+		WithVersionPins(&VersionPin{Path: "users/jsmith", VersionID: "a1b2c3d4-5678-90ab-cdef-EXAMPLE11111"}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	if _, err := pinned.GetSecret(ctx, "users/jsmith"); !errors.Is(err, ErrVersionIDNotSupported) {
+		t.Fatalf("expected ErrVersionIDNotSupported, got: %v", err)
+	}
+
+	if _, err := NewClient(ctx, WithConfig(&ClientConfig{
+		ID:            "foo",
+		Provider:      "memory",
+		BackendConfig: map[string]string{"fail": "backend unavailable"},
+	})); err == nil || err.Error() != "backend unavailable" {
+		t.Fatalf("expected backend error, got: %v", err)
+	}
+}
+
+func TestRegisterBackend(t *testing.T) {
+	factory := func(context.Context, *ClientConfig) (Backend, error) { return nil, nil }
+	testcases := []struct {
+		name     string
+		provider string
+		factory  BackendFactory
+		err      error
+	}{
+		{name: "test empty provider", factory: factory, err: errors.New("backend provider is empty")},
+		{name: "test nil factory", provider: "gcp", err: fmt.Errorf("backend factory of %q provider is nil", "gcp")},
+		{name: "test native provider", provider: "aws_secrets_manager", factory: factory, err: fmt.Errorf("backend %q already registered", "aws_secrets_manager")},
+		{name: "test registered provider", provider: "file", factory: factory, err: fmt.Errorf("backend %q already registered", "file")},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := RegisterBackend(tc.provider, tc.factory)
+			if err == nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+				t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+			}
+		})
+	}
+	if diff := cmp.Diff([]string{"aws_secrets_manager", "file", "memory", "proxy"}, Backends()); diff != "" {
+		t.Fatalf("Backends() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := NewClient(context.TODO(), WithConfig(&ClientConfig{ID: "foo", Provider: "gcp"})); err == nil {
+		t.Fatalf("unexpected success with unregistered provider")
+	}
+}
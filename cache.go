@@ -0,0 +1,125 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"sync"
+	"time"
+)
+
+// cacheKey identifies a cached version of a secret.
+type cacheKey struct {
+	region  string
+	roleARN string
+	path    string
+	stage   string
+	// versionID is set for the pinned version, instead of the stage.
+	versionID string
+}
+
+type cacheEntry struct {
+	secret  map[string]interface{}
+	expires time.Time
+}
+
+// secretCache holds the secrets retrieved from AWS Secrets Manager for
+// the configured period of time.
+type secretCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	clock   Clock
+	entries map[cacheKey]*cacheEntry
+}
+
+func newSecretCache(ttl time.Duration, clock Clock) *secretCache {
+	return &secretCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[cacheKey]*cacheEntry),
+	}
+}
+
+// get returns a copy of the cached secret, if it did not expire.
+func (sc *secretCache) get(k cacheKey) (map[string]interface{}, bool) {
+	if sc == nil {
+		return nil, false
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	entry, exists := sc.entries[k]
+	if !exists {
+		return nil, false
+	}
+	if sc.clock.Now().After(entry.expires) {
+		delete(sc.entries, k)
+		return nil, false
+	}
+	return copySecret(entry.secret), true
+}
+
+// set stores a copy of the secret.
+func (sc *secretCache) set(k cacheKey, m map[string]interface{}) {
+	if sc == nil {
+		return
+	}
+	sc.setWithTTL(k, m, sc.ttl)
+}
+
+// setWithTTL stores a copy of the secret for the period of time, unless
+// it exceeds the TTL of the cache. The zero period removes the secret.
+func (sc *secretCache) setWithTTL(k cacheKey, m map[string]interface{}, ttl time.Duration) {
+	if sc == nil {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if ttl <= 0 {
+		delete(sc.entries, k)
+		return
+	}
+	if ttl > sc.ttl {
+		ttl = sc.ttl
+	}
+	sc.entries[k] = &cacheEntry{
+		secret:  copySecret(m),
+		expires: sc.clock.Now().Add(ttl),
+	}
+}
+
+// invalidate removes all cached versions of the secret. It returns true
+// when the secret was in the cache.
+func (sc *secretCache) invalidate(path string) bool {
+	if sc == nil {
+		return false
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	var found bool
+	for k := range sc.entries {
+		if k.path == path {
+			delete(sc.entries, k)
+			found = true
+		}
+	}
+	return found
+}
+
+func copySecret(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCachedSecret(t *testing.T) {
+	v1 := map[string]interface{}{"username": "webadmin", "password": "bcrypt:10:foo"}
+	v2 := map[string]interface{}{"username": "webadmin", "password": "bcrypt:10:bar"}
+	path := "authcrunch/caddy/webadmin"
+
+	testcases := []struct {
+		name       string
+		cacheTTL   string
+		invalidate bool
+		want       []map[string]interface{}
+	}{
+		{
+			name: "test secret retrieval without cache",
+			want: []map[string]interface{}{v1, v2},
+		},
+		{
+			name:     "test secret retrieval with cache",
+			cacheTTL: "1h",
+			want:     []map[string]interface{}{v1, v1},
+		},
+		{
+			name:       "test secret retrieval with invalidated cache",
+			cacheTTL:   "1h",
+			invalidate: true,
+			want:       []map[string]interface{}{v1, v2},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClientWithConfig(context.TODO(), &ClientConfig{
+				ID:       "foo",
+				Region:   "us-east-1",
+				Provider: "aws_secrets_manager",
+				CacheTTL: tc.cacheTTL,
+			})
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newSequenceMockClient(t, []map[string]interface{}{v1, v2}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			var got []map[string]interface{}
+			for i := 0; i < len(tc.want); i++ {
+				m, err := c.GetSecret(context.TODO(), path)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got = append(got, m)
+				if tc.invalidate {
+					c.InvalidateCache(path)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSecretCacheExpiry(t *testing.T) {
+	sc := newSecretCache(-1, systemClock{})
+	k := cacheKey{path: "authcrunch/caddy/webadmin", stage: versionStageCurrent}
+	sc.set(k, map[string]interface{}{"username": "webadmin"})
+	if _, found := sc.get(k); found {
+		t.Fatalf("unexpected cache hit for expired secret")
+	}
+	if sc.invalidate(k.path) {
+		t.Fatalf("unexpected invalidation of expired secret")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CallOption tunes a single call of GetSecret, GetSecretByKey, or
+// PutSecret.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	stage   string
+	timeout time.Duration
+	noCache bool
+	region  string
+	flatten bool
+}
+
+// WithVersionStage selects the version stage of the secret, e.g.
+// "AWSPREVIOUS". PutSecret labels the new version with it.
+func WithVersionStage(stage string) CallOption {
+	return func(o *callOptions) {
+		o.stage = stage
+	}
+}
+
+// WithTimeout bounds the call by the period of time.
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithNoCache retrieves the secret from the service even when it is
+// cached. The retrieved secret still refreshes the cache.
+func WithNoCache() CallOption {
+	return func(o *callOptions) {
+		o.noCache = true
+	}
+}
+
+// WithRegionOverride sends the call to the region instead of the one of
+// the client or the matching route.
+func WithRegionOverride(region string) CallOption {
+	return func(o *callOptions) {
+		o.region = region
+	}
+}
+
+// WithFlatten makes GetSecret and GetSecretByKey flatten the nested
+// objects of the secret into the dotted keys, e.g. {"smtp": {"password":
+// "foo"}} into {"smtp.password": "foo"}. PutSecret ignores it.
+func WithFlatten() CallOption {
+	return func(o *callOptions) {
+		o.flatten = true
+	}
+}
+
+func newCallOptions(opts []CallOption) (*callOptions, error) {
+	o := &callOptions{stage: versionStageCurrent}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.stage == "" {
+		return nil, errors.New("version stage is empty")
+	}
+	if o.timeout < 0 {
+		return nil, fmt.Errorf("malformed %s timeout", o.timeout)
+	}
+	if o.region != "" && !awsRegionRgx.MatchString(o.region) {
+		return nil, fmt.Errorf("malformed %q region override", o.region)
+	}
+	return o, nil
+}
+
+// withTimeout returns the context bounded by the timeout, when it is set.
+func (o *callOptions) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, o.timeout)
+}
@@ -0,0 +1,172 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// callRequest is the summary of a request received by the mock client.
+type callRequest struct {
+	Host          string
+	Target        string
+	VersionStage  string
+	VersionStages []string
+	Deadline      bool
+}
+
+func newCallOptionsMockClient(t *testing.T, requests *[]*callRequest) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return mockFailure(t, "failed reading request: %v", err)
+		}
+		req := &callRequest{
+			Host:   r.URL.Host,
+			Target: strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "secretsmanager."),
+		}
+		if err := json.Unmarshal(b, req); err != nil {
+			return mockFailure(t, "failed parsing request: %v", err)
+		}
+		_, req.Deadline = r.Context().Deadline()
+		*requests = append(*requests, req)
+		return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+	})
+}
+
+func TestCallOptions(t *testing.T) {
+	testcases := []struct {
+		name      string
+		call      func(Client) error
+		want      []*callRequest
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test get secret without options",
+			call: func(c Client) error {
+				_, err := c.GetSecret(context.TODO(), "users/jsmith")
+				return err
+			},
+			want: []*callRequest{
+				{Host: "secretsmanager.us-east-1.amazonaws.com", Target: "GetSecretValue", VersionStage: "AWSCURRENT"},
+			},
+		},
+		{
+			name: "test get secret with version stage, timeout, and region override",
+			call: func(c Client) error {
+				_, err := c.GetSecret(context.TODO(), "users/jsmith",
+					WithVersionStage("AWSPREVIOUS"),
+					WithTimeout(time.Minute),
+					WithRegionOverride("eu-west-1"),
+				)
+				return err
+			},
+			want: []*callRequest{
+				{Host: "secretsmanager.eu-west-1.amazonaws.com", Target: "GetSecretValue", VersionStage: "AWSPREVIOUS", Deadline: true},
+			},
+		},
+		{
+			name: "test get secret by key with and without cache",
+			call: func(c Client) error {
+				for _, opts := range [][]CallOption{nil, nil, {WithNoCache()}} {
+					if _, err := c.GetSecretByKey(context.TODO(), "users/jsmith", "username", opts...); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			want: []*callRequest{
+				{Host: "secretsmanager.us-east-1.amazonaws.com", Target: "GetSecretValue", VersionStage: "AWSCURRENT"},
+				{Host: "secretsmanager.us-east-1.amazonaws.com", Target: "GetSecretValue", VersionStage: "AWSCURRENT"},
+			},
+		},
+		{
+			name: "test put secret with version stage and region override",
+			call: func(c Client) error {
+				_, err := c.PutSecret(context.TODO(), "users/jsmith", map[string]interface{}{"username": "jsmith"},
+					WithVersionStage("STAGED"),
+					WithRegionOverride("eu-west-1"),
+				)
+				return err
+			},
+			want: []*callRequest{
+				{Host: "secretsmanager.eu-west-1.amazonaws.com", Target: "GetSecretValue"},
+				{Host: "secretsmanager.eu-west-1.amazonaws.com", Target: "PutSecretValue", VersionStages: []string{"STAGED"}},
+			},
+		},
+		{
+			name: "test get secret with malformed region override",
+			call: func(c Client) error {
+				_, err := c.GetSecret(context.TODO(), "users/jsmith", WithRegionOverride("foo"))
+				return err
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q region override", "foo"),
+		},
+		{
+			name: "test get secret with empty version stage",
+			call: func(c Client) error {
+				_, err := c.GetSecret(context.TODO(), "users/jsmith", WithVersionStage(""))
+				return err
+			},
+			shouldErr: true,
+			err:       errors.New("version stage is empty"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []*callRequest
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithCacheTTL(5*time.Minute),
+				WithHTTPClient(newCallOptionsMockClient(t, &requests)),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			err = tc.call(c)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, requests); diff != "" {
+				t.Errorf("requests mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrCanaryFailed is returned when the fetched version of a secret fails
+// the canary validation and no previously validated version is known.
+var ErrCanaryFailed = errors.New("secret failed canary validation")
+
+// CanaryConfig is the configuration of the canary validation of the
+// secrets fetched from the service, e.g. a test connection to the database
+// with the fetched credentials. When a new version of the secret fails the
+// validation, the client keeps serving the previously validated version
+// and raises the alert.
+type CanaryConfig struct {
+	// Validate checks the version of the secret at the path. It is called
+	// once per distinct version of the secret.
+	Validate func(ctx context.Context, path string, m map[string]interface{}) error
+	// Alert, when set, is called once per version failing the validation.
+	// It must not block.
+	Alert func(*CanaryFailure)
+}
+
+func (cfg *CanaryConfig) validate() error {
+	if cfg.Validate == nil {
+		return errors.New("canary validate function is nil")
+	}
+	return nil
+}
+
+// CanaryFailure describes the version of a secret failing the canary
+// validation.
+type CanaryFailure struct {
+	Path string
+	Err  error
+	Time time.Time
+	// Fallback is true when the previously validated version is served
+	// instead.
+	Fallback bool
+}
+
+// canaryState is the last validated and the last failed version of a
+// secret.
+type canaryState struct {
+	good         map[string]interface{}
+	goodDigest   string
+	failedDigest string
+}
+
+// canaryValidator tracks the validated versions of the secrets.
+type canaryValidator struct {
+	mu     sync.Mutex
+	cfg    CanaryConfig
+	states map[cacheKey]*canaryState
+}
+
+func newCanaryValidator(cfg *CanaryConfig) *canaryValidator {
+	return &canaryValidator{
+		cfg:    *cfg,
+		states: make(map[cacheKey]*canaryState),
+	}
+}
+
+// checkCanary validates the fetched version of the secret, when the canary
+// validation is enabled. It returns the fetched version, when it is valid,
+// or the previously validated one.
+func (c *client) checkCanary(ctx context.Context, key cacheKey, m map[string]interface{}) (map[string]interface{}, error) {
+	v := c.canary
+	if v == nil {
+		return m, nil
+	}
+	digest := secretDigest(m)
+	v.mu.Lock()
+	state := v.states[key]
+	if state == nil {
+		state = &canaryState{}
+		v.states[key] = state
+	}
+	good, goodDigest, failedDigest := state.good, state.goodDigest, state.failedDigest
+	v.mu.Unlock()
+
+	switch digest {
+	case goodDigest:
+		return m, nil
+	case failedDigest:
+		if good == nil {
+			return nil, fmt.Errorf("%w: %q", ErrCanaryFailed, key.path)
+		}
+		return copySecret(good), nil
+	}
+
+	err := v.cfg.Validate(ctx, key.path, copySecret(m))
+	v.mu.Lock()
+	if err == nil {
+		state.good, state.goodDigest, state.failedDigest = copySecret(m), digest, ""
+	} else {
+		state.failedDigest = digest
+	}
+	v.mu.Unlock()
+	if err == nil {
+		return m, nil
+	}
+
+	failure := &CanaryFailure{
+		Path:     key.path,
+		Err:      err,
+		Time:     c.clock.Now(),
+		Fallback: good != nil,
+	}
+	c.getLogger().Error(
+		"secret failed canary validation",
+		zap.String("path", failure.Path),
+		zap.Bool("fallback", failure.Fallback),
+		zap.Error(err),
+	)
+	if v.cfg.Alert != nil {
+		v.cfg.Alert(failure)
+	}
+	if good == nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrCanaryFailed, key.path, err)
+	}
+	return copySecret(good), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestCanaryValidation(t *testing.T) {
+	var current map[string]interface{}
+	var validated []string
+	var failures []*CanaryFailure
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			return secretsmock.SecretStringResponse(current), nil
+		})),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+		WithCanaryValidation(&CanaryConfig{
+			Validate: func(_ context.Context, path string, m map[string]interface{}) error {
+				validated = append(validated, m["password"].(string))
+				if m["password"] == "bad" {
+					return errors.New("authentication failed")
+				}
+				return nil
+			},
+			Alert: func(f *CanaryFailure) {
+				failures = append(failures, f)
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+
+	// The first version is invalid and there is nothing to fall back to.
+	current = map[string]interface{}{"password": "bad"}
+	if _, err := c.GetSecret(context.TODO(), "authcrunch/db"); !errors.Is(err, ErrCanaryFailed) {
+		t.Fatalf("expected ErrCanaryFailed, got: %v", err)
+	}
+
+	for i, tc := range []struct {
+		password string
+		want     string
+	}{
+		{password: "foo", want: "foo"},
+		{password: "foo", want: "foo"},
+		{password: "bad", want: "foo"},
+		{password: "bad", want: "foo"},
+		{password: "bar", want: "bar"},
+	} {
+		current = map[string]interface{}{"password": tc.password}
+		got, err := c.GetSecret(context.TODO(), "authcrunch/db", WithNoCache())
+		if err != nil {
+			t.Fatalf("%d: expected success, got: %v", i, err)
+		}
+		if got["password"] != tc.want {
+			t.Fatalf("%d: unexpected %v password, want %s", i, got["password"], tc.want)
+		}
+	}
+	if diff := cmp.Diff([]string{"bad", "foo", "bad", "bar"}, validated); diff != "" {
+		t.Fatalf("validated versions mismatch (-want +got):\n%s", diff)
+	}
+	if len(failures) != 2 || failures[0].Fallback || !failures[1].Fallback {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+}
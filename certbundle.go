@@ -0,0 +1,153 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+const certificateBundleKey = "bundle"
+
+// CertificateBundle holds the certificates and the private keys of the
+// certificate bundle secret.
+type CertificateBundle struct {
+	// Certificates are in the order of the bundle, usually the leaf
+	// certificate followed by the intermediate ones.
+	Certificates []*x509.Certificate
+	// PrivateKeys are *rsa.PrivateKey, *ecdsa.PrivateKey, or
+	// ed25519.PrivateKey.
+	PrivateKeys []crypto.PrivateKey
+}
+
+// Leaf returns the first certificate of the bundle.
+func (b *CertificateBundle) Leaf() *x509.Certificate {
+	if len(b.Certificates) == 0 {
+		return nil
+	}
+	return b.Certificates[0]
+}
+
+// GetCertificateBundle returns the certificates and the private keys
+// stored in the bundle key of the secret, either as PEM blocks or as the
+// base64-encoded PKCS#12 archive. The optional passphrase key is the key
+// of the secret holding the passphrase of the PKCS#12 archive or of the
+// encrypted PEM private keys.
+func (c *client) GetCertificateBundle(ctx context.Context, path, passphraseKey string) (*CertificateBundle, error) {
+	m, err := c.GetSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := parseCertificateBundle(m, passphraseKey)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	return bundle, nil
+}
+
+func parseCertificateBundle(m map[string]interface{}, passphraseKey string) (*CertificateBundle, error) {
+	s, err := getStringValue(m, certificateBundleKey, true)
+	if err != nil {
+		return nil, err
+	}
+	var passphrase string
+	if passphraseKey != "" {
+		if passphrase, err = getStringValue(m, passphraseKey, true); err != nil {
+			return nil, err
+		}
+	}
+	var blocks []*pem.Block
+	if strings.Contains(s, "-----BEGIN ") {
+		rest := []byte(s)
+		for {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			blocks = append(blocks, block)
+		}
+	} else {
+		der, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("key %q value is neither pem nor base64", certificateBundleKey)
+		}
+		if blocks, err = pkcs12.ToPEM(der, passphrase); err != nil {
+			return nil, fmt.Errorf("key %q value is malformed: %v", certificateBundleKey, err)
+		}
+	}
+
+	bundle := &CertificateBundle{}
+	for _, block := range blocks {
+		switch {
+		case block.Type == "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("key %q value has malformed certificate: %v", certificateBundleKey, err)
+			}
+			bundle.Certificates = append(bundle.Certificates, cert)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			der := block.Bytes
+			// The legacy encryption of PEM blocks is insecure by design, yet
+			// still common among the exported keys.
+			if x509.IsEncryptedPEMBlock(block) {
+				if passphrase == "" {
+					return nil, fmt.Errorf("key %q value has encrypted private key without passphrase", certificateBundleKey)
+				}
+				if der, err = x509.DecryptPEMBlock(block, []byte(passphrase)); err != nil {
+					return nil, fmt.Errorf("key %q value has malformed private key: %v", certificateBundleKey, err)
+				}
+			}
+			key, err := parsePrivateKey(der)
+			if err != nil {
+				return nil, fmt.Errorf("key %q value has malformed private key: %v", certificateBundleKey, err)
+			}
+			bundle.PrivateKeys = append(bundle.PrivateKeys, key)
+		}
+	}
+	if len(bundle.Certificates) == 0 {
+		return nil, fmt.Errorf("key %q value has no certificates", certificateBundleKey)
+	}
+	return bundle, nil
+}
+
+// parsePrivateKey parses the DER-encoded PKCS#8, PKCS#1, or SEC 1 private
+// key.
+func parsePrivateKey(der []byte) (crypto.PrivateKey, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		switch key := key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+			return key, nil
+		}
+		return nil, errors.New("unsupported private key type")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported private key encoding")
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testPKCS12Bundle is the PKCS#12 archive with the self-signed certificate
+// of authcrunch.local and its P-256 key, protected with "changeit".
+const testPKCS12Bundle = "" +
+	"MIIDkgIBAzCCA1gGCSqGSIb3DQEHAaCCA0kEggNFMIIDQTCCAjcGCSqGSIb3DQEHBqCCAigwggIk" +
+	"AgEAMIICHQYJKoZIhvcNAQcBMBwGCiqGSIb3DQEMAQMwDgQIcQtr4mN4w0MCAggAgIIB8OmjhwM1" +
+	"o6a02geWb3i1xrYROkxR98Vb0mJNDTtZ9i110NdWimrWRTq+HveOcfPSers+zdq9DUCBtWn01d1i" +
+	"VR2CCr565eqqg2ucmbMyiHmQjUc5YsZ9mfO+7SkRBhXkAENMdFClEla9tjojJ1eDX/68PKO3xmGD" +
+	"4oFzGHBhuCxCF+Kv0WdVy8/t6XPls8rC1rfESHHpbhE9XaW43sV/ZA9fY35qttfnwEP/ALB6hwGL" +
+	"D6iwAX0UZauJGwgi8nmv5B+KgiB9m8b29IXXUQGW7ucPMBsqfWU2kH5/p6PybX4G946Fm+NslvHY" +
+	"Bchn77gMhQadxC6rf9lan71Z3BZCWGoV+aQuPcgVcbkO9zD4/pl1UIuPnKoL3n3VP/K4wqWjd8ut" +
+	"uLR9sTAtkCJKPuqTNGNtje8BH+K11cjOmrajyrQZ2k81s6gca4kQQrfmSHHZ70QVz7PVRg8aX/Dl" +
+	"IFjpUZrWAtyGFzTj0v9mODgWLqDLLSKOtyC/LNbtiTRyrJuFISo10yzvYwdcmkpNsix+vtFKPi7K" +
+	"ubm+72BnhlrrykEwhjj8ZKtcQHpWpwNHa10qyK+moJCnouKJ6mv2IM//rYXdTqikKB4y0OyguHNe" +
+	"V03Wbmnry32sG83BpIRZ9cfU63sJzgcz9oEVZqD+7ieNYWIwggECBgkqhkiG9w0BBwGggfQEgfEw" +
+	"ge4wgesGCyqGSIb3DQEMCgECoIG0MIGxMBwGCiqGSIb3DQEMAQMwDgQI4bcrWv62jzoCAggABIGQ" +
+	"+TMiar8yHsj4IG2YgeOcC16WUvKsAkxbWw7Xef7vlNI1Qhslp4zQwEh4/pwuiowH1d/plcD/PXPu" +
+	"6k8tLrv+B4OnYMbIZP5BgLey68+nAohQtdkh2nHv26oP9T/FG17Q+J9P0AYIlOLl3UJ64zdD4gaN" +
+	"C/gqPjnRFitNOx41ibP9uE7c7AdglBLYNsIMbjPLMSUwIwYJKoZIhvcNAQkVMRYEFMSFjzPILqWs" +
+	"By0axQvPIkCCXngTMDEwITAJBgUrDgMCGgUABBSQKTKYQeyhH1bCVv+TVb6/BGd4dQQIseyEvYUg" +
+	"8wECAggA"
+
+func TestGetCertificateBundle(t *testing.T) {
+	path := "authcrunch/caddy/tls"
+	key, cert := newSNSSigningKey(t)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	testcases := []struct {
+		name          string
+		secret        map[string]interface{}
+		passphraseKey string
+		commonName    string
+		keys          int
+		shouldErr     bool
+		err           error
+	}{
+		{
+			name:       "test pem bundle",
+			secret:     map[string]interface{}{"bundle": string(cert) + string(keyPEM)},
+			commonName: "sns.amazonaws.com",
+			keys:       1,
+		},
+		{
+			name:          "test pkcs12 bundle",
+			secret:        map[string]interface{}{"bundle": testPKCS12Bundle, "passphrase": "changeit"},
+			passphraseKey: "passphrase",
+			commonName:    "authcrunch.local",
+			keys:          1,
+		},
+		{
+			name:          "test pkcs12 bundle with wrong passphrase",
+			secret:        map[string]interface{}{"bundle": testPKCS12Bundle, "passphrase": "foobar"},
+			passphraseKey: "passphrase",
+			shouldErr:     true,
+			err:           fmt.Errorf("malformed %q secret: key %q value is malformed: pkcs12: decryption password incorrect", path, "bundle"),
+		},
+		{
+			name:          "test bundle without passphrase",
+			secret:        map[string]interface{}{"bundle": testPKCS12Bundle},
+			passphraseKey: "passphrase",
+			shouldErr:     true,
+			err:           fmt.Errorf("malformed %q secret: key %q not found", path, "passphrase"),
+		},
+		{
+			name:      "test bundle without certificates",
+			secret:    map[string]interface{}{"bundle": string(keyPEM)},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value has no certificates", path, "bundle"),
+		},
+		{
+			name:      "test malformed bundle",
+			secret:    map[string]interface{}{"bundle": "foo bar"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value is neither pem nor base64", path, "bundle"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, map[string]map[string]interface{}{"AWSCURRENT": tc.secret}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetCertificateBundle(context.TODO(), path, tc.passphraseKey)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("GetCertificateBundle() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if cn := got.Leaf().Subject.CommonName; cn != tc.commonName {
+				t.Errorf("unexpected leaf certificate %q, want %q", cn, tc.commonName)
+			}
+			if len(got.PrivateKeys) != tc.keys {
+				t.Fatalf("unexpected number of private keys: %d, want %d", len(got.PrivateKeys), tc.keys)
+			}
+			switch k := got.PrivateKeys[0].(type) {
+			case *rsa.PrivateKey:
+				if !k.PublicKey.Equal(got.Leaf().PublicKey) {
+					t.Errorf("private key does not match leaf certificate")
+				}
+			case *ecdsa.PrivateKey:
+				if !k.PublicKey.Equal(got.Leaf().PublicKey) {
+					t.Errorf("private key does not match leaf certificate")
+				}
+			default:
+				t.Errorf("unexpected private key type %T", k)
+			}
+		})
+	}
+}
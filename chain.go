@@ -0,0 +1,668 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrSecretNotFound is the error the Client implementations other than
+// AWS Secrets Manager wrap, when the secret does not exist. ChainClient
+// falls back to the next backend on it, as well as on
+// ResourceNotFoundException of AWS Secrets Manager.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// ChainBackend is a named Client of ChainClient.
+type ChainBackend struct {
+	Name   string
+	Client Client
+}
+
+// ChainBackendStats are the counters of the requests to a backend of
+// ChainClient.
+type ChainBackendStats struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Requests is the number of the requests sent to the backend.
+	Requests uint64 `json:"requests" xml:"requests" yaml:"requests"`
+	// Hits is the number of the successful requests.
+	Hits uint64 `json:"hits" xml:"hits" yaml:"hits"`
+	// NotFound is the number of the requests for the secrets the backend
+	// does not have, which fall back to the next backend.
+	NotFound uint64 `json:"not_found" xml:"not_found" yaml:"not_found"`
+	// Errors is the number of the failed requests.
+	Errors uint64 `json:"errors" xml:"errors" yaml:"errors"`
+}
+
+// chainBackend is the backend with its counters, shared by the scoped
+// views of the chain.
+type chainBackend struct {
+	name   string
+	client Client
+	stats  *ChainBackendStats
+}
+
+// ChainClient composes the clients, e.g. AWS Secrets Manager, Parameter
+// Store, or local files, into a Client. The reads try the backends in
+// order, falling back to the next one when the secret is not found. The
+// other errors, e.g. the policy violations, are returned as is. The writes,
+// the watchers, and the event listeners use the first, primary, backend.
+type ChainClient struct {
+	backends []*chainBackend
+}
+
+// NewChainClient returns the client composing the backends in the order
+// of priority.
+func NewChainClient(backends ...*ChainBackend) (*ChainClient, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("chain backends not found")
+	}
+	ch := &ChainClient{}
+	names := make(map[string]bool)
+	for i, b := range backends {
+		if b == nil || b.Client == nil {
+			return nil, fmt.Errorf("chain backend %d has no client", i)
+		}
+		if b.Name == "" {
+			return nil, fmt.Errorf("chain backend %d has no name", i)
+		}
+		if names[b.Name] {
+			return nil, fmt.Errorf("duplicate %q chain backend", b.Name)
+		}
+		names[b.Name] = true
+		ch.backends = append(ch.backends, &chainBackend{
+			name:   b.Name,
+			client: b.Client,
+			stats:  &ChainBackendStats{Name: b.Name},
+		})
+	}
+	return ch, nil
+}
+
+// Stats returns the counters of the requests to the backends.
+func (ch *ChainClient) Stats() []ChainBackendStats {
+	stats := make([]ChainBackendStats, 0, len(ch.backends))
+	for _, b := range ch.backends {
+		stats = append(stats, ChainBackendStats{
+			Name:     b.name,
+			Requests: atomic.LoadUint64(&b.stats.Requests),
+			Hits:     atomic.LoadUint64(&b.stats.Hits),
+			NotFound: atomic.LoadUint64(&b.stats.NotFound),
+			Errors:   atomic.LoadUint64(&b.stats.Errors),
+		})
+	}
+	return stats
+}
+
+func (ch *ChainClient) primary() Client {
+	return ch.backends[0].client
+}
+
+// try calls fn with the clients of the backends in order, until it
+// succeeds or fails with an error other than not found. When no backend
+// has the secret, it returns the error of the first backend.
+func (ch *ChainClient) try(fn func(Client) error) error {
+	var notFoundErr error
+	for _, b := range ch.backends {
+		atomic.AddUint64(&b.stats.Requests, 1)
+		err := fn(b.client)
+		switch {
+		case err == nil:
+			atomic.AddUint64(&b.stats.Hits, 1)
+			return nil
+		case isNotFound(err):
+			atomic.AddUint64(&b.stats.NotFound, 1)
+			if notFoundErr == nil {
+				notFoundErr = err
+			}
+		default:
+			atomic.AddUint64(&b.stats.Errors, 1)
+			return err
+		}
+	}
+	return notFoundErr
+}
+
+// GetSecret returns the key-value map of the secret from the first backend
+// having it.
+func (ch *ChainClient) GetSecret(ctx context.Context, path string, opts ...CallOption) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	err := ch.try(func(c Client) (err error) {
+		m, err = c.GetSecret(ctx, path, opts...)
+		return err
+	})
+	return m, err
+}
+
+// GetSecretByKey returns the value of the key of the secret from the first
+// backend having it.
+func (ch *ChainClient) GetSecretByKey(ctx context.Context, path, key string, opts ...CallOption) (interface{}, error) {
+	var v interface{}
+	err := ch.try(func(c Client) (err error) {
+		v, err = c.GetSecretByKey(ctx, path, key, opts...)
+		return err
+	})
+	return v, err
+}
+
+// GetSecretBytes returns the value of the key of the secret from the first
+// backend having it.
+func (ch *ChainClient) GetSecretBytes(ctx context.Context, path, key string, opts ...CallOption) (*Secret, error) {
+	var s *Secret
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetSecretBytes(ctx, path, key, opts...)
+		return err
+	})
+	return s, err
+}
+
+// GetSecretInto writes the value of the secret from the first backend
+// having it.
+func (ch *ChainClient) GetSecretInto(ctx context.Context, path string, w io.Writer) error {
+	return ch.try(func(c Client) error {
+		return c.GetSecretInto(ctx, path, w)
+	})
+}
+
+// GetSecretTemplated returns the key-value map of the secret at the path
+// rendered from the template from the first backend having it.
+func (ch *ChainClient) GetSecretTemplated(ctx context.Context, tmpl string, vars map[string]string) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	err := ch.try(func(c Client) (err error) {
+		m, err = c.GetSecretTemplated(ctx, tmpl, vars)
+		return err
+	})
+	return m, err
+}
+
+// GetTokenSecrets returns the token signing secrets from the first backend
+// having them.
+func (ch *ChainClient) GetTokenSecrets(ctx context.Context, path string) (*TokenSecrets, error) {
+	var s *TokenSecrets
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetTokenSecrets(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetAccessToken returns the access token secret from the first backend
+// having it.
+func (ch *ChainClient) GetAccessToken(ctx context.Context, path string) (*AccessToken, error) {
+	var s *AccessToken
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetAccessToken(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetOAuthClientCredentials returns the OAuth client credentials from the
+// first backend having them.
+func (ch *ChainClient) GetOAuthClientCredentials(ctx context.Context, path string) (*OAuthClientCredentials, error) {
+	var s *OAuthClientCredentials
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetOAuthClientCredentials(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetSMTPCredentials returns the SMTP credentials from the first backend
+// having them.
+func (ch *ChainClient) GetSMTPCredentials(ctx context.Context, path string) (*SMTPCredentials, error) {
+	var s *SMTPCredentials
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetSMTPCredentials(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetDatabaseCredentials returns the database credentials from the first
+// backend having them.
+func (ch *ChainClient) GetDatabaseCredentials(ctx context.Context, path string) (*DatabaseCredentials, error) {
+	var s *DatabaseCredentials
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetDatabaseCredentials(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetUserCredentials returns the user credentials from the first backend
+// having them.
+func (ch *ChainClient) GetUserCredentials(ctx context.Context, path string) (*UserCredentials, error) {
+	var s *UserCredentials
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetUserCredentials(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetLDAPBindCredentials returns the LDAP bind credentials from the first
+// backend having them.
+func (ch *ChainClient) GetLDAPBindCredentials(ctx context.Context, path string) (*LDAPBindCredentials, error) {
+	var s *LDAPBindCredentials
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetLDAPBindCredentials(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetCertificateBundle returns the certificate bundle from the first
+// backend having it.
+func (ch *ChainClient) GetCertificateBundle(ctx context.Context, path, passphraseKey string) (*CertificateBundle, error) {
+	var s *CertificateBundle
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetCertificateBundle(ctx, path, passphraseKey)
+		return err
+	})
+	return s, err
+}
+
+// GetTLSCertificate returns the TLS certificate from the first backend
+// having it.
+func (ch *ChainClient) GetTLSCertificate(ctx context.Context, path string) (*tls.Certificate, error) {
+	var s *tls.Certificate
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetTLSCertificate(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetSSHSigner returns the SSH signer from the first backend having the
+// private key.
+func (ch *ChainClient) GetSSHSigner(ctx context.Context, path, key string) (ssh.Signer, error) {
+	var s ssh.Signer
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetSSHSigner(ctx, path, key)
+		return err
+	})
+	return s, err
+}
+
+// GetTOTPSeed returns the TOTP seed from the first backend having it.
+func (ch *ChainClient) GetTOTPSeed(ctx context.Context, path string) (*TOTPSeed, error) {
+	var s *TOTPSeed
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetTOTPSeed(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetPGPEntities returns the PGP entities from the first backend having
+// the keys.
+func (ch *ChainClient) GetPGPEntities(ctx context.Context, path, key string) (openpgp.EntityList, error) {
+	var s openpgp.EntityList
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetPGPEntities(ctx, path, key)
+		return err
+	})
+	return s, err
+}
+
+// GetWebhookSecret returns the webhook secret from the first backend
+// having it.
+func (ch *ChainClient) GetWebhookSecret(ctx context.Context, path string) (*WebhookSecret, error) {
+	var s *WebhookSecret
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetWebhookSecret(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetSessionKeys returns the session keys from the first backend having
+// them.
+func (ch *ChainClient) GetSessionKeys(ctx context.Context, path string) (*SessionKeys, error) {
+	var s *SessionKeys
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetSessionKeys(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// VerifyUserPassword verifies the candidate password of the user secret
+// from the first backend having it.
+func (ch *ChainClient) VerifyUserPassword(ctx context.Context, path, candidate string) (bool, error) {
+	var ok bool
+	err := ch.try(func(c Client) (err error) {
+		ok, err = c.VerifyUserPassword(ctx, path, candidate)
+		return err
+	})
+	return ok, err
+}
+
+// GetUserSecret returns the key-value map of the user secret in the realm
+// from the first backend having it.
+func (ch *ChainClient) GetUserSecret(ctx context.Context, realmName, username string) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	err := ch.try(func(c Client) (err error) {
+		m, err = c.GetUserSecret(ctx, realmName, username)
+		return err
+	})
+	return m, err
+}
+
+// GetSecretWithPrevious returns the current and the previous versions of
+// the secret from the first backend having it.
+func (ch *ChainClient) GetSecretWithPrevious(ctx context.Context, path string) (*SecretWithPrevious, error) {
+	var s *SecretWithPrevious
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetSecretWithPrevious(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// GetSecretVersion returns the current or the pinned version of the secret
+// from the first backend having it.
+func (ch *ChainClient) GetSecretVersion(ctx context.Context, path string) (*SecretVersion, error) {
+	var v *SecretVersion
+	err := ch.try(func(c Client) (err error) {
+		v, err = c.GetSecretVersion(ctx, path)
+		return err
+	})
+	return v, err
+}
+
+// DiffSecretVersions compares the versions of the secret from the first
+// backend having it.
+func (ch *ChainClient) DiffSecretVersions(ctx context.Context, path, v1, v2 string, opts ...DiffOption) (*VersionDiff, error) {
+	var d *VersionDiff
+	err := ch.try(func(c Client) (err error) {
+		d, err = c.DiffSecretVersions(ctx, path, v1, v2, opts...)
+		return err
+	})
+	return d, err
+}
+
+// GetSecretWithMetadata returns the current version of the secret along
+// with its metadata from the first backend having it.
+func (ch *ChainClient) GetSecretWithMetadata(ctx context.Context, path string) (*SecretWithMetadata, error) {
+	var s *SecretWithMetadata
+	err := ch.try(func(c Client) (err error) {
+		s, err = c.GetSecretWithMetadata(ctx, path)
+		return err
+	})
+	return s, err
+}
+
+// ExportEnv writes the secrets at the paths, each from the first backend
+// having it, to the writer.
+func (ch *ChainClient) ExportEnv(ctx context.Context, paths []string, w io.Writer, format string, opts ...ExportOption) error {
+	return exportEnv(ctx, ch, paths, w, format, opts...)
+}
+
+// LoadIntoEnv sets the environment variables to the values of the
+// secrets, each from the first backend having it.
+func (ch *ChainClient) LoadIntoEnv(ctx context.Context, mapping map[string]string, opts ...EnvOption) error {
+	return loadIntoEnv(ctx, ch, mapping, nil, opts...)
+}
+
+// RenderTemplate renders the template with the secrets, each from the
+// first backend having it.
+func (ch *ChainClient) RenderTemplate(ctx context.Context, tmpl string, w io.Writer) error {
+	return renderTemplate(ctx, ch, tmpl, w)
+}
+
+// ListSecrets returns the sorted union of the paths of the secrets with
+// the prefix in all the backends.
+func (ch *ChainClient) ListSecrets(ctx context.Context, prefix string) ([]string, error) {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, b := range ch.backends {
+		atomic.AddUint64(&b.stats.Requests, 1)
+		items, err := b.client.ListSecrets(ctx, prefix)
+		if err != nil {
+			atomic.AddUint64(&b.stats.Errors, 1)
+			return nil, err
+		}
+		atomic.AddUint64(&b.stats.Hits, 1)
+		for _, path := range items {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// DescribeSecret returns the metadata of the secret from the first backend
+// having it.
+func (ch *ChainClient) DescribeSecret(ctx context.Context, path string) (*SecretMetadata, error) {
+	var m *SecretMetadata
+	err := ch.try(func(c Client) (err error) {
+		m, err = c.DescribeSecret(ctx, path)
+		return err
+	})
+	return m, err
+}
+
+// ListVersionHistory returns the versions of the secret from the first
+// backend having it.
+func (ch *ChainClient) ListVersionHistory(ctx context.Context, path string) ([]*SecretVersionInfo, error) {
+	var versions []*SecretVersionInfo
+	err := ch.try(func(c Client) (err error) {
+		versions, err = c.ListVersionHistory(ctx, path)
+		return err
+	})
+	return versions, err
+}
+
+// AnnotateVersion writes the annotation of the version of the secret in
+// the primary backend.
+func (ch *ChainClient) AnnotateVersion(ctx context.Context, path, versionID, annotation string) (*Change, error) {
+	return ch.primary().AnnotateVersion(ctx, path, versionID, annotation)
+}
+
+// GetSecretAcrossAccounts returns the secret fetched in the accounts of
+// the roles with the primary backend.
+func (ch *ChainClient) GetSecretAcrossAccounts(ctx context.Context, path string, roleARNs []string) (map[string]*AccountSecret, error) {
+	return ch.primary().GetSecretAcrossAccounts(ctx, path, roleARNs)
+}
+
+// CreateSecret creates the secret in the primary backend.
+func (ch *ChainClient) CreateSecret(ctx context.Context, path string, m map[string]interface{}) (*Change, error) {
+	return ch.primary().CreateSecret(ctx, path, m)
+}
+
+// PutSecret stores the new version of the secret in the primary backend.
+func (ch *ChainClient) PutSecret(ctx context.Context, path string, m map[string]interface{}, opts ...CallOption) (*Change, error) {
+	return ch.primary().PutSecret(ctx, path, m, opts...)
+}
+
+// DeleteSecret schedules the deletion of the secret in the primary
+// backend.
+func (ch *ChainClient) DeleteSecret(ctx context.Context, path string) (*Change, error) {
+	return ch.primary().DeleteSecret(ctx, path)
+}
+
+// RotateSecret starts the rotation of the secret in the primary backend.
+func (ch *ChainClient) RotateSecret(ctx context.Context, path string) (*Change, error) {
+	return ch.primary().RotateSecret(ctx, path)
+}
+
+// Sync makes the secrets in the primary backend match the key-value maps.
+func (ch *ChainClient) Sync(ctx context.Context, secrets map[string]map[string]interface{}) ([]*Change, error) {
+	return ch.primary().Sync(ctx, secrets)
+}
+
+// Watch polls the secrets in the primary backend for changes.
+func (ch *ChainClient) Watch(ctx context.Context, paths []string) (<-chan SecretEvent, error) {
+	return ch.primary().Watch(ctx, paths)
+}
+
+// SetMockClient sets the HTTP client of all the backends.
+func (ch *ChainClient) SetMockClient(httpClient aws.HTTPClient) {
+	for _, b := range ch.backends {
+		b.client.SetMockClient(httpClient)
+	}
+}
+
+// SetMockCredentialsProvider sets the credentials provider of all the
+// backends.
+func (ch *ChainClient) SetMockCredentialsProvider(provider aws.CredentialsProvider) {
+	for _, b := range ch.backends {
+		b.client.SetMockCredentialsProvider(provider)
+	}
+}
+
+// SetLogger sets the logger of all the backends.
+func (ch *ChainClient) SetLogger(logger *zap.Logger) {
+	for _, b := range ch.backends {
+		b.client.SetLogger(logger)
+	}
+}
+
+// GetConfig returns the effective configuration of the primary backend.
+func (ch *ChainClient) GetConfig(ctx context.Context) *Config {
+	return ch.primary().GetConfig(ctx)
+}
+
+// Simple returns the context-free view of the chain, bounded by the
+// default timeout of the primary backend.
+func (ch *ChainClient) Simple() *SimpleClient {
+	return NewSimpleClient(ch)
+}
+
+// Diagnose checks the primary backend.
+func (ch *ChainClient) Diagnose(ctx context.Context) *DiagnosticReport {
+	return ch.primary().Diagnose(ctx)
+}
+
+// Reconfigure applies the configuration to the primary backend.
+func (ch *ChainClient) Reconfigure(ctx context.Context, cfg *ClientConfig) error {
+	return ch.primary().Reconfigure(ctx, cfg)
+}
+
+// InvalidateCache removes the cached versions of the secret in all the
+// backends.
+func (ch *ChainClient) InvalidateCache(path string) {
+	for _, b := range ch.backends {
+		b.client.InvalidateCache(path)
+	}
+}
+
+// BlockAccess blocks the reads of the secrets under the prefix in all the
+// backends.
+func (ch *ChainClient) BlockAccess(prefix string, d time.Duration) error {
+	for _, b := range ch.backends {
+		if err := b.client.BlockAccess(prefix, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnblockAccess removes the block of the prefix in all the backends.
+func (ch *ChainClient) UnblockAccess(prefix string) error {
+	for _, b := range ch.backends {
+		if err := b.client.UnblockAccess(prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AnomalyStats returns the sums of the counters of the anomaly detection
+// of all the backends.
+func (ch *ChainClient) AnomalyStats() (AnomalyStats, error) {
+	var stats AnomalyStats
+	for _, b := range ch.backends {
+		s, err := b.client.AnomalyStats()
+		if err != nil {
+			return AnomalyStats{}, err
+		}
+		stats.Reads += s.Reads
+		stats.Anomalies += s.Anomalies
+		stats.Blocked += s.Blocked
+	}
+	return stats, nil
+}
+
+// ReportInvalid refreshes the secret reported invalid in the first backend
+// having it.
+func (ch *ChainClient) ReportInvalid(ctx context.Context, path string, opts ...InvalidOption) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	err := ch.try(func(c Client) (err error) {
+		m, err = c.ReportInvalid(ctx, path, opts...)
+		return err
+	})
+	return m, err
+}
+
+// ListenSQS receives the events of the primary backend.
+func (ch *ChainClient) ListenSQS(ctx context.Context, queueURL string) (<-chan SecretEvent, error) {
+	return ch.primary().ListenSQS(ctx, queueURL)
+}
+
+// SetupEventWiring sets up the delivery of the events of the primary
+// backend.
+func (ch *ChainClient) SetupEventWiring(ctx context.Context, cfg *EventWiringConfig) (*EventWiring, error) {
+	return ch.primary().SetupEventWiring(ctx, cfg)
+}
+
+// SNSHandler receives the events of the primary backend.
+func (ch *ChainClient) SNSHandler(ctx context.Context, topicARN string) (http.Handler, <-chan SecretEvent, error) {
+	return ch.primary().SNSHandler(ctx, topicARN)
+}
+
+// Scoped returns the chain of the scoped views of the backends. The views
+// share the counters with the chain.
+func (ch *ChainClient) Scoped(prefix string) Client {
+	scoped := &ChainClient{}
+	for _, b := range ch.backends {
+		scoped.backends = append(scoped.backends, &chainBackend{
+			name:   b.name,
+			client: b.client.Scoped(prefix),
+			stats:  b.stats,
+		})
+	}
+	return scoped
+}
+
+// Close closes all the backends. It returns the first error.
+func (ch *ChainClient) Close() error {
+	var firstErr error
+	for _, b := range ch.backends {
+		if err := b.client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+var _ Client = (*ChainClient)(nil)
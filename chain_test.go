@@ -0,0 +1,148 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChainClient(t *testing.T) {
+	primaryStore := map[string]string{
+		"users/jsmith": `{"username":"jsmith"}`,
+	}
+	secondaryStore := map[string]string{
+		"users/jsmith": `{"username":"legacy"}`,
+		"users/mjones": `{"username":"mjones"}`,
+		"admin/root":   `{"username":"root"}`,
+	}
+	var primaryRequests, secondaryRequests []string
+	ch, err := NewChainClient(
+		&ChainBackend{
+			Name: "secretsmanager",
+			Client: newWritesTestClient(t, primaryStore, &primaryRequests,
+				WithPathPolicy(&PathPolicyConfig{Deny: []string{"admin/*"}}),
+			),
+		},
+		&ChainBackend{
+			Name:   "legacy",
+			Client: newWritesTestClient(t, secondaryStore, &secondaryRequests),
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during chain initialization: %v", err)
+	}
+	ctx := context.TODO()
+
+	testcases := []struct {
+		path      string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{path: "users/jsmith", want: map[string]interface{}{"username": "jsmith"}},
+		{path: "users/mjones", want: map[string]interface{}{"username": "mjones"}},
+		{path: "users/guest", shouldErr: true},
+		{
+			path:      "admin/root",
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q matches %q deny pattern", ErrPathNotAllowed, "admin/root", "admin/*"),
+		},
+	}
+	for _, tc := range testcases {
+		got, err := ch.GetSecret(ctx, tc.path)
+		if err != nil {
+			if !tc.shouldErr {
+				t.Fatalf("%s: expected success, got: %v", tc.path, err)
+			}
+			if tc.err == nil && !isNotFound(err) {
+				t.Fatalf("%s: expected not found error, got: %v", tc.path, err)
+			}
+			if tc.err != nil && tc.err.Error() != err.Error() {
+				t.Fatalf("%s: unexpected error: %v, want: %v", tc.path, err, tc.err)
+			}
+			continue
+		}
+		if tc.shouldErr {
+			t.Fatalf("%s: unexpected success", tc.path)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Fatalf("%s: GetSecret() mismatch (-want +got):\n%s", tc.path, diff)
+		}
+	}
+
+	wantStats := []ChainBackendStats{
+		{Name: "secretsmanager", Requests: 4, Hits: 1, NotFound: 2, Errors: 1},
+		{Name: "legacy", Requests: 2, Hits: 1, NotFound: 1},
+	}
+	if diff := cmp.Diff(wantStats, ch.Stats()); diff != "" {
+		t.Fatalf("Stats() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ch.CreateSecret(ctx, "users/guest", map[string]interface{}{"username": "guest"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"CreateSecret users/guest"}, primaryRequests); diff != "" {
+		t.Fatalf("primary requests mismatch (-want +got):\n%s", diff)
+	}
+	if len(secondaryRequests) != 0 {
+		t.Fatalf("unexpected secondary requests: %v", secondaryRequests)
+	}
+
+	if _, err := ch.Scoped("users").GetSecret(ctx, "admin/root"); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+	}
+}
+
+func TestNewChainClient(t *testing.T) {
+	c := newWritesTestClient(t, map[string]string{}, &[]string{})
+	testcases := []struct {
+		name     string
+		backends []*ChainBackend
+		err      error
+	}{
+		{name: "test chain without backends", err: errors.New("chain backends not found")},
+		{
+			name:     "test backend without client",
+			backends: []*ChainBackend{{Name: "secretsmanager"}},
+			err:      errors.New("chain backend 0 has no client"),
+		},
+		{
+			name:     "test backend without name",
+			backends: []*ChainBackend{{Client: c}},
+			err:      errors.New("chain backend 0 has no name"),
+		},
+		{
+			name:     "test duplicate backends",
+			backends: []*ChainBackend{{Name: "secretsmanager", Client: c}, {Name: "secretsmanager", Client: c}},
+			err:      fmt.Errorf("duplicate %q chain backend", "secretsmanager"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewChainClient(tc.backends...)
+			if err == nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+				t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrChecksumMismatch is returned when the checksum stored in a secret
+// does not match its content.
+var ErrChecksumMismatch = errors.New("secret checksum mismatch")
+
+// SecretChecksum returns the hex-encoded SHA-256 checksum of the canonical
+// JSON encoding of the key-value map without the checksum key. The
+// canonical encoding follows RFC 8785 (JSON Canonicalization Scheme), so
+// that the checksums computed in other languages match:
+//
+//   - no whitespace between the tokens;
+//   - the object keys are sorted by their UTF-16 code units at every level;
+//   - the strings escape only the quotation mark, the reverse solidus, and
+//     the control characters, using \b, \t, \n, \f, \r, or lowercase
+//     \u00xx, so "<", ">", "&", U+2028, and U+2029 are written as is;
+//   - the numbers, including json.Number, are IEEE 754 doubles in the
+//     shortest form of ECMAScript, e.g. 1, 0.5, 1e+21, with negative zero
+//     written as 0. NaN and infinities are errors.
+//
+// The values of other types are encoded with encoding/json first.
+func SecretChecksum(m map[string]interface{}, checksumKey string) (string, error) {
+	b, err := appendCanonicalJSON(nil, withoutKey(m, checksumKey))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// appendCanonicalJSON appends the canonical JSON encoding of the value to
+// the buffer, see SecretChecksum.
+func appendCanonicalJSON(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case bool:
+		return strconv.AppendBool(b, v), nil
+	case string:
+		return appendCanonicalString(b, v), nil
+	case float64:
+		return appendCanonicalNumber(b, v)
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed %q number: %v", v, err)
+		}
+		return appendCanonicalNumber(b, f)
+	case []interface{}:
+		b = append(b, '[')
+		for i, item := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendCanonicalJSON(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+		b = append(b, '{')
+		for i, k := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(appendCanonicalString(b, k), ':')
+			var err error
+			if b, err = appendCanonicalJSON(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return appendCanonicalJSON(b, decoded)
+}
+
+// appendCanonicalNumber appends the ECMAScript representation of the
+// number. The encoding/json formatting of float64 values matches it.
+func appendCanonicalNumber(b []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("unsupported %v number", f)
+	}
+	if f == 0 {
+		return append(b, '0'), nil
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, data...), nil
+}
+
+// appendCanonicalString appends the quoted string. The invalid UTF-8
+// bytes are replaced with U+FFFD, as encoding/json does.
+func appendCanonicalString(b []byte, s string) []byte {
+	const hexDigits = "0123456789abcdef"
+	b = append(b, '"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b = append(b, '\\', byte(r))
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\t':
+			b = append(b, '\\', 't')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\r':
+			b = append(b, '\\', 'r')
+		default:
+			if r < 0x20 {
+				b = append(b, '\\', 'u', '0', '0', hexDigits[r>>4], hexDigits[r&0xf])
+				continue
+			}
+			b = utf8.AppendRune(b, r)
+		}
+	}
+	return append(b, '"')
+}
+
+// lessUTF16 reports whether the string sorts before the other one by
+// their UTF-16 code units.
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+// withoutKey returns the shallow copy of the map without the key, or the
+// map itself, when it has no such key.
+func withoutKey(m map[string]interface{}, key string) map[string]interface{} {
+	if _, found := m[key]; !found {
+		return m
+	}
+	out := make(map[string]interface{}, len(m)-1)
+	for k, v := range m {
+		if k != key {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// verifyChecksum returns ErrChecksumMismatch when the secret lacks the
+// checksum key or the checksum does not match the content.
+func verifyChecksum(checksumKey, secretPath string, m map[string]interface{}) error {
+	if checksumKey == "" {
+		return nil
+	}
+	v, found := m[checksumKey]
+	if !found {
+		return fmt.Errorf("%w: %q has no %q key", ErrChecksumMismatch, secretPath, checksumKey)
+	}
+	stored, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("%w: %q has non-string %q key", ErrChecksumMismatch, secretPath, checksumKey)
+	}
+	sum, err := SecretChecksum(m, checksumKey)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(sum)) != 1 {
+		return fmt.Errorf("%w: %q content does not match %q key", ErrChecksumMismatch, secretPath, checksumKey)
+	}
+	return nil
+}
+
+// withChecksum returns the copy of the map with the checksum key set to
+// the checksum of its content.
+func withChecksum(checksumKey string, m map[string]interface{}) (map[string]interface{}, error) {
+	sum, err := SecretChecksum(m, checksumKey)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	out[checksumKey] = sum
+	return out, nil
+}
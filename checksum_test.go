@@ -0,0 +1,159 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testChecksum = "4b6390bd70f7d7c30b3a4825ba5557ee5f555035a1187cd1d6102ee00bfa6cf2"
+
+func TestVerifyChecksum(t *testing.T) {
+	testcases := []struct {
+		name      string
+		key       string
+		secret    map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test secret without checksum key configured",
+			secret: map[string]interface{}{"username": "jsmith"},
+		},
+		{
+			name:   "test secret with valid checksum",
+			key:    "_sha256",
+			secret: map[string]interface{}{"username": "jsmith", "password": "secret", "_sha256": testChecksum},
+		},
+		{
+			name:      "test secret without checksum",
+			key:       "_sha256",
+			secret:    map[string]interface{}{"username": "jsmith", "password": "secret"},
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q has no %q key", ErrChecksumMismatch, "users/jsmith", "_sha256"),
+		},
+		{
+			name:      "test secret with non-string checksum",
+			key:       "_sha256",
+			secret:    map[string]interface{}{"username": "jsmith", "_sha256": float64(1)},
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q has non-string %q key", ErrChecksumMismatch, "users/jsmith", "_sha256"),
+		},
+		{
+			name:      "test tampered secret",
+			key:       "_sha256",
+			secret:    map[string]interface{}{"username": "jsmith", "password": "guessed", "_sha256": testChecksum},
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q content does not match %q key", ErrChecksumMismatch, "users/jsmith", "_sha256"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyChecksum(tc.key, "users/jsmith", tc.secret)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, ErrChecksumMismatch) {
+					t.Fatalf("expected ErrChecksumMismatch, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestSecretChecksum(t *testing.T) {
+	m := map[string]interface{}{
+		"b":          "<a&b> \"\\\x01",
+		"a":          json.Number("1.0"),
+		"c":          []interface{}{true, nil, math.Copysign(0, -1), 1e21, 0.5, 1e-7},
+		"\u00e9":     "tab\there",
+		"\U0001F600": "x",
+		"\uFB01":     "y",
+		"n":          5,
+		"_sha256":    "ignored",
+	}
+	b, err := appendCanonicalJSON(nil, withoutKey(m, "_sha256"))
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	want := `{"a":1,"b":"<a&b> \"\\\u0001","c":[true,null,0,1e+21,0.5,1e-7],"n":5,"` + "\u00e9" + `":"tab\there","` + "\U0001F600" + `":"x","` + "\uFB01" + `":"y"}`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Fatalf("canonical encoding mismatch (-want +got):\n%s", diff)
+	}
+	sum, err := SecretChecksum(m, "_sha256")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff("57feb4792c1fb7ca5cd8713d7228128b5bff27ea46128c1ff3a79aa4c5dde4e8", sum); diff != "" {
+		t.Fatalf("SecretChecksum() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := SecretChecksum(map[string]interface{}{"n": math.NaN()}, "_sha256"); err == nil {
+		t.Fatalf("unexpected success for NaN number")
+	}
+}
+
+func TestChecksumWrites(t *testing.T) {
+	store := make(map[string]string)
+	var requests []string
+	c := newWritesTestClient(t, store, &requests, WithChecksumKey("_sha256"))
+	ctx := context.TODO()
+
+	if _, err := c.CreateSecret(ctx, "users/jsmith", map[string]interface{}{"username": "jsmith", "password": "secret"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(`{"_sha256":"`+testChecksum+`","password":"secret","username":"jsmith"}`, store["users/jsmith"]); diff != "" {
+		t.Fatalf("stored secret mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := c.GetSecret(ctx, "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	ch, err := c.PutSecret(ctx, "users/jsmith", map[string]interface{}{"username": "jsmith", "password": "changed"})
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(&Change{Op: "put", Path: "users/jsmith", ModifiedKeys: []string{"password"}}, ch); diff != "" {
+		t.Fatalf("PutSecret() mismatch (-want +got):\n%s", diff)
+	}
+	changes, err := c.Sync(ctx, map[string]map[string]interface{}{
+		"users/jsmith": {"username": "jsmith", "password": "changed"},
+	})
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+
+	store["users/jsmith"] = `{"_sha256":"` + testChecksum + `","password":"changed","username":"jsmith"}`
+	if _, err := c.GetSecret(ctx, "users/jsmith", WithNoCache()); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// testClock is the Clock moving only when advanced.
+type testClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*testTicker
+}
+
+type testTicker struct {
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+	stopped  bool
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ticker := &testTicker{c: make(chan time.Time, 1), interval: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, ticker)
+	return ticker
+}
+
+// Advance moves the clock forward and fires the due tickers. Like the
+// tickers of time package, they drop the ticks for the slow receivers.
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, ticker := range c.tickers {
+		if ticker.stopped || ticker.next.After(c.now) {
+			continue
+		}
+		select {
+		case ticker.c <- c.now:
+		default:
+		}
+		for !ticker.next.After(c.now) {
+			ticker.next = ticker.next.Add(ticker.interval)
+		}
+	}
+}
+
+func (t *testTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *testTicker) Stop() {
+	t.stopped = true
+}
+
+func TestCacheWithClock(t *testing.T) {
+	var requests int
+	clock := newTestClock()
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithCacheTTL(time.Minute),
+		WithClock(clock),
+		WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+		})),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+
+	for _, step := range []struct {
+		advance time.Duration
+		want    int
+	}{
+		{want: 1},
+		{advance: 59 * time.Second, want: 1},
+		{advance: 2 * time.Second, want: 2},
+		{advance: time.Second, want: 2},
+	} {
+		clock.Advance(step.advance)
+		if _, err := c.GetSecret(context.TODO(), "authcrunch/caddy/users/jsmith"); err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		if diff := cmp.Diff(step.want, requests); diff != "" {
+			t.Fatalf("requests after %s mismatch (-want +got):\n%s", step.advance, diff)
+		}
+	}
+}
+
+func TestWatchWithClock(t *testing.T) {
+	v1 := map[string]interface{}{"id": "0"}
+	v2 := map[string]interface{}{"id": "1"}
+	path := "authcrunch/caddy/access_token"
+	clock := newTestClock()
+	c, err := NewClient(context.TODO(),
+		WithConfig(&ClientConfig{ID: "foo", Region: "us-east-1", WatchInterval: "1h"}),
+		WithClock(clock),
+		WithHTTPClient(newSequenceMockClient(t, []map[string]interface{}{v1, v2})),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := c.Watch(ctx, []string{path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff(SecretEvent{Type: SecretAdded, Path: path, Secret: v1}, <-ch); diff != "" {
+		t.Errorf("Watch() first event mismatch (-want +got):\n%s", diff)
+	}
+	clock.Advance(time.Hour)
+	if diff := cmp.Diff(SecretEvent{Type: SecretUpdated, Path: path, Secret: v2}, <-ch); diff != "" {
+		t.Errorf("Watch() second event mismatch (-want +got):\n%s", diff)
+	}
+	cancel()
+	for range ch {
+	}
+}
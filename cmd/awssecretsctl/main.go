@@ -0,0 +1,363 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command awssecretsctl inspects and manages the secrets the way the
+// AuthCrunch plugin sees them, i.e. with the same configuration file,
+// roles, base prefix, and path policy.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	secrets "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const usage = `Usage: awssecretsctl [flags] <command> [args]
+
+Commands:
+  get <path> [key]     print the secret or the value of its key
+  put <path>           store the JSON object read from stdin as the secret
+  create <path>        create the secret with the JSON object read from stdin
+  list [prefix]        list the paths of the secrets
+  describe <path>      print the metadata of the secret
+  export -unsafe [-format dotenv|json] <path>...
+                       print the secrets for local development, only with
+                       non-production credentials
+  render <template>    render the text/template file calling the secret
+                       function, e.g. {{ secret "db/app" "password" }}
+  localdb export <prefix>
+                       print the go-authcrunch local user database of the
+                       user secrets under the prefix
+  localdb import <prefix>
+                       store the users of the local user database read
+                       from stdin as the user secrets under the prefix
+  user-secret          render the user secret, with the password and the
+                       api key read from stdin hashed with bcrypt
+  diagnose             check credentials, connectivity, and IAM access
+  serve [-listen 127.0.0.1:8200] [-allow <patterns>]
+        [-tls-cert <file> -tls-key <file> -client-ca <file>]
+                       serve the secrets to the local processes over HTTP
+                       with shared caching, see the "proxy" provider, or
+                       to the remote ones with mutual TLS
+
+Flags:
+`
+
+// newClient returns the client for the commands. The tests replace it.
+var newClient = func(ctx context.Context, configPath string, opts ...secrets.Option) (secrets.Client, error) {
+	if configPath == "" {
+		return secrets.NewClient(ctx, append([]secrets.Option{secrets.WithID("awssecretsctl")}, opts...)...)
+	}
+	return secrets.NewClientFromFile(ctx, configPath, opts...)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("awssecretsctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", os.Getenv("AWSSECRETSCTL_CONFIG"), "path to client configuration file")
+	region := fs.String("region", "", "AWS region, overrides the configuration")
+	roleARN := fs.String("role-arn", "", "ARN of IAM role to assume, overrides the configuration")
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+
+	if cmd == "user-secret" {
+		return report(stderr, renderUserSecret(cmdArgs, stdin, stdout, stderr))
+	}
+
+	var opts []secrets.Option
+	if *region != "" {
+		opts = append(opts, secrets.WithRegion(*region))
+	}
+	if *roleARN != "" {
+		opts = append(opts, secrets.WithRoleARN(*roleARN))
+	}
+	if *dryRun {
+		opts = append(opts, secrets.WithDryRun())
+	}
+	c, err := newClient(ctx, *configPath, opts...)
+	if err != nil {
+		return report(stderr, err)
+	}
+	defer c.Close()
+
+	switch {
+	case cmd == "get" && (len(cmdArgs) == 1 || len(cmdArgs) == 2):
+		m, err := c.GetSecret(ctx, cmdArgs[0])
+		if err != nil {
+			return report(stderr, err)
+		}
+		if len(cmdArgs) == 1 {
+			return report(stderr, writeJSON(stdout, m))
+		}
+		v, exists := m[cmdArgs[1]]
+		if !exists {
+			return report(stderr, fmt.Errorf("key %q not found in %q secret", cmdArgs[1], cmdArgs[0]))
+		}
+		if s, ok := v.(string); ok {
+			fmt.Fprintln(stdout, s)
+			return exitOK
+		}
+		return report(stderr, writeJSON(stdout, v))
+	case (cmd == "put" || cmd == "create") && len(cmdArgs) == 1:
+		m := make(map[string]interface{})
+		if err := json.NewDecoder(stdin).Decode(&m); err != nil {
+			return report(stderr, fmt.Errorf("malformed secret on stdin: %v", err))
+		}
+		var ch *secrets.Change
+		if cmd == "put" {
+			ch, err = c.PutSecret(ctx, cmdArgs[0], m)
+		} else {
+			ch, err = c.CreateSecret(ctx, cmdArgs[0], m)
+		}
+		if err != nil {
+			return report(stderr, err)
+		}
+		return report(stderr, writeJSON(stdout, ch))
+	case cmd == "list" && len(cmdArgs) <= 1:
+		var prefix string
+		if len(cmdArgs) == 1 {
+			prefix = cmdArgs[0]
+		}
+		paths, err := c.ListSecrets(ctx, prefix)
+		if err != nil {
+			return report(stderr, err)
+		}
+		for _, path := range paths {
+			fmt.Fprintln(stdout, path)
+		}
+		return exitOK
+	case cmd == "describe" && len(cmdArgs) == 1:
+		m, err := c.DescribeSecret(ctx, cmdArgs[0])
+		if err != nil {
+			return report(stderr, err)
+		}
+		return report(stderr, writeJSON(stdout, m))
+	case cmd == "render" && len(cmdArgs) == 1:
+		b, err := ioutil.ReadFile(cmdArgs[0])
+		if err != nil {
+			return report(stderr, err)
+		}
+		return report(stderr, c.RenderTemplate(ctx, string(b), stdout))
+	case cmd == "localdb" && len(cmdArgs) == 2 && cmdArgs[0] == "export":
+		db, err := secrets.ExportLocalUserDatabase(ctx, c, cmdArgs[1])
+		if err != nil {
+			return report(stderr, err)
+		}
+		return report(stderr, writeJSON(stdout, db))
+	case cmd == "localdb" && len(cmdArgs) == 2 && cmdArgs[0] == "import":
+		db := &secrets.LocalUserDatabase{}
+		if err := json.NewDecoder(stdin).Decode(db); err != nil {
+			return report(stderr, fmt.Errorf("malformed local user database on stdin: %v", err))
+		}
+		changes, err := secrets.ImportLocalUserDatabase(ctx, c, cmdArgs[1], db)
+		if err != nil {
+			return report(stderr, err)
+		}
+		return report(stderr, writeJSON(stdout, changes))
+	case cmd == "export":
+		return report(stderr, exportSecrets(ctx, c, cmdArgs, stdout, stderr))
+	case cmd == "serve":
+		return report(stderr, serve(ctx, c, cmdArgs, stderr))
+	case cmd == "diagnose" && len(cmdArgs) == 0:
+		r := c.Diagnose(ctx)
+		if err := writeJSON(stdout, r); err != nil {
+			return report(stderr, err)
+		}
+		if !r.OK() {
+			return exitError
+		}
+		return exitOK
+	}
+	fs.Usage()
+	return exitUsage
+}
+
+// exportSecrets prints the secrets in dotenv or JSON format. It refuses to
+// do so without -unsafe flag.
+func exportSecrets(ctx context.Context, c secrets.Client, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", secrets.ExportFormatDotenv, "output format, either dotenv or json")
+	unsafe := fs.Bool("unsafe", false, "acknowledge that the secrets are printed in plain text")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("no secret paths to export")
+	}
+	if !*unsafe {
+		return errors.New("exporting secrets in plain text is unsafe, acknowledge with -unsafe")
+	}
+	return c.ExportEnv(ctx, fs.Args(), stdout, *format, secrets.WithUnsafeExport())
+}
+
+// serve runs the caching proxy until the context is done. Without mutual
+// TLS, it refuses to listen on the addresses other than the loopback ones,
+// because the proxy has no other authentication.
+func serve(ctx context.Context, c secrets.Client, args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", "127.0.0.1:8200", "address to listen on, loopback one without mutual TLS")
+	allow := fs.String("allow", "", "comma-separated path patterns of the secrets served")
+	certFile := fs.String("tls-cert", "", "path to PEM-encoded server certificate")
+	keyFile := fs.String("tls-key", "", "path to PEM-encoded server key")
+	clientCAFile := fs.String("client-ca", "", "path to PEM-encoded certificate authority of clients")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected %q arguments", fs.Args())
+	}
+	mutualTLS := *certFile != "" || *keyFile != "" || *clientCAFile != ""
+	if mutualTLS && (*certFile == "" || *keyFile == "" || *clientCAFile == "") {
+		return errors.New("mutual tls requires -tls-cert, -tls-key, and -client-ca")
+	}
+	host, _, err := net.SplitHostPort(*listen)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); !mutualTLS && host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("refusing to listen on %q, non-loopback address without mutual tls", *listen)
+	}
+	var opts []secrets.ProxyOption
+	if *allow != "" {
+		opts = append(opts, secrets.WithProxyPathPolicy(&secrets.PathPolicyConfig{Allow: strings.Split(*allow, ",")}))
+	}
+	srv := &http.Server{
+		Handler:           secrets.NewProxyHandler(c, opts...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if mutualTLS {
+		if srv.TLSConfig, err = secrets.NewProxyTLSConfig(*certFile, *keyFile, *clientCAFile); err != nil {
+			return err
+		}
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	fmt.Fprintf(stderr, "awssecretsctl: serving on %s\n", ln.Addr())
+	if mutualTLS {
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		err = srv.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// renderUserSecret prints the user secret in the format expected by the
+// plugin. The first line of stdin is the password, and the optional second
+// one is the api key.
+func renderUserSecret(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("user-secret", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	username := fs.String("username", "", "username")
+	email := fs.String("email", "", "email address")
+	name := fs.String("name", "", "full name")
+	cost := fs.Int("cost", bcrypt.DefaultCost, "bcrypt cost")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("username is empty")
+	}
+	scanner := bufio.NewScanner(stdin)
+	var lines []string
+	for len(lines) < 2 && scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(lines) == 0 || lines[0] == "" {
+		return errors.New("password not found on stdin")
+	}
+	m := map[string]interface{}{"username": *username}
+	for i, k := range []string{"password", "api_key"} {
+		if i >= len(lines) || lines[i] == "" {
+			continue
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(lines[i]), *cost)
+		if err != nil {
+			return err
+		}
+		m[k] = fmt.Sprintf("bcrypt:%d:%s", *cost, hash)
+	}
+	if *email != "" {
+		m["email"] = *email
+	}
+	if *name != "" {
+		m["name"] = *name
+	}
+	return writeJSON(stdout, m)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func report(stderr io.Writer, err error) int {
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "awssecretsctl: %v\n", err)
+	return exitError
+}
@@ -0,0 +1,201 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	secrets "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newMockClient(t *testing.T) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		var response interface{}
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "secretsmanager.GetSecretValue":
+			b, _ := json.Marshal(map[string]interface{}{"username": "jsmith", "email": "jsmith@localhost"})
+			response = map[string]interface{}{"SecretString": string(b)}
+		case "secretsmanager.ListSecrets":
+			response = map[string]interface{}{
+				"SecretList": []map[string]interface{}{
+					{"Name": "authcrunch/users/jsmith"},
+					{"Name": "authcrunch/access_token"},
+				},
+			}
+		default:
+			err := fmt.Errorf("unexpected %q target", target)
+			t.Error(err)
+			return nil, err
+		}
+		b, err := json.Marshal(response)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewReader(b)),
+		}, nil
+	})
+}
+
+func TestRun(t *testing.T) {
+	orig := newClient
+	defer func() { newClient = orig }()
+	newClient = func(ctx context.Context, _ string, opts ...secrets.Option) (secrets.Client, error) {
+		return secrets.NewClient(ctx, append([]secrets.Option{
+			secrets.WithID("awssecretsctl"),
+			secrets.WithRegion("us-east-1"),
+			secrets.WithHTTPClient(newMockClient(t)),
+			secrets.WithCredentialsProvider(secrets.MockCredentialsProvider{}),
+		}, opts...)...)
+	}
+
+	testcases := []struct {
+		name       string
+		args       []string
+		stdin      string
+		want       string
+		wantStderr string
+		wantCode   int
+	}{
+		{
+			name:     "test get secret",
+			args:     []string{"get", "authcrunch/users/jsmith"},
+			want:     "{\n  \"email\": \"jsmith@localhost\",\n  \"username\": \"jsmith\"\n}\n",
+			wantCode: exitOK,
+		},
+		{
+			name:     "test get secret key",
+			args:     []string{"get", "authcrunch/users/jsmith", "email"},
+			want:     "jsmith@localhost\n",
+			wantCode: exitOK,
+		},
+		{
+			name:       "test get missing secret key",
+			args:       []string{"get", "authcrunch/users/jsmith", "password"},
+			wantStderr: "awssecretsctl: key \"password\" not found in \"authcrunch/users/jsmith\" secret\n",
+			wantCode:   exitError,
+		},
+		{
+			name:     "test list secrets",
+			args:     []string{"list", "authcrunch/"},
+			want:     "authcrunch/access_token\nauthcrunch/users/jsmith\n",
+			wantCode: exitOK,
+		},
+		{
+			name:     "test put secret in dry-run mode",
+			args:     []string{"-dry-run", "put", "authcrunch/users/jsmith"},
+			stdin:    `{"username": "jsmith", "name": "John Smith"}`,
+			want:     "{\n  \"op\": \"put\",\n  \"path\": \"authcrunch/users/jsmith\",\n  \"added_keys\": [\n    \"name\"\n  ],\n  \"removed_keys\": [\n    \"email\"\n  ],\n  \"dry_run\": true\n}\n",
+			wantCode: exitOK,
+		},
+		{
+			name:       "test put malformed secret",
+			args:       []string{"put", "authcrunch/users/jsmith"},
+			stdin:      `[]`,
+			wantStderr: "awssecretsctl: malformed secret on stdin: json: cannot unmarshal array into Go value of type map[string]interface {}\n",
+			wantCode:   exitError,
+		},
+		{
+			name:     "test export secret",
+			args:     []string{"export", "-unsafe", "authcrunch/users/jsmith"},
+			want:     "AUTHCRUNCH_USERS_JSMITH_EMAIL=\"jsmith@localhost\"\nAUTHCRUNCH_USERS_JSMITH_USERNAME=\"jsmith\"\n",
+			wantCode: exitOK,
+		},
+		{
+			name:       "test export secret without acknowledgment",
+			args:       []string{"export", "-format", "json", "authcrunch/users/jsmith"},
+			wantStderr: "awssecretsctl: exporting secrets in plain text is unsafe, acknowledge with -unsafe\n",
+			wantCode:   exitError,
+		},
+		{
+			name:       "test serve on non-loopback address",
+			args:       []string{"serve", "-listen", "0.0.0.0:8200"},
+			wantStderr: "awssecretsctl: refusing to listen on \"0.0.0.0:8200\", non-loopback address without mutual tls\n",
+			wantCode:   exitError,
+		},
+		{
+			name:       "test serve with partial mutual tls",
+			args:       []string{"serve", "-tls-cert", "server.pem"},
+			wantStderr: "awssecretsctl: mutual tls requires -tls-cert, -tls-key, and -client-ca\n",
+			wantCode:   exitError,
+		},
+		{
+			name:     "test render template",
+			args:     []string{"render", "testdata/app.conf.tmpl"},
+			want:     "user = jsmith\nemail = jsmith@localhost\n",
+			wantCode: exitOK,
+		},
+		{
+			name:     "test unknown command",
+			args:     []string{"foo"},
+			wantCode: exitUsage,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(context.TODO(), tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			if diff := cmp.Diff(tc.wantCode, code); diff != "" {
+				t.Fatalf("exit code mismatch (-want +got):\n%s\nstderr: %s", diff, stderr.String())
+			}
+			if diff := cmp.Diff(tc.want, stdout.String()); diff != "" {
+				t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+			}
+			if tc.wantCode == exitUsage {
+				return
+			}
+			if diff := cmp.Diff(tc.wantStderr, stderr.String()); diff != "" {
+				t.Errorf("stderr mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderUserSecret(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"user-secret", "-username", "jsmith", "-email", "jsmith@localhost", "-cost", "4"}
+	if code := run(context.TODO(), args, strings.NewReader("foobar\nbarfoo\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("unexpected %d exit code, stderr: %s", code, stderr.String())
+	}
+	m := make(map[string]string)
+	if err := json.Unmarshal(stdout.Bytes(), &m); err != nil {
+		t.Fatalf("failed parsing output: %v", err)
+	}
+	for k, password := range map[string]string{"password": "foobar", "api_key": "barfoo"} {
+		if !strings.HasPrefix(m[k], "bcrypt:4:") {
+			t.Fatalf("key %q is not in bcrypt format: %q", k, m[k])
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(strings.TrimPrefix(m[k], "bcrypt:4:")), []byte(password)); err != nil {
+			t.Fatalf("key %q does not match: %v", k, err)
+		}
+		delete(m, k)
+	}
+	want := map[string]string{"username": "jsmith", "email": "jsmith@localhost"}
+	if diff := cmp.Diff(want, m); diff != "" {
+		t.Errorf("user secret mismatch (-want +got):\n%s", diff)
+	}
+}
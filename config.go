@@ -0,0 +1,507 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultProvider = "aws_secrets_manager"
+)
+
+var (
+	roleARNRgx *regexp.Regexp = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+)
+
+// ClientConfig is the configuration of AWS Secrets Manager client.
+type ClientConfig struct {
+	// Version is the version of the configuration schema. The unmarshaled
+	// configuration is always of the current version.
+	Version  int    `json:"version,omitempty" xml:"version,omitempty" yaml:"version,omitempty"`
+	ID       string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Region   string `json:"region,omitempty" xml:"region,omitempty" yaml:"region,omitempty"`
+	Provider string `json:"provider,omitempty" xml:"provider,omitempty" yaml:"provider,omitempty"`
+	// FilePath is the directory of JSON files or the YAML or JSON document
+	// holding the secrets of the "file" provider, meant for the local
+	// development without AWS.
+	FilePath string `json:"file_path,omitempty" xml:"file_path,omitempty" yaml:"file_path,omitempty"`
+	// ProxyURL is the base URL of the caching proxy serving the secrets of
+	// the "proxy" provider, e.g. "http://127.0.0.1:8200", or the target of
+	// the gRPC server of the "grpc" provider, e.g. "127.0.0.1:8201".
+	ProxyURL string `json:"proxy_url,omitempty" xml:"proxy_url,omitempty" yaml:"proxy_url,omitempty"`
+	// ProxyTLS is the client certificate of the "proxy" and "grpc"
+	// providers, when the server requires mutual TLS. The "grpc" provider
+	// always requires it.
+	ProxyTLS *ProxyTLSConfig `json:"proxy_tls,omitempty" xml:"proxy_tls,omitempty" yaml:"proxy_tls,omitempty"`
+	// BackendConfig is the configuration of the backend of a provider
+	// registered with RegisterBackend.
+	BackendConfig map[string]string `json:"backend_config,omitempty" xml:"backend_config,omitempty" yaml:"backend_config,omitempty"`
+	// FieldAliases maps the keys found in secrets to the keys expected
+	// by the consumers, e.g. "user" to "username".
+	FieldAliases map[string]string `json:"field_aliases,omitempty" xml:"field_aliases,omitempty" yaml:"field_aliases,omitempty"`
+	// KeyNormalization lists the normalizations of the keys of the
+	// fetched secrets, i.e. "trim", "lowercase", and "snake_case". The
+	// keys are normalized before the field aliases apply.
+	KeyNormalization []string `json:"key_normalization,omitempty" xml:"key_normalization,omitempty" yaml:"key_normalization,omitempty"`
+	// Schemas are validated against the secrets matching their paths.
+	Schemas []*SecretSchema `json:"schemas,omitempty" xml:"schemas,omitempty" yaml:"schemas,omitempty"`
+	// PasswordPolicy is either "warn" or "reject". It controls what happens
+	// when a secret has the password or api_key not in bcrypt format.
+	PasswordPolicy string `json:"password_policy,omitempty" xml:"password_policy,omitempty" yaml:"password_policy,omitempty"`
+	// Realms map authentication realms to their path prefixes.
+	Realms []*RealmConfig `json:"realms,omitempty" xml:"realms,omitempty" yaml:"realms,omitempty"`
+	// WatchInterval is the polling interval of watched secrets, e.g. "30s".
+	WatchInterval string `json:"watch_interval,omitempty" xml:"watch_interval,omitempty" yaml:"watch_interval,omitempty"`
+	// CacheTTL is the period of time the retrieved secrets are cached for,
+	// e.g. "5m". The caching is disabled when it is empty.
+	CacheTTL string `json:"cache_ttl,omitempty" xml:"cache_ttl,omitempty" yaml:"cache_ttl,omitempty"`
+	// DefaultTimeout bounds the calls of the SimpleClient, which take no
+	// context, e.g. "10s". When empty, it is thirty seconds.
+	DefaultTimeout string `json:"default_timeout,omitempty" xml:"default_timeout,omitempty" yaml:"default_timeout,omitempty"`
+	// Endpoint is the URL of AWS Secrets Manager endpoint. It overrides
+	// the endpoint resolved for the region.
+	Endpoint string `json:"endpoint,omitempty" xml:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// RoleARN is the ARN of IAM role assumed to access the secrets.
+	RoleARN string `json:"role_arn,omitempty" xml:"role_arn,omitempty" yaml:"role_arn,omitempty"`
+	// MaxRetries is the maximum number of retries of failed requests. The
+	// SDK default applies when it is zero.
+	MaxRetries int `json:"max_retries,omitempty" xml:"max_retries,omitempty" yaml:"max_retries,omitempty"`
+	// BasePrefix is prepended to the paths of all secrets, e.g.
+	// "authcrunch/prod/". The paths in the other settings, e.g. schemas
+	// and realms, and in the secret events are relative to it.
+	BasePrefix string `json:"base_prefix,omitempty" xml:"base_prefix,omitempty" yaml:"base_prefix,omitempty"`
+	// Routes send the requests for the secrets with matching path prefixes
+	// to other regions or endpoints. The longest matching prefix wins.
+	Routes []*RouteConfig `json:"routes,omitempty" xml:"routes,omitempty" yaml:"routes,omitempty"`
+	// VersionPins pin the secrets at the paths to the versions, while the
+	// other secrets track AWSCURRENT version.
+	VersionPins []*VersionPin `json:"version_pins,omitempty" xml:"version_pins,omitempty" yaml:"version_pins,omitempty"`
+	// ReadOnly makes the methods mutating secrets return ErrReadOnly.
+	ReadOnly bool `json:"read_only,omitempty" xml:"read_only,omitempty" yaml:"read_only,omitempty"`
+	// DryRun makes the methods mutating secrets log and return the changes
+	// without applying them.
+	DryRun bool `json:"dry_run,omitempty" xml:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+	// PathPolicy restricts the paths of the secrets the client may access.
+	PathPolicy *PathPolicyConfig `json:"path_policy,omitempty" xml:"path_policy,omitempty" yaml:"path_policy,omitempty"`
+	// TagPolicy refuses to return the secrets carrying the denied tags.
+	TagPolicy *TagPolicyConfig `json:"tag_policy,omitempty" xml:"tag_policy,omitempty" yaml:"tag_policy,omitempty"`
+	// RequiredKMSKeyID is the ARN, alias or ID of the KMS key the fetched
+	// secrets must be encrypted with, e.g. "alias/authcrunch".
+	RequiredKMSKeyID string `json:"required_kms_key_id,omitempty" xml:"required_kms_key_id,omitempty" yaml:"required_kms_key_id,omitempty"`
+	// ChecksumKey is the key holding the SHA-256 checksum of the content of
+	// the secrets, e.g. "_sha256". The client verifies it on fetch and sets
+	// it on write. See SecretChecksum.
+	ChecksumKey string `json:"checksum_key,omitempty" xml:"checksum_key,omitempty" yaml:"checksum_key,omitempty"`
+	// TLS hardens the TLS connections of the HTTP client, including the
+	// one set with WithHTTPClient. The client fails to initialize when the
+	// HTTP client is neither AWS SDK nor *http.Client with *http.Transport.
+	TLS *TLSConfig `json:"tls,omitempty" xml:"tls,omitempty" yaml:"tls,omitempty"`
+	// Limits reject the fetched secrets exceeding the size, the number of
+	// keys, or the nesting depth.
+	Limits *SecretLimitsConfig `json:"limits,omitempty" xml:"limits,omitempty" yaml:"limits,omitempty"`
+	// Staleness warns about or refuses the secrets not rotated within the
+	// maximum age.
+	Staleness *StalenessConfig `json:"staleness,omitempty" xml:"staleness,omitempty" yaml:"staleness,omitempty"`
+	// SSMFallback looks up the secrets not found in AWS Secrets Manager in
+	// AWS Systems Manager Parameter Store.
+	SSMFallback *SSMFallbackConfig `json:"ssm_fallback,omitempty" xml:"ssm_fallback,omitempty" yaml:"ssm_fallback,omitempty"`
+	// S3Overflow follows the secrets pointing to the objects in Amazon S3
+	// holding their content.
+	S3Overflow *S3OverflowConfig `json:"s3_overflow,omitempty" xml:"s3_overflow,omitempty" yaml:"s3_overflow,omitempty"`
+	// DynamoDBCache shares the retrieved secrets across the instances of
+	// a fleet through DynamoDB table.
+	DynamoDBCache *DynamoDBCacheConfig `json:"dynamodb_cache,omitempty" xml:"dynamodb_cache,omitempty" yaml:"dynamodb_cache,omitempty"`
+	// Webhook is notified about the updated and the deleted secrets
+	// detected by Watch, ListenSQS, or SNSHandler.
+	Webhook *WebhookConfig `json:"webhook,omitempty" xml:"webhook,omitempty" yaml:"webhook,omitempty"`
+	// RotationCache shortens or disables the caching of the secrets with
+	// a rotation in progress.
+	RotationCache *RotationCacheConfig `json:"rotation_cache,omitempty" xml:"rotation_cache,omitempty" yaml:"rotation_cache,omitempty"`
+	// FallbackRegions are tried in order, when the endpoint of the preferred
+	// region cannot be resolved or does not respond at construction time.
+	FallbackRegions []string `json:"fallback_regions,omitempty" xml:"fallback_regions,omitempty" yaml:"fallback_regions,omitempty"`
+	// ProbePath is the path of the secret used by Diagnose and the health
+	// checks of the fallback regions.
+	ProbePath string `json:"probe_path,omitempty" xml:"probe_path,omitempty" yaml:"probe_path,omitempty"`
+	// Deprecations are the warnings about the deprecated keys found in the
+	// unmarshaled configuration. NewClient logs them.
+	Deprecations []string `json:"-" xml:"-" yaml:"-"`
+}
+
+// UnmarshalJSON unpacks and validates JSON-encoded configuration. The
+// configuration of an older version is migrated to the current one, and
+// the references to environment variables, e.g. ${AWS_REGION}, are
+// expanded.
+func (cfg *ClientConfig) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("malformed config: %v", err)
+	}
+	var version int
+	if v, exists := m["version"]; exists {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return fmt.Errorf("malformed config: %v", err)
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	names, deprecations, err := migrateConfigKeys(version, keys)
+	if err != nil {
+		return err
+	}
+	migrated := make(map[string]json.RawMessage, len(m))
+	for k, v := range m {
+		var nested interface{}
+		if err := json.Unmarshal(v, &nested); err != nil {
+			return fmt.Errorf("malformed config: %v", err)
+		}
+		if err := checkConfigKeys("json", configType, "", names[k], nested); err != nil {
+			return err
+		}
+		migrated[names[k]] = v
+	}
+	if data, err = json.Marshal(migrated); err != nil {
+		return fmt.Errorf("malformed config: %v", err)
+	}
+	type alias ClientConfig
+	var a alias
+	if err := json.Unmarshal(data, &a); err != nil {
+		return fmt.Errorf("malformed config: %v", err)
+	}
+	*cfg = ClientConfig(a)
+	cfg.Version = CurrentConfigVersion()
+	cfg.Deprecations = deprecations
+	if err := cfg.expandEnv(); err != nil {
+		return err
+	}
+	cfg.setDefaults()
+	return cfg.Validate()
+}
+
+// UnmarshalYAML unpacks and validates YAML-encoded configuration. The
+// configuration of an older version is migrated to the current one, and
+// the references to environment variables, e.g. ${AWS_REGION}, are
+// expanded.
+func (cfg *ClientConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("malformed config: expected mapping, got %q", value.Tag)
+	}
+	var version int
+	keys := make([]string, 0, len(value.Content)/2)
+	for i := 0; i < len(value.Content); i += 2 {
+		k := value.Content[i].Value
+		if k == "version" && i+1 < len(value.Content) {
+			if err := value.Content[i+1].Decode(&version); err != nil {
+				return fmt.Errorf("malformed config: %v", err)
+			}
+		}
+		keys = append(keys, k)
+	}
+	names, deprecations, err := migrateConfigKeys(version, keys)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(value.Content); i += 2 {
+		k := value.Content[i]
+		var nested interface{}
+		if i+1 < len(value.Content) {
+			if err := value.Content[i+1].Decode(&nested); err != nil {
+				return fmt.Errorf("malformed config: %v", err)
+			}
+		}
+		if err := checkConfigKeys("yaml", configType, "", names[k.Value], nested); err != nil {
+			return err
+		}
+		k.Value = names[k.Value]
+	}
+	type alias ClientConfig
+	var a alias
+	if err := value.Decode(&a); err != nil {
+		return fmt.Errorf("malformed config: %v", err)
+	}
+	*cfg = ClientConfig(a)
+	cfg.Version = CurrentConfigVersion()
+	cfg.Deprecations = deprecations
+	if err := cfg.expandEnv(); err != nil {
+		return err
+	}
+	cfg.setDefaults()
+	return cfg.Validate()
+}
+
+// Validate validates the configuration.
+func (cfg *ClientConfig) Validate() error {
+	if cfg.Version < 0 || cfg.Version > CurrentConfigVersion() {
+		return fmt.Errorf("unsupported config version %d, the current version is %d", cfg.Version, CurrentConfigVersion())
+	}
+	if cfg.ID == "" {
+		return errors.New("client id is empty")
+	}
+	if _, err := lookupBackend(cfg.Provider); err != nil {
+		return err
+	}
+	if cfg.Region != "" {
+		if !awsRegionRgx.MatchString(cfg.Region) {
+			return fmt.Errorf("malformed %q region", cfg.Region)
+		}
+	}
+	for alias, field := range cfg.FieldAliases {
+		if alias == "" || field == "" {
+			return fmt.Errorf("malformed %q field alias for %q", alias, field)
+		}
+		if alias == field {
+			return fmt.Errorf("field alias %q points to itself", alias)
+		}
+	}
+	if err := validateKeyNormalization(cfg.KeyNormalization); err != nil {
+		return err
+	}
+	for i, schema := range cfg.Schemas {
+		if schema == nil {
+			return fmt.Errorf("schema %d is empty", i)
+		}
+		if err := schema.validate(); err != nil {
+			return err
+		}
+	}
+	switch cfg.PasswordPolicy {
+	case "", passwordPolicyWarn, passwordPolicyReject:
+	default:
+		return fmt.Errorf("unsupported %q password policy", cfg.PasswordPolicy)
+	}
+	realms := make(map[string]bool)
+	for i, realm := range cfg.Realms {
+		if realm == nil {
+			return fmt.Errorf("realm %d is empty", i)
+		}
+		if err := realm.validate(); err != nil {
+			return err
+		}
+		if realms[realm.Name] {
+			return fmt.Errorf("duplicate %q realm", realm.Name)
+		}
+		realms[realm.Name] = true
+	}
+	if cfg.WatchInterval != "" {
+		if d, err := time.ParseDuration(cfg.WatchInterval); err != nil || d <= 0 {
+			return fmt.Errorf("malformed %q watch interval", cfg.WatchInterval)
+		}
+	}
+	if cfg.CacheTTL != "" {
+		if d, err := time.ParseDuration(cfg.CacheTTL); err != nil || d <= 0 {
+			return fmt.Errorf("malformed %q cache ttl", cfg.CacheTTL)
+		}
+	}
+	if cfg.DefaultTimeout != "" {
+		if d, err := time.ParseDuration(cfg.DefaultTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("malformed %q default timeout", cfg.DefaultTimeout)
+		}
+	}
+	if cfg.Endpoint != "" {
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("malformed %q endpoint", cfg.Endpoint)
+		}
+	}
+	if cfg.RoleARN != "" && !roleARNRgx.MatchString(cfg.RoleARN) {
+		return fmt.Errorf("malformed %q role arn", cfg.RoleARN)
+	}
+	if cfg.MaxRetries < 0 {
+		return fmt.Errorf("malformed %d max retries", cfg.MaxRetries)
+	}
+	if cfg.BasePrefix != "" {
+		if strings.HasPrefix(cfg.BasePrefix, "/") || strings.HasPrefix(cfg.BasePrefix, "arn:") || strings.Trim(cfg.BasePrefix, "/") == "" {
+			return fmt.Errorf("malformed %q base prefix", cfg.BasePrefix)
+		}
+	}
+	if cfg.PathPolicy != nil {
+		if err := cfg.PathPolicy.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.TagPolicy != nil {
+		if err := cfg.TagPolicy.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.SSMFallback != nil {
+		if err := cfg.SSMFallback.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.S3Overflow != nil {
+		if err := cfg.S3Overflow.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.DynamoDBCache != nil {
+		if err := cfg.DynamoDBCache.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.Webhook != nil {
+		if err := cfg.Webhook.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.RotationCache != nil {
+		if err := cfg.RotationCache.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.Staleness != nil {
+		if err := cfg.Staleness.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.Limits != nil {
+		if err := cfg.Limits.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.TLS != nil {
+		if err := cfg.TLS.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.RequiredKMSKeyID != "" {
+		if err := validateKMSKeyID(cfg.RequiredKMSKeyID); err != nil {
+			return err
+		}
+	}
+	for _, region := range cfg.FallbackRegions {
+		if !awsRegionRgx.MatchString(region) {
+			return fmt.Errorf("malformed %q fallback region", region)
+		}
+	}
+	routes := make(map[string]bool)
+	for i, route := range cfg.Routes {
+		if route == nil {
+			return fmt.Errorf("route %d is empty", i)
+		}
+		if err := route.validate(); err != nil {
+			return err
+		}
+		if routes[route.PathPrefix] {
+			return fmt.Errorf("duplicate route for %q path prefix", route.PathPrefix)
+		}
+		routes[route.PathPrefix] = true
+	}
+	pins := make(map[string]bool)
+	for i, pin := range cfg.VersionPins {
+		if pin == nil {
+			return fmt.Errorf("version pin %d is empty", i)
+		}
+		if err := pin.validate(); err != nil {
+			return err
+		}
+		if pins[pin.Path] {
+			return fmt.Errorf("duplicate version pin for %q path", pin.Path)
+		}
+		pins[pin.Path] = true
+	}
+	return nil
+}
+
+// newCache returns the cache configured with the TTL, or nil when the
+// caching is disabled.
+func (cfg *ClientConfig) newCache(clock Clock) *secretCache {
+	if cfg.CacheTTL == "" {
+		return nil
+	}
+	d, _ := time.ParseDuration(cfg.CacheTTL)
+	return newSecretCache(d, clock)
+}
+
+func (cfg *ClientConfig) setDefaults() {
+	if cfg.Provider == "" {
+		cfg.Provider = defaultProvider
+	}
+}
+
+// configType is the type of the configuration checked by checkConfigKeys.
+var configType = reflect.TypeOf(ClientConfig{})
+
+// checkConfigKeys returns an error when the key of the object at the
+// path, or any of the keys nested in its value, does not match the fields
+// of the struct type for the provided encoding. The nested keys are
+// reported with their dotted paths, e.g. "tls.min_version", and the list
+// items with their indexes.
+func checkConfigKeys(encoding string, t reflect.Type, path, key string, value interface{}) error {
+	if path != "" {
+		path += "."
+	}
+	path += key
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get(encoding), ",")[0]
+		if name == key && name != "-" {
+			return checkNestedConfigKeys(encoding, t.Field(i).Type, path, value)
+		}
+	}
+	return fmt.Errorf("unsupported %q config key", path)
+}
+
+// checkNestedConfigKeys checks the keys of the value at the path against
+// the type. The mismatched value types are reported by the decoding.
+func checkNestedConfigKeys(encoding string, t reflect.Type, path string, value interface{}) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for k, v := range m {
+			if err := checkConfigKeys(encoding, t, path, k, v); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for k, v := range m {
+			if err := checkNestedConfigKeys(encoding, t.Elem(), path+"."+k, v); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, v := range items {
+			if err := checkNestedConfigKeys(encoding, t.Elem(), fmt.Sprintf("%s[%d]", path, i), v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
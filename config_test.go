@@ -0,0 +1,241 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestUnmarshalConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		encoding  string
+		data      string
+		want      *ClientConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "test valid json config",
+			encoding: "json",
+			data:     `{"id": "foo", "region": "us-east-1", "provider": "aws_secrets_manager"}`,
+			want: &ClientConfig{
+				Version:  1,
+				ID:       "foo",
+				Region:   "us-east-1",
+				Provider: "aws_secrets_manager",
+			},
+		},
+		{
+			name:     "test valid json config with default provider",
+			encoding: "json",
+			data:     `{"id": "foo"}`,
+			want: &ClientConfig{
+				Version:  1,
+				ID:       "foo",
+				Provider: "aws_secrets_manager",
+			},
+		},
+		{
+			name:     "test valid yaml config",
+			encoding: "yaml",
+			data:     "id: foo\nregion: us-east-1\n",
+			want: &ClientConfig{
+				Version:  1,
+				ID:       "foo",
+				Region:   "us-east-1",
+				Provider: "aws_secrets_manager",
+			},
+		},
+		{
+			name:     "test valid yaml config with field aliases",
+			encoding: "yaml",
+			data:     "id: foo\nfield_aliases:\n  user: username\n  pass: password\n",
+			want: &ClientConfig{
+				Version:  1,
+				ID:       "foo",
+				Provider: "aws_secrets_manager",
+				FieldAliases: map[string]string{
+					"user": "username",
+					"pass": "password",
+				},
+			},
+		},
+		{
+			name:      "test json config with unknown key",
+			encoding:  "json",
+			data:      `{"id": "foo", "regoin": "us-east-1"}`,
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q config key", "regoin"),
+		},
+		{
+			name:      "test yaml config with unknown key",
+			encoding:  "yaml",
+			data:      "id: foo\nregoin: us-east-1\n",
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q config key", "regoin"),
+		},
+		{
+			name:      "test json config with unknown nested key",
+			encoding:  "json",
+			data:      `{"id": "foo", "tls": {"min_versoin": "1.2"}}`,
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q config key", "tls.min_versoin"),
+		},
+		{
+			name:      "test yaml config with unknown key in list item",
+			encoding:  "yaml",
+			data:      "id: foo\nroutes:\n  - path_prefix: apps/\n    region: us-west-2\n  - path_prefx: users/\n",
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q config key", "routes[1].path_prefx"),
+		},
+		{
+			name:      "test json config with null realm",
+			encoding:  "json",
+			data:      `{"id": "foo", "realms": [{"name": "local", "path_prefix": "local/"}, null]}`,
+			shouldErr: true,
+			err:       errors.New("realm 1 is empty"),
+		},
+		{
+			name:      "test yaml config with null version pin",
+			encoding:  "yaml",
+			data:      "id: foo\nversion_pins:\n  -\n",
+			shouldErr: true,
+			err:       errors.New("version pin 0 is empty"),
+		},
+		{
+			name:      "test json config without id",
+			encoding:  "json",
+			data:      `{"region": "us-east-1"}`,
+			shouldErr: true,
+			err:       errors.New("client id is empty"),
+		},
+		{
+			name:      "test yaml config with malformed region",
+			encoding:  "yaml",
+			data:      "id: foo\nregion: foo-bar-baz\n",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q region", "foo-bar-baz"),
+		},
+		{
+			name:      "test yaml config with malformed fallback region",
+			encoding:  "yaml",
+			data:      "id: foo\nregion: us-east-1\nfallback_regions:\n  - us-west-2\n  - foo\n",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q fallback region", "foo"),
+		},
+		{
+			name:      "test json config with unsupported provider",
+			encoding:  "json",
+			data:      `{"id": "foo", "provider": "vault"}`,
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q provider", "vault"),
+		},
+		{
+			name:     "test valid yaml config with cache ttl",
+			encoding: "yaml",
+			data:     "id: foo\ncache_ttl: 5m\n",
+			want: &ClientConfig{
+				Version:  1,
+				ID:       "foo",
+				Provider: "aws_secrets_manager",
+				CacheTTL: "5m",
+			},
+		},
+		{
+			name:      "test json config with malformed cache ttl",
+			encoding:  "json",
+			data:      `{"id": "foo", "cache_ttl": "-1m"}`,
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q cache ttl", "-1m"),
+		},
+		{
+			name:      "test json config with malformed default timeout",
+			encoding:  "json",
+			data:      `{"id": "foo", "default_timeout": "soon"}`,
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q default timeout", "soon"),
+		},
+		{
+			name:     "test valid json config with endpoint, role, and retries",
+			encoding: "json",
+			data:     `{"id": "foo", "endpoint": "http://localhost:4566", "role_arn": "arn:aws:iam::123456789012:role/authcrunch", "max_retries": 3}`,
+			want: &ClientConfig{
+				Version:    1,
+				ID:         "foo",
+				Provider:   "aws_secrets_manager",
+				Endpoint:   "http://localhost:4566",
+				RoleARN:    "arn:aws:iam::123456789012:role/authcrunch",
+				MaxRetries: 3,
+			},
+		},
+		{
+			name:      "test yaml config with absolute base prefix",
+			encoding:  "yaml",
+			data:      "id: foo\nbase_prefix: /authcrunch/prod/\n",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q base prefix", "/authcrunch/prod/"),
+		},
+		{
+			name:      "test malformed json config",
+			encoding:  "json",
+			data:      `{"id": 1}`,
+			shouldErr: true,
+			err:       errors.New("malformed config: json: cannot unmarshal number into Go struct field alias.id of type string"),
+		},
+		{
+			name:      "test yaml config that is not a mapping",
+			encoding:  "yaml",
+			data:      "- foo\n- bar\n",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed config: expected mapping, got %q", "!!seq"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := &ClientConfig{}
+			var err error
+			switch tc.encoding {
+			case "json":
+				err = json.Unmarshal([]byte(tc.data), got)
+			case "yaml":
+				err = yaml.Unmarshal([]byte(tc.data), got)
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("Unmarshal() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfigFile reads the client configuration from JSON or YAML file.
+// The format is determined by the file extension, i.e. .json, .yaml, or
+// .yml. For the other extensions, the document starting with "{" is
+// treated as JSON and anything else as YAML.
+func LoadConfigFile(path string) (*ClientConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &ClientConfig{}
+	if isJSONConfigFile(path, b) {
+		err = json.Unmarshal(b, cfg)
+	} else {
+		err = yaml.Unmarshal(b, cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed loading %q config file: %v", path, err)
+	}
+	return cfg, nil
+}
+
+func isJSONConfigFile(path string, b []byte) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return true
+	case ".yaml", ".yml":
+		return false
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("{"))
+}
+
+// NewClientFromFile returns an instance of Client configured with the
+// configuration file. The options are applied after the configuration
+// from the file, so they override it.
+func NewClientFromFile(ctx context.Context, path string, opts ...Option) (Client, error) {
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(ctx, append([]Option{WithConfig(cfg)}, opts...)...)
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewClientFromFile(t *testing.T) {
+	testcases := []struct {
+		name      string
+		file      string
+		data      string
+		opts      []Option
+		want      *ClientConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test json config file",
+			file: "config.json",
+			data: `{"id": "foo", "region": "us-east-1", "cache_ttl": "5m", "max_retries": 3}`,
+			want: &ClientConfig{
+				Version:    1,
+				ID:         "foo",
+				Region:     "us-east-1",
+				Provider:   "aws_secrets_manager",
+				CacheTTL:   "5m",
+				MaxRetries: 3,
+			},
+		},
+		{
+			name: "test yaml config file",
+			file: "config.yaml",
+			data: "id: foo\nregion: us-east-1\nrole_arn: arn:aws:iam::123456789012:role/authcrunch\nrealms:\n  - name: local\n    path_prefix: authcrunch/local\n",
+			want: &ClientConfig{
+				Version:  1,
+				ID:       "foo",
+				Region:   "us-east-1",
+				Provider: "aws_secrets_manager",
+				RoleARN:  "arn:aws:iam::123456789012:role/authcrunch",
+				Realms: []*RealmConfig{
+					{Name: "local", PathPrefix: "authcrunch/local"},
+				},
+			},
+		},
+		{
+			name: "test json config file without extension and with options",
+			file: "config",
+			data: `{"id": "foo", "region": "us-east-1"}`,
+			opts: []Option{WithRegion("us-west-2")},
+			want: &ClientConfig{
+				Version:  1,
+				ID:       "foo",
+				Region:   "us-west-2",
+				Provider: "aws_secrets_manager",
+			},
+		},
+		{
+			name:      "test config file with unknown key",
+			file:      "config.yml",
+			data:      "id: foo\nregoin: us-east-1\n",
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q config key", "regoin"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tc.file)
+			if err := ioutil.WriteFile(path, []byte(tc.data), 0600); err != nil {
+				t.Fatalf("failed writing config file: %v", err)
+			}
+			c, err := NewClientFromFile(context.TODO(), path, tc.opts...)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				want := fmt.Errorf("failed loading %q config file: %v", path, tc.err)
+				if diff := cmp.Diff(want.Error(), err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("NewClientFromFile() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, c.(*client).getConfig()); diff != "" {
+				t.Errorf("NewClientFromFile() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
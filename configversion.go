@@ -0,0 +1,73 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"sort"
+)
+
+// configMigration upgrades the configuration to the next version.
+type configMigration struct {
+	// renamedKeys maps the keys deprecated by the next version to their
+	// replacements.
+	renamedKeys map[string]string
+}
+
+// configMigrations are applied in order to the configuration older than
+// the current version. The first one upgrades version 1 to version 2.
+var configMigrations []*configMigration
+
+// CurrentConfigVersion returns the version of the configuration schema.
+// The configuration without the version is treated as version 1.
+func CurrentConfigVersion() int {
+	return len(configMigrations) + 1
+}
+
+// migrateConfigKeys returns the keys of the configuration of the version
+// renamed to the ones of the current version, and the deprecation
+// warnings for the renamed keys.
+func migrateConfigKeys(version int, keys []string) (map[string]string, []string, error) {
+	if version == 0 {
+		version = 1
+	}
+	if version < 0 || version > CurrentConfigVersion() {
+		return nil, nil, fmt.Errorf("unsupported config version %d, the current version is %d", version, CurrentConfigVersion())
+	}
+	sort.Strings(keys)
+	names := make(map[string]string, len(keys))
+	for _, k := range keys {
+		names[k] = k
+	}
+	var warnings []string
+	for i := version - 1; i < len(configMigrations); i++ {
+		for _, k := range keys {
+			newName, exists := configMigrations[i].renamedKeys[names[k]]
+			if !exists {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("config key %q is deprecated since version %d, use %q", names[k], i+2, newName))
+			names[k] = newName
+		}
+	}
+	seen := make(map[string]string, len(names))
+	for _, k := range keys {
+		if other, exists := seen[names[k]]; exists {
+			return nil, nil, fmt.Errorf("config keys %q and %q conflict", other, k)
+		}
+		seen[names[k]] = k
+	}
+	return names, warnings, nil
+}
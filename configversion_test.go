@@ -0,0 +1,231 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gopkg.in/yaml.v3"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// withConfigMigrations replaces the config migrations for the duration of
+// the test.
+func withConfigMigrations(t *testing.T, migrations ...*configMigration) {
+	orig := configMigrations
+	configMigrations = migrations
+	t.Cleanup(func() {
+		configMigrations = orig
+	})
+}
+
+func TestMigrateConfigKeys(t *testing.T) {
+	withConfigMigrations(t,
+		&configMigration{renamedKeys: map[string]string{"ttl": "cache_ttl", "role": "role_arn"}},
+		&configMigration{renamedKeys: map[string]string{"cache_ttl": "cache_duration"}},
+	)
+	testcases := []struct {
+		name         string
+		version      int
+		keys         []string
+		want         map[string]string
+		wantWarnings []string
+		shouldErr    bool
+		err          error
+	}{
+		{
+			name: "test unversioned keys",
+			keys: []string{"id", "role", "ttl"},
+			want: map[string]string{"id": "id", "role": "role_arn", "ttl": "cache_duration"},
+			wantWarnings: []string{
+				`config key "role" is deprecated since version 2, use "role_arn"`,
+				`config key "ttl" is deprecated since version 2, use "cache_ttl"`,
+				`config key "cache_ttl" is deprecated since version 3, use "cache_duration"`,
+			},
+		},
+		{
+			name:    "test version 2 keys",
+			version: 2,
+			keys:    []string{"cache_ttl", "role"},
+			want:    map[string]string{"cache_ttl": "cache_duration", "role": "role"},
+			wantWarnings: []string{
+				`config key "cache_ttl" is deprecated since version 3, use "cache_duration"`,
+			},
+		},
+		{
+			name:    "test current version keys",
+			version: 3,
+			keys:    []string{"cache_duration"},
+			want:    map[string]string{"cache_duration": "cache_duration"},
+		},
+		{
+			name:      "test future version",
+			version:   4,
+			keys:      []string{"id"},
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported config version %d, the current version is %d", 4, 3),
+		},
+		{
+			name:      "test conflicting keys",
+			keys:      []string{"role", "role_arn"},
+			shouldErr: true,
+			err:       fmt.Errorf("config keys %q and %q conflict", "role", "role_arn"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, warnings, err := migrateConfigKeys(tc.version, tc.keys)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("migrateConfigKeys() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantWarnings, warnings); diff != "" {
+				t.Errorf("migrateConfigKeys() warnings mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshalConfigMigration(t *testing.T) {
+	withConfigMigrations(t,
+		&configMigration{renamedKeys: map[string]string{"role": "role_arn", "ttl": "cache_ttl"}},
+	)
+	want := &ClientConfig{
+		Version:  2,
+		ID:       "foo",
+		Provider: "aws_secrets_manager",
+		RoleARN:  "arn:aws:iam::123456789012:role/authcrunch",
+		CacheTTL: "5m",
+		Deprecations: []string{
+			`config key "role" is deprecated since version 2, use "role_arn"`,
+			`config key "ttl" is deprecated since version 2, use "cache_ttl"`,
+		},
+	}
+	testcases := []struct {
+		name      string
+		encoding  string
+		data      string
+		want      *ClientConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "test unversioned json config",
+			encoding: "json",
+			data:     `{"id": "foo", "role": "arn:aws:iam::123456789012:role/authcrunch", "ttl": "5m"}`,
+			want:     want,
+		},
+		{
+			name:     "test version 1 yaml config",
+			encoding: "yaml",
+			data:     "version: 1\nid: foo\nrole: arn:aws:iam::123456789012:role/authcrunch\nttl: 5m\n",
+			want:     want,
+		},
+		{
+			name:     "test current json config",
+			encoding: "json",
+			data:     `{"version": 2, "id": "foo", "cache_ttl": "5m"}`,
+			want: &ClientConfig{
+				Version:  2,
+				ID:       "foo",
+				Provider: "aws_secrets_manager",
+				CacheTTL: "5m",
+			},
+		},
+		{
+			name:      "test current yaml config with deprecated key",
+			encoding:  "yaml",
+			data:      "version: 2\nid: foo\nttl: 5m\n",
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q config key", "ttl"),
+		},
+		{
+			name:      "test future json config",
+			encoding:  "json",
+			data:      `{"version": 3, "id": "foo"}`,
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported config version %d, the current version is %d", 3, 2),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := &ClientConfig{}
+			var err error
+			switch tc.encoding {
+			case "json":
+				err = json.Unmarshal([]byte(tc.data), got)
+			case "yaml":
+				err = yaml.Unmarshal([]byte(tc.data), got)
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewClientLogsDeprecations(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	_, err := NewClient(context.TODO(),
+		WithConfig(&ClientConfig{
+			ID:           "foo",
+			Region:       "us-east-1",
+			Deprecations: []string{`config key "ttl" is deprecated since version 2, use "cache_ttl"`},
+		}),
+		WithLogger(zap.New(core)),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	var got []string
+	for _, entry := range logs.All() {
+		got = append(got, entry.Message+": "+entry.ContextMap()["warning"].(string))
+	}
+	want := []string{`deprecated config: config key "ttl" is deprecated since version 2, use "cache_ttl"`}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("logs mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	dbFamilyPostgres  = "postgres"
+	dbFamilyMySQL     = "mysql"
+	dbFamilySQLServer = "sqlserver"
+	dbFamilyOracle    = "oracle"
+	dbFamilyMongoDB   = "mongodb"
+	dbFamilyRedis     = "redis"
+)
+
+var dbDefaultPorts = map[string]int{
+	dbFamilyPostgres:  5432,
+	dbFamilyMySQL:     3306,
+	dbFamilySQLServer: 1433,
+	dbFamilyOracle:    1521,
+	dbFamilyMongoDB:   27017,
+	dbFamilyRedis:     6379,
+	"redshift":        5439,
+}
+
+// DatabaseCredentials holds the credentials of a database stored in the
+// secret of the schemas used by the rotation functions of Amazon RDS,
+// Redshift, DocumentDB, and ElastiCache.
+type DatabaseCredentials struct {
+	// Engine is the database engine, e.g. postgres, aurora-mysql,
+	// sqlserver-ee, redshift, mongo, or redis.
+	Engine   string `json:"engine,omitempty" xml:"engine,omitempty" yaml:"engine,omitempty"`
+	Host     string `json:"host,omitempty" xml:"host,omitempty" yaml:"host,omitempty"`
+	Port     int    `json:"port,omitempty" xml:"port,omitempty" yaml:"port,omitempty"`
+	DBName   string `json:"dbname,omitempty" xml:"dbname,omitempty" yaml:"dbname,omitempty"`
+	Username string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+	// SSL is true when the connections to the database require TLS, e.g.
+	// DocumentDB clusters or ElastiCache users.
+	SSL bool `json:"ssl,omitempty" xml:"ssl,omitempty" yaml:"ssl,omitempty"`
+	// ClusterID is the identifier of the RDS, Redshift, or DocumentDB
+	// cluster.
+	ClusterID string `json:"cluster_id,omitempty" xml:"cluster_id,omitempty" yaml:"cluster_id,omitempty"`
+	// UserARN is the ARN of the ElastiCache user.
+	UserARN string `json:"user_arn,omitempty" xml:"user_arn,omitempty" yaml:"user_arn,omitempty"`
+	// PreviousUsername and PreviousPassword are the credentials prior to
+	// the last rotation. The username differs from the current one when
+	// the secret is rotated with the alternating users strategy. They are
+	// empty when the secret has not been rotated yet.
+	PreviousUsername string `json:"previous_username,omitempty" xml:"previous_username,omitempty" yaml:"previous_username,omitempty"`
+	PreviousPassword string `json:"previous_password,omitempty" xml:"previous_password,omitempty" yaml:"previous_password,omitempty"`
+}
+
+// DSN returns the data source name of the database with the current
+// credentials, in the format of the common Go driver of the engine:
+// pgx or lib/pq, go-sql-driver/mysql, go-mssqldb, go-ora, mongo-driver,
+// or go-redis.
+func (creds *DatabaseCredentials) DSN() (string, error) {
+	return creds.dsn(creds.Username, creds.Password)
+}
+
+// PreviousDSN returns the data source name of the database with the
+// credentials prior to the last rotation. The connections failing to
+// authenticate with DSN are expected to retry with PreviousDSN while the
+// rotation is in progress. It returns an empty string when the previous
+// credentials are not available.
+func (creds *DatabaseCredentials) PreviousDSN() (string, error) {
+	if creds.PreviousPassword == "" {
+		return "", nil
+	}
+	return creds.dsn(creds.PreviousUsername, creds.PreviousPassword)
+}
+
+func (creds *DatabaseCredentials) dsn(username, password string) (string, error) {
+	family := dbEngineFamily(creds.Engine)
+	if family == "" {
+		return "", fmt.Errorf("unsupported %q database engine", creds.Engine)
+	}
+	if creds.Host == "" {
+		return "", errors.New("database host is empty")
+	}
+	port := creds.Port
+	if port == 0 {
+		if port = dbDefaultPorts[creds.Engine]; port == 0 {
+			port = dbDefaultPorts[family]
+		}
+	}
+	hostport := net.JoinHostPort(creds.Host, strconv.Itoa(port))
+	switch family {
+	case dbFamilyMySQL:
+		return fmt.Sprintf("%s:%s@tcp(%s)/%s", username, password, hostport, creds.DBName), nil
+	case dbFamilySQLServer:
+		u := &url.URL{Scheme: "sqlserver", User: url.UserPassword(username, password), Host: hostport}
+		if creds.DBName != "" {
+			u.RawQuery = url.Values{"database": {creds.DBName}}.Encode()
+		}
+		return u.String(), nil
+	case dbFamilyRedis:
+		u := &url.URL{Scheme: "redis", User: url.UserPassword(username, password), Host: hostport}
+		if creds.SSL {
+			u.Scheme = "rediss"
+		}
+		return u.String(), nil
+	}
+	u := &url.URL{Scheme: family, User: url.UserPassword(username, password), Host: hostport}
+	if creds.DBName != "" {
+		u.Path = "/" + creds.DBName
+	}
+	if family == dbFamilyMongoDB {
+		// DocumentDB does not support retryable writes.
+		q := url.Values{"retryWrites": {"false"}}
+		if creds.SSL {
+			q.Set("tls", "true")
+		}
+		u.Path = "/" + creds.DBName
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
+}
+
+// dbEngineFamily returns the family of the database engine, or an empty
+// string when the engine is not supported.
+func dbEngineFamily(engine string) string {
+	switch {
+	case engine == "postgres", engine == "postgresql", engine == "aurora-postgresql", engine == "redshift":
+		return dbFamilyPostgres
+	case engine == "mysql", engine == "mariadb", engine == "aurora", engine == "aurora-mysql":
+		return dbFamilyMySQL
+	case strings.HasPrefix(engine, "sqlserver"):
+		return dbFamilySQLServer
+	case strings.HasPrefix(engine, "oracle"):
+		return dbFamilyOracle
+	case engine == "mongo", engine == "docdb", engine == "mongodb":
+		return dbFamilyMongoDB
+	case engine == "redis", engine == "valkey":
+		return dbFamilyRedis
+	}
+	return ""
+}
+
+// GetDatabaseCredentials returns the database credentials stored in the
+// engine, host, port, dbname, username, and password keys of the secret.
+// The username and the password are required. The schemas of Redshift,
+// DocumentDB, and ElastiCache secrets are detected with the engine,
+// dbClusterIdentifier, ssl, and user_arn keys. The previous credentials
+// are retrieved from the AWSPREVIOUS version of the secret, so that the
+// connections keep working while the rotated password propagates.
+func (c *client) GetDatabaseCredentials(ctx context.Context, path string) (*DatabaseCredentials, error) {
+	m, err := c.getSecretValue(ctx, path, versionStageCurrent)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := parseDatabaseCredentials(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed current version of %q secret: %v", path, err)
+	}
+
+	m, err = c.getSecretValue(ctx, path, versionStagePrevious)
+	if err != nil {
+		if isNotFound(err) {
+			return creds, nil
+		}
+		return nil, err
+	}
+	previous, err := parseDatabaseCredentials(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed previous version of %q secret: %v", path, err)
+	}
+	if previous.Host == creds.Host && (previous.Username != creds.Username || previous.Password != creds.Password) {
+		creds.PreviousUsername = previous.Username
+		creds.PreviousPassword = previous.Password
+	}
+	return creds, nil
+}
+
+func parseDatabaseCredentials(m map[string]interface{}) (*DatabaseCredentials, error) {
+	creds := &DatabaseCredentials{}
+	var err error
+	if creds.Engine, err = getStringValue(m, "engine", false); err != nil {
+		return nil, err
+	}
+	if creds.UserARN, err = getStringValue(m, "user_arn", false); err != nil {
+		return nil, err
+	}
+	if creds.Engine == "" && strings.Contains(creds.UserARN, ":elasticache:") {
+		// The secrets of ElastiCache users lack the engine, and the users
+		// authenticate over TLS only.
+		creds.Engine = dbFamilyRedis
+		creds.SSL = true
+	}
+	if creds.Engine != "" && dbEngineFamily(creds.Engine) == "" {
+		return nil, fmt.Errorf("unsupported %q database engine", creds.Engine)
+	}
+	if creds.ClusterID, err = getStringValue(m, "dbClusterIdentifier", false); err != nil {
+		return nil, err
+	}
+	if creds.Host, err = getStringValue(m, "host", false); err != nil {
+		return nil, err
+	}
+	if creds.Port, err = getIntValue(m, "port"); err != nil {
+		return nil, err
+	}
+	if creds.Port < 0 || creds.Port > 65535 {
+		return nil, fmt.Errorf("key %q value %d is out of range", "port", creds.Port)
+	}
+	if creds.DBName, err = getStringValue(m, "dbname", false); err != nil {
+		return nil, err
+	}
+	if creds.Username, err = getStringValue(m, "username", true); err != nil {
+		return nil, err
+	}
+	if creds.Password, err = getStringValue(m, "password", true); err != nil {
+		return nil, err
+	}
+	if _, exists := m["ssl"]; exists {
+		if creds.SSL, err = getBoolValue(m, "ssl"); err != nil {
+			return nil, err
+		}
+	}
+	return creds, nil
+}
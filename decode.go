@@ -0,0 +1,147 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// maxSecretDepth is the maximum nesting depth of the values in a secret.
+const maxSecretDepth = 32
+
+// ErrMalformedSecret is matched by the errors of DecodeSecretString.
+var ErrMalformedSecret = errors.New("malformed secret string")
+
+// DecodeError is the error of decoding the secret string. It matches
+// ErrMalformedSecret.
+type DecodeError struct {
+	// Reason describes the problem with the secret string.
+	Reason string
+	// Err is the underlying JSON error, if any.
+	Err error
+}
+
+// Error implements error interface.
+func (e *DecodeError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", ErrMalformedSecret, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", ErrMalformedSecret, e.Reason)
+}
+
+// Unwrap returns the underlying JSON error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is ErrMalformedSecret.
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrMalformedSecret
+}
+
+// DecodeSecretString decodes the secret string of AWS Secrets Manager into
+// the key-value map of the secret. The secret string must be a valid UTF-8
+// JSON object with the values nested at most 32 levels deep. The numbers
+// are decoded as float64. Any other input results in *DecodeError.
+func DecodeSecretString(s string) (map[string]interface{}, error) {
+	if !utf8.ValidString(s) {
+		return nil, &DecodeError{Reason: "invalid utf-8"}
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, &DecodeError{Reason: "invalid json", Err: err}
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, &DecodeError{Reason: fmt.Sprintf("%s is not a json object", jsonTypeName(v))}
+	}
+	if depth(m) > maxSecretDepth {
+		return nil, &DecodeError{Reason: fmt.Sprintf("nesting exceeds %d levels", maxSecretDepth)}
+	}
+	return m, nil
+}
+
+// depth returns the nesting depth of the value.
+func depth(v interface{}) int {
+	var max int
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, item := range v {
+			if d := depth(item); d > max {
+				max = d
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if d := depth(item); d > max {
+				max = d
+			}
+		}
+	default:
+		return 0
+	}
+	return max + 1
+}
+
+// flattenSecret returns the copy of the secret with the nested objects
+// replaced by their values under the dotted keys. The arrays and the empty
+// objects are kept as they are. The top-level key takes precedence over
+// the dotted key of a nested value it collides with.
+func flattenSecret(m map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenInto(flat, k, nested)
+		}
+	}
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); !ok || len(nested) == 0 {
+			flat[k] = v
+		}
+	}
+	return flat
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		key := prefix + "." + k
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenInto(flat, key, nested)
+			continue
+		}
+		flat[key] = v
+	}
+}
+
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
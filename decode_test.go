@@ -0,0 +1,205 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeSecretString(t *testing.T) {
+	testcases := []struct {
+		name      string
+		input     string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "test json object",
+			input: `{"username": "jsmith", "uid": 1000, "roles": ["admin"], "mfa": {"enabled": true}}`,
+			want: map[string]interface{}{
+				"username": "jsmith",
+				"uid":      float64(1000),
+				"roles":    []interface{}{"admin"},
+				"mfa":      map[string]interface{}{"enabled": true},
+			},
+		},
+		{
+			name:  "test empty json object",
+			input: `{}`,
+			want:  map[string]interface{}{},
+		},
+		{
+			name:      "test null",
+			input:     `null`,
+			shouldErr: true,
+			err:       errors.New("malformed secret string: null is not a json object"),
+		},
+		{
+			name:      "test plain text",
+			input:     `foobar`,
+			shouldErr: true,
+			err:       errors.New("malformed secret string: invalid json: invalid character 'o' in literal false (expecting 'a')"),
+		},
+		{
+			name:      "test json array",
+			input:     `["foo"]`,
+			shouldErr: true,
+			err:       errors.New("malformed secret string: array is not a json object"),
+		},
+		{
+			name:      "test huge number",
+			input:     `{"n": 1e400}`,
+			shouldErr: true,
+			err:       errors.New("malformed secret string: invalid json: json: cannot unmarshal number 1e400"),
+		},
+		{
+			name:      "test invalid utf-8",
+			input:     "{\"username\": \"\xff\"}",
+			shouldErr: true,
+			err:       errors.New("malformed secret string: invalid utf-8"),
+		},
+		{
+			name:      "test deep nesting",
+			input:     `{"a":` + strings.Repeat("[", 40) + strings.Repeat("]", 40) + `}`,
+			shouldErr: true,
+			err:       errors.New("malformed secret string: nesting exceeds 32 levels"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DecodeSecretString(tc.input)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, ErrMalformedSecret) {
+					t.Fatalf("expected ErrMalformedSecret, got: %v", err)
+				}
+				// The details of JSON errors vary between Go versions.
+				if !strings.HasPrefix(err.Error(), tc.err.Error()) {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("DecodeSecretString() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// FuzzDecodeSecretString checks that arbitrary secret strings either decode
+// into the key-value maps, which encode back to JSON, or fail with
+// *DecodeError.
+func FuzzDecodeSecretString(f *testing.F) {
+	for _, seed := range []string{
+		`{"username": "jsmith", "password": "bcrypt:10:$2a$10$iqq53VjdCwknBSBrnyLd9OH1Mfh6kqPezMMy6h6F41iLdVDkj13I6"}`,
+		`{"id": "0", "usage": "sign-verify", "value": "b006d65b-c923-46a1-8da1-7d52558508fe"}`,
+		`{"n": 1e400}`,
+		`{"n": -0.000000000000000000000000000001}`,
+		`{"a": {"b": {"c": [1, [2, [3]]]}}}`,
+		"{\"k\": \"\xff\xfe\"}",
+		`null`,
+		`[]`,
+		`"foo"`,
+		``,
+		`{"a":1}{"b":2}`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		m, err := DecodeSecretString(s)
+		if err != nil {
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) || !errors.Is(err, ErrMalformedSecret) {
+				t.Fatalf("expected *DecodeError, got %T: %v", err, err)
+			}
+			return
+		}
+		if m == nil {
+			t.Fatalf("expected key-value map, got nil")
+		}
+		if depth(m) > maxSecretDepth {
+			t.Fatalf("decoded secret exceeds %d levels", maxSecretDepth)
+		}
+		if _, err := json.Marshal(m); err != nil {
+			t.Fatalf("failed encoding decoded secret: %v", err)
+		}
+	})
+}
+
+func TestFlattenSecret(t *testing.T) {
+	stages := map[string]map[string]interface{}{
+		"AWSCURRENT": {
+			"username": "jsmith",
+			"smtp": map[string]interface{}{
+				"host": "smtp.localdomain",
+				"auth": map[string]interface{}{"password": "foo"},
+			},
+			"smtp.host": "mail.localdomain",
+			"roles":     []interface{}{"admin"},
+			"extra":     map[string]interface{}{},
+		},
+	}
+	want := map[string]interface{}{
+		"username":           "jsmith",
+		"smtp.host":          "mail.localdomain",
+		"smtp.auth.password": "foo",
+		"roles":              []interface{}{"admin"},
+		"extra":              map[string]interface{}{},
+	}
+
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"), WithCacheTTL(time.Minute))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	c.SetMockClient(newStagedMockClient(t, stages))
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+	got, err := c.GetSecret(context.TODO(), "authcrunch/smtp", WithFlatten())
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+	}
+	value, err := c.GetSecretByKey(context.TODO(), "authcrunch/smtp", "smtp.auth.password", WithFlatten())
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if value != "foo" {
+		t.Errorf("unexpected value: %v", value)
+	}
+
+	// The cached secret is kept nested.
+	got, err = c.GetSecret(context.TODO(), "authcrunch/smtp")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(stages["AWSCURRENT"], got); diff != "" {
+		t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	defaultProbePath = "diagnostics/probe"
+
+	// DiagnosticOK indicates the check passed.
+	DiagnosticOK = "ok"
+	// DiagnosticFailed indicates the check failed.
+	DiagnosticFailed = "failed"
+	// DiagnosticSkipped indicates the check was not performed, because a
+	// check it depends on failed.
+	DiagnosticSkipped = "skipped"
+)
+
+// DiagnosticCheck is the outcome of a diagnostic check.
+type DiagnosticCheck struct {
+	Name    string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Status  string `json:"status,omitempty" xml:"status,omitempty" yaml:"status,omitempty"`
+	Message string `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// DiagnosticReport describes how the client accesses AWS Secrets Manager
+// and whether the access works.
+type DiagnosticReport struct {
+	Region             string             `json:"region,omitempty" xml:"region,omitempty" yaml:"region,omitempty"`
+	RegionSource       string             `json:"region_source,omitempty" xml:"region_source,omitempty" yaml:"region_source,omitempty"`
+	Endpoint           string             `json:"endpoint,omitempty" xml:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	CredentialSource   string             `json:"credential_source,omitempty" xml:"credential_source,omitempty" yaml:"credential_source,omitempty"`
+	CredentialProvider string             `json:"credential_provider,omitempty" xml:"credential_provider,omitempty" yaml:"credential_provider,omitempty"`
+	CredentialExpires  *time.Time         `json:"credential_expires,omitempty" xml:"credential_expires,omitempty" yaml:"credential_expires,omitempty"`
+	CallerARN          string             `json:"caller_arn,omitempty" xml:"caller_arn,omitempty" yaml:"caller_arn,omitempty"`
+	ProbePath          string             `json:"probe_path,omitempty" xml:"probe_path,omitempty" yaml:"probe_path,omitempty"`
+	Checks             []*DiagnosticCheck `json:"checks,omitempty" xml:"checks,omitempty" yaml:"checks,omitempty"`
+}
+
+// OK returns true when all the checks passed.
+func (r *DiagnosticReport) OK() bool {
+	for _, check := range r.Checks {
+		if check.Status != DiagnosticOK {
+			return false
+		}
+	}
+	return true
+}
+
+// newDiagnosticCheck returns the passed check with the message, or the
+// failed check with the error.
+func newDiagnosticCheck(name string, msg string, err error) *DiagnosticCheck {
+	if err != nil {
+		return &DiagnosticCheck{Name: name, Status: DiagnosticFailed, Message: err.Error()}
+	}
+	return &DiagnosticCheck{Name: name, Status: DiagnosticOK, Message: msg}
+}
+
+func (r *DiagnosticReport) add(check *DiagnosticCheck) {
+	r.Checks = append(r.Checks, check)
+}
+
+func (r *DiagnosticReport) skip(names ...string) {
+	for _, name := range names {
+		r.Checks = append(r.Checks, &DiagnosticCheck{Name: name, Status: DiagnosticSkipped})
+	}
+}
+
+// Diagnose checks the credentials, the identity of the caller, the
+// reachability of AWS Secrets Manager, and, using IAM policy simulation,
+// whether the caller may retrieve the probe secret. The probe path, if
+// not configured, is "diagnostics/probe" under the base prefix. The probe
+// secret does not need to exist. The failed checks are reported rather
+// than returned as errors.
+func (c *client) Diagnose(ctx context.Context) *DiagnosticReport {
+	info := c.GetConfig(ctx)
+	cfg := c.getConfig()
+	c.mu.RLock()
+	serviceConfig := c.serviceConfig
+	c.mu.RUnlock()
+
+	report := &DiagnosticReport{
+		Region:           info.Region,
+		RegionSource:     info.RegionSource,
+		Endpoint:         info.Endpoint,
+		CredentialSource: info.CredentialSource,
+		ProbePath:        cfg.ProbePath,
+	}
+	if report.ProbePath == "" {
+		report.ProbePath = defaultProbePath
+	}
+
+	creds, err := serviceConfig.Credentials.Retrieve(ctx)
+	report.add(newDiagnosticCheck("credentials", "credentials retrieved", err))
+	if err != nil {
+		report.skip("caller_identity", "reachability", "iam_simulation")
+		return report
+	}
+	report.CredentialProvider = creds.Source
+	if creds.CanExpire {
+		expires := creds.Expires
+		report.CredentialExpires = &expires
+	}
+
+	identity, err := sts.NewFromConfig(serviceConfig).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err == nil {
+		report.CallerARN = aws.ToString(identity.Arn)
+	}
+	report.add(newDiagnosticCheck("caller_identity", "caller identity is "+report.CallerARN, err))
+
+	report.add(c.checkReachability(ctx, report.ProbePath))
+
+	if report.CallerARN == "" {
+		report.skip("iam_simulation")
+		return report
+	}
+	report.add(c.simulateGetSecretValue(ctx, serviceConfig, report.CallerARN, report.Region, report.ProbePath))
+	return report
+}
+
+// checkReachability requests the probe secret. Any response from the
+// service, including not found and access denied errors, proves that the
+// service is reachable.
+func (c *client) checkReachability(ctx context.Context, path string) *DiagnosticCheck {
+	const name = "reachability"
+	cfg := c.getConfig()
+	secretName, err := cfg.resolvePath(path)
+	if err != nil {
+		return newDiagnosticCheck(name, "", err)
+	}
+	region, endpoint := "", ""
+	if route := cfg.route(path); route != nil {
+		region, endpoint = route.Region, route.Endpoint
+	}
+	_, err = c.getServiceClient(region, endpoint).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretName),
+	})
+	var apiErr smithy.APIError
+	switch {
+	case err == nil:
+		return newDiagnosticCheck(name, "probe secret retrieved", nil)
+	case isNotFound(err):
+		return newDiagnosticCheck(name, "service reachable, probe secret not found", nil)
+	case errors.As(err, &apiErr):
+		return newDiagnosticCheck(name, fmt.Sprintf("service reachable, probe secret request failed with %s", apiErr.ErrorCode()), nil)
+	}
+	return newDiagnosticCheck(name, "", fmt.Errorf("service unreachable: %v", err))
+}
+
+// simulateGetSecretValue evaluates the IAM policies of the caller for the
+// retrieval of the probe secret.
+func (c *client) simulateGetSecretValue(ctx context.Context, serviceConfig aws.Config, callerARN, region, path string) *DiagnosticCheck {
+	const name = "iam_simulation"
+	principalARN, partition, account, err := principalFromCallerARN(callerARN)
+	if err != nil {
+		return newDiagnosticCheck(name, "", err)
+	}
+	secretName, err := c.getConfig().resolvePath(path)
+	if err != nil {
+		return newDiagnosticCheck(name, "", err)
+	}
+	// The ARN of a secret ends with six random characters, the wildcard
+	// matches any of them.
+	resourceARN := fmt.Sprintf("arn:%s:secretsmanager:%s:%s:secret:%s-??????", partition, region, account, secretName)
+	output, err := iam.NewFromConfig(serviceConfig).SimulatePrincipalPolicy(ctx, &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(principalARN),
+		ActionNames:     []string{"secretsmanager:GetSecretValue"},
+		ResourceArns:    []string{resourceARN},
+	})
+	if err != nil {
+		return newDiagnosticCheck(name, "", fmt.Errorf("simulation failed: %v", err))
+	}
+	for _, result := range output.EvaluationResults {
+		if result.EvalDecision != "allowed" {
+			return newDiagnosticCheck(name, "", fmt.Errorf("secretsmanager:GetSecretValue on %s is %s", resourceARN, result.EvalDecision))
+		}
+	}
+	return newDiagnosticCheck(name, fmt.Sprintf("secretsmanager:GetSecretValue on %s is allowed", resourceARN), nil)
+}
+
+// principalFromCallerARN returns the ARN of IAM principal, whose policies
+// are simulated, for the caller ARN. The assumed role sessions map to
+// their roles.
+func principalFromCallerARN(callerARN string) (string, string, string, error) {
+	parts := strings.SplitN(callerARN, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return "", "", "", fmt.Errorf("malformed %q caller arn", callerARN)
+	}
+	partition, service, account, resource := parts[1], parts[2], parts[4], parts[5]
+	switch {
+	case service == "iam":
+		return callerARN, partition, account, nil
+	case service == "sts" && strings.HasPrefix(resource, "assumed-role/"):
+		role := strings.SplitN(strings.TrimPrefix(resource, "assumed-role/"), "/", 2)[0]
+		return fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, account, role), partition, account, nil
+	}
+	return "", "", "", fmt.Errorf("unsupported %q caller arn", callerARN)
+}
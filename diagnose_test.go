@@ -0,0 +1,216 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+type failingCredentialsProvider struct{}
+
+func (failingCredentialsProvider) Retrieve(context.Context) (aws.Credentials, error) {
+	return aws.Credentials{}, errors.New("no valid credential sources found")
+}
+
+// newDiagnosticsMockClient returns mock HTTP client serving STS, IAM, and
+// AWS Secrets Manager requests.
+func newDiagnosticsMockClient(t *testing.T, decision string) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Header.Get("X-Amz-Target") == "secretsmanager.GetSecretValue" {
+			return secretsmock.NotFoundResponse("524b9962-6854-4b5c-aa53-81759ef610dd"), nil
+		}
+		if err := r.ParseForm(); err != nil {
+			return mockFailure(t, "failed parsing request: %v", err)
+		}
+		var response string
+		switch action := r.PostForm.Get("Action"); action {
+		case "GetCallerIdentity":
+			response = "<GetCallerIdentityResponse><GetCallerIdentityResult>" +
+				"<Arn>arn:aws:sts::123456789012:assumed-role/AuthCrunch/i-0123456789abcdef0</Arn>" +
+				"<UserId>AROAEXAMPLE:i-0123456789abcdef0</UserId><Account>123456789012</Account>" +
+				"</GetCallerIdentityResult></GetCallerIdentityResponse>"
+		case "SimulatePrincipalPolicy":
+			if got := r.PostForm.Get("PolicySourceArn"); got != "arn:aws:iam::123456789012:role/AuthCrunch" {
+				return mockFailure(t, "unexpected %q policy source arn", got)
+			}
+			response = "<SimulatePrincipalPolicyResponse><SimulatePrincipalPolicyResult>" +
+				"<IsTruncated>false</IsTruncated><EvaluationResults><member>" +
+				"<EvalActionName>secretsmanager:GetSecretValue</EvalActionName>" +
+				"<EvalResourceName>" + r.PostForm.Get("ResourceArns.member.1") + "</EvalResourceName>" +
+				"<EvalDecision>" + decision + "</EvalDecision>" +
+				"</member></EvaluationResults></SimulatePrincipalPolicyResult></SimulatePrincipalPolicyResponse>"
+		default:
+			return mockFailure(t, "unexpected %q action", action)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(response)),
+		}, nil
+	})
+}
+
+func TestDiagnose(t *testing.T) {
+	resourceARN := "arn:aws:secretsmanager:us-east-1:123456789012:secret:authcrunch/prod/diagnostics/probe-??????"
+	testcases := []struct {
+		name        string
+		credentials aws.CredentialsProvider
+		decision    string
+		want        *DiagnosticReport
+		wantOK      bool
+	}{
+		{
+			name:        "test diagnostics with allowed access",
+			credentials: MockCredentialsProvider{},
+			decision:    "allowed",
+			wantOK:      true,
+			want: &DiagnosticReport{
+				Region:             "us-east-1",
+				RegionSource:       "config",
+				CredentialSource:   "custom",
+				CredentialProvider: "mock credentials",
+				CallerARN:          "arn:aws:sts::123456789012:assumed-role/AuthCrunch/i-0123456789abcdef0",
+				ProbePath:          "diagnostics/probe",
+				Checks: []*DiagnosticCheck{
+					{Name: "credentials", Status: "ok", Message: "credentials retrieved"},
+					{Name: "caller_identity", Status: "ok", Message: "caller identity is arn:aws:sts::123456789012:assumed-role/AuthCrunch/i-0123456789abcdef0"},
+					{Name: "reachability", Status: "ok", Message: "service reachable, probe secret not found"},
+					{Name: "iam_simulation", Status: "ok", Message: "secretsmanager:GetSecretValue on " + resourceARN + " is allowed"},
+				},
+			},
+		},
+		{
+			name:        "test diagnostics with denied access",
+			credentials: MockCredentialsProvider{},
+			decision:    "implicitDeny",
+			want: &DiagnosticReport{
+				Region:             "us-east-1",
+				RegionSource:       "config",
+				CredentialSource:   "custom",
+				CredentialProvider: "mock credentials",
+				CallerARN:          "arn:aws:sts::123456789012:assumed-role/AuthCrunch/i-0123456789abcdef0",
+				ProbePath:          "diagnostics/probe",
+				Checks: []*DiagnosticCheck{
+					{Name: "credentials", Status: "ok", Message: "credentials retrieved"},
+					{Name: "caller_identity", Status: "ok", Message: "caller identity is arn:aws:sts::123456789012:assumed-role/AuthCrunch/i-0123456789abcdef0"},
+					{Name: "reachability", Status: "ok", Message: "service reachable, probe secret not found"},
+					{Name: "iam_simulation", Status: "failed", Message: "secretsmanager:GetSecretValue on " + resourceARN + " is implicitDeny"},
+				},
+			},
+		},
+		{
+			name:        "test diagnostics without credentials",
+			credentials: failingCredentialsProvider{},
+			want: &DiagnosticReport{
+				Region:           "us-east-1",
+				RegionSource:     "config",
+				CredentialSource: "custom",
+				ProbePath:        "diagnostics/probe",
+				Checks: []*DiagnosticCheck{
+					{Name: "credentials", Status: "failed", Message: "no valid credential sources found"},
+					{Name: "caller_identity", Status: "skipped"},
+					{Name: "reachability", Status: "skipped"},
+					{Name: "iam_simulation", Status: "skipped"},
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithBasePrefix("authcrunch/prod/"),
+				WithHTTPClient(newDiagnosticsMockClient(t, tc.decision)),
+				WithCredentialsProvider(tc.credentials),
+			)
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			got := c.Diagnose(context.TODO())
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Diagnose() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantOK, got.OK()); diff != "" {
+				t.Errorf("OK() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrincipalFromCallerARN(t *testing.T) {
+	testcases := []struct {
+		name      string
+		arn       string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test iam user",
+			arn:  "arn:aws:iam::123456789012:user/jsmith",
+			want: "arn:aws:iam::123456789012:user/jsmith",
+		},
+		{
+			name: "test assumed role in other partition",
+			arn:  "arn:aws-us-gov:sts::123456789012:assumed-role/AuthCrunch/session",
+			want: "arn:aws-us-gov:iam::123456789012:role/AuthCrunch",
+		},
+		{
+			name:      "test federated user",
+			arn:       "arn:aws:sts::123456789012:federated-user/jsmith",
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q caller arn", "arn:aws:sts::123456789012:federated-user/jsmith"),
+		},
+		{
+			name:      "test malformed arn",
+			arn:       "jsmith",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q caller arn", "jsmith"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, _, err := principalFromCallerARN(tc.arn)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("principalFromCallerARN() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("principalFromCallerARN() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
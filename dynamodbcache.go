@@ -0,0 +1,219 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// DynamoDBCacheKeyAttribute is the string partition key of the table
+	// of the shared cache.
+	DynamoDBCacheKeyAttribute = "cache_key"
+	// DynamoDBCacheExpiresAttribute is the number attribute holding the
+	// expiration time of the items in Unix seconds. It should be the time
+	// to live attribute of the table.
+	DynamoDBCacheExpiresAttribute = "expires_at"
+
+	dynamoDBCacheValueAttribute = "secret"
+	defaultDynamoDBCacheTTL     = 5 * time.Minute
+)
+
+var dynamoDBTableNameRgx *regexp.Regexp = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,255}$`)
+
+// DynamoDBCacheConfig makes the instances of a fleet share the secrets
+// retrieved from AWS Secrets Manager through the DynamoDB table, so that
+// they collectively make one request per secret per TTL. The table has the
+// string partition key DynamoDBCacheKeyAttribute and the time to live
+// enabled on DynamoDBCacheExpiresAttribute. The secrets are encrypted with
+// AES-256-GCM before they leave the instance. The shared cache is consulted
+// after the local one and the checks of the metadata of the secret, and its
+// failures are logged, not returned. The secrets read from it are decoded
+// and checked the same way as the ones retrieved from the service.
+type DynamoDBCacheConfig struct {
+	// TableName is the name of DynamoDB table.
+	TableName string `json:"table_name,omitempty" xml:"table_name,omitempty" yaml:"table_name,omitempty"`
+	// TTL is the period of time the secrets are shared for, e.g. "5m".
+	// When empty, it is five minutes.
+	TTL string `json:"ttl,omitempty" xml:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// EncryptionKey is the base64-encoded 256-bit key encrypting the
+	// items, usually a reference to an environment variable, e.g.
+	// "${AUTHCRUNCH_CACHE_KEY}". All instances of the fleet use the same
+	// key.
+	EncryptionKey string `json:"encryption_key,omitempty" xml:"encryption_key,omitempty" yaml:"encryption_key,omitempty"`
+}
+
+func (cfg *DynamoDBCacheConfig) validate() error {
+	if !dynamoDBTableNameRgx.MatchString(cfg.TableName) {
+		return fmt.Errorf("malformed %q dynamodb cache table name", cfg.TableName)
+	}
+	if cfg.TTL != "" {
+		if d, err := time.ParseDuration(cfg.TTL); err != nil || d < time.Second {
+			return fmt.Errorf("malformed %q dynamodb cache ttl", cfg.TTL)
+		}
+	}
+	if _, err := cfg.newCipher(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ttl returns the period of time the secrets are shared for.
+func (cfg *DynamoDBCacheConfig) ttl() time.Duration {
+	if cfg.TTL == "" {
+		return defaultDynamoDBCacheTTL
+	}
+	d, _ := time.ParseDuration(cfg.TTL)
+	return d
+}
+
+// newCipher returns the AEAD encrypting the items.
+func (cfg *DynamoDBCacheConfig) newCipher() (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(cfg.EncryptionKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("dynamodb cache encryption key is not base64-encoded 256-bit key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// itemKey returns the partition key of the item holding the secret.
+func (k cacheKey) itemKey(name string) string {
+	label := k.stage
+	if k.versionID != "" {
+		label = k.versionID
+	}
+	return strings.Join([]string{k.region, k.roleARN, name, label}, "|")
+}
+
+// getSharedSecret returns the secret string with the name from the shared
+// cache.
+func (c *client) getSharedSecret(ctx context.Context, cfg *DynamoDBCacheConfig, key cacheKey, name string) (string, bool) {
+	secretString, found, err := c.loadSharedSecret(ctx, cfg, key.itemKey(name))
+	if err != nil {
+		c.getLogger().Warn(
+			"failed reading secret from dynamodb cache",
+			zap.String("path", key.path),
+			zap.String("table", cfg.TableName),
+			zap.Error(err),
+		)
+		return "", false
+	}
+	return secretString, found
+}
+
+func (c *client) loadSharedSecret(ctx context.Context, cfg *DynamoDBCacheConfig, itemKey string) (string, bool, error) {
+	output, err := c.getDynamoDBClient().GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(cfg.TableName),
+		Key:            map[string]ddbtypes.AttributeValue{DynamoDBCacheKeyAttribute: &ddbtypes.AttributeValueMemberS{Value: itemKey}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if len(output.Item) == 0 {
+		return "", false, nil
+	}
+	// The expired items are deleted by DynamoDB with a delay.
+	expires, ok := output.Item[DynamoDBCacheExpiresAttribute].(*ddbtypes.AttributeValueMemberN)
+	if !ok {
+		return "", false, fmt.Errorf("item has no %q attribute", DynamoDBCacheExpiresAttribute)
+	}
+	ts, err := strconv.ParseInt(expires.Value, 10, 64)
+	if err != nil {
+		return "", false, fmt.Errorf("malformed %q attribute: %v", DynamoDBCacheExpiresAttribute, err)
+	}
+	if !c.clock.Now().Before(time.Unix(ts, 0)) {
+		return "", false, nil
+	}
+	value, ok := output.Item[dynamoDBCacheValueAttribute].(*ddbtypes.AttributeValueMemberB)
+	if !ok {
+		return "", false, fmt.Errorf("item has no %q attribute", dynamoDBCacheValueAttribute)
+	}
+	aead, err := cfg.newCipher()
+	if err != nil {
+		return "", false, err
+	}
+	if len(value.Value) < aead.NonceSize() {
+		return "", false, errors.New("item value is truncated")
+	}
+	nonce, ciphertext := value.Value[:aead.NonceSize()], value.Value[aead.NonceSize():]
+	b, err := aead.Open(nil, nonce, ciphertext, []byte(itemKey+"|"+expires.Value))
+	if err != nil {
+		return "", false, errors.New("item value cannot be decrypted")
+	}
+	return string(b), true, nil
+}
+
+// putSharedSecret stores the secret string with the name in the shared
+// cache.
+func (c *client) putSharedSecret(ctx context.Context, cfg *DynamoDBCacheConfig, key cacheKey, name string, secretString string) {
+	if err := c.storeSharedSecret(ctx, cfg, key.itemKey(name), secretString); err != nil {
+		c.getLogger().Warn(
+			"failed writing secret to dynamodb cache",
+			zap.String("path", key.path),
+			zap.String("table", cfg.TableName),
+			zap.Error(err),
+		)
+	}
+}
+
+func (c *client) storeSharedSecret(ctx context.Context, cfg *DynamoDBCacheConfig, itemKey string, secretString string) error {
+	aead, err := cfg.newCipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	expires := strconv.FormatInt(c.clock.Now().Add(cfg.ttl()).Unix(), 10)
+	value := aead.Seal(nonce, nonce, []byte(secretString), []byte(itemKey+"|"+expires))
+	_, err = c.getDynamoDBClient().PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(cfg.TableName),
+		Item: map[string]ddbtypes.AttributeValue{
+			DynamoDBCacheKeyAttribute:     &ddbtypes.AttributeValueMemberS{Value: itemKey},
+			DynamoDBCacheExpiresAttribute: &ddbtypes.AttributeValueMemberN{Value: expires},
+			dynamoDBCacheValueAttribute:   &ddbtypes.AttributeValueMemberB{Value: value},
+		},
+	})
+	return err
+}
+
+// getDynamoDBClient returns DynamoDB client sharing the service
+// configuration of the client.
+func (c *client) getDynamoDBClient() *dynamodb.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.dynamodbClient
+}
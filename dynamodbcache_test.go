@@ -0,0 +1,187 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// dynamoDBTestTable is the table of the mock DynamoDB endpoint shared by
+// the clients.
+type dynamoDBTestTable struct {
+	mu    sync.Mutex
+	items map[string]map[string]map[string]string
+}
+
+// newDynamoDBCacheMockClient returns HTTP client serving the secrets and
+// the items of the table. It counts the requests for the secrets.
+func newDynamoDBCacheMockClient(t *testing.T, secrets map[string]string, table *dynamoDBTestTable, fetches *int) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(r.Body)
+		var input struct {
+			Key  map[string]map[string]string `json:"Key"`
+			Item map[string]map[string]string `json:"Item"`
+		}
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "secretsmanager.GetSecretValue":
+			table.mu.Lock()
+			*fetches++
+			table.mu.Unlock()
+			for name, v := range secrets {
+				if strings.Contains(string(b), `"`+name+`"`) {
+					return secretsmock.JSONResponse(map[string]interface{}{"SecretString": v}), nil
+				}
+			}
+			return secretsmock.NotFoundResponse(""), nil
+		case "DynamoDB_20120810.GetItem":
+			if err := json.Unmarshal(b, &input); err != nil {
+				return mockFailure(t, "malformed GetItem request: %v", err)
+			}
+			table.mu.Lock()
+			defer table.mu.Unlock()
+			item, found := table.items[input.Key[DynamoDBCacheKeyAttribute]["S"]]
+			if !found {
+				return secretsmock.JSONResponse(map[string]interface{}{}), nil
+			}
+			return secretsmock.JSONResponse(map[string]interface{}{"Item": item}), nil
+		case "DynamoDB_20120810.PutItem":
+			if err := json.Unmarshal(b, &input); err != nil {
+				return mockFailure(t, "malformed PutItem request: %v", err)
+			}
+			table.mu.Lock()
+			defer table.mu.Unlock()
+			table.items[input.Item[DynamoDBCacheKeyAttribute]["S"]] = input.Item
+			return secretsmock.JSONResponse(map[string]interface{}{}), nil
+		default:
+			return mockFailure(t, "unexpected %q target", target)
+		}
+	})
+}
+
+func TestDynamoDBCache(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	secrets := map[string]string{
+		"authcrunch/users/jsmith": `{"username":"jsmith","password":"foobar"}`,
+	}
+	table := &dynamoDBTestTable{items: make(map[string]map[string]map[string]string)}
+	clock := newTestClock()
+	var fetches int
+	newFleetClient := func(encryptionKey string, opts ...Option) Client {
+		c, err := NewClient(context.TODO(), append([]Option{
+			WithID("foo"),
+			WithRegion("us-east-1"),
+			WithBasePrefix("authcrunch/"),
+			WithClock(clock),
+			WithDynamoDBCache(&DynamoDBCacheConfig{TableName: "authcrunch-cache", TTL: "1m", EncryptionKey: encryptionKey}),
+			WithHTTPClient(newDynamoDBCacheMockClient(t, secrets, table, &fetches)),
+			WithCredentialsProvider(MockCredentialsProvider{}),
+		}, opts...)...)
+		if err != nil {
+			t.Fatalf("unexpected error during client initialization: %v", err)
+		}
+		return c
+	}
+	want := map[string]interface{}{"username": "jsmith", "password": "foobar"}
+
+	// The instances of the fleet make one request per TTL.
+	for i := 0; i < 3; i++ {
+		got, err := newFleetClient(key).GetSecret(context.TODO(), "users/jsmith")
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("GetSecret() mismatch (-want +got):\n%s", diff)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("unexpected %d secret fetches, want 1", fetches)
+	}
+	for _, item := range table.items {
+		if strings.Contains(item[dynamoDBCacheValueAttribute]["B"], base64.StdEncoding.EncodeToString([]byte("foobar"))) {
+			t.Fatalf("item holds the plaintext secret: %v", item)
+		}
+	}
+
+	// The shared secrets pass the checks of the instance.
+	_, err := newFleetClient(key, WithSecretLimits(&SecretLimitsConfig{MaxKeys: 1})).GetSecret(context.TODO(), "users/jsmith")
+	if !errors.Is(err, ErrSecretTooLarge) {
+		t.Fatalf("unexpected error: %v, want: %v", err, ErrSecretTooLarge)
+	}
+	if fetches != 1 {
+		t.Fatalf("unexpected %d secret fetches, want 1", fetches)
+	}
+
+	// The instance with another key does not decrypt the items.
+	otherKey := base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+	if _, err := newFleetClient(otherKey).GetSecret(context.TODO(), "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if fetches != 2 {
+		t.Fatalf("unexpected %d secret fetches, want 2", fetches)
+	}
+
+	// The expired items are ignored.
+	clock.Advance(2 * time.Minute)
+	if _, err := newFleetClient(otherKey).GetSecret(context.TODO(), "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if fetches != 3 {
+		t.Fatalf("unexpected %d secret fetches, want 3", fetches)
+	}
+
+	// The uncached reads bypass the shared cache.
+	if _, err := newFleetClient(otherKey).GetSecret(context.TODO(), "users/jsmith", WithNoCache()); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if fetches != 4 {
+		t.Fatalf("unexpected %d secret fetches, want 4", fetches)
+	}
+}
+
+func TestDynamoDBCacheConfigValidate(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	for _, tc := range []struct {
+		cfg *DynamoDBCacheConfig
+		err string
+	}{
+		{cfg: &DynamoDBCacheConfig{TableName: "authcrunch-cache", EncryptionKey: key}},
+		{cfg: &DynamoDBCacheConfig{TableName: "authcrunch-cache", TTL: "10m", EncryptionKey: key}},
+		{cfg: &DynamoDBCacheConfig{EncryptionKey: key}, err: `malformed "" dynamodb cache table name`},
+		{cfg: &DynamoDBCacheConfig{TableName: "authcrunch-cache", TTL: "1ms", EncryptionKey: key}, err: `malformed "1ms" dynamodb cache ttl`},
+		{cfg: &DynamoDBCacheConfig{TableName: "authcrunch-cache"}, err: "dynamodb cache encryption key is not base64-encoded 256-bit key"},
+		{
+			cfg: &DynamoDBCacheConfig{TableName: "authcrunch-cache", EncryptionKey: base64.StdEncoding.EncodeToString(make([]byte, 16))},
+			err: "dynamodb cache encryption key is not base64-encoded 256-bit key",
+		},
+	} {
+		err := tc.cfg.validate()
+		if (err == nil && tc.err != "") || (err != nil && err.Error() != tc.err) {
+			t.Errorf("unexpected error: %v, want: %s", err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+var (
+	envVarRgx *regexp.Regexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+)
+
+// expandEnv replaces ${VAR} and ${VAR:-default} references in the string
+// with the values of the environment variables. It returns an error when
+// the variable is not set and has no default value.
+func expandEnv(s string) (string, error) {
+	var err error
+	expanded := envVarRgx.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envVarRgx.FindStringSubmatch(ref)
+		if v, exists := os.LookupEnv(m[1]); exists {
+			return v
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		if err == nil {
+			err = fmt.Errorf("environment variable %q is not set", m[1])
+		}
+		return ref
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// expandEnv replaces the references to environment variables in the
+// configuration values that differ across deployments.
+func (cfg *ClientConfig) expandEnv() error {
+	fields := []*string{
+		&cfg.ID,
+		&cfg.Region,
+		&cfg.Endpoint,
+		&cfg.RoleARN,
+		&cfg.CacheTTL,
+		&cfg.DefaultTimeout,
+		&cfg.WatchInterval,
+		&cfg.BasePrefix,
+	}
+	for _, realm := range cfg.Realms {
+		if realm == nil {
+			continue
+		}
+		fields = append(fields, &realm.PathPrefix, &realm.Region)
+	}
+	for i := range cfg.FallbackRegions {
+		fields = append(fields, &cfg.FallbackRegions[i])
+	}
+	for _, route := range cfg.Routes {
+		if route == nil {
+			continue
+		}
+		fields = append(fields, &route.PathPrefix, &route.Region, &route.Endpoint)
+	}
+	if cfg.DynamoDBCache != nil {
+		fields = append(fields, &cfg.DynamoDBCache.TableName, &cfg.DynamoDBCache.EncryptionKey)
+	}
+	if cfg.Webhook != nil {
+		fields = append(fields, &cfg.Webhook.URL, &cfg.Webhook.SigningKey)
+	}
+	for _, schema := range cfg.Schemas {
+		if schema == nil {
+			continue
+		}
+		fields = append(fields, &schema.Path)
+	}
+	for _, field := range fields {
+		v, err := expandEnv(*field)
+		if err != nil {
+			return fmt.Errorf("malformed config: %v", err)
+		}
+		*field = v
+	}
+	return nil
+}
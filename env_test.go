@@ -0,0 +1,124 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("AUTHCRUNCH_ENV", "prod")
+	t.Setenv("AUTHCRUNCH_EMPTY", "")
+
+	testcases := []struct {
+		name      string
+		input     string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "test string without references",
+			input: "authcrunch/prod",
+			want:  "authcrunch/prod",
+		},
+		{
+			name:  "test string with reference",
+			input: "authcrunch/${AUTHCRUNCH_ENV}/users",
+			want:  "authcrunch/prod/users",
+		},
+		{
+			name:  "test reference to empty variable",
+			input: "authcrunch/${AUTHCRUNCH_EMPTY:-dev}",
+			want:  "authcrunch/",
+		},
+		{
+			name:  "test reference with default value",
+			input: "${AUTHCRUNCH_UNDEFINED:-us-east-1}",
+			want:  "us-east-1",
+		},
+		{
+			name:  "test dollar sign without braces",
+			input: "$AUTHCRUNCH_ENV",
+			want:  "$AUTHCRUNCH_ENV",
+		},
+		{
+			name:      "test reference to undefined variable",
+			input:     "${AUTHCRUNCH_UNDEFINED}",
+			shouldErr: true,
+			err:       fmt.Errorf("environment variable %q is not set", "AUTHCRUNCH_UNDEFINED"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := expandEnv(tc.input)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("expandEnv() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("expandEnv() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshalConfigWithEnv(t *testing.T) {
+	t.Setenv("AUTHCRUNCH_ENV", "prod")
+	t.Setenv("AWS_REGION", "us-west-2")
+	t.Setenv("AWS_ACCOUNT_ID", "123456789012")
+
+	want := &ClientConfig{
+		Version:  1,
+		ID:       "foo",
+		Region:   "us-west-2",
+		Provider: "aws_secrets_manager",
+		RoleARN:  "arn:aws:iam::123456789012:role/authcrunch-prod",
+		Realms: []*RealmConfig{
+			{Name: "local", PathPrefix: "authcrunch/prod/local"},
+		},
+	}
+
+	got := &ClientConfig{}
+	data := "id: foo\nregion: ${AWS_REGION}\nrole_arn: arn:aws:iam::${AWS_ACCOUNT_ID}:role/authcrunch-${AUTHCRUNCH_ENV}\nrealms:\n  - name: local\n    path_prefix: authcrunch/${AUTHCRUNCH_ENV}/local\n"
+	if err := yaml.Unmarshal([]byte(data), got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("yaml.Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+
+	err := json.Unmarshal([]byte(`{"id": "foo", "region": "${AWS_UNDEFINED_REGION}"}`), &ClientConfig{})
+	wantErr := fmt.Errorf("malformed config: environment variable %q is not set", "AWS_UNDEFINED_REGION")
+	if err == nil {
+		t.Fatalf("unexpected success, want: %v", wantErr)
+	}
+	if diff := cmp.Diff(wantErr.Error(), err.Error()); diff != "" {
+		t.Errorf("json.Unmarshal() error mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+const (
+	eventWiringTargetID  = "authcrunch-secrets"
+	eventWiringPolicySid = "AllowEventBridgeRule"
+	defaultEventBusName  = "default"
+)
+
+var (
+	// ErrEventWiring is returned by SetupEventWiring in validation mode,
+	// when a resource is missing or misconfigured.
+	ErrEventWiring = errors.New("event listener wiring incomplete")
+
+	eventWiringNameRgx  *regexp.Regexp = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+	policySidInvalidRgx *regexp.Regexp = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// EventWiringConfig is the configuration of SetupEventWiring.
+type EventWiringConfig struct {
+	// RuleName is the name of EventBridge rule matching AWS Secrets
+	// Manager events.
+	RuleName string `json:"rule_name,omitempty" xml:"rule_name,omitempty" yaml:"rule_name,omitempty"`
+	// QueueName is the name of SQS queue receiving the events.
+	QueueName string `json:"queue_name,omitempty" xml:"queue_name,omitempty" yaml:"queue_name,omitempty"`
+	// EventBusName is the name of the event bus of the rule. When empty,
+	// it is the default bus, which receives the events of AWS services.
+	EventBusName string `json:"event_bus_name,omitempty" xml:"event_bus_name,omitempty" yaml:"event_bus_name,omitempty"`
+	// ValidateOnly checks the existing resources without creating or
+	// updating them.
+	ValidateOnly bool `json:"validate_only,omitempty" xml:"validate_only,omitempty" yaml:"validate_only,omitempty"`
+}
+
+func (cfg *EventWiringConfig) validate() error {
+	if !eventWiringNameRgx.MatchString(cfg.RuleName) {
+		return fmt.Errorf("malformed %q event rule name", cfg.RuleName)
+	}
+	if !eventWiringNameRgx.MatchString(cfg.QueueName) {
+		return fmt.Errorf("malformed %q event queue name", cfg.QueueName)
+	}
+	return nil
+}
+
+// EventWiring is the EventBridge rule and SQS queue delivering AWS Secrets
+// Manager events to ListenSQS.
+type EventWiring struct {
+	RuleARN  string `json:"rule_arn,omitempty" xml:"rule_arn,omitempty" yaml:"rule_arn,omitempty"`
+	QueueARN string `json:"queue_arn,omitempty" xml:"queue_arn,omitempty" yaml:"queue_arn,omitempty"`
+	QueueURL string `json:"queue_url,omitempty" xml:"queue_url,omitempty" yaml:"queue_url,omitempty"`
+	// Changes are the resources created or updated, i.e. "queue",
+	// "rule", "target", and "queue_policy".
+	Changes []string `json:"changes,omitempty" xml:"changes,omitempty" yaml:"changes,omitempty"`
+}
+
+// secretsManagerEventPattern returns the EventBridge event pattern
+// matching the events ListenSQS recognizes.
+func secretsManagerEventPattern() string {
+	names := make([]string, 0, len(secretsManagerEventTypes))
+	for name := range secretsManagerEventTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b, _ := json.Marshal(map[string]interface{}{
+		"source":      []string{secretsManagerSource},
+		"detail-type": []string{"AWS API Call via CloudTrail", "AWS Service Event via CloudTrail"},
+		"detail":      map[string]interface{}{"eventName": names},
+	})
+	return string(b)
+}
+
+// SetupEventWiring creates or updates the EventBridge rule matching AWS
+// Secrets Manager events, the SQS queue, the rule target, and the queue
+// policy allowing the rule to send the messages, so that ListenSQS with
+// the returned queue URL reacts to the rotations in near real time. It is
+// idempotent. With ValidateOnly, it returns ErrEventWiring for the first
+// missing or misconfigured resource instead.
+func (c *client) SetupEventWiring(ctx context.Context, cfg *EventWiringConfig) (*EventWiring, error) {
+	if provider := c.getConfig().Provider; provider != defaultProvider {
+		return nil, fmt.Errorf("event wiring is not supported with %q provider", provider)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	busName := cfg.EventBusName
+	if busName == "" {
+		busName = defaultEventBusName
+	}
+	c.mu.RLock()
+	queue := sqs.NewFromConfig(c.serviceConfig)
+	events := eventbridge.NewFromConfig(c.serviceConfig)
+	c.mu.RUnlock()
+	w := &EventWiring{}
+
+	// The queue.
+	queueURLOutput, err := queue.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(cfg.QueueName)})
+	var queueNotFound *sqstypes.QueueDoesNotExist
+	switch {
+	case errors.As(err, &queueNotFound) && cfg.ValidateOnly:
+		return nil, fmt.Errorf("%w: %q queue not found", ErrEventWiring, cfg.QueueName)
+	case errors.As(err, &queueNotFound):
+		output, err := queue.CreateQueue(ctx, &sqs.CreateQueueInput{
+			QueueName:  aws.String(cfg.QueueName),
+			Attributes: map[string]string{string(sqstypes.QueueAttributeNameSqsManagedSseEnabled): "true"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		w.QueueURL = aws.ToString(output.QueueUrl)
+		w.Changes = append(w.Changes, "queue")
+	case err != nil:
+		return nil, err
+	default:
+		w.QueueURL = aws.ToString(queueURLOutput.QueueUrl)
+	}
+	attrs, err := queue.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(w.QueueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn, sqstypes.QueueAttributeNamePolicy},
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.QueueARN = attrs.Attributes[string(sqstypes.QueueAttributeNameQueueArn)]
+
+	// The rule.
+	pattern := secretsManagerEventPattern()
+	rule, err := events.DescribeRule(ctx, &eventbridge.DescribeRuleInput{
+		Name:         aws.String(cfg.RuleName),
+		EventBusName: aws.String(busName),
+	})
+	var ruleNotFound *ebtypes.ResourceNotFoundException
+	switch {
+	case errors.As(err, &ruleNotFound) && cfg.ValidateOnly:
+		return nil, fmt.Errorf("%w: %q rule not found", ErrEventWiring, cfg.RuleName)
+	case err != nil && !errors.As(err, &ruleNotFound):
+		return nil, err
+	case err == nil && equalJSON(aws.ToString(rule.EventPattern), pattern) && rule.State == ebtypes.RuleStateEnabled:
+		w.RuleARN = aws.ToString(rule.Arn)
+	case err == nil && cfg.ValidateOnly:
+		return nil, fmt.Errorf("%w: %q rule is disabled or has unexpected event pattern", ErrEventWiring, cfg.RuleName)
+	default:
+		output, err := events.PutRule(ctx, &eventbridge.PutRuleInput{
+			Name:         aws.String(cfg.RuleName),
+			EventBusName: aws.String(busName),
+			EventPattern: aws.String(pattern),
+			State:        ebtypes.RuleStateEnabled,
+			Description:  aws.String("Delivers AWS Secrets Manager events to the AuthCrunch secrets listener"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		w.RuleARN = aws.ToString(output.RuleArn)
+		w.Changes = append(w.Changes, "rule")
+	}
+
+	// The target of the rule.
+	targets, err := events.ListTargetsByRule(ctx, &eventbridge.ListTargetsByRuleInput{
+		Rule:         aws.String(cfg.RuleName),
+		EventBusName: aws.String(busName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var targetFound bool
+	for _, target := range targets.Targets {
+		if aws.ToString(target.Arn) == w.QueueARN {
+			targetFound = true
+			break
+		}
+	}
+	if !targetFound {
+		if cfg.ValidateOnly {
+			return nil, fmt.Errorf("%w: %q queue is not the target of %q rule", ErrEventWiring, cfg.QueueName, cfg.RuleName)
+		}
+		output, err := events.PutTargets(ctx, &eventbridge.PutTargetsInput{
+			Rule:         aws.String(cfg.RuleName),
+			EventBusName: aws.String(busName),
+			Targets:      []ebtypes.Target{{Id: aws.String(eventWiringTargetID), Arn: aws.String(w.QueueARN)}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if output.FailedEntryCount > 0 && len(output.FailedEntries) > 0 {
+			return nil, fmt.Errorf("failed adding %q rule target: %s", cfg.RuleName, aws.ToString(output.FailedEntries[0].ErrorMessage))
+		}
+		w.Changes = append(w.Changes, "target")
+	}
+
+	// The queue policy.
+	policy, changed, err := queuePolicyWithRule(attrs.Attributes[string(sqstypes.QueueAttributeNamePolicy)], cfg.RuleName, w.QueueARN, w.RuleARN)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if cfg.ValidateOnly {
+			return nil, fmt.Errorf("%w: %q queue policy does not allow %q rule", ErrEventWiring, cfg.QueueName, cfg.RuleName)
+		}
+		if _, err := queue.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+			QueueUrl:   aws.String(w.QueueURL),
+			Attributes: map[string]string{string(sqstypes.QueueAttributeNamePolicy): policy},
+		}); err != nil {
+			return nil, err
+		}
+		w.Changes = append(w.Changes, "queue_policy")
+	}
+	return w, nil
+}
+
+// queuePolicyWithRule returns the queue policy with the statement allowing
+// the rule to send the messages to the queue, and whether the policy
+// changed. The other statements are kept.
+func queuePolicyWithRule(policy, ruleName, queueARN, ruleARN string) (string, bool, error) {
+	doc := map[string]interface{}{"Version": "2012-10-17"}
+	if policy != "" {
+		if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+			return "", false, fmt.Errorf("malformed queue policy: %v", err)
+		}
+	}
+	sid := eventWiringPolicySid + policySidInvalidRgx.ReplaceAllString(ruleName, "")
+	b, _ := json.Marshal(map[string]interface{}{
+		"Sid":       sid,
+		"Effect":    "Allow",
+		"Principal": map[string]interface{}{"Service": "events.amazonaws.com"},
+		"Action":    "sqs:SendMessage",
+		"Resource":  queueARN,
+		"Condition": map[string]interface{}{"ArnEquals": map[string]interface{}{"aws:SourceArn": ruleARN}},
+	})
+	var want interface{}
+	json.Unmarshal(b, &want)
+
+	var statements []interface{}
+	switch v := doc["Statement"].(type) {
+	case []interface{}:
+		statements = v
+	case map[string]interface{}:
+		statements = []interface{}{v}
+	}
+	updated := make([]interface{}, 0, len(statements)+1)
+	for _, statement := range statements {
+		if m, ok := statement.(map[string]interface{}); ok && m["Sid"] == sid {
+			if reflect.DeepEqual(statement, want) {
+				return policy, false, nil
+			}
+			continue
+		}
+		updated = append(updated, statement)
+	}
+	doc["Statement"] = append(updated, want)
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", false, err
+	}
+	return string(b), true, nil
+}
+
+// equalJSON reports whether the JSON documents are equal.
+func equalJSON(a, b string) bool {
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,297 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+const (
+	testWiringQueueARN = "arn:aws:sqs:us-east-1:123456789012:authcrunch-events"
+	testWiringQueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/authcrunch-events"
+	testWiringRuleARN  = "arn:aws:events:us-east-1:123456789012:rule/authcrunch-secrets"
+)
+
+// eventWiringState is the state of the EventBridge rule and SQS queue
+// served by newEventWiringMockClient.
+type eventWiringState struct {
+	queue    bool
+	policy   string
+	pattern  string
+	state    string
+	targets  []string
+	requests []string
+}
+
+func sqsXMLResponse(action, result string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body: ioutil.NopCloser(strings.NewReader(fmt.Sprintf(
+			`<%sResponse><%sResult>%s</%sResult></%sResponse>`, action, action, result, action, action))),
+	}
+}
+
+func newEventWiringMockClient(t *testing.T, state *eventWiringState) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return mockFailure(t, "failed reading request: %v", err)
+		}
+		if target := r.Header.Get("X-Amz-Target"); target != "" {
+			op := strings.TrimPrefix(target, "AWSEvents.")
+			state.requests = append(state.requests, op)
+			var input map[string]interface{}
+			if err := json.Unmarshal(b, &input); err != nil {
+				return mockFailure(t, "malformed %s request: %v", op, err)
+			}
+			switch op {
+			case "DescribeRule":
+				if state.pattern == "" {
+					return secretsmock.ErrorResponse("", http.StatusBadRequest, "ResourceNotFoundException", "rule does not exist"), nil
+				}
+				return secretsmock.JSONResponse(map[string]interface{}{
+					"Arn": testWiringRuleARN, "EventPattern": state.pattern, "State": state.state,
+				}), nil
+			case "PutRule":
+				state.pattern = input["EventPattern"].(string)
+				state.state = input["State"].(string)
+				return secretsmock.JSONResponse(map[string]interface{}{"RuleArn": testWiringRuleARN}), nil
+			case "ListTargetsByRule":
+				var targets []interface{}
+				for _, arn := range state.targets {
+					targets = append(targets, map[string]interface{}{"Id": "other", "Arn": arn})
+				}
+				return secretsmock.JSONResponse(map[string]interface{}{"Targets": targets}), nil
+			case "PutTargets":
+				for _, target := range input["Targets"].([]interface{}) {
+					state.targets = append(state.targets, target.(map[string]interface{})["Arn"].(string))
+				}
+				return secretsmock.JSONResponse(map[string]interface{}{"FailedEntryCount": 0}), nil
+			}
+			return mockFailure(t, "unexpected %s request", op)
+		}
+		form, err := url.ParseQuery(string(b))
+		if err != nil {
+			return mockFailure(t, "malformed %q request: %v", b, err)
+		}
+		action := form.Get("Action")
+		state.requests = append(state.requests, action)
+		switch action {
+		case "GetQueueUrl":
+			if !state.queue {
+				return &http.Response{
+					StatusCode: http.StatusBadRequest,
+					Header:     http.Header{},
+					Body: ioutil.NopCloser(strings.NewReader(`<ErrorResponse><Error><Type>Sender</Type>` +
+						`<Code>AWS.SimpleQueueService.NonExistentQueue</Code><Message>queue does not exist</Message></Error></ErrorResponse>`)),
+				}, nil
+			}
+			return sqsXMLResponse(action, "<QueueUrl>"+testWiringQueueURL+"</QueueUrl>"), nil
+		case "CreateQueue":
+			if form.Get("Attribute.1.Name") != "SqsManagedSseEnabled" || form.Get("Attribute.1.Value") != "true" {
+				return mockFailure(t, "unencrypted queue requested: %v", form)
+			}
+			state.queue = true
+			return sqsXMLResponse(action, "<QueueUrl>"+testWiringQueueURL+"</QueueUrl>"), nil
+		case "GetQueueAttributes":
+			attrs := "<Attribute><Name>QueueArn</Name><Value>" + testWiringQueueARN + "</Value></Attribute>"
+			if state.policy != "" {
+				var value strings.Builder
+				xml.EscapeText(&value, []byte(state.policy))
+				attrs += "<Attribute><Name>Policy</Name><Value>" + value.String() + "</Value></Attribute>"
+			}
+			return sqsXMLResponse(action, attrs), nil
+		case "SetQueueAttributes":
+			state.policy = form.Get("Attribute.1.Value")
+			return sqsXMLResponse(action, ""), nil
+		}
+		return mockFailure(t, "unexpected %q request", b)
+	})
+}
+
+func TestSetupEventWiring(t *testing.T) {
+	otherStatement := `{"Sid":"AllowOther","Effect":"Allow","Principal":"*","Action":"sqs:ReceiveMessage","Resource":"` + testWiringQueueARN + `"}`
+	testcases := []struct {
+		name      string
+		state     *eventWiringState
+		cfg       *EventWiringConfig
+		want      *EventWiring
+		requests  []string
+		statement int
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "create queue, rule, target, and queue policy",
+			state: &eventWiringState{},
+			cfg:   &EventWiringConfig{RuleName: "authcrunch-secrets", QueueName: "authcrunch-events"},
+			want: &EventWiring{
+				RuleARN:  testWiringRuleARN,
+				QueueARN: testWiringQueueARN,
+				QueueURL: testWiringQueueURL,
+				Changes:  []string{"queue", "rule", "target", "queue_policy"},
+			},
+			requests: []string{
+				"GetQueueUrl", "CreateQueue", "GetQueueAttributes", "DescribeRule", "PutRule",
+				"ListTargetsByRule", "PutTargets", "SetQueueAttributes",
+			},
+			statement: 1,
+		},
+		{
+			name: "keep existing queue policy statements",
+			state: &eventWiringState{
+				queue:   true,
+				policy:  `{"Version":"2012-10-17","Statement":[` + otherStatement + `]}`,
+				pattern: secretsManagerEventPattern(),
+				state:   "ENABLED",
+				targets: []string{testWiringQueueARN},
+			},
+			cfg: &EventWiringConfig{RuleName: "authcrunch-secrets", QueueName: "authcrunch-events"},
+			want: &EventWiring{
+				RuleARN:  testWiringRuleARN,
+				QueueARN: testWiringQueueARN,
+				QueueURL: testWiringQueueURL,
+				Changes:  []string{"queue_policy"},
+			},
+			requests: []string{
+				"GetQueueUrl", "GetQueueAttributes", "DescribeRule", "ListTargetsByRule", "SetQueueAttributes",
+			},
+			statement: 2,
+		},
+		{
+			name: "update disabled rule",
+			state: &eventWiringState{
+				queue:   true,
+				pattern: `{"source":["aws.secretsmanager"]}`,
+				state:   "DISABLED",
+				targets: []string{testWiringQueueARN},
+			},
+			cfg: &EventWiringConfig{RuleName: "authcrunch-secrets", QueueName: "authcrunch-events"},
+			want: &EventWiring{
+				RuleARN:  testWiringRuleARN,
+				QueueARN: testWiringQueueARN,
+				QueueURL: testWiringQueueURL,
+				Changes:  []string{"rule", "queue_policy"},
+			},
+			requests: []string{
+				"GetQueueUrl", "GetQueueAttributes", "DescribeRule", "PutRule", "ListTargetsByRule", "SetQueueAttributes",
+			},
+			statement: 1,
+		},
+		{
+			name:      "validate missing queue",
+			state:     &eventWiringState{},
+			cfg:       &EventWiringConfig{RuleName: "authcrunch-secrets", QueueName: "authcrunch-events", ValidateOnly: true},
+			requests:  []string{"GetQueueUrl"},
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q queue not found", ErrEventWiring, "authcrunch-events"),
+		},
+		{
+			name: "validate missing target",
+			state: &eventWiringState{
+				queue:   true,
+				pattern: secretsManagerEventPattern(),
+				state:   "ENABLED",
+			},
+			cfg:       &EventWiringConfig{RuleName: "authcrunch-secrets", QueueName: "authcrunch-events", ValidateOnly: true},
+			requests:  []string{"GetQueueUrl", "GetQueueAttributes", "DescribeRule", "ListTargetsByRule"},
+			shouldErr: true,
+			err: fmt.Errorf("%w: %q queue is not the target of %q rule",
+				ErrEventWiring, "authcrunch-events", "authcrunch-secrets"),
+		},
+		{
+			name:      "malformed queue name",
+			state:     &eventWiringState{},
+			cfg:       &EventWiringConfig{RuleName: "authcrunch-secrets", QueueName: "authcrunch events"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q event queue name", "authcrunch events"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithHTTPClient(newEventWiringMockClient(t, tc.state)),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			got, err := c.SetupEventWiring(context.TODO(), tc.cfg)
+			if diff := cmp.Diff(tc.requests, tc.state.requests); diff != "" {
+				t.Errorf("SetupEventWiring() requests mismatch (-want +got):\n%s", diff)
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				if tc.cfg.ValidateOnly && !errors.Is(err, ErrEventWiring) {
+					t.Fatalf("expected ErrEventWiring, got: %v", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("SetupEventWiring() mismatch (-want +got):\n%s", diff)
+			}
+
+			var policy struct {
+				Statement []map[string]interface{}
+			}
+			if err := json.Unmarshal([]byte(tc.state.policy), &policy); err != nil {
+				t.Fatalf("malformed queue policy: %v", err)
+			}
+			if len(policy.Statement) != tc.statement {
+				t.Fatalf("unexpected queue policy statements: %v", policy.Statement)
+			}
+			statement := policy.Statement[len(policy.Statement)-1]
+			if statement["Sid"] != "AllowEventBridgeRuleauthcrunchsecrets" || statement["Resource"] != testWiringQueueARN {
+				t.Fatalf("unexpected queue policy statement: %v", statement)
+			}
+
+			// The second setup finds everything in place.
+			tc.state.requests = nil
+			got, err = c.SetupEventWiring(context.TODO(), &EventWiringConfig{
+				RuleName: tc.cfg.RuleName, QueueName: tc.cfg.QueueName, ValidateOnly: true,
+			})
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if len(got.Changes) != 0 {
+				t.Fatalf("unexpected changes: %v", got.Changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// ExportFormatDotenv is the format of .env files, i.e. KEY="value"
+	// lines.
+	ExportFormatDotenv = "dotenv"
+	// ExportFormatJSON is the JSON object mapping the paths of the secrets
+	// to their key-value maps.
+	ExportFormatJSON = "json"
+)
+
+var (
+	// ErrUnsafeExport is returned by ExportEnv without WithUnsafeExport.
+	ErrUnsafeExport = errors.New("exporting secrets in plain text is unsafe, acknowledge with WithUnsafeExport")
+
+	envVarInvalidCharsRgx *regexp.Regexp = regexp.MustCompile(`[^A-Z0-9_]+`)
+	dotenvEscaper                        = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+)
+
+// ExportOption configures ExportEnv.
+type ExportOption func(*exportOptions)
+
+type exportOptions struct {
+	unsafe bool
+}
+
+// WithUnsafeExport acknowledges that ExportEnv writes the secret values in
+// plain text, which is acceptable only for non-production credentials.
+func WithUnsafeExport() ExportOption {
+	return func(o *exportOptions) {
+		o.unsafe = true
+	}
+}
+
+// ExportEnv writes the secrets at the paths to the writer in either dotenv
+// or JSON format. In dotenv format, the variable names are made of the
+// path and the key, e.g. USERS_JSMITH_PASSWORD for the password key of
+// users/jsmith secret. It requires WithUnsafeExport.
+func (c *client) ExportEnv(ctx context.Context, paths []string, w io.Writer, format string, opts ...ExportOption) error {
+	return exportEnv(ctx, c, paths, w, format, opts...)
+}
+
+// exportEnv writes the secrets at the paths, retrieved with the client, to
+// the writer.
+func exportEnv(ctx context.Context, c Client, paths []string, w io.Writer, format string, opts ...ExportOption) error {
+	o := &exportOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.unsafe {
+		return ErrUnsafeExport
+	}
+	if format != ExportFormatDotenv && format != ExportFormatJSON {
+		return fmt.Errorf("unsupported %q export format", format)
+	}
+	secrets := make(map[string]map[string]interface{}, len(paths))
+	for _, path := range paths {
+		m, err := c.GetSecret(ctx, path)
+		if err != nil {
+			return err
+		}
+		secrets[path] = m
+	}
+	if format == ExportFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(secrets)
+	}
+	return writeDotenv(w, paths, secrets)
+}
+
+func writeDotenv(w io.Writer, paths []string, secrets map[string]map[string]interface{}) error {
+	names := make(map[string]string)
+	bw := bufio.NewWriter(w)
+	for _, path := range paths {
+		m := secrets[path]
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name := envVarName(path, k)
+			if other, exists := names[name]; exists {
+				return fmt.Errorf("variable %s of %q conflicts with %s", name, path+"#"+k, other)
+			}
+			names[name] = path + "#" + k
+			var value string
+			switch v := m[k].(type) {
+			case string:
+				value = v
+			default:
+				b, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				value = string(b)
+			}
+			if _, err := fmt.Fprintf(bw, "%s=\"%s\"\n", name, dotenvEscaper.Replace(value)); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// envVarName returns the environment variable name for the key of the
+// secret at the path.
+func envVarName(path, key string) string {
+	name := envVarInvalidCharsRgx.ReplaceAllString(strings.ToUpper(path+"_"+key), "_")
+	name = strings.Trim(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportEnv(t *testing.T) {
+	store := map[string]string{
+		"dev/users/jsmith":  `{"username":"jsmith","password":"foo\"bar$baz","roles":["admin"]}`,
+		"dev/access-token":  `{"id":"0","value":"b006d65b"}`,
+		"dev/users_jsmith":  `{"password":"other"}`,
+		"prod/users/jsmith": `{"password":"prod"}`,
+	}
+	testcases := []struct {
+		name      string
+		paths     []string
+		format    string
+		opts      []ExportOption
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test dotenv export",
+			paths:  []string{"dev/users/jsmith", "dev/access-token"},
+			format: ExportFormatDotenv,
+			opts:   []ExportOption{WithUnsafeExport()},
+			want: `DEV_USERS_JSMITH_PASSWORD="foo\"bar\$baz"` + "\n" +
+				`DEV_USERS_JSMITH_ROLES="[\"admin\"]"` + "\n" +
+				`DEV_USERS_JSMITH_USERNAME="jsmith"` + "\n" +
+				`DEV_ACCESS_TOKEN_ID="0"` + "\n" +
+				`DEV_ACCESS_TOKEN_VALUE="b006d65b"` + "\n",
+		},
+		{
+			name:   "test json export",
+			paths:  []string{"dev/access-token"},
+			format: ExportFormatJSON,
+			opts:   []ExportOption{WithUnsafeExport()},
+			want:   "{\n  \"dev/access-token\": {\n    \"id\": \"0\",\n    \"value\": \"b006d65b\"\n  }\n}\n",
+		},
+		{
+			name:      "test export without acknowledgment",
+			paths:     []string{"dev/access-token"},
+			format:    ExportFormatDotenv,
+			shouldErr: true,
+			err:       ErrUnsafeExport,
+		},
+		{
+			name:      "test export with unsupported format",
+			paths:     []string{"dev/access-token"},
+			format:    "yaml",
+			opts:      []ExportOption{WithUnsafeExport()},
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q export format", "yaml"),
+		},
+		{
+			name:      "test dotenv export with conflicting variables",
+			paths:     []string{"dev/users/jsmith", "dev/users_jsmith"},
+			format:    ExportFormatDotenv,
+			opts:      []ExportOption{WithUnsafeExport()},
+			shouldErr: true,
+			err:       fmt.Errorf("variable DEV_USERS_JSMITH_PASSWORD of %q conflicts with %s", "dev/users_jsmith#password", "dev/users/jsmith#password"),
+		},
+		{
+			name:      "test export outside path policy",
+			paths:     []string{"prod/users/jsmith"},
+			format:    ExportFormatJSON,
+			opts:      []ExportOption{WithUnsafeExport()},
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q matches no allow pattern", ErrPathNotAllowed, "prod/users/jsmith"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []string
+			c := newWritesTestClient(t, store, &requests, WithPathPolicy(&PathPolicyConfig{Allow: []string{"regex:^dev/"}}))
+			var buf bytes.Buffer
+			err := c.ExportEnv(context.TODO(), tc.paths, &buf, tc.format, tc.opts...)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Errorf("ExportEnv() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"go.uber.org/zap"
+)
+
+// Factory builds the clients sharing one AWS configuration. The default
+// configuration, i.e. the credentials chain and the region, is resolved
+// once, and the credentials of the assumed roles are cached per role, so
+// that the clients do not repeat the STS and IMDS requests.
+type Factory struct {
+	mu              sync.Mutex
+	serviceConfig   aws.Config
+	regionSource    string
+	roleCredentials map[string]aws.CredentialsProvider
+}
+
+// NewFactory resolves the AWS configuration shared by the clients. The
+// region, HTTP client, credentials provider, and max retries options
+// apply to the shared configuration. The other options are ignored.
+func NewFactory(ctx context.Context, opts ...Option) (*Factory, error) {
+	c := &client{
+		config: &ClientConfig{},
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	cfg := c.config
+	if cfg.Region != "" && !awsRegionRgx.MatchString(cfg.Region) {
+		return nil, fmt.Errorf("malformed %q region", cfg.Region)
+	}
+	if cfg.MaxRetries < 0 {
+		return nil, fmt.Errorf("malformed %d max retries", cfg.MaxRetries)
+	}
+	loadOpts := []func(*config.LoadOptions) error{
+		config.WithRegion(cfg.Region),
+	}
+	if cfg.MaxRetries > 0 {
+		loadOpts = append(loadOpts, config.WithRetryMaxAttempts(cfg.MaxRetries+1))
+	}
+	serviceConfig, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, err
+	}
+	region, regionSource, err := resolveRegion(ctx, cfg, serviceConfig)
+	if err != nil {
+		return nil, err
+	}
+	serviceConfig.Region = region
+	if c.httpClient != nil {
+		serviceConfig.HTTPClient = c.httpClient
+	}
+	if c.credentials != nil {
+		serviceConfig.Credentials = c.credentials
+	}
+	return &Factory{
+		serviceConfig:   serviceConfig,
+		regionSource:    regionSource,
+		roleCredentials: make(map[string]aws.CredentialsProvider),
+	}, nil
+}
+
+// NewClientFromConfig returns the client using the AWS configuration built
+// by the application, e.g. with its own credentials, middleware, and
+// endpoint resolution, instead of the one loaded with LoadDefaultConfig.
+// The region, role, and max retries in the client configuration override
+// the ones of the AWS configuration. When the AWS configuration lacks the
+// region, the region is resolved from the environment or the instance
+// metadata.
+func NewClientFromConfig(ctx context.Context, serviceConfig aws.Config, opts ...Option) (Client, error) {
+	f, err := newFactoryFromConfig(ctx, serviceConfig)
+	if err != nil {
+		return nil, err
+	}
+	return f.NewClient(ctx, opts...)
+}
+
+func newFactoryFromConfig(ctx context.Context, serviceConfig aws.Config) (*Factory, error) {
+	serviceConfig = serviceConfig.Copy()
+	regionSource := RegionSourceAWSConfig
+	if serviceConfig.Region == "" {
+		region, source, err := resolveRegion(ctx, &ClientConfig{}, serviceConfig)
+		if err != nil {
+			return nil, err
+		}
+		serviceConfig.Region, regionSource = region, source
+	}
+	return &Factory{
+		serviceConfig:   serviceConfig,
+		regionSource:    regionSource,
+		roleCredentials: make(map[string]aws.CredentialsProvider),
+	}, nil
+}
+
+// NewClient returns the client using the shared AWS configuration. The
+// region, role, and max retries in the client configuration override the
+// shared ones.
+func (f *Factory) NewClient(ctx context.Context, opts ...Option) (Client, error) {
+	return NewClient(ctx, append([]Option{withFactory(f)}, opts...)...)
+}
+
+// NewClients returns the clients for the configurations, keyed by their
+// IDs.
+func (f *Factory) NewClients(ctx context.Context, cfgs ...*ClientConfig) (map[string]Client, error) {
+	clients := make(map[string]Client)
+	for _, cfg := range cfgs {
+		if cfg == nil {
+			return nil, errors.New("client config is nil")
+		}
+		if _, exists := clients[cfg.ID]; exists {
+			return nil, fmt.Errorf("duplicate %q client id", cfg.ID)
+		}
+		c, err := f.NewClient(ctx, WithConfig(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("failed creating %q client: %v", cfg.ID, err)
+		}
+		clients[cfg.ID] = c
+	}
+	return clients, nil
+}
+
+func withFactory(f *Factory) Option {
+	return func(c *client) error {
+		c.factory = f
+		return nil
+	}
+}
+
+// getServiceConfig returns the copy of the shared AWS configuration, with
+// the region and its source, adjusted for the client configuration.
+func (f *Factory) getServiceConfig(cfg *ClientConfig) (aws.Config, string, string) {
+	serviceConfig := f.serviceConfig.Copy()
+	if cfg.MaxRetries > 0 {
+		serviceConfig.RetryMaxAttempts = cfg.MaxRetries + 1
+	}
+	if cfg.Region != "" {
+		return serviceConfig, cfg.Region, RegionSourceConfig
+	}
+	return serviceConfig, serviceConfig.Region, f.regionSource
+}
+
+// getRoleCredentials returns the cached credentials of the assumed role.
+func (f *Factory) getRoleCredentials(roleARN string) aws.CredentialsProvider {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, exists := f.roleCredentials[roleARN]; exists {
+		return p
+	}
+	p := aws.NewCredentialsCache(
+		stscreds.NewAssumeRoleProvider(sts.NewFromConfig(f.serviceConfig), roleARN),
+	)
+	f.roleCredentials[roleARN] = p
+	return p
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newFactoryMockClient returns HTTP client counting the requests by host
+// and, for STS, by action.
+func newFactoryMockClient(t *testing.T, counts map[string]int) smithyhttp.ClientDoFunc {
+	var mu sync.Mutex
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		var response string
+		if r.Header.Get("X-Amz-Target") == "secretsmanager.GetSecretValue" {
+			counts[r.URL.Host]++
+			response = packMapToJSON(t, map[string]interface{}{
+				"SecretString": packMapToJSON(t, map[string]interface{}{"username": "jsmith"}),
+			})
+		} else {
+			if err := r.ParseForm(); err != nil {
+				return mockFailure(t, "failed parsing request: %v", err)
+			}
+			action := r.PostForm.Get("Action")
+			if action != "AssumeRole" {
+				return mockFailure(t, "unexpected %q action", action)
+			}
+			counts[action+" "+r.PostForm.Get("RoleArn")]++
+			response = "<AssumeRoleResponse><AssumeRoleResult><Credentials>" +
+				"<AccessKeyId>ASIAEXAMPLE</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>" +
+				"<SessionToken>token</SessionToken><Expiration>2099-01-01T00:00:00Z</Expiration>" +
+				"</Credentials></AssumeRoleResult></AssumeRoleResponse>"
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(response)),
+		}, nil
+	})
+}
+
+func TestFactory(t *testing.T) {
+	roleARN := "arn:aws:iam::123456789012:role/AuthCrunch"
+	counts := make(map[string]int)
+	f, err := NewFactory(context.TODO(),
+		WithRegion("us-east-1"),
+		WithHTTPClient(newFactoryMockClient(t, counts)),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during factory initialization: %v", err)
+	}
+	clients, err := f.NewClients(context.TODO(),
+		&ClientConfig{ID: "users", BasePrefix: "authcrunch/users/", RoleARN: roleARN},
+		&ClientConfig{ID: "tokens", BasePrefix: "authcrunch/tokens/", RoleARN: roleARN},
+		&ClientConfig{ID: "eu", Region: "eu-west-1"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during clients initialization: %v", err)
+	}
+
+	got := make(map[string]*Config)
+	for id, c := range clients {
+		if _, err := c.GetSecret(context.TODO(), "jsmith"); err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		cfg := c.GetConfig(context.TODO())
+		got[id] = &Config{Region: cfg.Region, RegionSource: cfg.RegionSource, CredentialSource: cfg.CredentialSource}
+	}
+	want := map[string]*Config{
+		"users":  {Region: "us-east-1", RegionSource: "config", CredentialSource: "assume_role"},
+		"tokens": {Region: "us-east-1", RegionSource: "config", CredentialSource: "assume_role"},
+		"eu":     {Region: "eu-west-1", RegionSource: "config", CredentialSource: "default_chain"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("config mismatch (-want +got):\n%s", diff)
+	}
+	wantCounts := map[string]int{
+		"AssumeRole " + roleARN:                  1,
+		"secretsmanager.us-east-1.amazonaws.com": 2,
+		"secretsmanager.eu-west-1.amazonaws.com": 1,
+	}
+	if diff := cmp.Diff(wantCounts, counts); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	testcases := []struct {
+		name       string
+		opts       []Option
+		wantRegion string
+		wantSource string
+	}{
+		{
+			name:       "test region of aws config",
+			wantRegion: "eu-central-1",
+			wantSource: RegionSourceAWSConfig,
+		},
+		{
+			name:       "test region of client config",
+			opts:       []Option{WithRegion("us-west-2")},
+			wantRegion: "us-west-2",
+			wantSource: RegionSourceConfig,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			counts := make(map[string]int)
+			serviceConfig := aws.Config{
+				Region:      "eu-central-1",
+				Credentials: MockCredentialsProvider{},
+				HTTPClient:  newFactoryMockClient(t, counts),
+			}
+			c, err := NewClientFromConfig(context.TODO(), serviceConfig, append([]Option{WithID("foo")}, tc.opts...)...)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			if _, err := c.GetSecret(context.TODO(), "jsmith"); err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			cfg := c.GetConfig(context.TODO())
+			if cfg.Region != tc.wantRegion || cfg.RegionSource != tc.wantSource {
+				t.Errorf("unexpected region: got %q (%s), want %q (%s)", cfg.Region, cfg.RegionSource, tc.wantRegion, tc.wantSource)
+			}
+			wantCounts := map[string]int{"secretsmanager." + tc.wantRegion + ".amazonaws.com": 1}
+			if diff := cmp.Diff(wantCounts, counts); diff != "" {
+				t.Errorf("requests mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFactoryNewClientsErrors(t *testing.T) {
+	f, err := NewFactory(context.TODO(),
+		WithRegion("us-east-1"),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during factory initialization: %v", err)
+	}
+	testcases := []struct {
+		name string
+		cfgs []*ClientConfig
+		err  error
+	}{
+		{
+			name: "test duplicate client id",
+			cfgs: []*ClientConfig{{ID: "foo"}, {ID: "foo"}},
+			err:  fmt.Errorf("duplicate %q client id", "foo"),
+		},
+		{
+			name: "test malformed client config",
+			cfgs: []*ClientConfig{{ID: "foo", RoleARN: "bar"}},
+			err:  fmt.Errorf("failed creating %q client: %v", "foo", fmt.Errorf("malformed %q role arn", "bar")),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := f.NewClients(context.TODO(), tc.cfgs...)
+			if err == nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+				t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/smithy-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// RegionSourceFallback indicates the region selected from the fallback
+	// regions, because the preferred region was unavailable.
+	RegionSourceFallback = "fallback"
+
+	regionHealthTimeout = 5 * time.Second
+)
+
+// selectHealthyRegion returns the first healthy region among the preferred
+// region and the fallback regions, in order.
+func (c *client) selectHealthyRegion(ctx context.Context, cfg *ClientConfig, serviceConfig aws.Config, region, regionSource string) (string, string, error) {
+	candidates := append([]string{region}, cfg.FallbackRegions...)
+	for i, candidate := range candidates {
+		if i > 0 && candidate == region {
+			continue
+		}
+		err := checkRegionHealth(ctx, cfg, serviceConfig, candidate)
+		if err == nil {
+			if i > 0 {
+				regionSource = RegionSourceFallback
+			}
+			return candidate, regionSource, nil
+		}
+		c.getLogger().Warn("region is unavailable", zap.String("region", candidate), zap.Error(err))
+	}
+	return "", "", fmt.Errorf("no healthy region among %q", candidates)
+}
+
+// checkRegionHealth resolves the endpoint of AWS Secrets Manager in the
+// region and requests the probe secret. Any response from the service,
+// including not found and access denied errors, means the region is
+// healthy.
+func checkRegionHealth(ctx context.Context, cfg *ClientConfig, serviceConfig aws.Config, region string) error {
+	if cfg.Endpoint == "" {
+		endpointResolver := secretsmanager.NewDefaultEndpointResolver()
+		if _, err := endpointResolver.ResolveEndpoint(region, secretsmanager.EndpointResolverOptions{}); err != nil {
+			return fmt.Errorf("endpoint not resolved: %v", err)
+		}
+	}
+	probePath := cfg.ProbePath
+	if probePath == "" {
+		probePath = defaultProbePath
+	}
+	secretName, err := cfg.resolvePath(probePath)
+	if err != nil {
+		return err
+	}
+	serviceClient := secretsmanager.NewFromConfig(serviceConfig, func(o *secretsmanager.Options) {
+		o.Region = region
+		if cfg.Endpoint != "" {
+			o.EndpointResolver = secretsmanager.EndpointResolverFromURL(cfg.Endpoint)
+		}
+		o.Retryer = aws.NopRetryer{}
+	})
+	ctx, cancel := context.WithTimeout(ctx, regionHealthTimeout)
+	defer cancel()
+	_, err = serviceClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretName),
+	})
+	var apiErr smithy.APIError
+	if err == nil || errors.As(err, &apiErr) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// newRegionHealthMockClient returns HTTP client failing the requests to the
+// hosts of unhealthy regions.
+func newRegionHealthMockClient(t *testing.T, unhealthy ...string) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		for _, region := range unhealthy {
+			if strings.Contains(r.URL.Host, "."+region+".") {
+				return nil, errors.New("connection refused")
+			}
+		}
+		return secretsmock.NotFoundResponse("524b9962-6854-4b5c-aa53-81759ef610dd"), nil
+	})
+}
+
+func TestRegionFallback(t *testing.T) {
+	testcases := []struct {
+		name             string
+		region           string
+		fallbackRegions  []string
+		unhealthy        []string
+		wantRegion       string
+		wantRegionSource string
+		shouldErr        bool
+		err              error
+	}{
+		{
+			name:             "test healthy preferred region",
+			region:           "us-east-1",
+			fallbackRegions:  []string{"us-west-2"},
+			wantRegion:       "us-east-1",
+			wantRegionSource: "config",
+		},
+		{
+			name:             "test unhealthy preferred region",
+			region:           "us-east-1",
+			fallbackRegions:  []string{"us-east-2", "us-west-2"},
+			unhealthy:        []string{"us-east-1", "us-east-2"},
+			wantRegion:       "us-west-2",
+			wantRegionSource: "fallback",
+		},
+		{
+			name:             "test fallback region without preferred region",
+			fallbackRegions:  []string{"us-gov-west-1"},
+			wantRegion:       "us-gov-west-1",
+			wantRegionSource: "fallback",
+		},
+		{
+			name:            "test unhealthy regions",
+			region:          "us-east-1",
+			fallbackRegions: []string{"us-west-2"},
+			unhealthy:       []string{"us-east-1", "us-west-2"},
+			shouldErr:       true,
+			err:             fmt.Errorf("no healthy region among %q", []string{"us-east-1", "us-west-2"}),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AWS_REGION", "")
+			t.Setenv("AWS_DEFAULT_REGION", "")
+			t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+			opts := []Option{
+				WithID("foo"),
+				WithFallbackRegions(tc.fallbackRegions...),
+				WithHTTPClient(newRegionHealthMockClient(t, tc.unhealthy...)),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			}
+			if tc.region != "" {
+				opts = append(opts, WithRegion(tc.region))
+			}
+			c, err := NewClient(context.TODO(), opts...)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			got := c.GetConfig(context.TODO())
+			if diff := cmp.Diff(tc.wantRegion, got.Region); diff != "" {
+				t.Errorf("region mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantRegionSource, got.RegionSource); diff != "" {
+				t.Errorf("region source mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
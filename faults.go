@@ -0,0 +1,146 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// FaultConfig is the configuration of the faults injected into the
+// requests to AWS services, for testing the retry and fallback behavior
+// under degraded conditions. The rates are fractions of the requests
+// between 0 and 1.
+type FaultConfig struct {
+	// Latency is the delay added to the requests.
+	Latency time.Duration
+	// LatencyRate is the fraction of the delayed requests.
+	LatencyRate float64
+	// ErrorRate is the fraction of the requests failing with
+	// InternalServiceError.
+	ErrorRate float64
+	// ThrottleRate is the fraction of the requests failing with
+	// ThrottlingException.
+	ThrottleRate float64
+	// Seed seeds the random choice of the faulty requests. When zero, the
+	// current time is used. The source set with WithRandSource takes
+	// precedence over the seed.
+	Seed int64
+}
+
+func (cfg *FaultConfig) validate() error {
+	if cfg.Latency < 0 {
+		return fmt.Errorf("malformed %s fault latency", cfg.Latency)
+	}
+	for name, rate := range map[string]float64{
+		"latency":  cfg.LatencyRate,
+		"error":    cfg.ErrorRate,
+		"throttle": cfg.ThrottleRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("malformed %v fault %s rate", rate, name)
+		}
+	}
+	if cfg.ErrorRate+cfg.ThrottleRate > 1 {
+		return errors.New("fault error and throttle rates exceed 1")
+	}
+	return nil
+}
+
+// faultInjector is the HTTP client injecting faults into the requests
+// sent with the next client.
+type faultInjector struct {
+	cfg  FaultConfig
+	next aws.HTTPClient
+
+	rand *lockedRand
+}
+
+// newFaultInjector returns the injector drawing the faults from the
+// source. When the source is nil, it is seeded with the configured seed.
+func newFaultInjector(cfg *FaultConfig, next aws.HTTPClient, r *lockedRand) *faultInjector {
+	if r == nil {
+		seed := cfg.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		r = newLockedRand(rand.NewSource(seed))
+	}
+	return &faultInjector{
+		cfg:  *cfg,
+		next: next,
+		rand: r,
+	}
+}
+
+// Do implements aws.HTTPClient interface.
+func (f *faultInjector) Do(r *http.Request) (*http.Response, error) {
+	delay := f.cfg.Latency > 0 && f.rand.Float64() < f.cfg.LatencyRate
+	fault := f.rand.Float64()
+
+	if delay {
+		timer := time.NewTimer(f.cfg.Latency)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	switch {
+	case fault < f.cfg.ErrorRate:
+		return newFaultResponse(r, http.StatusInternalServerError, "InternalServiceError", "injected internal service error"), nil
+	case fault < f.cfg.ErrorRate+f.cfg.ThrottleRate:
+		return newFaultResponse(r, http.StatusBadRequest, "ThrottlingException", "Rate exceeded"), nil
+	}
+	return f.next.Do(r)
+}
+
+// newFaultResponse returns the error response in the shape of AWS JSON
+// protocol.
+func newFaultResponse(r *http.Request, statusCode int, code, message string) *http.Response {
+	body, _ := json.Marshal(map[string]string{"__type": code, "message": message})
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-amz-json-1.1")
+	header.Set("X-Amzn-Errortype", code)
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    r,
+	}
+}
+
+// transport returns the HTTP client sending the requests to AWS services,
+// with the faults injected when configured with WithFaultInjection. The
+// caller must hold the lock.
+func (c *client) transport(httpClient aws.HTTPClient) aws.HTTPClient {
+	if c.faults == nil {
+		return httpClient
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return newFaultInjector(c.faults, httpClient, c.rand)
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestFaultConfig(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cfg       *FaultConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test valid fault config",
+			cfg:  &FaultConfig{Latency: time.Second, LatencyRate: 0.5, ErrorRate: 0.1, ThrottleRate: 0.2},
+		},
+		{
+			name:      "test nil fault config",
+			shouldErr: true,
+			err:       errors.New("fault config is nil"),
+		},
+		{
+			name:      "test negative latency",
+			cfg:       &FaultConfig{Latency: -time.Second},
+			shouldErr: true,
+			err:       errors.New("malformed -1s fault latency"),
+		},
+		{
+			name:      "test malformed error rate",
+			cfg:       &FaultConfig{ErrorRate: 1.5},
+			shouldErr: true,
+			err:       errors.New("malformed 1.5 fault error rate"),
+		},
+		{
+			name:      "test rates exceeding one",
+			cfg:       &FaultConfig{ErrorRate: 0.6, ThrottleRate: 0.6},
+			shouldErr: true,
+			err:       errors.New("fault error and throttle rates exceed 1"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithFaultInjection(tc.cfg),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestFaultInjector(t *testing.T) {
+	var requests int
+	next := smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		}, nil
+	})
+	f := newFaultInjector(&FaultConfig{ErrorRate: 0.2, ThrottleRate: 0.3, Seed: 42}, next, nil)
+
+	counts := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		req, _ := http.NewRequest(http.MethodPost, "https://secretsmanager.us-east-1.amazonaws.com/", nil)
+		resp, err := f.Do(req)
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		counts[resp.StatusCode]++
+	}
+	for code, want := range map[int]int{200: 500, 400: 300, 500: 200} {
+		if got := counts[code]; got < want-50 || got > want+50 {
+			t.Errorf("status %d responses: got %d, want about %d", code, got, want)
+		}
+	}
+	if diff := cmp.Diff(counts[200], requests); diff != "" {
+		t.Errorf("forwarded requests mismatch (-want +got):\n%s", diff)
+	}
+
+	f = newFaultInjector(&FaultConfig{Latency: time.Minute, LatencyRate: 1}, next, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://secretsmanager.us-east-1.amazonaws.com/", nil)
+	if _, err := f.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestGetSecretWithFaults(t *testing.T) {
+	srv := secretsmock.NewServer(t)
+	srv.SetSecret("authcrunch/users/jsmith", packMapToJSON(t, map[string]interface{}{"username": "jsmith"}))
+
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithEndpoint(srv.URL),
+		WithFaultInjection(&FaultConfig{Latency: 20 * time.Millisecond, LatencyRate: 1}),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	start := time.Now()
+	if _, err := c.GetSecret(context.TODO(), "authcrunch/users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected injected latency, got %s", elapsed)
+	}
+
+	c, err = NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithEndpoint(srv.URL),
+		WithFaultInjection(&FaultConfig{ThrottleRate: 1}),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetSecret(ctx, "authcrunch/users/jsmith"); err == nil {
+		t.Fatalf("expected throttling error, got success")
+	}
+	if diff := cmp.Diff(1, len(srv.Requests())); diff != "" {
+		t.Errorf("requests reaching the server mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+)
+
+// ErrSecretUnchanged is returned by ReportInvalid when the current version
+// of the secret is the one reported invalid and no other version may be
+// tried.
+var ErrSecretUnchanged = errors.New("secret reported invalid is unchanged")
+
+// InvalidOption configures ReportInvalid.
+type InvalidOption func(*invalidOptions)
+
+type invalidOptions struct {
+	tryPrevious bool
+}
+
+// WithTryPrevious makes ReportInvalid return the previous version of the
+// secret, when the current one is unchanged, e.g. when the downstream
+// system did not pick up the rotated credentials yet.
+func WithTryPrevious() InvalidOption {
+	return func(o *invalidOptions) {
+		o.tryPrevious = true
+	}
+}
+
+// ReportInvalid tells the client that a downstream system rejected the
+// credentials of the secret at the path. The client invalidates the cached
+// versions of the secret and fetches the current version again. It returns
+// the fetched version, when it differs from the cached one or when the
+// secret was not cached. Otherwise, it returns the previous version with
+// WithTryPrevious, or ErrSecretUnchanged.
+func (c *client) ReportInvalid(ctx context.Context, path string, opts ...InvalidOption) (map[string]interface{}, error) {
+	o := &invalidOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	cached, found := c.getCache().get(cacheKey{path: path, stage: versionStageCurrent})
+	c.InvalidateCache(path)
+	c.getLogger().Info("secret reported invalid", zap.String("path", path), zap.Bool("cached", found))
+
+	m, err := c.fetchSecret(ctx, &secretRequest{path: path, stage: versionStageCurrent, skipCache: true})
+	if err != nil {
+		return nil, err
+	}
+	if !found || !reflect.DeepEqual(m, cached) {
+		return m, nil
+	}
+	if !o.tryPrevious {
+		return nil, fmt.Errorf("%w: %q", ErrSecretUnchanged, path)
+	}
+	previous, err := c.fetchSecret(ctx, &secretRequest{path: path, stage: versionStagePrevious, skipCache: true})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %q has no previous version", ErrSecretUnchanged, path)
+		}
+		return nil, err
+	}
+	return previous, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReportInvalid(t *testing.T) {
+	path := "authcrunch/ldap"
+	stages := map[string]map[string]interface{}{
+		"AWSCURRENT": {"bind_password": "foo"},
+	}
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"), WithCacheTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	c.SetMockClient(newStagedMockClient(t, stages))
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+	if _, err := c.GetSecret(context.TODO(), path); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	// The current version is unchanged.
+	if _, err := c.ReportInvalid(context.TODO(), path); !errors.Is(err, ErrSecretUnchanged) {
+		t.Fatalf("expected ErrSecretUnchanged, got: %v", err)
+	}
+	if _, err := c.ReportInvalid(context.TODO(), path, WithTryPrevious()); !errors.Is(err, ErrSecretUnchanged) {
+		t.Fatalf("expected ErrSecretUnchanged, got: %v", err)
+	}
+	stages["AWSPREVIOUS"] = map[string]interface{}{"bind_password": "bar"}
+	got, err := c.ReportInvalid(context.TODO(), path, WithTryPrevious())
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"bind_password": "bar"}, got); diff != "" {
+		t.Errorf("ReportInvalid() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The secret was rotated after it was cached.
+	stages["AWSCURRENT"] = map[string]interface{}{"bind_password": "baz"}
+	got, err = c.ReportInvalid(context.TODO(), path)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"bind_password": "baz"}, got); diff != "" {
+		t.Errorf("ReportInvalid() mismatch (-want +got):\n%s", diff)
+	}
+	got, err = c.GetSecret(context.TODO(), path)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"bind_password": "baz"}, got); diff != "" {
+		t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileProvider is the provider reading the secrets from local files
+// instead of AWS Secrets Manager.
+const fileProvider = "file"
+
+func init() {
+	if err := RegisterBackend(fileProvider, newFileBackend); err != nil {
+		panic(err)
+	}
+}
+
+// fileBackend serves the secrets stored in local files. The path is either
+// a directory of JSON files, one per secret, e.g. "users/jsmith.json" for
+// the secret named "users/jsmith", or a single YAML or JSON document
+// mapping the names of the secrets to their key-value maps. The files are
+// read on every request, so the changes apply without restarts. The
+// secrets are read-only and have the current version only.
+type fileBackend struct {
+	path string
+}
+
+func newFileBackend(_ context.Context, cfg *ClientConfig) (Backend, error) {
+	if cfg.FilePath == "" {
+		return nil, fmt.Errorf("file path of %q provider is empty", fileProvider)
+	}
+	if _, err := os.Stat(cfg.FilePath); err != nil {
+		return nil, err
+	}
+	return &fileBackend{path: cfg.FilePath}, nil
+}
+
+func (fb *fileBackend) notFound(name string) error {
+	return fmt.Errorf("%w: %q in %q", ErrSecretNotFound, name, fb.path)
+}
+
+func (fb *fileBackend) readOnly(op string) error {
+	return fmt.Errorf("%w: %s with %q provider", ErrReadOnly, op, fileProvider)
+}
+
+// isDir returns true when the path is a directory of JSON files.
+func (fb *fileBackend) isDir() (bool, error) {
+	info, err := os.Stat(fb.path)
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// readDocument returns the key-value maps of the secrets in the document.
+func (fb *fileBackend) readDocument() (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(fb.path)
+	if err != nil {
+		return nil, err
+	}
+	var secrets map[string]interface{}
+	switch strings.ToLower(filepath.Ext(fb.path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &secrets)
+	case ".json":
+		err = json.Unmarshal(b, &secrets)
+	default:
+		return nil, fmt.Errorf("unsupported %q secrets file extension", filepath.Ext(fb.path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q secrets file: %v", fb.path, err)
+	}
+	return secrets, nil
+}
+
+// readSecret returns the JSON-encoded secret with the name.
+func (fb *fileBackend) readSecret(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", fb.notFound(name)
+	}
+	dir, err := fb.isDir()
+	if err != nil {
+		return "", err
+	}
+	if dir {
+		b, err := ioutil.ReadFile(filepath.Join(fb.path, filepath.FromSlash(name)+".json"))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return "", fb.notFound(name)
+			}
+			return "", err
+		}
+		return string(b), nil
+	}
+	secrets, err := fb.readDocument()
+	if err != nil {
+		return "", err
+	}
+	m, found := secrets[name]
+	if !found {
+		return "", fb.notFound(name)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("malformed %q secret in %q secrets file: %v", name, fb.path, err)
+	}
+	return string(b), nil
+}
+
+// listNames returns the sorted names of the secrets.
+func (fb *fileBackend) listNames() ([]string, error) {
+	dir, err := fb.isDir()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if dir {
+		err := filepath.WalkDir(fb.path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(p) != ".json" {
+				return nil
+			}
+			rel, err := filepath.Rel(fb.path, p)
+			if err != nil {
+				return err
+			}
+			names = append(names, strings.TrimSuffix(filepath.ToSlash(rel), ".json"))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		secrets, err := fb.readDocument()
+		if err != nil {
+			return nil, err
+		}
+		for name := range secrets {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetSecretString implements Backend.
+func (fb *fileBackend) GetSecretString(_ context.Context, name, versionID, stage string) (string, error) {
+	if versionID != "" {
+		return "", fmt.Errorf("%w: %q version of %q", ErrVersionIDNotSupported, versionID, name)
+	}
+	if stage != "" && stage != versionStageCurrent {
+		return "", fb.notFound(name)
+	}
+	return fb.readSecret(name)
+}
+
+// DescribeSecret implements Backend.
+func (fb *fileBackend) DescribeSecret(_ context.Context, name string) (*SecretMetadata, error) {
+	if _, err := fb.readSecret(name); err != nil {
+		return nil, err
+	}
+	return &SecretMetadata{}, nil
+}
+
+// ListSecrets implements Backend.
+func (fb *fileBackend) ListSecrets(_ context.Context, prefix string) ([]string, error) {
+	names, err := fb.listNames()
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matched = append(matched, name)
+		}
+	}
+	return matched, nil
+}
+
+// CreateSecret implements Backend. It returns ErrReadOnly.
+func (fb *fileBackend) CreateSecret(context.Context, string, string) error {
+	return fb.readOnly("create")
+}
+
+// PutSecretValue implements Backend. It returns ErrReadOnly.
+func (fb *fileBackend) PutSecretValue(context.Context, string, string, []string) error {
+	return fb.readOnly("put")
+}
+
+// DeleteSecret implements Backend. It returns ErrReadOnly.
+func (fb *fileBackend) DeleteSecret(context.Context, string) error {
+	return fb.readOnly("delete")
+}
+
+// RotateSecret implements Backend. It returns ErrReadOnly.
+func (fb *fileBackend) RotateSecret(context.Context, string) error {
+	return fb.readOnly("rotate")
+}
+
+var _ Backend = (*fileBackend)(nil)
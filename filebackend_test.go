@@ -0,0 +1,112 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("failed creating directory: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed writing file: %v", err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "secrets", "authcrunch", "users", "jsmith.json"), `{"username":"jsmith","password":"secret"}`)
+	writeTestFile(t, filepath.Join(dir, "secrets", "authcrunch", "users", "mjones.json"), `{"username":"mjones","password":"secret"}`)
+	writeTestFile(t, filepath.Join(dir, "secrets", "authcrunch", "README.md"), `not a secret`)
+	writeTestFile(t, filepath.Join(dir, "secrets.yaml"), `
+authcrunch/users/jsmith:
+  username: jsmith
+  password: secret
+authcrunch/users/mjones:
+  username: mjones
+  password: secret
+`)
+
+	testcases := []struct {
+		name string
+		path string
+	}{
+		{name: "test directory of json files", path: filepath.Join(dir, "secrets")},
+		{name: "test yaml document", path: filepath.Join(dir, "secrets.yaml")},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithBasePrefix("authcrunch/"),
+				WithFileProvider(tc.path),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			ctx := context.TODO()
+
+			got, err := c.GetSecret(ctx, "users/jsmith")
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(map[string]interface{}{"username": "jsmith", "password": "secret"}, got); diff != "" {
+				t.Fatalf("GetSecret() mismatch (-want +got):\n%s", diff)
+			}
+			v, err := c.GetSecretByKey(ctx, "users/mjones", "username")
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if v != "mjones" {
+				t.Fatalf("unexpected %v username", v)
+			}
+			if _, err := c.GetSecret(ctx, "users/admin"); !isNotFound(err) {
+				t.Fatalf("expected not found error, got: %v", err)
+			}
+			if _, err := c.GetSecret(ctx, "users/../../jsmith"); !isNotFound(err) {
+				t.Fatalf("expected not found error, got: %v", err)
+			}
+			paths, err := c.ListSecrets(ctx, "users/")
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff([]string{"users/jsmith", "users/mjones"}, paths); diff != "" {
+				t.Fatalf("ListSecrets() mismatch (-want +got):\n%s", diff)
+			}
+			if _, err := c.CreateSecret(ctx, "users/admin", map[string]interface{}{"username": "admin"}); !errors.Is(err, ErrReadOnly) {
+				t.Fatalf("expected ErrReadOnly, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestFileProviderConfig(t *testing.T) {
+	if _, err := NewClient(context.TODO(), WithID("foo"), WithFileProvider("")); err == nil {
+		t.Fatalf("unexpected success with empty file path")
+	}
+	if _, err := NewClient(context.TODO(), WithID("foo"), WithFileProvider(filepath.Join(t.TempDir(), "missing"))); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected os.ErrNotExist, got: %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+//go:generate protoc -I proto --go_out=proto --go_opt=paths=source_relative --go-grpc_out=proto --go-grpc_opt=paths=source_relative proto/authcrunch/secrets/v1/secrets.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	secretsv1 "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/proto/authcrunch/secrets/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcProvider is the provider reading the secrets from the gRPC server
+// instead of AWS Secrets Manager.
+const grpcProvider = "grpc"
+
+func init() {
+	if err := RegisterBackend(grpcProvider, newGRPCBackend); err != nil {
+		panic(err)
+	}
+}
+
+// grpcServer serves the secrets of the client over gRPC.
+type grpcServer struct {
+	secretsv1.UnimplementedSecretsServer
+	h *proxyHandler
+}
+
+// NewGRPCServer returns the implementation of the Secrets gRPC service
+// described in proto/authcrunch/secrets/v1/secrets.proto, so that the
+// components written in other languages reuse the caching, the policies,
+// and the failover of the client. It accepts the options of
+// NewProxyHandler, shares the concurrent requests for the same secret
+// the same way, and identifies the clients of WithProxyClientPolicies by
+// their verified TLS certificates, e.g.
+//
+//	tlsConfig, err := secrets.NewProxyTLSConfig(certFile, keyFile, clientCAFile)
+//	...
+//	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+//	secretsv1.RegisterSecretsServer(s, secrets.NewGRPCServer(c, opts...))
+//
+// The Go processes use the "grpc" provider, see WithGRPCProvider.
+func NewGRPCServer(c Client, opts ...ProxyOption) secretsv1.SecretsServer {
+	return &grpcServer{h: newProxyHandler(c, opts...)}
+}
+
+// clientPolicy returns the path policy of the client of the call,
+// identified by the TLS state of its connection.
+func (s *grpcServer) clientPolicy(ctx context.Context) (*PathPolicyConfig, error) {
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+	}
+	return s.h.clientPolicy(state)
+}
+
+// check returns the status error of the call when the server does not
+// serve the secret at the path to its client.
+func (s *grpcServer) check(ctx context.Context, secretPath string) error {
+	clientPolicy, err := s.clientPolicy(ctx)
+	if err == nil {
+		err = s.h.check(clientPolicy, secretPath)
+	}
+	if err != nil {
+		return grpcStatus(err)
+	}
+	return nil
+}
+
+// GetSecret implements secretsv1.SecretsServer.
+func (s *grpcServer) GetSecret(ctx context.Context, req *secretsv1.GetSecretRequest) (*secretsv1.GetSecretResponse, error) {
+	if err := s.check(ctx, req.GetPath()); err != nil {
+		return nil, err
+	}
+	m, err := s.h.getSecret(ctx, req.GetPath(), req.GetStage())
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	secret, err := structpb.NewStruct(m)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "malformed %q secret: %v", req.GetPath(), err)
+	}
+	return &secretsv1.GetSecretResponse{Secret: secret}, nil
+}
+
+// DescribeSecret implements secretsv1.SecretsServer.
+func (s *grpcServer) DescribeSecret(ctx context.Context, req *secretsv1.DescribeSecretRequest) (*secretsv1.SecretMetadata, error) {
+	if err := s.check(ctx, req.GetPath()); err != nil {
+		return nil, err
+	}
+	m, err := s.h.c.DescribeSecret(ctx, req.GetPath())
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	return &secretsv1.SecretMetadata{
+		Path:               m.Path,
+		Arn:                m.ARN,
+		Description:        m.Description,
+		KmsKeyId:           m.KMSKeyID,
+		RotationEnabled:    m.RotationEnabled,
+		CreatedDate:        newTimestamp(m.CreatedDate),
+		LastChangedDate:    newTimestamp(m.LastChangedDate),
+		LastRotatedDate:    newTimestamp(m.LastRotatedDate),
+		DeletedDate:        newTimestamp(m.DeletedDate),
+		Tags:               m.Tags,
+		VersionIdsToStages: newVersionStages(m.VersionIdsToStages),
+		NextRotationDate:   newTimestamp(m.NextRotationDate),
+	}, nil
+}
+
+// ListSecrets implements secretsv1.SecretsServer. The paths not served to
+// the client of the call are omitted.
+func (s *grpcServer) ListSecrets(ctx context.Context, req *secretsv1.ListSecretsRequest) (*secretsv1.ListSecretsResponse, error) {
+	clientPolicy, err := s.clientPolicy(ctx)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	paths, err := s.h.c.ListSecrets(ctx, req.GetPrefix())
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	resp := &secretsv1.ListSecretsResponse{}
+	for _, secretPath := range paths {
+		if s.h.check(clientPolicy, secretPath) == nil {
+			resp.Paths = append(resp.Paths, secretPath)
+		}
+	}
+	return resp, nil
+}
+
+// grpcStatus returns the gRPC status error of the error.
+func grpcStatus(err error) error {
+	switch {
+	case isNotFound(err):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrPathNotAllowed), errors.Is(err, ErrTagDenied), errors.Is(err, ErrAccessBlocked):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+func newTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timestampTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func newVersionStages(m map[string][]string) map[string]*secretsv1.VersionStages {
+	if len(m) == 0 {
+		return nil
+	}
+	stages := make(map[string]*secretsv1.VersionStages, len(m))
+	for versionID, v := range m {
+		stages[versionID] = &secretsv1.VersionStages{Stages: v}
+	}
+	return stages
+}
+
+func versionStages(stages map[string]*secretsv1.VersionStages) map[string][]string {
+	if len(stages) == 0 {
+		return nil
+	}
+	m := make(map[string][]string, len(stages))
+	for versionID, v := range stages {
+		m[versionID] = v.GetStages()
+	}
+	return m
+}
+
+// grpcBackend reads the secrets from the gRPC server, see NewGRPCServer.
+// The names of the secrets are the paths served by the server, so the
+// clients of the "grpc" provider usually have no base prefix. The secrets
+// are read-only.
+type grpcBackend struct {
+	conn   *grpc.ClientConn
+	client secretsv1.SecretsClient
+	// mu is held for reading by the calls in flight, so that Close lets
+	// them complete before closing the connection.
+	mu sync.RWMutex
+}
+
+func newGRPCBackend(_ context.Context, cfg *ClientConfig) (Backend, error) {
+	if cfg.ProxyURL == "" {
+		return nil, fmt.Errorf("proxy url of %q provider is empty", grpcProvider)
+	}
+	// The server requires mutual TLS, see the Secrets service.
+	if cfg.ProxyTLS == nil {
+		return nil, fmt.Errorf("proxy tls of %q provider is empty", grpcProvider)
+	}
+	tlsConfig, err := cfg.ProxyTLS.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	// The connection is established lazily, on the first call.
+	conn, err := grpc.Dial(cfg.ProxyURL, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q grpc target: %v", cfg.ProxyURL, err)
+	}
+	return &grpcBackend{conn: conn, client: secretsv1.NewSecretsClient(conn)}, nil
+}
+
+// Close closes the connection to the gRPC server once the calls in flight
+// complete.
+func (gb *grpcBackend) Close() error {
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
+	return gb.conn.Close()
+}
+
+// grpcError returns the error of the status error of the gRPC server.
+func grpcError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.NotFound:
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, st.Message())
+	case codes.PermissionDenied:
+		return fmt.Errorf("%w: %s", ErrPathNotAllowed, st.Message())
+	case codes.Canceled:
+		return fmt.Errorf("%w: %s", context.Canceled, st.Message())
+	case codes.DeadlineExceeded:
+		return fmt.Errorf("%w: %s", context.DeadlineExceeded, st.Message())
+	}
+	return fmt.Errorf("grpc error: %s", st.Message())
+}
+
+func (gb *grpcBackend) GetSecretString(ctx context.Context, name, versionID, stage string) (string, error) {
+	gb.mu.RLock()
+	defer gb.mu.RUnlock()
+	if versionID != "" {
+		return "", fmt.Errorf("%w: %q version of %q", ErrVersionIDNotSupported, versionID, name)
+	}
+	req := &secretsv1.GetSecretRequest{Path: name}
+	if stage != versionStageCurrent {
+		req.Stage = stage
+	}
+	resp, err := gb.client.GetSecret(ctx, req)
+	if err != nil {
+		return "", grpcError(err)
+	}
+	b, err := json.Marshal(resp.GetSecret().AsMap())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (gb *grpcBackend) DescribeSecret(ctx context.Context, name string) (*SecretMetadata, error) {
+	gb.mu.RLock()
+	defer gb.mu.RUnlock()
+	resp, err := gb.client.DescribeSecret(ctx, &secretsv1.DescribeSecretRequest{Path: name})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &SecretMetadata{
+		Path:               resp.GetPath(),
+		ARN:                resp.GetArn(),
+		Description:        resp.GetDescription(),
+		KMSKeyID:           resp.GetKmsKeyId(),
+		RotationEnabled:    resp.GetRotationEnabled(),
+		CreatedDate:        timestampTime(resp.GetCreatedDate()),
+		LastChangedDate:    timestampTime(resp.GetLastChangedDate()),
+		LastRotatedDate:    timestampTime(resp.GetLastRotatedDate()),
+		DeletedDate:        timestampTime(resp.GetDeletedDate()),
+		Tags:               resp.GetTags(),
+		VersionIdsToStages: versionStages(resp.GetVersionIdsToStages()),
+		NextRotationDate:   timestampTime(resp.GetNextRotationDate()),
+	}, nil
+}
+
+func (gb *grpcBackend) ListSecrets(ctx context.Context, prefix string) ([]string, error) {
+	gb.mu.RLock()
+	defer gb.mu.RUnlock()
+	resp, err := gb.client.ListSecrets(ctx, &secretsv1.ListSecretsRequest{Prefix: prefix})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.GetPaths(), nil
+}
+
+func (gb *grpcBackend) readOnly(op string) error {
+	return fmt.Errorf("%w: %s with %q provider", ErrReadOnly, op, grpcProvider)
+}
+
+func (gb *grpcBackend) CreateSecret(context.Context, string, string) error {
+	return gb.readOnly("create")
+}
+
+func (gb *grpcBackend) PutSecretValue(context.Context, string, string, []string) error {
+	return gb.readOnly("put")
+}
+
+func (gb *grpcBackend) DeleteSecret(context.Context, string) error {
+	return gb.readOnly("delete")
+}
+
+func (gb *grpcBackend) RotateSecret(context.Context, string) error {
+	return gb.readOnly("rotate")
+}
@@ -0,0 +1,209 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	secretsv1 "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/proto/authcrunch/secrets/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// newGRPCTestServer serves the secrets of the upstream client over gRPC
+// with mutual TLS on the loopback interface and returns its target. The
+// certificates of the server and of the clients are written to the
+// directory, see grpcTestClientTLS.
+func newGRPCTestServer(t *testing.T, upstream Client, dir string, clients []string, opts ...ProxyOption) string {
+	t.Helper()
+	ca, caKey := writeTestCertificate(t, dir, "ca", nil, nil)
+	writeTestCertificate(t, dir, "proxy", ca, caKey)
+	for _, name := range clients {
+		writeTestCertificate(t, dir, name, ca, caKey)
+	}
+	tlsConfig, err := NewProxyTLSConfig(filepath.Join(dir, "proxy.pem"), filepath.Join(dir, "proxy-key.pem"), filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatalf("failed creating tls config: %v", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed listening: %v", err)
+	}
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	secretsv1.RegisterSecretsServer(s, NewGRPCServer(upstream, opts...))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// grpcTestClientTLS returns the client certificate of the client written
+// to the directory by newGRPCTestServer.
+func grpcTestClientTLS(dir, client string) *ProxyTLSConfig {
+	return &ProxyTLSConfig{
+		CertFile: filepath.Join(dir, client+".pem"),
+		KeyFile:  filepath.Join(dir, client+"-key.pem"),
+		CAFile:   filepath.Join(dir, "ca.pem"),
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	dir := t.TempDir()
+	target := newGRPCTestServer(t, newProxyTestUpstream(t), dir, []string{"app"})
+
+	c, err := NewClient(context.TODO(), WithID("bar"), WithGRPCProvider(target), WithProxyTLS(grpcTestClientTLS(dir, "app")))
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	ctx := context.TODO()
+
+	got, err := c.GetSecret(ctx, "users/jsmith")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"username": "jsmith", "email": "jsmith@localhost"}, got); diff != "" {
+		t.Fatalf("GetSecret() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := c.GetSecret(ctx, "users/mjones"); !isNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "admin/root"); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+	}
+	m, err := c.DescribeSecret(ctx, "users/jsmith")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if m.Path != "users/jsmith" {
+		t.Fatalf("unexpected %q path, want: %q", m.Path, "users/jsmith")
+	}
+	paths, err := c.ListSecrets(ctx, "users/")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"users/jsmith"}, paths); diff != "" {
+		t.Fatalf("ListSecrets() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := c.CreateSecret(ctx, "users/mjones", map[string]interface{}{"username": "mjones"}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got: %v", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := c.ListSecrets(ctx, "users/"); err == nil {
+		t.Fatalf("unexpected success after the connection was closed")
+	}
+}
+
+func TestGRPCProviderConfig(t *testing.T) {
+	testcases := []struct {
+		name string
+		opts []Option
+		err  error
+	}{
+		{
+			name: "test empty proxy url",
+			opts: []Option{WithGRPCProvider(""), WithProxyTLS(grpcTestClientTLS("", "app"))},
+			err:  fmt.Errorf("proxy url of %q provider is empty", "grpc"),
+		},
+		{
+			name: "test missing client certificate",
+			opts: []Option{WithGRPCProvider("127.0.0.1:8201")},
+			err:  fmt.Errorf("proxy tls of %q provider is empty", "grpc"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewClient(context.TODO(), append([]Option{WithID("foo")}, tc.opts...)...)
+			if err == nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+				t.Errorf("unexpected error (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGRPCMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	target := newGRPCTestServer(t, newProxyTestUpstream(t), dir, []string{"app", "other"},
+		WithProxyClientPolicies(map[string]*PathPolicyConfig{
+			"app":   {Allow: []string{"users/*"}},
+			"other": {Allow: []string{"apps/*"}},
+		}),
+	)
+
+	testcases := []struct {
+		name      string
+		client    string
+		want      map[string]interface{}
+		wantPaths []string
+		err       error
+	}{
+		{
+			name:      "test allowed client",
+			client:    "app",
+			want:      map[string]interface{}{"username": "jsmith", "email": "jsmith@localhost"},
+			wantPaths: []string{"users/jsmith"},
+		},
+		{
+			name:   "test client not allowed the path",
+			client: "other",
+			err:    ErrPathNotAllowed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(),
+				WithID("bar"),
+				WithGRPCProvider(target),
+				WithProxyTLS(grpcTestClientTLS(dir, tc.client)),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			paths, err := c.ListSecrets(context.TODO(), "")
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantPaths, paths); diff != "" {
+				t.Errorf("ListSecrets() mismatch (-want +got):\n%s", diff)
+			}
+			got, err := c.GetSecret(context.TODO(), "users/jsmith")
+			if err != nil {
+				if tc.err == nil {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.err != nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("GetSecret() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"net/url"
+)
+
+const (
+	// CredentialSourceDefault indicates the default AWS credentials chain.
+	CredentialSourceDefault = "default_chain"
+	// CredentialSourceAssumeRole indicates the credentials of the assumed role.
+	CredentialSourceAssumeRole = "assume_role"
+	// CredentialSourceCustom indicates the credentials provider set with
+	// WithCredentialsProvider or SetMockCredentialsProvider.
+	CredentialSourceCustom = "custom"
+)
+
+// Config is the effective configuration of the client. It is safe to log,
+// because the sensitive values are redacted.
+type Config struct {
+	ID               string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Provider         string `json:"provider,omitempty" xml:"provider,omitempty" yaml:"provider,omitempty"`
+	Region           string `json:"region,omitempty" xml:"region,omitempty" yaml:"region,omitempty"`
+	RegionSource     string `json:"region_source,omitempty" xml:"region_source,omitempty" yaml:"region_source,omitempty"`
+	Endpoint         string `json:"endpoint,omitempty" xml:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	RoleARN          string `json:"role_arn,omitempty" xml:"role_arn,omitempty" yaml:"role_arn,omitempty"`
+	BasePrefix       string `json:"base_prefix,omitempty" xml:"base_prefix,omitempty" yaml:"base_prefix,omitempty"`
+	ReadOnly         bool   `json:"read_only,omitempty" xml:"read_only,omitempty" yaml:"read_only,omitempty"`
+	DryRun           bool   `json:"dry_run,omitempty" xml:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+	CacheEnabled     bool   `json:"cache_enabled,omitempty" xml:"cache_enabled,omitempty" yaml:"cache_enabled,omitempty"`
+	CacheTTL         string `json:"cache_ttl,omitempty" xml:"cache_ttl,omitempty" yaml:"cache_ttl,omitempty"`
+	DefaultTimeout   string `json:"default_timeout,omitempty" xml:"default_timeout,omitempty" yaml:"default_timeout,omitempty"`
+	MaxRetries       int    `json:"max_retries,omitempty" xml:"max_retries,omitempty" yaml:"max_retries,omitempty"`
+	CredentialSource string `json:"credential_source,omitempty" xml:"credential_source,omitempty" yaml:"credential_source,omitempty"`
+}
+
+// Map returns the id, region, and provider of the configuration as the
+// key-value map, the way GetConfig returned them before Config existed.
+func (cfg *Config) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       cfg.ID,
+		"region":   cfg.Region,
+		"provider": cfg.Provider,
+	}
+}
+
+// GetConfig returns the effective client configuration.
+func (c *client) GetConfig(_ context.Context) *Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cfg := &Config{
+		ID:               c.config.ID,
+		Provider:         c.config.Provider,
+		Region:           c.serviceConfig.Region,
+		RegionSource:     c.regionSource,
+		Endpoint:         redactURL(c.config.Endpoint),
+		RoleARN:          c.config.RoleARN,
+		BasePrefix:       c.config.BasePrefix,
+		ReadOnly:         c.config.ReadOnly,
+		DryRun:           c.config.DryRun,
+		CacheEnabled:     c.cache != nil,
+		CacheTTL:         c.config.CacheTTL,
+		DefaultTimeout:   c.config.DefaultTimeout,
+		MaxRetries:       c.config.MaxRetries,
+		CredentialSource: CredentialSourceDefault,
+	}
+	switch {
+	case c.credentials != nil:
+		cfg.CredentialSource = CredentialSourceCustom
+	case c.config.RoleARN != "":
+		cfg.CredentialSource = CredentialSourceAssumeRole
+	}
+	return cfg
+}
+
+// redactURL removes the credentials and the query, which may carry
+// signatures or tokens, from the URL.
+func redactURL(s string) string {
+	if s == "" {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "REDACTED"
+	}
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "REDACTED"
+	}
+	return u.String()
+}
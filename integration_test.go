@@ -0,0 +1,173 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+// +build integration
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/smithy-go"
+	"github.com/google/go-cmp/cmp"
+)
+
+// The integration tests run against LocalStack or an existing AWS Secrets
+// Manager endpoint, e.g.
+//
+//	docker run -d -p 4566:4566 localstack/localstack
+//	AWS_SECRETS_INTEGRATION=1 go test -tags integration -run Integration ./...
+//
+// The endpoint, the region, and the credentials are taken from the
+// following environment variables.
+const (
+	integrationEnvEnabled  = "AWS_SECRETS_INTEGRATION"
+	integrationEnvEndpoint = "AWS_SECRETS_INTEGRATION_ENDPOINT"
+	integrationEnvRegion   = "AWS_SECRETS_INTEGRATION_REGION"
+	integrationEnvStatic   = "AWS_SECRETS_INTEGRATION_STATIC_CREDENTIALS"
+
+	integrationDefaultEndpoint = "http://localhost:4566"
+	integrationDefaultRegion   = "us-east-1"
+)
+
+// integrationSignatureErrors are the error codes indicating that the
+// request signing is broken.
+var integrationSignatureErrors = map[string]bool{
+	"IncompleteSignature":         true,
+	"InvalidSignatureException":   true,
+	"SignatureDoesNotMatch":       true,
+	"UnrecognizedClientException": true,
+}
+
+// newIntegrationClient returns the client talking to the integration
+// endpoint. It skips the test unless the integration tests are enabled.
+// An empty endpoint makes the client use the default AWS endpoint with
+// the default credentials.
+func newIntegrationClient(t *testing.T, prefix string) Client {
+	t.Helper()
+	if os.Getenv(integrationEnvEnabled) == "" {
+		t.Skipf("set %s to run the integration tests", integrationEnvEnabled)
+	}
+	endpoint, found := os.LookupEnv(integrationEnvEndpoint)
+	if !found {
+		endpoint = integrationDefaultEndpoint
+	}
+	region := os.Getenv(integrationEnvRegion)
+	if region == "" {
+		region = integrationDefaultRegion
+	}
+	opts := []Option{
+		WithID("integration"),
+		WithRegion(region),
+		WithBasePrefix(prefix),
+		WithMaxRetries(1),
+	}
+	if endpoint != "" {
+		opts = append(opts, WithEndpoint(endpoint))
+	}
+	// LocalStack accepts any credentials, however they must be present
+	// for the requests to get signed.
+	if endpoint == integrationDefaultEndpoint || os.Getenv(integrationEnvStatic) != "" {
+		opts = append(opts, WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")))
+	}
+	c, err := NewClient(context.TODO(), opts...)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	return c
+}
+
+// checkIntegrationError fails the test when the error indicates that AWS
+// Secrets Manager rejected the request signature.
+func checkIntegrationError(t *testing.T, op string, err error) {
+	t.Helper()
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("%s: expected API error, got: %v", op, err)
+	}
+	if integrationSignatureErrors[apiErr.ErrorCode()] {
+		t.Fatalf("%s: request signing failed: %v", op, err)
+	}
+}
+
+func TestIntegrationSecretLifecycle(t *testing.T) {
+	prefix := fmt.Sprintf("authcrunch-integration/%d/", time.Now().UnixNano())
+	c := newIntegrationClient(t, prefix)
+	ctx := context.TODO()
+	path := "caddy/access_token"
+
+	t.Cleanup(func() {
+		// Schedule the deletion of the secret left behind by the failed
+		// test.
+		if t.Failed() {
+			c.DeleteSecret(ctx, path)
+		}
+	})
+
+	if _, err := c.CreateSecret(ctx, path, map[string]interface{}{"secret": "foobar"}); err != nil {
+		t.Fatalf("CreateSecret() failed: %v", err)
+	}
+
+	got, err := c.GetSecretByKey(ctx, path, "secret")
+	if err != nil {
+		t.Fatalf("GetSecretByKey() failed: %v", err)
+	}
+	if diff := cmp.Diff("foobar", got); diff != "" {
+		t.Errorf("GetSecretByKey() mismatch (-want +got):\n%s", diff)
+	}
+
+	ch, err := c.PutSecret(ctx, path, map[string]interface{}{"secret": "barfoo", "kid": "a"})
+	if err != nil {
+		t.Fatalf("PutSecret() failed: %v", err)
+	}
+	if diff := cmp.Diff(&Change{Op: "put", Path: path, AddedKeys: []string{"kid"}, ModifiedKeys: []string{"secret"}}, ch); diff != "" {
+		t.Errorf("PutSecret() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = c.GetSecretByKey(ctx, path, "secret", WithNoCache())
+	if err != nil {
+		t.Fatalf("GetSecretByKey() failed: %v", err)
+	}
+	if diff := cmp.Diff("barfoo", got); diff != "" {
+		t.Errorf("GetSecretByKey() after PutSecret() mismatch (-want +got):\n%s", diff)
+	}
+
+	paths, err := c.ListSecrets(ctx, "caddy/")
+	if err != nil {
+		t.Fatalf("ListSecrets() failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{path}, paths); diff != "" {
+		t.Errorf("ListSecrets() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The secret has no rotation function, therefore the rotation may be
+	// rejected. The rejection must not be caused by the signing.
+	if _, err := c.RotateSecret(ctx, path); err != nil {
+		checkIntegrationError(t, "RotateSecret()", err)
+	}
+
+	if _, err := c.DeleteSecret(ctx, path); err != nil {
+		t.Fatalf("DeleteSecret() failed: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, path, WithNoCache()); err == nil {
+		t.Fatalf("GetSecret() after DeleteSecret() expected error, got success")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package secrets
+
+import (
+	"context"
+	"iter"
+)
+
+// Secrets returns the iterator over the summaries of the secrets starting
+// with the prefix, e.g.
+//
+//	for summary, err := range secrets.Secrets(ctx, c, "users/") {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(summary.Path)
+//	}
+//
+// The secrets are listed page by page as the loop advances, and breaking
+// out of the loop stops the listing. The failure ends the iteration with
+// the error. The clients of NewClient stream the listing, the other ones,
+// e.g. ChainClient, are iterated over the result of ListSecrets, and
+// their summaries hold the paths only.
+func Secrets(ctx context.Context, c Client, prefix string) iter.Seq2[*SecretSummary, error] {
+	return func(yield func(*SecretSummary, error) bool) {
+		if w, ok := c.(secretWalker); ok {
+			stopped := false
+			err := w.walkSecrets(ctx, prefix, func(summary *SecretSummary) bool {
+				stopped = !yield(summary, nil)
+				return !stopped
+			})
+			if err != nil && !stopped {
+				yield(nil, err)
+			}
+			return
+		}
+		paths, err := c.ListSecrets(ctx, prefix)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, path := range paths {
+			if !yield(&SecretSummary{Path: path}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SecretPaths returns the iterator over the paths of the secrets starting
+// with the prefix. See Secrets.
+func SecretPaths(ctx context.Context, c Client, prefix string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for summary, err := range Secrets(ctx, c, prefix) {
+			if err != nil {
+				yield("", err)
+				return
+			}
+			if !yield(summary.Path, nil) {
+				return
+			}
+		}
+	}
+}
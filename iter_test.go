@@ -0,0 +1,91 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestSecrets(t *testing.T) {
+	srv := secretsmock.NewServer(t)
+	for _, name := range []string{"authcrunch/users/jsmith", "authcrunch/users/mjones", "authcrunch/users/rkim"} {
+		srv.SetSecret(name, `{"username":"foo"}`)
+	}
+	srv.SetPageSize(1)
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithBasePrefix("authcrunch/"),
+		WithEndpoint(srv.URL),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+
+	var paths []string
+	for path, err := range SecretPaths(context.TODO(), c, "users/") {
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	if diff := cmp.Diff([]string{"users/jsmith", "users/mjones", "users/rkim"}, paths); diff != "" {
+		t.Fatalf("SecretPaths() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Breaking out of the loop stops the listing.
+	before := len(srv.Requests())
+	for summary, err := range Secrets(context.TODO(), c, "users/") {
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		if summary.ARN == "" {
+			t.Errorf("unexpected summary without arn: %+v", summary)
+		}
+		break
+	}
+	if n := len(srv.Requests()) - before; n != 1 {
+		t.Errorf("unexpected number of requests: %d, want 1", n)
+	}
+
+	srv.InjectError("ListSecrets", "", &secretsmock.Error{
+		StatusCode: http.StatusBadRequest,
+		Code:       "AccessDeniedException",
+		Message:    "User is not authorized to perform: secretsmanager:ListSecrets",
+	})
+	var errs int
+	for summary, err := range Secrets(context.TODO(), c, "users/") {
+		if err == nil {
+			t.Fatalf("unexpected summary: %+v", summary)
+		}
+		var apiErr interface{ ErrorCode() string }
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDeniedException" {
+			t.Errorf("unexpected error: %v", err)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("unexpected number of errors: %d, want 1", errs)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// KeyNormalizationTrim removes the leading and trailing whitespace
+	// from the keys of the secrets.
+	KeyNormalizationTrim = "trim"
+	// KeyNormalizationLowercase converts the keys of the secrets to lower
+	// case, e.g. "Password" to "password".
+	KeyNormalizationLowercase = "lowercase"
+	// KeyNormalizationSnakeCase converts the keys of the secrets to snake
+	// case, e.g. "apiKey", "API-Key", and "api key" to "api_key".
+	KeyNormalizationSnakeCase = "snake_case"
+)
+
+func validateKeyNormalization(modes []string) error {
+	for _, mode := range modes {
+		switch mode {
+		case KeyNormalizationTrim, KeyNormalizationLowercase, KeyNormalizationSnakeCase:
+		default:
+			return fmt.Errorf("unsupported %q key normalization", mode)
+		}
+	}
+	return nil
+}
+
+// normalizeKey applies the normalization modes to the key. The modes apply
+// in the fixed order, regardless of the order they are listed in.
+func normalizeKey(k string, modes []string) string {
+	var trim, lower, snake bool
+	for _, mode := range modes {
+		switch mode {
+		case KeyNormalizationTrim:
+			trim = true
+		case KeyNormalizationLowercase:
+			lower = true
+		case KeyNormalizationSnakeCase:
+			snake = true
+		}
+	}
+	if trim || snake {
+		k = strings.TrimSpace(k)
+	}
+	if snake {
+		k = toSnakeCase(k)
+	}
+	if lower {
+		k = strings.ToLower(k)
+	}
+	return k
+}
+
+// normalizeKeys renames the top-level keys of the secret with the
+// normalization modes. When several keys normalize to the same key, the
+// key already in the normalized form wins, followed by the first one in
+// the lexical order.
+func normalizeKeys(m map[string]interface{}, modes []string) {
+	if len(modes) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		nk := normalizeKey(k, modes)
+		if nk == k {
+			continue
+		}
+		v := m[k]
+		delete(m, k)
+		if _, exists := m[nk]; !exists {
+			m[nk] = v
+		}
+	}
+}
+
+// toSnakeCase converts the camel case, kebab case, and space-separated
+// words to snake case. The acronyms are kept together, e.g. "DBHost" is
+// converted to "db_host".
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == '_' || unicode.IsSpace(r):
+			r = '_'
+		case unicode.IsUpper(r) && i > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		if r == '_' && strings.HasSuffix(b.String(), "_") {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestNormalizeKey(t *testing.T) {
+	testcases := []struct {
+		key   string
+		modes []string
+		want  string
+	}{
+		{key: " Password ", modes: []string{KeyNormalizationTrim}, want: "Password"},
+		{key: "Password", modes: []string{KeyNormalizationLowercase}, want: "password"},
+		{key: "apiKey", modes: []string{KeyNormalizationSnakeCase}, want: "api_key"},
+		{key: "API-Key", modes: []string{KeyNormalizationSnakeCase}, want: "api_key"},
+		{key: " api  key ", modes: []string{KeyNormalizationSnakeCase}, want: "api_key"},
+		{key: "DBHost", modes: []string{KeyNormalizationSnakeCase}, want: "db_host"},
+		{key: "oauth2ClientID", modes: []string{KeyNormalizationSnakeCase}, want: "oauth2_client_id"},
+		{key: "smtp.Password", modes: []string{KeyNormalizationSnakeCase}, want: "smtp.password"},
+		{key: " UserName ", modes: []string{KeyNormalizationLowercase, KeyNormalizationTrim}, want: "username"},
+		{key: " UserName ", want: " UserName "},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("test %q with %v", tc.key, tc.modes), func(t *testing.T) {
+			if got := normalizeKey(tc.key, tc.modes); got != tc.want {
+				t.Errorf("normalizeKey() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyNormalization(t *testing.T) {
+	testcases := []struct {
+		name      string
+		modes     []string
+		secret    map[string]interface{}
+		key       string
+		want      map[string]interface{}
+		wantValue interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "test lowercase and trimmed keys",
+			modes: []string{KeyNormalizationTrim, KeyNormalizationLowercase},
+			secret: map[string]interface{}{
+				" Username": "jsmith",
+				"PASSWORD ": "foo",
+			},
+			key: "Password",
+			want: map[string]interface{}{
+				"username": "jsmith",
+				"password": "foo",
+			},
+			wantValue: "foo",
+		},
+		{
+			name:  "test snake case keys with collision",
+			modes: []string{KeyNormalizationSnakeCase},
+			secret: map[string]interface{}{
+				"apiKey":  "foo",
+				"api_key": "bar",
+				"DBHost":  "localhost",
+			},
+			key: "apiKey",
+			want: map[string]interface{}{
+				"api_key": "bar",
+				"db_host": "localhost",
+			},
+			wantValue: "bar",
+		},
+		{
+			name:      "test unsupported key normalization",
+			modes:     []string{"camelCase"},
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q key normalization", "camelCase"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"), WithKeyNormalization(tc.modes...))
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("NewClient() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			c.SetMockClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+				return secretsmock.SecretStringResponse(tc.secret), nil
+			}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetSecret(context.TODO(), "authcrunch/caddy/users/jsmith")
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+			}
+			value, err := c.GetSecretByKey(context.TODO(), "authcrunch/caddy/users/jsmith", tc.key)
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if value != tc.wantValue {
+				t.Errorf("GetSecretByKey() = %v, want %v", value, tc.wantValue)
+			}
+		})
+	}
+}
+
+func TestGetSecretByKeyValueType(t *testing.T) {
+	stages := map[string]map[string]interface{}{
+		"AWSCURRENT": {
+			"username": "jsmith",
+			"port":     float64(636),
+			"tls":      true,
+			"roles":    []interface{}{"admin"},
+			"smtp":     map[string]interface{}{"password": "foo"},
+		},
+	}
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	c.SetMockClient(newStagedMockClient(t, stages))
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+	for key, typ := range map[string]string{"port": "number", "tls": "boolean", "roles": "array", "smtp": "object"} {
+		_, err := c.GetSecretByKey(context.TODO(), "authcrunch/ldap", key)
+		if !errors.Is(err, ErrKeyValueType) {
+			t.Fatalf("expected ErrKeyValueType for %q key, got: %v", key, err)
+		}
+		want := &KeyTypeError{Path: "authcrunch/ldap", Key: key, Type: typ}
+		if diff := cmp.Diff(want.Error(), err.Error()); diff != "" {
+			t.Errorf("GetSecretByKey() error mismatch (-want +got):\n%s", diff)
+		}
+	}
+	if v, err := c.GetSecretByKey(context.TODO(), "authcrunch/ldap", "username"); err != nil || v != "jsmith" {
+		t.Errorf("unexpected %v value, error: %v", v, err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultKMSKeyAlias is the alias of the AWS managed key encrypting the
+// secrets created without a customer managed key.
+const defaultKMSKeyAlias = "alias/aws/secretsmanager"
+
+// ErrKMSKeyMismatch is returned when a secret is not encrypted with the
+// required KMS key.
+var ErrKMSKeyMismatch = errors.New("secret not encrypted with required kms key")
+
+func validateKMSKeyID(s string) error {
+	switch {
+	case strings.TrimSpace(s) != s, strings.HasSuffix(s, "/"), strings.HasSuffix(s, ":"):
+		return fmt.Errorf("malformed %q required kms key", s)
+	case strings.HasPrefix(s, "arn:"), strings.HasPrefix(s, "alias/"):
+		return nil
+	case strings.ContainsAny(s, "/:"):
+		return fmt.Errorf("malformed %q required kms key", s)
+	}
+	return nil
+}
+
+// matchKMSKeyID reports whether the KMS key of the secret, as returned by
+// DescribeSecret, is the required one. The required key is either an ARN,
+// an alias, e.g. "alias/authcrunch", or a key ID. A secret without the key
+// is encrypted with the AWS managed key.
+func matchKMSKeyID(required, actual string) bool {
+	if actual == "" {
+		actual = defaultKMSKeyAlias
+	}
+	if required == actual {
+		return true
+	}
+	if strings.HasPrefix(required, "arn:") {
+		return false
+	}
+	if !strings.HasPrefix(required, "alias/") {
+		required = "key/" + required
+	}
+	return strings.HasPrefix(actual, "arn:") && strings.HasSuffix(actual, ":"+required)
+}
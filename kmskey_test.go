@@ -0,0 +1,141 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+const testKMSKeyARN = "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+func TestMatchKMSKeyID(t *testing.T) {
+	testcases := []struct {
+		name     string
+		required string
+		actual   string
+		want     bool
+	}{
+		{name: "test same arn", required: testKMSKeyARN, actual: testKMSKeyARN, want: true},
+		{name: "test key id of arn", required: "1234abcd-12ab-34cd-56ef-1234567890ab", actual: testKMSKeyARN, want: true},
+		{name: "test other key id", required: "0000abcd-12ab-34cd-56ef-1234567890ab", actual: testKMSKeyARN},
+		{name: "test alias", required: "alias/authcrunch", actual: "alias/authcrunch", want: true},
+		{name: "test alias of arn", required: "alias/authcrunch", actual: "arn:aws:kms:us-east-1:123456789012:alias/authcrunch", want: true},
+		{name: "test arn of other key", required: testKMSKeyARN, actual: "alias/authcrunch"},
+		{name: "test default key", required: "alias/aws/secretsmanager", actual: "", want: true},
+		{name: "test default key not required", required: "alias/authcrunch", actual: ""},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchKMSKeyID(tc.required, tc.actual); got != tc.want {
+				t.Fatalf("matchKMSKeyID(%q, %q) = %t, want %t", tc.required, tc.actual, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateKMSKeyID(t *testing.T) {
+	testcases := []struct {
+		name      string
+		id        string
+		shouldErr bool
+		err       error
+	}{
+		{name: "test arn", id: testKMSKeyARN},
+		{name: "test alias", id: "alias/authcrunch"},
+		{name: "test key id", id: "1234abcd-12ab-34cd-56ef-1234567890ab"},
+		{
+			name:      "test alias without name",
+			id:        "alias/",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q required kms key", "alias/"),
+		},
+		{
+			name:      "test path",
+			id:        "keys/authcrunch",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q required kms key", "keys/authcrunch"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateKMSKeyID(tc.id)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestRequiredKMSKey(t *testing.T) {
+	keys := map[string]string{
+		"authcrunch/users/jsmith": testKMSKeyARN,
+		"authcrunch/users/mjones": "",
+	}
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithBasePrefix("authcrunch/"),
+		WithRequiredKMSKey("1234abcd-12ab-34cd-56ef-1234567890ab"),
+		WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			var input struct {
+				SecretId string
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				return mockFailure(t, "failed parsing request: %v", err)
+			}
+			if r.Header.Get("X-Amz-Target") == "secretsmanager.DescribeSecret" {
+				m := map[string]interface{}{"Name": input.SecretId}
+				if key := keys[input.SecretId]; key != "" {
+					m["KmsKeyId"] = key
+				}
+				return secretsmock.JSONResponse(m), nil
+			}
+			return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+		})),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	if _, err := c.GetSecret(context.TODO(), "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	_, err = c.GetSecret(context.TODO(), "users/mjones")
+	if !errors.Is(err, ErrKMSKeyMismatch) {
+		t.Fatalf("expected ErrKMSKeyMismatch, got: %v", err)
+	}
+	want := fmt.Errorf("%w: %q is encrypted with %q, want %q", ErrKMSKeyMismatch, "users/mjones", "alias/aws/secretsmanager", "1234abcd-12ab-34cd-56ef-1234567890ab")
+	if diff := cmp.Diff(want.Error(), err.Error()); diff != "" {
+		t.Fatalf("unexpected error: %v, want: %v", err, want)
+	}
+}
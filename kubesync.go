@@ -0,0 +1,441 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// KubernetesSecretHashAnnotation is the annotation holding the hash of
+	// the data of the synced Kubernetes Secret.
+	KubernetesSecretHashAnnotation = "secrets.authcrunch.com/hash"
+	// KubernetesSecretPathAnnotation is the annotation holding the path of
+	// the secret the Kubernetes Secret is synced from.
+	KubernetesSecretPathAnnotation = "secrets.authcrunch.com/path"
+	// KubernetesManagedByLabel is the label marking the Kubernetes Secrets
+	// managed by KubernetesSync. The unmarked Secrets are never updated
+	// or deleted.
+	KubernetesManagedByLabel = "app.kubernetes.io/managed-by"
+
+	kubernetesManagedBy            = "authcrunch-secrets"
+	defaultKubernetesAPIServer     = "https://kubernetes.default.svc"
+	defaultKubernetesSecretType    = "Opaque"
+	kubernetesServiceAccountDir    = "/var/run/secrets/kubernetes.io/serviceaccount/"
+	defaultKubernetesTokenFile     = kubernetesServiceAccountDir + "token"
+	defaultKubernetesCAFile        = kubernetesServiceAccountDir + "ca.crt"
+	defaultKubernetesNamespaceFile = kubernetesServiceAccountDir + "namespace"
+)
+
+var (
+	// ErrKubernetesSecretNotManaged is returned when the Kubernetes Secret
+	// to sync exists, but it is not managed by KubernetesSync.
+	ErrKubernetesSecretNotManaged = errors.New("kubernetes secret is not managed by authcrunch secrets")
+
+	kubernetesNameRgx    *regexp.Regexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+	kubernetesDataKeyRgx *regexp.Regexp = regexp.MustCompile(`^[-._a-zA-Z0-9]{1,253}$`)
+)
+
+// KubernetesSecret is the Kubernetes Secret a secret is synced to. Every
+// key of the secret becomes the key of the data of the Kubernetes Secret.
+// The string values are synced as is, and the others as JSON.
+type KubernetesSecret struct {
+	// Path is the path of the secret.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// Name is the name of the Kubernetes Secret.
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Type is the type of the Kubernetes Secret, e.g.
+	// "kubernetes.io/basic-auth". When empty, it is "Opaque".
+	Type string `json:"type,omitempty" xml:"type,omitempty" yaml:"type,omitempty"`
+}
+
+func (s *KubernetesSecret) validate() error {
+	if s.Path == "" {
+		return errors.New("kubernetes secret path is empty")
+	}
+	if !kubernetesNameRgx.MatchString(s.Name) {
+		return fmt.Errorf("malformed %q kubernetes secret name", s.Name)
+	}
+	return nil
+}
+
+// KubernetesSyncConfig is the configuration of KubernetesSync. The
+// defaults are of the service account of the pod the process runs in.
+type KubernetesSyncConfig struct {
+	// APIServer is the URL of Kubernetes API server. When empty, it is
+	// "https://kubernetes.default.svc".
+	APIServer string `json:"api_server,omitempty" xml:"api_server,omitempty" yaml:"api_server,omitempty"`
+	// Namespace is the namespace of the Kubernetes Secrets. When empty,
+	// it is the namespace of the service account.
+	Namespace string `json:"namespace,omitempty" xml:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// TokenFile is the file holding the bearer token. It is read before
+	// every sync, so that the rotated tokens are picked up.
+	TokenFile string `json:"token_file,omitempty" xml:"token_file,omitempty" yaml:"token_file,omitempty"`
+	// CAFile is the PEM-encoded certificate authority of the API server.
+	CAFile string `json:"ca_file,omitempty" xml:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+	// Secrets are the Kubernetes Secrets the secrets are synced to.
+	Secrets []*KubernetesSecret `json:"secrets,omitempty" xml:"secrets,omitempty" yaml:"secrets,omitempty"`
+	// ResyncInterval is the interval of the full sync repairing the drift
+	// of the Kubernetes Secrets, e.g. "10m". When empty, Run syncs on the
+	// change events only.
+	ResyncInterval string `json:"resync_interval,omitempty" xml:"resync_interval,omitempty" yaml:"resync_interval,omitempty"`
+}
+
+// KubernetesSyncOption configures KubernetesSync.
+type KubernetesSyncOption func(*KubernetesSync)
+
+// WithKubernetesHTTPClient sets the HTTP client of Kubernetes API server.
+// It replaces the client trusting the CAFile.
+func WithKubernetesHTTPClient(httpClient *http.Client) KubernetesSyncOption {
+	return func(ks *KubernetesSync) {
+		ks.httpClient = httpClient
+	}
+}
+
+// KubernetesSync mirrors the secrets into Kubernetes Secrets for the
+// workloads unable to access AWS Secrets Manager directly, e.g. without
+// IAM roles for service accounts. The Kubernetes Secrets are created or
+// updated when the hash of their data differs from the one of the secret.
+type KubernetesSync struct {
+	c          Client
+	httpClient *http.Client
+	apiServer  string
+	namespace  string
+	tokenFile  string
+	resync     time.Duration
+	// secrets maps the paths of the secrets to their Kubernetes Secrets.
+	secrets map[string][]*KubernetesSecret
+	paths   []string
+}
+
+// NewKubernetesSync returns the sync of the secrets retrieved with the
+// client.
+func NewKubernetesSync(c Client, cfg *KubernetesSyncConfig, opts ...KubernetesSyncOption) (*KubernetesSync, error) {
+	if len(cfg.Secrets) == 0 {
+		return nil, errors.New("kubernetes secrets not found")
+	}
+	ks := &KubernetesSync{
+		c:         c,
+		apiServer: strings.TrimSuffix(cfg.APIServer, "/"),
+		namespace: cfg.Namespace,
+		tokenFile: cfg.TokenFile,
+		secrets:   make(map[string][]*KubernetesSecret),
+	}
+	for _, opt := range opts {
+		opt(ks)
+	}
+	if ks.apiServer == "" {
+		ks.apiServer = defaultKubernetesAPIServer
+	}
+	if u, err := url.Parse(ks.apiServer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("malformed %q kubernetes api server", cfg.APIServer)
+	}
+	if ks.tokenFile == "" {
+		ks.tokenFile = defaultKubernetesTokenFile
+	}
+	if ks.namespace == "" {
+		b, err := ioutil.ReadFile(defaultKubernetesNamespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes namespace not found: %v", err)
+		}
+		ks.namespace = strings.TrimSpace(string(b))
+	}
+	if !kubernetesNameRgx.MatchString(ks.namespace) {
+		return nil, fmt.Errorf("malformed %q kubernetes namespace", ks.namespace)
+	}
+	if cfg.ResyncInterval != "" {
+		d, err := time.ParseDuration(cfg.ResyncInterval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("malformed %q kubernetes resync interval", cfg.ResyncInterval)
+		}
+		ks.resync = d
+	}
+	if ks.httpClient == nil {
+		caFile := cfg.CAFile
+		if caFile == "" {
+			caFile = defaultKubernetesCAFile
+		}
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		ks.httpClient = &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+			},
+		}
+	}
+	names := make(map[string]bool)
+	for _, s := range cfg.Secrets {
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("duplicate %q kubernetes secret", s.Name)
+		}
+		names[s.Name] = true
+		if _, found := ks.secrets[s.Path]; !found {
+			ks.paths = append(ks.paths, s.Path)
+		}
+		ks.secrets[s.Path] = append(ks.secrets[s.Path], s)
+	}
+	return ks, nil
+}
+
+// Sync creates or updates the Kubernetes Secrets of all the secrets.
+func (ks *KubernetesSync) Sync(ctx context.Context) error {
+	for _, p := range ks.paths {
+		secret, err := ks.c.GetSecret(ctx, p)
+		if err != nil {
+			return err
+		}
+		if err := ks.write(ctx, p, secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run syncs the Kubernetes Secrets and resyncs them whenever the secrets
+// change, e.g. on rotation, and on the resync interval, until the context
+// is done. The Kubernetes Secrets of the deleted secrets are deleted. It
+// returns the first error syncing the Kubernetes Secrets.
+func (ks *KubernetesSync) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := ks.c.Watch(ctx, ks.paths)
+	if err != nil {
+		return err
+	}
+	var resync <-chan time.Time
+	if ks.resync > 0 {
+		ticker := time.NewTicker(ks.resync)
+		defer ticker.Stop()
+		resync = ticker.C
+	}
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return ctx.Err()
+			}
+			switch ev.Type {
+			case SecretAdded, SecretUpdated:
+				err = ks.write(ctx, ev.Path, ev.Secret)
+			case SecretDeleted:
+				err = ks.remove(ctx, ev.Path)
+			}
+		case <-resync:
+			err = ks.Sync(ctx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// kubernetesSecret is the subset of Kubernetes Secret object the sync
+// manages. The other fields of the existing objects are kept.
+type kubernetesSecret map[string]interface{}
+
+// kubernetesSecretData returns the data of Kubernetes Secret for the
+// secret, and its hash.
+func kubernetesSecretData(s *KubernetesSecret, secret map[string]interface{}) (map[string][]byte, string, error) {
+	data := make(map[string][]byte, len(secret))
+	keys := make([]string, 0, len(secret))
+	for k, v := range secret {
+		if !kubernetesDataKeyRgx.MatchString(k) {
+			return nil, "", fmt.Errorf("key %q of %q secret is not valid kubernetes secret key", k, s.Path)
+		}
+		if str, ok := v.(string); ok {
+			data[k] = []byte(str)
+		} else {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, "", err
+			}
+			data[k] = b
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00", s.secretType())
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s\x00%d\x00", k, len(data[k]))
+		h.Write(data[k])
+	}
+	return data, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *KubernetesSecret) secretType() string {
+	if s.Type == "" {
+		return defaultKubernetesSecretType
+	}
+	return s.Type
+}
+
+// write creates or updates the Kubernetes Secrets of the secret at the
+// path.
+func (ks *KubernetesSync) write(ctx context.Context, path string, secret map[string]interface{}) error {
+	for _, s := range ks.secrets[path] {
+		data, hash, err := kubernetesSecretData(s, secret)
+		if err != nil {
+			return err
+		}
+		var existing kubernetesSecret
+		found, err := ks.do(ctx, http.MethodGet, s.Name, nil, &existing)
+		if err != nil {
+			return err
+		}
+		if !found {
+			obj := kubernetesSecret{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata": map[string]interface{}{
+					"name":        s.Name,
+					"namespace":   ks.namespace,
+					"labels":      map[string]string{KubernetesManagedByLabel: kubernetesManagedBy},
+					"annotations": map[string]string{KubernetesSecretHashAnnotation: hash, KubernetesSecretPathAnnotation: path},
+				},
+				"type": s.secretType(),
+				"data": data,
+			}
+			if _, err := ks.do(ctx, http.MethodPost, s.Name, obj, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		metadata, _ := existing["metadata"].(map[string]interface{})
+		if !isManagedKubernetesSecret(metadata) {
+			return fmt.Errorf("%w: %q", ErrKubernetesSecretNotManaged, s.Name)
+		}
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		if annotations == nil {
+			annotations = make(map[string]interface{})
+		}
+		if annotations[KubernetesSecretHashAnnotation] == hash {
+			continue
+		}
+		annotations[KubernetesSecretHashAnnotation] = hash
+		annotations[KubernetesSecretPathAnnotation] = path
+		metadata["annotations"] = annotations
+		// The resource version of the existing object makes the update
+		// fail, when the object changed in the meantime.
+		existing["type"] = s.secretType()
+		existing["data"] = data
+		delete(existing, "stringData")
+		if _, err := ks.do(ctx, http.MethodPut, s.Name, existing, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove deletes the Kubernetes Secrets of the secret at the path.
+func (ks *KubernetesSync) remove(ctx context.Context, path string) error {
+	for _, s := range ks.secrets[path] {
+		var existing kubernetesSecret
+		found, err := ks.do(ctx, http.MethodGet, s.Name, nil, &existing)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		metadata, _ := existing["metadata"].(map[string]interface{})
+		if !isManagedKubernetesSecret(metadata) {
+			return fmt.Errorf("%w: %q", ErrKubernetesSecretNotManaged, s.Name)
+		}
+		if _, err := ks.do(ctx, http.MethodDelete, s.Name, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isManagedKubernetesSecret(metadata map[string]interface{}) bool {
+	labels, _ := metadata["labels"].(map[string]interface{})
+	return labels[KubernetesManagedByLabel] == kubernetesManagedBy
+}
+
+// do sends the request for the Kubernetes Secret with the name, or to the
+// collection of the Kubernetes Secrets, when it creates one, and decodes
+// the response into v. It returns false when the Kubernetes Secret is not
+// found.
+func (ks *KubernetesSync) do(ctx context.Context, method, name string, body, v interface{}) (bool, error) {
+	u := ks.apiServer + "/api/v1/namespaces/" + url.PathEscape(ks.namespace) + "/secrets"
+	if method != http.MethodPost {
+		u += "/" + url.PathEscape(name)
+	}
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return false, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return false, err
+	}
+	token, err := ioutil.ReadFile(ks.tokenFile)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := ks.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && (method == http.MethodGet || method == http.MethodDelete) {
+		return false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var status struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&status)
+		return false, fmt.Errorf("kubernetes api %s %q secret failed with status %d: %s", strings.ToLower(method), name, resp.StatusCode, status.Message)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return false, fmt.Errorf("malformed kubernetes api response: %v", err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// kubernetesTestServer is the mock Kubernetes API server holding the
+// Secrets of "authcrunch" namespace.
+type kubernetesTestServer struct {
+	mu       sync.Mutex
+	secrets  map[string]map[string]interface{}
+	requests []string
+}
+
+func (s *kubernetesTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer foobar" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/authcrunch/secrets")
+	name = strings.TrimPrefix(name, "/")
+	s.requests = append(s.requests, r.Method+" "+name)
+	var obj map[string]interface{}
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		json.NewDecoder(r.Body).Decode(&obj)
+	}
+	switch r.Method {
+	case http.MethodGet:
+		obj, found := s.secrets[name]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+	case http.MethodPost:
+		name = obj["metadata"].(map[string]interface{})["name"].(string)
+		s.secrets[name] = obj
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(obj)
+	case http.MethodPut:
+		s.secrets[name] = obj
+		json.NewEncoder(w).Encode(obj)
+	case http.MethodDelete:
+		delete(s.secrets, name)
+		w.Write([]byte(`{}`))
+	}
+}
+
+func TestKubernetesSync(t *testing.T) {
+	src, dir := t.TempDir(), t.TempDir()
+	writeTestFile(t, filepath.Join(src, "authcrunch", "ldap.json"), `{"bind_dn":"cn=authcrunch","bind_password":"foobar","port":636}`)
+	writeTestFile(t, filepath.Join(dir, "token"), "foobar\n")
+	c, err := NewClient(context.TODO(),
+		WithConfig(&ClientConfig{ID: "foo", Provider: fileProvider, FilePath: src}),
+		WithBasePrefix("authcrunch/"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	server := &kubernetesTestServer{secrets: map[string]map[string]interface{}{
+		"other": {"metadata": map[string]interface{}{"name": "other"}},
+	}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	ks, err := NewKubernetesSync(c, &KubernetesSyncConfig{
+		APIServer: ts.URL,
+		Namespace: "authcrunch",
+		TokenFile: filepath.Join(dir, "token"),
+		Secrets: []*KubernetesSecret{
+			{Path: "ldap", Name: "ldap-bind"},
+		},
+	}, WithKubernetesHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ks.Sync(context.TODO()); err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+	}
+	if diff := cmp.Diff([]string{"GET ldap-bind", "POST ", "GET ldap-bind"}, server.requests); diff != "" {
+		t.Fatalf("requests mismatch (-want +got):\n%s", diff)
+	}
+	obj := server.secrets["ldap-bind"]
+	if diff := cmp.Diff(map[string]interface{}{
+		"bind_dn":       "Y249YXV0aGNydW5jaA==",
+		"bind_password": "Zm9vYmFy",
+		"port":          "NjM2",
+	}, obj["data"]); diff != "" {
+		t.Fatalf("data mismatch (-want +got):\n%s", diff)
+	}
+	metadata := obj["metadata"].(map[string]interface{})
+	if got := metadata["labels"].(map[string]interface{})[KubernetesManagedByLabel]; got != "authcrunch-secrets" {
+		t.Fatalf("unexpected %v managed-by label", got)
+	}
+	hash := metadata["annotations"].(map[string]interface{})[KubernetesSecretHashAnnotation]
+
+	// The changed secret updates the Kubernetes Secret.
+	server.requests = nil
+	writeTestFile(t, filepath.Join(src, "authcrunch", "ldap.json"), `{"bind_dn":"cn=authcrunch","bind_password":"barfoo"}`)
+	if err := ks.Sync(context.TODO()); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"GET ldap-bind", "PUT ldap-bind"}, server.requests); diff != "" {
+		t.Fatalf("requests mismatch (-want +got):\n%s", diff)
+	}
+	obj = server.secrets["ldap-bind"]
+	if diff := cmp.Diff(map[string]interface{}{"bind_dn": "Y249YXV0aGNydW5jaA==", "bind_password": "YmFyZm9v"}, obj["data"]); diff != "" {
+		t.Fatalf("data mismatch (-want +got):\n%s", diff)
+	}
+	if got := obj["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})[KubernetesSecretHashAnnotation]; got == hash {
+		t.Fatalf("hash annotation not updated")
+	}
+
+	// The Secrets not managed by the sync are never overwritten.
+	ks, err = NewKubernetesSync(c, &KubernetesSyncConfig{
+		APIServer: ts.URL,
+		Namespace: "authcrunch",
+		TokenFile: filepath.Join(dir, "token"),
+		Secrets:   []*KubernetesSecret{{Path: "ldap", Name: "other"}},
+	}, WithKubernetesHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if err := ks.Sync(context.TODO()); !errors.Is(err, ErrKubernetesSecretNotManaged) {
+		t.Fatalf("expected ErrKubernetesSecretNotManaged, got: %v", err)
+	}
+}
+
+func TestNewKubernetesSyncErrors(t *testing.T) {
+	httpClient := WithKubernetesHTTPClient(http.DefaultClient)
+	for _, tc := range []struct {
+		cfg *KubernetesSyncConfig
+		err string
+	}{
+		{cfg: &KubernetesSyncConfig{Namespace: "authcrunch"}, err: "kubernetes secrets not found"},
+		{
+			cfg: &KubernetesSyncConfig{Namespace: "authcrunch", Secrets: []*KubernetesSecret{{Path: "ldap", Name: "LDAP"}}},
+			err: `malformed "LDAP" kubernetes secret name`,
+		},
+		{
+			cfg: &KubernetesSyncConfig{Namespace: "authcrunch", Secrets: []*KubernetesSecret{{Name: "ldap"}}},
+			err: "kubernetes secret path is empty",
+		},
+		{
+			cfg: &KubernetesSyncConfig{Namespace: "authcrunch", Secrets: []*KubernetesSecret{{Path: "ldap", Name: "ldap"}, {Path: "smtp", Name: "ldap"}}},
+			err: `duplicate "ldap" kubernetes secret`,
+		},
+		{
+			cfg: &KubernetesSyncConfig{Namespace: "Auth Crunch", Secrets: []*KubernetesSecret{{Path: "ldap", Name: "ldap"}}},
+			err: `malformed "Auth Crunch" kubernetes namespace`,
+		},
+		{
+			cfg: &KubernetesSyncConfig{APIServer: "kubernetes", Namespace: "authcrunch", Secrets: []*KubernetesSecret{{Path: "ldap", Name: "ldap"}}},
+			err: `malformed "kubernetes" kubernetes api server`,
+		},
+		{
+			cfg: &KubernetesSyncConfig{Namespace: "authcrunch", ResyncInterval: "1x", Secrets: []*KubernetesSecret{{Path: "ldap", Name: "ldap"}}},
+			err: `malformed "1x" kubernetes resync interval`,
+		},
+	} {
+		_, err := NewKubernetesSync(nil, tc.cfg, httpClient)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("unexpected error: %v, want: %s", err, tc.err)
+		}
+	}
+}
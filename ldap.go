@@ -0,0 +1,99 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// LDAPBindCredentials holds the credentials of the service account used to
+// bind to LDAP server.
+type LDAPBindCredentials struct {
+	BindDN       string `json:"bind_dn,omitempty" xml:"bind_dn,omitempty" yaml:"bind_dn,omitempty"`
+	BindPassword string `json:"bind_password,omitempty" xml:"bind_password,omitempty" yaml:"bind_password,omitempty"`
+	// PreviousBindPassword is the bind password prior to the last rotation.
+	// It is empty when the secret has not been rotated yet, or the bind DN
+	// changed during the rotation.
+	PreviousBindPassword string `json:"previous_bind_password,omitempty" xml:"previous_bind_password,omitempty" yaml:"previous_bind_password,omitempty"`
+	// CACertPEM holds PEM-encoded certificates of the authorities trusted
+	// to issue LDAP server certificate.
+	CACertPEM string `json:"ca_cert_pem,omitempty" xml:"ca_cert_pem,omitempty" yaml:"ca_cert_pem,omitempty"`
+}
+
+// CertPool returns the pool with the trusted certificate authorities. It
+// returns nil when the CA certificates are not provided.
+func (creds *LDAPBindCredentials) CertPool() (*x509.CertPool, error) {
+	if creds.CACertPEM == "" {
+		return nil, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(creds.CACertPEM)) {
+		return nil, errors.New("ca certificates not found")
+	}
+	return pool, nil
+}
+
+// GetLDAPBindCredentials returns the LDAP bind credentials stored in the
+// bind_dn, bind_password, and the optional ca_cert keys of the secret.
+// The previous bind password is retrieved from the AWSPREVIOUS version of
+// the secret, so that the binds keep working while the rotated password
+// propagates across LDAP servers.
+func (c *client) GetLDAPBindCredentials(ctx context.Context, path string) (*LDAPBindCredentials, error) {
+	m, err := c.getSecretValue(ctx, path, versionStageCurrent)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := parseLDAPBindCredentials(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed current version of %q secret: %v", path, err)
+	}
+
+	m, err = c.getSecretValue(ctx, path, versionStagePrevious)
+	if err != nil {
+		if isNotFound(err) {
+			return creds, nil
+		}
+		return nil, err
+	}
+	previous, err := parseLDAPBindCredentials(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed previous version of %q secret: %v", path, err)
+	}
+	if previous.BindDN == creds.BindDN && previous.BindPassword != creds.BindPassword {
+		creds.PreviousBindPassword = previous.BindPassword
+	}
+	return creds, nil
+}
+
+func parseLDAPBindCredentials(m map[string]interface{}) (*LDAPBindCredentials, error) {
+	creds := &LDAPBindCredentials{}
+	var err error
+	if creds.BindDN, err = getStringValue(m, "bind_dn", true); err != nil {
+		return nil, err
+	}
+	if creds.BindPassword, err = getStringValue(m, "bind_password", true); err != nil {
+		return nil, err
+	}
+	if creds.CACertPEM, err = getStringValue(m, "ca_cert", false); err != nil {
+		return nil, err
+	}
+	if _, err := creds.CertPool(); err != nil {
+		return nil, fmt.Errorf("key %q value is malformed: %v", "ca_cert", err)
+	}
+	return creds, nil
+}
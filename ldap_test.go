@@ -0,0 +1,131 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetLDAPBindCredentials(t *testing.T) {
+	_, caCert := newSNSSigningKey(t)
+	path := "authcrunch/caddy/ldap"
+	bindDN := "CN=authzsvc,OU=Service Accounts,DC=CONTOSO,DC=COM"
+
+	testcases := []struct {
+		name      string
+		stages    map[string]map[string]interface{}
+		want      *LDAPBindCredentials
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test rotated ldap bind credentials with ca certificate",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"bind_dn":       bindDN,
+					"bind_password": "P@ssW0rd123",
+					"ca_cert":       string(caCert),
+				},
+				"AWSPREVIOUS": {
+					"bind_dn":       bindDN,
+					"bind_password": "P@ssW0rd122",
+					"ca_cert":       string(caCert),
+				},
+			},
+			want: &LDAPBindCredentials{
+				BindDN:               bindDN,
+				BindPassword:         "P@ssW0rd123",
+				PreviousBindPassword: "P@ssW0rd122",
+				CACertPEM:            string(caCert),
+			},
+		},
+		{
+			name: "test ldap bind credentials without previous version",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"bind_dn":       bindDN,
+					"bind_password": "P@ssW0rd123",
+				},
+			},
+			want: &LDAPBindCredentials{
+				BindDN:       bindDN,
+				BindPassword: "P@ssW0rd123",
+			},
+		},
+		{
+			name: "test ldap bind credentials without bind dn",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"bind_password": "P@ssW0rd123",
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed current version of %q secret: key %q not found", path, "bind_dn"),
+		},
+		{
+			name: "test ldap bind credentials with malformed ca certificate",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"bind_dn":       bindDN,
+					"bind_password": "P@ssW0rd123",
+					"ca_cert":       "foo",
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed current version of %q secret: key %q value is malformed: ca certificates not found", path, "ca_cert"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+
+			c.SetMockClient(newStagedMockClient(t, tc.stages))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetLDAPBindCredentials(context.TODO(), path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("GetLDAPBindCredentials() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetLDAPBindCredentials() mismatch (-want +got):\n%s", diff)
+			}
+			pool, err := got.CertPool()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (pool != nil) != (got.CACertPEM != "") {
+				t.Errorf("CertPool() returned %v for %q", pool, got.CACertPEM)
+			}
+		})
+	}
+}
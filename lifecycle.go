@@ -0,0 +1,69 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClientClosed is returned when the background components are started
+// after the client was closed.
+var ErrClientClosed = errors.New("client is closed")
+
+// goBackground runs the function in the goroutine stopped by Close. The
+// context passed to the function, which is also returned, is cancelled
+// when the provided context is done or the client is closed.
+func (c *client) goBackground(ctx context.Context, f func(context.Context)) (context.Context, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.background.Add(2)
+	go func() {
+		defer c.background.Done()
+		defer cancel()
+		f(ctx)
+	}()
+	go func() {
+		defer c.background.Done()
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, nil
+}
+
+// Close stops the watchers, the SQS listeners, and the SNS handlers, and
+// waits for their goroutines to exit. The channels of their events are
+// closed. Once closed, the client does not start them again, but it keeps
+// serving the secrets, unless its backend holds a connection, e.g. of the
+// "grpc" provider, which is closed too.
+func (c *client) Close() error {
+	var backend SecretsManagerAPI
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+		backend = c.backend
+	}
+	c.mu.Unlock()
+	c.background.Wait()
+	return closeBackend(backend)
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	v1 := map[string]interface{}{"id": "0"}
+	clock := newTestClock()
+	var deleted []string
+	c, err := NewClient(context.TODO(),
+		WithConfig(&ClientConfig{ID: "foo", Region: "us-east-1", WatchInterval: "1h"}),
+		WithClock(clock),
+		WithHTTPClient(newSQSMockClient(t, nil, newSequenceMockClient(t, []map[string]interface{}{v1}), &deleted)),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+
+	// The contexts are never cancelled, only Close stops the components.
+	ctx := context.Background()
+	watchCh, err := c.Watch(ctx, []string{"authcrunch/caddy/access_token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-watchCh
+	sqsCh, err := c.ListenSQS(ctx, "https://sqs.us-east-1.amazonaws.com/123456789012/authcrunch")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, snsCh, err := c.SNSHandler(ctx, "arn:aws:sns:us-east-1:123456789012:authcrunch")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- c.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for Close()")
+	}
+
+	for name, ch := range map[string]<-chan SecretEvent{"watch": watchCh, "sqs": sqsCh, "sns": snsCh} {
+		if _, open := <-ch; open {
+			t.Errorf("%s channel is open after Close()", name)
+		}
+	}
+	for _, ticker := range clock.tickers {
+		if !ticker.stopped {
+			t.Errorf("watch ticker is running after Close()")
+		}
+	}
+
+	if _, err := c.Watch(ctx, []string{"authcrunch/caddy/access_token"}); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Watch() after Close() expected ErrClientClosed, got: %v", err)
+	}
+	if _, err := c.ListenSQS(ctx, "https://sqs.us-east-1.amazonaws.com/123456789012/authcrunch"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("ListenSQS() after Close() expected ErrClientClosed, got: %v", err)
+	}
+	if _, _, err := c.SNSHandler(ctx, "arn:aws:sns:us-east-1:123456789012:authcrunch"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("SNSHandler() after Close() expected ErrClientClosed, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "authcrunch/caddy/access_token"); err != nil {
+		t.Errorf("GetSecret() after Close() expected success, got: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close() failed: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSecretTooLarge is matched by the errors of the secrets exceeding the
+// configured limits.
+var ErrSecretTooLarge = errors.New("secret exceeds limits")
+
+// LimitError is the error of a secret exceeding a limit. It matches
+// ErrSecretTooLarge.
+type LimitError struct {
+	// Limit is the name of the exceeded limit, i.e. "size", "keys", or
+	// "depth".
+	Limit string
+	Value int
+	Max   int
+}
+
+// Error implements error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s %d exceeds %d", ErrSecretTooLarge, e.Limit, e.Value, e.Max)
+}
+
+// Is reports whether the target is ErrSecretTooLarge.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrSecretTooLarge
+}
+
+// SecretLimitsConfig rejects the pathological secrets before they reach
+// the applications. The zero limits are not enforced.
+type SecretLimitsConfig struct {
+	// MaxSize is the maximum size of the secret string in bytes. It is
+	// checked before decoding.
+	MaxSize int `json:"max_size,omitempty" xml:"max_size,omitempty" yaml:"max_size,omitempty"`
+	// MaxKeys is the maximum number of the keys in the secret, counting
+	// the keys of the nested objects.
+	MaxKeys int `json:"max_keys,omitempty" xml:"max_keys,omitempty" yaml:"max_keys,omitempty"`
+	// MaxDepth is the maximum nesting depth of the values in the secret.
+	// It cannot exceed the depth of 32 accepted by DecodeSecretString.
+	MaxDepth int `json:"max_depth,omitempty" xml:"max_depth,omitempty" yaml:"max_depth,omitempty"`
+}
+
+func (l *SecretLimitsConfig) validate() error {
+	if l.MaxSize < 0 {
+		return fmt.Errorf("malformed %d max secret size", l.MaxSize)
+	}
+	if l.MaxKeys < 0 {
+		return fmt.Errorf("malformed %d max secret keys", l.MaxKeys)
+	}
+	if l.MaxDepth < 0 || l.MaxDepth > maxSecretDepth {
+		return fmt.Errorf("malformed %d max secret depth", l.MaxDepth)
+	}
+	return nil
+}
+
+// decode decodes the secret string with DecodeSecretString, enforcing the
+// limits.
+func (l *SecretLimitsConfig) decode(s string) (map[string]interface{}, error) {
+	if l == nil {
+		return DecodeSecretString(s)
+	}
+	if l.MaxSize > 0 && len(s) > l.MaxSize {
+		return nil, &LimitError{Limit: "size", Value: len(s), Max: l.MaxSize}
+	}
+	m, err := DecodeSecretString(s)
+	if err != nil {
+		return nil, err
+	}
+	if l.MaxKeys > 0 {
+		if n := countKeys(m); n > l.MaxKeys {
+			return nil, &LimitError{Limit: "keys", Value: n, Max: l.MaxKeys}
+		}
+	}
+	if l.MaxDepth > 0 {
+		if d := depth(m); d > l.MaxDepth {
+			return nil, &LimitError{Limit: "depth", Value: d, Max: l.MaxDepth}
+		}
+	}
+	return m, nil
+}
+
+// countKeys returns the number of the keys of the objects in the value.
+func countKeys(v interface{}) int {
+	var n int
+	switch v := v.(type) {
+	case map[string]interface{}:
+		n += len(v)
+		for _, item := range v {
+			n += countKeys(item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			n += countKeys(item)
+		}
+	}
+	return n
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestSecretLimits(t *testing.T) {
+	testcases := []struct {
+		name      string
+		limits    *SecretLimitsConfig
+		secret    string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test secret without limits",
+			secret: `{"username":"jsmith","password":"secret"}`,
+		},
+		{
+			name:   "test secret within limits",
+			limits: &SecretLimitsConfig{MaxSize: 64, MaxKeys: 3, MaxDepth: 2},
+			secret: `{"username":"jsmith","roles":{"admin":true}}`,
+		},
+		{
+			name:      "test secret exceeding size",
+			limits:    &SecretLimitsConfig{MaxSize: 16},
+			secret:    `{"username":"jsmith"}`,
+			shouldErr: true,
+			err:       fmt.Errorf("%w: size 21 exceeds 16", ErrSecretTooLarge),
+		},
+		{
+			name:      "test secret exceeding keys",
+			limits:    &SecretLimitsConfig{MaxKeys: 2},
+			secret:    `{"username":"jsmith","roles":{"admin":true}}`,
+			shouldErr: true,
+			err:       fmt.Errorf("%w: keys 3 exceeds 2", ErrSecretTooLarge),
+		},
+		{
+			name:      "test secret exceeding depth",
+			limits:    &SecretLimitsConfig{MaxDepth: 2},
+			secret:    `{"roles":[{"admin":true}]}`,
+			shouldErr: true,
+			err:       fmt.Errorf("%w: depth 3 exceeds 2", ErrSecretTooLarge),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithSecretLimits(tc.limits),
+				WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+					return secretsmock.JSONResponse(map[string]interface{}{"SecretString": tc.secret}), nil
+				})),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			_, err = c.GetSecret(context.TODO(), "users/jsmith")
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				var limitErr *LimitError
+				if !errors.Is(err, ErrSecretTooLarge) || !errors.As(err, &limitErr) {
+					t.Fatalf("expected LimitError, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestSecretLimitsValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		limits    *SecretLimitsConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test valid limits",
+			limits: &SecretLimitsConfig{MaxSize: 65536, MaxKeys: 100, MaxDepth: 8},
+		},
+		{
+			name:      "test negative size",
+			limits:    &SecretLimitsConfig{MaxSize: -1},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed -1 max secret size"),
+		},
+		{
+			name:      "test negative keys",
+			limits:    &SecretLimitsConfig{MaxKeys: -1},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed -1 max secret keys"),
+		},
+		{
+			name:      "test depth exceeding decoder depth",
+			limits:    &SecretLimitsConfig{MaxDepth: 33},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed 33 max secret depth"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.limits.validate()
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
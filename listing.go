@@ -0,0 +1,86 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// SecretSummary describes a listed secret without its value.
+type SecretSummary struct {
+	Path            string    `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	ARN             string    `json:"arn,omitempty" xml:"arn,omitempty" yaml:"arn,omitempty"`
+	Description     string    `json:"description,omitempty" xml:"description,omitempty" yaml:"description,omitempty"`
+	LastChangedDate time.Time `json:"last_changed_date,omitempty" xml:"last_changed_date,omitempty" yaml:"last_changed_date,omitempty"`
+}
+
+// secretWalker is implemented by the clients listing the secrets page by
+// page, without collecting the paths first.
+type secretWalker interface {
+	walkSecrets(context.Context, string, func(*SecretSummary) bool) error
+}
+
+// walkSecrets calls the function with the summaries of the secrets
+// starting with the prefix, in the order of the listing, until the
+// function returns false. The paths are relative to the base prefix, and
+// the ones not allowed by the path policy are skipped. The next page is
+// requested only once the function consumed the previous one.
+func (c *client) walkSecrets(ctx context.Context, prefix string, fn func(*SecretSummary) bool) error {
+	cfg := c.getConfig()
+	name, err := cfg.resolvePath(prefix)
+	if err != nil {
+		return err
+	}
+	input := &secretsmanager.ListSecretsInput{}
+	if name != "" {
+		input.Filters = []types.Filter{
+			{Key: types.FilterNameStringTypeName, Values: []string{name}},
+		}
+	}
+	var region, endpoint string
+	if route := cfg.route(prefix); route != nil {
+		region, endpoint = route.Region, route.Endpoint
+	}
+	paginator := secretsmanager.NewListSecretsPaginator(c.getServiceClient(region, endpoint), input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, entry := range output.SecretList {
+			path, ok := cfg.relativePath(aws.ToString(entry.Name))
+			if !ok {
+				continue
+			}
+			if cfg.PathPolicy.check(path) != nil {
+				continue
+			}
+			if !fn(&SecretSummary{
+				Path:            path,
+				ARN:             aws.ToString(entry.ARN),
+				Description:     aws.ToString(entry.Description),
+				LastChangedDate: aws.ToTime(entry.LastChangedDate),
+			}) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
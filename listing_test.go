@@ -0,0 +1,111 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestWalkSecrets(t *testing.T) {
+	srv := secretsmock.NewServer(t)
+	for _, name := range []string{
+		"authcrunch/tokens/github",
+		"authcrunch/users/admin",
+		"authcrunch/users/jsmith",
+		"authcrunch/users/mjones",
+		"authcrunch/users/rkim",
+		"other/users/jsmith",
+	} {
+		srv.SetSecret(name, `{"username":"foo"}`)
+	}
+	srv.SetPageSize(2)
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithBasePrefix("authcrunch/"),
+		WithPathPolicy(&PathPolicyConfig{Deny: []string{"users/admin"}}),
+		WithEndpoint(srv.URL),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+
+	testcases := []struct {
+		name   string
+		prefix string
+		// limit stops the walk after the number of the summaries, when set.
+		limit        int
+		want         []string
+		wantRequests int
+	}{
+		{
+			name:         "test walk of all pages",
+			want:         []string{"tokens/github", "users/jsmith", "users/mjones", "users/rkim"},
+			wantRequests: 3,
+		},
+		{
+			name:         "test walk filtered by prefix",
+			prefix:       "users/",
+			want:         []string{"users/jsmith", "users/mjones", "users/rkim"},
+			wantRequests: 2,
+		},
+		{
+			name:         "test walk filtered by prefix without matches",
+			prefix:       "apps/",
+			wantRequests: 1,
+		},
+		{
+			name:         "test walk stopped on first page",
+			prefix:       "users/",
+			limit:        1,
+			want:         []string{"users/jsmith"},
+			wantRequests: 1,
+		},
+		{
+			name:         "test walk stopped on second page",
+			prefix:       "users/",
+			limit:        2,
+			want:         []string{"users/jsmith", "users/mjones"},
+			wantRequests: 2,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			before := len(srv.Requests())
+			var got []string
+			err := c.(*client).walkSecrets(context.TODO(), tc.prefix, func(summary *SecretSummary) bool {
+				if summary.ARN == "" {
+					t.Errorf("unexpected summary without arn: %+v", summary)
+				}
+				got = append(got, summary.Path)
+				return tc.limit == 0 || len(got) < tc.limit
+			})
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("walkSecrets() mismatch (-want +got):\n%s", diff)
+			}
+			if n := len(srv.Requests()) - before; n != tc.wantRequests {
+				t.Errorf("unexpected number of requests: %d, want %d", n, tc.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvCollisionPolicy decides what LoadIntoEnv does when an environment
+// variable is already set.
+type EnvCollisionPolicy string
+
+const (
+	// EnvCollisionError fails without setting any variable. It is the
+	// default.
+	EnvCollisionError EnvCollisionPolicy = "error"
+	// EnvCollisionOverwrite replaces the value of the variable.
+	EnvCollisionOverwrite EnvCollisionPolicy = "overwrite"
+	// EnvCollisionKeep keeps the value of the variable, e.g. to let the
+	// operators override the secrets locally.
+	EnvCollisionKeep EnvCollisionPolicy = "keep"
+)
+
+// EnvOption configures LoadIntoEnv.
+type EnvOption func(*envOptions)
+
+type envOptions struct {
+	collision EnvCollisionPolicy
+}
+
+// WithEnvCollisionPolicy sets the policy applied to the environment
+// variables already set.
+func WithEnvCollisionPolicy(policy EnvCollisionPolicy) EnvOption {
+	return func(o *envOptions) {
+		o.collision = policy
+	}
+}
+
+// LoadIntoEnv sets the environment variables of the process to the values
+// of the secrets, for the legacy components configured with environment
+// variables. The mapping maps the names of the variables to the
+// references of the values, either "path#key" for the value of the key,
+// or "path" for the key-value map of the secret as JSON object. It is
+// meant to be called at startup, before the rest of the application
+// initializes. The secrets are fetched before any variable is set, so
+// that an error leaves the environment intact. Each variable set or kept
+// is recorded in the audit log.
+func (c *client) LoadIntoEnv(ctx context.Context, mapping map[string]string, opts ...EnvOption) error {
+	return loadIntoEnv(ctx, c, mapping, c.audit, opts...)
+}
+
+// loadIntoEnv sets the environment variables to the values of the
+// secrets retrieved with the client. The audit function is optional.
+func loadIntoEnv(ctx context.Context, c Client, mapping map[string]string, audit func(string, string, error), opts ...EnvOption) error {
+	o := &envOptions{collision: EnvCollisionError}
+	for _, opt := range opts {
+		opt(o)
+	}
+	switch o.collision {
+	case EnvCollisionError, EnvCollisionOverwrite, EnvCollisionKeep:
+	default:
+		return fmt.Errorf("unsupported %q env collision policy", o.collision)
+	}
+	if audit == nil {
+		audit = func(string, string, error) {}
+	}
+
+	names := make([]string, 0, len(mapping))
+	for name := range mapping {
+		if !pathVarNameRgx.MatchString(name) {
+			return fmt.Errorf("malformed %q environment variable name", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	secrets := make(map[string]map[string]interface{})
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		ref := mapping[name]
+		path, key, hasKey := strings.Cut(ref, "#")
+		if path == "" || (hasKey && key == "") {
+			return fmt.Errorf("malformed %q secret reference of %s environment variable", ref, name)
+		}
+		m, fetched := secrets[path]
+		if !fetched {
+			var err error
+			if m, err = c.GetSecret(ctx, path); err != nil {
+				return err
+			}
+			secrets[path] = m
+		}
+		var v interface{} = m
+		if hasKey {
+			var found bool
+			if v, found = m[key]; !found {
+				return fmt.Errorf("key %q not found in %q secret", key, path)
+			}
+		}
+		if s, ok := v.(string); ok {
+			values[name] = s
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		values[name] = string(b)
+	}
+
+	if o.collision == EnvCollisionError {
+		for _, name := range names {
+			if _, exists := os.LookupEnv(name); exists {
+				err := fmt.Errorf("environment variable %s is already set", name)
+				audit("load_env", mapping[name], err)
+				return err
+			}
+		}
+	}
+	for _, name := range names {
+		if _, exists := os.LookupEnv(name); exists && o.collision == EnvCollisionKeep {
+			audit("load_env_keep", mapping[name], nil)
+			continue
+		}
+		err := os.Setenv(name, values[name])
+		audit("load_env", mapping[name], err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// auditRecorder keeps the audit records in memory.
+type auditRecorder struct {
+	records []*AuditRecord
+}
+
+func (r *auditRecorder) WriteAuditRecord(record *AuditRecord) error {
+	r.records = append(r.records, record)
+	return nil
+}
+
+func TestLoadIntoEnv(t *testing.T) {
+	store := map[string]string{
+		"authcrunch/ldap": `{"bind_dn":"cn=authcrunch","bind_password":"foobar","port":636}`,
+	}
+	testcases := []struct {
+		name        string
+		mapping     map[string]string
+		opts        []EnvOption
+		env         map[string]string
+		want        map[string]string
+		wantRecords []*AuditRecord
+		shouldErr   bool
+		err         error
+	}{
+		{
+			name: "test load keys and secret",
+			mapping: map[string]string{
+				"TEST_LDAP_BIND_PASSWORD": "ldap#bind_password",
+				"TEST_LDAP_PORT":          "ldap#port",
+				"TEST_LDAP":               "ldap",
+			},
+			want: map[string]string{
+				"TEST_LDAP_BIND_PASSWORD": "foobar",
+				"TEST_LDAP_PORT":          "636",
+				"TEST_LDAP":               `{"bind_dn":"cn=authcrunch","bind_password":"foobar","port":636}`,
+			},
+			wantRecords: []*AuditRecord{
+				{ClientID: "foo", Op: "get", Path: "ldap"},
+				{ClientID: "foo", Op: "load_env", Path: "ldap"},
+				{ClientID: "foo", Op: "load_env", Path: "ldap#bind_password"},
+				{ClientID: "foo", Op: "load_env", Path: "ldap#port"},
+			},
+		},
+		{
+			name:    "test collision with error policy",
+			mapping: map[string]string{"TEST_LDAP_BIND_DN": "ldap#bind_dn", "TEST_LDAP_BIND_PASSWORD": "ldap#bind_password"},
+			env:     map[string]string{"TEST_LDAP_BIND_PASSWORD": "local"},
+			want:    map[string]string{"TEST_LDAP_BIND_PASSWORD": "local"},
+			wantRecords: []*AuditRecord{
+				{ClientID: "foo", Op: "get", Path: "ldap"},
+				{ClientID: "foo", Op: "load_env", Path: "ldap#bind_password", Error: "environment variable TEST_LDAP_BIND_PASSWORD is already set"},
+			},
+			shouldErr: true,
+			err:       errors.New("environment variable TEST_LDAP_BIND_PASSWORD is already set"),
+		},
+		{
+			name:    "test collision with keep policy",
+			mapping: map[string]string{"TEST_LDAP_BIND_DN": "ldap#bind_dn", "TEST_LDAP_BIND_PASSWORD": "ldap#bind_password"},
+			opts:    []EnvOption{WithEnvCollisionPolicy(EnvCollisionKeep)},
+			env:     map[string]string{"TEST_LDAP_BIND_PASSWORD": "local"},
+			want:    map[string]string{"TEST_LDAP_BIND_DN": "cn=authcrunch", "TEST_LDAP_BIND_PASSWORD": "local"},
+			wantRecords: []*AuditRecord{
+				{ClientID: "foo", Op: "get", Path: "ldap"},
+				{ClientID: "foo", Op: "load_env", Path: "ldap#bind_dn"},
+				{ClientID: "foo", Op: "load_env_keep", Path: "ldap#bind_password"},
+			},
+		},
+		{
+			name:    "test collision with overwrite policy",
+			mapping: map[string]string{"TEST_LDAP_BIND_PASSWORD": "ldap#bind_password"},
+			opts:    []EnvOption{WithEnvCollisionPolicy(EnvCollisionOverwrite)},
+			env:     map[string]string{"TEST_LDAP_BIND_PASSWORD": "local"},
+			want:    map[string]string{"TEST_LDAP_BIND_PASSWORD": "foobar"},
+			wantRecords: []*AuditRecord{
+				{ClientID: "foo", Op: "get", Path: "ldap"},
+				{ClientID: "foo", Op: "load_env", Path: "ldap#bind_password"},
+			},
+		},
+		{
+			name:    "test missing key leaves env intact",
+			mapping: map[string]string{"TEST_LDAP_BIND_DN": "ldap#bind_dn", "TEST_LDAP_BIND_USER": "ldap#bind_user"},
+			want:    map[string]string{"TEST_LDAP_BIND_DN": ""},
+			wantRecords: []*AuditRecord{
+				{ClientID: "foo", Op: "get", Path: "ldap"},
+			},
+			shouldErr: true,
+			err:       errors.New(`key "bind_user" not found in "ldap" secret`),
+		},
+		{
+			name:      "test malformed variable name",
+			mapping:   map[string]string{"TEST-LDAP": "ldap"},
+			shouldErr: true,
+			err:       errors.New(`malformed "TEST-LDAP" environment variable name`),
+		},
+		{
+			name:      "test malformed reference",
+			mapping:   map[string]string{"TEST_LDAP": "ldap#"},
+			shouldErr: true,
+			err:       errors.New(`malformed "ldap#" secret reference of TEST_LDAP environment variable`),
+		},
+		{
+			name:      "test unsupported collision policy",
+			mapping:   map[string]string{"TEST_LDAP": "ldap"},
+			opts:      []EnvOption{WithEnvCollisionPolicy("merge")},
+			shouldErr: true,
+			err:       errors.New(`unsupported "merge" env collision policy`),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range []string{"TEST_LDAP", "TEST_LDAP_BIND_DN", "TEST_LDAP_BIND_PASSWORD", "TEST_LDAP_PORT"} {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			recorder := &auditRecorder{}
+			var requests []string
+			c := newWritesTestClient(t, store, &requests, WithBasePrefix("authcrunch/"), WithAuditSink(recorder))
+			err := c.LoadIntoEnv(context.TODO(), tc.mapping, tc.opts...)
+			if diff := cmp.Diff(tc.wantRecords, recorder.records, cmpopts.IgnoreFields(AuditRecord{}, "Time")); diff != "" {
+				t.Errorf("audit records mismatch (-want +got):\n%s", diff)
+			}
+			for name, want := range tc.want {
+				if got := os.Getenv(name); got != want {
+					t.Errorf("unexpected %q value of %s, want: %q", got, name, want)
+				}
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	localUserDatabaseVersion = "1.0.0"
+	localPasswordPurpose     = "generic"
+	localPasswordAlgorithm   = "bcrypt"
+	localAPIKeyUsage         = "api"
+)
+
+// LocalUserDatabase is the user database of the local identity store of
+// go-authcrunch. It has the subset of the fields the user secrets map to.
+type LocalUserDatabase struct {
+	Version      string       `json:"version"`
+	Revision     uint64       `json:"revision"`
+	LastModified time.Time    `json:"last_modified"`
+	Users        []*LocalUser `json:"users"`
+}
+
+// LocalUser is the user of the local identity store.
+type LocalUser struct {
+	ID             string               `json:"id"`
+	Username       string               `json:"username"`
+	Name           *LocalUserName       `json:"name,omitempty"`
+	EmailAddress   *LocalEmailAddress   `json:"email_address,omitempty"`
+	EmailAddresses []*LocalEmailAddress `json:"email_addresses,omitempty"`
+	Passwords      []*LocalPassword     `json:"passwords,omitempty"`
+	APIKeys        []*LocalAPIKey       `json:"api_keys,omitempty"`
+	Roles          []*LocalRole         `json:"roles,omitempty"`
+	Created        time.Time            `json:"created"`
+	LastModified   time.Time            `json:"last_modified"`
+	Revision       int                  `json:"revision"`
+}
+
+// LocalUserName is the name of the user of the local identity store.
+type LocalUserName struct {
+	First string `json:"first,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// LocalEmailAddress is the email address of the user of the local identity
+// store.
+type LocalEmailAddress struct {
+	Address string `json:"address"`
+	Domain  string `json:"domain"`
+}
+
+// LocalPassword is the hashed password of the user of the local identity
+// store.
+type LocalPassword struct {
+	Purpose   string `json:"purpose"`
+	Algorithm string `json:"algorithm"`
+	Hash      string `json:"hash"`
+	Cost      int    `json:"cost"`
+}
+
+// LocalAPIKey is the hashed API key of the user of the local identity
+// store.
+type LocalAPIKey struct {
+	Usage   string `json:"usage"`
+	Payload string `json:"payload"`
+}
+
+// LocalRole is the role of the user of the local identity store, e.g.
+// "authp/admin".
+type LocalRole struct {
+	Name         string `json:"name"`
+	Organization string `json:"organization,omitempty"`
+}
+
+// ExportLocalUserDatabase returns the local user database of the user
+// secrets under the prefix, e.g. "users/". The username, id, name, email,
+// password, api_key, and roles keys of the secrets map to the users. The
+// other keys are not exported. The password and api_key must be in
+// "bcrypt:<cost>:<hash>" format.
+func ExportLocalUserDatabase(ctx context.Context, c Client, prefix string) (*LocalUserDatabase, error) {
+	paths, err := c.ListSecrets(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	db := &LocalUserDatabase{
+		Version:      localUserDatabaseVersion,
+		Revision:     1,
+		LastModified: now,
+		Users:        []*LocalUser{},
+	}
+	for _, path := range paths {
+		m, err := c.GetSecret(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		user, err := newLocalUser(path, m, now)
+		if err != nil {
+			return nil, err
+		}
+		db.Users = append(db.Users, user)
+	}
+	sort.Slice(db.Users, func(i, j int) bool { return db.Users[i].Username < db.Users[j].Username })
+	return db, nil
+}
+
+// ImportLocalUserDatabase stores the users of the local user database as
+// the user secrets under the prefix, e.g. "users/jsmith". The first email
+// address, password, and API key of each user are imported. It creates
+// the missing secrets and updates the ones that differ, see Sync.
+func ImportLocalUserDatabase(ctx context.Context, c Client, prefix string, db *LocalUserDatabase) ([]*Change, error) {
+	secrets := make(map[string]map[string]interface{}, len(db.Users))
+	for _, user := range db.Users {
+		if !usernameRgx.MatchString(user.Username) {
+			return nil, fmt.Errorf("malformed %q username", user.Username)
+		}
+		path := prefix + user.Username
+		if _, exists := secrets[path]; exists {
+			return nil, fmt.Errorf("duplicate %q user", user.Username)
+		}
+		m, err := user.secret()
+		if err != nil {
+			return nil, fmt.Errorf("user %q: %v", user.Username, err)
+		}
+		secrets[path] = m
+	}
+	return c.Sync(ctx, secrets)
+}
+
+// newLocalUser returns the user of the user secret at the path.
+func newLocalUser(path string, m map[string]interface{}, now time.Time) (*LocalUser, error) {
+	str := func(k string) (string, error) {
+		v, exists := m[k]
+		if !exists {
+			return "", nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("key %q in %q secret is not a string", k, path)
+		}
+		return s, nil
+	}
+	user := &LocalUser{Created: now, LastModified: now}
+	var err error
+	if user.Username, err = str("username"); err != nil {
+		return nil, err
+	}
+	if user.Username == "" {
+		return nil, fmt.Errorf("key %q not found in %q secret", "username", path)
+	}
+	if user.ID, err = str("id"); err != nil {
+		return nil, err
+	}
+	if user.ID == "" {
+		if user.ID, err = newUUID(); err != nil {
+			return nil, err
+		}
+	}
+	name, err := str("name")
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		user.Name = &LocalUserName{First: name}
+		if i := strings.LastIndex(name, " "); i > 0 {
+			user.Name = &LocalUserName{First: name[:i], Last: name[i+1:]}
+		}
+	}
+	email, err := str("email")
+	if err != nil {
+		return nil, err
+	}
+	if email != "" {
+		addr := &LocalEmailAddress{Address: email}
+		if i := strings.LastIndex(email, "@"); i >= 0 {
+			addr.Domain = email[i+1:]
+		}
+		user.EmailAddress = addr
+		user.EmailAddresses = []*LocalEmailAddress{addr}
+	}
+	for _, k := range hashedPasswordKeys {
+		s, err := str(k)
+		if err != nil {
+			return nil, err
+		}
+		if s == "" {
+			continue
+		}
+		hash, err := parseHashedPassword(s)
+		if err != nil {
+			return nil, fmt.Errorf("key %q in %q secret is malformed: %v", k, path, err)
+		}
+		if k == "api_key" {
+			user.APIKeys = []*LocalAPIKey{{Usage: localAPIKeyUsage, Payload: string(hash)}}
+			continue
+		}
+		cost, _ := bcrypt.Cost(hash)
+		user.Passwords = []*LocalPassword{{
+			Purpose:   localPasswordPurpose,
+			Algorithm: localPasswordAlgorithm,
+			Hash:      string(hash),
+			Cost:      cost,
+		}}
+	}
+	if v, exists := m["roles"]; exists {
+		roles, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("key %q in %q secret is not a list", "roles", path)
+		}
+		for _, r := range roles {
+			s, ok := r.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("key %q in %q secret has malformed %v role", "roles", path, r)
+			}
+			role := &LocalRole{Name: s}
+			if i := strings.LastIndex(s, "/"); i >= 0 {
+				role = &LocalRole{Organization: s[:i], Name: s[i+1:]}
+			}
+			user.Roles = append(user.Roles, role)
+		}
+	}
+	return user, nil
+}
+
+// secret returns the key-value map of the user secret of the user.
+func (user *LocalUser) secret() (map[string]interface{}, error) {
+	m := map[string]interface{}{"username": user.Username}
+	if user.ID != "" {
+		m["id"] = user.ID
+	}
+	if user.Name != nil {
+		if name := strings.TrimSpace(user.Name.First + " " + user.Name.Last); name != "" {
+			m["name"] = name
+		}
+	}
+	switch {
+	case user.EmailAddress != nil && user.EmailAddress.Address != "":
+		m["email"] = user.EmailAddress.Address
+	case len(user.EmailAddresses) > 0:
+		m["email"] = user.EmailAddresses[0].Address
+	}
+	for _, p := range user.Passwords {
+		if p.Algorithm != localPasswordAlgorithm || (p.Purpose != "" && p.Purpose != localPasswordPurpose) {
+			continue
+		}
+		s, err := hashedPassword(p.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("malformed password: %v", err)
+		}
+		m["password"] = s
+		break
+	}
+	if len(user.APIKeys) > 0 {
+		s, err := hashedPassword(user.APIKeys[0].Payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed api key: %v", err)
+		}
+		m["api_key"] = s
+	}
+	if len(user.Roles) > 0 {
+		roles := make([]interface{}, 0, len(user.Roles))
+		for _, r := range user.Roles {
+			if r.Organization == "" {
+				roles = append(roles, r.Name)
+				continue
+			}
+			roles = append(roles, r.Organization+"/"+r.Name)
+		}
+		m["roles"] = roles
+	}
+	return m, nil
+}
+
+// hashedPassword returns the bcrypt hash in "bcrypt:<cost>:<hash>" format.
+func hashedPassword(hash string) (string, error) {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("bcrypt:%d:%s", cost, hash), nil
+}
+
+// newUUID returns the random, version 4 UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
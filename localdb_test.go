@@ -0,0 +1,178 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLocalUserDatabase(t *testing.T) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte("foobar"), 4)
+	if err != nil {
+		t.Fatalf("failed hashing password: %v", err)
+	}
+	apiKeyHash, err := bcrypt.GenerateFromPassword([]byte("barfoo"), 5)
+	if err != nil {
+		t.Fatalf("failed hashing api key: %v", err)
+	}
+	store := map[string]string{
+		"authcrunch/users/jsmith": packMapToJSON(t, map[string]interface{}{
+			"id":       "b006d65b-c923-46a1-8da1-7d52558508fe",
+			"username": "jsmith",
+			"name":     "John Smith",
+			"email":    "jsmith@localhost",
+			"password": fmt.Sprintf("bcrypt:4:%s", passwordHash),
+			"api_key":  fmt.Sprintf("bcrypt:5:%s", apiKeyHash),
+			"roles":    []interface{}{"authp/admin", "viewer"},
+		}),
+		"authcrunch/users/mjones": packMapToJSON(t, map[string]interface{}{
+			"username": "mjones",
+		}),
+	}
+	var requests []string
+	c := newWritesTestClient(t, store, &requests, WithBasePrefix("authcrunch/"))
+	ctx := context.TODO()
+
+	db, err := ExportLocalUserDatabase(ctx, c, "users/")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	email := &LocalEmailAddress{Address: "jsmith@localhost", Domain: "localhost"}
+	want := &LocalUserDatabase{
+		Version:  "1.0.0",
+		Revision: 1,
+		Users: []*LocalUser{
+			{
+				ID:             "b006d65b-c923-46a1-8da1-7d52558508fe",
+				Username:       "jsmith",
+				Name:           &LocalUserName{First: "John", Last: "Smith"},
+				EmailAddress:   email,
+				EmailAddresses: []*LocalEmailAddress{email},
+				Passwords:      []*LocalPassword{{Purpose: "generic", Algorithm: "bcrypt", Hash: string(passwordHash), Cost: 4}},
+				APIKeys:        []*LocalAPIKey{{Usage: "api", Payload: string(apiKeyHash)}},
+				Roles:          []*LocalRole{{Organization: "authp", Name: "admin"}, {Name: "viewer"}},
+			},
+			{Username: "mjones"},
+		},
+	}
+	opts := []cmp.Option{
+		cmpopts.IgnoreFields(LocalUserDatabase{}, "LastModified"),
+		cmpopts.IgnoreFields(LocalUser{}, "Created", "LastModified"),
+		cmpopts.IgnoreFields(LocalUser{}, "ID"),
+	}
+	if diff := cmp.Diff(want, db, opts...); diff != "" {
+		t.Fatalf("ExportLocalUserDatabase() mismatch (-want +got):\n%s", diff)
+	}
+	if db.Users[0].ID != want.Users[0].ID {
+		t.Fatalf("unexpected %q user id, want: %q", db.Users[0].ID, want.Users[0].ID)
+	}
+	if len(db.Users[1].ID) != 36 {
+		t.Fatalf("malformed %q generated user id", db.Users[1].ID)
+	}
+
+	// The database survives the round trip through JSON and the import
+	// under another prefix.
+	b, err := json.Marshal(db)
+	if err != nil {
+		t.Fatalf("failed packing database: %v", err)
+	}
+	imported := &LocalUserDatabase{}
+	if err := json.Unmarshal(b, imported); err != nil {
+		t.Fatalf("failed parsing database: %v", err)
+	}
+	changes, err := ImportLocalUserDatabase(ctx, c, "migrated/", imported)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(2, len(changes)); diff != "" {
+		t.Fatalf("ImportLocalUserDatabase() changes mismatch (-want +got):\n%s", diff)
+	}
+	var original, migrated map[string]interface{}
+	json.Unmarshal([]byte(store["authcrunch/users/jsmith"]), &original)
+	json.Unmarshal([]byte(store["authcrunch/migrated/jsmith"]), &migrated)
+	if diff := cmp.Diff(original, migrated); diff != "" {
+		t.Fatalf("migrated user secret mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalUserDatabaseErrors(t *testing.T) {
+	testcases := []struct {
+		name  string
+		store map[string]string
+		db    *LocalUserDatabase
+		err   error
+	}{
+		{
+			name:  "test secret without username",
+			store: map[string]string{"authcrunch/users/jsmith": `{"email":"jsmith@localhost"}`},
+			err:   errors.New(`key "username" not found in "users/jsmith" secret`),
+		},
+		{
+			name:  "test secret with plain text password",
+			store: map[string]string{"authcrunch/users/jsmith": `{"username":"jsmith","password":"foobar"}`},
+			err:   errors.New(`key "password" in "users/jsmith" secret is malformed: not in bcrypt:<cost>:<hash> format`),
+		},
+		{
+			name:  "test secret with malformed roles",
+			store: map[string]string{"authcrunch/users/jsmith": `{"username":"jsmith","roles":"admin"}`},
+			err:   errors.New(`key "roles" in "users/jsmith" secret is not a list`),
+		},
+		{
+			name: "test import malformed username",
+			db:   &LocalUserDatabase{Users: []*LocalUser{{Username: "../jsmith"}}},
+			err:  errors.New(`malformed "../jsmith" username`),
+		},
+		{
+			name: "test import duplicate user",
+			db:   &LocalUserDatabase{Users: []*LocalUser{{Username: "jsmith"}, {Username: "jsmith"}}},
+			err:  errors.New(`duplicate "jsmith" user`),
+		},
+		{
+			name: "test import malformed password hash",
+			db:   &LocalUserDatabase{Users: []*LocalUser{{Username: "jsmith", Passwords: []*LocalPassword{{Algorithm: "bcrypt", Hash: "foobar"}}}}},
+			err:  errors.New(`user "jsmith": malformed password: crypto/bcrypt: hashedSecret too short to be a bcrypted password`),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.store
+			if store == nil {
+				store = make(map[string]string)
+			}
+			var requests []string
+			c := newWritesTestClient(t, store, &requests, WithBasePrefix("authcrunch/"))
+			var err error
+			if tc.db != nil {
+				_, err = ImportLocalUserDatabase(context.TODO(), c, "users/", tc.db)
+			} else {
+				_, err = ExportLocalUserDatabase(context.TODO(), c, "users/")
+			}
+			if err == nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+				t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+			}
+		})
+	}
+}
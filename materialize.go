@@ -0,0 +1,252 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const defaultMaterializedFileMode os.FileMode = 0o400
+
+// MaterializedFile is the file a secret, or the value of its key, is
+// written to.
+type MaterializedFile struct {
+	// Path is the path of the secret.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// Key is the key of the secret whose value is written. When empty, the
+	// key-value map of the secret is written as JSON object. The string
+	// values are written as is, and the others as JSON.
+	Key string `json:"key,omitempty" xml:"key,omitempty" yaml:"key,omitempty"`
+	// Name is the name of the file, relative to the directory of the
+	// materializer, e.g. "ldap/bind_password".
+	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Mode is the permission bits of the file. When zero, it is 0400.
+	Mode os.FileMode `json:"mode,omitempty" xml:"mode,omitempty" yaml:"mode,omitempty"`
+	// Owner is the numeric "uid:gid" the file is owned by. When empty, the
+	// owner is the user of the process.
+	Owner string `json:"owner,omitempty" xml:"owner,omitempty" yaml:"owner,omitempty"`
+
+	uid, gid int
+}
+
+// MaterializerConfig is the configuration of Materializer.
+type MaterializerConfig struct {
+	// Dir is the directory the files are written to. It must be on tmpfs,
+	// so that the secrets never reach the disk.
+	Dir   string              `json:"dir,omitempty" xml:"dir,omitempty" yaml:"dir,omitempty"`
+	Files []*MaterializedFile `json:"files,omitempty" xml:"files,omitempty" yaml:"files,omitempty"`
+	// AllowDisk permits the directory not on tmpfs, e.g. in tests or on
+	// the platforms without tmpfs.
+	AllowDisk bool `json:"allow_disk,omitempty" xml:"allow_disk,omitempty" yaml:"allow_disk,omitempty"`
+}
+
+// Materializer writes the secrets to the files for the consumers able to
+// read the credentials from disk only. The files are replaced atomically,
+// i.e. the consumers read either the old or the new content.
+type Materializer struct {
+	c   Client
+	dir string
+	// files maps the paths of the secrets to their files.
+	files map[string][]*MaterializedFile
+	paths []string
+}
+
+// NewMaterializer returns the materializer writing the secrets retrieved
+// with the client.
+func NewMaterializer(c Client, cfg *MaterializerConfig) (*Materializer, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("materializer dir is empty")
+	}
+	if len(cfg.Files) == 0 {
+		return nil, errors.New("materializer files not found")
+	}
+	if !cfg.AllowDisk {
+		ok, err := isTmpfs(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("materializer dir %q is not on tmpfs", cfg.Dir)
+		}
+	}
+	m := &Materializer{c: c, dir: cfg.Dir, files: make(map[string][]*MaterializedFile)}
+	names := make(map[string]bool)
+	for _, f := range cfg.Files {
+		if err := f.validate(); err != nil {
+			return nil, err
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("duplicate %q materialized file", f.Name)
+		}
+		names[f.Name] = true
+		if _, found := m.files[f.Path]; !found {
+			m.paths = append(m.paths, f.Path)
+		}
+		m.files[f.Path] = append(m.files[f.Path], f)
+	}
+	return m, nil
+}
+
+func (f *MaterializedFile) validate() error {
+	if f.Path == "" {
+		return errors.New("materialized file path is empty")
+	}
+	name := filepath.ToSlash(f.Name)
+	if name == "" || strings.HasPrefix(name, "/") || filepath.Clean(f.Name) != f.Name || strings.HasPrefix(name, "../") || name == ".." {
+		return fmt.Errorf("malformed %q materialized file name", f.Name)
+	}
+	if f.Mode&^os.ModePerm != 0 {
+		return fmt.Errorf("malformed %v materialized file mode", f.Mode)
+	}
+	f.uid, f.gid = -1, -1
+	if f.Owner != "" {
+		uid, gid, found := strings.Cut(f.Owner, ":")
+		var err1, err2 error
+		f.uid, err1 = strconv.Atoi(uid)
+		f.gid, err2 = strconv.Atoi(gid)
+		if !found || err1 != nil || err2 != nil || f.uid < 0 || f.gid < 0 {
+			return fmt.Errorf("malformed %q materialized file owner", f.Owner)
+		}
+	}
+	return nil
+}
+
+// content returns the content of the file for the secret.
+func (f *MaterializedFile) content(m map[string]interface{}) ([]byte, error) {
+	if f.Key == "" {
+		return json.Marshal(m)
+	}
+	v, found := m[f.Key]
+	if !found {
+		return nil, fmt.Errorf("key %q not found in %q secret", f.Key, f.Path)
+	}
+	if s, ok := v.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(v)
+}
+
+// Sync writes the files of all the secrets.
+func (m *Materializer) Sync(ctx context.Context) error {
+	for _, p := range m.paths {
+		secret, err := m.c.GetSecret(ctx, p)
+		if err != nil {
+			return err
+		}
+		if err := m.write(p, secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run writes the files and rewrites them whenever the secrets change, e.g.
+// on rotation, until the context is done. The files of the deleted secrets
+// are removed. It returns the first error writing the files.
+func (m *Materializer) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := m.c.Watch(ctx, m.paths)
+	if err != nil {
+		return err
+	}
+	for ev := range events {
+		switch ev.Type {
+		case SecretAdded, SecretUpdated:
+			err = m.write(ev.Path, ev.Secret)
+		case SecretDeleted:
+			err = m.remove(ev.Path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// write replaces the files of the secret at the path.
+func (m *Materializer) write(path string, secret map[string]interface{}) error {
+	for _, f := range m.files[path] {
+		b, err := f.content(secret)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(m.dir, f.Name), b, f.mode(), f.uid, f.gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove removes the files of the secret at the path.
+func (m *Materializer) remove(path string) error {
+	for _, f := range m.files[path] {
+		if err := os.Remove(filepath.Join(m.dir, f.Name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *MaterializedFile) mode() os.FileMode {
+	if f.Mode == 0 {
+		return defaultMaterializedFileMode
+	}
+	return f.Mode
+}
+
+// writeFileAtomic writes the data to the temporary file in the directory
+// of the file, sets its mode and owner, and renames it to the file.
+func writeFileAtomic(name string, data []byte, mode os.FileMode, uid, gid int) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(name)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if uid >= 0 {
+		if err := tmp.Chown(uid, gid); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
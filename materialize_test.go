@@ -0,0 +1,163 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// waitForFile waits until the content of the file is the wanted one, or
+// until the file is removed when the wanted content is empty.
+func waitForFile(t *testing.T, name, want string) {
+	t.Helper()
+	var got string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		b, err := ioutil.ReadFile(name)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatalf("failed reading file: %v", err)
+		}
+		if got = string(b); got == want {
+			return
+		}
+	}
+	t.Fatalf("file %q content mismatch, got: %q, want: %q", name, got, want)
+}
+
+func TestMaterializer(t *testing.T) {
+	src, dir := t.TempDir(), t.TempDir()
+	writeTestFile(t, filepath.Join(src, "authcrunch", "ldap.json"), `{"bind_dn":"cn=authcrunch","bind_password":"foobar"}`)
+	c, err := NewClient(context.TODO(),
+		WithConfig(&ClientConfig{ID: "foo", Provider: fileProvider, FilePath: src, WatchInterval: "10ms"}),
+		WithBasePrefix("authcrunch/"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	m, err := NewMaterializer(c, &MaterializerConfig{
+		Dir: dir,
+		Files: []*MaterializedFile{
+			{Path: "ldap", Name: "ldap.json"},
+			{Path: "ldap", Key: "bind_password", Name: "ldap/bind_password", Mode: 0o440},
+		},
+		AllowDisk: true,
+	})
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	if err := m.Sync(context.TODO()); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	waitForFile(t, filepath.Join(dir, "ldap.json"), `{"bind_dn":"cn=authcrunch","bind_password":"foobar"}`)
+	waitForFile(t, filepath.Join(dir, "ldap", "bind_password"), "foobar")
+	for name, want := range map[string]os.FileMode{"ldap.json": 0o400, "ldap/bind_password": 0o440} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		if diff := cmp.Diff(want, info.Mode().Perm()); diff != "" {
+			t.Errorf("file %q mode mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+	writeTestFile(t, filepath.Join(src, "authcrunch", "ldap.json"), `{"bind_dn":"cn=authcrunch","bind_password":"barfoo"}`)
+	waitForFile(t, filepath.Join(dir, "ldap", "bind_password"), "barfoo")
+	if err := os.Remove(filepath.Join(src, "authcrunch", "ldap.json")); err != nil {
+		t.Fatalf("failed removing file: %v", err)
+	}
+	waitForFile(t, filepath.Join(dir, "ldap.json"), "")
+	waitForFile(t, filepath.Join(dir, "ldap", "bind_password"), "")
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v, want: %v", err, context.Canceled)
+	}
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "ldap"))
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("unexpected %d temporary files left", len(entries))
+	}
+}
+
+func TestNewMaterializer(t *testing.T) {
+	dir := t.TempDir()
+	testcases := []struct {
+		name string
+		cfg  *MaterializerConfig
+		err  string
+	}{
+		{name: "test empty dir", cfg: &MaterializerConfig{}, err: "materializer dir is empty"},
+		{name: "test no files", cfg: &MaterializerConfig{Dir: dir}, err: "materializer files not found"},
+		{
+			name: "test file outside dir",
+			cfg:  &MaterializerConfig{Dir: dir, AllowDisk: true, Files: []*MaterializedFile{{Path: "ldap", Name: "../ldap.json"}}},
+			err:  `malformed "../ldap.json" materialized file name`,
+		},
+		{
+			name: "test absolute file name",
+			cfg:  &MaterializerConfig{Dir: dir, AllowDisk: true, Files: []*MaterializedFile{{Path: "ldap", Name: "/etc/ldap.json"}}},
+			err:  `malformed "/etc/ldap.json" materialized file name`,
+		},
+		{
+			name: "test duplicate file name",
+			cfg:  &MaterializerConfig{Dir: dir, AllowDisk: true, Files: []*MaterializedFile{{Path: "ldap", Name: "ldap.json"}, {Path: "smtp", Name: "ldap.json"}}},
+			err:  `duplicate "ldap.json" materialized file`,
+		},
+		{
+			name: "test malformed owner",
+			cfg:  &MaterializerConfig{Dir: dir, AllowDisk: true, Files: []*MaterializedFile{{Path: "ldap", Name: "ldap.json", Owner: "caddy"}}},
+			err:  `malformed "caddy" materialized file owner`,
+		},
+		{
+			name: "test malformed mode",
+			cfg:  &MaterializerConfig{Dir: dir, AllowDisk: true, Files: []*MaterializedFile{{Path: "ldap", Name: "ldap.json", Mode: os.ModeSetuid | 0o400}}},
+			err:  `malformed ur-------- materialized file mode`,
+		},
+	}
+	if ok, _ := isTmpfs(dir); !ok {
+		testcases = append(testcases, struct {
+			name string
+			cfg  *MaterializerConfig
+			err  string
+		}{
+			name: "test dir not on tmpfs",
+			cfg:  &MaterializerConfig{Dir: dir, Files: []*MaterializedFile{{Path: "ldap", Name: "ldap.json"}}},
+			err:  `materializer dir "` + dir + `" is not on tmpfs`,
+		})
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewMaterializer(nil, tc.cfg)
+			if err == nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.err, err.Error()); diff != "" {
+				t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// SecretMetadata describes a secret without its value.
+type SecretMetadata struct {
+	Path               string              `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	ARN                string              `json:"arn,omitempty" xml:"arn,omitempty" yaml:"arn,omitempty"`
+	Description        string              `json:"description,omitempty" xml:"description,omitempty" yaml:"description,omitempty"`
+	KMSKeyID           string              `json:"kms_key_id,omitempty" xml:"kms_key_id,omitempty" yaml:"kms_key_id,omitempty"`
+	RotationEnabled    bool                `json:"rotation_enabled,omitempty" xml:"rotation_enabled,omitempty" yaml:"rotation_enabled,omitempty"`
+	CreatedDate        time.Time           `json:"created_date,omitempty" xml:"created_date,omitempty" yaml:"created_date,omitempty"`
+	LastChangedDate    time.Time           `json:"last_changed_date,omitempty" xml:"last_changed_date,omitempty" yaml:"last_changed_date,omitempty"`
+	LastRotatedDate    time.Time           `json:"last_rotated_date,omitempty" xml:"last_rotated_date,omitempty" yaml:"last_rotated_date,omitempty"`
+	NextRotationDate   time.Time           `json:"next_rotation_date,omitempty" xml:"next_rotation_date,omitempty" yaml:"next_rotation_date,omitempty"`
+	DeletedDate        time.Time           `json:"deleted_date,omitempty" xml:"deleted_date,omitempty" yaml:"deleted_date,omitempty"`
+	Tags               map[string]string   `json:"tags,omitempty" xml:"tags,omitempty" yaml:"tags,omitempty"`
+	VersionIdsToStages map[string][]string `json:"version_ids_to_stages,omitempty" xml:"version_ids_to_stages,omitempty" yaml:"version_ids_to_stages,omitempty"`
+}
+
+// ListSecrets returns the paths of the secrets starting with the prefix.
+// The paths are relative to the base prefix, and the ones not allowed by
+// the path policy are omitted.
+func (c *client) ListSecrets(ctx context.Context, prefix string) ([]string, error) {
+	var paths []string
+	if err := c.walkSecrets(ctx, prefix, func(summary *SecretSummary) bool {
+		paths = append(paths, summary.Path)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// DescribeSecret returns the metadata of the secret at the path.
+func (c *client) DescribeSecret(ctx context.Context, path string) (*SecretMetadata, error) {
+	cfg := c.getConfig()
+	if err := cfg.PathPolicy.check(path); err != nil {
+		return nil, err
+	}
+	name, err := cfg.resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var region, endpoint string
+	if route := cfg.route(path); route != nil {
+		region, endpoint = route.Region, route.Endpoint
+	}
+	output, err := c.getServiceClient(region, endpoint).DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	m := &SecretMetadata{
+		Path:               path,
+		ARN:                aws.ToString(output.ARN),
+		Description:        aws.ToString(output.Description),
+		KMSKeyID:           aws.ToString(output.KmsKeyId),
+		RotationEnabled:    aws.ToBool(output.RotationEnabled),
+		CreatedDate:        aws.ToTime(output.CreatedDate),
+		LastChangedDate:    aws.ToTime(output.LastChangedDate),
+		LastRotatedDate:    aws.ToTime(output.LastRotatedDate),
+		NextRotationDate:   aws.ToTime(output.NextRotationDate),
+		DeletedDate:        aws.ToTime(output.DeletedDate),
+		VersionIdsToStages: output.VersionIdsToStages,
+	}
+	if len(output.Tags) > 0 {
+		m.Tags = make(map[string]string, len(output.Tags))
+		for _, tag := range output.Tags {
+			m.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+	return m, nil
+}
+
+// SecretWithMetadata holds the secret along with its metadata, e.g. for
+// the dashboards showing the age of the credentials and their upcoming
+// rotations.
+type SecretWithMetadata struct {
+	// Data is the key-value map of AWSCURRENT version.
+	Data     map[string]interface{} `json:"-" xml:"-" yaml:"-"`
+	Metadata *SecretMetadata        `json:"metadata,omitempty" xml:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// GetSecretWithMetadata returns the current version of the secret at the
+// path along with its metadata, including the last and the next rotation
+// dates.
+func (c *client) GetSecretWithMetadata(ctx context.Context, path string) (*SecretWithMetadata, error) {
+	m, err := c.DescribeSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.getSecretValue(ctx, path, versionStageCurrent)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretWithMetadata{Data: data, Metadata: m}, nil
+}
+
+// checkDescription fetches the description of the secret, when the tag
+// policy, the required KMS key, the staleness policy, or the rotation-aware
+// cache need it, and checks the secret against them. It returns the
+// description, or nil when the secret was not described.
+func (c *client) checkDescription(ctx context.Context, cfg *ClientConfig, api SecretsManagerAPI, secretPath, name string) (*secretsmanager.DescribeSecretOutput, error) {
+	hasTagPolicy := cfg.TagPolicy != nil && len(cfg.TagPolicy.Deny) > 0
+	if !hasTagPolicy && cfg.RequiredKMSKeyID == "" && cfg.Staleness == nil && cfg.RotationCache == nil {
+		return nil, nil
+	}
+	output, err := api.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RequiredKMSKeyID != "" {
+		actual := aws.ToString(output.KmsKeyId)
+		if !matchKMSKeyID(cfg.RequiredKMSKeyID, actual) {
+			if actual == "" {
+				actual = defaultKMSKeyAlias
+			}
+			return nil, fmt.Errorf("%w: %q is encrypted with %q, want %q", ErrKMSKeyMismatch, secretPath, actual, cfg.RequiredKMSKeyID)
+		}
+	}
+	if hasTagPolicy {
+		tags := make(map[string]string, len(output.Tags))
+		for _, tag := range output.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		if err := cfg.TagPolicy.check(secretPath, tags); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Staleness != nil {
+		if err := c.checkStaleness(cfg.Staleness, secretPath, output); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
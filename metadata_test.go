@@ -0,0 +1,151 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newMetadataMockClient returns HTTP client serving the list of secrets in
+// two pages, and the description and the value of a secret.
+func newMetadataMockClient(t *testing.T) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		var input struct {
+			NextToken string
+			SecretId  string
+			Filters   []struct {
+				Key    string
+				Values []string
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return mockFailure(t, "failed parsing request: %v", err)
+		}
+		var response string
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "secretsmanager.ListSecrets":
+			if len(input.Filters) != 1 || input.Filters[0].Values[0] != "authcrunch/users/" {
+				return mockFailure(t, "unexpected filters: %v", input.Filters)
+			}
+			if input.NextToken == "" {
+				response = packMapToJSON(t, map[string]interface{}{
+					"SecretList": []map[string]interface{}{
+						{"Name": "authcrunch/users/mjones"},
+						{"Name": "authcrunch/users/admin"},
+					},
+					"NextToken": "page2",
+				})
+			} else {
+				response = packMapToJSON(t, map[string]interface{}{
+					"SecretList": []map[string]interface{}{
+						{"Name": "authcrunch/users/jsmith"},
+					},
+				})
+			}
+		case "secretsmanager.DescribeSecret":
+			response = packMapToJSON(t, map[string]interface{}{
+				"ARN":              "arn:aws:secretsmanager:us-east-1:123456789012:secret:" + input.SecretId + "-tz6d06",
+				"Name":             input.SecretId,
+				"Description":      "Caddy User Credentials for jsmith",
+				"RotationEnabled":  true,
+				"LastChangedDate":  1673135119,
+				"LastRotatedDate":  1673135119,
+				"NextRotationDate": 1675727119,
+				"Tags": []map[string]interface{}{
+					{"Key": "env", "Value": "prod"},
+				},
+				"VersionIdsToStages": map[string]interface{}{
+					"278a2e61-f3e3-4280-a444-333d7186d5ce": []string{"AWSCURRENT"},
+				},
+			})
+		case "secretsmanager.GetSecretValue":
+			response = packMapToJSON(t, map[string]interface{}{
+				"Name":         input.SecretId,
+				"SecretString": `{"username":"jsmith"}`,
+			})
+		default:
+			return mockFailure(t, "unexpected %q target", target)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(response)),
+		}, nil
+	})
+}
+
+func TestSecretMetadata(t *testing.T) {
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithBasePrefix("authcrunch/"),
+		WithPathPolicy(&PathPolicyConfig{Deny: []string{"users/admin"}}),
+		WithHTTPClient(newMetadataMockClient(t)),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+
+	paths, err := c.ListSecrets(context.TODO(), "users/")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"users/jsmith", "users/mjones"}, paths); diff != "" {
+		t.Errorf("ListSecrets() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := c.DescribeSecret(context.TODO(), "users/jsmith")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	want := &SecretMetadata{
+		Path:             "users/jsmith",
+		ARN:              "arn:aws:secretsmanager:us-east-1:123456789012:secret:authcrunch/users/jsmith-tz6d06",
+		Description:      "Caddy User Credentials for jsmith",
+		RotationEnabled:  true,
+		LastChangedDate:  time.Unix(1673135119, 0),
+		LastRotatedDate:  time.Unix(1673135119, 0),
+		NextRotationDate: time.Unix(1675727119, 0),
+		Tags:             map[string]string{"env": "prod"},
+		VersionIdsToStages: map[string][]string{
+			"278a2e61-f3e3-4280-a444-333d7186d5ce": {"AWSCURRENT"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DescribeSecret() mismatch (-want +got):\n%s", diff)
+	}
+
+	s, err := c.GetSecretWithMetadata(context.TODO(), "users/jsmith")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(&SecretWithMetadata{Data: map[string]interface{}{"username": "jsmith"}, Metadata: want}, s); diff != "" {
+		t.Errorf("GetSecretWithMetadata() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := c.DescribeSecret(context.TODO(), "users/admin"); err == nil {
+		t.Fatalf("unexpected success for the path denied by policy")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+)
+
+// OAuthClientCredentials holds the credentials of OAuth 2.0 or OpenID
+// Connect client registered with an identity provider.
+type OAuthClientCredentials struct {
+	ClientID     string `json:"client_id,omitempty" xml:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty" xml:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	// PreviousClientSecret is the client secret prior to the last rotation.
+	// It is empty when the secret has not been rotated yet, or the client
+	// ID changed during the rotation.
+	PreviousClientSecret string `json:"previous_client_secret,omitempty" xml:"previous_client_secret,omitempty" yaml:"previous_client_secret,omitempty"`
+	// Extra holds the other keys of the secret, e.g. tenant_id or scopes.
+	Extra map[string]interface{} `json:"extra,omitempty" xml:"-" yaml:"extra,omitempty"`
+}
+
+// GetOAuthClientCredentials returns the OAuth client credentials stored
+// in the client_id and client_secret keys of the secret. The previous
+// client secret is retrieved from the AWSPREVIOUS version of the secret.
+// Both versions are cached when the caching is enabled.
+func (c *client) GetOAuthClientCredentials(ctx context.Context, path string) (*OAuthClientCredentials, error) {
+	m, err := c.getSecretValue(ctx, path, versionStageCurrent)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := parseOAuthClientCredentials(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed current version of %q secret: %v", path, err)
+	}
+
+	m, err = c.getSecretValue(ctx, path, versionStagePrevious)
+	if err != nil {
+		if isNotFound(err) {
+			return creds, nil
+		}
+		return nil, err
+	}
+	previous, err := parseOAuthClientCredentials(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed previous version of %q secret: %v", path, err)
+	}
+	if previous.ClientID == creds.ClientID && previous.ClientSecret != creds.ClientSecret {
+		creds.PreviousClientSecret = previous.ClientSecret
+	}
+	return creds, nil
+}
+
+func parseOAuthClientCredentials(m map[string]interface{}) (*OAuthClientCredentials, error) {
+	creds := &OAuthClientCredentials{}
+	var err error
+	if creds.ClientID, err = getStringValue(m, "client_id", true); err != nil {
+		return nil, err
+	}
+	if creds.ClientSecret, err = getStringValue(m, "client_secret", true); err != nil {
+		return nil, err
+	}
+	creds.Extra = getExtraValues(m, "client_id", "client_secret")
+	return creds, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetOAuthClientCredentials(t *testing.T) {
+	testcases := []struct {
+		name      string
+		path      string
+		stages    map[string]map[string]interface{}
+		want      *OAuthClientCredentials
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test rotated oauth client secret",
+			path: "authcrunch/caddy/google_oauth",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"client_id":     "foo.apps.googleusercontent.com",
+					"client_secret": "GOCSPX-bar",
+					"scopes":        "openid email profile",
+				},
+				"AWSPREVIOUS": {
+					"client_id":     "foo.apps.googleusercontent.com",
+					"client_secret": "GOCSPX-baz",
+				},
+			},
+			want: &OAuthClientCredentials{
+				ClientID:             "foo.apps.googleusercontent.com",
+				ClientSecret:         "GOCSPX-bar",
+				PreviousClientSecret: "GOCSPX-baz",
+				Extra: map[string]interface{}{
+					"scopes": "openid email profile",
+				},
+			},
+		},
+		{
+			name: "test oauth client secret without previous version",
+			path: "authcrunch/caddy/google_oauth",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"client_id":     "foo.apps.googleusercontent.com",
+					"client_secret": "GOCSPX-bar",
+				},
+			},
+			want: &OAuthClientCredentials{
+				ClientID:     "foo.apps.googleusercontent.com",
+				ClientSecret: "GOCSPX-bar",
+			},
+		},
+		{
+			name: "test oauth client secret with changed client id",
+			path: "authcrunch/caddy/google_oauth",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"client_id":     "foo.apps.googleusercontent.com",
+					"client_secret": "GOCSPX-bar",
+				},
+				"AWSPREVIOUS": {
+					"client_id":     "bar.apps.googleusercontent.com",
+					"client_secret": "GOCSPX-baz",
+				},
+			},
+			want: &OAuthClientCredentials{
+				ClientID:     "foo.apps.googleusercontent.com",
+				ClientSecret: "GOCSPX-bar",
+			},
+		},
+		{
+			name: "test oauth client secret without client secret",
+			path: "authcrunch/caddy/google_oauth",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"client_id": "foo.apps.googleusercontent.com",
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed current version of %q secret: key %q not found", "authcrunch/caddy/google_oauth", "client_secret"),
+		},
+		{
+			name: "test malformed previous version of oauth client secret",
+			path: "authcrunch/caddy/google_oauth",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"client_id":     "foo.apps.googleusercontent.com",
+					"client_secret": "GOCSPX-bar",
+				},
+				"AWSPREVIOUS": {
+					"client_id":     "foo.apps.googleusercontent.com",
+					"client_secret": 12345,
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed previous version of %q secret: key %q value is not a string", "authcrunch/caddy/google_oauth", "client_secret"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+
+			c.SetMockClient(newStagedMockClient(t, tc.stages))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetOAuthClientCredentials(context.TODO(), tc.path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("GetOAuthClientCredentials() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetOAuthClientCredentials() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.uber.org/zap"
+)
+
+// Option configures the client created by NewClient. The options are
+// applied in order, so the later options override the earlier ones.
+type Option func(*client) error
+
+// WithConfig replaces the client configuration with the provided one.
+func WithConfig(cfg *ClientConfig) Option {
+	return func(c *client) error {
+		if cfg == nil {
+			return errors.New("client config is nil")
+		}
+		clientConfig := *cfg
+		c.config = &clientConfig
+		return nil
+	}
+}
+
+// WithID sets the client ID.
+func WithID(id string) Option {
+	return func(c *client) error {
+		c.config.ID = id
+		return nil
+	}
+}
+
+// WithRegion sets the AWS region of AWS Secrets Manager.
+func WithRegion(region string) Option {
+	return func(c *client) error {
+		c.config.Region = region
+		return nil
+	}
+}
+
+// WithReadOnly makes the methods mutating secrets return ErrReadOnly.
+func WithReadOnly() Option {
+	return func(c *client) error {
+		c.config.ReadOnly = true
+		return nil
+	}
+}
+
+// WithDryRun makes the methods mutating secrets log and return the changes
+// without applying them.
+func WithDryRun() Option {
+	return func(c *client) error {
+		c.config.DryRun = true
+		return nil
+	}
+}
+
+// WithFileProvider makes the client read the secrets from the directory
+// of JSON files or the YAML or JSON document at the path instead of AWS
+// Secrets Manager.
+func WithFileProvider(path string) Option {
+	return func(c *client) error {
+		c.config.Provider = fileProvider
+		c.config.FilePath = path
+		return nil
+	}
+}
+
+// WithProxyProvider makes the client read the secrets from the caching
+// proxy at the URL instead of AWS Secrets Manager. See NewProxyHandler.
+func WithProxyProvider(url string) Option {
+	return func(c *client) error {
+		c.config.Provider = proxyProvider
+		c.config.ProxyURL = url
+		return nil
+	}
+}
+
+// WithGRPCProvider makes the client read the secrets from the gRPC server
+// at the target, e.g. "127.0.0.1:8201", instead of AWS Secrets Manager.
+// The server requires mutual TLS, so the client certificate must be set
+// with WithProxyTLS. See NewGRPCServer.
+func WithGRPCProvider(target string) Option {
+	return func(c *client) error {
+		c.config.Provider = grpcProvider
+		c.config.ProxyURL = target
+		return nil
+	}
+}
+
+// WithProxyTLS sets the client certificate of the "proxy" and "grpc"
+// providers, when the server requires mutual TLS.
+func WithProxyTLS(cfg *ProxyTLSConfig) Option {
+	return func(c *client) error {
+		c.config.ProxyTLS = cfg
+		return nil
+	}
+}
+
+// WithPathPolicy restricts the paths of the secrets the client may access.
+func WithPathPolicy(policy *PathPolicyConfig) Option {
+	return func(c *client) error {
+		c.config.PathPolicy = policy
+		return nil
+	}
+}
+
+// WithTagPolicy refuses to return the secrets carrying the denied tags.
+func WithTagPolicy(policy *TagPolicyConfig) Option {
+	return func(c *client) error {
+		c.config.TagPolicy = policy
+		return nil
+	}
+}
+
+// WithRequiredKMSKey makes the client fail fetching the secrets not
+// encrypted with the KMS key having the ARN, alias or ID.
+func WithRequiredKMSKey(id string) Option {
+	return func(c *client) error {
+		c.config.RequiredKMSKeyID = id
+		return nil
+	}
+}
+
+// WithChecksumKey makes the client verify the checksum stored under the
+// key on fetch and set it on write.
+func WithChecksumKey(key string) Option {
+	return func(c *client) error {
+		c.config.ChecksumKey = key
+		return nil
+	}
+}
+
+// WithKeyNormalization normalizes the keys of the fetched secrets, e.g.
+// with KeyNormalizationLowercase.
+func WithKeyNormalization(modes ...string) Option {
+	return func(c *client) error {
+		c.config.KeyNormalization = modes
+		return nil
+	}
+}
+
+// WithTLS sets the minimum TLS version and the cipher suites of the HTTP
+// client.
+func WithTLS(cfg *TLSConfig) Option {
+	return func(c *client) error {
+		c.config.TLS = cfg
+		return nil
+	}
+}
+
+// WithSecretLimits rejects the fetched secrets exceeding the limits.
+func WithSecretLimits(limits *SecretLimitsConfig) Option {
+	return func(c *client) error {
+		c.config.Limits = limits
+		return nil
+	}
+}
+
+// WithStaleness warns about or refuses the secrets not rotated within the
+// maximum age.
+func WithStaleness(cfg *StalenessConfig) Option {
+	return func(c *client) error {
+		c.config.Staleness = cfg
+		return nil
+	}
+}
+
+// WithSSMFallback looks up the secrets not found in AWS Secrets Manager in
+// AWS Systems Manager Parameter Store.
+func WithSSMFallback(cfg *SSMFallbackConfig) Option {
+	return func(c *client) error {
+		c.config.SSMFallback = cfg
+		return nil
+	}
+}
+
+// WithS3Overflow follows the secrets pointing to the objects in Amazon S3
+// holding their content. See S3OverflowConfig.
+func WithS3Overflow(cfg *S3OverflowConfig) Option {
+	return func(c *client) error {
+		c.config.S3Overflow = cfg
+		return nil
+	}
+}
+
+// WithDynamoDBCache shares the retrieved secrets across the instances of a
+// fleet through DynamoDB table. See DynamoDBCacheConfig.
+func WithDynamoDBCache(cfg *DynamoDBCacheConfig) Option {
+	return func(c *client) error {
+		c.config.DynamoDBCache = cfg
+		return nil
+	}
+}
+
+// WithWebhook notifies the webhook about the updated and the deleted
+// secrets. See WebhookConfig.
+func WithWebhook(cfg *WebhookConfig) Option {
+	return func(c *client) error {
+		c.config.Webhook = cfg
+		return nil
+	}
+}
+
+// WithRotationCache shortens or disables the caching of the secrets with a
+// rotation in progress. See RotationCacheConfig.
+func WithRotationCache(cfg *RotationCacheConfig) Option {
+	return func(c *client) error {
+		c.config.RotationCache = cfg
+		return nil
+	}
+}
+
+// WithVersionPins pins the secrets at the paths to the versions. See
+// VersionPin.
+func WithVersionPins(pins ...*VersionPin) Option {
+	return func(c *client) error {
+		c.config.VersionPins = append(c.config.VersionPins, pins...)
+		return nil
+	}
+}
+
+// WithFallbackRegions sets the regions tried in order when the preferred
+// region is unavailable.
+func WithFallbackRegions(regions ...string) Option {
+	return func(c *client) error {
+		c.config.FallbackRegions = regions
+		return nil
+	}
+}
+
+// WithEndpoint sets the URL of AWS Secrets Manager endpoint, e.g. VPC
+// endpoint or local emulator.
+func WithEndpoint(endpoint string) Option {
+	return func(c *client) error {
+		c.config.Endpoint = endpoint
+		return nil
+	}
+}
+
+// WithRoleARN sets the ARN of IAM role the client assumes to access
+// AWS Secrets Manager.
+func WithRoleARN(arn string) Option {
+	return func(c *client) error {
+		c.config.RoleARN = arn
+		return nil
+	}
+}
+
+// WithBasePrefix sets the prefix prepended to the paths of all secrets.
+func WithBasePrefix(prefix string) Option {
+	return func(c *client) error {
+		c.config.BasePrefix = prefix
+		return nil
+	}
+}
+
+// WithCacheTTL enables the caching of the retrieved secrets.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *client) error {
+		c.config.CacheTTL = ttl.String()
+		return nil
+	}
+}
+
+// WithDefaultTimeout sets the timeout of the calls of the SimpleClient.
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(c *client) error {
+		c.config.DefaultTimeout = timeout.String()
+		return nil
+	}
+}
+
+// WithMaxRetries sets the maximum number of retries of failed requests.
+func WithMaxRetries(n int) Option {
+	return func(c *client) error {
+		c.config.MaxRetries = n
+		return nil
+	}
+}
+
+// WithLogger sets the logger.
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *client) error {
+		if logger == nil {
+			return errors.New("logger is nil")
+		}
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests to AWS.
+func WithHTTPClient(httpClient aws.HTTPClient) Option {
+	return func(c *client) error {
+		if httpClient == nil {
+			return errors.New("http client is nil")
+		}
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithSecretsManagerAPI makes the client send all requests to the API
+// implementation, e.g. a test double, instead of AWS Secrets Manager. The
+// regions and the endpoints of the routes do not apply to it.
+func WithSecretsManagerAPI(api SecretsManagerAPI) Option {
+	return func(c *client) error {
+		if api == nil {
+			return errors.New("secrets manager api is nil")
+		}
+		c.api = api
+		return nil
+	}
+}
+
+// WithFaultInjection makes the client inject the faults into the requests
+// to AWS services. It is meant for testing the retry and fallback behavior
+// of the applications. The faults are not injected into the requests
+// served by the API set with WithSecretsManagerAPI.
+func WithFaultInjection(cfg *FaultConfig) Option {
+	return func(c *client) error {
+		if cfg == nil {
+			return errors.New("fault config is nil")
+		}
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		faults := *cfg
+		c.faults = &faults
+		return nil
+	}
+}
+
+// WithAnomalyDetection makes the client track the reads of the secrets
+// and report, and optionally block, the spikes exceeding the baseline.
+func WithAnomalyDetection(cfg *AnomalyConfig) Option {
+	return func(c *client) error {
+		if cfg == nil {
+			return errors.New("anomaly config is nil")
+		}
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		c.anomalies = newAnomalyDetector(cfg)
+		return nil
+	}
+}
+
+// WithCanaryValidation validates the new versions of the secrets before
+// they are served and cached. See CanaryConfig.
+func WithCanaryValidation(cfg *CanaryConfig) Option {
+	return func(c *client) error {
+		if cfg == nil {
+			return errors.New("canary config is nil")
+		}
+		if err := cfg.validate(); err != nil {
+			return err
+		}
+		c.canary = newCanaryValidator(cfg)
+		return nil
+	}
+}
+
+// WithAuditSink makes the client send the records of the access to the
+// secrets to the sink, e.g. the hash-chained AuditLog.
+func WithAuditSink(sink AuditSink) Option {
+	return func(c *client) error {
+		if sink == nil {
+			return errors.New("audit sink is nil")
+		}
+		c.auditSink = sink
+		return nil
+	}
+}
+
+// WithClock sets the clock of the cache and the watchers, replacing the
+// system clock in the tests.
+func WithClock(clock Clock) Option {
+	return func(c *client) error {
+		if clock == nil {
+			return errors.New("clock is nil")
+		}
+		c.clock = clock
+		return nil
+	}
+}
+
+// WithRandSource sets the source of the retry backoff jitter and the
+// injected faults, making them reproducible in the tests and simulations.
+func WithRandSource(src rand.Source) Option {
+	return func(c *client) error {
+		if src == nil {
+			return errors.New("rand source is nil")
+		}
+		c.rand = newLockedRand(src)
+		return nil
+	}
+}
+
+// WithCredentialsProvider sets the provider of AWS credentials. It takes
+// precedence over the role configured with WithRoleARN.
+func WithCredentialsProvider(provider aws.CredentialsProvider) Option {
+	return func(c *client) error {
+		if provider == nil {
+			return errors.New("credentials provider is nil")
+		}
+		c.credentials = provider
+		return nil
+	}
+}
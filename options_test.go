@@ -0,0 +1,171 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"go.uber.org/zap"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestNewClientWithOptions(t *testing.T) {
+	testcases := []struct {
+		name      string
+		opts      []Option
+		want      *ClientConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test new client with all options",
+			opts: []Option{
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithEndpoint("https://vpce-0123.secretsmanager.us-east-1.vpce.amazonaws.com"),
+				WithRoleARN("arn:aws:iam::123456789012:role/AuthCrunchSecretsManagerAccess"),
+				WithCacheTTL(5 * time.Minute),
+				WithMaxRetries(5),
+				WithLogger(zap.NewNop()),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			},
+			want: &ClientConfig{
+				ID:         "foo",
+				Region:     "us-east-1",
+				Provider:   "aws_secrets_manager",
+				Endpoint:   "https://vpce-0123.secretsmanager.us-east-1.vpce.amazonaws.com",
+				RoleARN:    "arn:aws:iam::123456789012:role/AuthCrunchSecretsManagerAccess",
+				CacheTTL:   "5m0s",
+				MaxRetries: 5,
+			},
+		},
+		{
+			name: "test new client with options overriding config",
+			opts: []Option{
+				WithConfig(&ClientConfig{ID: "foo", Region: "us-east-1"}),
+				WithRegion("us-west-2"),
+			},
+			want: &ClientConfig{
+				ID:       "foo",
+				Region:   "us-west-2",
+				Provider: "aws_secrets_manager",
+			},
+		},
+		{
+			name:      "test new client without id",
+			opts:      []Option{WithRegion("us-east-1")},
+			shouldErr: true,
+			err:       errors.New("client id is empty"),
+		},
+		{
+			name:      "test new client with malformed endpoint",
+			opts:      []Option{WithID("foo"), WithEndpoint("vpce-0123")},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q endpoint", "vpce-0123"),
+		},
+		{
+			name:      "test new client with malformed role arn",
+			opts:      []Option{WithID("foo"), WithRoleARN("arn:aws:iam::123456789012:user/foo")},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q role arn", "arn:aws:iam::123456789012:user/foo"),
+		},
+		{
+			name:      "test new client with negative max retries",
+			opts:      []Option{WithID("foo"), WithMaxRetries(-1)},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %d max retries", -1),
+		},
+		{
+			name:      "test new client with nil logger",
+			opts:      []Option{WithID("foo"), WithLogger(nil)},
+			shouldErr: true,
+			err:       errors.New("logger is nil"),
+		},
+		{
+			name:      "test new client with nil config",
+			opts:      []Option{WithConfig(nil)},
+			shouldErr: true,
+			err:       errors.New("client config is nil"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), tc.opts...)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("NewClient() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, c.(*client).getConfig()); diff != "" {
+				t.Errorf("NewClient() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewClientWithEndpoint(t *testing.T) {
+	var hosts []string
+	httpClient := smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		hosts = append(hosts, r.URL.Host)
+		return secretsmock.SecretStringResponse(map[string]interface{}{"foo": "bar"}), nil
+	})
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithEndpoint("https://secretsmanager.example.com"),
+		WithHTTPClient(httpClient),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	if _, err := c.GetSecret(context.TODO(), "authcrunch/caddy/foo"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"secretsmanager.example.com"}, hosts); diff != "" {
+		t.Errorf("GetSecret() hosts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewClientWithRegion(t *testing.T) {
+	c, err := NewClientWithRegion(context.TODO(), "foo", "us-east-1")
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	want := map[string]interface{}{
+		"id":       "foo",
+		"region":   "us-east-1",
+		"provider": "aws_secrets_manager",
+	}
+	if diff := cmp.Diff(want, c.GetConfig(context.TODO()).Map()); diff != "" {
+		t.Errorf("NewClientWithRegion() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// VerifyUserPassword compares the candidate password with the bcrypt hash
+// stored in the password key of the user secret. The hash never leaves
+// the package, the caller learns only whether the password matches.
+func (c *client) VerifyUserPassword(ctx context.Context, path, candidate string) (bool, error) {
+	secret, err := c.GetSecret(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	v, exists := secret["password"]
+	if !exists {
+		return false, fmt.Errorf("key %q not found in %q secret", "password", path)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false, fmt.Errorf("key %q in %q secret is not a string", "password", path)
+	}
+	hash, err := parseHashedPassword(s)
+	if err != nil {
+		return false, fmt.Errorf("key %q in %q secret is malformed: %v", "password", path, err)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(candidate)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return false, fmt.Errorf("key %q in %q secret is malformed: %v", "password", path, err)
+	}
+	return true, nil
+}
+
+// parseHashedPassword returns the hash of the password stored in
+// "bcrypt:<cost>:<hash>" format.
+func parseHashedPassword(s string) ([]byte, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[0] != "bcrypt" {
+		return nil, errors.New("not in bcrypt:<cost>:<hash> format")
+	}
+	cost, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, errors.New("bcrypt cost is not a number")
+	}
+	hash := []byte(parts[2])
+	hashCost, err := bcrypt.Cost(hash)
+	if err != nil {
+		return nil, err
+	}
+	if cost != hashCost {
+		return nil, fmt.Errorf("bcrypt cost %d does not match hash cost %d", cost, hashCost)
+	}
+	return hash, nil
+}
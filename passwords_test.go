@@ -0,0 +1,119 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestVerifyUserPassword(t *testing.T) {
+	testcases := []struct {
+		name      string
+		password  interface{}
+		candidate string
+		want      bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:      "test matching password",
+			password:  "bcrypt:10:$2a$10$iqq53VjdCwknBSBrnyLd9OH1Mfh6kqPezMMy6h6F41iLdVDkj13I6",
+			candidate: "rbrH97m9bpbk3qRphHFNM9ksJfRcWdvr",
+			want:      true,
+		},
+		{
+			name:      "test mismatched password",
+			password:  "bcrypt:10:$2a$10$iqq53VjdCwknBSBrnyLd9OH1Mfh6kqPezMMy6h6F41iLdVDkj13I6",
+			candidate: "kqvc7cgk44dtpX9nXx4NL9krH4g7fqdJ",
+		},
+		{
+			name:      "test plaintext password",
+			password:  "rbrH97m9bpbk3qRphHFNM9ksJfRcWdvr",
+			candidate: "rbrH97m9bpbk3qRphHFNM9ksJfRcWdvr",
+			shouldErr: true,
+			err: fmt.Errorf("key %q in %q secret is malformed: %v",
+				"password", "authcrunch/caddy/users/jsmith", "not in bcrypt:<cost>:<hash> format",
+			),
+		},
+		{
+			name:      "test password with mismatched cost",
+			password:  "bcrypt:12:$2a$10$iqq53VjdCwknBSBrnyLd9OH1Mfh6kqPezMMy6h6F41iLdVDkj13I6",
+			candidate: "rbrH97m9bpbk3qRphHFNM9ksJfRcWdvr",
+			shouldErr: true,
+			err: fmt.Errorf("key %q in %q secret is malformed: %v",
+				"password", "authcrunch/caddy/users/jsmith", "bcrypt cost 12 does not match hash cost 10",
+			),
+		},
+		{
+			name:      "test password that is not a string",
+			password:  12345,
+			candidate: "12345",
+			shouldErr: true,
+			err:       fmt.Errorf("key %q in %q secret is not a string", "password", "authcrunch/caddy/users/jsmith"),
+		},
+		{
+			name:      "test secret without password",
+			candidate: "rbrH97m9bpbk3qRphHFNM9ksJfRcWdvr",
+			shouldErr: true,
+			err:       fmt.Errorf("key %q not found in %q secret", "password", "authcrunch/caddy/users/jsmith"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+
+			secret := map[string]interface{}{
+				"username": "jsmith",
+			}
+			if tc.password != nil {
+				secret["password"] = tc.password
+			}
+			c.SetMockClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+				return secretsmock.SecretStringResponse(secret), nil
+			}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.VerifyUserPassword(context.TODO(), "authcrunch/caddy/users/jsmith", tc.candidate)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("VerifyUserPassword() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("VerifyUserPassword() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
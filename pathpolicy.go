@@ -0,0 +1,95 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const pathPatternRegexPrefix = "regex:"
+
+// ErrPathNotAllowed is returned when the path policy does not allow
+// access to a secret.
+var ErrPathNotAllowed = errors.New("secret path not allowed by policy")
+
+// PathPolicyConfig restricts the paths of the secrets the client may
+// access. A pattern is either a path.Match pattern, e.g.
+// "authcrunch/users/*", or a regular expression prefixed with "regex:",
+// e.g. "regex:^authcrunch/(users|tokens)/". A path matching any of the
+// Deny patterns is rejected. When Allow patterns are present, a path must
+// match one of them.
+type PathPolicyConfig struct {
+	Allow []string `json:"allow,omitempty" xml:"allow,omitempty" yaml:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty" xml:"deny,omitempty" yaml:"deny,omitempty"`
+}
+
+func (p *PathPolicyConfig) validate() error {
+	for _, pattern := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if err := validatePathPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePathPattern(pattern string) error {
+	if pattern == "" {
+		return errors.New("path policy pattern is empty")
+	}
+	if strings.HasPrefix(pattern, pathPatternRegexPrefix) {
+		if _, err := regexp.Compile(strings.TrimPrefix(pattern, pathPatternRegexPrefix)); err != nil {
+			return fmt.Errorf("malformed %q path policy pattern: %v", pattern, err)
+		}
+		return nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("malformed %q path policy pattern: %v", pattern, err)
+	}
+	return nil
+}
+
+func matchPathPattern(pattern, secretPath string) bool {
+	if strings.HasPrefix(pattern, pathPatternRegexPrefix) {
+		return regexp.MustCompile(strings.TrimPrefix(pattern, pathPatternRegexPrefix)).MatchString(secretPath)
+	}
+	matched, _ := path.Match(pattern, secretPath)
+	return matched
+}
+
+// check returns ErrPathNotAllowed when the policy does not allow access to
+// the secret at the path. The path is relative to the base prefix.
+func (p *PathPolicyConfig) check(secretPath string) error {
+	if p == nil {
+		return nil
+	}
+	for _, pattern := range p.Deny {
+		if matchPathPattern(pattern, secretPath) {
+			return fmt.Errorf("%w: %q matches %q deny pattern", ErrPathNotAllowed, secretPath, pattern)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	for _, pattern := range p.Allow {
+		if matchPathPattern(pattern, secretPath) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q matches no allow pattern", ErrPathNotAllowed, secretPath)
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestPathPolicy(t *testing.T) {
+	testcases := []struct {
+		name      string
+		policy    *PathPolicyConfig
+		path      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test path without policy",
+			path: "prod/aws/root",
+		},
+		{
+			name:   "test path matching allow glob",
+			policy: &PathPolicyConfig{Allow: []string{"authcrunch/users/*"}},
+			path:   "authcrunch/users/jsmith",
+		},
+		{
+			name:   "test path matching allow regex",
+			policy: &PathPolicyConfig{Allow: []string{"regex:^authcrunch/(users|tokens)/"}},
+			path:   "authcrunch/tokens/github/access",
+		},
+		{
+			name:      "test path matching no allow pattern",
+			policy:    &PathPolicyConfig{Allow: []string{"authcrunch/users/*"}},
+			path:      "prod/aws/root",
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q matches no allow pattern", ErrPathNotAllowed, "prod/aws/root"),
+		},
+		{
+			name: "test path matching deny pattern",
+			policy: &PathPolicyConfig{
+				Allow: []string{"regex:^authcrunch/"},
+				Deny:  []string{"authcrunch/admin/*"},
+			},
+			path:      "authcrunch/admin/root",
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q matches %q deny pattern", ErrPathNotAllowed, "authcrunch/admin/root", "authcrunch/admin/*"),
+		},
+		{
+			name:   "test path not matching deny pattern",
+			policy: &PathPolicyConfig{Deny: []string{"authcrunch/admin/*"}},
+			path:   "authcrunch/users/jsmith",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.check(tc.path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, ErrPathNotAllowed) {
+					t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestPathPolicyValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		policy    *PathPolicyConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test valid policy",
+			policy: &PathPolicyConfig{Allow: []string{"authcrunch/*", "regex:^shared/"}, Deny: []string{"authcrunch/admin"}},
+		},
+		{
+			name:      "test empty pattern",
+			policy:    &PathPolicyConfig{Allow: []string{""}},
+			shouldErr: true,
+			err:       errors.New("path policy pattern is empty"),
+		},
+		{
+			name:      "test malformed glob",
+			policy:    &PathPolicyConfig{Deny: []string{"authcrunch/["}},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q path policy pattern: syntax error in pattern", "authcrunch/["),
+		},
+		{
+			name:      "test malformed regex",
+			policy:    &PathPolicyConfig{Allow: []string{"regex:^(authcrunch"}},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q path policy pattern: error parsing regexp: missing closing ): `^(authcrunch`", "regex:^(authcrunch"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.validate()
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestGetSecretWithPathPolicy(t *testing.T) {
+	srv := secretsmock.NewServer(t)
+	srv.SetSecret("authcrunch/users/jsmith", packMapToJSON(t, map[string]interface{}{"username": "jsmith"}))
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithPathPolicy(&PathPolicyConfig{Allow: []string{"authcrunch/users/*"}}),
+		WithEndpoint(srv.URL),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	if _, err := c.GetSecret(context.TODO(), "authcrunch/users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := c.GetSecret(context.TODO(), "prod/aws/root"); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+	}
+	if diff := cmp.Diff(1, len(srv.Requests())); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+}
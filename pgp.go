@@ -0,0 +1,85 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// GetPGPEntities returns the PGP entities of the ASCII-armored public or
+// private keys stored in the key of the secret, e.g. to encrypt the
+// notification payloads or to sign the audit exports. The encrypted
+// private keys are decrypted with the passphrase stored in the key with
+// the "_passphrase" suffix, e.g. "private_key_passphrase" for
+// "private_key".
+func (c *client) GetPGPEntities(ctx context.Context, path, key string) (openpgp.EntityList, error) {
+	m, err := c.GetSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := parsePGPEntities(m, key)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	return entities, nil
+}
+
+func parsePGPEntities(m map[string]interface{}, key string) (openpgp.EntityList, error) {
+	armored, err := getStringValue(m, key, true)
+	if err != nil {
+		return nil, err
+	}
+	passphraseKey := key + passphraseKeySuffix
+	passphrase, err := getStringValue(m, passphraseKey, false)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("key %q value is malformed: %v", key, err)
+	}
+	decrypt := func(pk *packet.PrivateKey) error {
+		if pk == nil || !pk.Encrypted {
+			return nil
+		}
+		if passphrase == "" {
+			return fmt.Errorf("key %q value is encrypted, but key %q not found", key, passphraseKey)
+		}
+		if err := pk.Decrypt([]byte(passphrase)); err != nil {
+			return fmt.Errorf("key %q value is malformed: %v", key, err)
+		}
+		return nil
+	}
+	for _, entity := range entities {
+		if err := decrypt(entity.PrivateKey); err != nil {
+			return nil, err
+		}
+		for _, subkey := range entity.Subkeys {
+			if err := decrypt(subkey.PrivateKey); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(entities) == 0 {
+		return nil, errors.New("pgp keys not found")
+	}
+	return entities, nil
+}
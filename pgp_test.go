@@ -0,0 +1,133 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// newTestPGPKeys returns the ASCII-armored public and private keys of the
+// new PGP entity.
+func newTestPGPKeys(t *testing.T) (string, string) {
+	entity, err := openpgp.NewEntity("Audit Exporter", "", "audit@authcrunch.local", nil)
+	if err != nil {
+		t.Fatalf("failed generating pgp entity: %v", err)
+	}
+	var public, private bytes.Buffer
+	w, err := armor.Encode(&public, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("failed encoding pgp key: %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("failed serializing pgp key: %v", err)
+	}
+	w.Close()
+	if w, err = armor.Encode(&private, openpgp.PrivateKeyType, nil); err != nil {
+		t.Fatalf("failed encoding pgp key: %v", err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("failed serializing pgp key: %v", err)
+	}
+	w.Close()
+	return public.String(), private.String()
+}
+
+func TestGetPGPEntities(t *testing.T) {
+	path := "authcrunch/caddy/audit_pgp"
+	publicKey, privateKey := newTestPGPKeys(t)
+
+	testcases := []struct {
+		name      string
+		secret    map[string]interface{}
+		key       string
+		private   bool
+		shouldErr bool
+		errPrefix string
+		err       error
+	}{
+		{
+			name:   "test public key",
+			secret: map[string]interface{}{"public_key": publicKey},
+			key:    "public_key",
+		},
+		{
+			name:    "test private key",
+			secret:  map[string]interface{}{"private_key": privateKey},
+			key:     "private_key",
+			private: true,
+		},
+		{
+			name:      "test missing key",
+			secret:    map[string]interface{}{"public_key": publicKey},
+			key:       "private_key",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q not found", path, "private_key"),
+		},
+		{
+			name:      "test malformed key",
+			secret:    map[string]interface{}{"public_key": "foobar"},
+			key:       "public_key",
+			shouldErr: true,
+			errPrefix: fmt.Sprintf("malformed %q secret: key %q value is malformed: ", path, "public_key"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, map[string]map[string]interface{}{"AWSCURRENT": tc.secret}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetPGPEntities(context.TODO(), path, tc.key)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if tc.errPrefix != "" {
+					if !strings.HasPrefix(err.Error(), tc.errPrefix) {
+						t.Fatalf("unexpected error: %v, want prefix: %s", err, tc.errPrefix)
+					}
+					return
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("GetPGPEntities() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("unexpected number of entities: %d", len(got))
+			}
+			if _, found := got[0].Identities["Audit Exporter <audit@authcrunch.local>"]; !found {
+				t.Errorf("unexpected identities: %v", got[0].Identities)
+			}
+			if (got[0].PrivateKey != nil) != tc.private {
+				t.Errorf("unexpected private key: %v", got[0].PrivateKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.uber.org/zap"
+)
+
+const (
+	passwordPolicyWarn   = "warn"
+	passwordPolicyReject = "reject"
+)
+
+var (
+	hashedPasswordRgx  = regexp.MustCompile(`^bcrypt:\d+:`)
+	hashedPasswordKeys = []string{"password", "api_key"}
+)
+
+// checkPasswordPolicy inspects the password and api_key values of the
+// secret. When a value is not in "bcrypt:<cost>:<hash>" format, it either
+// logs a warning or returns an error, depending on the policy.
+func (c *client) checkPasswordPolicy(policy, path string, m map[string]interface{}) error {
+	if policy == "" {
+		return nil
+	}
+	for _, k := range hashedPasswordKeys {
+		v, exists := m[k]
+		if !exists {
+			continue
+		}
+		if s, ok := v.(string); ok && hashedPasswordRgx.MatchString(s) {
+			continue
+		}
+		if policy == passwordPolicyReject {
+			return fmt.Errorf("key %q in %q secret is not a hashed password", k, path)
+		}
+		c.getLogger().Warn(
+			"secret has key that is not a hashed password",
+			zap.String("path", path),
+			zap.String("key", k),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	testcases := []struct {
+		name         string
+		policy       string
+		secret       map[string]interface{}
+		wantWarnings []string
+		shouldErr    bool
+		err          error
+	}{
+		{
+			name:   "test hashed credentials with reject policy",
+			policy: "reject",
+			secret: map[string]interface{}{
+				"username": "jsmith",
+				"password": "bcrypt:10:$2a$10$iqq53VjdCwknBSBrnyLd9OH1Mfh6kqPezMMy6h6F41iLdVDkj13I6",
+				"api_key":  "bcrypt:10:$2a$10$TEQ7ZG9cAdWwhQK36orCGOlokqQA55ddE0WEsl00oLZh567okdcZ6",
+			},
+		},
+		{
+			name:   "test plaintext password with reject policy",
+			policy: "reject",
+			secret: map[string]interface{}{
+				"username": "jsmith",
+				"password": "rbrH97m9bpbk3qRphHFNM9ksJfRcWdvr",
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("key %q in %q secret is not a hashed password", "password", "authcrunch/caddy/users/jsmith"),
+		},
+		{
+			name:   "test plaintext credentials with warn policy",
+			policy: "warn",
+			secret: map[string]interface{}{
+				"username": "jsmith",
+				"password": "rbrH97m9bpbk3qRphHFNM9ksJfRcWdvr",
+				"api_key":  "kqvc7cgk44dtpX9nXx4NL9krH4g7fqdJ",
+			},
+			wantWarnings: []string{"password", "api_key"},
+		},
+		{
+			name: "test plaintext password without policy",
+			secret: map[string]interface{}{
+				"username": "jsmith",
+				"password": "rbrH97m9bpbk3qRphHFNM9ksJfRcWdvr",
+			},
+		},
+		{
+			name:      "test unsupported policy",
+			policy:    "ignore",
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q password policy", "ignore"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClientWithConfig(context.TODO(), &ClientConfig{
+				ID:             "foo",
+				Region:         "us-east-1",
+				Provider:       "aws_secrets_manager",
+				PasswordPolicy: tc.policy,
+			})
+			core, logs := observer.New(zapcore.WarnLevel)
+			if err == nil {
+				c.SetLogger(zap.New(core))
+				c.SetMockClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+					return secretsmock.SecretStringResponse(tc.secret), nil
+				}))
+				c.SetMockCredentialsProvider(MockCredentialsProvider{})
+				_, err = c.GetSecret(context.TODO(), "authcrunch/caddy/users/jsmith")
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("GetSecret() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			var gotWarnings []string
+			for _, entry := range logs.All() {
+				gotWarnings = append(gotWarnings, entry.ContextMap()["key"].(string))
+			}
+			if diff := cmp.Diff(tc.wantWarnings, gotWarnings); diff != "" {
+				t.Errorf("GetSecret() warnings mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"strings"
+)
+
+// normalizeBasePrefix returns the base prefix ending with a slash.
+func normalizeBasePrefix(prefix string) string {
+	if prefix == "" {
+		return ""
+	}
+	return strings.TrimSuffix(prefix, "/") + "/"
+}
+
+// resolvePath returns the name of the secret at the path relative to the
+// base prefix. The paths that would escape the base prefix, i.e. ARNs and
+// absolute paths, are rejected.
+func (cfg *ClientConfig) resolvePath(path string) (string, error) {
+	prefix := normalizeBasePrefix(cfg.BasePrefix)
+	if prefix == "" {
+		return path, nil
+	}
+	if strings.HasPrefix(path, "/") || strings.HasPrefix(path, "arn:") {
+		return "", fmt.Errorf("secret path %q is outside of %q base prefix", path, prefix)
+	}
+	return prefix + path, nil
+}
+
+// relativePath returns the path of the secret name relative to the base
+// prefix. It returns false when the secret is outside of the base prefix.
+func (cfg *ClientConfig) relativePath(name string) (string, bool) {
+	prefix := normalizeBasePrefix(cfg.BasePrefix)
+	if prefix == "" {
+		return name, true
+	}
+	if !strings.HasPrefix(name, prefix) || name == prefix {
+		return "", false
+	}
+	return strings.TrimPrefix(name, prefix), true
+}
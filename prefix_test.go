@@ -0,0 +1,154 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestResolvePath(t *testing.T) {
+	testcases := []struct {
+		name      string
+		prefix    string
+		path      string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test path without base prefix",
+			path: "authcrunch/prod/users/jsmith",
+			want: "authcrunch/prod/users/jsmith",
+		},
+		{
+			name:   "test path with base prefix",
+			prefix: "authcrunch/prod/",
+			path:   "users/jsmith",
+			want:   "authcrunch/prod/users/jsmith",
+		},
+		{
+			name:   "test path with base prefix without trailing slash",
+			prefix: "authcrunch/prod",
+			path:   "users/jsmith",
+			want:   "authcrunch/prod/users/jsmith",
+		},
+		{
+			name:      "test absolute path with base prefix",
+			prefix:    "authcrunch/prod/",
+			path:      "/authcrunch/dev/users/jsmith",
+			shouldErr: true,
+			err:       fmt.Errorf("secret path %q is outside of %q base prefix", "/authcrunch/dev/users/jsmith", "authcrunch/prod/"),
+		},
+		{
+			name:      "test arn with base prefix",
+			prefix:    "authcrunch/prod/",
+			path:      "arn:aws:secretsmanager:us-east-1:123456789012:secret:authcrunch/dev/users/jsmith-a1B2c3",
+			shouldErr: true,
+			err:       fmt.Errorf("secret path %q is outside of %q base prefix", "arn:aws:secretsmanager:us-east-1:123456789012:secret:authcrunch/dev/users/jsmith-a1B2c3", "authcrunch/prod/"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &ClientConfig{BasePrefix: tc.prefix}
+			got, err := cfg.resolvePath(tc.path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("resolvePath() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("resolvePath() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	testcases := []struct {
+		name   string
+		prefix string
+		secret string
+		want   string
+		ok     bool
+	}{
+		{
+			name:   "test secret without base prefix",
+			secret: "authcrunch/prod/users/jsmith",
+			want:   "authcrunch/prod/users/jsmith",
+			ok:     true,
+		},
+		{
+			name:   "test secret inside base prefix",
+			prefix: "authcrunch/prod",
+			secret: "authcrunch/prod/users/jsmith",
+			want:   "users/jsmith",
+			ok:     true,
+		},
+		{
+			name:   "test secret outside of base prefix",
+			prefix: "authcrunch/prod",
+			secret: "authcrunch/production/users/jsmith",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &ClientConfig{BasePrefix: tc.prefix}
+			got, ok := cfg.relativePath(tc.secret)
+			if diff := cmp.Diff(tc.ok, ok); diff != "" {
+				t.Fatalf("relativePath() ok mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("relativePath() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetSecretWithBasePrefix(t *testing.T) {
+	srv := secretsmock.NewServer(t)
+	srv.SetSecret("authcrunch/prod/users/jsmith", packMapToJSON(t, map[string]interface{}{"username": "jsmith"}))
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithBasePrefix("authcrunch/prod/"),
+		WithEndpoint(srv.URL),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	if _, err := c.GetSecret(context.TODO(), "users/jsmith"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.GetSecret(context.TODO(), "/authcrunch/dev/users/jsmith"); err == nil {
+		t.Fatalf("unexpected success for path outside of base prefix")
+	}
+	want := []secretsmock.Request{{Operation: "GetSecretValue", SecretID: "authcrunch/prod/users/jsmith"}}
+	if diff := cmp.Diff(want, srv.Requests()); diff != "" {
+		t.Errorf("GetSecret() requests mismatch (-want +got):\n%s", diff)
+	}
+}
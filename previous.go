@@ -0,0 +1,49 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+)
+
+// SecretWithPrevious holds the current and the previous versions of a
+// secret, e.g. for the HMAC verifiers accepting the signatures made with
+// either of them during the rotation window.
+type SecretWithPrevious struct {
+	// Current is the key-value map of AWSCURRENT version.
+	Current map[string]interface{} `json:"-" xml:"-" yaml:"-"`
+	// Previous is the key-value map of AWSPREVIOUS version. It is nil when
+	// the secret was never rotated.
+	Previous map[string]interface{} `json:"-" xml:"-" yaml:"-"`
+}
+
+// GetSecretWithPrevious returns the current and the previous versions of
+// the secret in one result. The missing previous version is not an error.
+func (c *client) GetSecretWithPrevious(ctx context.Context, path string) (*SecretWithPrevious, error) {
+	current, err := c.getSecretValue(ctx, path, versionStageCurrent)
+	if err != nil {
+		return nil, err
+	}
+	s := &SecretWithPrevious{Current: current}
+	previous, err := c.getSecretValue(ctx, path, versionStagePrevious)
+	if err != nil {
+		if isNotFound(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	s.Previous = previous
+	return s, nil
+}
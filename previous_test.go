@@ -0,0 +1,84 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetSecretWithPrevious(t *testing.T) {
+	testcases := []struct {
+		name      string
+		stages    map[string]map[string]interface{}
+		want      *SecretWithPrevious
+		shouldErr bool
+	}{
+		{
+			name: "test rotated secret",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT":  {"hmac_key": "bar"},
+				"AWSPREVIOUS": {"hmac_key": "foo"},
+			},
+			want: &SecretWithPrevious{
+				Current:  map[string]interface{}{"hmac_key": "bar"},
+				Previous: map[string]interface{}{"hmac_key": "foo"},
+			},
+		},
+		{
+			name: "test secret without previous version",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {"hmac_key": "foo"},
+			},
+			want: &SecretWithPrevious{
+				Current: map[string]interface{}{"hmac_key": "foo"},
+			},
+		},
+		{
+			name:      "test secret not found",
+			stages:    map[string]map[string]interface{}{},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, tc.stages))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetSecretWithPrevious(context.TODO(), "authcrunch/webhooks/github")
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !isNotFound(err) {
+					t.Fatalf("expected not found error, got: %v", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success")
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetSecretWithPrevious() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// proxyProvider is the provider reading the secrets from the caching
+	// proxy instead of AWS Secrets Manager.
+	proxyProvider = "proxy"
+
+	proxySecretsPath  = "/v1/secrets/"
+	proxyMetadataPath = "/v1/metadata/"
+	proxyListPath     = "/v1/list"
+	proxyMaxBodySize  = 10 << 20
+)
+
+func init() {
+	if err := RegisterBackend(proxyProvider, newProxyBackend); err != nil {
+		panic(err)
+	}
+}
+
+// proxyHTTPClient is the HTTP client of the proxy backend.
+var proxyHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// proxyUpstreamTimeout bounds the upstream call shared by the concurrent
+// requests, which is not bound to the context of any of them.
+const proxyUpstreamTimeout = 30 * time.Second
+
+// proxyError is the body of the error responses of the proxy.
+type proxyError struct {
+	Error string `json:"error"`
+}
+
+// proxyCall is the upstream call shared by the concurrent requests for the
+// same secret. The done channel is closed when the call completes.
+type proxyCall struct {
+	done chan struct{}
+	m    map[string]interface{}
+	err  error
+}
+
+// proxyHandler serves the secrets of the client to the local processes.
+type proxyHandler struct {
+	c        Client
+	policy   *PathPolicyConfig
+	policies map[string]*PathPolicyConfig
+	mu       sync.Mutex
+	calls    map[string]*proxyCall
+}
+
+// ProxyOption configures the proxy handler.
+type ProxyOption func(*proxyHandler)
+
+// WithProxyPathPolicy restricts the paths of the secrets the proxy serves,
+// in addition to the path policy of the client.
+func WithProxyPathPolicy(policy *PathPolicyConfig) ProxyOption {
+	return func(h *proxyHandler) {
+		h.policy = policy
+	}
+}
+
+// WithProxyClientPolicies restricts the paths of the secrets the proxy
+// serves to each client, keyed by the common name of the verified client
+// certificate. The requests without the certificate of a listed client are
+// refused, so the proxy must require the client certificates, see
+// NewProxyTLSConfig.
+func WithProxyClientPolicies(policies map[string]*PathPolicyConfig) ProxyOption {
+	return func(h *proxyHandler) {
+		h.policies = policies
+	}
+}
+
+// NewProxyHandler returns the HTTP handler serving the secrets of the
+// client, so that a fleet of processes on one host shares its cache, and
+// the concurrent requests for the same secret make a single upstream call.
+// The processes use the "proxy" provider, see WithProxyProvider. The
+// handler is read-only and, unless the server requires mutual TLS, has no
+// authentication, so it must listen on the loopback interface only. The
+// endpoints, also served over gRPC by NewGRPCServer, are:
+//
+//	GET /v1/secrets/<path>[?stage=<stage>]
+//	GET /v1/metadata/<path>
+//	GET /v1/list[?prefix=<prefix>]
+//
+// The paths are relative to the base prefix of the client, and its
+// policies apply.
+func NewProxyHandler(c Client, opts ...ProxyOption) http.Handler {
+	return newProxyHandler(c, opts...)
+}
+
+func newProxyHandler(c Client, opts ...ProxyOption) *proxyHandler {
+	h := &proxyHandler{c: c, calls: make(map[string]*proxyCall)}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// clientPolicy returns the path policy of the client of the connection
+// with the TLS state.
+func (h *proxyHandler) clientPolicy(state *tls.ConnectionState) (*PathPolicyConfig, error) {
+	if h.policies == nil {
+		return nil, nil
+	}
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return nil, fmt.Errorf("%w: client certificate not verified", ErrPathNotAllowed)
+	}
+	name := state.VerifiedChains[0][0].Subject.CommonName
+	policy, found := h.policies[name]
+	if !found {
+		return nil, fmt.Errorf("%w: %q client not allowed", ErrPathNotAllowed, name)
+	}
+	return policy, nil
+}
+
+// check returns ErrPathNotAllowed when the proxy does not serve the secret
+// at the path to the client.
+func (h *proxyHandler) check(clientPolicy *PathPolicyConfig, secretPath string) error {
+	if err := h.policy.check(secretPath); err != nil {
+		return err
+	}
+	return clientPolicy.check(secretPath)
+}
+
+func (h *proxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeProxyError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	clientPolicy, err := h.clientPolicy(r.TLS)
+	if err != nil {
+		writeProxyError(w, http.StatusForbidden, err)
+		return
+	}
+	var v interface{}
+	switch {
+	case strings.HasPrefix(r.URL.Path, proxySecretsPath):
+		secretPath := strings.TrimPrefix(r.URL.Path, proxySecretsPath)
+		if err = h.check(clientPolicy, secretPath); err == nil {
+			v, err = h.getSecret(r.Context(), secretPath, r.URL.Query().Get("stage"))
+		}
+	case strings.HasPrefix(r.URL.Path, proxyMetadataPath):
+		secretPath := strings.TrimPrefix(r.URL.Path, proxyMetadataPath)
+		if err = h.check(clientPolicy, secretPath); err == nil {
+			v, err = h.c.DescribeSecret(r.Context(), secretPath)
+		}
+	case r.URL.Path == proxyListPath:
+		var paths []string
+		paths, err = h.c.ListSecrets(r.Context(), r.URL.Query().Get("prefix"))
+		allowed := []string{}
+		for _, secretPath := range paths {
+			if h.check(clientPolicy, secretPath) == nil {
+				allowed = append(allowed, secretPath)
+			}
+		}
+		v = allowed
+	default:
+		writeProxyError(w, http.StatusNotFound, errors.New("endpoint not found"))
+		return
+	}
+	if err != nil {
+		writeProxyError(w, proxyStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
+
+// getSecret returns the secret, sharing the upstream call with the
+// concurrent requests for the same path and stage. The upstream call runs
+// with its own timeout, so that the request which started it being
+// cancelled does not fail the others. Each request stops waiting when its
+// own context is done.
+func (h *proxyHandler) getSecret(ctx context.Context, path, stage string) (map[string]interface{}, error) {
+	key := stage + "\x00" + path
+	h.mu.Lock()
+	call, found := h.calls[key]
+	if !found {
+		call = &proxyCall{done: make(chan struct{})}
+		h.calls[key] = call
+		go h.call(key, call, path, stage)
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.m, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// call makes the upstream call shared by the requests for the secret.
+func (h *proxyHandler) call(key string, call *proxyCall, path, stage string) {
+	ctx, cancel := context.WithTimeout(context.Background(), proxyUpstreamTimeout)
+	defer cancel()
+	var opts []CallOption
+	if stage != "" {
+		opts = append(opts, WithVersionStage(stage))
+	}
+	call.m, call.err = h.c.GetSecret(ctx, path, opts...)
+
+	h.mu.Lock()
+	delete(h.calls, key)
+	h.mu.Unlock()
+	close(call.done)
+}
+
+// proxyStatus returns the HTTP status code of the error.
+func proxyStatus(err error) int {
+	switch {
+	case isNotFound(err):
+		return http.StatusNotFound
+	case errors.Is(err, ErrPathNotAllowed), errors.Is(err, ErrTagDenied), errors.Is(err, ErrAccessBlocked):
+		return http.StatusForbidden
+	}
+	return http.StatusBadGateway
+}
+
+func writeProxyError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&proxyError{Error: err.Error()})
+}
+
+// proxyBackend reads the secrets from the caching proxy. The names of the
+// secrets are the paths served by the proxy, so the clients of the "proxy"
+// provider usually have no base prefix. The secrets are read-only.
+type proxyBackend struct {
+	url        string
+	httpClient *http.Client
+}
+
+// ProxyTLSConfig is the client certificate and the certificate authority
+// the clients of the "proxy" and "grpc" providers use with the server
+// requiring mutual TLS. The paths are of PEM-encoded files.
+type ProxyTLSConfig struct {
+	CertFile string `json:"cert_file,omitempty" xml:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty" xml:"key_file,omitempty" yaml:"key_file,omitempty"`
+	// CAFile is the certificate authority of the proxy. When empty, the
+	// system roots apply.
+	CAFile string `json:"ca_file,omitempty" xml:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+}
+
+// clientConfig returns the TLS configuration of the proxy backend.
+func (cfg *ProxyTLSConfig) clientConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading proxy client certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if cfg.CAFile != "" {
+		if tlsConfig.RootCAs, err = loadCertPool(cfg.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	return tlsConfig, nil
+}
+
+// NewProxyTLSConfig returns the TLS configuration of the proxy requiring
+// mutual TLS, i.e. the clients present the certificates issued by the
+// certificate authority in the PEM-encoded clientCAFile. See
+// WithProxyClientPolicies.
+func NewProxyTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading proxy certificate: %v", err)
+	}
+	pool, err := loadCertPool(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
+// loadCertPool returns the pool of the certificates in the PEM-encoded
+// file.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in %q", path)
+	}
+	return pool, nil
+}
+
+func newProxyBackend(_ context.Context, cfg *ClientConfig) (Backend, error) {
+	if cfg.ProxyURL == "" {
+		return nil, fmt.Errorf("proxy url of %q provider is empty", proxyProvider)
+	}
+	u, err := url.Parse(cfg.ProxyURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("malformed %q proxy url", cfg.ProxyURL)
+	}
+	pb := &proxyBackend{url: strings.TrimSuffix(cfg.ProxyURL, "/"), httpClient: proxyHTTPClient}
+	if cfg.ProxyTLS != nil {
+		if u.Scheme != "https" {
+			return nil, fmt.Errorf("proxy tls requires https, got %q proxy url", cfg.ProxyURL)
+		}
+		tlsConfig, err := cfg.ProxyTLS.clientConfig()
+		if err != nil {
+			return nil, err
+		}
+		pb.httpClient = &http.Client{
+			Timeout:   proxyHTTPClient.Timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		}
+	}
+	return pb, nil
+}
+
+// get decodes the response of the proxy to the request into v.
+func (pb *proxyBackend) get(ctx context.Context, endpoint string, query url.Values, v interface{}) error {
+	u := pb.url + endpoint
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := pb.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body := io.LimitReader(resp.Body, proxyMaxBodySize)
+	if resp.StatusCode != http.StatusOK {
+		var e proxyError
+		if err := json.NewDecoder(body).Decode(&e); err != nil || e.Error == "" {
+			e.Error = resp.Status
+		}
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %s", ErrSecretNotFound, e.Error)
+		case http.StatusForbidden:
+			return fmt.Errorf("%w: %s", ErrPathNotAllowed, e.Error)
+		}
+		return fmt.Errorf("proxy error: %s", e.Error)
+	}
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return fmt.Errorf("malformed proxy response: %v", err)
+	}
+	return nil
+}
+
+func (pb *proxyBackend) GetSecretString(ctx context.Context, name, versionID, stage string) (string, error) {
+	if versionID != "" {
+		return "", fmt.Errorf("%w: %q version of %q", ErrVersionIDNotSupported, versionID, name)
+	}
+	query := url.Values{}
+	if stage != "" && stage != versionStageCurrent {
+		query.Set("stage", stage)
+	}
+	var m map[string]interface{}
+	if err := pb.get(ctx, proxySecretsPath+name, query, &m); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (pb *proxyBackend) DescribeSecret(ctx context.Context, name string) (*SecretMetadata, error) {
+	var m SecretMetadata
+	if err := pb.get(ctx, proxyMetadataPath+name, nil, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (pb *proxyBackend) ListSecrets(ctx context.Context, prefix string) ([]string, error) {
+	query := url.Values{}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	var names []string
+	if err := pb.get(ctx, proxyListPath, query, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (pb *proxyBackend) readOnly(op string) error {
+	return fmt.Errorf("%w: %s with %q provider", ErrReadOnly, op, proxyProvider)
+}
+
+func (pb *proxyBackend) CreateSecret(context.Context, string, string) error {
+	return pb.readOnly("create")
+}
+
+func (pb *proxyBackend) PutSecretValue(context.Context, string, string, []string) error {
+	return pb.readOnly("put")
+}
+
+func (pb *proxyBackend) DeleteSecret(context.Context, string) error {
+	return pb.readOnly("delete")
+}
+
+func (pb *proxyBackend) RotateSecret(context.Context, string) error {
+	return pb.readOnly("rotate")
+}
@@ -0,0 +1,317 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// countingClient counts the secrets fetched by the client. The fetches
+// block until the gate is closed.
+type countingClient struct {
+	Client
+	count int64
+	gate  chan struct{}
+}
+
+func (c *countingClient) GetSecret(ctx context.Context, path string, opts ...CallOption) (map[string]interface{}, error) {
+	atomic.AddInt64(&c.count, 1)
+	<-c.gate
+	return c.Client.GetSecret(ctx, path, opts...)
+}
+
+func newProxyTestUpstream(t *testing.T) Client {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "authcrunch", "users", "jsmith.json"), `{"username":"jsmith","email":"jsmith@localhost"}`)
+	writeTestFile(t, filepath.Join(dir, "authcrunch", "admin", "root.json"), `{"username":"root"}`)
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithFileProvider(dir),
+		WithBasePrefix("authcrunch/"),
+		WithPathPolicy(&PathPolicyConfig{Allow: []string{"users/*"}}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	return c
+}
+
+func TestProxy(t *testing.T) {
+	ts := httptest.NewServer(NewProxyHandler(newProxyTestUpstream(t)))
+	defer ts.Close()
+
+	c, err := NewClient(context.TODO(), WithID("bar"), WithProxyProvider(ts.URL))
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	ctx := context.TODO()
+
+	got, err := c.GetSecret(ctx, "users/jsmith")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"username": "jsmith", "email": "jsmith@localhost"}, got); diff != "" {
+		t.Fatalf("GetSecret() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := c.GetSecret(ctx, "users/mjones"); !isNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "admin/root"); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+	}
+	paths, err := c.ListSecrets(ctx, "users/")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"users/jsmith"}, paths); diff != "" {
+		t.Fatalf("ListSecrets() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := c.CreateSecret(ctx, "users/mjones", map[string]interface{}{"username": "mjones"}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got: %v", err)
+	}
+
+	resp, err := http.Post(ts.URL+"/v1/secrets/users/jsmith", "application/json", nil)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected %d status code, want: %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestProxySharedCall(t *testing.T) {
+	upstream := &countingClient{Client: newProxyTestUpstream(t), gate: make(chan struct{})}
+	h := NewProxyHandler(upstream).(*proxyHandler)
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := h.getSecret(context.TODO(), "users/jsmith", "")
+			errs <- err
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(upstream.gate)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+	}
+	if n := atomic.LoadInt64(&upstream.count); n != 1 {
+		t.Fatalf("unexpected %d upstream calls, want: 1", n)
+	}
+}
+
+func TestProxySharedCallCancel(t *testing.T) {
+	upstream := &countingClient{Client: newProxyTestUpstream(t), gate: make(chan struct{})}
+	h := NewProxyHandler(upstream).(*proxyHandler)
+	ctx, cancel := context.WithCancel(context.TODO())
+	first := make(chan error, 1)
+	go func() {
+		_, err := h.getSecret(ctx, "users/jsmith", "")
+		first <- err
+	}()
+	second := make(chan error, 1)
+	go func() {
+		_, err := h.getSecret(context.TODO(), "users/jsmith", "")
+		second <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	close(upstream.gate)
+	if err := <-second; err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if n := atomic.LoadInt64(&upstream.count); n != 1 {
+		t.Fatalf("unexpected %d upstream calls, want: 1", n)
+	}
+}
+
+func TestProxyProviderConfig(t *testing.T) {
+	for _, tc := range []struct {
+		url string
+		err string
+	}{
+		{url: "", err: `proxy url of "proxy" provider is empty`},
+		{url: "127.0.0.1:8200", err: `malformed "127.0.0.1:8200" proxy url`},
+		{url: "unix:///var/run/secrets.sock", err: `malformed "unix:///var/run/secrets.sock" proxy url`},
+	} {
+		_, err := NewClient(context.TODO(), WithID("foo"), WithProxyProvider(tc.url))
+		if err == nil {
+			t.Fatalf("unexpected success, want: %v", tc.err)
+		}
+		if diff := cmp.Diff(tc.err, err.Error()); diff != "" {
+			t.Errorf("unexpected error: %v, want: %v", err, tc.err)
+		}
+	}
+}
+
+// writeTestCertificate writes the PEM-encoded certificate and key with the
+// common name to the directory. The certificate is signed by the parent,
+// or is self-signed authority when the parent is nil.
+func writeTestCertificate(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed marshaling key: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, name+".pem"), string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	writeTestFile(t, filepath.Join(dir, name+"-key.pem"), string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed parsing certificate: %v", err)
+	}
+	return cert, key
+}
+
+func TestProxyMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeTestCertificate(t, dir, "ca", nil, nil)
+	writeTestCertificate(t, dir, "proxy", ca, caKey)
+	writeTestCertificate(t, dir, "app", ca, caKey)
+	writeTestCertificate(t, dir, "other", ca, caKey)
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	tlsConfig, err := NewProxyTLSConfig(file("proxy.pem"), file("proxy-key.pem"), file("ca.pem"))
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(NewProxyHandler(newProxyTestUpstream(t),
+		WithProxyClientPolicies(map[string]*PathPolicyConfig{
+			"app":   {Allow: []string{"users/*"}},
+			"other": {Allow: []string{"apps/*"}},
+		}),
+	))
+	ts.TLS = tlsConfig
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	testcases := []struct {
+		name      string
+		client    string
+		want      map[string]interface{}
+		wantPaths []string
+		err       error
+	}{
+		{
+			name:      "test allowed client",
+			client:    "app",
+			want:      map[string]interface{}{"username": "jsmith", "email": "jsmith@localhost"},
+			wantPaths: []string{"users/jsmith"},
+		},
+		{
+			name:   "test client not allowed the path",
+			client: "other",
+			err:    ErrPathNotAllowed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(),
+				WithID("bar"),
+				WithProxyProvider(ts.URL),
+				WithProxyTLS(&ProxyTLSConfig{
+					CertFile: file(tc.client + ".pem"),
+					KeyFile:  file(tc.client + "-key.pem"),
+					CAFile:   file("ca.pem"),
+				}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			paths, err := c.ListSecrets(context.TODO(), "")
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantPaths, paths); diff != "" {
+				t.Errorf("ListSecrets() mismatch (-want +got):\n%s", diff)
+			}
+			got, err := c.GetSecret(context.TODO(), "users/jsmith")
+			if err != nil {
+				if tc.err == nil {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.err != nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("GetSecret() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: tlsConfig.ClientCAs}}}).Get(ts.URL + "/v1/list")
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("unexpected success without client certificate")
+	}
+}
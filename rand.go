@@ -0,0 +1,71 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// lockedRand is the source of randomness safe for concurrent use. The
+// client draws from it the retry backoff jitter and the injected faults
+// when the source is set with WithRandSource.
+type lockedRand struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newLockedRand(src rand.Source) *lockedRand {
+	return &lockedRand{rand: rand.New(src)}
+}
+
+// Float64 returns a number in [0.0, 1.0).
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Float64()
+}
+
+// jitterBackoff is the exponential backoff with full jitter of AWS SDK
+// standard retryer, drawing the jitter from the injected source.
+type jitterBackoff struct {
+	rand       *lockedRand
+	maxBackoff time.Duration
+}
+
+// BackoffDelay implements retry.BackoffDelayer interface.
+func (b *jitterBackoff) BackoffDelay(attempt int, _ error) (time.Duration, error) {
+	if attempt > int(math.Log2(b.maxBackoff.Seconds())) {
+		return b.maxBackoff, nil
+	}
+	delay := b.rand.Float64() * float64(int64(1)<<uint(attempt))
+	return time.Duration(delay * float64(time.Second)), nil
+}
+
+// newRetryer returns the function creating AWS SDK standard retryers with
+// the jitter drawn from the source. The maximum number of attempts is set
+// by the service configuration.
+func newRetryer(r *lockedRand) func() aws.Retryer {
+	return func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = &jitterBackoff{rand: r, maxBackoff: retry.DefaultMaxBackoff}
+		})
+	}
+}
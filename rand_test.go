@@ -0,0 +1,99 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJitterBackoff(t *testing.T) {
+	delays := func(seed int64) []time.Duration {
+		b := &jitterBackoff{rand: newLockedRand(rand.NewSource(seed)), maxBackoff: retry.DefaultMaxBackoff}
+		var got []time.Duration
+		for attempt := 1; attempt <= 6; attempt++ {
+			d, err := b.BackoffDelay(attempt, nil)
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			got = append(got, d)
+		}
+		return got
+	}
+	first, second := delays(42), delays(42)
+	if diff := cmp.Diff(first, second); diff != "" {
+		t.Fatalf("backoff delays with the same seed mismatch (-want +got):\n%s", diff)
+	}
+	for i, d := range first[:4] {
+		if max := time.Duration(int64(1)<<uint(i+1)) * time.Second; d < 0 || d >= max {
+			t.Errorf("attempt %d: delay %s is out of [0, %s) range", i+1, d, max)
+		}
+	}
+	if diff := cmp.Diff(retry.DefaultMaxBackoff, first[5]); diff != "" {
+		t.Errorf("maximum backoff mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRandSource(t *testing.T) {
+	outcomes := func(seed int64) []int {
+		c, err := NewClient(context.TODO(),
+			WithID("foo"),
+			WithRegion("us-east-1"),
+			WithRandSource(rand.NewSource(seed)),
+			WithFaultInjection(&FaultConfig{ErrorRate: 0.5}),
+			WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: 200,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(strings.NewReader("{}")),
+				}, nil
+			})),
+			WithCredentialsProvider(MockCredentialsProvider{}),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error during client initialization: %v", err)
+		}
+		serviceConfig := c.(*client).serviceConfig
+		if serviceConfig.Retryer == nil {
+			t.Fatalf("expected retryer with injected jitter, got none")
+		}
+		var got []int
+		for i := 0; i < 20; i++ {
+			req, _ := http.NewRequest(http.MethodPost, "https://secretsmanager.us-east-1.amazonaws.com/", nil)
+			resp, err := serviceConfig.HTTPClient.Do(req)
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			got = append(got, resp.StatusCode)
+		}
+		return got
+	}
+	if diff := cmp.Diff(outcomes(7), outcomes(7)); diff != "" {
+		t.Errorf("injected faults with the same rand source mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := NewClient(context.TODO(), WithRandSource(nil)); err == nil {
+		t.Fatalf("expected error for nil rand source, got success")
+	}
+}
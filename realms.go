@@ -0,0 +1,84 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	usernameRgx *regexp.Regexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@+-]*$`)
+)
+
+// RealmConfig maps an authentication realm, or tenant, to the path prefix
+// of its secrets. The user secrets of the realm are stored under
+// "<path_prefix>/users/<username>". When the region is set, the secrets
+// of the realm are retrieved from that region.
+type RealmConfig struct {
+	Name       string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	PathPrefix string `json:"path_prefix,omitempty" xml:"path_prefix,omitempty" yaml:"path_prefix,omitempty"`
+	Region     string `json:"region,omitempty" xml:"region,omitempty" yaml:"region,omitempty"`
+}
+
+func (r *RealmConfig) validate() error {
+	if r.Name == "" {
+		return errors.New("realm name is empty")
+	}
+	if strings.Trim(r.PathPrefix, "/") == "" {
+		return fmt.Errorf("realm %q path prefix is empty", r.Name)
+	}
+	if r.Region != "" {
+		if !awsRegionRgx.MatchString(r.Region) {
+			return fmt.Errorf("malformed %q region in %q realm", r.Region, r.Name)
+		}
+	}
+	return nil
+}
+
+// GetUserSecret returns the key-value map of the user secret in the realm.
+func (c *client) GetUserSecret(ctx context.Context, realmName, username string) (map[string]interface{}, error) {
+	req, err := c.newUserSecretRequest(realmName, username)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetchSecret(ctx, req)
+}
+
+// newUserSecretRequest returns the request for the secret of the user in
+// the realm.
+func (c *client) newUserSecretRequest(realmName, username string) (*secretRequest, error) {
+	var realm *RealmConfig
+	for _, r := range c.getConfig().Realms {
+		if r.Name == realmName {
+			realm = r
+			break
+		}
+	}
+	if realm == nil {
+		return nil, fmt.Errorf("realm %q not found", realmName)
+	}
+	if !usernameRgx.MatchString(username) {
+		return nil, fmt.Errorf("malformed %q username", username)
+	}
+	return &secretRequest{
+		path:   strings.TrimSuffix(realm.PathPrefix, "/") + "/users/" + username,
+		stage:  versionStageCurrent,
+		region: realm.Region,
+	}, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetUserSecret(t *testing.T) {
+	realms := []*RealmConfig{
+		{
+			Name:       "local",
+			PathPrefix: "authcrunch/caddy/",
+		},
+		{
+			Name:       "acme",
+			PathPrefix: "authcrunch/tenants/acme",
+			Region:     "eu-west-1",
+		},
+	}
+
+	testcases := []struct {
+		name      string
+		realms    []*RealmConfig
+		realm     string
+		username  string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "test user secret in default region realm",
+			realms:   realms,
+			realm:    "local",
+			username: "jsmith",
+			want: map[string]interface{}{
+				"host": "secretsmanager.us-east-1.amazonaws.com",
+				"path": "authcrunch/caddy/users/jsmith",
+			},
+		},
+		{
+			name:     "test user secret in realm with region",
+			realms:   realms,
+			realm:    "acme",
+			username: "jsmith@acme.com",
+			want: map[string]interface{}{
+				"host": "secretsmanager.eu-west-1.amazonaws.com",
+				"path": "authcrunch/tenants/acme/users/jsmith@acme.com",
+			},
+		},
+		{
+			name:      "test unknown realm",
+			realms:    realms,
+			realm:     "foo",
+			username:  "jsmith",
+			shouldErr: true,
+			err:       fmt.Errorf("realm %q not found", "foo"),
+		},
+		{
+			name:      "test username with path traversal",
+			realms:    realms,
+			realm:     "local",
+			username:  "../access_token",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q username", "../access_token"),
+		},
+		{
+			name:      "test duplicate realm",
+			realms:    []*RealmConfig{realms[0], realms[0]},
+			shouldErr: true,
+			err:       fmt.Errorf("duplicate %q realm", "local"),
+		},
+		{
+			name:      "test realm without name",
+			realms:    []*RealmConfig{{PathPrefix: "authcrunch/caddy"}},
+			shouldErr: true,
+			err:       errors.New("realm name is empty"),
+		},
+		{
+			name:      "test realm without path prefix",
+			realms:    []*RealmConfig{{Name: "local", PathPrefix: "/"}},
+			shouldErr: true,
+			err:       fmt.Errorf("realm %q path prefix is empty", "local"),
+		},
+		{
+			name:      "test realm with malformed region",
+			realms:    []*RealmConfig{{Name: "local", PathPrefix: "authcrunch/caddy", Region: "foo"}},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q region in %q realm", "foo", "local"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got map[string]interface{}
+			c, err := NewClientWithConfig(context.TODO(), &ClientConfig{
+				ID:       "foo",
+				Region:   "us-east-1",
+				Provider: "aws_secrets_manager",
+				Realms:   tc.realms,
+			})
+			if err == nil {
+				c.SetMockClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+					var input struct {
+						SecretId string
+					}
+					if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+						return mockFailure(t, "failed to decode request body: %v", err)
+					}
+					response := packMapToJSON(t, map[string]interface{}{
+						"SecretString": packMapToJSON(t, map[string]interface{}{
+							"host": r.URL.Host,
+							"path": input.SecretId,
+						}),
+					})
+					return &http.Response{
+						StatusCode: 200,
+						Header:     http.Header{},
+						Body:       ioutil.NopCloser(strings.NewReader(response)),
+					}, nil
+				}))
+				c.SetMockCredentialsProvider(MockCredentialsProvider{})
+				got, err = c.GetUserSecret(context.TODO(), tc.realm, tc.username)
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("GetUserSecret() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetUserSecret() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
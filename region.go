@@ -0,0 +1,83 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+)
+
+const (
+	// RegionSourceConfig indicates the region set in the configuration.
+	RegionSourceConfig = "config"
+	// RegionSourceEnv indicates the region set with AWS_REGION or
+	// AWS_DEFAULT_REGION environment variable.
+	RegionSourceEnv = "env"
+	// RegionSourceSharedConfig indicates the region of the profile in AWS
+	// shared configuration file.
+	RegionSourceSharedConfig = "shared_config"
+	// RegionSourceIMDS indicates the region of EC2 instance retrieved from
+	// instance metadata service.
+	RegionSourceIMDS = "imds"
+	// RegionSourceAWSConfig indicates the region of the AWS configuration
+	// provided with NewClientFromConfig.
+	RegionSourceAWSConfig = "aws_config"
+
+	imdsRegionTimeout = 2 * time.Second
+)
+
+// imdsRegion returns the region of EC2 instance. The lookup is skipped when
+// AWS_EC2_METADATA_DISABLED environment variable is set to true.
+var imdsRegion = func(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, imdsRegionTimeout)
+	defer cancel()
+	output, err := imds.New(imds.Options{}).GetRegion(ctx, &imds.GetRegionInput{})
+	if err != nil {
+		return "", err
+	}
+	return output.Region, nil
+}
+
+// resolveRegion returns the region and its source. The region in the
+// configuration takes precedence, followed by AWS_REGION and
+// AWS_DEFAULT_REGION environment variables, the region in the AWS shared
+// configuration, and the region of EC2 instance the client runs on.
+func resolveRegion(ctx context.Context, cfg *ClientConfig, serviceConfig aws.Config) (string, string, error) {
+	if cfg.Region != "" {
+		return cfg.Region, RegionSourceConfig, nil
+	}
+	for _, k := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if v := os.Getenv(k); v != "" {
+			if !awsRegionRgx.MatchString(v) {
+				return "", "", fmt.Errorf("malformed %q region in %s environment variable", v, k)
+			}
+			return v, RegionSourceEnv, nil
+		}
+	}
+	if serviceConfig.Region != "" {
+		return serviceConfig.Region, RegionSourceSharedConfig, nil
+	}
+	region, err := imdsRegion(ctx)
+	if err == nil && region != "" {
+		return region, RegionSourceIMDS, nil
+	}
+	return "", "", errors.New("region not found in config, environment, shared config, or instance metadata")
+}
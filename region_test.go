@@ -0,0 +1,130 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveRegion(t *testing.T) {
+	testcases := []struct {
+		name         string
+		region       string
+		env          map[string]string
+		sharedConfig string
+		imds         string
+		want         string
+		wantSource   string
+		shouldErr    bool
+		err          error
+	}{
+		{
+			name:       "test region from config",
+			region:     "us-east-1",
+			env:        map[string]string{"AWS_REGION": "us-west-2"},
+			want:       "us-east-1",
+			wantSource: "config",
+		},
+		{
+			name:       "test region from aws region environment variable",
+			env:        map[string]string{"AWS_REGION": "us-west-2", "AWS_DEFAULT_REGION": "eu-west-1"},
+			want:       "us-west-2",
+			wantSource: "env",
+		},
+		{
+			name:       "test region from aws default region environment variable",
+			env:        map[string]string{"AWS_DEFAULT_REGION": "eu-west-1"},
+			want:       "eu-west-1",
+			wantSource: "env",
+		},
+		{
+			name:         "test region from shared config",
+			sharedConfig: "[default]\nregion = ap-southeast-2\n",
+			want:         "ap-southeast-2",
+			wantSource:   "shared_config",
+		},
+		{
+			name:       "test region from instance metadata",
+			imds:       "ca-central-1",
+			want:       "ca-central-1",
+			wantSource: "imds",
+		},
+		{
+			name:      "test malformed region in environment variable",
+			env:       map[string]string{"AWS_DEFAULT_REGION": "europe"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q region in %s environment variable", "europe", "AWS_DEFAULT_REGION"),
+		},
+		{
+			name:      "test region not found",
+			shouldErr: true,
+			err:       errors.New("region not found in config, environment, shared config, or instance metadata"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			sharedConfigFile := filepath.Join(dir, "config")
+			if err := ioutil.WriteFile(sharedConfigFile, []byte(tc.sharedConfig), 0600); err != nil {
+				t.Fatalf("failed writing shared config file: %v", err)
+			}
+			t.Setenv("AWS_CONFIG_FILE", sharedConfigFile)
+			t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+			t.Setenv("AWS_PROFILE", "")
+			t.Setenv("AWS_REGION", "")
+			t.Setenv("AWS_DEFAULT_REGION", "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			defaultIMDSRegion := imdsRegion
+			defer func() { imdsRegion = defaultIMDSRegion }()
+			imdsRegion = func(context.Context) (string, error) {
+				if tc.imds == "" {
+					return "", errors.New("instance metadata service is not available")
+				}
+				return tc.imds, nil
+			}
+
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion(tc.region))
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("NewClient() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			cfg := c.GetConfig(context.TODO())
+			if diff := cmp.Diff(tc.want, cfg.Region); diff != "" {
+				t.Errorf("GetConfig() region mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantSource, cfg.RegionSource); diff != "" {
+				t.Errorf("GetConfig() region source mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
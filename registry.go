@@ -0,0 +1,70 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// registry holds the clients registered in the process.
+var registry = struct {
+	mu      sync.RWMutex
+	clients map[string]Client
+}{
+	clients: make(map[string]Client),
+}
+
+// Register adds the client to the process-wide registry under its ID.
+func Register(c Client) error {
+	if c == nil {
+		return errors.New("client is nil")
+	}
+	id := c.GetConfig(context.Background()).ID
+	if id == "" {
+		return errors.New("client id is empty")
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, exists := registry.clients[id]; exists {
+		return fmt.Errorf("client %q already registered", id)
+	}
+	registry.clients[id] = c
+	return nil
+}
+
+// Lookup returns the registered client with the ID.
+func Lookup(id string) (Client, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	c, exists := registry.clients[id]
+	if !exists {
+		return nil, fmt.Errorf("client %q not registered", id)
+	}
+	return c, nil
+}
+
+// Unregister removes the client with the ID from the registry.
+func Unregister(id string) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, exists := registry.clients[id]; !exists {
+		return fmt.Errorf("client %q not registered", id)
+	}
+	delete(registry.clients, id)
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRegistry(t *testing.T) {
+	c, err := NewClient(context.TODO(), WithID("registry-foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+
+	if err := Register(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer Unregister("registry-foo")
+
+	err = Register(c)
+	if diff := cmp.Diff(fmt.Errorf("client %q already registered", "registry-foo").Error(), err.Error()); diff != "" {
+		t.Errorf("Register() error mismatch (-want +got):\n%s", diff)
+	}
+	err = Register(nil)
+	if diff := cmp.Diff(errors.New("client is nil").Error(), err.Error()); diff != "" {
+		t.Errorf("Register() error mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := Lookup("registry-foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != c {
+		t.Errorf("Lookup() returned unexpected client")
+	}
+
+	if err := Unregister("registry-foo"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = Lookup("registry-foo")
+	if diff := cmp.Diff(fmt.Errorf("client %q not registered", "registry-foo").Error(), err.Error()); diff != "" {
+		t.Errorf("Lookup() error mismatch (-want +got):\n%s", diff)
+	}
+	err = Unregister("registry-foo")
+	if diff := cmp.Diff(fmt.Errorf("client %q not registered", "registry-foo").Error(), err.Error()); diff != "" {
+		t.Errorf("Unregister() error mismatch (-want +got):\n%s", diff)
+	}
+}
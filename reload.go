@@ -0,0 +1,76 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Reconfigure applies new configuration to the client. The new service
+// configuration is prepared first and then swapped in, so the requests
+// already in flight complete with the previous configuration, while the
+// subsequent requests use the new one. The mock HTTP client and credentials
+// provider, if any, are carried over. The cached secrets are discarded,
+// and the previous backend is closed. The client ID cannot be changed.
+func (c *client) Reconfigure(ctx context.Context, cfg *ClientConfig) error {
+	if cfg == nil {
+		return errors.New("client config is nil")
+	}
+	clientConfig := *cfg
+	clientConfig.setDefaults()
+	if err := clientConfig.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	id := c.config.ID
+	c.mu.RUnlock()
+	if cfg.ID != id {
+		return fmt.Errorf("client id change from %q to %q is not supported", id, cfg.ID)
+	}
+
+	backend, err := newBackendAPI(ctx, &clientConfig)
+	if err != nil {
+		return err
+	}
+	serviceConfig, regionSource, err := c.loadServiceConfig(ctx, &clientConfig)
+	if err != nil {
+		closeBackend(backend)
+		return err
+	}
+
+	c.mu.Lock()
+	previous := c.backend
+	if c.httpClient != nil {
+		serviceConfig.HTTPClient = c.transport(c.httpClient)
+	}
+	if c.credentials != nil {
+		serviceConfig.Credentials = c.credentials
+	}
+	c.config = &clientConfig
+	c.serviceConfig = serviceConfig
+	c.dynamodbClient = dynamodb.NewFromConfig(serviceConfig)
+	c.backend = backend
+	c.regionSource = regionSource
+	c.serviceClients = nil
+	c.roleCredentials = nil
+	c.cache = clientConfig.newCache(c.clock)
+	c.mu.Unlock()
+	return closeBackend(previous)
+}
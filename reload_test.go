@@ -0,0 +1,207 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestReconfigure(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *ClientConfig
+		want      map[string]interface{}
+		wantHost  string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test reconfigure region",
+			config: &ClientConfig{
+				ID:       "foo",
+				Region:   "us-west-2",
+				Provider: "aws_secrets_manager",
+			},
+			want: map[string]interface{}{
+				"id":       "foo",
+				"region":   "us-west-2",
+				"provider": "aws_secrets_manager",
+			},
+			wantHost: "secretsmanager.us-west-2.amazonaws.com",
+		},
+		{
+			name: "test reconfigure with default provider",
+			config: &ClientConfig{
+				ID:     "foo",
+				Region: "eu-west-1",
+			},
+			want: map[string]interface{}{
+				"id":       "foo",
+				"region":   "eu-west-1",
+				"provider": "aws_secrets_manager",
+			},
+			wantHost: "secretsmanager.eu-west-1.amazonaws.com",
+		},
+		{
+			name: "test reconfigure with different client id",
+			config: &ClientConfig{
+				ID:       "bar",
+				Region:   "us-west-2",
+				Provider: "aws_secrets_manager",
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("client id change from %q to %q is not supported", "foo", "bar"),
+		},
+		{
+			name: "test reconfigure with malformed region",
+			config: &ClientConfig{
+				ID:       "foo",
+				Region:   "foo-bar-baz",
+				Provider: "aws_secrets_manager",
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q region", "foo-bar-baz"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+
+			var gotHost string
+			c.SetMockClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+				gotHost = r.URL.Host
+				return secretsmock.SecretStringResponse(map[string]interface{}{"foo": "bar"}), nil
+			}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			err = c.Reconfigure(context.TODO(), tc.config)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("Reconfigure() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			if diff := cmp.Diff(tc.want, c.GetConfig(context.TODO()).Map()); diff != "" {
+				t.Errorf("Reconfigure() config mismatch (-want +got):\n%s", diff)
+			}
+
+			if _, err := c.GetSecret(context.TODO(), "authcrunch/caddy/foo"); err != nil {
+				t.Fatalf("unexpected error after reconfiguration: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantHost, gotHost); diff != "" {
+				t.Errorf("Reconfigure() endpoint mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReconfigureInFlight(t *testing.T) {
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var hosts []string
+	c.SetMockClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		hosts = append(hosts, r.URL.Host)
+		mu.Unlock()
+		if strings.Contains(r.URL.Host, "us-east-1") {
+			close(started)
+			<-release
+		}
+		return secretsmock.SecretStringResponse(map[string]interface{}{"foo": "bar"}), nil
+	}))
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+	done := make(chan error)
+	go func() {
+		_, err := c.GetSecret(context.TODO(), "authcrunch/caddy/foo")
+		done <- err
+	}()
+
+	<-started
+	if err := c.Reconfigure(context.TODO(), &ClientConfig{ID: "foo", Region: "us-west-2", Provider: "aws_secrets_manager"}); err != nil {
+		t.Fatalf("unexpected reconfiguration error: %v", err)
+	}
+	if _, err := c.GetSecret(context.TODO(), "authcrunch/caddy/foo"); err != nil {
+		t.Fatalf("unexpected error after reconfiguration: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error of in-flight request: %v", err)
+	}
+
+	want := []string{
+		"secretsmanager.us-east-1.amazonaws.com",
+		"secretsmanager.us-west-2.amazonaws.com",
+	}
+	if diff := cmp.Diff(want, hosts); diff != "" {
+		t.Errorf("Reconfigure() in-flight mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReconfigureClosesBackend(t *testing.T) {
+	testClosingBackends = nil
+	c, err := NewClient(context.TODO(), WithConfig(&ClientConfig{ID: "foo", Provider: "memory", BackendConfig: map[string]string{"closable": "yes"}}))
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	if err := c.Reconfigure(context.TODO(), nil); err == nil || err.Error() != "client config is nil" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Reconfigure(context.TODO(), &ClientConfig{ID: "foo", Provider: "memory", BackendConfig: map[string]string{"closable": "yes"}}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if len(testClosingBackends) != 2 {
+		t.Fatalf("unexpected %d backends, want: 2", len(testClosingBackends))
+	}
+	if !testClosingBackends[0].closed {
+		t.Errorf("expected the previous backend closed")
+	}
+	if testClosingBackends[1].closed {
+		t.Errorf("expected the current backend open")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if !testClosingBackends[1].closed {
+		t.Errorf("expected the current backend closed")
+	}
+}
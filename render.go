@@ -0,0 +1,78 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// RenderTemplate renders the text/template to the writer, e.g. the
+// configuration file with the database DSN, without storing the secrets
+// in plain text elsewhere. The template calls the secret function for the
+// values of the keys of the secrets, e.g.
+//
+//	postgres://{{ secret "db/app" "username" }}:{{ secret "db/app" "password" | urlquery }}@db:5432/app
+//
+// The string values are rendered as is, and the others as JSON. Each
+// secret is fetched once per rendering. Nothing is written when the
+// rendering fails.
+func (c *client) RenderTemplate(ctx context.Context, tmpl string, w io.Writer) error {
+	return renderTemplate(ctx, c, tmpl, w)
+}
+
+// renderTemplate renders the template with the secrets retrieved with the
+// client.
+func renderTemplate(ctx context.Context, c Client, tmpl string, w io.Writer) error {
+	secrets := make(map[string]map[string]interface{})
+	funcs := template.FuncMap{
+		"secret": func(path, key string) (string, error) {
+			m, fetched := secrets[path]
+			if !fetched {
+				var err error
+				if m, err = c.GetSecret(ctx, path); err != nil {
+					return "", err
+				}
+				secrets[path] = m
+			}
+			v, found := m[key]
+			if !found {
+				return "", fmt.Errorf("key %q not found in %q secret", key, path)
+			}
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			return string(b), nil
+		},
+	}
+	t, err := template.New("secrets").Funcs(funcs).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("malformed template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	store := map[string]string{
+		"authcrunch/db/app": `{"username":"app","password":"p@ss/word","port":5432}`,
+	}
+	testcases := []struct {
+		name      string
+		tmpl      string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test render dsn",
+			tmpl: `postgres://{{ secret "db/app" "username" }}:{{ secret "db/app" "password" | urlquery }}@db:{{ secret "db/app" "port" }}/app`,
+			want: "postgres://app:p%40ss%2Fword@db:5432/app",
+		},
+		{
+			name:      "test missing key",
+			tmpl:      `{{ secret "db/app" "dsn" }}`,
+			shouldErr: true,
+			err:       errors.New(`template: secrets:1:3: executing "secrets" at <secret "db/app" "dsn">: error calling secret: key "dsn" not found in "db/app" secret`),
+		},
+		{
+			name:      "test missing secret",
+			tmpl:      `user={{ secret "db/other" "username" }}`,
+			shouldErr: true,
+		},
+		{
+			name:      "test malformed template",
+			tmpl:      `{{ secret "db/app" }`,
+			shouldErr: true,
+			err:       errors.New(`malformed template: template: secrets:1: unexpected "}" in operand`),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []string
+			c := newWritesTestClient(t, store, &requests, WithBasePrefix("authcrunch/"))
+			var buf bytes.Buffer
+			err := c.RenderTemplate(context.TODO(), tc.tmpl, &buf)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if buf.Len() != 0 {
+					t.Fatalf("unexpected partial output: %q", buf.String())
+				}
+				if tc.err != nil {
+					if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+						t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+					}
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Fatalf("RenderTemplate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
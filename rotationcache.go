@@ -0,0 +1,77 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// RotationCacheConfig makes the cache aware of the rotations. The client
+// describes each fetched secret, and, when a version of the secret is
+// labeled AWSPENDING, but not AWSCURRENT, i.e. the rotation is in
+// progress, it caches the secret for the pending TTL instead of the TTL of
+// the cache. It does not store such secrets in the shared cache. The
+// secrets cached before the rotation started keep their TTL, use
+// RotationPoller to invalidate them once rotated.
+type RotationCacheConfig struct {
+	// PendingTTL is the period of time the secrets with the rotation in
+	// progress are cached for, e.g. "10s". When empty, they are not
+	// cached.
+	PendingTTL string `json:"pending_ttl,omitempty" xml:"pending_ttl,omitempty" yaml:"pending_ttl,omitempty"`
+}
+
+func (cfg *RotationCacheConfig) validate() error {
+	if cfg.PendingTTL == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(cfg.PendingTTL); err != nil || d < 0 {
+		return fmt.Errorf("malformed %q rotation pending ttl", cfg.PendingTTL)
+	}
+	return nil
+}
+
+// pendingTTL returns the cache TTL of the secrets with the rotation in
+// progress.
+func (cfg *RotationCacheConfig) pendingTTL() time.Duration {
+	// The configuration is validated.
+	d, _ := time.ParseDuration(cfg.PendingTTL)
+	return d
+}
+
+// rotationPending reports whether the described secret has a version
+// labeled AWSPENDING, but not AWSCURRENT.
+func rotationPending(output *secretsmanager.DescribeSecretOutput) bool {
+	if output == nil {
+		return false
+	}
+	for _, stages := range output.VersionIdsToStages {
+		var isCurrent, isPending bool
+		for _, stage := range stages {
+			switch stage {
+			case versionStageCurrent:
+				isCurrent = true
+			case versionStagePending:
+				isPending = true
+			}
+		}
+		if isPending && !isCurrent {
+			return true
+		}
+	}
+	return false
+}
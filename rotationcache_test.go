@@ -0,0 +1,108 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestRotationCache(t *testing.T) {
+	testcases := []struct {
+		name       string
+		stages     map[string]interface{}
+		pendingTTL string
+		advance    time.Duration
+		want       int
+	}{
+		{
+			name:    "test secret without rotation in progress",
+			stages:  map[string]interface{}{"v1": []string{"AWSPREVIOUS"}, "v2": []string{"AWSCURRENT", "AWSPENDING"}},
+			advance: 30 * time.Second,
+			want:    1,
+		},
+		{
+			name:   "test secret with rotation in progress not cached",
+			stages: map[string]interface{}{"v1": []string{"AWSCURRENT"}, "v2": []string{"AWSPENDING"}},
+			want:   2,
+		},
+		{
+			name:       "test secret with rotation in progress cached briefly",
+			stages:     map[string]interface{}{"v1": []string{"AWSCURRENT"}, "v2": []string{"AWSPENDING"}},
+			pendingTTL: "10s",
+			want:       1,
+		},
+		{
+			name:       "test secret with rotation in progress expired",
+			stages:     map[string]interface{}{"v1": []string{"AWSCURRENT"}, "v2": []string{"AWSPENDING"}},
+			pendingTTL: "10s",
+			advance:    30 * time.Second,
+			want:       2,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var fetches int
+			clock := newTestClock()
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithClock(clock),
+				WithCacheTTL(time.Hour),
+				WithRotationCache(&RotationCacheConfig{PendingTTL: tc.pendingTTL}),
+				WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+					if r.Header.Get("X-Amz-Target") == "secretsmanager.DescribeSecret" {
+						return secretsmock.JSONResponse(map[string]interface{}{"VersionIdsToStages": tc.stages}), nil
+					}
+					fetches++
+					return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+				})),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			for i := 0; i < 2; i++ {
+				if _, err := c.GetSecret(context.TODO(), "authcrunch/ldap"); err != nil {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				clock.Advance(tc.advance)
+			}
+			if fetches != tc.want {
+				t.Fatalf("unexpected %d fetches, want %d", fetches, tc.want)
+			}
+		})
+	}
+}
+
+func TestRotationCacheConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		ttl string
+		err string
+	}{
+		{ttl: "foo", err: `malformed "foo" rotation pending ttl`},
+		{ttl: "-1s", err: `malformed "-1s" rotation pending ttl`},
+	} {
+		err := (&RotationCacheConfig{PendingTTL: tc.ttl}).validate()
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("unexpected error: %v, want: %s", err, tc.err)
+		}
+	}
+}
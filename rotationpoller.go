@@ -0,0 +1,263 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	versionStagePending = "AWSPENDING"
+
+	defaultRotationPollInterval   = 5 * time.Minute
+	defaultRotationPendingTimeout = 30 * time.Minute
+)
+
+// RotationCallbacks are invoked by RotationPoller. Any of them may be nil.
+// They are invoked sequentially from the goroutine polling the secrets.
+type RotationCallbacks struct {
+	// OnRotationEnabledChange is invoked when the rotation of the secret
+	// is turned on or off.
+	OnRotationEnabledChange func(path string, enabled bool)
+	// OnNewVersion is invoked when the current version of the secret
+	// changes, after the cached versions of the secret are invalidated.
+	OnNewVersion func(path, versionID string)
+	// OnRotationFailed is invoked once for the pending version of the
+	// secret not promoted to the current one within the pending timeout.
+	OnRotationFailed func(path, versionID string)
+	// OnError is invoked when the secret cannot be described.
+	OnError func(path string, err error)
+}
+
+// RotationPollerConfig is the configuration of RotationPoller.
+type RotationPollerConfig struct {
+	// Paths are the paths of the secrets. More paths may be added with
+	// Register.
+	Paths []string `json:"paths,omitempty" xml:"paths,omitempty" yaml:"paths,omitempty"`
+	// Interval is the polling interval, e.g. "1m". When empty, it is five
+	// minutes.
+	Interval string `json:"interval,omitempty" xml:"interval,omitempty" yaml:"interval,omitempty"`
+	// PendingTimeout is the period of time a pending version may remain
+	// pending before the rotation is reported as failed, e.g. "15m". When
+	// empty, it is 30 minutes.
+	PendingTimeout string `json:"pending_timeout,omitempty" xml:"pending_timeout,omitempty" yaml:"pending_timeout,omitempty"`
+}
+
+// RotationPollerOption configures RotationPoller.
+type RotationPollerOption func(*RotationPoller)
+
+// WithRotationPollerClock sets the clock of the poller, replacing the
+// system clock in the tests.
+func WithRotationPollerClock(clock Clock) RotationPollerOption {
+	return func(p *RotationPoller) {
+		p.clock = clock
+	}
+}
+
+// rotationState is the rotation status of a secret observed by the last
+// poll.
+type rotationState struct {
+	known           bool
+	rotationEnabled bool
+	currentVersion  string
+	pendingVersion  string
+	pendingSince    time.Time
+	pendingReported bool
+}
+
+// RotationPoller periodically describes the secrets and reports the
+// changes of their rotation status. It invalidates the cached versions of
+// the rotated secrets. The first poll of a secret establishes its status
+// and reports nothing.
+type RotationPoller struct {
+	c              Client
+	callbacks      *RotationCallbacks
+	clock          Clock
+	interval       time.Duration
+	pendingTimeout time.Duration
+
+	mu     sync.Mutex
+	states map[string]*rotationState
+}
+
+// NewRotationPoller returns the poller of the secrets described with the
+// client.
+func NewRotationPoller(c Client, cfg *RotationPollerConfig, callbacks *RotationCallbacks, opts ...RotationPollerOption) (*RotationPoller, error) {
+	if callbacks == nil {
+		callbacks = &RotationCallbacks{}
+	}
+	p := &RotationPoller{
+		c:              c,
+		callbacks:      callbacks,
+		clock:          systemClock{},
+		interval:       defaultRotationPollInterval,
+		pendingTimeout: defaultRotationPendingTimeout,
+		states:         make(map[string]*rotationState),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("malformed %q rotation poll interval", cfg.Interval)
+		}
+		p.interval = d
+	}
+	if cfg.PendingTimeout != "" {
+		d, err := time.ParseDuration(cfg.PendingTimeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("malformed %q rotation pending timeout", cfg.PendingTimeout)
+		}
+		p.pendingTimeout = d
+	}
+	for _, path := range cfg.Paths {
+		if err := p.Register(path); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Register adds the secret at the path to the polled ones.
+func (p *RotationPoller) Register(path string) error {
+	if path == "" {
+		return errors.New("rotation poller path is empty")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.states[path]; !exists {
+		p.states[path] = &rotationState{}
+	}
+	return nil
+}
+
+// Unregister removes the secret at the path from the polled ones.
+func (p *RotationPoller) Unregister(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.states, path)
+}
+
+// paths returns the sorted paths of the polled secrets.
+func (p *RotationPoller) paths() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	paths := make([]string, 0, len(p.states))
+	for path := range p.states {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Poll describes the secrets once and invokes the callbacks for the
+// changes since the previous poll.
+func (p *RotationPoller) Poll(ctx context.Context) {
+	for _, path := range p.paths() {
+		if ctx.Err() != nil {
+			return
+		}
+		m, err := p.c.DescribeSecret(ctx, path)
+		if err != nil {
+			if p.callbacks.OnError != nil {
+				p.callbacks.OnError(path, err)
+			}
+			continue
+		}
+		p.update(path, m)
+	}
+}
+
+// update compares the metadata of the secret with its previous status.
+func (p *RotationPoller) update(path string, m *SecretMetadata) {
+	var current, pending string
+	for versionID, stages := range m.VersionIdsToStages {
+		var isCurrent, isPending bool
+		for _, stage := range stages {
+			switch stage {
+			case versionStageCurrent:
+				isCurrent = true
+			case versionStagePending:
+				isPending = true
+			}
+		}
+		if isCurrent {
+			current = versionID
+		}
+		if isPending && !isCurrent {
+			pending = versionID
+		}
+	}
+
+	p.mu.Lock()
+	state, exists := p.states[path]
+	if !exists {
+		p.mu.Unlock()
+		return
+	}
+	prev := *state
+	now := p.clock.Now()
+	state.known = true
+	state.rotationEnabled = m.RotationEnabled
+	state.currentVersion = current
+	if pending != state.pendingVersion {
+		state.pendingVersion = pending
+		state.pendingSince = now
+		state.pendingReported = false
+	}
+	var pendingFailed bool
+	if pending != "" && !state.pendingReported && now.Sub(state.pendingSince) >= p.pendingTimeout {
+		state.pendingReported = true
+		pendingFailed = true
+	}
+	p.mu.Unlock()
+
+	if !prev.known {
+		return
+	}
+	if prev.rotationEnabled != m.RotationEnabled && p.callbacks.OnRotationEnabledChange != nil {
+		p.callbacks.OnRotationEnabledChange(path, m.RotationEnabled)
+	}
+	if prev.currentVersion != current && current != "" {
+		p.c.InvalidateCache(path)
+		if p.callbacks.OnNewVersion != nil {
+			p.callbacks.OnNewVersion(path, current)
+		}
+	}
+	if pendingFailed && p.callbacks.OnRotationFailed != nil {
+		p.callbacks.OnRotationFailed(path, pending)
+	}
+}
+
+// Run polls the secrets on the interval until the context is done. The
+// first poll happens immediately.
+func (p *RotationPoller) Run(ctx context.Context) error {
+	ticker := p.clock.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.Poll(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
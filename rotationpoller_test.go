@@ -0,0 +1,135 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// describingClient is the Client serving the metadata of the secrets and
+// recording the invalidations of the cache.
+type describingClient struct {
+	Client
+	metadata    map[string]*SecretMetadata
+	invalidated []string
+}
+
+func (c *describingClient) DescribeSecret(_ context.Context, path string) (*SecretMetadata, error) {
+	m, found := c.metadata[path]
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrSecretNotFound, path)
+	}
+	return m, nil
+}
+
+func (c *describingClient) InvalidateCache(path string) {
+	c.invalidated = append(c.invalidated, path)
+}
+
+func TestRotationPoller(t *testing.T) {
+	c := &describingClient{metadata: map[string]*SecretMetadata{
+		"ldap": {
+			RotationEnabled:    false,
+			VersionIdsToStages: map[string][]string{"v1": {"AWSCURRENT"}},
+		},
+	}}
+	var events []string
+	clock := newTestClock()
+	p, err := NewRotationPoller(c, &RotationPollerConfig{
+		Paths:          []string{"ldap", "smtp"},
+		PendingTimeout: "10m",
+	}, &RotationCallbacks{
+		OnRotationEnabledChange: func(path string, enabled bool) {
+			events = append(events, fmt.Sprintf("enabled %s %t", path, enabled))
+		},
+		OnNewVersion: func(path, versionID string) {
+			events = append(events, "new version "+path+" "+versionID)
+		},
+		OnRotationFailed: func(path, versionID string) {
+			events = append(events, "failed "+path+" "+versionID)
+		},
+		OnError: func(path string, err error) {
+			events = append(events, "error "+path)
+		},
+	}, WithRotationPollerClock(clock))
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	// The first poll establishes the status.
+	p.Poll(context.TODO())
+	if diff := cmp.Diff([]string{"error smtp"}, events); diff != "" {
+		t.Fatalf("events mismatch (-want +got):\n%s", diff)
+	}
+	p.Unregister("smtp")
+
+	// The rotation is turned on and starts.
+	events = nil
+	c.metadata["ldap"] = &SecretMetadata{
+		RotationEnabled:    true,
+		VersionIdsToStages: map[string][]string{"v1": {"AWSCURRENT"}, "v2": {"AWSPENDING"}},
+	}
+	p.Poll(context.TODO())
+	clock.Advance(5 * time.Minute)
+	p.Poll(context.TODO())
+	if diff := cmp.Diff([]string{"enabled ldap true"}, events); diff != "" {
+		t.Fatalf("events mismatch (-want +got):\n%s", diff)
+	}
+
+	// The pending version is stuck.
+	events = nil
+	clock.Advance(5 * time.Minute)
+	p.Poll(context.TODO())
+	clock.Advance(5 * time.Minute)
+	p.Poll(context.TODO())
+	if diff := cmp.Diff([]string{"failed ldap v2"}, events); diff != "" {
+		t.Fatalf("events mismatch (-want +got):\n%s", diff)
+	}
+
+	// The next rotation succeeds.
+	events = nil
+	c.metadata["ldap"] = &SecretMetadata{
+		RotationEnabled:    true,
+		VersionIdsToStages: map[string][]string{"v1": {"AWSPREVIOUS"}, "v3": {"AWSCURRENT", "AWSPENDING"}},
+	}
+	p.Poll(context.TODO())
+	if diff := cmp.Diff([]string{"new version ldap v3"}, events); diff != "" {
+		t.Fatalf("events mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"ldap"}, c.invalidated); diff != "" {
+		t.Fatalf("invalidated paths mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewRotationPollerErrors(t *testing.T) {
+	for _, tc := range []struct {
+		cfg *RotationPollerConfig
+		err string
+	}{
+		{cfg: &RotationPollerConfig{Interval: "0s"}, err: `malformed "0s" rotation poll interval`},
+		{cfg: &RotationPollerConfig{PendingTimeout: "foo"}, err: `malformed "foo" rotation pending timeout`},
+		{cfg: &RotationPollerConfig{Paths: []string{""}}, err: "rotation poller path is empty"},
+	} {
+		_, err := NewRotationPoller(nil, tc.cfg, nil)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("unexpected error: %v, want: %s", err, tc.err)
+		}
+	}
+}
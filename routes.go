@@ -0,0 +1,64 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RouteConfig routes the secrets with the path prefix to the region and,
+// optionally, the endpoint.
+type RouteConfig struct {
+	PathPrefix string `json:"path_prefix,omitempty" xml:"path_prefix,omitempty" yaml:"path_prefix,omitempty"`
+	Region     string `json:"region,omitempty" xml:"region,omitempty" yaml:"region,omitempty"`
+	Endpoint   string `json:"endpoint,omitempty" xml:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+}
+
+func (r *RouteConfig) validate() error {
+	if r.PathPrefix == "" {
+		return errors.New("route path prefix is empty")
+	}
+	if r.Region == "" && r.Endpoint == "" {
+		return fmt.Errorf("route for %q path prefix has neither region nor endpoint", r.PathPrefix)
+	}
+	if r.Region != "" && !awsRegionRgx.MatchString(r.Region) {
+		return fmt.Errorf("malformed %q region in route for %q path prefix", r.Region, r.PathPrefix)
+	}
+	if r.Endpoint != "" {
+		u, err := url.Parse(r.Endpoint)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("malformed %q endpoint in route for %q path prefix", r.Endpoint, r.PathPrefix)
+		}
+	}
+	return nil
+}
+
+// route returns the route with the longest path prefix matching the path,
+// or nil when none matches.
+func (cfg *ClientConfig) route(path string) *RouteConfig {
+	var match *RouteConfig
+	for _, r := range cfg.Routes {
+		if !strings.HasPrefix(path, r.PathPrefix) {
+			continue
+		}
+		if match == nil || len(r.PathPrefix) > len(match.PathPrefix) {
+			match = r
+		}
+	}
+	return match
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestRoutes(t *testing.T) {
+	routes := []*RouteConfig{
+		{PathPrefix: "authcrunch/eu/", Region: "eu-west-1"},
+		{PathPrefix: "authcrunch/eu/local/", Region: "eu-central-1"},
+		{PathPrefix: "authcrunch/lab/", Endpoint: "http://localhost:4566"},
+	}
+	testcases := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "test path without route",
+			path: "authcrunch/us/local/users/jsmith",
+			want: "secretsmanager.us-east-1.amazonaws.com",
+		},
+		{
+			name: "test path with route",
+			path: "authcrunch/eu/ldap/users/jsmith",
+			want: "secretsmanager.eu-west-1.amazonaws.com",
+		},
+		{
+			name: "test path with longest matching route",
+			path: "authcrunch/eu/local/users/jsmith",
+			want: "secretsmanager.eu-central-1.amazonaws.com",
+		},
+		{
+			name: "test path with endpoint route",
+			path: "authcrunch/lab/users/jsmith",
+			want: "localhost:4566",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			httpClient := smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+				got = r.URL.Host
+				return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+			})
+			c, err := NewClient(context.TODO(),
+				WithConfig(&ClientConfig{ID: "foo", Region: "us-east-1", Routes: routes}),
+				WithHTTPClient(httpClient),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			if _, err := c.GetSecret(context.TODO(), tc.path); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetSecret() host mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	testcases := []struct {
+		name   string
+		routes []*RouteConfig
+		err    error
+	}{
+		{
+			name:   "test route without path prefix",
+			routes: []*RouteConfig{{Region: "eu-west-1"}},
+			err:    errors.New("route path prefix is empty"),
+		},
+		{
+			name:   "test route without region and endpoint",
+			routes: []*RouteConfig{{PathPrefix: "authcrunch/eu/"}},
+			err:    fmt.Errorf("route for %q path prefix has neither region nor endpoint", "authcrunch/eu/"),
+		},
+		{
+			name:   "test route with malformed region",
+			routes: []*RouteConfig{{PathPrefix: "authcrunch/eu/", Region: "europe"}},
+			err:    fmt.Errorf("malformed %q region in route for %q path prefix", "europe", "authcrunch/eu/"),
+		},
+		{
+			name:   "test route with malformed endpoint",
+			routes: []*RouteConfig{{PathPrefix: "authcrunch/eu/", Endpoint: "localhost"}},
+			err:    fmt.Errorf("malformed %q endpoint in route for %q path prefix", "localhost", "authcrunch/eu/"),
+		},
+		{
+			name: "test duplicate routes",
+			routes: []*RouteConfig{
+				{PathPrefix: "authcrunch/eu/", Region: "eu-west-1"},
+				{PathPrefix: "authcrunch/eu/", Region: "eu-central-1"},
+			},
+			err: fmt.Errorf("duplicate route for %q path prefix", "authcrunch/eu/"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &ClientConfig{ID: "foo", Provider: defaultProvider, Routes: tc.routes}
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+				t.Errorf("Validate() error mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
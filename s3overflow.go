@@ -0,0 +1,180 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// S3PointerURIKey is the key of the secret holding the s3:// URI of the
+	// object with the content of the secret.
+	S3PointerURIKey = "s3_uri"
+	// S3PointerKMSKeyIDKey is the key of the secret holding the KMS key the
+	// object is encrypted with.
+	S3PointerKMSKeyIDKey = "kms_key_id"
+
+	defaultS3OverflowMaxSize = 10 << 20
+)
+
+// ErrS3Overflow is returned when the object a secret points to cannot be
+// used as its content.
+var ErrS3Overflow = errors.New("s3 overflow object rejected")
+
+// S3OverflowConfig makes the client follow the secrets pointing to the
+// objects in Amazon S3, i.e. the credential blobs exceeding the size limit
+// of AWS Secrets Manager. Such secret holds two keys only, S3PointerURIKey
+// and S3PointerKMSKeyIDKey, e.g.
+//
+//	{"s3_uri": "s3://authcrunch-secrets/users/jsmith.json", "kms_key_id": "1234abcd-12ab-34cd-56ef-1234567890ab"}
+//
+// The object holds the JSON object of the secret and must be encrypted
+// with the KMS key (SSE-KMS), which S3 decrypts transparently. The key is
+// either a key ID or a key ARN. S3 reports the ARN of the key, not its
+// alias, so the aliases are rejected. The limits, checksum, schema, and
+// password policies apply to the content of the object.
+type S3OverflowConfig struct {
+	// Buckets are the buckets the pointers may refer to. When empty, any
+	// bucket is allowed.
+	Buckets []string `json:"buckets,omitempty" xml:"buckets,omitempty" yaml:"buckets,omitempty"`
+	// MaxSize is the maximum size of the object in bytes. When zero, it is
+	// 10 MiB.
+	MaxSize int64 `json:"max_size,omitempty" xml:"max_size,omitempty" yaml:"max_size,omitempty"`
+}
+
+func (cfg *S3OverflowConfig) validate() error {
+	if cfg.MaxSize < 0 {
+		return fmt.Errorf("malformed %d s3 overflow max size", cfg.MaxSize)
+	}
+	for _, bucket := range cfg.Buckets {
+		if bucket == "" || strings.ContainsAny(bucket, "/ ") {
+			return fmt.Errorf("malformed %q s3 overflow bucket", bucket)
+		}
+	}
+	return nil
+}
+
+// allowsBucket reports whether the pointers may refer to the bucket.
+func (cfg *S3OverflowConfig) allowsBucket(bucket string) bool {
+	if len(cfg.Buckets) == 0 {
+		return true
+	}
+	for _, b := range cfg.Buckets {
+		if b == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// s3Pointer is the reference to the object holding the content of a
+// secret.
+type s3Pointer struct {
+	bucket   string
+	key      string
+	kmsKeyID string
+}
+
+// parseS3Pointer returns the pointer held by the secret, or nil when the
+// secret is not a pointer.
+func parseS3Pointer(m map[string]interface{}) (*s3Pointer, error) {
+	v, found := m[S3PointerURIKey]
+	if !found || len(m) > 2 {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok || !strings.HasPrefix(s, "s3://") {
+		return nil, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("%w: malformed %q uri", ErrS3Overflow, s)
+	}
+	p := &s3Pointer{bucket: u.Host, key: strings.TrimPrefix(u.Path, "/")}
+	p.kmsKeyID, _ = m[S3PointerKMSKeyIDKey].(string)
+	if p.kmsKeyID == "" {
+		return nil, fmt.Errorf("%w: %q pointer has no kms key", ErrS3Overflow, s)
+	}
+	if err := validateKMSKeyID(p.kmsKeyID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrS3Overflow, err)
+	}
+	if strings.HasPrefix(p.kmsKeyID, "alias/") || strings.Contains(p.kmsKeyID, ":alias/") {
+		return nil, fmt.Errorf("%w: %q pointer has %q kms key alias", ErrS3Overflow, s, p.kmsKeyID)
+	}
+	return p, nil
+}
+
+func (p *s3Pointer) String() string {
+	return "s3://" + p.bucket + "/" + p.key
+}
+
+// followS3Pointer returns the content of the object the secret points to,
+// or an empty string when the secret is not a pointer.
+func (c *client) followS3Pointer(ctx context.Context, cfg *S3OverflowConfig, region, path string, m map[string]interface{}) (string, error) {
+	p, err := parseS3Pointer(m)
+	if err != nil || p == nil {
+		return "", err
+	}
+	if !cfg.allowsBucket(p.bucket) {
+		return "", fmt.Errorf("%w: %q bucket not allowed", ErrS3Overflow, p.bucket)
+	}
+	c.mu.RLock()
+	serviceConfig := c.serviceConfig
+	c.mu.RUnlock()
+	if region != "" {
+		serviceConfig.Region = region
+	}
+	output, err := s3.NewFromConfig(serviceConfig).GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(p.key),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer output.Body.Close()
+	if output.ServerSideEncryption != s3types.ServerSideEncryptionAwsKms {
+		return "", fmt.Errorf("%w: %q is not encrypted with kms key", ErrS3Overflow, p)
+	}
+	if !matchKMSKeyID(p.kmsKeyID, aws.ToString(output.SSEKMSKeyId)) {
+		return "", fmt.Errorf("%w: %q is encrypted with %q, want %q", ErrS3Overflow, p, aws.ToString(output.SSEKMSKeyId), p.kmsKeyID)
+	}
+	maxSize := cfg.MaxSize
+	if maxSize == 0 {
+		maxSize = defaultS3OverflowMaxSize
+	}
+	b, err := io.ReadAll(io.LimitReader(output.Body, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(b)) > maxSize {
+		return "", fmt.Errorf("%w: %q exceeds %d bytes", ErrS3Overflow, p, maxSize)
+	}
+	c.getLogger().Debug(
+		"secret retrieved from s3 object",
+		zap.String("path", path),
+		zap.String("object", p.String()),
+	)
+	return string(b), nil
+}
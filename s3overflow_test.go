@@ -0,0 +1,234 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// s3TestObject is the object served by the mock S3 endpoint.
+type s3TestObject struct {
+	body     string
+	kmsKeyID string
+}
+
+// newS3OverflowMockClient returns HTTP client serving the secrets and the
+// objects keyed by "bucket/key". It records the requested objects.
+func newS3OverflowMockClient(t *testing.T, secrets map[string]string, objects map[string]*s3TestObject, requested *[]string) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		if target := r.Header.Get("X-Amz-Target"); target != "" {
+			if target != "secretsmanager.GetSecretValue" {
+				return mockFailure(t, "unexpected %q target", target)
+			}
+			b, _ := io.ReadAll(r.Body)
+			for name, v := range secrets {
+				if strings.Contains(string(b), `"`+name+`"`) {
+					return secretsmock.JSONResponse(map[string]interface{}{"SecretString": v}), nil
+				}
+			}
+			return secretsmock.NotFoundResponse(""), nil
+		}
+		bucket := strings.SplitN(r.URL.Host, ".", 2)[0]
+		name := bucket + r.URL.Path
+		*requested = append(*requested, name)
+		obj, found := objects[name]
+		if !found {
+			return secretsmock.ErrorResponse("", http.StatusNotFound, "NoSuchKey", ""), nil
+		}
+		header := http.Header{}
+		if obj.kmsKeyID != "" {
+			header.Set("X-Amz-Server-Side-Encryption", "aws:kms")
+			header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", obj.kmsKeyID)
+		} else {
+			header.Set("X-Amz-Server-Side-Encryption", "AES256")
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader(obj.body)),
+		}, nil
+	})
+}
+
+func TestS3Overflow(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	secrets := map[string]string{
+		"authcrunch/users/jsmith":    `{"s3_uri":"s3://authcrunch-secrets/users/jsmith.json","kms_key_id":"1234abcd-12ab-34cd-56ef-1234567890ab"}`,
+		"authcrunch/users/mjones":    `{"s3_uri":"s3://authcrunch-secrets/users/mjones.json","kms_key_id":"0987dcba-09fe-87dc-65ba-ab0987654321"}`,
+		"authcrunch/users/sse":       `{"s3_uri":"s3://authcrunch-secrets/users/sse.json","kms_key_id":"1234abcd-12ab-34cd-56ef-1234567890ab"}`,
+		"authcrunch/users/other":     `{"s3_uri":"s3://other-bucket/users/other.json","kms_key_id":"1234abcd-12ab-34cd-56ef-1234567890ab"}`,
+		"authcrunch/users/alias":     `{"s3_uri":"s3://authcrunch-secrets/users/jsmith.json","kms_key_id":"alias/authcrunch"}`,
+		"authcrunch/users/nokey":     `{"s3_uri":"s3://authcrunch-secrets/users/jsmith.json"}`,
+		"authcrunch/users/plain":     `{"username":"plain","s3_uri":"s3://authcrunch-secrets/users/jsmith.json","kms_key_id":"alias/authcrunch"}`,
+		"authcrunch/users/malformed": `{"s3_uri":"s3://authcrunch-secrets/","kms_key_id":"alias/authcrunch"}`,
+	}
+	objects := map[string]*s3TestObject{
+		"authcrunch-secrets/users/jsmith.json": {body: `{"username":"jsmith","certificate":"MIIB..."}`, kmsKeyID: keyARN},
+		"authcrunch-secrets/users/mjones.json": {body: `{"username":"mjones"}`, kmsKeyID: keyARN},
+		"authcrunch-secrets/users/sse.json":    {body: `{"username":"sse"}`},
+		"other-bucket/users/other.json":        {body: `{"username":"other"}`, kmsKeyID: keyARN},
+	}
+	testcases := []struct {
+		name          string
+		overflow      *S3OverflowConfig
+		path          string
+		want          map[string]interface{}
+		wantRequested []string
+		shouldErr     bool
+		err           error
+	}{
+		{
+			name:          "test secret pointing to object",
+			overflow:      &S3OverflowConfig{Buckets: []string{"authcrunch-secrets"}},
+			path:          "users/jsmith",
+			want:          map[string]interface{}{"username": "jsmith", "certificate": "MIIB..."},
+			wantRequested: []string{"authcrunch-secrets/users/jsmith.json"},
+		},
+		{
+			name: "test secret pointing to object without overflow",
+			path: "users/jsmith",
+			want: map[string]interface{}{
+				"s3_uri":     "s3://authcrunch-secrets/users/jsmith.json",
+				"kms_key_id": "1234abcd-12ab-34cd-56ef-1234567890ab",
+			},
+		},
+		{
+			name:     "test secret with other keys",
+			overflow: &S3OverflowConfig{},
+			path:     "users/plain",
+			want: map[string]interface{}{
+				"username":   "plain",
+				"s3_uri":     "s3://authcrunch-secrets/users/jsmith.json",
+				"kms_key_id": "alias/authcrunch",
+			},
+		},
+		{
+			name:          "test object encrypted with kms key other than pointer key",
+			overflow:      &S3OverflowConfig{},
+			path:          "users/mjones",
+			wantRequested: []string{"authcrunch-secrets/users/mjones.json"},
+			shouldErr:     true,
+			err:           errors.New(`s3 overflow object rejected: "s3://authcrunch-secrets/users/mjones.json" is encrypted with "` + keyARN + `", want "0987dcba-09fe-87dc-65ba-ab0987654321"`),
+		},
+		{
+			name:          "test object without kms encryption",
+			overflow:      &S3OverflowConfig{},
+			path:          "users/sse",
+			wantRequested: []string{"authcrunch-secrets/users/sse.json"},
+			shouldErr:     true,
+			err:           errors.New(`s3 overflow object rejected: "s3://authcrunch-secrets/users/sse.json" is not encrypted with kms key`),
+		},
+		{
+			name:      "test object in bucket not allowed",
+			overflow:  &S3OverflowConfig{Buckets: []string{"authcrunch-secrets"}},
+			path:      "users/other",
+			shouldErr: true,
+			err:       errors.New(`s3 overflow object rejected: "other-bucket" bucket not allowed`),
+		},
+		{
+			name:      "test pointer without kms key",
+			overflow:  &S3OverflowConfig{},
+			path:      "users/nokey",
+			shouldErr: true,
+			err:       errors.New(`s3 overflow object rejected: "s3://authcrunch-secrets/users/jsmith.json" pointer has no kms key`),
+		},
+		{
+			name:      "test pointer with kms key alias",
+			overflow:  &S3OverflowConfig{},
+			path:      "users/alias",
+			shouldErr: true,
+			err:       errors.New(`s3 overflow object rejected: "s3://authcrunch-secrets/users/jsmith.json" pointer has "alias/authcrunch" kms key alias`),
+		},
+		{
+			name:      "test malformed pointer",
+			overflow:  &S3OverflowConfig{},
+			path:      "users/malformed",
+			shouldErr: true,
+			err:       errors.New(`s3 overflow object rejected: malformed "s3://authcrunch-secrets/" uri`),
+		},
+		{
+			name:          "test object exceeding max size",
+			overflow:      &S3OverflowConfig{MaxSize: 16},
+			path:          "users/jsmith",
+			wantRequested: []string{"authcrunch-secrets/users/jsmith.json"},
+			shouldErr:     true,
+			err:           errors.New(`s3 overflow object rejected: "s3://authcrunch-secrets/users/jsmith.json" exceeds 16 bytes`),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requested []string
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithBasePrefix("authcrunch/"),
+				WithS3Overflow(tc.overflow),
+				WithHTTPClient(newS3OverflowMockClient(t, secrets, objects, &requested)),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			got, err := c.GetSecret(context.TODO(), tc.path)
+			if diff := cmp.Diff(tc.wantRequested, requested); diff != "" {
+				t.Errorf("requested objects mismatch (-want +got):\n%s", diff)
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, ErrS3Overflow) {
+					t.Fatalf("expected ErrS3Overflow, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("GetSecret() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestS3OverflowConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		cfg *S3OverflowConfig
+		err string
+	}{
+		{cfg: &S3OverflowConfig{}},
+		{cfg: &S3OverflowConfig{Buckets: []string{"authcrunch-secrets"}, MaxSize: 1 << 20}},
+		{cfg: &S3OverflowConfig{MaxSize: -1}, err: "malformed -1 s3 overflow max size"},
+		{cfg: &S3OverflowConfig{Buckets: []string{"authcrunch/secrets"}}, err: `malformed "authcrunch/secrets" s3 overflow bucket`},
+	} {
+		err := tc.cfg.validate()
+		if (err == nil && tc.err != "") || (err != nil && err.Error() != tc.err) {
+			t.Errorf("unexpected error: %v, want: %s", err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+)
+
+// SecretSchema describes the expected structure of the secrets with the
+// paths matching the Path pattern, e.g. "authcrunch/caddy/users/*". The
+// pattern syntax is the one of path.Match.
+type SecretSchema struct {
+	Path         string            `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	RequiredKeys []string          `json:"required_keys,omitempty" xml:"required_keys,omitempty" yaml:"required_keys,omitempty"`
+	Patterns     map[string]string `json:"patterns,omitempty" xml:"patterns,omitempty" yaml:"patterns,omitempty"`
+}
+
+func (s *SecretSchema) validate() error {
+	if s.Path == "" {
+		return errors.New("secret schema path is empty")
+	}
+	if _, err := path.Match(s.Path, ""); err != nil {
+		return fmt.Errorf("malformed %q secret schema path: %v", s.Path, err)
+	}
+	for k, p := range s.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("malformed %q secret schema pattern for %q key: %v", p, k, err)
+		}
+	}
+	return nil
+}
+
+// Check returns an error when the schema is malformed or the secret at the
+// path matching the schema path pattern does not conform to the schema.
+func (s *SecretSchema) Check(secretPath string, m map[string]interface{}) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	return s.check(secretPath, m)
+}
+
+// check returns an error when the secret at the path matching the schema
+// path pattern does not conform to the schema.
+func (s *SecretSchema) check(secretPath string, m map[string]interface{}) error {
+	if matched, _ := path.Match(s.Path, secretPath); !matched {
+		return nil
+	}
+	for _, k := range s.RequiredKeys {
+		if _, exists := m[k]; !exists {
+			return fmt.Errorf("secret %q does not match schema: key %q not found", secretPath, k)
+		}
+	}
+	keys := make([]string, 0, len(s.Patterns))
+	for k := range s.Patterns {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, exists := m[k]
+		if !exists {
+			continue
+		}
+		value, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("secret %q does not match schema: key %q value is not a string", secretPath, k)
+		}
+		if !regexp.MustCompile(s.Patterns[k]).MatchString(value) {
+			return fmt.Errorf("secret %q does not match schema: key %q value does not match %q pattern", secretPath, k, s.Patterns[k])
+		}
+	}
+	return nil
+}
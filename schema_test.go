@@ -0,0 +1,206 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestSecretSchema(t *testing.T) {
+	bcryptPattern := `^bcrypt:\d+:\$2[aby]\$\d{2}\$[./A-Za-z0-9]{53}$`
+	userSchema := &SecretSchema{
+		Path:         "authcrunch/caddy/users/*",
+		RequiredKeys: []string{"username", "password"},
+		Patterns: map[string]string{
+			"password": bcryptPattern,
+			"api_key":  bcryptPattern,
+		},
+	}
+
+	testcases := []struct {
+		name      string
+		path      string
+		schemas   []*SecretSchema
+		secret    map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:    "test valid user secret",
+			path:    "authcrunch/caddy/users/jsmith",
+			schemas: []*SecretSchema{userSchema},
+			secret: map[string]interface{}{
+				"username": "jsmith",
+				"password": "bcrypt:10:$2a$10$iqq53VjdCwknBSBrnyLd9OH1Mfh6kqPezMMy6h6F41iLdVDkj13I6",
+				"api_key":  "bcrypt:10:$2a$10$TEQ7ZG9cAdWwhQK36orCGOlokqQA55ddE0WEsl00oLZh567okdcZ6",
+			},
+		},
+		{
+			name:    "test user secret without password",
+			path:    "authcrunch/caddy/users/jsmith",
+			schemas: []*SecretSchema{userSchema},
+			secret: map[string]interface{}{
+				"username": "jsmith",
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("secret %q does not match schema: key %q not found", "authcrunch/caddy/users/jsmith", "password"),
+		},
+		{
+			name:    "test user secret with plaintext password",
+			path:    "authcrunch/caddy/users/jsmith",
+			schemas: []*SecretSchema{userSchema},
+			secret: map[string]interface{}{
+				"username": "jsmith",
+				"password": "rbrH97m9bpbk3qRphHFNM9ksJfRcWdvr",
+			},
+			shouldErr: true,
+			err: fmt.Errorf("secret %q does not match schema: key %q value does not match %q pattern",
+				"authcrunch/caddy/users/jsmith", "password", bcryptPattern,
+			),
+		},
+		{
+			name:    "test user secret with non-string api key",
+			path:    "authcrunch/caddy/users/jsmith",
+			schemas: []*SecretSchema{userSchema},
+			secret: map[string]interface{}{
+				"username": "jsmith",
+				"password": "bcrypt:10:$2a$10$iqq53VjdCwknBSBrnyLd9OH1Mfh6kqPezMMy6h6F41iLdVDkj13I6",
+				"api_key":  12345,
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("secret %q does not match schema: key %q value is not a string", "authcrunch/caddy/users/jsmith", "api_key"),
+		},
+		{
+			name:    "test secret outside of schema path",
+			path:    "authcrunch/caddy/access_token",
+			schemas: []*SecretSchema{userSchema},
+			secret: map[string]interface{}{
+				"value": "b006d65b-c923-46a1-8da1-7d52558508fe",
+			},
+		},
+		{
+			name:      "test schema without path",
+			schemas:   []*SecretSchema{{RequiredKeys: []string{"username"}}},
+			shouldErr: true,
+			err:       errors.New("secret schema path is empty"),
+		},
+		{
+			name: "test schema with malformed pattern",
+			schemas: []*SecretSchema{{
+				Path:     "authcrunch/caddy/users/*",
+				Patterns: map[string]string{"password": "^bcrypt:("},
+			}},
+			shouldErr: true,
+			err: fmt.Errorf("malformed %q secret schema pattern for %q key: %v",
+				"^bcrypt:(", "password", "error parsing regexp: missing closing ): `^bcrypt:(`",
+			),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClientWithConfig(context.TODO(), &ClientConfig{
+				ID:       "foo",
+				Region:   "us-east-1",
+				Provider: "aws_secrets_manager",
+				Schemas:  tc.schemas,
+			})
+			if err == nil {
+				c.SetMockClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+					return secretsmock.SecretStringResponse(tc.secret), nil
+				}))
+				c.SetMockCredentialsProvider(MockCredentialsProvider{})
+				_, err = c.GetSecret(context.TODO(), tc.path)
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("GetSecret() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestSecretSchemaCheck(t *testing.T) {
+	testcases := []struct {
+		name      string
+		schema    *SecretSchema
+		path      string
+		secret    map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test conforming secret",
+			schema: &SecretSchema{Path: "users/*", RequiredKeys: []string{"username"}},
+			path:   "users/jsmith",
+			secret: map[string]interface{}{"username": "jsmith"},
+		},
+		{
+			name:   "test secret outside of schema path",
+			schema: &SecretSchema{Path: "users/*", RequiredKeys: []string{"username"}},
+			path:   "tokens/access_token",
+			secret: map[string]interface{}{"secret": "foobar"},
+		},
+		{
+			name:      "test malformed schema",
+			schema:    &SecretSchema{Path: "users/["},
+			path:      "users/jsmith",
+			shouldErr: true,
+			err:       errors.New(`malformed "users/[" secret schema path: syntax error in pattern`),
+		},
+		{
+			name:      "test non-conforming secret",
+			schema:    &SecretSchema{Path: "users/*", RequiredKeys: []string{"username"}},
+			path:      "users/jsmith",
+			secret:    map[string]interface{}{"email": "jsmith@localhost.localdomain"},
+			shouldErr: true,
+			err:       errors.New(`secret "users/jsmith" does not match schema: key "username" not found`),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.schema.Check(tc.path, tc.secret)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,509 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrScopedClient is returned by the methods of a scoped client that are
+// not available to it, e.g. the ones changing the configuration.
+var ErrScopedClient = errors.New("operation not available to scoped client")
+
+// scopedClient is the view of the client restricted to the paths under the
+// prefixes. Nested scopes add prefixes, and a path must be under all of
+// them.
+type scopedClient struct {
+	c        *client
+	prefixes []string
+}
+
+// Scoped returns the view of the client that can only access the secrets
+// with the paths under the prefix, e.g. "portals/users", and cannot change
+// the configuration. The paths are not relative to the prefix. Closing the
+// view does not close the client.
+func (c *client) Scoped(prefix string) Client {
+	return &scopedClient{c: c, prefixes: []string{strings.TrimSuffix(prefix, "/")}}
+}
+
+// Scoped returns the view of the scoped client further restricted to the
+// paths under the prefix.
+func (s *scopedClient) Scoped(prefix string) Client {
+	prefixes := append(append([]string{}, s.prefixes...), strings.TrimSuffix(prefix, "/"))
+	return &scopedClient{c: s.c, prefixes: prefixes}
+}
+
+// inScope returns true when the path is under all the prefixes. The paths
+// with empty, "." or ".." segments are never in scope.
+func (s *scopedClient) inScope(secretPath string) bool {
+	for _, segment := range strings.Split(secretPath, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	for _, prefix := range s.prefixes {
+		if prefix == "" || (secretPath != prefix && !strings.HasPrefix(secretPath, prefix+"/")) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *scopedClient) check(paths ...string) error {
+	for _, secretPath := range paths {
+		if !s.inScope(secretPath) {
+			return fmt.Errorf("%w: %q outside %q scope", ErrPathNotAllowed, secretPath, strings.Join(s.prefixes, ", "))
+		}
+	}
+	return nil
+}
+
+// GetSecret returns the key-value map of the stored secret.
+func (s *scopedClient) GetSecret(ctx context.Context, path string, opts ...CallOption) (map[string]interface{}, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSecret(ctx, path, opts...)
+}
+
+// GetSecretByKey returns the value of the key of the stored secret.
+func (s *scopedClient) GetSecretByKey(ctx context.Context, path, key string, opts ...CallOption) (interface{}, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSecretByKey(ctx, path, key, opts...)
+}
+
+// GetSecretBytes returns the value of the key of the stored secret.
+func (s *scopedClient) GetSecretBytes(ctx context.Context, path, key string, opts ...CallOption) (*Secret, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSecretBytes(ctx, path, key, opts...)
+}
+
+// GetSecretInto writes the value of the secret to the writer.
+func (s *scopedClient) GetSecretInto(ctx context.Context, path string, w io.Writer) error {
+	if err := s.check(path); err != nil {
+		return err
+	}
+	return s.c.GetSecretInto(ctx, path, w)
+}
+
+// GetSecretTemplated returns the key-value map of the secret at the path
+// rendered from the template.
+func (s *scopedClient) GetSecretTemplated(ctx context.Context, tmpl string, vars map[string]string) (map[string]interface{}, error) {
+	path, err := renderSecretPath(tmpl, vars)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetSecret(ctx, path)
+}
+
+// GetTokenSecrets returns the token signing secrets.
+func (s *scopedClient) GetTokenSecrets(ctx context.Context, path string) (*TokenSecrets, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetTokenSecrets(ctx, path)
+}
+
+// GetAccessToken returns the access token secret.
+func (s *scopedClient) GetAccessToken(ctx context.Context, path string) (*AccessToken, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetAccessToken(ctx, path)
+}
+
+// GetOAuthClientCredentials returns the OAuth client credentials.
+func (s *scopedClient) GetOAuthClientCredentials(ctx context.Context, path string) (*OAuthClientCredentials, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetOAuthClientCredentials(ctx, path)
+}
+
+// GetDatabaseCredentials returns the database credentials.
+func (s *scopedClient) GetDatabaseCredentials(ctx context.Context, path string) (*DatabaseCredentials, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetDatabaseCredentials(ctx, path)
+}
+
+// GetUserCredentials returns the user credentials.
+func (s *scopedClient) GetUserCredentials(ctx context.Context, path string) (*UserCredentials, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetUserCredentials(ctx, path)
+}
+
+// GetSMTPCredentials returns the SMTP credentials.
+func (s *scopedClient) GetSMTPCredentials(ctx context.Context, path string) (*SMTPCredentials, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSMTPCredentials(ctx, path)
+}
+
+// GetLDAPBindCredentials returns the LDAP bind credentials.
+func (s *scopedClient) GetLDAPBindCredentials(ctx context.Context, path string) (*LDAPBindCredentials, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetLDAPBindCredentials(ctx, path)
+}
+
+// GetCertificateBundle returns the certificate bundle.
+func (s *scopedClient) GetCertificateBundle(ctx context.Context, path, passphraseKey string) (*CertificateBundle, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetCertificateBundle(ctx, path, passphraseKey)
+}
+
+// GetTLSCertificate returns the TLS certificate.
+func (s *scopedClient) GetTLSCertificate(ctx context.Context, path string) (*tls.Certificate, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetTLSCertificate(ctx, path)
+}
+
+// GetSSHSigner returns the SSH signer.
+func (s *scopedClient) GetSSHSigner(ctx context.Context, path, key string) (ssh.Signer, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSSHSigner(ctx, path, key)
+}
+
+// GetTOTPSeed returns the TOTP seed.
+func (s *scopedClient) GetTOTPSeed(ctx context.Context, path string) (*TOTPSeed, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetTOTPSeed(ctx, path)
+}
+
+// GetPGPEntities returns the PGP entities.
+func (s *scopedClient) GetPGPEntities(ctx context.Context, path, key string) (openpgp.EntityList, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetPGPEntities(ctx, path, key)
+}
+
+// GetWebhookSecret returns the webhook secret.
+func (s *scopedClient) GetWebhookSecret(ctx context.Context, path string) (*WebhookSecret, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetWebhookSecret(ctx, path)
+}
+
+// GetSessionKeys returns the session keys.
+func (s *scopedClient) GetSessionKeys(ctx context.Context, path string) (*SessionKeys, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSessionKeys(ctx, path)
+}
+
+// VerifyUserPassword verifies the candidate password of the user secret.
+func (s *scopedClient) VerifyUserPassword(ctx context.Context, path, candidate string) (bool, error) {
+	if err := s.check(path); err != nil {
+		return false, err
+	}
+	return s.c.VerifyUserPassword(ctx, path, candidate)
+}
+
+// GetUserSecret returns the key-value map of the user secret in the realm.
+func (s *scopedClient) GetUserSecret(ctx context.Context, realmName, username string) (map[string]interface{}, error) {
+	req, err := s.c.newUserSecretRequest(realmName, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.check(req.path); err != nil {
+		return nil, err
+	}
+	return s.c.fetchSecret(ctx, req)
+}
+
+// GetSecretWithPrevious returns the current and the previous versions of
+// the secret.
+func (s *scopedClient) GetSecretWithPrevious(ctx context.Context, path string) (*SecretWithPrevious, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSecretWithPrevious(ctx, path)
+}
+
+// GetSecretVersion returns the current or the pinned version of the
+// secret.
+func (s *scopedClient) GetSecretVersion(ctx context.Context, path string) (*SecretVersion, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSecretVersion(ctx, path)
+}
+
+// DiffSecretVersions compares the versions of the secret.
+func (s *scopedClient) DiffSecretVersions(ctx context.Context, path, v1, v2 string, opts ...DiffOption) (*VersionDiff, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.DiffSecretVersions(ctx, path, v1, v2, opts...)
+}
+
+// GetSecretWithMetadata returns the current version of the secret along
+// with its metadata.
+func (s *scopedClient) GetSecretWithMetadata(ctx context.Context, path string) (*SecretWithMetadata, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSecretWithMetadata(ctx, path)
+}
+
+// GetSecretAcrossAccounts returns the secret in scope fetched in the
+// accounts of the roles.
+func (s *scopedClient) GetSecretAcrossAccounts(ctx context.Context, path string, roleARNs []string) (map[string]*AccountSecret, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.GetSecretAcrossAccounts(ctx, path, roleARNs)
+}
+
+// ExportEnv writes the secrets at the paths to the writer.
+func (s *scopedClient) ExportEnv(ctx context.Context, paths []string, w io.Writer, format string, opts ...ExportOption) error {
+	if err := s.check(paths...); err != nil {
+		return err
+	}
+	return s.c.ExportEnv(ctx, paths, w, format, opts...)
+}
+
+// LoadIntoEnv sets the environment variables to the values of the
+// secrets.
+func (s *scopedClient) LoadIntoEnv(ctx context.Context, mapping map[string]string, opts ...EnvOption) error {
+	for _, ref := range mapping {
+		path, _, _ := strings.Cut(ref, "#")
+		if err := s.check(path); err != nil {
+			return err
+		}
+	}
+	return s.c.LoadIntoEnv(ctx, mapping, opts...)
+}
+
+// RenderTemplate renders the template with the secrets in scope.
+func (s *scopedClient) RenderTemplate(ctx context.Context, tmpl string, w io.Writer) error {
+	return renderTemplate(ctx, s, tmpl, w)
+}
+
+// ListSecrets returns the paths of the secrets in scope with the prefix.
+// The empty prefix lists the secrets under the prefix of the scope.
+func (s *scopedClient) ListSecrets(ctx context.Context, prefix string) ([]string, error) {
+	if prefix == "" {
+		prefix = s.prefixes[len(s.prefixes)-1] + "/"
+	}
+	if err := s.check(strings.TrimSuffix(prefix, "/")); err != nil {
+		return nil, err
+	}
+	paths, err := s.c.ListSecrets(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	filtered := paths[:0]
+	for _, path := range paths {
+		if s.inScope(path) {
+			filtered = append(filtered, path)
+		}
+	}
+	return filtered, nil
+}
+
+// DescribeSecret returns the metadata of the secret at the path.
+func (s *scopedClient) DescribeSecret(ctx context.Context, path string) (*SecretMetadata, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.DescribeSecret(ctx, path)
+}
+
+// ListVersionHistory returns the versions of the secret at the path.
+func (s *scopedClient) ListVersionHistory(ctx context.Context, path string) ([]*SecretVersionInfo, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.ListVersionHistory(ctx, path)
+}
+
+// AnnotateVersion writes the annotation of the version of the secret at
+// the path.
+func (s *scopedClient) AnnotateVersion(ctx context.Context, path, versionID, annotation string) (*Change, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.AnnotateVersion(ctx, path, versionID, annotation)
+}
+
+// CreateSecret creates the secret with the key-value map at the path.
+func (s *scopedClient) CreateSecret(ctx context.Context, path string, m map[string]interface{}) (*Change, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.CreateSecret(ctx, path, m)
+}
+
+// PutSecret stores the key-value map as the new version of the secret.
+func (s *scopedClient) PutSecret(ctx context.Context, path string, m map[string]interface{}, opts ...CallOption) (*Change, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.PutSecret(ctx, path, m, opts...)
+}
+
+// DeleteSecret schedules the deletion of the secret at the path.
+func (s *scopedClient) DeleteSecret(ctx context.Context, path string) (*Change, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.DeleteSecret(ctx, path)
+}
+
+// RotateSecret starts the rotation of the secret at the path.
+func (s *scopedClient) RotateSecret(ctx context.Context, path string) (*Change, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.RotateSecret(ctx, path)
+}
+
+// Sync makes the secrets at the paths match the key-value maps.
+func (s *scopedClient) Sync(ctx context.Context, secrets map[string]map[string]interface{}) ([]*Change, error) {
+	for path := range secrets {
+		if err := s.check(path); err != nil {
+			return nil, err
+		}
+	}
+	return s.c.Sync(ctx, secrets)
+}
+
+// Watch polls the secrets at the paths for changes.
+func (s *scopedClient) Watch(ctx context.Context, paths []string) (<-chan SecretEvent, error) {
+	if err := s.check(paths...); err != nil {
+		return nil, err
+	}
+	return s.c.Watch(ctx, paths)
+}
+
+// SetMockClient does nothing, because the scoped client cannot change the
+// configuration.
+func (s *scopedClient) SetMockClient(aws.HTTPClient) {}
+
+// SetMockCredentialsProvider does nothing, because the scoped client cannot
+// change the configuration.
+func (s *scopedClient) SetMockCredentialsProvider(aws.CredentialsProvider) {}
+
+// SetLogger does nothing, because the scoped client cannot change the
+// configuration.
+func (s *scopedClient) SetLogger(*zap.Logger) {}
+
+// GetConfig returns the effective configuration of the client.
+func (s *scopedClient) GetConfig(ctx context.Context) *Config {
+	return s.c.GetConfig(ctx)
+}
+
+// Simple returns the context-free view of the scoped client.
+func (s *scopedClient) Simple() *SimpleClient {
+	return NewSimpleClient(s)
+}
+
+// Diagnose checks the client the view belongs to.
+func (s *scopedClient) Diagnose(ctx context.Context) *DiagnosticReport {
+	return s.c.Diagnose(ctx)
+}
+
+// Reconfigure returns ErrScopedClient.
+func (s *scopedClient) Reconfigure(context.Context, *ClientConfig) error {
+	return fmt.Errorf("%w: reconfigure", ErrScopedClient)
+}
+
+// InvalidateCache removes the cached versions of the secret in scope.
+func (s *scopedClient) InvalidateCache(path string) {
+	if s.inScope(path) {
+		s.c.InvalidateCache(path)
+	}
+}
+
+// BlockAccess returns ErrScopedClient.
+func (s *scopedClient) BlockAccess(string, time.Duration) error {
+	return fmt.Errorf("%w: block access", ErrScopedClient)
+}
+
+// UnblockAccess returns ErrScopedClient.
+func (s *scopedClient) UnblockAccess(string) error {
+	return fmt.Errorf("%w: unblock access", ErrScopedClient)
+}
+
+// AnomalyStats returns ErrScopedClient.
+func (s *scopedClient) AnomalyStats() (AnomalyStats, error) {
+	return AnomalyStats{}, fmt.Errorf("%w: anomaly stats", ErrScopedClient)
+}
+
+// ReportInvalid refreshes the secret in scope reported invalid.
+func (s *scopedClient) ReportInvalid(ctx context.Context, path string, opts ...InvalidOption) (map[string]interface{}, error) {
+	if err := s.check(path); err != nil {
+		return nil, err
+	}
+	return s.c.ReportInvalid(ctx, path, opts...)
+}
+
+// ListenSQS returns ErrScopedClient, because the events are not limited
+// to the secrets in scope.
+func (s *scopedClient) ListenSQS(context.Context, string) (<-chan SecretEvent, error) {
+	return nil, fmt.Errorf("%w: listen sqs", ErrScopedClient)
+}
+
+// SetupEventWiring returns ErrScopedClient.
+func (s *scopedClient) SetupEventWiring(context.Context, *EventWiringConfig) (*EventWiring, error) {
+	return nil, fmt.Errorf("%w: setup event wiring", ErrScopedClient)
+}
+
+// SNSHandler returns ErrScopedClient, because the events are not limited
+// to the secrets in scope.
+func (s *scopedClient) SNSHandler(context.Context, string) (http.Handler, <-chan SecretEvent, error) {
+	return nil, nil, fmt.Errorf("%w: sns handler", ErrScopedClient)
+}
+
+// Close does nothing. The client the view belongs to is closed separately.
+func (s *scopedClient) Close() error {
+	return nil
+}
+
+var _ Client = (*scopedClient)(nil)
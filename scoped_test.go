@@ -0,0 +1,106 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestScopedClient(t *testing.T) {
+	store := map[string]string{
+		"portals/users/jsmith":  `{"username":"jsmith"}`,
+		"portals/users/mjones":  `{"username":"mjones"}`,
+		"portals/usersadmin":    `{"username":"admin"}`,
+		"portals/tokens/github": `{"token":"secret"}`,
+	}
+	var requests []string
+	c := newWritesTestClient(t, store, &requests)
+	scoped := c.Scoped("portals/users/")
+	ctx := context.TODO()
+
+	testcases := []struct {
+		name      string
+		path      string
+		shouldErr bool
+	}{
+		{name: "test path in scope", path: "portals/users/jsmith"},
+		{name: "test path sharing prefix", path: "portals/usersadmin", shouldErr: true},
+		{name: "test path outside scope", path: "portals/tokens/github", shouldErr: true},
+		{name: "test path escaping scope", path: "portals/users/../tokens/github", shouldErr: true},
+		{name: "test path with empty segment", path: "portals/users//jsmith", shouldErr: true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := scoped.GetSecret(ctx, tc.path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, ErrPathNotAllowed) {
+					t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success")
+			}
+		})
+	}
+
+	if _, err := scoped.CreateSecret(ctx, "portals/tokens/gitlab", map[string]interface{}{"token": "secret"}); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+	}
+	if _, err := scoped.Sync(ctx, map[string]map[string]interface{}{
+		"portals/users/jsmith":  {"username": "jsmith"},
+		"portals/tokens/github": {"token": "changed"},
+	}); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+	}
+	if len(requests) != 0 {
+		t.Fatalf("unexpected mutating requests: %v", requests)
+	}
+	if _, err := scoped.CreateSecret(ctx, "portals/users/admin", map[string]interface{}{"username": "admin"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	if err := scoped.Reconfigure(ctx, &ClientConfig{ID: "bar"}); !errors.Is(err, ErrScopedClient) {
+		t.Fatalf("expected ErrScopedClient, got: %v", err)
+	}
+	if _, err := scoped.ListenSQS(ctx, "https://sqs.us-east-1.amazonaws.com/123456789012/events"); !errors.Is(err, ErrScopedClient) {
+		t.Fatalf("expected ErrScopedClient, got: %v", err)
+	}
+
+	// The nested scope cannot widen the scope.
+	nested := scoped.Scoped("portals")
+	if _, err := nested.GetSecret(ctx, "portals/tokens/github"); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+	}
+	nested = scoped.Scoped("portals/users/jsmith")
+	if _, err := nested.GetSecret(ctx, "portals/users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := nested.GetSecret(ctx, "portals/users/mjones"); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("expected ErrPathNotAllowed, got: %v", err)
+	}
+
+	if err := scoped.Close(); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := c.GetSecret(ctx, "portals/tokens/github"); err != nil {
+		t.Fatalf("expected the client usable after closing the scoped client, got: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+const redactedSecret = "[REDACTED]"
+
+// ErrSecretDestroyed is returned when the destroyed Secret is used.
+var ErrSecretDestroyed = errors.New("secret is destroyed")
+
+// Secret holds the secret value as bytes, which Destroy zeroes. The wiping
+// is best effort: the copies made by AWS SDK while receiving the secret,
+// by JSON decoding, and by the cache of the client are beyond its reach.
+// Use GetSecretBytes with WithNoCache to keep the secret out of the cache.
+//
+// The value is never printed or encoded, its String and MarshalJSON
+// return [REDACTED].
+type Secret struct {
+	mu        sync.Mutex
+	b         []byte
+	destroyed bool
+}
+
+// NewSecret returns the Secret taking the ownership of the bytes.
+func NewSecret(b []byte) *Secret {
+	s := &Secret{b: b}
+	runtime.SetFinalizer(s, (*Secret).Destroy)
+	return s
+}
+
+// Use calls the function with the bytes of the secret, which must not be
+// retained after the function returns. It returns an error when the
+// secret was destroyed.
+func (s *Secret) Use(fn func([]byte) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.destroyed {
+		return ErrSecretDestroyed
+	}
+	return fn(s.b)
+}
+
+// Len returns the length of the secret, zero once destroyed.
+func (s *Secret) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.b)
+}
+
+// Equal reports whether the secret equals the bytes. The comparison takes
+// constant time. A destroyed secret equals nothing.
+func (s *Secret) Equal(b []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.destroyed {
+		return false
+	}
+	return subtle.ConstantTimeCompare(s.b, b) == 1
+}
+
+// Destroy zeroes the bytes of the secret. It is safe to call it more than
+// once.
+func (s *Secret) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.b {
+		s.b[i] = 0
+	}
+	s.b = nil
+	s.destroyed = true
+}
+
+// Destroyed reports whether the secret was destroyed.
+func (s *Secret) Destroyed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.destroyed
+}
+
+// String implements fmt.Stringer interface without revealing the secret.
+func (s *Secret) String() string {
+	return redactedSecret
+}
+
+// GoString implements fmt.GoStringer interface without revealing the
+// secret.
+func (s *Secret) GoString() string {
+	return redactedSecret
+}
+
+// MarshalJSON implements json.Marshaler interface without revealing the
+// secret.
+func (s *Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redactedSecret + `"`), nil
+}
+
+// GetSecretBytes returns the string value of the key of the stored secret
+// as Secret. Unlike GetSecretByKey, it does not add the secret to the
+// cache. The caller destroys the Secret once done with it.
+func (c *client) GetSecretBytes(ctx context.Context, path, key string, opts ...CallOption) (*Secret, error) {
+	o, err := newCallOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	m, err := c.fetchSecret(ctx, &secretRequest{
+		path:      path,
+		stage:     o.stage,
+		region:    o.region,
+		skipCache: o.noCache,
+		noStore:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	v, exists := m[key]
+	if !exists {
+		return nil, fmt.Errorf("key %q not found in %q secret", key, path)
+	}
+	value, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("key %q value in %q secret is not a string", key, path)
+	}
+	return NewSecret([]byte(value)), nil
+}
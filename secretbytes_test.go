@@ -0,0 +1,109 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestSecret(t *testing.T) {
+	b := []byte("foobar")
+	s := NewSecret(b)
+
+	if !s.Equal([]byte("foobar")) || s.Equal([]byte("foo")) {
+		t.Fatalf("Equal() mismatch")
+	}
+	if diff := cmp.Diff("[REDACTED]|[REDACTED]|[REDACTED]", fmt.Sprintf("%v|%s|%#v", s, s, s)); diff != "" {
+		t.Errorf("formatted secret mismatch (-want +got):\n%s", diff)
+	}
+	encoded, err := json.Marshal(map[string]interface{}{"password": s})
+	if err != nil {
+		t.Fatalf("failed encoding secret: %v", err)
+	}
+	if diff := cmp.Diff(`{"password":"[REDACTED]"}`, string(encoded)); diff != "" {
+		t.Errorf("json.Marshal() mismatch (-want +got):\n%s", diff)
+	}
+	var used string
+	if err := s.Use(func(b []byte) error {
+		used = string(b)
+		return nil
+	}); err != nil {
+		t.Fatalf("Use() failed: %v", err)
+	}
+	if diff := cmp.Diff("foobar", used); diff != "" {
+		t.Errorf("Use() mismatch (-want +got):\n%s", diff)
+	}
+
+	s.Destroy()
+	s.Destroy()
+	if diff := cmp.Diff(make([]byte, 6), b); diff != "" {
+		t.Errorf("backing memory is not zeroed (-want +got):\n%s", diff)
+	}
+	if !s.Destroyed() || s.Len() != 0 || s.Equal(nil) {
+		t.Errorf("destroyed secret is usable")
+	}
+	if err := s.Use(func([]byte) error { return nil }); !errors.Is(err, ErrSecretDestroyed) {
+		t.Errorf("Use() after Destroy() expected ErrSecretDestroyed, got: %v", err)
+	}
+}
+
+func TestGetSecretBytes(t *testing.T) {
+	var requests int
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithCacheTTL(time.Minute),
+		WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return secretsmock.SecretStringResponse(map[string]interface{}{"password": "foobar", "uid": 1000}), nil
+		})),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	path := "authcrunch/caddy/users/jsmith"
+
+	for i := 0; i < 2; i++ {
+		s, err := c.GetSecretBytes(context.TODO(), path, "password")
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		if !s.Equal([]byte("foobar")) {
+			t.Errorf("GetSecretBytes() returned unexpected secret")
+		}
+		s.Destroy()
+	}
+	if diff := cmp.Diff(2, requests); diff != "" {
+		t.Errorf("requests mismatch, the secret must not be cached (-want +got):\n%s", diff)
+	}
+
+	if _, err := c.GetSecretBytes(context.TODO(), path, "uid"); err == nil {
+		t.Errorf("expected error for non-string value, got success")
+	}
+	if _, err := c.GetSecretBytes(context.TODO(), path, "foo"); err == nil {
+		t.Errorf("expected error for missing key, got success")
+	}
+}
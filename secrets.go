@@ -16,14 +16,29 @@ package secrets
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"regexp"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	versionStageCurrent  = "AWSCURRENT"
+	versionStagePrevious = "AWSPREVIOUS"
 )
 
 var (
@@ -43,109 +58,542 @@ func (MockCredentialsProvider) Retrieve(context.Context) (aws.Credentials, error
 
 // Client provides interface to query AWS Secrets Manager service.
 type Client interface {
-	GetSecret(context.Context, string) (map[string]interface{}, error)
-	GetSecretByKey(context.Context, string, string) (interface{}, error)
+	GetSecret(context.Context, string, ...CallOption) (map[string]interface{}, error)
+	GetSecretByKey(context.Context, string, string, ...CallOption) (interface{}, error)
+	GetSecretBytes(context.Context, string, string, ...CallOption) (*Secret, error)
+	GetSecretInto(context.Context, string, io.Writer) error
+	GetSecretTemplated(context.Context, string, map[string]string) (map[string]interface{}, error)
+	GetTokenSecrets(context.Context, string) (*TokenSecrets, error)
+	GetAccessToken(context.Context, string) (*AccessToken, error)
+	GetOAuthClientCredentials(context.Context, string) (*OAuthClientCredentials, error)
+	GetSMTPCredentials(context.Context, string) (*SMTPCredentials, error)
+	GetLDAPBindCredentials(context.Context, string) (*LDAPBindCredentials, error)
+	GetDatabaseCredentials(context.Context, string) (*DatabaseCredentials, error)
+	GetUserCredentials(context.Context, string) (*UserCredentials, error)
+	GetCertificateBundle(context.Context, string, string) (*CertificateBundle, error)
+	GetTLSCertificate(context.Context, string) (*tls.Certificate, error)
+	GetSSHSigner(context.Context, string, string) (ssh.Signer, error)
+	GetTOTPSeed(context.Context, string) (*TOTPSeed, error)
+	GetPGPEntities(context.Context, string, string) (openpgp.EntityList, error)
+	GetWebhookSecret(context.Context, string) (*WebhookSecret, error)
+	GetSessionKeys(context.Context, string) (*SessionKeys, error)
+	VerifyUserPassword(context.Context, string, string) (bool, error)
+	GetUserSecret(context.Context, string, string) (map[string]interface{}, error)
+	GetSecretWithPrevious(context.Context, string) (*SecretWithPrevious, error)
+	GetSecretVersion(context.Context, string) (*SecretVersion, error)
+	GetSecretWithMetadata(context.Context, string) (*SecretWithMetadata, error)
+	DiffSecretVersions(context.Context, string, string, string, ...DiffOption) (*VersionDiff, error)
+	GetSecretAcrossAccounts(context.Context, string, []string) (map[string]*AccountSecret, error)
+	ExportEnv(context.Context, []string, io.Writer, string, ...ExportOption) error
+	LoadIntoEnv(context.Context, map[string]string, ...EnvOption) error
+	RenderTemplate(context.Context, string, io.Writer) error
+	ListSecrets(context.Context, string) ([]string, error)
+	DescribeSecret(context.Context, string) (*SecretMetadata, error)
+	ListVersionHistory(context.Context, string) ([]*SecretVersionInfo, error)
+	AnnotateVersion(context.Context, string, string, string) (*Change, error)
+	CreateSecret(context.Context, string, map[string]interface{}) (*Change, error)
+	PutSecret(context.Context, string, map[string]interface{}, ...CallOption) (*Change, error)
+	DeleteSecret(context.Context, string) (*Change, error)
+	RotateSecret(context.Context, string) (*Change, error)
+	Sync(context.Context, map[string]map[string]interface{}) ([]*Change, error)
+	Watch(context.Context, []string) (<-chan SecretEvent, error)
 	SetMockClient(aws.HTTPClient)
 	SetMockCredentialsProvider(aws.CredentialsProvider)
-	GetConfig(context.Context) map[string]interface{}
-}
-
-type clientConfig struct {
-	ID       string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
-	Region   string `json:"region,omitempty" xml:"region,omitempty" yaml:"region,omitempty"`
-	Provider string `json:"provider,omitempty" xml:"provider,omitempty" yaml:"provider,omitempty"`
+	SetLogger(*zap.Logger)
+	GetConfig(context.Context) *Config
+	Simple() *SimpleClient
+	Diagnose(context.Context) *DiagnosticReport
+	Reconfigure(context.Context, *ClientConfig) error
+	InvalidateCache(string)
+	BlockAccess(string, time.Duration) error
+	UnblockAccess(string) error
+	AnomalyStats() (AnomalyStats, error)
+	ReportInvalid(context.Context, string, ...InvalidOption) (map[string]interface{}, error)
+	ListenSQS(context.Context, string) (<-chan SecretEvent, error)
+	SetupEventWiring(context.Context, *EventWiringConfig) (*EventWiring, error)
+	SNSHandler(context.Context, string) (http.Handler, <-chan SecretEvent, error)
+	Scoped(string) Client
+	Close() error
 }
 
 type client struct {
-	config        *clientConfig
+	mu            sync.RWMutex
+	config        *ClientConfig
 	serviceConfig aws.Config
-	serviceClient *secretsmanager.Client
+	// serviceClients are keyed by region and endpoint, the empty key
+	// holds the client for the configured region and endpoint.
+	serviceClients map[serviceClientKey]*secretsmanager.Client
+	// dynamodbClient serves the shared cache. It is rebuilt whenever the
+	// service configuration changes.
+	dynamodbClient *dynamodb.Client
+	httpClient     aws.HTTPClient
+	credentials    aws.CredentialsProvider
+	logger         *zap.Logger
+	cache          *secretCache
+	// regionSource tells where the region of the service configuration
+	// came from.
+	regionSource string
+	// factory, when set, provides the shared service configuration.
+	factory *Factory
+	// api, when set, serves all requests instead of the service clients.
+	api SecretsManagerAPI
+	// backend, when set, serves the requests for the providers other than
+	// AWS Secrets Manager, unless api is set.
+	backend SecretsManagerAPI
+	// roleCredentials are the cached credentials of the roles assumed
+	// across the accounts, keyed by role ARN.
+	roleCredentials map[string]aws.CredentialsProvider
+	// faults, when set, are injected into the requests.
+	faults *FaultConfig
+	clock  Clock
+	// anomalies, when set, tracks the reads of the secrets.
+	anomalies *anomalyDetector
+	// canary, when set, validates the fetched versions of the secrets.
+	canary *canaryValidator
+	// auditSink, when set, receives the records of the access to the
+	// secrets.
+	auditSink AuditSink
+	// rand, when set, is the source of the retry jitter and the faults.
+	rand *lockedRand
+	// background tracks the goroutines stopped by Close, which closes
+	// the done channel.
+	background sync.WaitGroup
+	done       chan struct{}
+	closed     bool
 }
 
-// NewClient returns an instance of Client.
-func NewClient(ctx context.Context, id string, region string) (Client, error) {
+// NewClient returns an instance of Client configured with the options.
+func NewClient(ctx context.Context, opts ...Option) (Client, error) {
 	c := &client{
-		config: &clientConfig{
-			ID:       id,
-			Region:   region,
-			Provider: "aws_secrets_manager",
-		},
+		config: &ClientConfig{},
+		logger: zap.NewNop(),
+		clock:  systemClock{},
+		done:   make(chan struct{}),
 	}
-
-	if region != "" {
-		if awsRegionRgx.MatchString(region) == false {
-			return nil, fmt.Errorf("malformed %q region", region)
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
 		}
 	}
+	c.config.setDefaults()
+	if err := c.config.Validate(); err != nil {
+		return nil, err
+	}
+	for _, warning := range c.config.Deprecations {
+		c.logger.Warn("deprecated config", zap.String("client_id", c.config.ID), zap.String("warning", warning))
+	}
+	c.cache = c.config.newCache(c.clock)
 
-	serviceConfig, err := config.LoadDefaultConfig(
-		ctx,
-		config.WithRegion(c.config.Region),
-		// config.WithClientLogMode(aws.LogRetries|aws.LogRequestWithBody|aws.LogResponseWithBody|aws.LogRequestEventMessage|aws.LogResponseEventMessage|aws.LogSigning),
-	)
+	backend, err := newBackendAPI(ctx, c.config)
+	if err != nil {
+		return nil, err
+	}
+	c.backend = backend
+	serviceConfig, regionSource, err := c.loadServiceConfig(ctx, c.config)
 	if err != nil {
 		return nil, err
 	}
 	c.serviceConfig = serviceConfig
+	c.dynamodbClient = dynamodb.NewFromConfig(serviceConfig)
+	c.regionSource = regionSource
 	return c, nil
 }
 
-// GetSecret returns the key-value map of the stored secret.
-func (c *client) GetSecret(ctx context.Context, path string) (map[string]interface{}, error) {
-	if c.serviceClient == nil {
-		c.serviceClient = secretsmanager.NewFromConfig(c.serviceConfig)
+// NewClientWithRegion returns an instance of Client with the provided
+// ID and region.
+//
+// Deprecated: Use NewClient with WithID and WithRegion options.
+func NewClientWithRegion(ctx context.Context, id string, region string) (Client, error) {
+	return NewClient(ctx, WithID(id), WithRegion(region))
+}
+
+// NewClientWithConfig returns an instance of Client configured with
+// the provided configuration.
+func NewClientWithConfig(ctx context.Context, cfg *ClientConfig) (Client, error) {
+	return NewClient(ctx, WithConfig(cfg))
+}
+
+// loadServiceConfig returns AWS service configuration for the provided
+// client configuration and the source of its region. The HTTP client and
+// the credentials provider set with the options or the mock setters take
+// precedence over the defaults.
+func (c *client) loadServiceConfig(ctx context.Context, cfg *ClientConfig) (aws.Config, string, error) {
+	var serviceConfig aws.Config
+	var region, regionSource string
+	var err error
+	if cfg.Provider != defaultProvider {
+		serviceConfig.Region = cfg.Region
+		return serviceConfig, RegionSourceConfig, nil
 	}
-	input := &secretsmanager.GetSecretValueInput{
-		SecretId:     aws.String(path),
-		VersionStage: aws.String("AWSCURRENT"),
+	if c.factory != nil {
+		serviceConfig, region, regionSource = c.factory.getServiceConfig(cfg)
+	} else {
+		opts := []func(*config.LoadOptions) error{
+			config.WithRegion(cfg.Region),
+			// config.WithClientLogMode(aws.LogRetries|aws.LogRequestWithBody|aws.LogResponseWithBody|aws.LogRequestEventMessage|aws.LogResponseEventMessage|aws.LogSigning),
+		}
+		if cfg.MaxRetries > 0 {
+			opts = append(opts, config.WithRetryMaxAttempts(cfg.MaxRetries+1))
+		}
+		serviceConfig, err = config.LoadDefaultConfig(ctx, opts...)
+		if err != nil {
+			return serviceConfig, "", err
+		}
+		region, regionSource, err = resolveRegion(ctx, cfg, serviceConfig)
+		if err != nil {
+			if len(cfg.FallbackRegions) == 0 {
+				return serviceConfig, "", err
+			}
+			region, regionSource = cfg.FallbackRegions[0], RegionSourceFallback
+		}
+	}
+	serviceConfig.Region = region
+	c.mu.RLock()
+	if c.httpClient != nil {
+		serviceConfig.HTTPClient = c.httpClient
+	}
+	httpClient, hardened := hardenHTTPClient(cfg.TLS, serviceConfig.HTTPClient)
+	serviceConfig.HTTPClient = c.transport(httpClient)
+	if c.rand != nil {
+		serviceConfig.Retryer = newRetryer(c.rand)
 	}
-	result, err := c.serviceClient.GetSecretValue(ctx, input)
+	credentials := c.credentials
+	c.mu.RUnlock()
+	if !hardened {
+		return serviceConfig, "", fmt.Errorf("tls settings cannot be applied to %T http client", httpClient)
+	}
+	switch {
+	case credentials != nil:
+		serviceConfig.Credentials = credentials
+	case cfg.RoleARN != "" && c.factory != nil:
+		serviceConfig.Credentials = c.factory.getRoleCredentials(cfg.RoleARN)
+	case cfg.RoleARN != "":
+		serviceConfig.Credentials = aws.NewCredentialsCache(
+			stscreds.NewAssumeRoleProvider(sts.NewFromConfig(serviceConfig), cfg.RoleARN),
+		)
+	}
+	if len(cfg.FallbackRegions) > 0 && c.api == nil {
+		region, regionSource, err = c.selectHealthyRegion(ctx, cfg, serviceConfig, region, regionSource)
+		if err != nil {
+			return serviceConfig, "", err
+		}
+		serviceConfig.Region = region
+	}
+	return serviceConfig, regionSource, nil
+}
+
+// getConfig returns current client configuration.
+func (c *client) getConfig() *ClientConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// getCache returns the secret cache, or nil when the caching is disabled.
+func (c *client) getCache() *secretCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cache
+}
+
+// InvalidateCache removes the cached versions of the secret.
+func (c *client) InvalidateCache(path string) {
+	c.getCache().invalidate(path)
+}
+
+// serviceClientKey identifies AWS Secrets Manager service client.
+type serviceClientKey struct {
+	region   string
+	endpoint string
+	// roleARN, when set, is the role assumed in addition to the configured
+	// credentials, see GetSecretAcrossAccounts.
+	roleARN string
+}
+
+// getServiceClient returns AWS Secrets Manager service client for the
+// region and endpoint. When the region or endpoint is empty, the client
+// uses the configured one. The client is created on first use. The API set
+// with WithSecretsManagerAPI takes precedence over the service clients.
+func (c *client) getServiceClient(region, endpoint string) SecretsManagerAPI {
+	return c.getServiceClientByKey(serviceClientKey{region: region, endpoint: endpoint})
+}
+
+// getServiceClientByKey returns the service client for the region, the
+// endpoint, and the assumed role of the key.
+func (c *client) getServiceClientByKey(key serviceClientKey) SecretsManagerAPI {
+	c.mu.RLock()
+	api, serviceClient := c.api, c.serviceClients[key]
+	if api == nil {
+		api = c.backend
+	}
+	c.mu.RUnlock()
+	if api != nil {
+		return api
+	}
+
+	if serviceClient != nil {
+		return serviceClient
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.serviceClients == nil {
+		c.serviceClients = make(map[serviceClientKey]*secretsmanager.Client)
+	}
+	if c.serviceClients[key] == nil {
+		region, endpoint := key.region, key.endpoint
+		if endpoint == "" {
+			endpoint = c.config.Endpoint
+		}
+		serviceConfig := c.serviceConfig
+		if key.roleARN != "" {
+			serviceConfig = serviceConfig.Copy()
+			serviceConfig.Credentials = c.getRoleCredentials(key.roleARN)
+		}
+		c.serviceClients[key] = secretsmanager.NewFromConfig(serviceConfig, func(o *secretsmanager.Options) {
+			if region != "" {
+				o.Region = region
+			}
+			if endpoint != "" {
+				o.EndpointResolver = secretsmanager.EndpointResolverFromURL(endpoint)
+			}
+		})
+	}
+	return c.serviceClients[key]
+}
+
+// GetSecret returns the key-value map of the stored secret.
+func (c *client) GetSecret(ctx context.Context, path string, opts ...CallOption) (map[string]interface{}, error) {
+	o, err := newCallOptions(opts)
 	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	m, err := c.fetchSecret(ctx, &secretRequest{
+		path:      path,
+		stage:     o.stage,
+		region:    o.region,
+		skipCache: o.noCache,
+	})
+	if err != nil || !o.flatten {
+		return m, err
+	}
+	return flattenSecret(m), nil
+}
+
+// secretRequest holds the parameters of secret retrieval.
+type secretRequest struct {
+	path   string
+	stage  string
+	region string
+	// versionID, when set, selects the version instead of the stage.
+	versionID string
+	// roleARN, when set, is the role assumed to fetch the secret in
+	// another account.
+	roleARN string
+	// skipCache forces the retrieval from the service. The retrieved
+	// secret still refreshes the cache.
+	skipCache bool
+	// noStore keeps the retrieved secret out of the cache.
+	noStore bool
+	// refresh marks the fetch by the client itself, e.g. the Watch poll,
+	// which the anomaly detection does not count.
+	refresh bool
+}
+
+// getSecretValue returns the key-value map of the stored secret
+// labeled with the provided version stage.
+func (c *client) getSecretValue(ctx context.Context, path, stage string) (map[string]interface{}, error) {
+	return c.fetchSecret(ctx, &secretRequest{path: path, stage: stage})
+}
+
+// fetchSecret returns the key-value map of the stored secret and audits
+// the access.
+func (c *client) fetchSecret(ctx context.Context, req *secretRequest) (map[string]interface{}, error) {
+	m, err := c.loadSecret(ctx, req)
+	c.audit("get", req.path, err)
+	return m, err
+}
 
-	if result.SecretString == nil {
-		return nil, errors.New("SecretString not found in response")
+func (c *client) loadSecret(ctx context.Context, req *secretRequest) (map[string]interface{}, error) {
+	path := req.path
+	cfg := c.getConfig()
+	if err := cfg.PathPolicy.check(path); err != nil {
+		return nil, err
+	}
+	if err := c.checkAccess(path); err != nil {
+		return nil, err
+	}
+	key := cacheKey{region: req.region, roleARN: req.roleARN, path: path, stage: req.stage, versionID: req.versionID}
+	if pin := cfg.pin(path); pin != nil && req.versionID == "" && req.stage == versionStageCurrent {
+		key.stage, key.versionID = "", pin.VersionID
+	}
+	cache := c.getCache()
+	if !req.skipCache {
+		if m, found := cache.get(key); found {
+			return m, nil
+		}
+	}
+	if !req.refresh {
+		if err := c.recordAccess(path); err != nil {
+			return nil, err
+		}
+	}
+	name, err := cfg.resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(name)}
+	if key.versionID != "" {
+		input.VersionId = aws.String(key.versionID)
+	} else {
+		input.VersionStage = aws.String(req.stage)
+	}
+	region, endpoint := req.region, ""
+	if region == "" {
+		if route := cfg.route(path); route != nil {
+			region, endpoint = route.Region, route.Endpoint
+		}
+	}
+	api := c.getServiceClientByKey(serviceClientKey{region: region, endpoint: endpoint, roleARN: req.roleARN})
+	var secretString string
+	var shared bool
+	var description *secretsmanager.DescribeSecretOutput
+	description, err = c.checkDescription(ctx, cfg, api, path, name)
+	if err == nil && cfg.DynamoDBCache != nil && !req.skipCache {
+		// The secrets from the shared cache pass the metadata checks above
+		// and the value checks below, same as the ones from the service.
+		secretString, shared = c.getSharedSecret(ctx, cfg.DynamoDBCache, key, name)
+	}
+	if err == nil && !shared {
+		var result *secretsmanager.GetSecretValueOutput
+		result, err = api.GetSecretValue(ctx, input)
+		if err == nil {
+			if result.SecretString == nil {
+				return nil, errors.New("SecretString not found in response")
+			}
+			secretString = *result.SecretString
+		}
+	}
+	if err != nil {
+		if cfg.SSMFallback == nil || !isNotFound(err) || key.versionID != "" || (req.stage != "" && req.stage != versionStageCurrent) {
+			return nil, err
+		}
+		if secretString, err = c.getParameter(ctx, cfg.SSMFallback, region, name, err); err != nil {
+			return nil, err
+		}
 	}
 
-	var secretString string = *result.SecretString
-	var m map[string]interface{}
-	if err := json.Unmarshal([]byte(secretString), &m); err != nil {
+	m, err := cfg.Limits.decode(secretString)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.S3Overflow != nil && !shared {
+		objectString, err := c.followS3Pointer(ctx, cfg.S3Overflow, region, path, m)
+		if err != nil {
+			return nil, err
+		}
+		if objectString != "" {
+			if m, err = cfg.Limits.decode(objectString); err != nil {
+				return nil, err
+			}
+			secretString = objectString
+		}
+	}
+	if err := verifyChecksum(cfg.ChecksumKey, path, m); err != nil {
 		return nil, err
 	}
 
+	normalizeKeys(m, cfg.KeyNormalization)
+	applyFieldAliases(m, cfg.FieldAliases)
+	for _, schema := range cfg.Schemas {
+		if err := schema.check(path, m); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.checkPasswordPolicy(cfg.PasswordPolicy, path, m); err != nil {
+		return nil, err
+	}
+	if m, err = c.checkCanary(ctx, key, m); err != nil {
+		return nil, err
+	}
+	switch {
+	case req.noStore:
+		// The secret is kept out of the cache.
+	case key.versionID == "" && cfg.RotationCache != nil && rotationPending(description):
+		ttl := cfg.RotationCache.pendingTTL()
+		c.getLogger().Debug("secret rotation in progress", zap.String("path", path), zap.Duration("cache_ttl", ttl))
+		cache.setWithTTL(key, m, ttl)
+	default:
+		cache.set(key, m)
+		if cfg.DynamoDBCache != nil && !shared {
+			c.putSharedSecret(ctx, cfg.DynamoDBCache, key, name, secretString)
+		}
+	}
 	return m, nil
 }
 
-// GetSecret returns the key-value map of the stored secret.
-func (c *client) GetSecretByKey(ctx context.Context, path string, key string) (interface{}, error) {
-	secret, err := c.GetSecret(ctx, path)
+// GetSecretByKey returns the string value of the key of the stored secret.
+// It returns *KeyTypeError when the value is not a string, e.g. a number
+// or a list.
+func (c *client) GetSecretByKey(ctx context.Context, path string, key string, opts ...CallOption) (interface{}, error) {
+	secret, err := c.GetSecret(ctx, path, opts...)
 	if err != nil {
 		return "", err
 	}
 	value, exists := secret[key]
+	if !exists {
+		value, exists = secret[normalizeKey(key, c.getConfig().KeyNormalization)]
+	}
 	if !exists {
 		return "", fmt.Errorf("key %q not found in %q secret", key, path)
 	}
-	return value.(string), nil
+	s, ok := value.(string)
+	if !ok {
+		return "", &KeyTypeError{Path: path, Key: key, Type: jsonTypeName(value)}
+	}
+	return s, nil
 }
 
 // SetMockClient configures mock HTTP client.
 func (c *client) SetMockClient(mockClient aws.HTTPClient) {
-	c.serviceConfig.HTTPClient = mockClient
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.httpClient = mockClient
+	httpClient, hardened := hardenHTTPClient(c.config.TLS, mockClient)
+	if !hardened {
+		c.logger.Warn("tls settings not applied to mock http client",
+			zap.String("client_id", c.config.ID),
+			zap.String("http_client", fmt.Sprintf("%T", mockClient)),
+		)
+	}
+	c.serviceConfig.HTTPClient = c.transport(httpClient)
+	c.dynamodbClient = dynamodb.NewFromConfig(c.serviceConfig)
+	c.serviceClients = nil
+	c.roleCredentials = nil
 }
 
 // SetMockCredentialsProvider configures mock AWS credentials provider.
 func (c *client) SetMockCredentialsProvider(mockProvider aws.CredentialsProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credentials = mockProvider
 	c.serviceConfig.Credentials = mockProvider
+	c.dynamodbClient = dynamodb.NewFromConfig(c.serviceConfig)
+	c.serviceClients = nil
+	c.roleCredentials = nil
 }
 
-// GetConfig returns client configuration.
-func (c *client) GetConfig(_ context.Context) map[string]interface{} {
-	cfg := map[string]interface{}{
-		"id":       c.config.ID,
-		"region":   c.config.Region,
-		"provider": c.config.Provider,
-	}
-	return cfg
+// SetLogger configures logger.
+func (c *client) SetLogger(logger *zap.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger = logger
+}
+
+// getLogger returns current logger.
+func (c *client) getLogger() *zap.Logger {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logger
 }
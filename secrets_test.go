@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
 	"strings"
 	"testing"
 
@@ -28,8 +29,17 @@ import (
 	smithyhttp "github.com/aws/smithy-go/transport/http"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
 )
 
+func TestMain(m *testing.M) {
+	// The tests must not depend on EC2 instance metadata service.
+	imdsRegion = func(context.Context) (string, error) {
+		return "", errors.New("instance metadata service is disabled")
+	}
+	os.Exit(m.Run())
+}
+
 // packMapToJSON converts a map to a JSON string.
 func packMapToJSON(t *testing.T, m map[string]interface{}) string {
 	b, err := json.Marshal(m)
@@ -39,6 +49,14 @@ func packMapToJSON(t *testing.T, m map[string]interface{}) string {
 	return string(b)
 }
 
+// mockFailure reports the unexpected request received by the mock HTTP
+// client and returns the error failing the request.
+func mockFailure(t *testing.T, format string, args ...interface{}) (*http.Response, error) {
+	err := fmt.Errorf(format, args...)
+	t.Error(err)
+	return nil, err
+}
+
 func TestNewClient(t *testing.T) {
 	testcases := []struct {
 		name      string
@@ -65,7 +83,7 @@ func TestNewClient(t *testing.T) {
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			c, err := NewClient(context.TODO(), "foo", tc.region)
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion(tc.region))
 			if err != nil {
 				if !tc.shouldErr {
 					t.Fatalf("expected success, got: %v", err)
@@ -80,7 +98,7 @@ func TestNewClient(t *testing.T) {
 				t.Fatalf("unexpected success, want: %v", tc.err)
 			}
 
-			got := c.GetConfig(context.TODO())
+			got := c.GetConfig(context.TODO()).Map()
 			if diff := cmp.Diff(tc.want, got); diff != "" {
 				t.Logf("JSON: %v", packMapToJSON(t, got))
 				t.Errorf("NewClient() mismatch (-want +got):\n%s", diff)
@@ -125,14 +143,7 @@ func TestGetSecret(t *testing.T) {
 				// 	t.Logf("failed dumping HTTP request: %v", err)
 				// }
 				// t.Logf("%q", dump)
-				response := packMapToJSON(t, map[string]interface{}{
-					"SecretString": packMapToJSON(t, jsmith),
-				})
-				return &http.Response{
-					StatusCode: 200,
-					Header:     http.Header{},
-					Body:       ioutil.NopCloser(strings.NewReader(response)),
-				}, nil
+				return secretsmock.SecretStringResponse(jsmith), nil
 			}),
 		},
 		{
@@ -141,14 +152,7 @@ func TestGetSecret(t *testing.T) {
 			region: "us-east-1",
 			want:   accessToken,
 			mockClient: smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
-				response := packMapToJSON(t, map[string]interface{}{
-					"SecretString": packMapToJSON(t, accessToken),
-				})
-				return &http.Response{
-					StatusCode: 200,
-					Header:     http.Header{},
-					Body:       ioutil.NopCloser(strings.NewReader(response)),
-				}, nil
+				return secretsmock.SecretStringResponse(accessToken), nil
 			}),
 		},
 		{
@@ -186,7 +190,7 @@ func TestGetSecret(t *testing.T) {
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			c, err := NewClient(context.TODO(), "foo", tc.region)
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion(tc.region))
 			if err != nil {
 				t.Fatalf("unxpected error during client initialization: %v", err)
 			}
@@ -244,14 +248,7 @@ func TestGetSecretByKey(t *testing.T) {
 			key:    "name",
 			want:   "John Smith",
 			mockClient: smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
-				response := packMapToJSON(t, map[string]interface{}{
-					"SecretString": packMapToJSON(t, jsmith),
-				})
-				return &http.Response{
-					StatusCode: 200,
-					Header:     http.Header{},
-					Body:       ioutil.NopCloser(strings.NewReader(response)),
-				}, nil
+				return secretsmock.SecretStringResponse(jsmith), nil
 			}),
 		},
 		{
@@ -260,14 +257,7 @@ func TestGetSecretByKey(t *testing.T) {
 			region: "us-east-1",
 			key:    "foo",
 			mockClient: smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
-				response := packMapToJSON(t, map[string]interface{}{
-					"SecretString": packMapToJSON(t, jsmith),
-				})
-				return &http.Response{
-					StatusCode: 200,
-					Header:     http.Header{},
-					Body:       ioutil.NopCloser(strings.NewReader(response)),
-				}, nil
+				return secretsmock.SecretStringResponse(jsmith), nil
 			}),
 			shouldErr: true,
 			err:       fmt.Errorf("key %q not found in %q secret", "foo", "authcrunch/caddy/users/jsmith"),
@@ -278,18 +268,7 @@ func TestGetSecretByKey(t *testing.T) {
 			region: "us-east-1",
 			key:    "bar",
 			mockClient: smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
-				response := packMapToJSON(t, map[string]interface{}{
-					"__type":  "ResourceNotFoundException",
-					"Message": "Secrets Manager can't find the specified secret.",
-				})
-
-				return &http.Response{
-					StatusCode: 400,
-					Header: http.Header{
-						"X-Amzn-Requestid": []string{"524b9962-6854-4b5c-aa53-81759ef610dd"},
-					},
-					Body: ioutil.NopCloser(strings.NewReader(response)),
-				}, nil
+				return secretsmock.NotFoundResponse("524b9962-6854-4b5c-aa53-81759ef610dd"), nil
 			}),
 			shouldErr: true,
 			err: fmt.Errorf("operation error Secrets Manager: GetSecretValue, https response error StatusCode: %d, RequestID: %s, %s: %s",
@@ -299,7 +278,7 @@ func TestGetSecretByKey(t *testing.T) {
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			c, err := NewClient(context.TODO(), "foo", tc.region)
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion(tc.region))
 			if err != nil {
 				t.Fatalf("unxpected error during client initialization: %v", err)
 			}
@@ -0,0 +1,78 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretsmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+// The response builders return the responses of AWS Secrets Manager for the
+// mock HTTP clients, e.g.
+//
+//	smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+//		return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+//	})
+//
+// They panic when the values cannot be encoded as JSON.
+
+// JSONResponse returns the successful response with the value encoded as
+// JSON body.
+func JSONResponse(v interface{}) *http.Response {
+	return newResponse(http.StatusOK, http.Header{}, v)
+}
+
+// SecretStringResponse returns the response of GetSecretValue operation
+// with the key-value map as the secret string.
+func SecretStringResponse(m map[string]interface{}) *http.Response {
+	b, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	return JSONResponse(map[string]interface{}{"SecretString": string(b)})
+}
+
+// ErrorResponse returns the error response with the request ID.
+func ErrorResponse(requestID string, statusCode int, code, message string) *http.Response {
+	header := http.Header{}
+	if requestID != "" {
+		header.Set("X-Amzn-Requestid", requestID)
+	}
+	return newResponse(statusCode, header, map[string]string{
+		"__type":  code,
+		"Message": message,
+	})
+}
+
+// NotFoundResponse returns ResourceNotFoundException response with the
+// request ID.
+func NotFoundResponse(requestID string) *http.Response {
+	return ErrorResponse(requestID, http.StatusBadRequest, "ResourceNotFoundException", "Secrets Manager can't find the specified secret.")
+}
+
+func newResponse(statusCode int, header http.Header, v interface{}) *http.Response {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	header.Set("Content-Type", "application/x-amz-json-1.1")
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewReader(b)),
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretsmock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResponses(t *testing.T) {
+	testcases := []struct {
+		name      string
+		resp      func() *http.Response
+		want      string
+		shouldErr bool
+		errCode   string
+	}{
+		{
+			name: "test secret string response",
+			resp: func() *http.Response {
+				return SecretStringResponse(map[string]interface{}{"username": "jsmith"})
+			},
+			want: `{"username":"jsmith"}`,
+		},
+		{
+			name: "test json response",
+			resp: func() *http.Response {
+				return JSONResponse(map[string]interface{}{"SecretString": "foobar"})
+			},
+			want: "foobar",
+		},
+		{
+			name: "test not found response",
+			resp: func() *http.Response {
+				return NotFoundResponse("524b9962-6854-4b5c-aa53-81759ef610dd")
+			},
+			shouldErr: true,
+			errCode:   "ResourceNotFoundException",
+		},
+		{
+			name: "test error response",
+			resp: func() *http.Response {
+				return ErrorResponse("", http.StatusBadRequest, "DecryptionFailure", "access to KMS is not allowed")
+			},
+			shouldErr: true,
+			errCode:   "DecryptionFailure",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := secretsmanager.New(secretsmanager.Options{
+				Region:      "us-east-1",
+				Credentials: credentials.NewStaticCredentialsProvider("test", "test", ""),
+				HTTPClient: smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+					return tc.resp(), nil
+				}),
+				Retryer: aws.NopRetryer{},
+			})
+			output, err := svc.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{SecretId: aws.String("foo")})
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				var apiErr smithy.APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("expected API error, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.errCode, apiErr.ErrorCode()); diff != "" {
+					t.Fatalf("unexpected error code (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %s", tc.errCode)
+			}
+			if diff := cmp.Diff(tc.want, aws.ToString(output.SecretString)); diff != "" {
+				t.Errorf("GetSecretValue() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
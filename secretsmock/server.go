@@ -0,0 +1,388 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secretsmock provides HTTP server emulating AWS Secrets Manager
+// API for the tests.
+//
+// The server speaks the JSON protocol of GetSecretValue, ListSecrets, and
+// BatchGetSecretValue operations, paginates the listings, and responds
+// with the error shapes of AWS Secrets Manager, including throttling. Point
+// the client at the server with its endpoint, e.g.
+//
+//	srv := secretsmock.NewServer(t)
+//	srv.SetSecret("authcrunch/users/jsmith", `{"username":"jsmith"}`)
+//	c, err := secrets.NewClient(ctx, secrets.WithEndpoint(srv.URL), ...)
+package secretsmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	defaultPageSize      = 100
+	versionStageCurrent  = "AWSCURRENT"
+	versionStagePrevious = "AWSPREVIOUS"
+	targetPrefix         = "secretsmanager."
+)
+
+// Error is the error response of AWS Secrets Manager.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// NewNotFoundError returns the error AWS Secrets Manager responds with when
+// the secret does not exist.
+func NewNotFoundError(name string) *Error {
+	return &Error{
+		StatusCode: http.StatusBadRequest,
+		Code:       "ResourceNotFoundException",
+		Message:    fmt.Sprintf("Secrets Manager can't find the specified secret %s.", name),
+	}
+}
+
+// NewThrottlingError returns the error AWS Secrets Manager responds with
+// when the request rate is exceeded.
+func NewThrottlingError() *Error {
+	return &Error{
+		StatusCode: http.StatusBadRequest,
+		Code:       "ThrottlingException",
+		Message:    "Rate exceeded",
+	}
+}
+
+// Request is the request received by the server.
+type Request struct {
+	// Operation is the name of the operation, e.g. GetSecretValue.
+	Operation string
+	// SecretID is the secret requested by GetSecretValue operation.
+	SecretID string
+}
+
+type secret struct {
+	created  time.Time
+	versions map[string]string
+}
+
+// Server is the mock AWS Secrets Manager server.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	secrets   map[string]*secret
+	pageSize  int
+	throttled int
+	errors    map[string]*Error
+	requests  []Request
+}
+
+// NewServer starts the server. It is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		secrets:  make(map[string]*secret),
+		pageSize: defaultPageSize,
+		errors:   make(map[string]*Error),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetSecret stores the current version of the secret.
+func (s *Server) SetSecret(name, value string) {
+	s.SetSecretVersion(name, versionStageCurrent, value)
+}
+
+// SetSecretVersion stores the version of the secret under the version
+// stage. Storing a new current version makes the replaced one previous.
+func (s *Server) SetSecretVersion(name, stage, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sec, exists := s.secrets[name]
+	if !exists {
+		sec = &secret{created: time.Now().UTC(), versions: make(map[string]string)}
+		s.secrets[name] = sec
+	}
+	if current, exists := sec.versions[versionStageCurrent]; exists && stage == versionStageCurrent {
+		sec.versions[versionStagePrevious] = current
+	}
+	sec.versions[stage] = value
+}
+
+// SetPageSize sets the maximum number of the items per page of the
+// listings, unless the request asks for fewer.
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageSize = n
+}
+
+// Throttle makes the server respond to the next n requests with
+// ThrottlingException.
+func (s *Server) Throttle(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.throttled = n
+}
+
+// InjectError makes the server respond with the error to the operation on
+// the secret. An empty name matches the requests of the operation
+// regardless of the secret.
+func (s *Server) InjectError(operation, name string, err *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[operation+"/"+name] = err
+}
+
+// ClearErrors removes the injected errors.
+func (s *Server) ClearErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = make(map[string]*Error)
+	s.throttled = 0
+}
+
+// Requests returns the requests received by the server.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+type filter struct {
+	Key    string
+	Values []string
+}
+
+type request struct {
+	SecretId     string
+	SecretIdList []string
+	VersionStage string
+	Filters      []filter
+	MaxResults   int
+	NextToken    string
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), targetPrefix)
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &Error{StatusCode: http.StatusBadRequest, Code: "SerializationException", Message: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Operation: operation, SecretID: req.SecretId})
+
+	if s.throttled > 0 {
+		s.throttled--
+		writeError(w, NewThrottlingError())
+		return
+	}
+	for _, key := range []string{operation + "/" + req.SecretId, operation + "/"} {
+		if err, exists := s.errors[key]; exists {
+			writeError(w, err)
+			return
+		}
+	}
+
+	var resp interface{}
+	var err *Error
+	switch operation {
+	case "GetSecretValue":
+		resp, err = s.getSecretValue(&req)
+	case "ListSecrets":
+		resp, err = s.listSecrets(&req)
+	case "BatchGetSecretValue":
+		resp, err = s.batchGetSecretValue(&req)
+	default:
+		err = &Error{
+			StatusCode: http.StatusBadRequest,
+			Code:       "UnknownOperationException",
+			Message:    fmt.Sprintf("operation %q is not supported", operation),
+		}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) getSecretValue(req *request) (interface{}, *Error) {
+	stage := req.VersionStage
+	if stage == "" {
+		stage = versionStageCurrent
+	}
+	sec, exists := s.secrets[req.SecretId]
+	if !exists {
+		return nil, NewNotFoundError(req.SecretId)
+	}
+	if _, exists := sec.versions[stage]; !exists {
+		return nil, NewNotFoundError(req.SecretId)
+	}
+	return secretValue(req.SecretId, sec, stage), nil
+}
+
+func (s *Server) listSecrets(req *request) (interface{}, *Error) {
+	names, next, err := s.page(s.filter(req.Filters), req)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, map[string]interface{}{
+			"ARN":         arn(name),
+			"Name":        name,
+			"CreatedDate": s.secrets[name].created.Unix(),
+		})
+	}
+	resp := map[string]interface{}{"SecretList": entries}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	return resp, nil
+}
+
+func (s *Server) batchGetSecretValue(req *request) (interface{}, *Error) {
+	if len(req.SecretIdList) > 0 && len(req.Filters) > 0 {
+		return nil, &Error{
+			StatusCode: http.StatusBadRequest,
+			Code:       "InvalidParameterException",
+			Message:    "Either SecretIdList or Filters must be provided, but not both.",
+		}
+	}
+	names := req.SecretIdList
+	if len(names) == 0 {
+		names = s.filter(req.Filters)
+	}
+	names, next, err := s.page(names, req)
+	if err != nil {
+		return nil, err
+	}
+	values := []interface{}{}
+	errs := []interface{}{}
+	for _, name := range names {
+		sec, exists := s.secrets[name]
+		if !exists {
+			notFound := NewNotFoundError(name)
+			errs = append(errs, map[string]interface{}{
+				"SecretId":     name,
+				"ErrorCode":    notFound.Code,
+				"ErrorMessage": notFound.Message,
+			})
+			continue
+		}
+		values = append(values, secretValue(name, sec, versionStageCurrent))
+	}
+	resp := map[string]interface{}{"SecretValues": values, "Errors": errs}
+	if next != "" {
+		resp["NextToken"] = next
+	}
+	return resp, nil
+}
+
+// filter returns the sorted names of the secrets matching the name
+// filters.
+func (s *Server) filter(filters []filter) []string {
+	var prefixes []string
+	for _, f := range filters {
+		if f.Key == "name" {
+			prefixes = append(prefixes, f.Values...)
+		}
+	}
+	names := []string{}
+	for name := range s.secrets {
+		if len(prefixes) == 0 {
+			names = append(names, name)
+			continue
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// page returns the page of the names requested with the token, and the
+// token of the next page.
+func (s *Server) page(names []string, req *request) ([]string, string, *Error) {
+	start := 0
+	if req.NextToken != "" {
+		n, err := strconv.Atoi(req.NextToken)
+		if err != nil || n < 0 || n > len(names) {
+			return nil, "", &Error{
+				StatusCode: http.StatusBadRequest,
+				Code:       "InvalidNextTokenException",
+				Message:    "The NextToken value is invalid.",
+			}
+		}
+		start = n
+	}
+	size := s.pageSize
+	if req.MaxResults > 0 && req.MaxResults < size {
+		size = req.MaxResults
+	}
+	end := start + size
+	if end >= len(names) {
+		return names[start:], "", nil
+	}
+	return names[start:end], strconv.Itoa(end), nil
+}
+
+func secretValue(name string, sec *secret, stage string) map[string]interface{} {
+	return map[string]interface{}{
+		"ARN":           arn(name),
+		"Name":          name,
+		"SecretString":  sec.versions[stage],
+		"VersionId":     strings.ToLower(stage),
+		"VersionStages": []string{stage},
+		"CreatedDate":   sec.created.Unix(),
+	}
+}
+
+func arn(name string) string {
+	return "arn:aws:secretsmanager:us-east-1:123456789012:secret:" + name + "-mock00"
+}
+
+func writeError(w http.ResponseWriter, err *Error) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Header().Set("X-Amzn-Errortype", err.Code)
+	w.WriteHeader(err.StatusCode)
+	json.NewEncoder(w).Encode(map[string]string{
+		"__type":  err.Code,
+		"message": err.Message,
+	})
+}
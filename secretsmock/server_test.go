@@ -0,0 +1,236 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretsmock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/smithy-go"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestServiceClient(srv *Server) *secretsmanager.Client {
+	return secretsmanager.New(secretsmanager.Options{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("test", "test", ""),
+		EndpointResolver: secretsmanager.EndpointResolverFromURL(srv.URL),
+		Retryer:          aws.NopRetryer{},
+	})
+}
+
+func TestGetSecretValue(t *testing.T) {
+	srv := NewServer(t)
+	srv.SetSecret("authcrunch/users/jsmith", `{"username":"jsmith"}`)
+	srv.SetSecret("authcrunch/users/jsmith", `{"username":"john.smith"}`)
+	svc := newTestServiceClient(srv)
+
+	testcases := []struct {
+		name      string
+		secretID  string
+		stage     string
+		want      string
+		shouldErr bool
+		errCode   string
+	}{
+		{
+			name:     "get current version",
+			secretID: "authcrunch/users/jsmith",
+			want:     `{"username":"john.smith"}`,
+		},
+		{
+			name:     "get previous version",
+			secretID: "authcrunch/users/jsmith",
+			stage:    "AWSPREVIOUS",
+			want:     `{"username":"jsmith"}`,
+		},
+		{
+			name:      "get missing secret",
+			secretID:  "authcrunch/users/mjones",
+			shouldErr: true,
+			errCode:   "ResourceNotFoundException",
+		},
+		{
+			name:      "get missing version",
+			secretID:  "authcrunch/users/jsmith",
+			stage:     "AWSPENDING",
+			shouldErr: true,
+			errCode:   "ResourceNotFoundException",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(tc.secretID)}
+			if tc.stage != "" {
+				input.VersionStage = aws.String(tc.stage)
+			}
+			output, err := svc.GetSecretValue(context.TODO(), input)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				var apiErr smithy.APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("expected API error, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.errCode, apiErr.ErrorCode()); diff != "" {
+					t.Fatalf("unexpected error code (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %s", tc.errCode)
+			}
+			if diff := cmp.Diff(tc.want, aws.ToString(output.SecretString)); diff != "" {
+				t.Errorf("GetSecretValue() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	var notFound *types.ResourceNotFoundException
+	_, err := svc.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{SecretId: aws.String("foo")})
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected ResourceNotFoundException, got: %v", err)
+	}
+}
+
+func TestListSecrets(t *testing.T) {
+	srv := NewServer(t)
+	srv.SetPageSize(2)
+	for _, name := range []string{"authcrunch/users/mjones", "authcrunch/users/jsmith", "authcrunch/users/admin", "other/secret"} {
+		srv.SetSecret(name, `{}`)
+	}
+	svc := newTestServiceClient(srv)
+
+	paginator := secretsmanager.NewListSecretsPaginator(svc, &secretsmanager.ListSecretsInput{
+		Filters: []types.Filter{{Key: types.FilterNameStringTypeName, Values: []string{"authcrunch/"}}},
+	})
+	var pages [][]string
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		var names []string
+		for _, entry := range output.SecretList {
+			names = append(names, aws.ToString(entry.Name))
+		}
+		pages = append(pages, names)
+	}
+	want := [][]string{
+		{"authcrunch/users/admin", "authcrunch/users/jsmith"},
+		{"authcrunch/users/mjones"},
+	}
+	if diff := cmp.Diff(want, pages); diff != "" {
+		t.Errorf("ListSecrets() pages mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err := svc.ListSecrets(context.TODO(), &secretsmanager.ListSecretsInput{NextToken: aws.String("foo")})
+	var invalidToken *types.InvalidNextTokenException
+	if !errors.As(err, &invalidToken) {
+		t.Fatalf("expected InvalidNextTokenException, got: %v", err)
+	}
+}
+
+func TestBatchGetSecretValue(t *testing.T) {
+	srv := NewServer(t)
+	srv.SetSecret("authcrunch/users/jsmith", `{"username":"jsmith"}`)
+
+	body := `{"SecretIdList":["authcrunch/users/jsmith","authcrunch/users/mjones"]}`
+	req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed creating request: %v", err)
+	}
+	req.Header.Set("X-Amz-Target", "secretsmanager.BatchGetSecretValue")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var output struct {
+		SecretValues []struct {
+			Name         string
+			SecretString string
+		}
+		Errors []struct {
+			SecretId  string
+			ErrorCode string
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+		t.Fatalf("failed decoding response: %v", err)
+	}
+	if diff := cmp.Diff(http.StatusOK, resp.StatusCode); diff != "" {
+		t.Fatalf("status code mismatch (-want +got):\n%s", diff)
+	}
+	if len(output.SecretValues) != 1 || output.SecretValues[0].SecretString != `{"username":"jsmith"}` {
+		t.Errorf("unexpected secret values: %+v", output.SecretValues)
+	}
+	if len(output.Errors) != 1 || output.Errors[0].ErrorCode != "ResourceNotFoundException" {
+		t.Errorf("unexpected errors: %+v", output.Errors)
+	}
+}
+
+func TestInjectedErrors(t *testing.T) {
+	srv := NewServer(t)
+	srv.SetSecret("authcrunch/users/jsmith", `{}`)
+	svc := newTestServiceClient(srv)
+	input := &secretsmanager.GetSecretValueInput{SecretId: aws.String("authcrunch/users/jsmith")}
+
+	srv.Throttle(1)
+	_, err := svc.GetSecretValue(context.TODO(), input)
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ThrottlingException" {
+		t.Fatalf("expected ThrottlingException, got: %v", err)
+	}
+	if _, err := svc.GetSecretValue(context.TODO(), input); err != nil {
+		t.Fatalf("expected success after throttling, got: %v", err)
+	}
+
+	srv.InjectError("GetSecretValue", "authcrunch/users/jsmith", &Error{
+		StatusCode: http.StatusBadRequest,
+		Code:       "DecryptionFailure",
+		Message:    "access to KMS is not allowed",
+	})
+	_, err = svc.GetSecretValue(context.TODO(), input)
+	var decryptionErr *types.DecryptionFailure
+	if !errors.As(err, &decryptionErr) {
+		t.Fatalf("expected DecryptionFailure, got: %v", err)
+	}
+
+	srv.ClearErrors()
+	if _, err := svc.GetSecretValue(context.TODO(), input); err != nil {
+		t.Fatalf("expected success after clearing errors, got: %v", err)
+	}
+
+	want := []Request{
+		{Operation: "GetSecretValue", SecretID: "authcrunch/users/jsmith"},
+		{Operation: "GetSecretValue", SecretID: "authcrunch/users/jsmith"},
+		{Operation: "GetSecretValue", SecretID: "authcrunch/users/jsmith"},
+		{Operation: "GetSecretValue", SecretID: "authcrunch/users/jsmith"},
+	}
+	if diff := cmp.Diff(want, srv.Requests()); diff != "" {
+		t.Errorf("Requests() mismatch (-want +got):\n%s", diff)
+	}
+}
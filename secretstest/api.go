@@ -0,0 +1,208 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretstest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	secrets "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager"
+)
+
+const (
+	versionStageCurrent  = "AWSCURRENT"
+	versionStagePrevious = "AWSPREVIOUS"
+)
+
+// store is the in-memory implementation of AWS Secrets Manager API.
+type store struct {
+	mu sync.Mutex
+	// secrets map the names of the secrets to their versions keyed by
+	// version stage.
+	secrets map[string]map[string]string
+}
+
+var _ secrets.SecretsManagerAPI = (*store)(nil)
+
+func newStore() *store {
+	return &store{secrets: make(map[string]map[string]string)}
+}
+
+// set stores the secret string under the version stage. Storing a new
+// current version makes the replaced one previous.
+func (s *store) set(name, stage, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions, exists := s.secrets[name]
+	if !exists {
+		versions = make(map[string]string)
+		s.secrets[name] = versions
+	}
+	setVersion(versions, stage, value)
+}
+
+func setVersion(versions map[string]string, stage, value string) {
+	if current, exists := versions[versionStageCurrent]; exists && stage == versionStageCurrent {
+		versions[versionStagePrevious] = current
+	}
+	versions[stage] = value
+}
+
+func (s *store) GetSecretValue(_ context.Context, input *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := aws.ToString(input.SecretId)
+	stage := aws.ToString(input.VersionStage)
+	if stage == "" {
+		stage = versionStageCurrent
+	}
+	value, exists := s.secrets[name][stage]
+	if !exists {
+		return nil, newNotFoundError(name)
+	}
+	return &secretsmanager.GetSecretValueOutput{
+		ARN:           aws.String(secretARN(name)),
+		Name:          aws.String(name),
+		SecretString:  aws.String(value),
+		VersionStages: []string{stage},
+	}, nil
+}
+
+func (s *store) CreateSecret(_ context.Context, input *secretsmanager.CreateSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := aws.ToString(input.Name)
+	if _, exists := s.secrets[name]; exists {
+		return nil, &types.ResourceExistsException{Message: aws.String(fmt.Sprintf("The secret %s already exists.", name))}
+	}
+	s.secrets[name] = map[string]string{versionStageCurrent: aws.ToString(input.SecretString)}
+	return &secretsmanager.CreateSecretOutput{ARN: aws.String(secretARN(name)), Name: aws.String(name)}, nil
+}
+
+func (s *store) PutSecretValue(_ context.Context, input *secretsmanager.PutSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := aws.ToString(input.SecretId)
+	versions, exists := s.secrets[name]
+	if !exists {
+		return nil, newNotFoundError(name)
+	}
+	stages := input.VersionStages
+	if len(stages) == 0 {
+		stages = []string{versionStageCurrent}
+	}
+	for _, stage := range stages {
+		setVersion(versions, stage, aws.ToString(input.SecretString))
+	}
+	return &secretsmanager.PutSecretValueOutput{ARN: aws.String(secretARN(name)), Name: aws.String(name), VersionStages: stages}, nil
+}
+
+func (s *store) DeleteSecret(_ context.Context, input *secretsmanager.DeleteSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.DeleteSecretOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := aws.ToString(input.SecretId)
+	if _, exists := s.secrets[name]; !exists {
+		return nil, newNotFoundError(name)
+	}
+	delete(s.secrets, name)
+	return &secretsmanager.DeleteSecretOutput{ARN: aws.String(secretARN(name)), Name: aws.String(name)}, nil
+}
+
+// RotateSecret only checks that the secret exists, because there is no
+// rotation function to invoke.
+func (s *store) RotateSecret(_ context.Context, input *secretsmanager.RotateSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.RotateSecretOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := aws.ToString(input.SecretId)
+	if _, exists := s.secrets[name]; !exists {
+		return nil, newNotFoundError(name)
+	}
+	return &secretsmanager.RotateSecretOutput{ARN: aws.String(secretARN(name)), Name: aws.String(name)}, nil
+}
+
+// ListSecrets returns all matching secrets in a single page. Only the name
+// filters are supported.
+func (s *store) ListSecrets(_ context.Context, input *secretsmanager.ListSecretsInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.ListSecretsOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var prefixes []string
+	for _, filter := range input.Filters {
+		if filter.Key == types.FilterNameStringTypeName {
+			prefixes = append(prefixes, filter.Values...)
+		}
+	}
+	var names []string
+	for name := range s.secrets {
+		if matchesAnyPrefix(name, prefixes) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	output := &secretsmanager.ListSecretsOutput{}
+	for _, name := range names {
+		output.SecretList = append(output.SecretList, types.SecretListEntry{
+			ARN:  aws.String(secretARN(name)),
+			Name: aws.String(name),
+		})
+	}
+	return output, nil
+}
+
+func (s *store) DescribeSecret(_ context.Context, input *secretsmanager.DescribeSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := aws.ToString(input.SecretId)
+	versions, exists := s.secrets[name]
+	if !exists {
+		return nil, newNotFoundError(name)
+	}
+	stages := make(map[string][]string, len(versions))
+	for stage := range versions {
+		stages[strings.ToLower(stage)] = []string{stage}
+	}
+	return &secretsmanager.DescribeSecretOutput{
+		ARN:                aws.String(secretARN(name)),
+		Name:               aws.String(name),
+		VersionIdsToStages: stages,
+	}, nil
+}
+
+func matchesAnyPrefix(name string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func secretARN(name string) string {
+	return "arn:aws:secretsmanager:us-east-1:123456789012:secret:" + name + "-fake00"
+}
+
+func newNotFoundError(name string) error {
+	return &types.ResourceNotFoundException{
+		Message: aws.String(fmt.Sprintf("Secrets Manager can't find the specified secret %s.", name)),
+	}
+}
@@ -0,0 +1,590 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secretstest provides an in-memory implementation of the secrets
+// Client for the tests of the packages using it.
+package secretstest
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	secrets "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Call is a recorded call of a Client method. The Args hold the paths and
+// the other identifying string arguments, but never the secret values or
+// the passwords.
+type Call struct {
+	Method string
+	Args   []string
+}
+
+type injectedError struct {
+	method string
+	path   string
+	err    error
+}
+
+// Fake is the Client backed by the in-memory AWS Secrets Manager. The
+// typed helpers, caching, aliases, schemas, and path policy behave the way
+// they do with the real service, because the Fake delegates to the real
+// client.
+type Fake struct {
+	client secrets.Client
+	store  *store
+
+	mu     sync.Mutex
+	calls  []Call
+	errors []*injectedError
+}
+
+// NewFake returns the Fake with the client options applied, e.g.
+// secrets.WithBasePrefix. The client ID defaults to "fake" and the region
+// to "us-east-1".
+func NewFake(t testing.TB, opts ...secrets.Option) *Fake {
+	t.Helper()
+	f := &Fake{store: newStore()}
+	opts = append([]secrets.Option{
+		secrets.WithID("fake"),
+		secrets.WithRegion("us-east-1"),
+	}, opts...)
+	opts = append(opts,
+		secrets.WithSecretsManagerAPI(f.store),
+		secrets.WithCredentialsProvider(secrets.MockCredentialsProvider{}),
+	)
+	c, err := secrets.NewClient(context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("failed creating fake secrets client: %v", err)
+	}
+	f.client = c
+	return f
+}
+
+// SetSecret stores the key-value map as the current version of the secret
+// with the name. The replaced current version becomes the previous one.
+// The name includes the base prefix, if any.
+func (f *Fake) SetSecret(name string, m map[string]interface{}) {
+	f.SetSecretVersion(name, versionStageCurrent, m)
+}
+
+// SetSecretVersion stores the key-value map as the version of the secret
+// with the stage, e.g. AWSPREVIOUS.
+func (f *Fake) SetSecretVersion(name, stage string, m map[string]interface{}) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	f.store.set(name, stage, string(b))
+}
+
+// InjectError makes the calls of the method for the path return the error.
+// The empty path matches any call of the method.
+func (f *Fake) InjectError(method, path string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, &injectedError{method: method, path: path, err: err})
+}
+
+// ClearErrors removes the injected errors.
+func (f *Fake) ClearErrors() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = nil
+}
+
+// Calls returns the recorded calls in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([]Call, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+// record records the call and returns the error injected for it, if any.
+func (f *Fake) record(method string, args ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+	for _, e := range f.errors {
+		if e.method != method {
+			continue
+		}
+		if e.path == "" || (len(args) > 0 && args[0] == e.path) {
+			return e.err
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetSecret implements secrets.Client.
+func (f *Fake) GetSecret(ctx context.Context, path string, opts ...secrets.CallOption) (map[string]interface{}, error) {
+	if err := f.record("GetSecret", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetSecret(ctx, path, opts...)
+}
+
+// GetSecretByKey implements secrets.Client.
+func (f *Fake) GetSecretByKey(ctx context.Context, path, key string, opts ...secrets.CallOption) (interface{}, error) {
+	if err := f.record("GetSecretByKey", path, key); err != nil {
+		return nil, err
+	}
+	return f.client.GetSecretByKey(ctx, path, key, opts...)
+}
+
+// GetSecretBytes implements secrets.Client.
+func (f *Fake) GetSecretBytes(ctx context.Context, path, key string, opts ...secrets.CallOption) (*secrets.Secret, error) {
+	if err := f.record("GetSecretBytes", path, key); err != nil {
+		return nil, err
+	}
+	return f.client.GetSecretBytes(ctx, path, key, opts...)
+}
+
+// GetSecretInto implements secrets.Client.
+func (f *Fake) GetSecretInto(ctx context.Context, path string, w io.Writer) error {
+	if err := f.record("GetSecretInto", path); err != nil {
+		return err
+	}
+	return f.client.GetSecretInto(ctx, path, w)
+}
+
+// GetSecretTemplated implements secrets.Client.
+func (f *Fake) GetSecretTemplated(ctx context.Context, path string, vars map[string]string) (map[string]interface{}, error) {
+	if err := f.record("GetSecretTemplated", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetSecretTemplated(ctx, path, vars)
+}
+
+// GetTokenSecrets implements secrets.Client.
+func (f *Fake) GetTokenSecrets(ctx context.Context, path string) (*secrets.TokenSecrets, error) {
+	if err := f.record("GetTokenSecrets", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetTokenSecrets(ctx, path)
+}
+
+// GetAccessToken implements secrets.Client.
+func (f *Fake) GetAccessToken(ctx context.Context, path string) (*secrets.AccessToken, error) {
+	if err := f.record("GetAccessToken", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetAccessToken(ctx, path)
+}
+
+// GetOAuthClientCredentials implements secrets.Client.
+func (f *Fake) GetOAuthClientCredentials(ctx context.Context, path string) (*secrets.OAuthClientCredentials, error) {
+	if err := f.record("GetOAuthClientCredentials", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetOAuthClientCredentials(ctx, path)
+}
+
+// GetDatabaseCredentials implements secrets.Client.
+func (f *Fake) GetDatabaseCredentials(ctx context.Context, path string) (*secrets.DatabaseCredentials, error) {
+	if err := f.record("GetDatabaseCredentials", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetDatabaseCredentials(ctx, path)
+}
+
+// GetUserCredentials implements secrets.Client.
+func (f *Fake) GetUserCredentials(ctx context.Context, path string) (*secrets.UserCredentials, error) {
+	if err := f.record("GetUserCredentials", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetUserCredentials(ctx, path)
+}
+
+// GetSMTPCredentials implements secrets.Client.
+func (f *Fake) GetSMTPCredentials(ctx context.Context, path string) (*secrets.SMTPCredentials, error) {
+	if err := f.record("GetSMTPCredentials", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetSMTPCredentials(ctx, path)
+}
+
+// GetLDAPBindCredentials implements secrets.Client.
+func (f *Fake) GetLDAPBindCredentials(ctx context.Context, path string) (*secrets.LDAPBindCredentials, error) {
+	if err := f.record("GetLDAPBindCredentials", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetLDAPBindCredentials(ctx, path)
+}
+
+// GetCertificateBundle implements secrets.Client.
+func (f *Fake) GetCertificateBundle(ctx context.Context, path, passphraseKey string) (*secrets.CertificateBundle, error) {
+	if err := f.record("GetCertificateBundle", path, passphraseKey); err != nil {
+		return nil, err
+	}
+	return f.client.GetCertificateBundle(ctx, path, passphraseKey)
+}
+
+// GetTLSCertificate implements secrets.Client.
+func (f *Fake) GetTLSCertificate(ctx context.Context, path string) (*tls.Certificate, error) {
+	if err := f.record("GetTLSCertificate", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetTLSCertificate(ctx, path)
+}
+
+// GetSSHSigner implements secrets.Client.
+func (f *Fake) GetSSHSigner(ctx context.Context, path, key string) (ssh.Signer, error) {
+	if err := f.record("GetSSHSigner", path, key); err != nil {
+		return nil, err
+	}
+	return f.client.GetSSHSigner(ctx, path, key)
+}
+
+// GetTOTPSeed implements secrets.Client.
+func (f *Fake) GetTOTPSeed(ctx context.Context, path string) (*secrets.TOTPSeed, error) {
+	if err := f.record("GetTOTPSeed", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetTOTPSeed(ctx, path)
+}
+
+// GetPGPEntities implements secrets.Client.
+func (f *Fake) GetPGPEntities(ctx context.Context, path, key string) (openpgp.EntityList, error) {
+	if err := f.record("GetPGPEntities", path, key); err != nil {
+		return nil, err
+	}
+	return f.client.GetPGPEntities(ctx, path, key)
+}
+
+// GetWebhookSecret implements secrets.Client.
+func (f *Fake) GetWebhookSecret(ctx context.Context, path string) (*secrets.WebhookSecret, error) {
+	if err := f.record("GetWebhookSecret", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetWebhookSecret(ctx, path)
+}
+
+// GetSessionKeys implements secrets.Client.
+func (f *Fake) GetSessionKeys(ctx context.Context, path string) (*secrets.SessionKeys, error) {
+	if err := f.record("GetSessionKeys", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetSessionKeys(ctx, path)
+}
+
+// VerifyUserPassword implements secrets.Client. The candidate password is
+// not recorded.
+func (f *Fake) VerifyUserPassword(ctx context.Context, path, candidate string) (bool, error) {
+	if err := f.record("VerifyUserPassword", path); err != nil {
+		return false, err
+	}
+	return f.client.VerifyUserPassword(ctx, path, candidate)
+}
+
+// GetUserSecret implements secrets.Client.
+func (f *Fake) GetUserSecret(ctx context.Context, realm, username string) (map[string]interface{}, error) {
+	if err := f.record("GetUserSecret", realm, username); err != nil {
+		return nil, err
+	}
+	return f.client.GetUserSecret(ctx, realm, username)
+}
+
+// GetSecretWithPrevious implements secrets.Client.
+func (f *Fake) GetSecretWithPrevious(ctx context.Context, path string) (*secrets.SecretWithPrevious, error) {
+	if err := f.record("GetSecretWithPrevious", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetSecretWithPrevious(ctx, path)
+}
+
+// GetSecretVersion implements secrets.Client.
+func (f *Fake) GetSecretVersion(ctx context.Context, path string) (*secrets.SecretVersion, error) {
+	if err := f.record("GetSecretVersion", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetSecretVersion(ctx, path)
+}
+
+// DiffSecretVersions implements secrets.Client.
+func (f *Fake) DiffSecretVersions(ctx context.Context, path, v1, v2 string, opts ...secrets.DiffOption) (*secrets.VersionDiff, error) {
+	if err := f.record("DiffSecretVersions", path, v1, v2); err != nil {
+		return nil, err
+	}
+	return f.client.DiffSecretVersions(ctx, path, v1, v2, opts...)
+}
+
+// GetSecretWithMetadata implements secrets.Client.
+func (f *Fake) GetSecretWithMetadata(ctx context.Context, path string) (*secrets.SecretWithMetadata, error) {
+	if err := f.record("GetSecretWithMetadata", path); err != nil {
+		return nil, err
+	}
+	return f.client.GetSecretWithMetadata(ctx, path)
+}
+
+// GetSecretAcrossAccounts implements secrets.Client.
+func (f *Fake) GetSecretAcrossAccounts(ctx context.Context, path string, roleARNs []string) (map[string]*secrets.AccountSecret, error) {
+	if err := f.record("GetSecretAcrossAccounts", append([]string{path}, roleARNs...)...); err != nil {
+		return nil, err
+	}
+	return f.client.GetSecretAcrossAccounts(ctx, path, roleARNs)
+}
+
+// ExportEnv implements secrets.Client.
+func (f *Fake) ExportEnv(ctx context.Context, paths []string, w io.Writer, format string, opts ...secrets.ExportOption) error {
+	if err := f.record("ExportEnv", paths...); err != nil {
+		return err
+	}
+	return f.client.ExportEnv(ctx, paths, w, format, opts...)
+}
+
+// LoadIntoEnv implements secrets.Client.
+func (f *Fake) LoadIntoEnv(ctx context.Context, mapping map[string]string, opts ...secrets.EnvOption) error {
+	names := make([]string, 0, len(mapping))
+	for name := range mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if err := f.record("LoadIntoEnv", names...); err != nil {
+		return err
+	}
+	return f.client.LoadIntoEnv(ctx, mapping, opts...)
+}
+
+// RenderTemplate implements secrets.Client.
+func (f *Fake) RenderTemplate(ctx context.Context, tmpl string, w io.Writer) error {
+	if err := f.record("RenderTemplate"); err != nil {
+		return err
+	}
+	return f.client.RenderTemplate(ctx, tmpl, w)
+}
+
+// ListSecrets implements secrets.Client.
+func (f *Fake) ListSecrets(ctx context.Context, prefix string) ([]string, error) {
+	if err := f.record("ListSecrets", prefix); err != nil {
+		return nil, err
+	}
+	return f.client.ListSecrets(ctx, prefix)
+}
+
+// DescribeSecret implements secrets.Client.
+func (f *Fake) DescribeSecret(ctx context.Context, path string) (*secrets.SecretMetadata, error) {
+	if err := f.record("DescribeSecret", path); err != nil {
+		return nil, err
+	}
+	return f.client.DescribeSecret(ctx, path)
+}
+
+// ListVersionHistory implements secrets.Client.
+func (f *Fake) ListVersionHistory(ctx context.Context, path string) ([]*secrets.SecretVersionInfo, error) {
+	if err := f.record("ListVersionHistory", path); err != nil {
+		return nil, err
+	}
+	return f.client.ListVersionHistory(ctx, path)
+}
+
+// AnnotateVersion implements secrets.Client.
+func (f *Fake) AnnotateVersion(ctx context.Context, path, versionID, annotation string) (*secrets.Change, error) {
+	if err := f.record("AnnotateVersion", path, versionID); err != nil {
+		return nil, err
+	}
+	return f.client.AnnotateVersion(ctx, path, versionID, annotation)
+}
+
+// CreateSecret implements secrets.Client.
+func (f *Fake) CreateSecret(ctx context.Context, path string, m map[string]interface{}) (*secrets.Change, error) {
+	if err := f.record("CreateSecret", path); err != nil {
+		return nil, err
+	}
+	return f.client.CreateSecret(ctx, path, m)
+}
+
+// PutSecret implements secrets.Client.
+func (f *Fake) PutSecret(ctx context.Context, path string, m map[string]interface{}, opts ...secrets.CallOption) (*secrets.Change, error) {
+	if err := f.record("PutSecret", path); err != nil {
+		return nil, err
+	}
+	return f.client.PutSecret(ctx, path, m, opts...)
+}
+
+// DeleteSecret implements secrets.Client.
+func (f *Fake) DeleteSecret(ctx context.Context, path string) (*secrets.Change, error) {
+	if err := f.record("DeleteSecret", path); err != nil {
+		return nil, err
+	}
+	return f.client.DeleteSecret(ctx, path)
+}
+
+// RotateSecret implements secrets.Client.
+func (f *Fake) RotateSecret(ctx context.Context, path string) (*secrets.Change, error) {
+	if err := f.record("RotateSecret", path); err != nil {
+		return nil, err
+	}
+	return f.client.RotateSecret(ctx, path)
+}
+
+// Sync implements secrets.Client.
+func (f *Fake) Sync(ctx context.Context, m map[string]map[string]interface{}) ([]*secrets.Change, error) {
+	if err := f.record("Sync", sortedKeys(m)...); err != nil {
+		return nil, err
+	}
+	return f.client.Sync(ctx, m)
+}
+
+// Watch implements secrets.Client.
+func (f *Fake) Watch(ctx context.Context, paths []string) (<-chan secrets.SecretEvent, error) {
+	if err := f.record("Watch", paths...); err != nil {
+		return nil, err
+	}
+	return f.client.Watch(ctx, paths)
+}
+
+// SetMockClient implements secrets.Client. The Fake keeps serving the
+// secrets from memory, so the call is only recorded.
+func (f *Fake) SetMockClient(aws.HTTPClient) {
+	f.record("SetMockClient")
+}
+
+// SetMockCredentialsProvider implements secrets.Client. The call is only
+// recorded.
+func (f *Fake) SetMockCredentialsProvider(aws.CredentialsProvider) {
+	f.record("SetMockCredentialsProvider")
+}
+
+// SetLogger implements secrets.Client.
+func (f *Fake) SetLogger(logger *zap.Logger) {
+	f.record("SetLogger")
+	f.client.SetLogger(logger)
+}
+
+// GetConfig implements secrets.Client.
+func (f *Fake) GetConfig(ctx context.Context) *secrets.Config {
+	f.record("GetConfig")
+	return f.client.GetConfig(ctx)
+}
+
+// Simple implements secrets.Client.
+func (f *Fake) Simple() *secrets.SimpleClient {
+	f.record("Simple")
+	return secrets.NewSimpleClient(f)
+}
+
+// Diagnose implements secrets.Client.
+func (f *Fake) Diagnose(ctx context.Context) *secrets.DiagnosticReport {
+	f.record("Diagnose")
+	return f.client.Diagnose(ctx)
+}
+
+// Reconfigure implements secrets.Client.
+func (f *Fake) Reconfigure(ctx context.Context, cfg *secrets.ClientConfig) error {
+	if err := f.record("Reconfigure"); err != nil {
+		return err
+	}
+	return f.client.Reconfigure(ctx, cfg)
+}
+
+// InvalidateCache implements secrets.Client.
+func (f *Fake) InvalidateCache(path string) {
+	f.record("InvalidateCache", path)
+	f.client.InvalidateCache(path)
+}
+
+// BlockAccess implements secrets.Client.
+func (f *Fake) BlockAccess(prefix string, d time.Duration) error {
+	if err := f.record("BlockAccess", prefix); err != nil {
+		return err
+	}
+	return f.client.BlockAccess(prefix, d)
+}
+
+// UnblockAccess implements secrets.Client.
+func (f *Fake) UnblockAccess(prefix string) error {
+	if err := f.record("UnblockAccess", prefix); err != nil {
+		return err
+	}
+	return f.client.UnblockAccess(prefix)
+}
+
+// AnomalyStats implements secrets.Client.
+func (f *Fake) AnomalyStats() (secrets.AnomalyStats, error) {
+	if err := f.record("AnomalyStats"); err != nil {
+		return secrets.AnomalyStats{}, err
+	}
+	return f.client.AnomalyStats()
+}
+
+// ReportInvalid implements secrets.Client.
+func (f *Fake) ReportInvalid(ctx context.Context, path string, opts ...secrets.InvalidOption) (map[string]interface{}, error) {
+	if err := f.record("ReportInvalid", path); err != nil {
+		return nil, err
+	}
+	return f.client.ReportInvalid(ctx, path, opts...)
+}
+
+// ListenSQS implements secrets.Client.
+func (f *Fake) ListenSQS(ctx context.Context, queueURL string) (<-chan secrets.SecretEvent, error) {
+	if err := f.record("ListenSQS", queueURL); err != nil {
+		return nil, err
+	}
+	return f.client.ListenSQS(ctx, queueURL)
+}
+
+// SetupEventWiring implements secrets.Client.
+func (f *Fake) SetupEventWiring(ctx context.Context, cfg *secrets.EventWiringConfig) (*secrets.EventWiring, error) {
+	if err := f.record("SetupEventWiring", cfg.RuleName, cfg.QueueName); err != nil {
+		return nil, err
+	}
+	return f.client.SetupEventWiring(ctx, cfg)
+}
+
+// SNSHandler implements secrets.Client.
+func (f *Fake) SNSHandler(ctx context.Context, topicARN string) (http.Handler, <-chan secrets.SecretEvent, error) {
+	if err := f.record("SNSHandler", topicARN); err != nil {
+		return nil, nil, err
+	}
+	return f.client.SNSHandler(ctx, topicARN)
+}
+
+// Scoped implements secrets.Client. The calls of the scoped client are
+// not recorded.
+func (f *Fake) Scoped(prefix string) secrets.Client {
+	return f.client.Scoped(prefix)
+}
+
+// Close implements secrets.Client.
+func (f *Fake) Close() error {
+	if err := f.record("Close"); err != nil {
+		return err
+	}
+	return f.client.Close()
+}
+
+var _ secrets.Client = (*Fake)(nil)
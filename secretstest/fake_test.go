@@ -0,0 +1,119 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	secrets "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager"
+)
+
+func TestFake(t *testing.T) {
+	ctx := context.TODO()
+	f := NewFake(t, secrets.WithBasePrefix("authcrunch/"))
+	f.SetSecret("authcrunch/access_token", map[string]interface{}{"id": "0", "usage": "sign-verify", "value": "foo"})
+	f.SetSecret("authcrunch/access_token", map[string]interface{}{"id": "1", "usage": "sign-verify", "value": "bar"})
+	f.SetSecret("authcrunch/users/jsmith", map[string]interface{}{"username": "jsmith"})
+
+	tokens, err := f.GetTokenSecrets(ctx, "access_token")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"1", "0"}, []string{tokens.Current.ID, tokens.Previous.ID}); diff != "" {
+		t.Errorf("GetTokenSecrets() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := f.PutSecret(ctx, "users/jsmith", map[string]interface{}{"username": "jsmith", "email": "jsmith@localhost"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	got, err := f.GetSecret(ctx, "users/jsmith")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"username": "jsmith", "email": "jsmith@localhost"}, got); diff != "" {
+		t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+	}
+
+	paths, err := f.ListSecrets(ctx, "")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"access_token", "users/jsmith"}, paths); diff != "" {
+		t.Errorf("ListSecrets() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := f.GetSecret(ctx, "users/mjones"); err == nil {
+		t.Fatalf("unexpected success for missing secret")
+	}
+
+	injected := errors.New("throttled")
+	f.InjectError("GetSecret", "users/jsmith", injected)
+	if _, err := f.GetSecret(ctx, "users/jsmith"); !errors.Is(err, injected) {
+		t.Fatalf("expected injected error, got: %v", err)
+	}
+	if _, err := f.GetSecret(ctx, "access_token"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	f.ClearErrors()
+	if _, err := f.GetSecret(ctx, "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	if _, err := f.VerifyUserPassword(ctx, "users/jsmith", "secret"); err == nil {
+		t.Fatalf("unexpected success for secret without password")
+	}
+
+	wantCalls := []Call{
+		{Method: "GetTokenSecrets", Args: []string{"access_token"}},
+		{Method: "PutSecret", Args: []string{"users/jsmith"}},
+		{Method: "GetSecret", Args: []string{"users/jsmith"}},
+		{Method: "ListSecrets", Args: []string{""}},
+		{Method: "GetSecret", Args: []string{"users/mjones"}},
+		{Method: "GetSecret", Args: []string{"users/jsmith"}},
+		{Method: "GetSecret", Args: []string{"access_token"}},
+		{Method: "GetSecret", Args: []string{"users/jsmith"}},
+		{Method: "VerifyUserPassword", Args: []string{"users/jsmith"}},
+	}
+	if diff := cmp.Diff(wantCalls, f.Calls()); diff != "" {
+		t.Errorf("Calls() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFakeCreateAndDelete(t *testing.T) {
+	ctx := context.TODO()
+	f := NewFake(t)
+	if _, err := f.CreateSecret(ctx, "users/jsmith", map[string]interface{}{"username": "jsmith"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := f.CreateSecret(ctx, "users/jsmith", map[string]interface{}{"username": "jsmith"}); err == nil {
+		t.Fatalf("unexpected success for existing secret")
+	}
+	metadata, err := f.DescribeSecret(ctx, "users/jsmith")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff("arn:aws:secretsmanager:us-east-1:123456789012:secret:users/jsmith-fake00", metadata.ARN); diff != "" {
+		t.Errorf("DescribeSecret() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := f.DeleteSecret(ctx, "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := f.GetSecret(ctx, "users/jsmith"); err == nil {
+		t.Fatalf("unexpected success for deleted secret")
+	}
+}
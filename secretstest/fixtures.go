@@ -0,0 +1,159 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	secrets "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures are the secrets loaded from the fixture file, e.g.
+//
+//	schemas:
+//	  - path: "authcrunch/users/*"
+//	    required_keys: [username, email]
+//	secrets:
+//	  authcrunch/users/jsmith:
+//	    username: jsmith
+//	    email: jsmith@localhost.localdomain
+//	versions:
+//	  authcrunch/users/jsmith:
+//	    AWSPREVIOUS:
+//	      username: jsmith
+//	      email: john.smith@localhost.localdomain
+//
+// The Secrets hold the current versions of the secrets keyed by name, and
+// the Versions hold the other versions keyed by name and version stage.
+type Fixtures struct {
+	Schemas  []*secrets.SecretSchema                      `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	Secrets  map[string]map[string]interface{}            `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+	Versions map[string]map[string]map[string]interface{} `json:"versions,omitempty" yaml:"versions,omitempty"`
+}
+
+// LoadFixtures loads the fixture file. The format is determined by the
+// file extension, i.e. .json, .yaml, or .yml. Every version of the secrets
+// is checked against the schemas from the file and the provided ones. The
+// test fails when the file cannot be loaded or a secret does not conform.
+func LoadFixtures(t testing.TB, path string, schemas ...*secrets.SecretSchema) *Fixtures {
+	t.Helper()
+	fx, err := loadFixtures(path, schemas)
+	if err != nil {
+		t.Fatalf("failed loading %q fixtures: %v", path, err)
+	}
+	return fx
+}
+
+func loadFixtures(path string, schemas []*secrets.SecretSchema) (*Fixtures, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fx := &Fixtures{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(fx)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		err = dec.Decode(fx)
+	default:
+		return nil, fmt.Errorf("unsupported %q fixture file extension", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := fx.validate(append(fx.Schemas, schemas...)); err != nil {
+		return nil, err
+	}
+	return fx, nil
+}
+
+func (fx *Fixtures) validate(schemas []*secrets.SecretSchema) error {
+	for _, name := range fx.names() {
+		versions := fx.Versions[name]
+		if _, exists := versions[versionStageCurrent]; exists {
+			return fmt.Errorf("secret %q has %s version, use secrets instead", name, versionStageCurrent)
+		}
+		check := []map[string]interface{}{}
+		if m, exists := fx.Secrets[name]; exists {
+			check = append(check, m)
+		}
+		for _, stage := range sortedKeys(versions) {
+			check = append(check, versions[stage])
+		}
+		for _, m := range check {
+			for _, schema := range schemas {
+				if err := schema.Check(name, m); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// names returns the sorted names of the secrets.
+func (fx *Fixtures) names() []string {
+	names := sortedKeys(fx.Secrets)
+	for name := range fx.Versions {
+		if _, exists := fx.Secrets[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetFixtures stores the secrets from the fixtures.
+func (f *Fake) SetFixtures(fx *Fixtures) {
+	fx.apply(f.SetSecretVersion)
+}
+
+// ServeFixtures stores the secrets from the fixtures in the mock server.
+func ServeFixtures(srv *secretsmock.Server, fx *Fixtures) {
+	fx.apply(func(name, stage string, m map[string]interface{}) {
+		b, err := json.Marshal(m)
+		if err != nil {
+			panic(err)
+		}
+		srv.SetSecretVersion(name, stage, string(b))
+	})
+}
+
+// apply stores the secrets with the setter. The current versions are
+// stored first, so that storing the other versions does not replace them.
+func (fx *Fixtures) apply(set func(name, stage string, m map[string]interface{})) {
+	for _, name := range fx.names() {
+		if m, exists := fx.Secrets[name]; exists {
+			set(name, versionStageCurrent, m)
+		}
+		versions := fx.Versions[name]
+		for _, stage := range sortedKeys(versions) {
+			set(name, stage, versions[stage])
+		}
+	}
+}
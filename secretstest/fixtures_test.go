@@ -0,0 +1,147 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretstest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	secrets "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	testcases := []struct {
+		name      string
+		file      string
+		content   string
+		schemas   []*secrets.SecretSchema
+		want      []string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test yaml fixtures",
+			file: "testdata/users.yaml",
+			want: []string{"authcrunch/users/jsmith", "authcrunch/users/mjones"},
+		},
+		{
+			name: "test json fixtures",
+			file: "testdata/users.json",
+			want: []string{"authcrunch/users/jsmith"},
+		},
+		{
+			name:      "test fixtures not matching provided schema",
+			file:      "testdata/users.json",
+			schemas:   []*secrets.SecretSchema{{Path: "authcrunch/users/*", RequiredKeys: []string{"password"}}},
+			shouldErr: true,
+			err:       errors.New(`secret "authcrunch/users/jsmith" does not match schema: key "password" not found`),
+		},
+		{
+			name: "test previous version not matching schema",
+			file: "previous.yaml",
+			content: "schemas:\n  - path: \"users/*\"\n    required_keys: [email]\n" +
+				"secrets:\n  users/jsmith:\n    email: jsmith@localhost.localdomain\n" +
+				"versions:\n  users/jsmith:\n    AWSPREVIOUS:\n      username: jsmith\n",
+			shouldErr: true,
+			err:       errors.New(`secret "users/jsmith" does not match schema: key "email" not found`),
+		},
+		{
+			name:      "test current version in versions",
+			file:      "current.yaml",
+			content:   "versions:\n  users/jsmith:\n    AWSCURRENT:\n      username: jsmith\n",
+			shouldErr: true,
+			err:       errors.New(`secret "users/jsmith" has AWSCURRENT version, use secrets instead`),
+		},
+		{
+			name:      "test unknown field",
+			file:      "unknown.json",
+			content:   `{"secret": {}}`,
+			shouldErr: true,
+			err:       errors.New(`json: unknown field "secret"`),
+		},
+		{
+			name:      "test unsupported extension",
+			file:      "users.txt",
+			content:   "secrets: {}",
+			shouldErr: true,
+			err:       errors.New(`unsupported ".txt" fixture file extension`),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := tc.file
+			if tc.content != "" {
+				path = filepath.Join(t.TempDir(), tc.file)
+				if err := ioutil.WriteFile(path, []byte(tc.content), 0600); err != nil {
+					t.Fatalf("failed writing fixture file: %v", err)
+				}
+			}
+			fx, err := loadFixtures(path, tc.schemas)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, fx.names()); diff != "" {
+				t.Errorf("LoadFixtures() secrets mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	fx := LoadFixtures(t, "testdata/users.yaml")
+
+	f := NewFake(t, secrets.WithBasePrefix("authcrunch/"))
+	f.SetFixtures(fx)
+	got, err := f.GetSecretByKey(context.TODO(), "users/jsmith", "email", secrets.WithVersionStage("AWSPREVIOUS"))
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff("john.smith@localhost.localdomain", got); diff != "" {
+		t.Errorf("GetSecretByKey() mismatch (-want +got):\n%s", diff)
+	}
+
+	srv := secretsmock.NewServer(t)
+	ServeFixtures(srv, fx)
+	c, err := secrets.NewClient(context.TODO(),
+		secrets.WithID("foo"),
+		secrets.WithRegion("us-east-1"),
+		secrets.WithEndpoint(srv.URL),
+		secrets.WithCredentialsProvider(secrets.MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	got, err = c.GetSecretByKey(context.TODO(), "authcrunch/users/mjones", "email")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff("mjones@localhost.localdomain", got); diff != "" {
+		t.Errorf("GetSecretByKey() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretstest
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// leakCheckTimeout is how long VerifyNoLeaks waits for the goroutines to
+// exit.
+var leakCheckTimeout = 2 * time.Second
+
+// createdByPrefix identifies the goroutines started by the secrets
+// package in the goroutine dumps.
+const createdByPrefix = "created by github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager."
+
+// VerifyNoLeaks fails the test when the goroutines started by the secrets
+// clients during the test are still running after it, e.g. because a
+// client was not closed. The check runs after the cleanups registered
+// later, so call it first in the test:
+//
+//	secretstest.VerifyNoLeaks(t)
+//	f := secretstest.NewFake(t)
+//	defer f.Close()
+func VerifyNoLeaks(t testing.TB) {
+	t.Helper()
+	before := make(map[string]bool)
+	for _, g := range backgroundGoroutines() {
+		before[goroutineID(g)] = true
+	}
+	t.Cleanup(func() {
+		deadline := time.Now().Add(leakCheckTimeout)
+		for {
+			var leaked []string
+			for _, g := range backgroundGoroutines() {
+				if !before[goroutineID(g)] {
+					leaked = append(leaked, g)
+				}
+			}
+			if len(leaked) == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("found %d leaked goroutines:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+// backgroundGoroutines returns the stacks of the goroutines started by the
+// secrets package.
+func backgroundGoroutines() []string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	var stacks []string
+	for _, g := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.Contains(g, []byte(createdByPrefix)) {
+			stacks = append(stacks, string(g))
+		}
+	}
+	return stacks
+}
+
+// goroutineID returns the header of the goroutine stack, e.g.
+// "goroutine 42", without its state.
+func goroutineID(stack string) string {
+	header := stack
+	if i := strings.Index(header, " ["); i >= 0 {
+		header = header[:i]
+	}
+	return header
+}
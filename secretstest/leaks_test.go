@@ -0,0 +1,91 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretstest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	secrets "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager"
+)
+
+// recordingTB is the testing.TB recording the errors and running the
+// cleanups on demand.
+type recordingTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Cleanup(f func()) {
+	tb.cleanups = append(tb.cleanups, f)
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, format)
+}
+
+func (tb *recordingTB) runCleanups() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestVerifyNoLeaks(t *testing.T) {
+	VerifyNoLeaks(t)
+	clock := NewClock(time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC))
+	f := NewFake(t, secrets.WithClock(clock))
+	f.SetSecret("authcrunch/access_token", map[string]interface{}{"id": "0"})
+
+	ch, err := f.Watch(context.Background(), []string{"authcrunch/access_token"})
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	<-ch
+	if diff := cmp.Diff(1, clock.ActiveTickers()); diff != "" {
+		t.Errorf("ActiveTickers() before Close() mismatch (-want +got):\n%s", diff)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if diff := cmp.Diff(0, clock.ActiveTickers()); diff != "" {
+		t.Errorf("ActiveTickers() after Close() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestVerifyNoLeaksDetectsLeak(t *testing.T) {
+	defer func(timeout time.Duration) {
+		leakCheckTimeout = timeout
+	}(leakCheckTimeout)
+	leakCheckTimeout = 50 * time.Millisecond
+	tb := &recordingTB{TB: t}
+	VerifyNoLeaks(tb)
+	f := NewFake(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := f.Watch(ctx, []string{"authcrunch/access_token"}); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	tb.runCleanups()
+	if len(tb.errors) != 1 || !strings.HasPrefix(tb.errors[0], "found %d leaked goroutines") {
+		t.Errorf("expected leaked goroutines error, got: %v", tb.errors)
+	}
+	cancel()
+	f.Close()
+}
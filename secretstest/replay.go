@@ -0,0 +1,329 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// RecordEnv is the environment variable making NewTransport record the
+// fixtures instead of replaying them.
+const RecordEnv = "SECRETSTEST_RECORD"
+
+const redacted = "REDACTED"
+
+var (
+	accountIDRegexp   = regexp.MustCompile(`(arn:aws[a-z-]*:[a-z0-9-]*:[a-z0-9-]*:)\d{12}(:)`)
+	xmlSecretRegexp   = regexp.MustCompile(`<(AccessKeyId|SecretAccessKey|SessionToken)>[^<]*</(AccessKeyId|SecretAccessKey|SessionToken)>`)
+	droppedJSONFields = []string{"ClientRequestToken"}
+	secretJSONFields  = []string{"SecretString", "SecretBinary"}
+)
+
+// Interaction is the recorded pair of the request and the response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the sanitized request. The Target is the value of
+// X-Amz-Target header identifying the operation of the JSON protocol.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Target string `json:"target,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is the sanitized response.
+type RecordedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+type fixture struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// NewTransport returns the HTTP client replaying the fixture. When
+// SECRETSTEST_RECORD environment variable is set, it returns the client
+// recording the fixture from AWS instead.
+func NewTransport(t testing.TB, path string) aws.HTTPClient {
+	t.Helper()
+	if os.Getenv(RecordEnv) != "" {
+		return NewRecorder(t, path, nil)
+	}
+	return NewReplayer(t, path)
+}
+
+// Recorder is the HTTP client sending the requests to AWS and recording
+// the sanitized interactions. The values of the secrets are replaced with
+// REDACTED, preserving the keys of JSON secrets, and so are the temporary
+// credentials. The AWS account IDs in ARNs become 123456789012.
+type Recorder struct {
+	next aws.HTTPClient
+
+	mu           sync.Mutex
+	interactions []*Interaction
+}
+
+// NewRecorder returns the Recorder sending the requests with the next
+// client, http.DefaultClient if nil. The fixture is written to the path
+// when the test finishes.
+func NewRecorder(t testing.TB, path string, next aws.HTTPClient) *Recorder {
+	t.Helper()
+	if next == nil {
+		next = http.DefaultClient
+	}
+	r := &Recorder{next: next}
+	t.Cleanup(func() {
+		if err := r.Save(path); err != nil {
+			t.Errorf("failed saving %q fixture: %v", path, err)
+		}
+	})
+	return r
+}
+
+// Do implements aws.HTTPClient interface.
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string)
+	for _, k := range []string{"Content-Type", "X-Amzn-Errortype"} {
+		if v := resp.Header.Get(k); v != "" {
+			headers[k] = v
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			Target: req.Header.Get("X-Amz-Target"),
+			Body:   sanitize(reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    headers,
+			Body:       sanitize(respBody),
+		},
+	})
+	return resp, nil
+}
+
+// Interactions returns the recorded interactions.
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Interaction(nil), r.interactions...)
+}
+
+// Save writes the recorded interactions to the fixture file.
+func (r *Recorder) Save(path string) error {
+	b, err := json.MarshalIndent(&fixture{Interactions: r.Interactions()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// Replayer is the HTTP client responding with the recorded interactions.
+// A request matches the first unused interaction with the same method,
+// operation, and sanitized body.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewReplayer returns the Replayer of the fixture. It fails the test when
+// the fixture cannot be read.
+func NewReplayer(t testing.TB, path string) *Replayer {
+	t.Helper()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed reading %q fixture: %v", path, err)
+	}
+	var f fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		t.Fatalf("failed parsing %q fixture: %v", path, err)
+	}
+	return &Replayer{interactions: f.Interactions, used: make([]bool, len(f.Interactions))}
+}
+
+// Do implements aws.HTTPClient interface.
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	target := req.Header.Get("X-Amz-Target")
+	body = sanitize(body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.interactions {
+		if r.used[i] {
+			continue
+		}
+		recorded := interaction.Request
+		if recorded.Method != req.Method || recorded.Target != target || recorded.Body != body {
+			continue
+		}
+		r.used[i] = true
+		resp := &http.Response{
+			StatusCode: interaction.Response.StatusCode,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+			Request:    req,
+		}
+		for k, v := range interaction.Response.Headers {
+			resp.Header.Set(k, v)
+		}
+		return resp, nil
+	}
+	return nil, &replayError{fmt.Errorf("no recorded response for %s %s request with %q body", req.Method, target, body)}
+}
+
+// replayError is the error of the request missing from the fixture. AWS SDK
+// does not retry it, because the retries would not find it either.
+type replayError struct {
+	error
+}
+
+// RetryableError implements the interface AWS SDK checks to decide
+// whether to retry the error.
+func (e *replayError) RetryableError() bool {
+	return false
+}
+
+// Unwrap returns the underlying error.
+func (e *replayError) Unwrap() error {
+	return e.error
+}
+
+// Remaining returns the number of the interactions not replayed yet.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// readBody reads the body and replaces it with the copy, so that it can
+// be read again.
+func readBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	b, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return "", err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(b))
+	return string(b), nil
+}
+
+// sanitize removes the secret values, the credentials, the account IDs,
+// and the random request tokens from the body.
+func sanitize(body string) string {
+	body = accountIDRegexp.ReplaceAllString(body, "${1}123456789012${2}")
+	body = xmlSecretRegexp.ReplaceAllString(body, "<${1}>"+redacted+"</${2}>")
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &m); err != nil {
+		return body
+	}
+	sanitizeJSON(m)
+	b, err := json.Marshal(m)
+	if err != nil {
+		return body
+	}
+	return string(b)
+}
+
+func sanitizeJSON(m map[string]interface{}) {
+	for _, k := range droppedJSONFields {
+		delete(m, k)
+	}
+	for _, k := range secretJSONFields {
+		if v, exists := m[k]; exists {
+			m[k] = redactSecret(v)
+		}
+	}
+	for _, v := range m {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			sanitizeJSON(v)
+		case []interface{}:
+			for _, item := range v {
+				if item, ok := item.(map[string]interface{}); ok {
+					sanitizeJSON(item)
+				}
+			}
+		}
+	}
+}
+
+// redactSecret replaces the value of the secret with REDACTED. The keys
+// of JSON secrets are preserved, so that the replayed secrets have the
+// shape of the recorded ones.
+func redactSecret(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return redacted
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return redacted
+	}
+	for k := range m {
+		m[k] = redacted
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return redacted
+	}
+	return string(b)
+}
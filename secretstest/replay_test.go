@@ -0,0 +1,131 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretstest
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	secrets "github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func newReplayTestClient(t *testing.T, httpClient secrets.Option, endpoint string) secrets.Client {
+	c, err := secrets.NewClient(context.TODO(),
+		secrets.WithID("foo"),
+		secrets.WithRegion("us-east-1"),
+		secrets.WithEndpoint(endpoint),
+		httpClient,
+		secrets.WithCredentialsProvider(secrets.MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	return c
+}
+
+func TestRecordReplay(t *testing.T) {
+	fixturePath := filepath.Join(t.TempDir(), "testdata", "get_secret.json")
+
+	t.Run("record", func(t *testing.T) {
+		srv := secretsmock.NewServer(t)
+		srv.SetSecret("authcrunch/users/jsmith", `{"username":"jsmith","password":"foobar"}`)
+		recorder := NewRecorder(t, fixturePath, nil)
+		c := newReplayTestClient(t, secrets.WithHTTPClient(recorder), srv.URL)
+		got, err := c.GetSecret(context.TODO(), "authcrunch/users/jsmith")
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		if diff := cmp.Diff("foobar", got["password"]); diff != "" {
+			t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+		}
+		if _, err := c.GetSecret(context.TODO(), "authcrunch/users/mjones"); err == nil {
+			t.Fatalf("expected error for missing secret, got success")
+		}
+		if diff := cmp.Diff(2, len(recorder.Interactions())); diff != "" {
+			t.Errorf("Interactions() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	b, err := ioutil.ReadFile(fixturePath)
+	if err != nil {
+		t.Fatalf("failed reading fixture: %v", err)
+	}
+	if strings.Contains(string(b), "foobar") {
+		t.Fatalf("fixture contains the secret value:\n%s", b)
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		replayer := NewReplayer(t, fixturePath)
+		c := newReplayTestClient(t, secrets.WithHTTPClient(replayer), "http://127.0.0.1:1")
+		got, err := c.GetSecret(context.TODO(), "authcrunch/users/jsmith")
+		if err != nil {
+			t.Fatalf("expected success, got: %v", err)
+		}
+		want := map[string]interface{}{"username": "REDACTED", "password": "REDACTED"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+		}
+		if _, err := c.GetSecret(context.TODO(), "authcrunch/users/mjones"); err == nil {
+			t.Fatalf("expected error for missing secret, got success")
+		}
+		if _, err := c.GetSecret(context.TODO(), "authcrunch/users/admin"); err == nil {
+			t.Fatalf("expected error for unrecorded request, got success")
+		}
+		if diff := cmp.Diff(0, replayer.Remaining()); diff != "" {
+			t.Errorf("Remaining() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestSanitize(t *testing.T) {
+	testcases := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "sanitize secret value and account id",
+			body: `{"ARN":"arn:aws:secretsmanager:us-east-1:210987654321:secret:foo-tz6d06","SecretString":"{\"password\":\"foobar\"}"}`,
+			want: `{"ARN":"arn:aws:secretsmanager:us-east-1:123456789012:secret:foo-tz6d06","SecretString":"{\"password\":\"REDACTED\"}"}`,
+		},
+		{
+			name: "sanitize plain secret value and request token",
+			body: `{"ClientRequestToken":"4f1f5a0e","SecretId":"foo","SecretString":"foobar"}`,
+			want: `{"SecretId":"foo","SecretString":"REDACTED"}`,
+		},
+		{
+			name: "sanitize nested secret values",
+			body: `{"SecretValues":[{"Name":"foo","SecretString":"foobar"}]}`,
+			want: `{"SecretValues":[{"Name":"foo","SecretString":"REDACTED"}]}`,
+		},
+		{
+			name: "sanitize temporary credentials",
+			body: `<Credentials><AccessKeyId>ASIAFOO</AccessKeyId><SecretAccessKey>bar</SecretAccessKey><SessionToken>baz</SessionToken></Credentials>`,
+			want: `<Credentials><AccessKeyId>REDACTED</AccessKeyId><SecretAccessKey>REDACTED</SecretAccessKey><SessionToken>REDACTED</SessionToken></Credentials>`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, sanitize(tc.body)); diff != "" {
+				t.Errorf("sanitize() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretstest
+
+import (
+	"net/http"
+
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// JSONResponse returns the successful response with the value encoded as
+// JSON body. See secretsmock.JSONResponse.
+func JSONResponse(v interface{}) *http.Response {
+	return secretsmock.JSONResponse(v)
+}
+
+// SecretStringResponse returns the response of GetSecretValue operation
+// with the key-value map as the secret string. See
+// secretsmock.SecretStringResponse.
+func SecretStringResponse(m map[string]interface{}) *http.Response {
+	return secretsmock.SecretStringResponse(m)
+}
+
+// ErrorResponse returns the error response with the request ID. See
+// secretsmock.ErrorResponse.
+func ErrorResponse(requestID string, statusCode int, code, message string) *http.Response {
+	return secretsmock.ErrorResponse(requestID, statusCode, code, message)
+}
+
+// NotFoundResponse returns ResourceNotFoundException response with the
+// request ID. See secretsmock.NotFoundResponse.
+func NotFoundResponse(requestID string) *http.Response {
+	return secretsmock.NotFoundResponse(requestID)
+}
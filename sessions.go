@@ -0,0 +1,98 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+)
+
+// SessionKey is AES key used to encrypt session cookies.
+type SessionKey struct {
+	ID    string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Value []byte `json:"-" xml:"-" yaml:"-"`
+}
+
+// SessionKeys holds the current and the previous session keys. The current
+// key encrypts new sessions, while both keys decrypt the existing ones.
+type SessionKeys struct {
+	Current  *SessionKey `json:"current,omitempty" xml:"current,omitempty" yaml:"current,omitempty"`
+	Previous *SessionKey `json:"previous,omitempty" xml:"previous,omitempty" yaml:"previous,omitempty"`
+}
+
+// Lookup returns the key with the ID, or nil when the key is not found.
+func (keys *SessionKeys) Lookup(id string) *SessionKey {
+	for _, key := range []*SessionKey{keys.Current, keys.Previous} {
+		if key != nil && key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// GetSessionKeys returns the session encryption keys stored in the id and
+// value keys of the AWSCURRENT and AWSPREVIOUS versions of the secret. The
+// value is base64-encoded AES-128, AES-192, or AES-256 key. The previous
+// key is nil when the secret has not been rotated yet, or the key ID did
+// not change during the rotation.
+func (c *client) GetSessionKeys(ctx context.Context, path string) (*SessionKeys, error) {
+	m, err := c.getSecretValue(ctx, path, versionStageCurrent)
+	if err != nil {
+		return nil, err
+	}
+	current, err := parseSessionKey(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed current version of %q secret: %v", path, err)
+	}
+	keys := &SessionKeys{Current: current}
+
+	m, err = c.getSecretValue(ctx, path, versionStagePrevious)
+	if err != nil {
+		if isNotFound(err) {
+			return keys, nil
+		}
+		return nil, err
+	}
+	previous, err := parseSessionKey(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed previous version of %q secret: %v", path, err)
+	}
+	if previous.ID != current.ID {
+		keys.Previous = previous
+	}
+	return keys, nil
+}
+
+func parseSessionKey(m map[string]interface{}) (*SessionKey, error) {
+	key := &SessionKey{}
+	var err error
+	if key.ID, err = getStringValue(m, "id", true); err != nil {
+		return nil, err
+	}
+	s, err := getStringValue(m, "value", true)
+	if err != nil {
+		return nil, err
+	}
+	if key.Value, err = base64.StdEncoding.DecodeString(s); err != nil {
+		return nil, fmt.Errorf("key %q value is not base64 encoded", "value")
+	}
+	switch len(key.Value) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("key %q value has unsupported %d-byte key length", "value", len(key.Value))
+	}
+	return key, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetSessionKeys(t *testing.T) {
+	path := "authcrunch/caddy/session_key"
+	key1 := bytes.Repeat([]byte{1}, 32)
+	key2 := bytes.Repeat([]byte{2}, 32)
+
+	testcases := []struct {
+		name      string
+		stages    map[string]map[string]interface{}
+		want      *SessionKeys
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test rotated session keys",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"id":    "session/2",
+					"value": base64.StdEncoding.EncodeToString(key2),
+				},
+				"AWSPREVIOUS": {
+					"id":    "session/1",
+					"value": base64.StdEncoding.EncodeToString(key1),
+				},
+			},
+			want: &SessionKeys{
+				Current:  &SessionKey{ID: "session/2", Value: key2},
+				Previous: &SessionKey{ID: "session/1", Value: key1},
+			},
+		},
+		{
+			name: "test session keys without previous version",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"id":    "session/1",
+					"value": base64.StdEncoding.EncodeToString(key1),
+				},
+			},
+			want: &SessionKeys{
+				Current: &SessionKey{ID: "session/1", Value: key1},
+			},
+		},
+		{
+			name: "test session keys with unchanged key id",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"id":    "session/1",
+					"value": base64.StdEncoding.EncodeToString(key1),
+				},
+				"AWSPREVIOUS": {
+					"id":    "session/1",
+					"value": base64.StdEncoding.EncodeToString(key2),
+				},
+			},
+			want: &SessionKeys{
+				Current: &SessionKey{ID: "session/1", Value: key1},
+			},
+		},
+		{
+			name: "test session key without id",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"value": base64.StdEncoding.EncodeToString(key1),
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed current version of %q secret: key %q not found", path, "id"),
+		},
+		{
+			name: "test session key that is not base64 encoded",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"id":    "session/1",
+					"value": "foo!",
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed current version of %q secret: key %q value is not base64 encoded", path, "value"),
+		},
+		{
+			name: "test previous session key with unsupported length",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"id":    "session/2",
+					"value": base64.StdEncoding.EncodeToString(key2),
+				},
+				"AWSPREVIOUS": {
+					"id":    "session/1",
+					"value": base64.StdEncoding.EncodeToString(key1[:20]),
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed previous version of %q secret: key %q value has unsupported %d-byte key length", path, "value", 20),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+
+			c.SetMockClient(newStagedMockClient(t, tc.stages))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetSessionKeys(context.TODO(), path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("GetSessionKeys() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetSessionKeys() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSessionKeysLookup(t *testing.T) {
+	keys := &SessionKeys{
+		Current:  &SessionKey{ID: "session/2"},
+		Previous: &SessionKey{ID: "session/1"},
+	}
+	for _, id := range []string{"session/1", "session/2"} {
+		if key := keys.Lookup(id); key == nil || key.ID != id {
+			t.Errorf("Lookup(%q) returned %v", id, key)
+		}
+	}
+	if key := keys.Lookup("session/0"); key != nil {
+		t.Errorf("Lookup(%q) returned %v, want nil", "session/0", key)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"time"
+)
+
+const defaultSimpleTimeout = 30 * time.Second
+
+// SimpleClient is the context-free view of a Client for the consumers
+// of the API preceding the contexts, e.g. GetSecret(path). Every call is
+// bounded by the default timeout of the client.
+type SimpleClient struct {
+	c Client
+}
+
+// NewSimpleClient returns the context-free view of the client.
+func NewSimpleClient(c Client) *SimpleClient {
+	return &SimpleClient{c: c}
+}
+
+// Simple returns the context-free view of the client.
+func (c *client) Simple() *SimpleClient {
+	return NewSimpleClient(c)
+}
+
+// timeout returns the default timeout of the client. The configuration
+// is read on every call, so that the reconfigured timeout applies.
+func (s *SimpleClient) timeout() time.Duration {
+	cfg := s.c.GetConfig(context.Background())
+	if cfg == nil || cfg.DefaultTimeout == "" {
+		return defaultSimpleTimeout
+	}
+	d, err := time.ParseDuration(cfg.DefaultTimeout)
+	if err != nil || d <= 0 {
+		return defaultSimpleTimeout
+	}
+	return d
+}
+
+// GetSecret returns the secret at the path.
+func (s *SimpleClient) GetSecret(path string, opts ...CallOption) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	return s.c.GetSecret(ctx, path, opts...)
+}
+
+// GetSecretByKey returns the value of the key of the secret at the path.
+func (s *SimpleClient) GetSecretByKey(path, key string, opts ...CallOption) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	return s.c.GetSecretByKey(ctx, path, key, opts...)
+}
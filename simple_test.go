@@ -0,0 +1,74 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSimpleClient(t *testing.T) {
+	stages := map[string]map[string]interface{}{
+		"AWSCURRENT": {"username": "jsmith", "password": "foo"},
+	}
+	testcases := []struct {
+		name        string
+		opts        []Option
+		wantTimeout time.Duration
+	}{
+		{
+			name:        "test default timeout",
+			wantTimeout: defaultSimpleTimeout,
+		},
+		{
+			name:        "test configured timeout",
+			opts:        []Option{WithDefaultTimeout(5 * time.Second)},
+			wantTimeout: 5 * time.Second,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithID("foo"), WithRegion("us-east-1")}, tc.opts...)
+			c, err := NewClient(context.TODO(), opts...)
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, stages))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			s := c.Simple()
+			if got := s.timeout(); got != tc.wantTimeout {
+				t.Errorf("unexpected timeout: got %s, want %s", got, tc.wantTimeout)
+			}
+			m, err := s.GetSecret("authcrunch/ldap")
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(stages["AWSCURRENT"], m); diff != "" {
+				t.Errorf("GetSecret() mismatch (-want +got):\n%s", diff)
+			}
+			v, err := s.GetSecretByKey("authcrunch/ldap", "username")
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if v != "jsmith" {
+				t.Errorf("unexpected value: %v", v)
+			}
+		})
+	}
+}
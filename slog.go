@@ -0,0 +1,115 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewSlogLogger returns the zap logger writing the entries to the slog
+// handler, so that the applications standardized on log/slog receive the
+// structured logs of the client, e.g.
+//
+//	c.SetLogger(secrets.NewSlogLogger(slog.Default().Handler()))
+func NewSlogLogger(handler slog.Handler) *zap.Logger {
+	return zap.New(&slogCore{handler: handler})
+}
+
+// WithSlogLogger sets the logger writing to the slog logger.
+func WithSlogLogger(logger *slog.Logger) Option {
+	return func(c *client) error {
+		if logger == nil {
+			return errors.New("logger is nil")
+		}
+		c.logger = NewSlogLogger(logger.Handler())
+		return nil
+	}
+}
+
+// slogCore is the zap core forwarding the entries to the slog handler.
+type slogCore struct {
+	handler slog.Handler
+}
+
+// Enabled implements zapcore.LevelEnabler interface.
+func (c *slogCore) Enabled(lvl zapcore.Level) bool {
+	return c.handler.Enabled(context.Background(), slogLevel(lvl))
+}
+
+// With implements zapcore.Core interface.
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	if len(fields) == 0 {
+		return c
+	}
+	return &slogCore{handler: c.handler.WithAttrs(slogAttrs(fields))}
+}
+
+// Check implements zapcore.Core interface.
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core interface.
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(ent.Time, slogLevel(ent.Level), ent.Message, 0)
+	if ent.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", ent.LoggerName))
+	}
+	r.AddAttrs(slogAttrs(fields)...)
+	return c.handler.Handle(context.Background(), r)
+}
+
+// Sync implements zapcore.Core interface.
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+// slogLevel maps the zap level to the slog one. The levels above error,
+// i.e. dpanic, panic, and fatal, map to error.
+func slogLevel(lvl zapcore.Level) slog.Level {
+	switch {
+	case lvl <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case lvl == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case lvl == zapcore.WarnLevel:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+// slogAttrs converts the zap fields to the slog attributes, preserving
+// their order.
+func slogAttrs(fields []zapcore.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		for k, v := range enc.Fields {
+			attrs = append(attrs, slog.Any(k, v))
+		}
+	}
+	return attrs
+}
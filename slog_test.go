@@ -0,0 +1,92 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger := NewSlogLogger(handler).Named("secrets").With(zap.String("client_id", "foo"))
+
+	logger.Debug("secret cached", zap.String("path", "authcrunch/ldap"))
+	logger.Warn("failed notifying webhook",
+		zap.String("path", "authcrunch/ldap"),
+		zap.Int("status_code", 500),
+		zap.Error(errors.New("connection refused")),
+	)
+
+	var got []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		m := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("failed parsing log entry %q: %v", line, err)
+		}
+		got = append(got, m)
+	}
+	want := []map[string]interface{}{
+		{
+			"level":       "WARN",
+			"msg":         "failed notifying webhook",
+			"logger":      "secrets",
+			"client_id":   "foo",
+			"path":        "authcrunch/ldap",
+			"status_code": float64(500),
+			"error":       "connection refused",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("log entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := NewClient(context.TODO(),
+		WithSlogLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+		WithConfig(&ClientConfig{ID: "foo", Region: "us-east-1", Deprecations: []string{"foo is deprecated"}}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	if !strings.Contains(buf.String(), `msg="deprecated config" client_id=foo warning="foo is deprecated"`) {
+		t.Errorf("unexpected log output: %q", buf.String())
+	}
+
+	if _, err := NewClient(context.TODO(), WithSlogLogger(nil)); err == nil {
+		t.Fatalf("unexpected success, want: logger is nil")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// SMTPTLSStartTLS upgrades plain connection with STARTTLS command.
+	SMTPTLSStartTLS = "starttls"
+	// SMTPTLSImplicit establishes TLS connection right away.
+	SMTPTLSImplicit = "tls"
+	// SMTPTLSNone disables TLS.
+	SMTPTLSNone = "none"
+)
+
+var smtpDefaultPorts = map[string]int{
+	SMTPTLSStartTLS: 587,
+	SMTPTLSImplicit: 465,
+	SMTPTLSNone:     25,
+}
+
+// SMTPCredentials holds the settings of SMTP server used to send email
+// messages.
+type SMTPCredentials struct {
+	Host     string          `json:"host,omitempty" xml:"host,omitempty" yaml:"host,omitempty"`
+	Port     int             `json:"port,omitempty" xml:"port,omitempty" yaml:"port,omitempty"`
+	Username string          `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Password string          `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+	TLS      *SMTPTLSOptions `json:"tls,omitempty" xml:"tls,omitempty" yaml:"tls,omitempty"`
+}
+
+// SMTPTLSOptions holds TLS settings of SMTP connection.
+type SMTPTLSOptions struct {
+	// Mode is one of starttls, tls, or none.
+	Mode               string `json:"mode,omitempty" xml:"mode,omitempty" yaml:"mode,omitempty"`
+	ServerName         string `json:"server_name,omitempty" xml:"server_name,omitempty" yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty" xml:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+}
+
+// GetSMTPCredentials returns the SMTP settings stored in the host, port,
+// username, password, tls, tls_server_name, and tls_insecure_skip_verify
+// keys of the secret. The host is required. The TLS mode defaults to
+// starttls and the port defaults to the standard port of the TLS mode.
+func (c *client) GetSMTPCredentials(ctx context.Context, path string) (*SMTPCredentials, error) {
+	m, err := c.GetSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := parseSMTPCredentials(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	return creds, nil
+}
+
+func parseSMTPCredentials(m map[string]interface{}) (*SMTPCredentials, error) {
+	creds := &SMTPCredentials{TLS: &SMTPTLSOptions{}}
+	var err error
+	if creds.Host, err = getStringValue(m, "host", true); err != nil {
+		return nil, err
+	}
+	if creds.Username, err = getStringValue(m, "username", false); err != nil {
+		return nil, err
+	}
+	if creds.Password, err = getStringValue(m, "password", false); err != nil {
+		return nil, err
+	}
+	if creds.TLS.Mode, err = getStringValue(m, "tls", false); err != nil {
+		return nil, err
+	}
+	if creds.TLS.Mode == "" {
+		creds.TLS.Mode = SMTPTLSStartTLS
+	}
+	if _, exists := smtpDefaultPorts[creds.TLS.Mode]; !exists {
+		return nil, fmt.Errorf("unsupported %q tls mode", creds.TLS.Mode)
+	}
+	if creds.TLS.ServerName, err = getStringValue(m, "tls_server_name", false); err != nil {
+		return nil, err
+	}
+	if creds.TLS.InsecureSkipVerify, err = getBoolValue(m, "tls_insecure_skip_verify"); err != nil {
+		return nil, err
+	}
+	if creds.Port, err = getIntValue(m, "port"); err != nil {
+		return nil, err
+	}
+	if creds.Port == 0 {
+		creds.Port = smtpDefaultPorts[creds.TLS.Mode]
+	}
+	if creds.Port < 0 || creds.Port > 65535 {
+		return nil, fmt.Errorf("key %q value %d is out of range", "port", creds.Port)
+	}
+	return creds, nil
+}
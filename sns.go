@@ -0,0 +1,315 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.uber.org/zap"
+)
+
+const (
+	snsNotification             = "Notification"
+	snsSubscriptionConfirmation = "SubscriptionConfirmation"
+	snsUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+	snsMaxMessageSize           = 256 * 1024
+)
+
+var (
+	snsHostRgx *regexp.Regexp = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+)
+
+// snsMessage is a message delivered by Amazon SNS to HTTP endpoints and,
+// unless raw message delivery is enabled, to SQS queues.
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token,omitempty"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject,omitempty"`
+	Message          string `json:"Message"`
+	SubscribeURL     string `json:"SubscribeURL,omitempty"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+}
+
+// stringToSign returns the canonical form of the message signed by SNS.
+func (m *snsMessage) stringToSign() string {
+	var sb strings.Builder
+	add := func(k, v string) {
+		sb.WriteString(k + "\n" + v + "\n")
+	}
+	add("Message", m.Message)
+	add("MessageId", m.MessageID)
+	if m.Type == snsNotification {
+		if m.Subject != "" {
+			add("Subject", m.Subject)
+		}
+	} else {
+		add("SubscribeURL", m.SubscribeURL)
+	}
+	add("Timestamp", m.Timestamp)
+	if m.Type != snsNotification {
+		add("Token", m.Token)
+	}
+	add("TopicArn", m.TopicArn)
+	add("Type", m.Type)
+	return sb.String()
+}
+
+// parseSNSEnvelope returns the message wrapped in SNS notification. When
+// the provided string is not SNS notification, it is returned unchanged.
+func parseSNSEnvelope(s string) string {
+	var m snsMessage
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return s
+	}
+	if m.Type != snsNotification || m.TopicArn == "" {
+		return s
+	}
+	return m.Message
+}
+
+// snsHandler receives the notifications from SNS topic.
+type snsHandler struct {
+	ctx      context.Context
+	client   *client
+	topicARN string
+	// mu guards the channel, which is closed when the context is done.
+	mu      sync.RWMutex
+	ch      chan SecretEvent
+	certsMu sync.Mutex
+	certs   map[string]*x509.Certificate
+}
+
+// SNSHandler returns HTTP handler for the endpoint subscribed to the SNS
+// topic carrying AWS Secrets Manager events, and the channel with the
+// corresponding secret events. The handler confirms the subscription to
+// the topic, verifies the signatures of the messages, and ignores the
+// messages from other topics. The cached versions of the affected secrets
+// are invalidated the same way as by ListenSQS. The channel is closed when
+// the context is done or the client is closed.
+func (c *client) SNSHandler(ctx context.Context, topicARN string) (http.Handler, <-chan SecretEvent, error) {
+	if topicARN == "" {
+		return nil, nil, errors.New("sns topic arn is empty")
+	}
+	ch := make(chan SecretEvent)
+	h := &snsHandler{
+		client:   c,
+		topicARN: topicARN,
+		ch:       ch,
+		certs:    make(map[string]*x509.Certificate),
+	}
+	ctx, err := c.goBackground(ctx, func(ctx context.Context) {
+		<-ctx.Done()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		close(h.ch)
+		h.ch = nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	h.ctx = ctx
+	return h, ch, nil
+}
+
+// ServeHTTP handles the messages delivered by SNS.
+func (h *snsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	log := h.client.getLogger()
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, snsMaxMessageSize+1))
+	if err != nil || len(b) > snsMaxMessageSize {
+		http.Error(w, "malformed message", http.StatusBadRequest)
+		return
+	}
+	var m snsMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		http.Error(w, "malformed message", http.StatusBadRequest)
+		return
+	}
+	if m.TopicArn != h.topicARN {
+		log.Warn("received sns message from unexpected topic", zap.String("topic_arn", m.TopicArn))
+		http.Error(w, "unexpected topic", http.StatusForbidden)
+		return
+	}
+	if err := h.verify(r.Context(), &m); err != nil {
+		log.Warn("failed verifying sns message", zap.String("message_id", m.MessageID), zap.Error(err))
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	switch m.Type {
+	case snsSubscriptionConfirmation:
+		if err := h.confirmSubscription(r.Context(), &m); err != nil {
+			log.Warn("failed confirming sns subscription", zap.String("topic_arn", m.TopicArn), zap.Error(err))
+			http.Error(w, "subscription confirmation failed", http.StatusBadGateway)
+			return
+		}
+	case snsNotification:
+		ev, err := parseSecretsManagerEvent(m.Message)
+		if err != nil {
+			log.Warn("failed parsing sns message", zap.String("message_id", m.MessageID), zap.Error(err))
+			break
+		}
+		if !h.client.applySecretEvent(r.Context(), ev) {
+			break
+		}
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		if h.ch == nil {
+			http.Error(w, "handler is closed", http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case h.ch <- *ev:
+		case <-r.Context().Done():
+			http.Error(w, "request cancelled", http.StatusServiceUnavailable)
+			return
+		case <-h.ctx.Done():
+			http.Error(w, "handler is closed", http.StatusServiceUnavailable)
+			return
+		}
+	case snsUnsubscribeConfirmation:
+		log.Info("sns subscription cancelled", zap.String("topic_arn", m.TopicArn))
+	default:
+		http.Error(w, "unsupported message type", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks the signature of the message.
+func (h *snsHandler) verify(ctx context.Context, m *snsMessage) error {
+	var hash crypto.Hash
+	var digest []byte
+	switch m.SignatureVersion {
+	case "1":
+		sum := sha1.Sum([]byte(m.stringToSign()))
+		hash, digest = crypto.SHA1, sum[:]
+	case "2":
+		sum := sha256.Sum256([]byte(m.stringToSign()))
+		hash, digest = crypto.SHA256, sum[:]
+	default:
+		return fmt.Errorf("unsupported %q signature version", m.SignatureVersion)
+	}
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %v", err)
+	}
+	cert, err := h.getCertificate(ctx, m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported %T signing key", cert.PublicKey)
+	}
+	return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+}
+
+// getCertificate returns the signing certificate. The certificates are
+// fetched from SNS endpoints only and cached.
+func (h *snsHandler) getCertificate(ctx context.Context, s string) (*x509.Certificate, error) {
+	h.certsMu.Lock()
+	cert := h.certs[s]
+	h.certsMu.Unlock()
+	if cert != nil {
+		return cert, nil
+	}
+	b, err := h.get(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("malformed %q signing certificate", s)
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q signing certificate: %v", s, err)
+	}
+	h.certsMu.Lock()
+	h.certs[s] = cert
+	h.certsMu.Unlock()
+	return cert, nil
+}
+
+// confirmSubscription visits the subscription confirmation URL.
+func (h *snsHandler) confirmSubscription(ctx context.Context, m *snsMessage) error {
+	if _, err := h.get(ctx, m.SubscribeURL); err != nil {
+		return err
+	}
+	h.client.getLogger().Info("sns subscription confirmed", zap.String("topic_arn", m.TopicArn))
+	return nil
+}
+
+// get fetches the resource from SNS endpoint.
+func (h *snsHandler) get(ctx context.Context, s string) ([]byte, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "https" || !snsHostRgx.MatchString(u.Host) {
+		return nil, fmt.Errorf("untrusted %q sns url", s)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.client.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected %d status code from %q", resp.StatusCode, u.Host)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, snsMaxMessageSize))
+}
+
+// getHTTPClient returns the HTTP client used outside of AWS SDK.
+func (c *client) getHTTPClient() aws.HTTPClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.httpClient != nil {
+		httpClient, _ := hardenHTTPClient(c.config.TLS, c.httpClient)
+		return httpClient
+	}
+	if c.config.TLS != nil {
+		return hardenedDefaultClient(c.config.TLS)
+	}
+	return http.DefaultClient
+}
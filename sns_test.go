@@ -0,0 +1,287 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	testSNSTopicARN       = "arn:aws:sns:us-east-1:123456789012:authcrunch"
+	testSNSSigningCertURL = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-0000000000000000000000.pem"
+	testSNSSubscribeURL   = "https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription&TopicArn=" + testSNSTopicARN + "&Token=foo"
+)
+
+// newSNSSigningKey returns the key and the PEM-encoded certificate used to
+// sign SNS messages.
+func newSNSSigningKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed generating certificate: %v", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// signSNSMessage returns JSON-encoded SNS message signed with the key.
+func signSNSMessage(t *testing.T, key *rsa.PrivateKey, m *snsMessage) string {
+	m.SignatureVersion = "2"
+	if m.SigningCertURL == "" {
+		m.SigningCertURL = testSNSSigningCertURL
+	}
+	digest := sha256.Sum256([]byte(m.stringToSign()))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed signing message: %v", err)
+	}
+	m.Signature = base64.StdEncoding.EncodeToString(signature)
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("failed encoding message: %v", err)
+	}
+	return string(b)
+}
+
+// newSNSMockClient returns mock HTTP client serving the signing certificate
+// and recording the visited URLs.
+func newSNSMockClient(cert []byte, visited *[]string) smithyhttp.ClientDoFunc {
+	var mu sync.Mutex
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		*visited = append(*visited, r.URL.String())
+		mu.Unlock()
+		body := "<ConfirmSubscriptionResponse/>"
+		if r.URL.String() == testSNSSigningCertURL {
+			body = string(cert)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+}
+
+func TestSNSHandler(t *testing.T) {
+	key, cert := newSNSSigningKey(t)
+	otherKey, _ := newSNSSigningKey(t)
+	event := `{"source": "aws.secretsmanager", "detail": {"eventName": "PutSecretValue", "requestParameters": {"secretId": "authcrunch/caddy/access_token"}}}`
+
+	testcases := []struct {
+		name        string
+		method      string
+		data        string
+		wantStatus  int
+		wantEvent   *SecretEvent
+		wantVisited []string
+	}{
+		{
+			name:   "test notification",
+			method: http.MethodPost,
+			data: signSNSMessage(t, key, &snsMessage{
+				Type:      snsNotification,
+				MessageID: "1",
+				TopicArn:  testSNSTopicARN,
+				Message:   event,
+				Timestamp: "2022-12-01T00:00:00.000Z",
+			}),
+			wantStatus:  http.StatusOK,
+			wantEvent:   &SecretEvent{Type: SecretUpdated, Path: "authcrunch/caddy/access_token"},
+			wantVisited: []string{testSNSSigningCertURL},
+		},
+		{
+			name:   "test subscription confirmation",
+			method: http.MethodPost,
+			data: signSNSMessage(t, key, &snsMessage{
+				Type:         snsSubscriptionConfirmation,
+				MessageID:    "2",
+				Token:        "foo",
+				TopicArn:     testSNSTopicARN,
+				Message:      "You have chosen to subscribe to the topic.",
+				SubscribeURL: testSNSSubscribeURL,
+				Timestamp:    "2022-12-01T00:00:00.000Z",
+			}),
+			wantStatus:  http.StatusOK,
+			wantVisited: []string{testSNSSigningCertURL, testSNSSubscribeURL},
+		},
+		{
+			name:   "test notification from unexpected topic",
+			method: http.MethodPost,
+			data: signSNSMessage(t, key, &snsMessage{
+				Type:      snsNotification,
+				MessageID: "3",
+				TopicArn:  "arn:aws:sns:us-east-1:123456789012:other",
+				Message:   event,
+				Timestamp: "2022-12-01T00:00:00.000Z",
+			}),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "test notification with invalid signature",
+			method: http.MethodPost,
+			data: signSNSMessage(t, otherKey, &snsMessage{
+				Type:      snsNotification,
+				MessageID: "4",
+				TopicArn:  testSNSTopicARN,
+				Message:   event,
+				Timestamp: "2022-12-01T00:00:00.000Z",
+			}),
+			wantStatus:  http.StatusForbidden,
+			wantVisited: []string{testSNSSigningCertURL},
+		},
+		{
+			name:   "test notification with untrusted signing certificate url",
+			method: http.MethodPost,
+			data: signSNSMessage(t, key, &snsMessage{
+				Type:           snsNotification,
+				MessageID:      "5",
+				TopicArn:       testSNSTopicARN,
+				Message:        event,
+				Timestamp:      "2022-12-01T00:00:00.000Z",
+				SigningCertURL: "https://sns.us-east-1.amazonaws.com.example.com/cert.pem",
+			}),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "test malformed message",
+			method:     http.MethodPost,
+			data:       "foo",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "test unsupported method",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			var visited []string
+			c.SetMockClient(newSNSMockClient(cert, &visited))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			h, ch, err := c.SNSHandler(ctx, testSNSTopicARN)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got *SecretEvent
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ev := range ch {
+					ev := ev
+					got = &ev
+				}
+			}()
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tc.method, "/sns", strings.NewReader(tc.data)))
+			cancel()
+			wg.Wait()
+
+			if diff := cmp.Diff(tc.wantStatus, w.Code); diff != "" {
+				t.Errorf("ServeHTTP() status mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantEvent, got); diff != "" {
+				t.Errorf("ServeHTTP() event mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantVisited, visited); diff != "" {
+				t.Errorf("ServeHTTP() visited urls mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSNSHandlerWithoutTopic(t *testing.T) {
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	_, _, err = c.SNSHandler(context.TODO(), "")
+	if diff := cmp.Diff(errors.New("sns topic arn is empty").Error(), err.Error()); diff != "" {
+		t.Errorf("SNSHandler() error mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseSNSEnvelope(t *testing.T) {
+	event := `{"source": "aws.secretsmanager"}`
+	testcases := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "test sns notification",
+			data: `{"Type": "Notification", "TopicArn": "` + testSNSTopicARN + `", "Message": "{\"source\": \"aws.secretsmanager\"}"}`,
+			want: event,
+		},
+		{
+			name: "test raw message",
+			data: event,
+			want: event,
+		},
+		{
+			name: "test malformed message",
+			data: "foo",
+			want: "foo",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseSNSEnvelope(tc.data)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseSNSEnvelope() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"go.uber.org/zap"
+)
+
+const (
+	sqsWaitTimeSeconds     = 20
+	sqsMaxNumberOfMessages = 10
+	sqsRetryInterval       = 5 * time.Second
+	secretsManagerSource   = "aws.secretsmanager"
+)
+
+// secretsManagerEventTypes maps the names of AWS Secrets Manager API calls
+// and service events to the types of secret changes.
+var secretsManagerEventTypes = map[string]SecretEventType{
+	"CreateSecret":             SecretAdded,
+	"PutSecretValue":           SecretUpdated,
+	"UpdateSecret":             SecretUpdated,
+	"UpdateSecretVersionStage": SecretUpdated,
+	"RestoreSecret":            SecretUpdated,
+	"RotationSucceeded":        SecretUpdated,
+	"DeleteSecret":             SecretDeleted,
+}
+
+// secretsManagerEvent is an EventBridge event emitted by AWS Secrets Manager,
+// either directly or via CloudTrail.
+type secretsManagerEvent struct {
+	Source string `json:"source"`
+	Detail struct {
+		EventName         string `json:"eventName"`
+		RequestParameters struct {
+			SecretID string `json:"secretId"`
+			Name     string `json:"name"`
+		} `json:"requestParameters"`
+		AdditionalEventData struct {
+			SecretID string `json:"SecretId"`
+		} `json:"additionalEventData"`
+	} `json:"detail"`
+}
+
+// ListenSQS receives the AWS Secrets Manager events, delivered by an
+// EventBridge rule to the SQS queue, and emits the corresponding secret
+// events. The events wrapped in SNS notifications, when the queue is
+// subscribed to SNS topic, are unwrapped. The cached versions of the
+// affected secrets are invalidated. When an updated secret was in the
+// cache, it is retrieved again and included in the event. The messages
+// are deleted from the queue once processed, including the ones that are
+// not recognized. The events for the secrets outside of the base prefix
+// are skipped. The channel is closed when the context is done or the
+// client is closed.
+func (c *client) ListenSQS(ctx context.Context, queueURL string) (<-chan SecretEvent, error) {
+	if queueURL == "" {
+		return nil, errors.New("sqs queue url is empty")
+	}
+	c.mu.RLock()
+	queue := sqs.NewFromConfig(c.serviceConfig)
+	c.mu.RUnlock()
+	ch := make(chan SecretEvent)
+	if _, err := c.goBackground(ctx, func(ctx context.Context) {
+		c.listenSQS(ctx, queue, queueURL, ch)
+	}); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (c *client) listenSQS(ctx context.Context, queue *sqs.Client, queueURL string, ch chan<- SecretEvent) {
+	defer close(ch)
+	for {
+		output, err := queue.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queueURL),
+			MaxNumberOfMessages: sqsMaxNumberOfMessages,
+			WaitTimeSeconds:     sqsWaitTimeSeconds,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.getLogger().Warn("failed receiving sqs messages", zap.String("queue_url", queueURL), zap.Error(err))
+			timer := time.NewTimer(sqsRetryInterval)
+			select {
+			case <-timer.C:
+				continue
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+		for _, msg := range output.Messages {
+			ev, err := parseSecretsManagerEvent(parseSNSEnvelope(aws.ToString(msg.Body)))
+			if err != nil {
+				c.getLogger().Warn("failed parsing sqs message",
+					zap.String("queue_url", queueURL),
+					zap.String("message_id", aws.ToString(msg.MessageId)),
+					zap.Error(err),
+				)
+			} else if !c.applySecretEvent(ctx, ev) {
+				ev = nil
+			}
+			if _, err := queue.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(queueURL),
+				ReceiptHandle: msg.ReceiptHandle,
+			}); err != nil && ctx.Err() == nil {
+				c.getLogger().Warn("failed deleting sqs message",
+					zap.String("queue_url", queueURL),
+					zap.String("message_id", aws.ToString(msg.MessageId)),
+					zap.Error(err),
+				)
+			}
+			if ev == nil {
+				continue
+			}
+			select {
+			case ch <- *ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// applySecretEvent invalidates the cached versions of the secret, notifies
+// the webhook and, for the updates of the cached secrets, retrieves the
+// new secret value. The path of the event is made relative to the base
+// prefix. It returns false when the secret is outside of the base prefix.
+func (c *client) applySecretEvent(ctx context.Context, ev *SecretEvent) bool {
+	path, ok := c.getConfig().relativePath(ev.Path)
+	if !ok {
+		return false
+	}
+	ev.Path = path
+	defer c.notifyWebhook(ctx, ev)
+	cached := c.getCache().invalidate(ev.Path)
+	if !cached || ev.Type == SecretDeleted {
+		return true
+	}
+	m, err := c.fetchSecret(ctx, &secretRequest{path: ev.Path, stage: versionStageCurrent, skipCache: true, refresh: true})
+	if err != nil {
+		if ctx.Err() == nil {
+			c.getLogger().Warn("failed refreshing cached secret", zap.String("path", ev.Path), zap.Error(err))
+		}
+		return true
+	}
+	ev.Secret = m
+	return true
+}
+
+// parseSecretsManagerEvent returns the secret event for the EventBridge
+// event emitted by AWS Secrets Manager.
+func parseSecretsManagerEvent(s string) (*SecretEvent, error) {
+	var event secretsManagerEvent
+	if err := json.Unmarshal([]byte(s), &event); err != nil {
+		return nil, fmt.Errorf("malformed event: %v", err)
+	}
+	if event.Source != secretsManagerSource {
+		return nil, fmt.Errorf("unsupported %q event source", event.Source)
+	}
+	eventType, exists := secretsManagerEventTypes[event.Detail.EventName]
+	if !exists {
+		return nil, fmt.Errorf("unsupported %q event name", event.Detail.EventName)
+	}
+	secretID := event.Detail.RequestParameters.SecretID
+	if secretID == "" {
+		secretID = event.Detail.AdditionalEventData.SecretID
+	}
+	if secretID == "" {
+		secretID = event.Detail.RequestParameters.Name
+	}
+	if secretID == "" {
+		return nil, fmt.Errorf("secret id not found in %q event", event.Detail.EventName)
+	}
+	return &SecretEvent{Type: eventType, Path: secretNameFromID(secretID)}, nil
+}
+
+// secretNameFromID returns the name of the secret referenced by either
+// its name or ARN. The ARN of a secret ends with the name followed by
+// a hyphen and six random characters.
+func secretNameFromID(id string) string {
+	if !strings.HasPrefix(id, "arn:") {
+		return id
+	}
+	parts := strings.SplitN(id, ":", 7)
+	if len(parts) != 7 || parts[5] != "secret" {
+		return id
+	}
+	name := parts[6]
+	if i := len(name) - 7; i > 0 && name[i] == '-' {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,224 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newSQSMockClient returns mock HTTP client serving the provided messages
+// from SQS queue, followed by empty responses. The requests to AWS Secrets
+// Manager are passed to the secrets client.
+func newSQSMockClient(t *testing.T, messages []string, secrets smithyhttp.ClientDoFunc, deleted *[]string) smithyhttp.ClientDoFunc {
+	var mu sync.Mutex
+	var received bool
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Header.Get("X-Amz-Target") != "" {
+			return secrets(r)
+		}
+		if err := r.ParseForm(); err != nil {
+			return mockFailure(t, "failed parsing sqs request: %v", err)
+		}
+		var response string
+		switch action := r.PostForm.Get("Action"); action {
+		case "ReceiveMessage":
+			mu.Lock()
+			var sb strings.Builder
+			if !received {
+				for i, body := range messages {
+					h := md5.Sum([]byte(body))
+					fmt.Fprintf(&sb, "<Message><MessageId>%d</MessageId><ReceiptHandle>handle-%d</ReceiptHandle><MD5OfBody>%s</MD5OfBody><Body>%s</Body></Message>",
+						i, i, hex.EncodeToString(h[:]), html.EscapeString(body))
+				}
+				received = true
+			} else {
+				time.Sleep(10 * time.Millisecond)
+			}
+			mu.Unlock()
+			response = "<ReceiveMessageResponse><ReceiveMessageResult>" + sb.String() + "</ReceiveMessageResult></ReceiveMessageResponse>"
+		case "DeleteMessage":
+			mu.Lock()
+			*deleted = append(*deleted, r.PostForm.Get("ReceiptHandle"))
+			mu.Unlock()
+			response = "<DeleteMessageResponse><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></DeleteMessageResponse>"
+		default:
+			return mockFailure(t, "unexpected %q sqs action", action)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(response)),
+		}, nil
+	})
+}
+
+func TestListenSQS(t *testing.T) {
+	v1 := map[string]interface{}{"id": "0", "value": "b006d65b-c923-46a1-8da1-7d52558508fe"}
+	v2 := map[string]interface{}{"id": "1", "value": "8b8c7b1e-6a2a-4e45-9d1c-1b0b6f0c2c51"}
+
+	messages := []string{
+		`{"source": "aws.secretsmanager", "detail": {"eventName": "PutSecretValue", "requestParameters": {"secretId": "arn:aws:secretsmanager:us-east-1:123456789012:secret:authcrunch/caddy/access_token-a1B2c3"}}}`,
+		`{"source": "aws.secretsmanager", "detail": {"eventName": "DeleteSecret", "requestParameters": {"secretId": "authcrunch/caddy/refresh_token"}}}`,
+		`{"source": "aws.ec2", "detail": {"eventName": "RunInstances"}}`,
+		`{"source": "aws.secretsmanager", "detail": {"eventName": "CreateSecret", "requestParameters": {"name": "authcrunch/caddy/api_token"}}}`,
+	}
+
+	c, err := NewClientWithConfig(context.TODO(), &ClientConfig{
+		ID:       "foo",
+		Region:   "us-east-1",
+		Provider: "aws_secrets_manager",
+		CacheTTL: "1h",
+	})
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	var deleted []string
+	c.SetMockClient(newSQSMockClient(t, messages, newSequenceMockClient(t, []map[string]interface{}{v1, v2}), &deleted))
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+	if _, err := c.GetSecret(context.TODO(), "authcrunch/caddy/access_token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := c.ListenSQS(ctx, "https://sqs.us-east-1.amazonaws.com/123456789012/authcrunch")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []SecretEvent{
+		{Type: SecretUpdated, Path: "authcrunch/caddy/access_token", Secret: v2},
+		{Type: SecretDeleted, Path: "authcrunch/caddy/refresh_token"},
+		{Type: SecretAdded, Path: "authcrunch/caddy/api_token"},
+	}
+	var got []SecretEvent
+	timeout := time.After(5 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case ev := <-ch:
+			got = append(got, ev)
+		case <-timeout:
+			t.Fatalf("timed out waiting for events, got: %v", got)
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListenSQS() mismatch (-want +got):\n%s", diff)
+	}
+
+	cancel()
+	for range ch {
+	}
+
+	if diff := cmp.Diff([]string{"handle-0", "handle-1", "handle-2", "handle-3"}, deleted); diff != "" {
+		t.Errorf("ListenSQS() deleted messages mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestListenSQSWithoutQueueURL(t *testing.T) {
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	_, err = c.ListenSQS(context.TODO(), "")
+	if diff := cmp.Diff(errors.New("sqs queue url is empty").Error(), err.Error()); diff != "" {
+		t.Errorf("ListenSQS() error mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseSecretsManagerEvent(t *testing.T) {
+	testcases := []struct {
+		name      string
+		data      string
+		want      *SecretEvent
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test api call event with secret name",
+			data: `{"source": "aws.secretsmanager", "detail": {"eventName": "UpdateSecret", "requestParameters": {"secretId": "authcrunch/caddy/access_token"}}}`,
+			want: &SecretEvent{Type: SecretUpdated, Path: "authcrunch/caddy/access_token"},
+		},
+		{
+			name: "test api call event with secret arn",
+			data: `{"source": "aws.secretsmanager", "detail": {"eventName": "DeleteSecret", "requestParameters": {"secretId": "arn:aws:secretsmanager:us-east-1:123456789012:secret:access_token-a1B2c3"}}}`,
+			want: &SecretEvent{Type: SecretDeleted, Path: "access_token"},
+		},
+		{
+			name: "test rotation event",
+			data: `{"source": "aws.secretsmanager", "detail": {"eventName": "RotationSucceeded", "additionalEventData": {"SecretId": "arn:aws:secretsmanager:us-east-1:123456789012:secret:authcrunch/caddy/access_token-a1B2c3"}}}`,
+			want: &SecretEvent{Type: SecretUpdated, Path: "authcrunch/caddy/access_token"},
+		},
+		{
+			name:      "test malformed event",
+			data:      `foo`,
+			shouldErr: true,
+			err:       errors.New("malformed event: invalid character 'o' in literal false (expecting 'a')"),
+		},
+		{
+			name:      "test event from other source",
+			data:      `{"source": "aws.ec2"}`,
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q event source", "aws.ec2"),
+		},
+		{
+			name:      "test unsupported event name",
+			data:      `{"source": "aws.secretsmanager", "detail": {"eventName": "GetSecretValue"}}`,
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q event name", "GetSecretValue"),
+		},
+		{
+			name:      "test event without secret id",
+			data:      `{"source": "aws.secretsmanager", "detail": {"eventName": "PutSecretValue"}}`,
+			shouldErr: true,
+			err:       fmt.Errorf("secret id not found in %q event", "PutSecretValue"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSecretsManagerEvent(tc.data)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("parseSecretsManagerEvent() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseSecretsManagerEvent() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
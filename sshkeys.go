@@ -0,0 +1,69 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// passphraseKeySuffix is appended to the key of the secret holding the
+// encrypted private key to get the key holding its passphrase.
+const passphraseKeySuffix = "_passphrase"
+
+// GetSSHSigner returns the signer of the SSH private key stored in the key
+// of the secret, either in OpenSSH or in PEM format. The encrypted private
+// key is decrypted with the passphrase stored in the key with the
+// "_passphrase" suffix, e.g. "private_key_passphrase" for "private_key".
+func (c *client) GetSSHSigner(ctx context.Context, path, key string) (ssh.Signer, error) {
+	m, err := c.GetSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := parseSSHSigner(m, key)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	return signer, nil
+}
+
+func parseSSHSigner(m map[string]interface{}, key string) (ssh.Signer, error) {
+	privateKey, err := getStringValue(m, key, true)
+	if err != nil {
+		return nil, err
+	}
+	passphraseKey := key + passphraseKeySuffix
+	passphrase, err := getStringValue(m, passphraseKey, false)
+	if err != nil {
+		return nil, err
+	}
+	var signer ssh.Signer
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(privateKey))
+	}
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, fmt.Errorf("key %q value is encrypted, but key %q not found", key, passphraseKey)
+		}
+		return nil, fmt.Errorf("key %q value is malformed: %v", key, err)
+	}
+	return signer, nil
+}
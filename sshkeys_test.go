@@ -0,0 +1,124 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetSSHSigner(t *testing.T) {
+	path := "authcrunch/caddy/ssh"
+	rsaKey, _ := newSNSSigningKey(t)
+	rsaBlock := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}
+	encryptedBlock, err := x509.EncryptPEMBlock(rand.Reader, rsaBlock.Type, rsaBlock.Bytes, []byte("changeit"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("failed encrypting key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed generating key: %v", err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatalf("failed encoding key: %v", err)
+	}
+
+	testcases := []struct {
+		name      string
+		secret    map[string]interface{}
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test rsa private key",
+			secret: map[string]interface{}{"private_key": string(pem.EncodeToMemory(rsaBlock))},
+			want:   "ssh-rsa",
+		},
+		{
+			name:   "test ed25519 private key",
+			secret: map[string]interface{}{"private_key": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}))},
+			want:   "ssh-ed25519",
+		},
+		{
+			name: "test encrypted private key",
+			secret: map[string]interface{}{
+				"private_key":            string(pem.EncodeToMemory(encryptedBlock)),
+				"private_key_passphrase": "changeit",
+			},
+			want: "ssh-rsa",
+		},
+		{
+			name:      "test encrypted private key without passphrase",
+			secret:    map[string]interface{}{"private_key": string(pem.EncodeToMemory(encryptedBlock))},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value is encrypted, but key %q not found", path, "private_key", "private_key_passphrase"),
+		},
+		{
+			name:      "test missing private key",
+			secret:    map[string]interface{}{"public_key": "ssh-rsa AAAA"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q not found", path, "private_key"),
+		},
+		{
+			name:      "test malformed private key",
+			secret:    map[string]interface{}{"private_key": "foobar"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value is malformed: ssh: no key found", path, "private_key"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, map[string]map[string]interface{}{"AWSCURRENT": tc.secret}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetSSHSigner(context.TODO(), path, "private_key")
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("GetSSHSigner() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if keyType := got.PublicKey().Type(); keyType != tc.want {
+				t.Errorf("unexpected key type %q, want %q", keyType, tc.want)
+			}
+			signature, err := got.Sign(rand.Reader, []byte("foo"))
+			if err != nil {
+				t.Fatalf("failed signing: %v", err)
+			}
+			if err := got.PublicKey().Verify([]byte("foo"), signature); err != nil {
+				t.Errorf("failed verifying signature: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"go.uber.org/zap"
+)
+
+// SSMFallbackConfig makes the client look up the secrets not found in AWS
+// Secrets Manager in AWS Systems Manager Parameter Store, e.g. during the
+// migration of the credentials. The parameters hold the same JSON objects
+// as the secrets, usually as SecureString. The fallback applies to the
+// current versions of the secrets only, and the tag, KMS key, and
+// staleness policies do not apply to the parameters.
+type SSMFallbackConfig struct {
+	// Prefix is prepended to the name of the secret to form the name of
+	// the parameter. When empty, it is "/", e.g. the secret
+	// "authcrunch/users/jsmith" falls back to the parameter
+	// "/authcrunch/users/jsmith".
+	Prefix string `json:"prefix,omitempty" xml:"prefix,omitempty" yaml:"prefix,omitempty"`
+}
+
+func (cfg *SSMFallbackConfig) validate() error {
+	if cfg.Prefix != "" && !strings.HasPrefix(cfg.Prefix, "/") {
+		return fmt.Errorf("malformed %q ssm fallback prefix", cfg.Prefix)
+	}
+	return nil
+}
+
+// parameterName returns the name of the parameter for the name of the
+// secret.
+func (cfg *SSMFallbackConfig) parameterName(name string) string {
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	return prefix + name
+}
+
+// getParameter returns the decrypted value of the parameter the secret
+// with the name falls back to. It returns the error of the secret, when
+// the parameter is not found either.
+func (c *client) getParameter(ctx context.Context, cfg *SSMFallbackConfig, region, name string, secretErr error) (string, error) {
+	c.mu.RLock()
+	serviceConfig := c.serviceConfig
+	c.mu.RUnlock()
+	if region != "" {
+		serviceConfig.Region = region
+	}
+	parameterName := cfg.parameterName(name)
+	output, err := ssm.NewFromConfig(serviceConfig).GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(parameterName),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		var notFound *ssmtypes.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return "", secretErr
+		}
+		return "", err
+	}
+	if output.Parameter == nil || output.Parameter.Value == nil {
+		return "", errors.New("Parameter value not found in response")
+	}
+	c.getLogger().Warn(
+		"secret retrieved from ssm parameter store",
+		zap.String("name", name),
+		zap.String("parameter", parameterName),
+	)
+	return *output.Parameter.Value, nil
+}
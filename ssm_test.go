@@ -0,0 +1,157 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// newSSMFallbackMockClient returns HTTP client serving the secrets and the
+// parameters. It records the names of the requested parameters.
+func newSSMFallbackMockClient(t *testing.T, secrets, parameters map[string]string, requested *[]string) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		var input struct {
+			SecretId       string
+			Name           string
+			WithDecryption bool
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return mockFailure(t, "failed parsing request: %v", err)
+		}
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "secretsmanager.GetSecretValue":
+			if v, found := secrets[input.SecretId]; found {
+				return secretsmock.JSONResponse(map[string]interface{}{"SecretString": v}), nil
+			}
+			return secretsmock.NotFoundResponse(""), nil
+		case "AmazonSSM.GetParameter":
+			*requested = append(*requested, input.Name)
+			if !input.WithDecryption {
+				return mockFailure(t, "expected parameter decryption")
+			}
+			if v, found := parameters[input.Name]; found {
+				return secretsmock.JSONResponse(map[string]interface{}{
+					"Parameter": map[string]interface{}{"Name": input.Name, "Type": "SecureString", "Value": v},
+				}), nil
+			}
+			return secretsmock.ErrorResponse("", http.StatusBadRequest, "ParameterNotFound", ""), nil
+		default:
+			return mockFailure(t, "unexpected %q target", target)
+		}
+	})
+}
+
+func TestSSMFallback(t *testing.T) {
+	secrets := map[string]string{
+		"authcrunch/users/jsmith": `{"username":"jsmith"}`,
+	}
+	parameters := map[string]string{
+		"/legacy/authcrunch/users/mjones": `{"username":"mjones"}`,
+	}
+	testcases := []struct {
+		name          string
+		fallback      *SSMFallbackConfig
+		path          string
+		want          map[string]interface{}
+		wantRequested []string
+		shouldErr     bool
+		err           error
+	}{
+		{
+			name:     "test secret found in secrets manager",
+			fallback: &SSMFallbackConfig{Prefix: "/legacy/"},
+			path:     "users/jsmith",
+			want:     map[string]interface{}{"username": "jsmith"},
+		},
+		{
+			name:          "test secret found in parameter store",
+			fallback:      &SSMFallbackConfig{Prefix: "/legacy/"},
+			path:          "users/mjones",
+			want:          map[string]interface{}{"username": "mjones"},
+			wantRequested: []string{"/legacy/authcrunch/users/mjones"},
+		},
+		{
+			name:          "test secret found nowhere",
+			fallback:      &SSMFallbackConfig{},
+			path:          "users/mjones",
+			wantRequested: []string{"/authcrunch/users/mjones"},
+			shouldErr:     true,
+		},
+		{
+			name:      "test secret without fallback",
+			path:      "users/mjones",
+			shouldErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requested []string
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithBasePrefix("authcrunch/"),
+				WithSSMFallback(tc.fallback),
+				WithHTTPClient(newSSMFallbackMockClient(t, secrets, parameters, &requested)),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			got, err := c.GetSecret(context.TODO(), tc.path)
+			if diff := cmp.Diff(tc.wantRequested, requested); diff != "" {
+				t.Errorf("requested parameters mismatch (-want +got):\n%s", diff)
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !isNotFound(err) {
+					t.Fatalf("expected the not found error of the secret, got: %v", err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success")
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("GetSecret() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSSMFallbackConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		prefix string
+		err    error
+	}{
+		{prefix: ""},
+		{prefix: "/legacy/"},
+		{prefix: "legacy/", err: fmt.Errorf("malformed %q ssm fallback prefix", "legacy/")},
+	} {
+		err := (&SSMFallbackConfig{Prefix: tc.prefix}).validate()
+		if fmt.Sprint(err) != fmt.Sprint(tc.err) {
+			t.Errorf("prefix %q: unexpected error: %v, want: %v", tc.prefix, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"go.uber.org/zap"
+)
+
+const (
+	// StalenessActionWarn logs the use of the stale secrets.
+	StalenessActionWarn = "warn"
+	// StalenessActionDeny refuses to return the stale secrets.
+	StalenessActionDeny = "deny"
+)
+
+// ErrSecretStale is returned when the staleness policy refuses to return a
+// secret not rotated within the maximum age.
+var ErrSecretStale = errors.New("secret is stale")
+
+// StalenessConfig enforces the rotation of the secrets at the point of
+// use. The age of a secret is the time since its last rotation or, when
+// it was never rotated, since its last change.
+type StalenessConfig struct {
+	// MaxAge is the maximum age of the secrets, e.g. "2160h" for 90 days.
+	MaxAge string `json:"max_age,omitempty" xml:"max_age,omitempty" yaml:"max_age,omitempty"`
+	// Action is either "warn" or "deny". When empty, it is "warn".
+	Action string `json:"action,omitempty" xml:"action,omitempty" yaml:"action,omitempty"`
+}
+
+func (cfg *StalenessConfig) validate() error {
+	if d, err := time.ParseDuration(cfg.MaxAge); err != nil || d <= 0 {
+		return fmt.Errorf("malformed %q staleness max age", cfg.MaxAge)
+	}
+	switch cfg.Action {
+	case "", StalenessActionWarn, StalenessActionDeny:
+	default:
+		return fmt.Errorf("unsupported %q staleness action", cfg.Action)
+	}
+	return nil
+}
+
+// secretAge returns the time of the last rotation, change, or creation of
+// the secret, whichever is known first.
+func secretAge(output *secretsmanager.DescribeSecretOutput) time.Time {
+	for _, t := range []*time.Time{output.LastRotatedDate, output.LastChangedDate, output.CreatedDate} {
+		if t != nil && !t.IsZero() {
+			return *t
+		}
+	}
+	return time.Time{}
+}
+
+// checkStaleness logs or, with the deny action, returns ErrSecretStale,
+// when the secret is older than the maximum age. The secrets of unknown
+// age are not checked.
+func (c *client) checkStaleness(cfg *StalenessConfig, secretPath string, output *secretsmanager.DescribeSecretOutput) error {
+	// The configuration is validated.
+	maxAge, _ := time.ParseDuration(cfg.MaxAge)
+	since := secretAge(output)
+	if since.IsZero() {
+		return nil
+	}
+	age := c.clock.Now().Sub(since)
+	if age <= maxAge {
+		return nil
+	}
+	if cfg.Action == StalenessActionDeny {
+		return fmt.Errorf("%w: %q last rotated %s ago, exceeding %s", ErrSecretStale, secretPath, age.Truncate(time.Second), maxAge)
+	}
+	c.getLogger().Warn(
+		"stale secret",
+		zap.String("path", secretPath),
+		zap.String("name", aws.ToString(output.Name)),
+		zap.Duration("age", age),
+		zap.Duration("max_age", maxAge),
+	)
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestStaleness(t *testing.T) {
+	clock := newTestClock()
+	dates := map[string]map[string]interface{}{
+		// Rotated 30 days ago.
+		"users/jsmith": {"LastRotatedDate": clock.Now().Add(-30 * 24 * time.Hour).Unix()},
+		// Never rotated, changed 100 days ago.
+		"users/mjones": {"LastChangedDate": clock.Now().Add(-100 * 24 * time.Hour).Unix()},
+		// Unknown age.
+		"users/guest": {},
+	}
+	testcases := []struct {
+		name      string
+		action    string
+		path      string
+		wantWarn  bool
+		shouldErr bool
+		err       error
+	}{
+		{name: "test fresh secret", action: StalenessActionDeny, path: "users/jsmith"},
+		{name: "test secret of unknown age", action: StalenessActionDeny, path: "users/guest"},
+		{name: "test stale secret with warn action", path: "users/mjones", wantWarn: true},
+		{
+			name:      "test stale secret with deny action",
+			action:    StalenessActionDeny,
+			path:      "users/mjones",
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q last rotated 2400h0m0s ago, exceeding 2160h0m0s", ErrSecretStale, "users/mjones"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.WarnLevel)
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithClock(clock),
+				WithLogger(zap.New(core)),
+				WithStaleness(&StalenessConfig{MaxAge: "2160h", Action: tc.action}),
+				WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+					var input struct {
+						SecretId string
+					}
+					if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+						return mockFailure(t, "failed parsing request: %v", err)
+					}
+					if r.Header.Get("X-Amz-Target") == "secretsmanager.DescribeSecret" {
+						return secretsmock.JSONResponse(dates[input.SecretId]), nil
+					}
+					return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+				})),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			_, err = c.GetSecret(context.TODO(), tc.path)
+			if got := logs.FilterMessage("stale secret").Len() > 0; got != tc.wantWarn {
+				t.Errorf("unexpected %t stale secret warning, want %t", got, tc.wantWarn)
+			}
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, ErrSecretStale) {
+					t.Fatalf("expected ErrSecretStale, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestStalenessConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cfg       *StalenessConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test valid config",
+			cfg:  &StalenessConfig{MaxAge: "2160h", Action: StalenessActionDeny},
+		},
+		{
+			name:      "test malformed max age",
+			cfg:       &StalenessConfig{MaxAge: "90d"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q staleness max age", "90d"),
+		},
+		{
+			name:      "test unsupported action",
+			cfg:       &StalenessConfig{MaxAge: "2160h", Action: "block"},
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q staleness action", "block"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.validate()
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// GetSecretInto writes the value of the secret at the path to the writer
+// as is: the bytes of the binary secret, or the secret string. Unlike
+// GetSecret, it neither decodes nor caches the value, so the large
+// secrets, e.g. the keystores, are held in memory once, as received from
+// AWS Secrets Manager. The path policy, the version pins, and the max
+// size limit apply.
+func (c *client) GetSecretInto(ctx context.Context, path string, w io.Writer) error {
+	err := c.writeSecret(ctx, path, w)
+	c.audit("get", path, err)
+	return err
+}
+
+func (c *client) writeSecret(ctx context.Context, path string, w io.Writer) error {
+	cfg := c.getConfig()
+	if err := cfg.PathPolicy.check(path); err != nil {
+		return err
+	}
+	if err := c.recordAccess(path); err != nil {
+		return err
+	}
+	name, err := cfg.resolvePath(path)
+	if err != nil {
+		return err
+	}
+	input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(name)}
+	if pin := cfg.pin(path); pin != nil {
+		input.VersionId = aws.String(pin.VersionID)
+	} else {
+		input.VersionStage = aws.String(versionStageCurrent)
+	}
+	var region, endpoint string
+	if route := cfg.route(path); route != nil {
+		region, endpoint = route.Region, route.Endpoint
+	}
+	api := c.getServiceClient(region, endpoint)
+	if _, err := c.checkDescription(ctx, cfg, api, path, name); err != nil {
+		return err
+	}
+	result, err := api.GetSecretValue(ctx, input)
+	if err != nil {
+		return err
+	}
+	var size int
+	switch {
+	case result.SecretBinary != nil:
+		size = len(result.SecretBinary)
+	case result.SecretString != nil:
+		size = len(*result.SecretString)
+	default:
+		return errors.New("secret value not found in response")
+	}
+	if cfg.Limits != nil && cfg.Limits.MaxSize > 0 && size > cfg.Limits.MaxSize {
+		return &LimitError{Limit: "size", Value: size, Max: cfg.Limits.MaxSize}
+	}
+	if result.SecretBinary != nil {
+		_, err = w.Write(result.SecretBinary)
+		return err
+	}
+	_, err = io.WriteString(w, *result.SecretString)
+	return err
+}
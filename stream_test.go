@@ -0,0 +1,93 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+func TestGetSecretInto(t *testing.T) {
+	keystore := []byte{0x30, 0x82, 0x0a, 0x00, 0xff, 0x00, 0x7f}
+	testcases := []struct {
+		name      string
+		response  map[string]interface{}
+		limits    *SecretLimitsConfig
+		want      []byte
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "test binary secret",
+			response: map[string]interface{}{"Name": "keystore", "SecretBinary": keystore},
+			want:     keystore,
+		},
+		{
+			name:     "test string secret",
+			response: map[string]interface{}{"Name": "keystore", "SecretString": `{"foo":"bar"}`},
+			want:     []byte(`{"foo":"bar"}`),
+		},
+		{
+			name:      "test binary secret exceeding size limit",
+			response:  map[string]interface{}{"Name": "keystore", "SecretBinary": keystore},
+			limits:    &SecretLimitsConfig{MaxSize: 4},
+			shouldErr: true,
+			err:       ErrSecretTooLarge,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithConfig(&ClientConfig{ID: "foo", Region: "us-east-1", Limits: tc.limits}))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+				return secretsmock.JSONResponse(tc.response), nil
+			}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			var buf bytes.Buffer
+			err = c.GetSecretInto(context.TODO(), "authcrunch/keystore", &buf)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				if buf.Len() != 0 {
+					t.Fatalf("unexpected partial write: %x", buf.Bytes())
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if !bytes.Equal(buf.Bytes(), tc.want) {
+				t.Errorf("GetSecretInto() wrote %x, want %x", buf.Bytes(), tc.want)
+			}
+			if _, found := c.(*client).getCache().get(cacheKey{path: "authcrunch/keystore", stage: versionStageCurrent}); found {
+				t.Errorf("unexpected cached secret")
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTagDenied is returned when the tag policy refuses to return a secret
+// because of its tags.
+var ErrTagDenied = errors.New("secret denied by tag policy")
+
+// TagPolicyConfig refuses to return the secrets carrying the configured
+// tags. An entry is either "key=value", e.g. "classification=restricted",
+// matching the tag with the value, or "key", matching the tag with any
+// value. A secret with a tag matching one of the Deny entries is rejected,
+// unless the tag also matches one of the Override entries. The policy is
+// a client-side defense in depth and does not replace the IAM policies.
+type TagPolicyConfig struct {
+	Deny     []string `json:"deny,omitempty" xml:"deny,omitempty" yaml:"deny,omitempty"`
+	Override []string `json:"override,omitempty" xml:"override,omitempty" yaml:"override,omitempty"`
+}
+
+func (p *TagPolicyConfig) validate() error {
+	for _, entry := range append(append([]string{}, p.Deny...), p.Override...) {
+		key, _, _ := cutTagEntry(entry)
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("malformed %q tag policy entry", entry)
+		}
+	}
+	return nil
+}
+
+func cutTagEntry(entry string) (key, value string, hasValue bool) {
+	if i := strings.Index(entry, "="); i >= 0 {
+		return entry[:i], entry[i+1:], true
+	}
+	return entry, "", false
+}
+
+func matchTagEntry(entry string, tags map[string]string) bool {
+	key, value, hasValue := cutTagEntry(entry)
+	v, found := tags[key]
+	if !found {
+		return false
+	}
+	return !hasValue || v == value
+}
+
+// check returns ErrTagDenied when the tags match a deny entry not lifted
+// by an override entry.
+func (p *TagPolicyConfig) check(secretPath string, tags map[string]string) error {
+	if p == nil || len(tags) == 0 {
+		return nil
+	}
+	for _, entry := range p.Deny {
+		if !matchTagEntry(entry, tags) {
+			continue
+		}
+		overridden := false
+		for _, override := range p.Override {
+			if matchTagEntry(override, tags) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			return fmt.Errorf("%w: %q matches %q deny entry", ErrTagDenied, secretPath, entry)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// newTagPolicyMockClient returns HTTP client describing the secrets with
+// the tags and serving their values.
+func newTagPolicyMockClient(t *testing.T, tags map[string]map[string]string) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		var input struct {
+			SecretId string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return mockFailure(t, "failed parsing request: %v", err)
+		}
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "secretsmanager.DescribeSecret":
+			var entries []map[string]string
+			for k, v := range tags[input.SecretId] {
+				entries = append(entries, map[string]string{"Key": k, "Value": v})
+			}
+			return secretsmock.JSONResponse(map[string]interface{}{
+				"Name": input.SecretId,
+				"Tags": entries,
+			}), nil
+		case "secretsmanager.GetSecretValue":
+			return secretsmock.SecretStringResponse(map[string]interface{}{"username": "jsmith"}), nil
+		default:
+			return mockFailure(t, "unexpected %q target", target)
+		}
+	})
+}
+
+func TestTagPolicy(t *testing.T) {
+	tags := map[string]map[string]string{
+		"authcrunch/users/jsmith": {"env": "prod"},
+		"authcrunch/users/admin":  {"env": "prod", "classification": "restricted"},
+		"authcrunch/users/root":   {"classification": "secret"},
+		"authcrunch/users/guest":  nil,
+	}
+	testcases := []struct {
+		name      string
+		policy    *TagPolicyConfig
+		path      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test secret without policy",
+			path: "users/admin",
+		},
+		{
+			name:   "test secret without denied tag",
+			policy: &TagPolicyConfig{Deny: []string{"classification=restricted"}},
+			path:   "users/jsmith",
+		},
+		{
+			name:   "test secret without tags",
+			policy: &TagPolicyConfig{Deny: []string{"classification"}},
+			path:   "users/guest",
+		},
+		{
+			name:      "test secret with denied tag value",
+			policy:    &TagPolicyConfig{Deny: []string{"classification=restricted"}},
+			path:      "users/admin",
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q matches %q deny entry", ErrTagDenied, "users/admin", "classification=restricted"),
+		},
+		{
+			name:      "test secret with denied tag key",
+			policy:    &TagPolicyConfig{Deny: []string{"classification"}},
+			path:      "users/root",
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q matches %q deny entry", ErrTagDenied, "users/root", "classification"),
+		},
+		{
+			name: "test secret with overridden tag",
+			policy: &TagPolicyConfig{
+				Deny:     []string{"classification"},
+				Override: []string{"classification=restricted"},
+			},
+			path: "users/admin",
+		},
+		{
+			name: "test secret with tag not overridden",
+			policy: &TagPolicyConfig{
+				Deny:     []string{"classification"},
+				Override: []string{"classification=restricted"},
+			},
+			path:      "users/root",
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q matches %q deny entry", ErrTagDenied, "users/root", "classification"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(),
+				WithID("foo"),
+				WithRegion("us-east-1"),
+				WithBasePrefix("authcrunch/"),
+				WithTagPolicy(tc.policy),
+				WithHTTPClient(newTagPolicyMockClient(t, tags)),
+				WithCredentialsProvider(MockCredentialsProvider{}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error during client initialization: %v", err)
+			}
+			_, err = c.GetSecret(context.TODO(), tc.path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !errors.Is(err, ErrTagDenied) {
+					t.Fatalf("expected ErrTagDenied, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestTagPolicyValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		policy    *TagPolicyConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test valid policy",
+			policy: &TagPolicyConfig{Deny: []string{"classification=restricted", "pii"}, Override: []string{"pii=low"}},
+		},
+		{
+			name:      "test empty entry",
+			policy:    &TagPolicyConfig{Deny: []string{""}},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q tag policy entry", ""),
+		},
+		{
+			name:      "test entry without key",
+			policy:    &TagPolicyConfig{Override: []string{"=restricted"}},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q tag policy entry", "=restricted"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.validate()
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
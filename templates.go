@@ -0,0 +1,88 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	pathVarNameRgx  *regexp.Regexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	pathVarValueRgx *regexp.Regexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@+=-]*$`)
+)
+
+// GetSecretTemplated returns the key-value map of the secret at the path
+// rendered from the template, e.g. "authcrunch/{env}/{realm}/users/{username}".
+// Each variable of the template must have a value, and each value must
+// be used by the template. The values must be single path segments, i.e.
+// they cannot contain slashes or start with a dot.
+func (c *client) GetSecretTemplated(ctx context.Context, tmpl string, vars map[string]string) (map[string]interface{}, error) {
+	path, err := renderSecretPath(tmpl, vars)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetSecret(ctx, path)
+}
+
+// renderSecretPath substitutes the {name} variables in the path template.
+func renderSecretPath(tmpl string, vars map[string]string) (string, error) {
+	var sb strings.Builder
+	used := make(map[string]bool)
+	s := tmpl
+	for {
+		i := strings.IndexAny(s, "{}")
+		if i < 0 {
+			sb.WriteString(s)
+			break
+		}
+		if s[i] == '}' {
+			return "", fmt.Errorf("malformed %q path template: unexpected closing brace", tmpl)
+		}
+		j := strings.IndexAny(s[i+1:], "{}")
+		if j < 0 || s[i+1+j] != '}' {
+			return "", fmt.Errorf("malformed %q path template: unclosed brace", tmpl)
+		}
+		name := s[i+1 : i+1+j]
+		if !pathVarNameRgx.MatchString(name) {
+			return "", fmt.Errorf("malformed %q path template: malformed %q variable name", tmpl, name)
+		}
+		value, exists := vars[name]
+		if !exists {
+			return "", fmt.Errorf("path template variable %q not found", name)
+		}
+		if !pathVarValueRgx.MatchString(value) {
+			return "", fmt.Errorf("malformed %q value of %q path template variable", value, name)
+		}
+		used[name] = true
+		sb.WriteString(s[:i])
+		sb.WriteString(value)
+		s = s[i+2+j:]
+	}
+	var unused []string
+	for name := range vars {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return "", fmt.Errorf("path template variables %q not used", unused)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderSecretPath(t *testing.T) {
+	tmpl := "authcrunch/{env}/{realm}/users/{username}"
+	testcases := []struct {
+		name      string
+		tmpl      string
+		vars      map[string]string
+		want      string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test path template",
+			tmpl: tmpl,
+			vars: map[string]string{"env": "prod", "realm": "local", "username": "jsmith@contoso.com"},
+			want: "authcrunch/prod/local/users/jsmith@contoso.com",
+		},
+		{
+			name: "test path without variables",
+			tmpl: "authcrunch/prod/local",
+			want: "authcrunch/prod/local",
+		},
+		{
+			name:      "test path template with missing variable",
+			tmpl:      tmpl,
+			vars:      map[string]string{"env": "prod", "realm": "local"},
+			shouldErr: true,
+			err:       fmt.Errorf("path template variable %q not found", "username"),
+		},
+		{
+			name:      "test path template with unused variables",
+			tmpl:      tmpl,
+			vars:      map[string]string{"env": "prod", "realm": "local", "username": "jsmith", "tenant": "a", "app": "b"},
+			shouldErr: true,
+			err:       fmt.Errorf("path template variables %q not used", []string{"app", "tenant"}),
+		},
+		{
+			name:      "test path template with traversal",
+			tmpl:      tmpl,
+			vars:      map[string]string{"env": "prod", "realm": "local", "username": "../../admin"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q value of %q path template variable", "../../admin", "username"),
+		},
+		{
+			name:      "test path template with slash in value",
+			tmpl:      tmpl,
+			vars:      map[string]string{"env": "prod", "realm": "local/users/admin", "username": "jsmith"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q value of %q path template variable", "local/users/admin", "realm"),
+		},
+		{
+			name:      "test path template with empty value",
+			tmpl:      tmpl,
+			vars:      map[string]string{"env": "", "realm": "local", "username": "jsmith"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q value of %q path template variable", "", "env"),
+		},
+		{
+			name:      "test path template with unclosed brace",
+			tmpl:      "authcrunch/{env/users",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q path template: unclosed brace", "authcrunch/{env/users"),
+		},
+		{
+			name:      "test path template with unexpected closing brace",
+			tmpl:      "authcrunch/env}/users",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q path template: unexpected closing brace", "authcrunch/env}/users"),
+		},
+		{
+			name:      "test path template with malformed variable name",
+			tmpl:      "authcrunch/{e-nv}/users",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q path template: malformed %q variable name", "authcrunch/{e-nv}/users", "e-nv"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := renderSecretPath(tc.tmpl, tc.vars)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("renderSecretPath() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("renderSecretPath() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetSecretTemplated(t *testing.T) {
+	secret := map[string]interface{}{"username": "jsmith", "password": "bcrypt:10:foo"}
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	c.SetMockClient(newStagedMockClient(t, map[string]map[string]interface{}{"AWSCURRENT": secret}))
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+	got, err := c.GetSecretTemplated(context.TODO(), "authcrunch/{realm}/users/{username}", map[string]string{"realm": "local", "username": "jsmith"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(secret, got); diff != "" {
+		t.Errorf("GetSecretTemplated() mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = c.GetSecretTemplated(context.TODO(), "authcrunch/{realm}/users/{username}", map[string]string{"realm": "local", "username": ".hidden"})
+	wantErr := errors.New(`malformed ".hidden" value of "username" path template variable`)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("GetSecretTemplated() error mismatch, want: %v, got: %v", wantErr, err)
+	}
+}
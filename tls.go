@@ -0,0 +1,134 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// TLSConfig hardens the TLS connections of the default HTTP client.
+type TLSConfig struct {
+	// MinVersion is the minimum TLS version, either "1.2" or "1.3". When
+	// empty, it is TLS 1.2.
+	MinVersion string `json:"min_version,omitempty" xml:"min_version,omitempty" yaml:"min_version,omitempty"`
+	// CipherSuites are the names of the cipher suites allowed with TLS 1.2,
+	// e.g. "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256". The suites of TLS 1.3
+	// are not configurable. When empty, the defaults of Go apply.
+	CipherSuites []string `json:"cipher_suites,omitempty" xml:"cipher_suites,omitempty" yaml:"cipher_suites,omitempty"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func (cfg *TLSConfig) validate() error {
+	if _, found := tlsVersions[cfg.MinVersion]; !found && cfg.MinVersion != "" {
+		return fmt.Errorf("malformed %q tls min version", cfg.MinVersion)
+	}
+	if cfg.MinVersion == "1.3" && len(cfg.CipherSuites) > 0 {
+		return fmt.Errorf("tls cipher suites are not configurable with tls 1.3")
+	}
+	_, err := cfg.cipherSuiteIDs()
+	return err
+}
+
+// cipherSuiteIDs returns the IDs of the cipher suites. It rejects the
+// suites Go considers insecure and the ones not supporting TLS 1.2.
+func (cfg *TLSConfig) cipherSuiteIDs() ([]uint16, error) {
+	if len(cfg.CipherSuites) == 0 {
+		return nil, nil
+	}
+	suites := make(map[string]*tls.CipherSuite)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite
+	}
+	ids := make([]uint16, 0, len(cfg.CipherSuites))
+	for _, name := range cfg.CipherSuites {
+		suite, found := suites[name]
+		if !found {
+			return nil, fmt.Errorf("unsupported %q tls cipher suite", name)
+		}
+		supportsTLS12 := false
+		for _, v := range suite.SupportedVersions {
+			if v == tls.VersionTLS12 {
+				supportsTLS12 = true
+			}
+		}
+		if !supportsTLS12 {
+			return nil, fmt.Errorf("unsupported %q tls cipher suite", name)
+		}
+		ids = append(ids, suite.ID)
+	}
+	return ids, nil
+}
+
+// apply sets the minimum version and the cipher suites of the transport.
+func (cfg *TLSConfig) apply(tr *http.Transport) {
+	if tr.TLSClientConfig == nil {
+		tr.TLSClientConfig = &tls.Config{}
+	} else {
+		tr.TLSClientConfig = tr.TLSClientConfig.Clone()
+	}
+	tr.TLSClientConfig.MinVersion = tls.VersionTLS12
+	if v, found := tlsVersions[cfg.MinVersion]; found {
+		tr.TLSClientConfig.MinVersion = v
+	}
+	// The configuration is validated.
+	tr.TLSClientConfig.CipherSuites, _ = cfg.cipherSuiteIDs()
+}
+
+// hardenHTTPClient returns the copy of the HTTP client with the TLS
+// settings applied. Only the default HTTP client of AWS SDK and the
+// *http.Client with *http.Transport can be hardened. The other clients,
+// e.g. the mocks set with WithHTTPClient, are returned as is, and the
+// returned bool is false.
+func hardenHTTPClient(cfg *TLSConfig, httpClient aws.HTTPClient) (aws.HTTPClient, bool) {
+	if cfg == nil {
+		return httpClient, true
+	}
+	switch client := httpClient.(type) {
+	case *awshttp.BuildableClient:
+		return client.WithTransportOptions(cfg.apply), true
+	case *http.Client:
+		var tr *http.Transport
+		switch t := client.Transport.(type) {
+		case nil:
+			tr = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			tr = t.Clone()
+		default:
+			return httpClient, false
+		}
+		cfg.apply(tr)
+		hardened := *client
+		hardened.Transport = tr
+		return &hardened, true
+	}
+	return httpClient, false
+}
+
+// hardenedDefaultClient returns the HTTP client with the TLS settings
+// applied to a copy of the default transport.
+func hardenedDefaultClient(cfg *TLSConfig) *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	cfg.apply(tr)
+	return &http.Client{Transport: tr}
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTLSConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cfg       *TLSConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test tls 1.3",
+			cfg:  &TLSConfig{MinVersion: "1.3"},
+		},
+		{
+			name: "test tls 1.2 with cipher suites",
+			cfg:  &TLSConfig{MinVersion: "1.2", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}},
+		},
+		{
+			name:      "test unsupported version",
+			cfg:       &TLSConfig{MinVersion: "1.1"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q tls min version", "1.1"),
+		},
+		{
+			name:      "test tls 1.3 with cipher suites",
+			cfg:       &TLSConfig{MinVersion: "1.3", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}},
+			shouldErr: true,
+			err:       fmt.Errorf("tls cipher suites are not configurable with tls 1.3"),
+		},
+		{
+			name:      "test insecure cipher suite",
+			cfg:       &TLSConfig{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q tls cipher suite", "TLS_RSA_WITH_RC4_128_SHA"),
+		},
+		{
+			name:      "test tls 1.3 cipher suite",
+			cfg:       &TLSConfig{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}},
+			shouldErr: true,
+			err:       fmt.Errorf("unsupported %q tls cipher suite", "TLS_AES_128_GCM_SHA256"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.validate()
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+		})
+	}
+}
+
+func TestHardenHTTPClient(t *testing.T) {
+	cfg := &TLSConfig{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}
+	hardenedClient, hardened := hardenHTTPClient(cfg, awshttp.NewBuildableClient())
+	httpClient, ok := hardenedClient.(*awshttp.BuildableClient)
+	if !ok || !hardened {
+		t.Fatalf("expected hardened buildable client")
+	}
+	tlsConfig := httpClient.GetTransport().TLSClientConfig
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("unexpected %x min version", tlsConfig.MinVersion)
+	}
+	if diff := cmp.Diff([]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, tlsConfig.CipherSuites); diff != "" {
+		t.Errorf("cipher suites mismatch (-want +got):\n%s", diff)
+	}
+
+	mockClient := smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, nil
+	})
+	if got, hardened := hardenHTTPClient(cfg, mockClient); hardened {
+		t.Errorf("expected the custom client not hardened")
+	} else if _, ok := got.(smithyhttp.ClientDoFunc); !ok {
+		t.Errorf("expected the custom client returned as is")
+	}
+
+	stdClient := &http.Client{Timeout: time.Minute}
+	got, hardened := hardenHTTPClient(cfg, stdClient)
+	if !hardened {
+		t.Fatalf("expected the standard client hardened")
+	}
+	tr, ok := got.(*http.Client).Transport.(*http.Transport)
+	if !ok || tr.TLSClientConfig.MinVersion != tls.VersionTLS12 || got.(*http.Client).Timeout != time.Minute {
+		t.Errorf("unexpected hardened standard client: %+v", got)
+	}
+	if stdClient.Transport != nil {
+		t.Errorf("expected the standard client left intact")
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	cfg := &TLSConfig{MinVersion: "1.3"}
+	mockClient := smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, nil
+	})
+	_, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithTLS(cfg),
+		WithHTTPClient(mockClient),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	want := "tls settings cannot be applied to http.ClientDoFunc http client"
+	if err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v, want: %s", err, want)
+	}
+
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithTLS(cfg),
+		WithHTTPClient(&http.Client{}),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	for name, httpClient := range map[string]aws.HTTPClient{
+		"service":  c.(*client).serviceConfig.HTTPClient,
+		"external": c.(*client).getHTTPClient(),
+	} {
+		tr, ok := httpClient.(*http.Client).Transport.(*http.Transport)
+		if !ok || tr.TLSClientConfig == nil || tr.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+			t.Errorf("expected %s http client hardened, got: %+v", name, httpClient)
+		}
+	}
+
+	c.SetMockClient(&http.Client{})
+	tr, ok := c.(*client).serviceConfig.HTTPClient.(*http.Client).Transport.(*http.Transport)
+	if !ok || tr.TLSClientConfig == nil || tr.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected mock http client hardened")
+	}
+}
+
+func TestHardenedDefaultClient(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	for _, tc := range []struct {
+		cfg       *TLSConfig
+		shouldErr bool
+	}{
+		{cfg: &TLSConfig{MinVersion: "1.2"}},
+		{cfg: &TLSConfig{MinVersion: "1.3"}, shouldErr: true},
+	} {
+		httpClient := hardenedDefaultClient(tc.cfg)
+		httpClient.Transport.(*http.Transport).TLSClientConfig.RootCAs = pool
+		resp, err := httpClient.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err != nil) != tc.shouldErr {
+			t.Errorf("tls %s: unexpected error: %v", tc.cfg.MinVersion, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+)
+
+// GetTLSCertificate returns the TLS certificate stored in the cert and key
+// keys of the secret as PEM blocks. The cert key holds the leaf
+// certificate followed by the intermediate ones.
+func (c *client) GetTLSCertificate(ctx context.Context, path string) (*tls.Certificate, error) {
+	m, err := c.GetSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := parseTLSCertificate(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	return cert, nil
+}
+
+func parseTLSCertificate(m map[string]interface{}) (*tls.Certificate, error) {
+	certPEM, err := getStringValue(m, "cert", true)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := getStringValue(m, "key", true)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("malformed key pair: %v", err)
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, fmt.Errorf("malformed key pair: %v", err)
+	}
+	return &cert, nil
+}
+
+// certificateReloader serves the TLS certificate of the secret, parsing
+// it again only when the PEM blocks in the secret change.
+type certificateReloader struct {
+	c    Client
+	path string
+
+	mu      sync.Mutex
+	certPEM string
+	keyPEM  string
+	cert    *tls.Certificate
+}
+
+func (r *certificateReloader) get(ctx context.Context) (*tls.Certificate, error) {
+	m, err := r.c.GetSecret(ctx, r.path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, err
+	}
+	certPEM, _ := m["cert"].(string)
+	keyPEM, _ := m["key"].(string)
+	if r.cert != nil && certPEM == r.certPEM && keyPEM == r.keyPEM {
+		return r.cert, nil
+	}
+	cert, err := parseTLSCertificate(m)
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("malformed %q secret: %v", r.path, err)
+	}
+	r.certPEM, r.keyPEM, r.cert = certPEM, keyPEM, cert
+	return cert, nil
+}
+
+// GetCertificateFunc returns the GetCertificate callback of tls.Config
+// serving the certificate of the secret at the path. The callback reads
+// the secret through the cache of the client, so the servers pick up the
+// renewed certificate once the cached secret expires or is refreshed by
+// Watch, ListenSQS, or SNSHandler, without restart. When the secret
+// cannot be retrieved or holds malformed certificate, the callback keeps
+// serving the last good one.
+func GetCertificateFunc(c Client, path string) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r := &certificateReloader{c: c, path: path}
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return r.get(handshakeContext(hello.Context()))
+	}
+}
+
+// NewClientTLSConfig returns tls.Config presenting the client certificate
+// of the secret at the path to the servers requesting one, e.g. for mutual
+// TLS with the upstream identity providers. The certificate is reloaded
+// the same way as with GetCertificateFunc, so the rotated certificate is
+// presented on the new connections. The secret is retrieved once to make
+// sure it holds valid certificate. The returned config verifies the
+// servers with the system roots, unless RootCAs is set.
+func NewClientTLSConfig(ctx context.Context, c Client, path string) (*tls.Config, error) {
+	r := &certificateReloader{c: c, path: path}
+	if _, err := r.get(ctx); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.get(handshakeContext(info.Context()))
+		},
+	}, nil
+}
+
+// handshakeContext returns the context of the TLS handshake, which is nil
+// when the handshake info was not created by crypto/tls.
+func handshakeContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newTestKeyPair returns the PEM-encoded self-signed certificate and its
+// private key.
+func newTestKeyPair(t *testing.T) (string, string) {
+	key, cert := newSNSSigningKey(t)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return string(cert), string(keyPEM)
+}
+
+func TestGetTLSCertificate(t *testing.T) {
+	path := "authcrunch/caddy/tls"
+	certPEM, keyPEM := newTestKeyPair(t)
+	_, otherKeyPEM := newTestKeyPair(t)
+
+	testcases := []struct {
+		name      string
+		secret    map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test tls certificate",
+			secret: map[string]interface{}{"cert": certPEM, "key": keyPEM},
+		},
+		{
+			name:      "test tls certificate without key",
+			secret:    map[string]interface{}{"cert": certPEM},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q not found", path, "key"),
+		},
+		{
+			name:      "test tls certificate with mismatched key",
+			secret:    map[string]interface{}{"cert": certPEM, "key": otherKeyPEM},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: malformed key pair: tls: private key does not match public key", path),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, map[string]map[string]interface{}{"AWSCURRENT": tc.secret}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetTLSCertificate(context.TODO(), path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("GetTLSCertificate() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if got.Leaf == nil || got.Leaf.Subject.CommonName != "sns.amazonaws.com" {
+				t.Errorf("unexpected leaf certificate: %v", got.Leaf)
+			}
+		})
+	}
+}
+
+func TestGetCertificateFunc(t *testing.T) {
+	path := "authcrunch/caddy/tls"
+	certPEM, keyPEM := newTestKeyPair(t)
+	stages := map[string]map[string]interface{}{
+		"AWSCURRENT": {"cert": certPEM, "key": keyPEM},
+	}
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	c.SetMockClient(newStagedMockClient(t, stages))
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+	getCertificate := GetCertificateFunc(c, path)
+	hello := &tls.ClientHelloInfo{}
+	first, err := getCertificate(hello)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if cert, _ := getCertificate(hello); cert != first {
+		t.Fatalf("expected the parsed certificate to be reused")
+	}
+
+	// The renewed certificate is served once the cached secret refreshes.
+	renewedCertPEM, renewedKeyPEM := newTestKeyPair(t)
+	stages["AWSCURRENT"] = map[string]interface{}{"cert": renewedCertPEM, "key": renewedKeyPEM}
+	c.InvalidateCache(path)
+	renewed, err := getCertificate(hello)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if renewed == first || renewed.Leaf.Equal(first.Leaf) {
+		t.Fatalf("expected the renewed certificate")
+	}
+
+	// The last good certificate is served when the secret is unavailable.
+	delete(stages, "AWSCURRENT")
+	c.InvalidateCache(path)
+	if cert, err := getCertificate(hello); err != nil || cert != renewed {
+		t.Fatalf("expected the last good certificate, got: %v", err)
+	}
+
+	_, err = GetCertificateFunc(c, path)(hello)
+	if !isNotFound(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNewClientTLSConfig(t *testing.T) {
+	path := "authcrunch/caddy/idp_client_cert"
+	certPEM, keyPEM := newTestKeyPair(t)
+	stages := map[string]map[string]interface{}{}
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	c.SetMockClient(newStagedMockClient(t, stages))
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+	if _, err := NewClientTLSConfig(context.TODO(), c, path); !isNotFound(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stages["AWSCURRENT"] = map[string]interface{}{"cert": certPEM, "key": keyPEM}
+	cfg, err := NewClientTLSConfig(context.TODO(), c, path)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if cfg.MinVersion != tls.VersionTLS12 {
+		t.Errorf("unexpected min version: %x", cfg.MinVersion)
+	}
+	first, err := cfg.GetClientCertificate(&tls.CertificateRequestInfo{})
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	// The rotated certificate is presented once the cached secret refreshes.
+	rotatedCertPEM, rotatedKeyPEM := newTestKeyPair(t)
+	stages["AWSCURRENT"] = map[string]interface{}{"cert": rotatedCertPEM, "key": rotatedKeyPEM}
+	c.InvalidateCache(path)
+	rotated, err := cfg.GetClientCertificate(&tls.CertificateRequestInfo{})
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if rotated.Leaf.Equal(first.Leaf) {
+		t.Fatalf("expected the rotated certificate")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// TokenSecret is a shared secret used to sign and verify tokens.
+type TokenSecret struct {
+	ID    string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Usage string `json:"usage,omitempty" xml:"usage,omitempty" yaml:"usage,omitempty"`
+	Value string `json:"value,omitempty" xml:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// TokenSecrets holds the current and the previous versions of a shared
+// token secret. The current version is used to sign new tokens, while
+// both versions are used to verify the tokens signed before a rotation.
+type TokenSecrets struct {
+	Current  *TokenSecret `json:"current,omitempty" xml:"current,omitempty" yaml:"current,omitempty"`
+	Previous *TokenSecret `json:"previous,omitempty" xml:"previous,omitempty" yaml:"previous,omitempty"`
+}
+
+// GetTokenSecrets returns the AWSCURRENT and AWSPREVIOUS versions of the
+// shared token secret. The previous version is nil when the secret has not
+// been rotated yet.
+func (c *client) GetTokenSecrets(ctx context.Context, path string) (*TokenSecrets, error) {
+	m, err := c.getSecretValue(ctx, path, versionStageCurrent)
+	if err != nil {
+		return nil, err
+	}
+	current, err := parseTokenSecret(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed current version of %q secret: %v", path, err)
+	}
+	secrets := &TokenSecrets{Current: current}
+
+	m, err = c.getSecretValue(ctx, path, versionStagePrevious)
+	if err != nil {
+		if isNotFound(err) {
+			return secrets, nil
+		}
+		return nil, err
+	}
+	previous, err := parseTokenSecret(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed previous version of %q secret: %v", path, err)
+	}
+	secrets.Previous = previous
+	return secrets, nil
+}
+
+func parseTokenSecret(m map[string]interface{}) (*TokenSecret, error) {
+	secret := &TokenSecret{}
+	for k, ptr := range map[string]*string{"id": &secret.ID, "usage": &secret.Usage, "value": &secret.Value} {
+		v, exists := m[k]
+		if !exists {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("key %q value is not a string", k)
+		}
+		*ptr = s
+	}
+	if secret.Value == "" {
+		return nil, fmt.Errorf("key %q not found", "value")
+	}
+	return secret, nil
+}
+
+// isNotFound returns true when the error indicates that the requested
+// secret or its version does not exist.
+func isNotFound(err error) bool {
+	var notFound *types.ResourceNotFoundException
+	return errors.As(err, &notFound) || errors.Is(err, ErrSecretNotFound)
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// newStagedMockClient returns mock HTTP client serving secret versions
+// keyed by version stage.
+func newStagedMockClient(t *testing.T, stages map[string]map[string]interface{}) aws.HTTPClient {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		var input struct {
+			SecretId     string
+			VersionStage string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+			return nil, err
+		}
+		secret, exists := stages[input.VersionStage]
+		if !exists {
+			return secretsmock.NotFoundResponse("524b9962-6854-4b5c-aa53-81759ef610dd"), nil
+		}
+		return secretsmock.SecretStringResponse(secret), nil
+	})
+}
+
+func TestGetTokenSecrets(t *testing.T) {
+	testcases := []struct {
+		name      string
+		path      string
+		stages    map[string]map[string]interface{}
+		want      *TokenSecrets
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test rotated access token secret",
+			path: "authcrunch/caddy/access_token",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"id":    "1",
+					"usage": "sign-verify",
+					"value": "8b8c7b1e-6a2a-4e45-9d1c-1b0b6f0c2c51",
+				},
+				"AWSPREVIOUS": {
+					"id":    "0",
+					"usage": "sign-verify",
+					"value": "b006d65b-c923-46a1-8da1-7d52558508fe",
+				},
+			},
+			want: &TokenSecrets{
+				Current: &TokenSecret{
+					ID:    "1",
+					Usage: "sign-verify",
+					Value: "8b8c7b1e-6a2a-4e45-9d1c-1b0b6f0c2c51",
+				},
+				Previous: &TokenSecret{
+					ID:    "0",
+					Usage: "sign-verify",
+					Value: "b006d65b-c923-46a1-8da1-7d52558508fe",
+				},
+			},
+		},
+		{
+			name: "test access token secret without previous version",
+			path: "authcrunch/caddy/access_token",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"id":    "0",
+					"usage": "sign-verify",
+					"value": "b006d65b-c923-46a1-8da1-7d52558508fe",
+				},
+			},
+			want: &TokenSecrets{
+				Current: &TokenSecret{
+					ID:    "0",
+					Usage: "sign-verify",
+					Value: "b006d65b-c923-46a1-8da1-7d52558508fe",
+				},
+			},
+		},
+		{
+			name: "test access token secret without value",
+			path: "authcrunch/caddy/access_token",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"id":    "0",
+					"usage": "sign-verify",
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed current version of %q secret: key %q not found", "authcrunch/caddy/access_token", "value"),
+		},
+		{
+			name: "test malformed previous version of access token secret",
+			path: "authcrunch/caddy/access_token",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {
+					"id":    "1",
+					"value": "8b8c7b1e-6a2a-4e45-9d1c-1b0b6f0c2c51",
+				},
+				"AWSPREVIOUS": {
+					"id":    "0",
+					"value": 12345,
+				},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed previous version of %q secret: key %q value is not a string", "authcrunch/caddy/access_token", "value"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+
+			c.SetMockClient(newStagedMockClient(t, tc.stages))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetTokenSecrets(context.TODO(), tc.path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Logf("unexpected error: %v", err)
+					t.Fatalf("GetTokenSecrets() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetTokenSecrets() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+const (
+	// TOTPAlgorithmSHA1 is the default HMAC algorithm of TOTP.
+	TOTPAlgorithmSHA1 = "SHA1"
+	// TOTPAlgorithmSHA256 is the SHA-256 HMAC algorithm of TOTP.
+	TOTPAlgorithmSHA256 = "SHA256"
+	// TOTPAlgorithmSHA512 is the SHA-512 HMAC algorithm of TOTP.
+	TOTPAlgorithmSHA512 = "SHA512"
+
+	defaultTOTPDigits = 6
+	defaultTOTPPeriod = 30
+)
+
+// TOTPSeed holds the shared secret and the parameters of the time-based
+// one-time passwords of RFC 6238.
+type TOTPSeed struct {
+	// Secret is the base32-encoded shared secret, without padding.
+	Secret string `json:"secret,omitempty" xml:"secret,omitempty" yaml:"secret,omitempty"`
+	// Algorithm is one of SHA1, SHA256, or SHA512.
+	Algorithm string `json:"algorithm,omitempty" xml:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	// Digits is the length of the passwords, either 6 or 8.
+	Digits int `json:"digits,omitempty" xml:"digits,omitempty" yaml:"digits,omitempty"`
+	// Period is the validity of the passwords in seconds.
+	Period int `json:"period,omitempty" xml:"period,omitempty" yaml:"period,omitempty"`
+}
+
+// Key returns the decoded shared secret.
+func (seed *TOTPSeed) Key() ([]byte, error) {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(seed.Secret)
+}
+
+// GetTOTPSeed returns the TOTP seed stored in the secret, algorithm,
+// digits, and period keys of the secret. The secret is required. The
+// algorithm, digits, and period default to SHA1, 6, and 30 seconds, the
+// defaults of the authenticator apps.
+func (c *client) GetTOTPSeed(ctx context.Context, path string) (*TOTPSeed, error) {
+	m, err := c.GetSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := parseTOTPSeed(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	return seed, nil
+}
+
+func parseTOTPSeed(m map[string]interface{}) (*TOTPSeed, error) {
+	seed := &TOTPSeed{}
+	var err error
+	if seed.Secret, err = getStringValue(m, "secret", true); err != nil {
+		return nil, err
+	}
+	// The secrets are often written in groups of lowercase letters, e.g.
+	// "jbsw y3dp ehpk 3pxp", and with padding.
+	seed.Secret = strings.TrimRight(strings.ToUpper(strings.ReplaceAll(seed.Secret, " ", "")), "=")
+	if key, err := seed.Key(); err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("key %q value is not base32", "secret")
+	}
+	if seed.Algorithm, err = getStringValue(m, "algorithm", false); err != nil {
+		return nil, err
+	}
+	switch seed.Algorithm = strings.ToUpper(strings.ReplaceAll(seed.Algorithm, "-", "")); seed.Algorithm {
+	case "":
+		seed.Algorithm = TOTPAlgorithmSHA1
+	case TOTPAlgorithmSHA1, TOTPAlgorithmSHA256, TOTPAlgorithmSHA512:
+	default:
+		return nil, fmt.Errorf("unsupported %q algorithm", seed.Algorithm)
+	}
+	if seed.Digits, err = getIntValue(m, "digits"); err != nil {
+		return nil, err
+	}
+	switch seed.Digits {
+	case 0:
+		seed.Digits = defaultTOTPDigits
+	case 6, 8:
+	default:
+		return nil, fmt.Errorf("key %q value %d is not 6 or 8", "digits", seed.Digits)
+	}
+	if seed.Period, err = getIntValue(m, "period"); err != nil {
+		return nil, err
+	}
+	if seed.Period == 0 {
+		seed.Period = defaultTOTPPeriod
+	}
+	if seed.Period < 0 {
+		return nil, fmt.Errorf("key %q value %d is out of range", "period", seed.Period)
+	}
+	return seed, nil
+}
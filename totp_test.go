@@ -0,0 +1,116 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetTOTPSeed(t *testing.T) {
+	path := "authcrunch/users/jsmith/totp"
+	testcases := []struct {
+		name      string
+		secret    map[string]interface{}
+		want      *TOTPSeed
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test totp seed with defaults",
+			secret: map[string]interface{}{"secret": "jbsw y3dp ehpk 3pxp"},
+			want: &TOTPSeed{
+				Secret:    "JBSWY3DPEHPK3PXP",
+				Algorithm: "SHA1",
+				Digits:    6,
+				Period:    30,
+			},
+		},
+		{
+			name: "test totp seed",
+			secret: map[string]interface{}{
+				"secret":    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+				"algorithm": "sha-256",
+				"digits":    float64(8),
+				"period":    "60",
+			},
+			want: &TOTPSeed{
+				Secret:    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+				Algorithm: "SHA256",
+				Digits:    8,
+				Period:    60,
+			},
+		},
+		{
+			name:      "test totp seed with malformed secret",
+			secret:    map[string]interface{}{"secret": "not-base32!"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value is not base32", path, "secret"),
+		},
+		{
+			name:      "test totp seed with unsupported algorithm",
+			secret:    map[string]interface{}{"secret": "JBSWY3DPEHPK3PXP", "algorithm": "MD5"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: unsupported %q algorithm", path, "MD5"),
+		},
+		{
+			name:      "test totp seed with unsupported digits",
+			secret:    map[string]interface{}{"secret": "JBSWY3DPEHPK3PXP", "digits": float64(7)},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value 7 is not 6 or 8", path, "digits"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, map[string]map[string]interface{}{"AWSCURRENT": tc.secret}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetTOTPSeed(context.TODO(), path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("GetTOTPSeed() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetTOTPSeed() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTOTPSeedKey(t *testing.T) {
+	seed := &TOTPSeed{Secret: "JBSWY3DPEHPK3PXP"}
+	key, err := seed.Key()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(key) != "Hello!\xde\xad\xbe\xef" {
+		t.Errorf("unexpected key: %x", key)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Get returns the secret at the path decoded into the value of the type,
+// e.g. a struct with the json tags matching the keys of the secret:
+//
+//	type smtpSecret struct {
+//		Host     string `json:"host"`
+//		Password string `json:"password"`
+//	}
+//	s, err := secrets.Get[smtpSecret](ctx, c, "authcrunch/smtp")
+//
+// The secret is retrieved with GetSecret, so the cache and the call
+// options apply. The unknown keys are ignored.
+func Get[T any](ctx context.Context, c Client, path string, opts ...CallOption) (T, error) {
+	var v T
+	m, err := c.GetSecret(ctx, path, opts...)
+	if err != nil {
+		return v, err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return v, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	return v, nil
+}
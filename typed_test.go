@@ -0,0 +1,101 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testTypedSecret struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles"`
+}
+
+func TestGet(t *testing.T) {
+	path := "authcrunch/caddy/smtp"
+	testcases := []struct {
+		name      string
+		secret    map[string]interface{}
+		want      testTypedSecret
+		shouldErr bool
+		errPrefix string
+	}{
+		{
+			name: "test typed secret",
+			secret: map[string]interface{}{
+				"host":     "smtp.contoso.com",
+				"port":     float64(587),
+				"password": "P@ssW0rd123",
+				"roles":    []interface{}{"admin"},
+				"comment":  "ignored",
+			},
+			want: testTypedSecret{
+				Host:     "smtp.contoso.com",
+				Port:     587,
+				Password: "P@ssW0rd123",
+				Roles:    []string{"admin"},
+			},
+		},
+		{
+			name:      "test typed secret with mismatched type",
+			secret:    map[string]interface{}{"port": "587"},
+			shouldErr: true,
+			errPrefix: fmt.Sprintf("malformed %q secret: json: cannot unmarshal string", path),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, map[string]map[string]interface{}{"AWSCURRENT": tc.secret}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := Get[testTypedSecret](context.TODO(), c, path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if !strings.HasPrefix(err.Error(), tc.errPrefix) {
+					t.Fatalf("unexpected error: %v, want prefix: %s", err, tc.errPrefix)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %s", tc.errPrefix)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Get() mismatch (-want +got):\n%s", diff)
+			}
+
+			// The map types are supported as well.
+			m, err := Get[map[string]interface{}](context.TODO(), c, path)
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(tc.secret, m); diff != "" {
+				t.Errorf("Get() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// UserCredentials holds the credentials of a user stored in the user
+// secret, e.g. "users/jsmith".
+type UserCredentials struct {
+	Username string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	// Password is either the plain text password or the hashed one, e.g.
+	// "bcrypt:10:$2a$10$...".
+	Password string   `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+	Email    string   `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Name     string   `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	APIKey   string   `json:"api_key,omitempty" xml:"api_key,omitempty" yaml:"api_key,omitempty"`
+	Roles    []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// GetUserCredentials returns the user credentials stored in the username,
+// password, email, name, api_key, and roles keys of the secret. The
+// username is required.
+func (c *client) GetUserCredentials(ctx context.Context, path string) (*UserCredentials, error) {
+	m, err := c.GetSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := parseUserCredentials(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed %q secret: %v", path, err)
+	}
+	return creds, nil
+}
+
+func parseUserCredentials(m map[string]interface{}) (*UserCredentials, error) {
+	creds := &UserCredentials{}
+	var err error
+	if creds.Username, err = getStringValue(m, "username", true); err != nil {
+		return nil, err
+	}
+	if !usernameRgx.MatchString(creds.Username) {
+		return nil, fmt.Errorf("malformed %q username", creds.Username)
+	}
+	if creds.Password, err = getStringValue(m, "password", false); err != nil {
+		return nil, err
+	}
+	if creds.Email, err = getStringValue(m, "email", false); err != nil {
+		return nil, err
+	}
+	if creds.Email != "" && strings.LastIndex(creds.Email, "@") < 1 {
+		return nil, fmt.Errorf("malformed %q email", creds.Email)
+	}
+	if creds.Name, err = getStringValue(m, "name", false); err != nil {
+		return nil, err
+	}
+	if creds.APIKey, err = getStringValue(m, "api_key", false); err != nil {
+		return nil, err
+	}
+	if creds.Roles, err = getStringSliceValue(m, "roles"); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetUserCredentials(t *testing.T) {
+	path := "authcrunch/caddy/users/jsmith"
+	testcases := []struct {
+		name      string
+		secret    map[string]interface{}
+		want      *UserCredentials
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test user credentials",
+			secret: map[string]interface{}{
+				"username": "jsmith",
+				"password": "bcrypt:10:$2a$10$TEQ7ZG9cAdWwhQK36orCGOlokqQA55ddE0WEsl00oLZh567okdcZ6",
+				"email":    "jsmith@localhost.localdomain",
+				"name":     "John Smith",
+				"api_key":  "bcrypt:10:$2a$10$TEQ7ZG9cAdWwhQK36orCGOlokqQA55ddE0WEsl00oLZh567okdcZ6",
+				"roles":    []interface{}{"authp/admin", "authp/user"},
+			},
+			want: &UserCredentials{
+				Username: "jsmith",
+				Password: "bcrypt:10:$2a$10$TEQ7ZG9cAdWwhQK36orCGOlokqQA55ddE0WEsl00oLZh567okdcZ6",
+				Email:    "jsmith@localhost.localdomain",
+				Name:     "John Smith",
+				APIKey:   "bcrypt:10:$2a$10$TEQ7ZG9cAdWwhQK36orCGOlokqQA55ddE0WEsl00oLZh567okdcZ6",
+				Roles:    []string{"authp/admin", "authp/user"},
+			},
+		},
+		{
+			name:   "test user credentials with username only",
+			secret: map[string]interface{}{"username": "jsmith"},
+			want:   &UserCredentials{Username: "jsmith"},
+		},
+		{
+			name:      "test user credentials without username",
+			secret:    map[string]interface{}{"password": "foobar"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q not found", path, "username"),
+		},
+		{
+			name:      "test user credentials with malformed email",
+			secret:    map[string]interface{}{"username": "jsmith", "email": "jsmith"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: malformed %q email", path, "jsmith"),
+		},
+		{
+			name:      "test user credentials with malformed roles",
+			secret:    map[string]interface{}{"username": "jsmith", "roles": "admin"},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value is not a list", path, "roles"),
+		},
+		{
+			name:      "test user credentials with empty role",
+			secret:    map[string]interface{}{"username": "jsmith", "roles": []interface{}{"admin", ""}},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed %q secret: key %q value has malformed item 1", path, "roles"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+
+			c.SetMockClient(newStagedMockClient(t, map[string]map[string]interface{}{"AWSCURRENT": tc.secret}))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetUserCredentials(context.TODO(), path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("GetUserCredentials() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetUserCredentials() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
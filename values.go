@@ -0,0 +1,170 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrKeyValueType is matched by the errors of the secret values of an
+// unexpected type.
+var ErrKeyValueType = errors.New("unexpected key value type")
+
+// KeyTypeError is the error of GetSecretByKey when the value of the key is
+// not a string. It matches ErrKeyValueType.
+type KeyTypeError struct {
+	Path string
+	Key  string
+	// Type is the JSON type of the value, e.g. "number" or "array".
+	Type string
+}
+
+// Error implements error interface.
+func (e *KeyTypeError) Error() string {
+	return fmt.Sprintf("key %q value in %q secret is %s, not string", e.Key, e.Path, e.Type)
+}
+
+// Is reports whether the target is ErrKeyValueType.
+func (e *KeyTypeError) Is(target error) bool {
+	return target == ErrKeyValueType
+}
+
+// getStringValue returns the string value of the key. When the key is
+// required, the value must not be empty.
+func getStringValue(m map[string]interface{}, k string, required bool) (string, error) {
+	v, exists := m[k]
+	if !exists {
+		if required {
+			return "", fmt.Errorf("key %q not found", k)
+		}
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("key %q value is not a string", k)
+	}
+	if s == "" && required {
+		return "", fmt.Errorf("key %q value is empty", k)
+	}
+	return s, nil
+}
+
+// getStringSliceValue returns the list of non-empty strings of the key.
+// It returns nil when the key does not exist.
+func getStringSliceValue(m map[string]interface{}, k string) ([]string, error) {
+	v, exists := m[k]
+	if !exists {
+		return nil, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("key %q value is not a list", k)
+	}
+	list := make([]string, 0, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("key %q value has malformed item %d", k, i)
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// getExtraValues returns the key-value pairs other than the provided keys.
+func getExtraValues(m map[string]interface{}, keys ...string) map[string]interface{} {
+	known := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		known[k] = true
+	}
+	var extra map[string]interface{}
+	for k, v := range m {
+		if known[k] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]interface{})
+		}
+		extra[k] = v
+	}
+	return extra
+}
+
+// getIntValue returns the integer value of the key. The value is either
+// a number or a string. It returns zero when the key does not exist.
+func getIntValue(m map[string]interface{}, k string) (int, error) {
+	v, exists := m[k]
+	if !exists {
+		return 0, nil
+	}
+	switch n := v.(type) {
+	case float64:
+		if n != float64(int(n)) {
+			return 0, fmt.Errorf("key %q value is not an integer", k)
+		}
+		return int(n), nil
+	case string:
+		i, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, fmt.Errorf("key %q value is not an integer", k)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("key %q value is not an integer", k)
+}
+
+// getBoolValue returns the boolean value of the key. The value is either
+// a boolean or a string. It returns false when the key does not exist.
+func getBoolValue(m map[string]interface{}, k string) (bool, error) {
+	v, exists := m[k]
+	if !exists {
+		return false, nil
+	}
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case string:
+		parsed, err := strconv.ParseBool(b)
+		if err != nil {
+			return false, fmt.Errorf("key %q value is not a boolean", k)
+		}
+		return parsed, nil
+	}
+	return false, fmt.Errorf("key %q value is not a boolean", k)
+}
+
+// getTimeValue returns the time value of the key. The value is either an
+// RFC 3339 string or a number of Unix seconds. It returns zero time when
+// the key does not exist.
+func getTimeValue(m map[string]interface{}, k string) (time.Time, error) {
+	v, exists := m[k]
+	if !exists {
+		return time.Time{}, nil
+	}
+	switch t := v.(type) {
+	case float64:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("key %q value is not a time", k)
+		}
+		return parsed, nil
+	}
+	return time.Time{}, fmt.Errorf("key %q value is not a time", k)
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValues(t *testing.T) {
+	secret := map[string]interface{}{
+		"username":   "jsmith",
+		"empty":      "",
+		"port":       float64(5432),
+		"port_str":   "5432",
+		"ratio":      0.5,
+		"enabled":    true,
+		"enabled_s":  "true",
+		"expires":    "2022-06-01T12:00:00Z",
+		"expires_n":  float64(1654084800),
+		"scopes":     []interface{}{"read", "write"},
+		"bad_scopes": []interface{}{"read", float64(1)},
+		"gaps":       []interface{}{"read", ""},
+		"nested":     map[string]interface{}{"foo": "bar"},
+		"nothing":    nil,
+	}
+	str := func(k string, required bool) func() (interface{}, error) {
+		return func() (interface{}, error) { return getStringValue(secret, k, required) }
+	}
+	list := func(k string) func() (interface{}, error) {
+		return func() (interface{}, error) { return getStringSliceValue(secret, k) }
+	}
+	integer := func(k string) func() (interface{}, error) {
+		return func() (interface{}, error) { return getIntValue(secret, k) }
+	}
+	boolean := func(k string) func() (interface{}, error) {
+		return func() (interface{}, error) { return getBoolValue(secret, k) }
+	}
+	timestamp := func(k string) func() (interface{}, error) {
+		return func() (interface{}, error) { return getTimeValue(secret, k) }
+	}
+	expires := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name      string
+		get       func() (interface{}, error)
+		want      interface{}
+		shouldErr bool
+		err       error
+	}{
+		{name: "test string", get: str("username", true), want: "jsmith"},
+		{name: "test missing optional string", get: str("password", false), want: ""},
+		{name: "test missing required string", get: str("password", true), shouldErr: true, err: fmt.Errorf("key %q not found", "password")},
+		{name: "test empty required string", get: str("empty", true), shouldErr: true, err: fmt.Errorf("key %q value is empty", "empty")},
+		{name: "test number as string", get: str("port", false), shouldErr: true, err: fmt.Errorf("key %q value is not a string", "port")},
+		{name: "test array as string", get: str("scopes", false), shouldErr: true, err: fmt.Errorf("key %q value is not a string", "scopes")},
+		{name: "test null as string", get: str("nothing", false), shouldErr: true, err: fmt.Errorf("key %q value is not a string", "nothing")},
+
+		{name: "test string list", get: list("scopes"), want: []string{"read", "write"}},
+		{name: "test missing string list", get: list("roles"), want: []string(nil)},
+		{name: "test string as list", get: list("username"), shouldErr: true, err: fmt.Errorf("key %q value is not a list", "username")},
+		{name: "test object as list", get: list("nested"), shouldErr: true, err: fmt.Errorf("key %q value is not a list", "nested")},
+		{name: "test list with non-string item", get: list("bad_scopes"), shouldErr: true, err: fmt.Errorf("key %q value has malformed item %d", "bad_scopes", 1)},
+		{name: "test list with empty item", get: list("gaps"), shouldErr: true, err: fmt.Errorf("key %q value has malformed item %d", "gaps", 1)},
+
+		{name: "test integer", get: integer("port"), want: 5432},
+		{name: "test integer string", get: integer("port_str"), want: 5432},
+		{name: "test missing integer", get: integer("timeout"), want: 0},
+		{name: "test fraction as integer", get: integer("ratio"), shouldErr: true, err: fmt.Errorf("key %q value is not an integer", "ratio")},
+		{name: "test malformed integer string", get: integer("username"), shouldErr: true, err: fmt.Errorf("key %q value is not an integer", "username")},
+		{name: "test boolean as integer", get: integer("enabled"), shouldErr: true, err: fmt.Errorf("key %q value is not an integer", "enabled")},
+		{name: "test array as integer", get: integer("scopes"), shouldErr: true, err: fmt.Errorf("key %q value is not an integer", "scopes")},
+
+		{name: "test boolean", get: boolean("enabled"), want: true},
+		{name: "test boolean string", get: boolean("enabled_s"), want: true},
+		{name: "test missing boolean", get: boolean("disabled"), want: false},
+		{name: "test malformed boolean string", get: boolean("username"), shouldErr: true, err: fmt.Errorf("key %q value is not a boolean", "username")},
+		{name: "test number as boolean", get: boolean("port"), shouldErr: true, err: fmt.Errorf("key %q value is not a boolean", "port")},
+		{name: "test array as boolean", get: boolean("scopes"), shouldErr: true, err: fmt.Errorf("key %q value is not a boolean", "scopes")},
+
+		{name: "test time", get: timestamp("expires"), want: expires},
+		{name: "test unix time", get: timestamp("expires_n"), want: expires},
+		{name: "test missing time", get: timestamp("created"), want: time.Time{}},
+		{name: "test malformed time string", get: timestamp("username"), shouldErr: true, err: fmt.Errorf("key %q value is not a time", "username")},
+		{name: "test boolean as time", get: timestamp("enabled"), shouldErr: true, err: fmt.Errorf("key %q value is not a time", "enabled")},
+		{name: "test array as time", get: timestamp("scopes"), shouldErr: true, err: fmt.Errorf("key %q value is not a time", "scopes")},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.get()
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(tc.err.Error(), err.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("value mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetExtraValues(t *testing.T) {
+	secret := map[string]interface{}{"username": "jsmith", "password": "secret", "note": "admin", "port": float64(22)}
+	if diff := cmp.Diff(map[string]interface{}{"note": "admin", "port": float64(22)}, getExtraValues(secret, "username", "password")); diff != "" {
+		t.Errorf("getExtraValues() mismatch (-want +got):\n%s", diff)
+	}
+	if got := getExtraValues(secret, "username", "password", "note", "port"); got != nil {
+		t.Errorf("unexpected extra values: %v", got)
+	}
+}
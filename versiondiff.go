@@ -0,0 +1,122 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+)
+
+// DiffOption configures DiffSecretVersions.
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	unmasked bool
+}
+
+// WithUnmaskedValues makes DiffSecretVersions return the actual values of
+// the added, removed, and modified keys instead of the masked ones.
+func WithUnmaskedValues() DiffOption {
+	return func(o *diffOptions) {
+		o.unmasked = true
+	}
+}
+
+// ValueChange holds the values of a key in the compared versions. The
+// value is nil when the version lacks the key.
+type ValueChange struct {
+	From interface{} `json:"from,omitempty" xml:"from,omitempty" yaml:"from,omitempty"`
+	To   interface{} `json:"to,omitempty" xml:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// VersionDiff describes the differences between two versions of a secret.
+type VersionDiff struct {
+	Path         string   `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	From         string   `json:"from,omitempty" xml:"from,omitempty" yaml:"from,omitempty"`
+	To           string   `json:"to,omitempty" xml:"to,omitempty" yaml:"to,omitempty"`
+	AddedKeys    []string `json:"added_keys,omitempty" xml:"added_keys,omitempty" yaml:"added_keys,omitempty"`
+	RemovedKeys  []string `json:"removed_keys,omitempty" xml:"removed_keys,omitempty" yaml:"removed_keys,omitempty"`
+	ModifiedKeys []string `json:"modified_keys,omitempty" xml:"modified_keys,omitempty" yaml:"modified_keys,omitempty"`
+	// Values are the values of the added, removed, and modified keys. They
+	// are masked unless WithUnmaskedValues is set.
+	Values map[string]*ValueChange `json:"values,omitempty" xml:"-" yaml:"values,omitempty"`
+}
+
+// Empty returns true when the versions hold the same keys and values.
+func (d *VersionDiff) Empty() bool {
+	return len(d.AddedKeys) == 0 && len(d.RemovedKeys) == 0 && len(d.ModifiedKeys) == 0
+}
+
+// newVersionRequest returns the request of the version of the secret. The
+// version is either a version id or a version stage, e.g. "AWSPREVIOUS".
+func newVersionRequest(path, version string) *secretRequest {
+	req := &secretRequest{path: path, noStore: true}
+	if versionIDRgx.MatchString(version) {
+		req.versionID = version
+	} else {
+		req.stage = version
+	}
+	return req
+}
+
+// DiffSecretVersions compares the versions of the secret at the path. The
+// versions are either version ids or version stages. The fetched versions
+// are not cached.
+func (c *client) DiffSecretVersions(ctx context.Context, path, v1, v2 string, opts ...DiffOption) (*VersionDiff, error) {
+	o := &diffOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	from, err := c.fetchSecret(ctx, newVersionRequest(path, v1))
+	if err != nil {
+		return nil, err
+	}
+	to, err := c.fetchSecret(ctx, newVersionRequest(path, v2))
+	if err != nil {
+		return nil, err
+	}
+	return newVersionDiff(path, v1, v2, from, to, o.unmasked), nil
+}
+
+func newVersionDiff(path, v1, v2 string, from, to map[string]interface{}, unmasked bool) *VersionDiff {
+	ch := newChange("diff", path, from, to)
+	d := &VersionDiff{
+		Path:         path,
+		From:         v1,
+		To:           v2,
+		AddedKeys:    ch.AddedKeys,
+		RemovedKeys:  ch.RemovedKeys,
+		ModifiedKeys: ch.ModifiedKeys,
+	}
+	if d.Empty() {
+		return d
+	}
+	value := func(m map[string]interface{}, k string) interface{} {
+		v, exists := m[k]
+		if !exists {
+			return nil
+		}
+		if !unmasked {
+			return redactedSecret
+		}
+		return v
+	}
+	d.Values = make(map[string]*ValueChange)
+	for _, keys := range [][]string{d.AddedKeys, d.RemovedKeys, d.ModifiedKeys} {
+		for _, k := range keys {
+			d.Values[k] = &ValueChange{From: value(from, k), To: value(to, k)}
+		}
+	}
+	return d
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffSecretVersions(t *testing.T) {
+	stages := map[string]map[string]interface{}{
+		"AWSPREVIOUS": {"username": "jsmith", "password": "foo", "host": "ldap1"},
+		"AWSCURRENT":  {"username": "jsmith", "password": "bar", "port": "636"},
+	}
+	testcases := []struct {
+		name string
+		opts []DiffOption
+		v2   string
+		want *VersionDiff
+	}{
+		{
+			name: "test masked values",
+			v2:   "AWSCURRENT",
+			want: &VersionDiff{
+				Path:         "authcrunch/ldap",
+				From:         "AWSPREVIOUS",
+				To:           "AWSCURRENT",
+				AddedKeys:    []string{"port"},
+				RemovedKeys:  []string{"host"},
+				ModifiedKeys: []string{"password"},
+				Values: map[string]*ValueChange{
+					"port":     {To: "[REDACTED]"},
+					"host":     {From: "[REDACTED]"},
+					"password": {From: "[REDACTED]", To: "[REDACTED]"},
+				},
+			},
+		},
+		{
+			name: "test unmasked values",
+			opts: []DiffOption{WithUnmaskedValues()},
+			v2:   "AWSCURRENT",
+			want: &VersionDiff{
+				Path:         "authcrunch/ldap",
+				From:         "AWSPREVIOUS",
+				To:           "AWSCURRENT",
+				AddedKeys:    []string{"port"},
+				RemovedKeys:  []string{"host"},
+				ModifiedKeys: []string{"password"},
+				Values: map[string]*ValueChange{
+					"port":     {To: "636"},
+					"host":     {From: "ldap1"},
+					"password": {From: "foo", To: "bar"},
+				},
+			},
+		},
+		{
+			name: "test same version",
+			v2:   "AWSPREVIOUS",
+			want: &VersionDiff{
+				Path: "authcrunch/ldap",
+				From: "AWSPREVIOUS",
+				To:   "AWSPREVIOUS",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, stages))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.DiffSecretVersions(context.TODO(), "authcrunch/ldap", "AWSPREVIOUS", tc.v2, tc.opts...)
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("DiffSecretVersions() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewVersionRequest(t *testing.T) {
+	if req := newVersionRequest("ldap", testPinnedVersionID); req.versionID != testPinnedVersionID || req.stage != "" {
+		t.Errorf("unexpected request for version id: %+v", req)
+	}
+	if req := newVersionRequest("ldap", "AWSPENDING"); req.versionID != "" || req.stage != "AWSPENDING" {
+		t.Errorf("unexpected request for version stage: %+v", req)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+const (
+	// VersionAnnotationTagPrefix is the prefix of the keys of the secret
+	// tags holding the annotations of the versions of the secret. The key
+	// is the prefix followed by the version id.
+	VersionAnnotationTagPrefix = "authcrunch:version:"
+
+	maxVersionAnnotationLength = 256
+)
+
+// versionAnnotationRgx matches the characters AWS allows in the values of
+// the tags.
+var versionAnnotationRgx = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// ErrVersionHistoryNotSupported is returned when the backend of the client
+// does not keep the versions of the secrets.
+var ErrVersionHistoryNotSupported = errors.New("version history is not supported by the backend")
+
+// versionLister is implemented by the service clients listing the versions
+// of the secrets, e.g. *secretsmanager.Client.
+type versionLister interface {
+	ListSecretVersionIds(context.Context, *secretsmanager.ListSecretVersionIdsInput, ...func(*secretsmanager.Options)) (*secretsmanager.ListSecretVersionIdsOutput, error)
+}
+
+// resourceTagger is implemented by the service clients tagging the
+// secrets, e.g. *secretsmanager.Client.
+type resourceTagger interface {
+	TagResource(context.Context, *secretsmanager.TagResourceInput, ...func(*secretsmanager.Options)) (*secretsmanager.TagResourceOutput, error)
+	UntagResource(context.Context, *secretsmanager.UntagResourceInput, ...func(*secretsmanager.Options)) (*secretsmanager.UntagResourceOutput, error)
+}
+
+// SecretVersionInfo describes a version of a secret without its value.
+type SecretVersionInfo struct {
+	VersionID        string    `json:"version_id,omitempty" xml:"version_id,omitempty" yaml:"version_id,omitempty"`
+	Stages           []string  `json:"stages,omitempty" xml:"stages,omitempty" yaml:"stages,omitempty"`
+	CreatedDate      time.Time `json:"created_date,omitempty" xml:"created_date,omitempty" yaml:"created_date,omitempty"`
+	LastAccessedDate time.Time `json:"last_accessed_date,omitempty" xml:"last_accessed_date,omitempty" yaml:"last_accessed_date,omitempty"`
+	// Deprecated is true for the versions without the version stages,
+	// which AWS Secrets Manager eventually deletes.
+	Deprecated bool `json:"deprecated,omitempty" xml:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// Annotation is the note written with AnnotateVersion.
+	Annotation string `json:"annotation,omitempty" xml:"annotation,omitempty" yaml:"annotation,omitempty"`
+}
+
+// ListVersionHistory returns the versions of the secret at the path,
+// including the deprecated ones, from the oldest to the newest.
+func (c *client) ListVersionHistory(ctx context.Context, path string) ([]*SecretVersionInfo, error) {
+	m, err := c.DescribeSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := c.getConfig()
+	name, err := cfg.resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var region, endpoint string
+	if route := cfg.route(path); route != nil {
+		region, endpoint = route.Region, route.Endpoint
+	}
+	api, ok := c.getServiceClient(region, endpoint).(versionLister)
+	if !ok {
+		return nil, ErrVersionHistoryNotSupported
+	}
+	paginator := secretsmanager.NewListSecretVersionIdsPaginator(api, &secretsmanager.ListSecretVersionIdsInput{
+		SecretId:          aws.String(name),
+		IncludeDeprecated: aws.Bool(true),
+	})
+	var versions []*SecretVersionInfo
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range output.Versions {
+			versions = append(versions, newSecretVersionInfo(entry, m))
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].CreatedDate.Before(versions[j].CreatedDate)
+	})
+	return versions, nil
+}
+
+// newSecretVersionInfo returns the description of the listed version. The
+// stages in the metadata of the secret take precedence.
+func newSecretVersionInfo(entry types.SecretVersionsListEntry, m *SecretMetadata) *SecretVersionInfo {
+	v := &SecretVersionInfo{
+		VersionID:        aws.ToString(entry.VersionId),
+		Stages:           entry.VersionStages,
+		CreatedDate:      aws.ToTime(entry.CreatedDate),
+		LastAccessedDate: aws.ToTime(entry.LastAccessedDate),
+	}
+	if stages, exists := m.VersionIdsToStages[v.VersionID]; exists {
+		v.Stages = stages
+	}
+	v.Deprecated = len(v.Stages) == 0
+	v.Annotation = m.Tags[VersionAnnotationTagPrefix+v.VersionID]
+	return v
+}
+
+// AnnotateVersion writes the annotation of the version of the secret at
+// the path, e.g. the ticket of the rotation, as the tag of the secret. The
+// annotation is up to 256 letters, numbers, spaces, and "_.:/=+-@", as
+// AWS allows in the values of the tags. AWS also allows 50 tags per
+// secret, the annotations included, so the annotations of the versions
+// no longer existing are removed.
+func (c *client) AnnotateVersion(ctx context.Context, path, versionID, annotation string) (*Change, error) {
+	if !versionIDRgx.MatchString(versionID) {
+		return nil, fmt.Errorf("malformed %q version id", versionID)
+	}
+	if utf8.RuneCountInString(annotation) > maxVersionAnnotationLength {
+		return nil, fmt.Errorf("version annotation exceeds %d characters", maxVersionAnnotationLength)
+	}
+	if !versionAnnotationRgx.MatchString(annotation) {
+		return nil, fmt.Errorf("malformed %q version annotation", annotation)
+	}
+	req, err := c.newWriteRequest("annotate", path)
+	if err != nil {
+		return nil, err
+	}
+	api, ok := req.serviceClient.(resourceTagger)
+	if !ok {
+		return nil, ErrVersionHistoryNotSupported
+	}
+	lister, ok := req.serviceClient.(versionLister)
+	if !ok {
+		return nil, ErrVersionHistoryNotSupported
+	}
+	staleKeys, err := staleVersionAnnotations(ctx, req, lister, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return c.apply(req, &Change{Op: "annotate", Path: path}, func() error {
+		if len(staleKeys) > 0 {
+			if _, err := api.UntagResource(ctx, &secretsmanager.UntagResourceInput{
+				SecretId: aws.String(req.name),
+				TagKeys:  staleKeys,
+			}); err != nil {
+				return err
+			}
+		}
+		_, err := api.TagResource(ctx, &secretsmanager.TagResourceInput{
+			SecretId: aws.String(req.name),
+			Tags: []types.Tag{
+				{Key: aws.String(VersionAnnotationTagPrefix + versionID), Value: aws.String(annotation)},
+			},
+		})
+		return err
+	})
+}
+
+// staleVersionAnnotations returns the keys of the tags annotating the
+// versions of the secret no longer existing. It fails when the annotated
+// version does not exist.
+func staleVersionAnnotations(ctx context.Context, req *writeRequest, api versionLister, versionID string) ([]string, error) {
+	output, err := req.serviceClient.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(req.name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]bool)
+	paginator := secretsmanager.NewListSecretVersionIdsPaginator(api, &secretsmanager.ListSecretVersionIdsInput{
+		SecretId:          aws.String(req.name),
+		IncludeDeprecated: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range page.Versions {
+			versions[aws.ToString(entry.VersionId)] = true
+		}
+	}
+	if !versions[versionID] {
+		return nil, fmt.Errorf("%q version of %q secret not found", versionID, req.path)
+	}
+	var keys []string
+	for _, tag := range output.Tags {
+		key := aws.ToString(tag.Key)
+		if strings.HasPrefix(key, VersionAnnotationTagPrefix) && !versions[strings.TrimPrefix(key, VersionAnnotationTagPrefix)] {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
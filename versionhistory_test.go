@@ -0,0 +1,132 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+const (
+	testVersionID1 = "11111111-f3e3-4280-a444-333d7186d5ce"
+	testVersionID2 = "22222222-f3e3-4280-a444-333d7186d5ce"
+	testVersionID3 = "33333333-f3e3-4280-a444-333d7186d5ce"
+	testVersionID4 = "44444444-f3e3-4280-a444-333d7186d5ce"
+)
+
+func TestVersionHistory(t *testing.T) {
+	var tags []map[string]string
+	var untagged []string
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			var input struct {
+				IncludeDeprecated bool
+				Tags              []map[string]string
+				TagKeys           []string
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				return mockFailure(t, "failed parsing request: %v", err)
+			}
+			switch target := r.Header.Get("X-Amz-Target"); target {
+			case "secretsmanager.DescribeSecret":
+				return secretsmock.JSONResponse(map[string]interface{}{
+					"Tags": []map[string]interface{}{
+						{"Key": "env", "Value": "prod"},
+						{"Key": VersionAnnotationTagPrefix + testVersionID3, "Value": "INC-42"},
+						{"Key": VersionAnnotationTagPrefix + testVersionID4, "Value": "INC-7"},
+					},
+					"VersionIdsToStages": map[string]interface{}{
+						testVersionID2: []string{"AWSPREVIOUS"},
+						testVersionID3: []string{"AWSCURRENT"},
+					},
+				}), nil
+			case "secretsmanager.ListSecretVersionIds":
+				if !input.IncludeDeprecated {
+					return mockFailure(t, "deprecated versions not requested")
+				}
+				return secretsmock.JSONResponse(map[string]interface{}{
+					"Versions": []map[string]interface{}{
+						{"VersionId": testVersionID3, "VersionStages": []string{"AWSCURRENT"}, "CreatedDate": 1673135119},
+						{"VersionId": testVersionID1, "CreatedDate": 1670456719},
+						{"VersionId": testVersionID2, "VersionStages": []string{"AWSPREVIOUS"}, "CreatedDate": 1671966319},
+					},
+				}), nil
+			case "secretsmanager.TagResource":
+				tags = input.Tags
+				return secretsmock.JSONResponse(map[string]interface{}{}), nil
+			case "secretsmanager.UntagResource":
+				untagged = input.TagKeys
+				return secretsmock.JSONResponse(map[string]interface{}{}), nil
+			default:
+				return mockFailure(t, "unexpected %q target", target)
+			}
+		})),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+
+	got, err := c.ListVersionHistory(context.TODO(), "authcrunch/ldap")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	want := []*SecretVersionInfo{
+		{VersionID: testVersionID1, CreatedDate: time.Unix(1670456719, 0), Deprecated: true},
+		{VersionID: testVersionID2, Stages: []string{"AWSPREVIOUS"}, CreatedDate: time.Unix(1671966319, 0)},
+		{VersionID: testVersionID3, Stages: []string{"AWSCURRENT"}, CreatedDate: time.Unix(1673135119, 0), Annotation: "INC-42"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ListVersionHistory() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := c.AnnotateVersion(context.TODO(), "authcrunch/ldap", testVersionID2, "rolled back"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff([]map[string]string{{"Key": VersionAnnotationTagPrefix + testVersionID2, "Value": "rolled back"}}, tags); diff != "" {
+		t.Fatalf("tags mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{VersionAnnotationTagPrefix + testVersionID4}, untagged); diff != "" {
+		t.Fatalf("removed tags mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, tc := range []struct {
+		versionID  string
+		annotation string
+		err        string
+	}{
+		{versionID: "v1", annotation: "foo", err: `malformed "v1" version id`},
+		{versionID: testVersionID2, annotation: strings.Repeat("é", 257), err: "version annotation exceeds 256 characters"},
+		{versionID: testVersionID2, annotation: "rolled back; see INC-42", err: `malformed "rolled back; see INC-42" version annotation`},
+		{versionID: testVersionID4, annotation: "INC-7", err: `"` + testVersionID4 + `" version of "authcrunch/ldap" secret not found`},
+	} {
+		if _, err := c.AnnotateVersion(context.TODO(), "authcrunch/ldap", tc.versionID, tc.annotation); err == nil || err.Error() != tc.err {
+			t.Errorf("unexpected error: %v, want: %s", err, tc.err)
+		}
+	}
+	if _, err := c.AnnotateVersion(context.TODO(), "authcrunch/ldap", testVersionID2, strings.Repeat("é", 256)); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+}
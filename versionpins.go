@@ -0,0 +1,87 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var versionIDRgx = regexp.MustCompile(`^[a-zA-Z0-9-]{32,64}$`)
+
+// VersionPin pins the secret at the path to the version, e.g. for a staged
+// rollout or a forensic investigation. The requests for AWSCURRENT version
+// of the secret return the pinned version instead. The requests for the
+// other version stages are not affected.
+type VersionPin struct {
+	Path      string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	VersionID string `json:"version_id,omitempty" xml:"version_id,omitempty" yaml:"version_id,omitempty"`
+	// Reason is the free-form note explaining the pin, e.g. the ticket
+	// number. It is returned with the pinned secret.
+	Reason string `json:"reason,omitempty" xml:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+func (p *VersionPin) validate() error {
+	if p.Path == "" {
+		return errors.New("version pin path is empty")
+	}
+	if !versionIDRgx.MatchString(p.VersionID) {
+		return fmt.Errorf("malformed %q version id in pin for %q path", p.VersionID, p.Path)
+	}
+	return nil
+}
+
+// pin returns the version pin of the path, or nil when the path is not
+// pinned.
+func (cfg *ClientConfig) pin(path string) *VersionPin {
+	for _, p := range cfg.VersionPins {
+		if p.Path == path {
+			return p
+		}
+	}
+	return nil
+}
+
+// SecretVersion is the secret with the information about the version
+// returned.
+type SecretVersion struct {
+	// Data is the key-value map of the secret.
+	Data map[string]interface{} `json:"-" xml:"-" yaml:"-"`
+	// VersionStage is the requested version stage. It is empty when the
+	// pinned version was returned.
+	VersionStage string `json:"version_stage,omitempty" xml:"version_stage,omitempty" yaml:"version_stage,omitempty"`
+	// VersionID is the id of the pinned version.
+	VersionID string `json:"version_id,omitempty" xml:"version_id,omitempty" yaml:"version_id,omitempty"`
+	// Pin is the pin applied to the secret, or nil.
+	Pin *VersionPin `json:"pin,omitempty" xml:"pin,omitempty" yaml:"pin,omitempty"`
+}
+
+// GetSecretVersion returns the current version of the secret, or the
+// pinned one, along with the pin applied.
+func (c *client) GetSecretVersion(ctx context.Context, path string) (*SecretVersion, error) {
+	m, err := c.fetchSecret(ctx, &secretRequest{path: path, stage: versionStageCurrent})
+	if err != nil {
+		return nil, err
+	}
+	v := &SecretVersion{Data: m, VersionStage: versionStageCurrent}
+	if pin := c.getConfig().pin(path); pin != nil {
+		v.VersionStage = ""
+		v.VersionID = pin.VersionID
+		v.Pin = pin
+	}
+	return v, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+const testPinnedVersionID = "a1b2c3d4-5678-90ab-cdef-EXAMPLE11111"
+
+func TestVersionPins(t *testing.T) {
+	var requests []string
+	httpClient := smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		var input struct {
+			SecretId     string
+			VersionId    string
+			VersionStage string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return mockFailure(t, "failed to decode request body: %v", err)
+		}
+		requests = append(requests, input.SecretId+" "+input.VersionId+input.VersionStage)
+		if input.VersionId != "" {
+			return secretsmock.SecretStringResponse(map[string]interface{}{"password": "foo"}), nil
+		}
+		return secretsmock.SecretStringResponse(map[string]interface{}{"password": "bar"}), nil
+	})
+	pin := &VersionPin{Path: "authcrunch/ldap", VersionID: testPinnedVersionID, Reason: "INC-42"}
+	c, err := NewClient(context.TODO(),
+		WithConfig(&ClientConfig{ID: "foo", Region: "us-east-1"}),
+		WithVersionPins(pin),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	c.SetMockClient(httpClient)
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+	got, err := c.GetSecretVersion(context.TODO(), "authcrunch/ldap")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(&SecretVersion{
+		Data:      map[string]interface{}{"password": "foo"},
+		VersionID: testPinnedVersionID,
+		Pin:       pin,
+	}, got); diff != "" {
+		t.Fatalf("GetSecretVersion() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = c.GetSecretVersion(context.TODO(), "authcrunch/smtp")
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if diff := cmp.Diff(&SecretVersion{
+		Data:         map[string]interface{}{"password": "bar"},
+		VersionStage: "AWSCURRENT",
+	}, got); diff != "" {
+		t.Fatalf("GetSecretVersion() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The other version stages of the pinned secret are not affected.
+	if _, err := c.GetSecret(context.TODO(), "authcrunch/ldap", WithVersionStage("AWSPREVIOUS")); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	want := []string{
+		"authcrunch/ldap " + testPinnedVersionID,
+		"authcrunch/smtp AWSCURRENT",
+		"authcrunch/ldap AWSPREVIOUS",
+	}
+	if diff := cmp.Diff(want, requests); diff != "" {
+		t.Fatalf("requests mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestVersionPinsValidate(t *testing.T) {
+	for _, tc := range []struct {
+		pins []*VersionPin
+		err  string
+	}{
+		{pins: []*VersionPin{{VersionID: testPinnedVersionID}}, err: "version pin path is empty"},
+		{
+			pins: []*VersionPin{{Path: "authcrunch/ldap", VersionID: "v1"}},
+			err:  `malformed "v1" version id in pin for "authcrunch/ldap" path`,
+		},
+		{
+			pins: []*VersionPin{
+				{Path: "authcrunch/ldap", VersionID: testPinnedVersionID},
+				{Path: "authcrunch/ldap", VersionID: testPinnedVersionID},
+			},
+			err: `duplicate version pin for "authcrunch/ldap" path`,
+		},
+	} {
+		cfg := &ClientConfig{ID: "foo", Region: "us-east-1", VersionPins: tc.pins}
+		err := cfg.Validate()
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("unexpected error: %v, want: %s", err, tc.err)
+		}
+	}
+}
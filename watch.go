@@ -0,0 +1,139 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWatchInterval = 60 * time.Second
+)
+
+// SecretEventType is the type of a change to a secret.
+type SecretEventType string
+
+const (
+	// SecretAdded indicates that the secret appeared.
+	SecretAdded SecretEventType = "add"
+	// SecretUpdated indicates that the secret value changed.
+	SecretUpdated SecretEventType = "update"
+	// SecretDeleted indicates that the secret disappeared.
+	SecretDeleted SecretEventType = "delete"
+)
+
+// SecretEvent is a notification about a change to a secret.
+type SecretEvent struct {
+	Type SecretEventType `json:"type,omitempty" xml:"type,omitempty" yaml:"type,omitempty"`
+	Path string          `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// Secret is the new key-value map of the secret. It is nil when the
+	// secret was deleted.
+	Secret map[string]interface{} `json:"-" xml:"-" yaml:"-"`
+}
+
+// Watch polls the secrets at the provided paths and emits the events
+// when they are added, updated, or deleted. The first poll happens
+// immediately and reports the existing secrets as added. The channel
+// is closed when the context is done or the client is closed.
+func (c *client) Watch(ctx context.Context, paths []string) (<-chan SecretEvent, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("watch paths not found")
+	}
+	interval := defaultWatchInterval
+	if s := c.getConfig().WatchInterval; s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		interval = d
+	}
+	ch := make(chan SecretEvent)
+	if _, err := c.goBackground(ctx, func(ctx context.Context) {
+		c.watch(ctx, paths, interval, ch)
+	}); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (c *client) watch(ctx context.Context, paths []string, interval time.Duration, ch chan<- SecretEvent) {
+	defer close(ch)
+	ticker := c.clock.NewTicker(interval)
+	defer ticker.Stop()
+	digests := make(map[string]string)
+	for {
+		for _, path := range paths {
+			ev := c.pollSecret(ctx, path, digests)
+			if ev == nil {
+				continue
+			}
+			c.notifyWebhook(ctx, ev)
+			select {
+			case ch <- *ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pollSecret fetches the secret and compares its digest with the one
+// recorded previously. It returns nil when the secret did not change.
+func (c *client) pollSecret(ctx context.Context, path string, digests map[string]string) *SecretEvent {
+	m, err := c.fetchSecret(ctx, &secretRequest{path: path, stage: versionStageCurrent, skipCache: true, refresh: true})
+	if err != nil {
+		if isNotFound(err) {
+			c.InvalidateCache(path)
+			if _, exists := digests[path]; exists {
+				delete(digests, path)
+				return &SecretEvent{Type: SecretDeleted, Path: path}
+			}
+			return nil
+		}
+		if ctx.Err() == nil {
+			c.getLogger().Warn("failed polling watched secret", zap.String("path", path), zap.Error(err))
+		}
+		return nil
+	}
+	digest := secretDigest(m)
+	prev, exists := digests[path]
+	digests[path] = digest
+	switch {
+	case !exists:
+		return &SecretEvent{Type: SecretAdded, Path: path, Secret: m}
+	case prev != digest:
+		return &SecretEvent{Type: SecretUpdated, Path: path, Secret: m}
+	}
+	return nil
+}
+
+// secretDigest returns SHA-256 digest of the key-value map of a secret.
+func secretDigest(m map[string]interface{}) string {
+	b, _ := json.Marshal(m)
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
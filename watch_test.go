@@ -0,0 +1,109 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// newSequenceMockClient returns mock HTTP client serving the provided
+// secret versions one after another. A nil version is served as missing
+// secret. The last version is served once the sequence is exhausted.
+func newSequenceMockClient(t *testing.T, versions []map[string]interface{}) smithyhttp.ClientDoFunc {
+	var mu sync.Mutex
+	var i int
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		secret := versions[i]
+		if i < len(versions)-1 {
+			i++
+		}
+		mu.Unlock()
+		if secret == nil {
+			return secretsmock.NotFoundResponse("524b9962-6854-4b5c-aa53-81759ef610dd"), nil
+		}
+		return secretsmock.SecretStringResponse(secret), nil
+	})
+}
+
+func TestWatch(t *testing.T) {
+	v1 := map[string]interface{}{"id": "0", "value": "b006d65b-c923-46a1-8da1-7d52558508fe"}
+	v2 := map[string]interface{}{"id": "1", "value": "8b8c7b1e-6a2a-4e45-9d1c-1b0b6f0c2c51"}
+	path := "authcrunch/caddy/access_token"
+
+	c, err := NewClientWithConfig(context.TODO(), &ClientConfig{
+		ID:            "foo",
+		Region:        "us-east-1",
+		Provider:      "aws_secrets_manager",
+		WatchInterval: "10ms",
+	})
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	c.SetMockClient(newSequenceMockClient(t, []map[string]interface{}{nil, v1, v1, v2, nil, v1}))
+	c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := c.Watch(ctx, []string{path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []SecretEvent{
+		{Type: SecretAdded, Path: path, Secret: v1},
+		{Type: SecretUpdated, Path: path, Secret: v2},
+		{Type: SecretDeleted, Path: path},
+		{Type: SecretAdded, Path: path, Secret: v1},
+	}
+	var got []SecretEvent
+	timeout := time.After(5 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case ev := <-ch:
+			got = append(got, ev)
+		case <-timeout:
+			t.Fatalf("timed out waiting for events, got: %v", got)
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Watch() mismatch (-want +got):\n%s", diff)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestWatchWithoutPaths(t *testing.T) {
+	c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("unxpected error during client initialization: %v", err)
+	}
+	_, err = c.Watch(context.TODO(), nil)
+	if diff := cmp.Diff(errors.New("watch paths not found").Error(), err.Error()); diff != "" {
+		t.Errorf("Watch() error mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// WebhookTimestampHeader is the header of the webhook request holding
+	// the time of the request in Unix seconds.
+	WebhookTimestampHeader = "X-Authcrunch-Timestamp"
+	// WebhookSignatureHeader is the header of the webhook request holding
+	// "sha256=" followed by the hex-encoded HMAC-SHA256 of the timestamp,
+	// a dot, and the body of the request, keyed with the signing key.
+	WebhookSignatureHeader = "X-Authcrunch-Signature"
+
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// WebhookConfig is the HTTP webhook notified when Watch, ListenSQS, or
+// SNSHandler detect that a secret was updated or deleted, e.g. to purge
+// the caches or restart the consumers of the rotated credentials. The
+// webhook receives POST request with the JSON-encoded WebhookPayload,
+// never the values of the secrets. The requests use the HTTP client of
+// the client, see WithHTTPClient and WithTLS, and are bounded by Timeout.
+// The failed requests are logged, not retried.
+type WebhookConfig struct {
+	// URL is the HTTPS, or HTTP, URL of the webhook.
+	URL string `json:"url,omitempty" xml:"url,omitempty" yaml:"url,omitempty"`
+	// SigningKey is the key of the HMAC signature of the requests, usually
+	// a reference to an environment variable, e.g. "${WEBHOOK_KEY}".
+	SigningKey string `json:"signing_key,omitempty" xml:"signing_key,omitempty" yaml:"signing_key,omitempty"`
+	// Timeout bounds the request, e.g. "5s". When empty, it is ten
+	// seconds.
+	Timeout string `json:"timeout,omitempty" xml:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+func (cfg *WebhookConfig) validate() error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("malformed %q webhook url", redactURL(cfg.URL))
+	}
+	if cfg.SigningKey == "" {
+		return errors.New("webhook signing key is empty")
+	}
+	if cfg.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("malformed %q webhook timeout", cfg.Timeout)
+		}
+	}
+	return nil
+}
+
+// timeout returns the timeout of the webhook requests.
+func (cfg *WebhookConfig) timeout() time.Duration {
+	if cfg.Timeout == "" {
+		return defaultWebhookTimeout
+	}
+	d, _ := time.ParseDuration(cfg.Timeout)
+	return d
+}
+
+// WebhookPayload is the body of the webhook request.
+type WebhookPayload struct {
+	ClientID  string          `json:"client_id,omitempty" xml:"client_id,omitempty" yaml:"client_id,omitempty"`
+	Type      SecretEventType `json:"type,omitempty" xml:"type,omitempty" yaml:"type,omitempty"`
+	Path      string          `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	Timestamp time.Time       `json:"timestamp,omitempty" xml:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+}
+
+// SignWebhookPayload returns the value of WebhookSignatureHeader for the
+// timestamp and the body of the request.
+func SignWebhookPayload(key []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyWebhook sends the secret event to the webhook, when it is
+// configured and the secret was updated or deleted.
+func (c *client) notifyWebhook(ctx context.Context, ev *SecretEvent) {
+	cfg := c.getConfig()
+	if cfg.Webhook == nil || (ev.Type != SecretUpdated && ev.Type != SecretDeleted) {
+		return
+	}
+	now := c.clock.Now()
+	body, err := json.Marshal(&WebhookPayload{
+		ClientID:  cfg.ID,
+		Type:      ev.Type,
+		Path:      ev.Path,
+		Timestamp: now.UTC(),
+	})
+	if err != nil {
+		return
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	ctx, cancel := context.WithTimeout(ctx, cfg.Webhook.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		c.getLogger().Warn("failed notifying webhook", zap.String("path", ev.Path), zap.Error(err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload([]byte(cfg.Webhook.SigningKey), timestamp, body))
+	resp, err := c.getHTTPClient().Do(req)
+	if err != nil {
+		if ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.getLogger().Warn("failed notifying webhook", zap.String("path", ev.Path), zap.Error(err))
+		}
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		c.getLogger().Warn("webhook rejected secret event",
+			zap.String("path", ev.Path),
+			zap.Int("status_code", resp.StatusCode),
+		)
+	}
+}
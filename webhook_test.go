@@ -0,0 +1,93 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWebhook(t *testing.T) {
+	var got []*WebhookPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		want := SignWebhookPayload([]byte("foobar"), r.Header.Get(WebhookTimestampHeader), body)
+		if r.Header.Get(WebhookSignatureHeader) != want {
+			t.Errorf("unexpected %q signature, want %q", r.Header.Get(WebhookSignatureHeader), want)
+		}
+		payload := &WebhookPayload{}
+		if err := json.Unmarshal(body, payload); err != nil {
+			t.Errorf("failed parsing payload: %v", err)
+		}
+		got = append(got, payload)
+	}))
+	defer ts.Close()
+
+	var requests int
+	clock := newTestClock()
+	c, err := NewClient(context.TODO(),
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithClock(clock),
+		WithWebhook(&WebhookConfig{URL: ts.URL, SigningKey: "foobar"}),
+		WithHTTPClient(smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return ts.Client().Do(r)
+		})),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	for _, ev := range []*SecretEvent{
+		{Type: SecretAdded, Path: "ldap"},
+		{Type: SecretUpdated, Path: "ldap", Secret: map[string]interface{}{"password": "foo"}},
+		{Type: SecretDeleted, Path: "smtp"},
+	} {
+		c.(*client).notifyWebhook(context.TODO(), ev)
+	}
+	want := []*WebhookPayload{
+		{ClientID: "foo", Type: SecretUpdated, Path: "ldap", Timestamp: clock.Now().UTC()},
+		{ClientID: "foo", Type: SecretDeleted, Path: "smtp", Timestamp: clock.Now().UTC()},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("webhook payloads mismatch (-want +got):\n%s", diff)
+	}
+	if requests != len(want) {
+		t.Fatalf("unexpected %d requests through http client, want: %d", requests, len(want))
+	}
+}
+
+func TestWebhookConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		cfg *WebhookConfig
+		err string
+	}{
+		{cfg: &WebhookConfig{URL: "example.com", SigningKey: "foobar"}, err: `malformed "example.com" webhook url`},
+		{cfg: &WebhookConfig{URL: "https://example.com/hooks"}, err: "webhook signing key is empty"},
+		{cfg: &WebhookConfig{URL: "https://example.com/hooks", SigningKey: "foobar", Timeout: "0s"}, err: `malformed "0s" webhook timeout`},
+	} {
+		err := tc.cfg.validate()
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("unexpected error: %v, want: %s", err, tc.err)
+		}
+	}
+}
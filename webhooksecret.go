@@ -0,0 +1,155 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+var webhookHMACAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// WebhookSecret holds the shared secrets of the webhook endpoint, used to
+// verify the HMAC signatures of the received payloads.
+type WebhookSecret struct {
+	// Secrets are the current shared secret followed by the previous one,
+	// which the senders may still use while the rotation propagates.
+	Secrets []string `json:"secrets,omitempty" xml:"secrets,omitempty" yaml:"secrets,omitempty"`
+	// Algorithm is one of sha1, sha256, or sha512.
+	Algorithm string `json:"algorithm,omitempty" xml:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+}
+
+// Sign returns the hex-encoded HMAC of the payload keyed with the current
+// shared secret, prefixed with the algorithm, e.g. "sha256=".
+func (s *WebhookSecret) Sign(payload []byte) (string, error) {
+	newHash, err := s.hashFunc()
+	if err != nil {
+		return "", err
+	}
+	if len(s.Secrets) == 0 || s.Secrets[0] == "" {
+		return "", errors.New("webhook secret is empty")
+	}
+	return s.Algorithm + "=" + hex.EncodeToString(hmacSum(newHash, s.Secrets[0], payload)), nil
+}
+
+// VerifyHMAC reports whether the signature is the HMAC of the payload
+// keyed with any of the shared secrets. The signature is hex-encoded,
+// optionally prefixed with the algorithm, e.g. "sha256=...". The
+// comparison takes constant time regardless of which secret matches. The
+// empty shared secrets never match, and neither does any signature when
+// the algorithm is not supported.
+func (s *WebhookSecret) VerifyHMAC(payload []byte, signature string) bool {
+	newHash, err := s.hashFunc()
+	if err != nil {
+		return false
+	}
+	if i := strings.IndexByte(signature, '='); i >= 0 {
+		if !strings.EqualFold(signature[:i], s.Algorithm) {
+			return false
+		}
+		signature = signature[i+1:]
+	}
+	mac, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	var valid int
+	for _, secret := range s.Secrets {
+		if secret == "" {
+			continue
+		}
+		valid |= subtle.ConstantTimeCompare(mac, hmacSum(newHash, secret, payload))
+	}
+	return valid == 1
+}
+
+// hashFunc returns the hash function of the algorithm.
+func (s *WebhookSecret) hashFunc() (func() hash.Hash, error) {
+	newHash, found := webhookHMACAlgorithms[s.Algorithm]
+	if !found {
+		return nil, fmt.Errorf("unsupported %q algorithm", s.Algorithm)
+	}
+	return newHash, nil
+}
+
+func hmacSum(newHash func() hash.Hash, secret string, payload []byte) []byte {
+	h := hmac.New(newHash, []byte(secret))
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// GetWebhookSecret returns the shared secret of the webhook endpoint
+// stored in the secret and algorithm keys of the secret. The algorithm
+// defaults to sha256. The shared secret of the AWSPREVIOUS version of the
+// secret remains valid, so that the senders are not rejected during the
+// rotation.
+func (c *client) GetWebhookSecret(ctx context.Context, path string) (*WebhookSecret, error) {
+	m, err := c.getSecretValue(ctx, path, versionStageCurrent)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseWebhookSecret(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed current version of %q secret: %v", path, err)
+	}
+
+	m, err = c.getSecretValue(ctx, path, versionStagePrevious)
+	if err != nil {
+		if isNotFound(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	previous, err := parseWebhookSecret(m)
+	if err != nil {
+		return nil, fmt.Errorf("malformed previous version of %q secret: %v", path, err)
+	}
+	if previous.Algorithm == s.Algorithm && previous.Secrets[0] != s.Secrets[0] {
+		s.Secrets = append(s.Secrets, previous.Secrets[0])
+	}
+	return s, nil
+}
+
+func parseWebhookSecret(m map[string]interface{}) (*WebhookSecret, error) {
+	secret, err := getStringValue(m, "secret", true)
+	if err != nil {
+		return nil, err
+	}
+	s := &WebhookSecret{Secrets: []string{secret}}
+	if s.Algorithm, err = getStringValue(m, "algorithm", false); err != nil {
+		return nil, err
+	}
+	s.Algorithm = strings.ToLower(s.Algorithm)
+	if s.Algorithm == "" {
+		s.Algorithm = "sha256"
+	}
+	if _, found := webhookHMACAlgorithms[s.Algorithm]; !found {
+		return nil, fmt.Errorf("unsupported %q algorithm", s.Algorithm)
+	}
+	return s, nil
+}
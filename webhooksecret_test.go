@@ -0,0 +1,171 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetWebhookSecret(t *testing.T) {
+	path := "authcrunch/caddy/webhook"
+	testcases := []struct {
+		name      string
+		stages    map[string]map[string]interface{}
+		want      *WebhookSecret
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "test rotated webhook secret",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT":  {"secret": "foo"},
+				"AWSPREVIOUS": {"secret": "bar"},
+			},
+			want: &WebhookSecret{Secrets: []string{"foo", "bar"}, Algorithm: "sha256"},
+		},
+		{
+			name: "test webhook secret with changed algorithm",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT":  {"secret": "foo", "algorithm": "SHA512"},
+				"AWSPREVIOUS": {"secret": "bar"},
+			},
+			want: &WebhookSecret{Secrets: []string{"foo"}, Algorithm: "sha512"},
+		},
+		{
+			name: "test webhook secret with unsupported algorithm",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {"secret": "foo", "algorithm": "md5"},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed current version of %q secret: unsupported %q algorithm", path, "md5"),
+		},
+		{
+			name: "test webhook secret without secret",
+			stages: map[string]map[string]interface{}{
+				"AWSCURRENT": {"algorithm": "sha1"},
+			},
+			shouldErr: true,
+			err:       fmt.Errorf("malformed current version of %q secret: key %q not found", path, "secret"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(context.TODO(), WithID("foo"), WithRegion("us-east-1"))
+			if err != nil {
+				t.Fatalf("unxpected error during client initialization: %v", err)
+			}
+			c.SetMockClient(newStagedMockClient(t, tc.stages))
+			c.SetMockCredentialsProvider(MockCredentialsProvider{})
+
+			got, err := c.GetWebhookSecret(context.TODO(), path)
+			if err != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", err)
+				}
+				if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+					t.Fatalf("GetWebhookSecret() error mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetWebhookSecret() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWebhookSecretVerifyHMAC(t *testing.T) {
+	payload := []byte(`{"action":"opened"}`)
+	current := &WebhookSecret{Secrets: []string{"foo"}, Algorithm: "sha256"}
+	previous := &WebhookSecret{Secrets: []string{"bar"}, Algorithm: "sha256"}
+	rotated := &WebhookSecret{Secrets: []string{"foo", "bar"}, Algorithm: "sha256"}
+	signature, err := previous.Sign(payload)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	currentSignature, err := current.Sign(payload)
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		s         *WebhookSecret
+		signature string
+		want      bool
+	}{
+		{name: "test previous secret during rotation", s: rotated, signature: signature, want: true},
+		{name: "test current secret", s: rotated, signature: currentSignature, want: true},
+		{name: "test signature without prefix", s: rotated, signature: signature[len("sha256="):], want: true},
+		{name: "test previous secret after rotation", s: current, signature: signature, want: false},
+		{name: "test mismatched algorithm", s: rotated, signature: "sha1=" + signature[len("sha256="):], want: false},
+		{name: "test malformed signature", s: rotated, signature: "sha256=foobar", want: false},
+		{name: "test empty signature", s: rotated, signature: "", want: false},
+		{name: "test empty secrets", s: &WebhookSecret{Algorithm: "sha256"}, signature: signature, want: false},
+		{name: "test empty algorithm", s: &WebhookSecret{Secrets: []string{"bar"}}, signature: signature[len("sha256="):], want: false},
+		{name: "test unsupported algorithm", s: &WebhookSecret{Secrets: []string{"bar"}, Algorithm: "md5"}, signature: signature[len("sha256="):], want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.s.VerifyHMAC(payload, tc.signature); got != tc.want {
+				t.Errorf("VerifyHMAC() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+	if tampered := append(payload, ' '); rotated.VerifyHMAC(tampered, signature) {
+		t.Errorf("VerifyHMAC() accepted tampered payload")
+	}
+}
+
+func TestWebhookSecretSign(t *testing.T) {
+	testcases := []struct {
+		name string
+		s    *WebhookSecret
+		err  error
+	}{
+		{
+			name: "test empty secrets",
+			s:    &WebhookSecret{Algorithm: "sha256"},
+			err:  fmt.Errorf("webhook secret is empty"),
+		},
+		{
+			name: "test empty algorithm",
+			s:    &WebhookSecret{Secrets: []string{"foo"}},
+			err:  fmt.Errorf("unsupported %q algorithm", ""),
+		},
+		{
+			name: "test unsupported algorithm",
+			s:    &WebhookSecret{Secrets: []string{"foo"}, Algorithm: "md5"},
+			err:  fmt.Errorf("unsupported %q algorithm", "md5"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.s.Sign([]byte("foo"))
+			if err == nil {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(err.Error(), tc.err.Error()); diff != "" {
+				t.Fatalf("Sign() error mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"go.uber.org/zap"
+)
+
+// ErrReadOnly is returned by the methods mutating secrets when the client
+// is read-only.
+var ErrReadOnly = errors.New("client is read-only")
+
+// Change describes a mutation of a secret. It lists the keys added,
+// removed, or modified by the mutation, but never the values.
+type Change struct {
+	Op           string   `json:"op,omitempty" xml:"op,omitempty" yaml:"op,omitempty"`
+	Path         string   `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	AddedKeys    []string `json:"added_keys,omitempty" xml:"added_keys,omitempty" yaml:"added_keys,omitempty"`
+	RemovedKeys  []string `json:"removed_keys,omitempty" xml:"removed_keys,omitempty" yaml:"removed_keys,omitempty"`
+	ModifiedKeys []string `json:"modified_keys,omitempty" xml:"modified_keys,omitempty" yaml:"modified_keys,omitempty"`
+	DryRun       bool     `json:"dry_run,omitempty" xml:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+// Empty returns true when the change neither adds, removes, nor modifies
+// any keys.
+func (ch *Change) Empty() bool {
+	return len(ch.AddedKeys) == 0 && len(ch.RemovedKeys) == 0 && len(ch.ModifiedKeys) == 0
+}
+
+func newChange(op, path string, current, desired map[string]interface{}) *Change {
+	ch := &Change{Op: op, Path: path}
+	for k, v := range desired {
+		cv, exists := current[k]
+		switch {
+		case !exists:
+			ch.AddedKeys = append(ch.AddedKeys, k)
+		case !reflect.DeepEqual(cv, v):
+			ch.ModifiedKeys = append(ch.ModifiedKeys, k)
+		}
+	}
+	for k := range current {
+		if _, exists := desired[k]; !exists {
+			ch.RemovedKeys = append(ch.RemovedKeys, k)
+		}
+	}
+	sort.Strings(ch.AddedKeys)
+	sort.Strings(ch.RemovedKeys)
+	sort.Strings(ch.ModifiedKeys)
+	return ch
+}
+
+// writeRequest is the target of a mutating operation.
+type writeRequest struct {
+	op            string
+	path          string
+	name          string
+	dryRun        bool
+	stage         string
+	checksumKey   string
+	serviceClient SecretsManagerAPI
+}
+
+// newWriteRequest checks that the client may mutate the secret at the path
+// and resolves the name of the secret and the service client for its
+// route.
+func (c *client) newWriteRequest(op, path string) (*writeRequest, error) {
+	cfg := c.getConfig()
+	if cfg.ReadOnly {
+		return nil, fmt.Errorf("%w: %s %q", ErrReadOnly, op, path)
+	}
+	if err := cfg.PathPolicy.check(path); err != nil {
+		return nil, err
+	}
+	name, err := cfg.resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var region, endpoint string
+	if route := cfg.route(path); route != nil {
+		region, endpoint = route.Region, route.Endpoint
+	}
+	return &writeRequest{
+		op:            op,
+		path:          path,
+		name:          name,
+		dryRun:        cfg.DryRun,
+		checksumKey:   cfg.ChecksumKey,
+		serviceClient: c.getServiceClient(region, endpoint),
+	}, nil
+}
+
+// getCurrentSecret returns the current version of the secret as stored,
+// without aliases, schemas, and the cache. It returns nil when the secret
+// does not exist.
+func (req *writeRequest) getCurrentSecret(ctx context.Context) (map[string]interface{}, error) {
+	result, err := req.serviceClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(req.name),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if result.SecretString == nil {
+		return nil, errors.New("SecretString not found in response")
+	}
+	return DecodeSecretString(*result.SecretString)
+}
+
+// encode returns the JSON encoding of the key-value map, with the checksum
+// key set, when the client maintains checksums.
+func (req *writeRequest) encode(m map[string]interface{}) ([]byte, error) {
+	if req.checksumKey != "" {
+		var err error
+		if m, err = withChecksum(req.checksumKey, m); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(m)
+}
+
+// apply logs the change and, unless in dry-run mode, invalidates the
+// cached versions of the secret and calls fn.
+func (c *client) apply(req *writeRequest, ch *Change, fn func() error) (*Change, error) {
+	ch.DryRun = req.dryRun
+	c.getLogger().Info(
+		"mutating secret",
+		zap.String("op", ch.Op),
+		zap.String("path", ch.Path),
+		zap.Strings("added_keys", ch.AddedKeys),
+		zap.Strings("removed_keys", ch.RemovedKeys),
+		zap.Strings("modified_keys", ch.ModifiedKeys),
+		zap.Bool("dry_run", ch.DryRun),
+	)
+	if req.dryRun {
+		return ch, nil
+	}
+	c.getCache().invalidate(req.path)
+	err := fn()
+	c.audit(ch.Op, ch.Path, err)
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// CreateSecret creates the secret with the key-value map at the path.
+func (c *client) CreateSecret(ctx context.Context, path string, m map[string]interface{}) (*Change, error) {
+	req, err := c.newWriteRequest("create", path)
+	if err != nil {
+		return nil, err
+	}
+	return c.createSecret(ctx, req, m)
+}
+
+func (c *client) createSecret(ctx context.Context, req *writeRequest, m map[string]interface{}) (*Change, error) {
+	b, err := req.encode(m)
+	if err != nil {
+		return nil, err
+	}
+	return c.apply(req, newChange("create", req.path, nil, m), func() error {
+		_, err := req.serviceClient.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+			Name:         aws.String(req.name),
+			SecretString: aws.String(string(b)),
+		})
+		return err
+	})
+}
+
+// PutSecret stores the key-value map as the new current version of the
+// secret at the path, unless WithVersionStage selects another stage. The
+// change lists the keys differing from the current version.
+func (c *client) PutSecret(ctx context.Context, path string, m map[string]interface{}, opts ...CallOption) (*Change, error) {
+	o, err := newCallOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	req, err := c.newWriteRequest("put", path)
+	if err != nil {
+		return nil, err
+	}
+	if o.region != "" {
+		req.serviceClient = c.getServiceClient(o.region, "")
+	}
+	req.stage = o.stage
+	current, err := req.getCurrentSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("secret %q not found", path)
+	}
+	return c.putSecret(ctx, req, current, m)
+}
+
+func (c *client) putSecret(ctx context.Context, req *writeRequest, current, m map[string]interface{}) (*Change, error) {
+	b, err := req.encode(m)
+	if err != nil {
+		return nil, err
+	}
+	if req.checksumKey != "" {
+		current, m = withoutKey(current, req.checksumKey), withoutKey(m, req.checksumKey)
+	}
+	return c.apply(req, newChange("put", req.path, current, m), func() error {
+		input := &secretsmanager.PutSecretValueInput{
+			SecretId:     aws.String(req.name),
+			SecretString: aws.String(string(b)),
+		}
+		if req.stage != "" && req.stage != versionStageCurrent {
+			input.VersionStages = []string{req.stage}
+		}
+		_, err := req.serviceClient.PutSecretValue(ctx, input)
+		return err
+	})
+}
+
+// DeleteSecret schedules the deletion of the secret at the path with the
+// default recovery window.
+func (c *client) DeleteSecret(ctx context.Context, path string) (*Change, error) {
+	req, err := c.newWriteRequest("delete", path)
+	if err != nil {
+		return nil, err
+	}
+	current, err := req.getCurrentSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("secret %q not found", path)
+	}
+	return c.apply(req, newChange("delete", path, current, nil), func() error {
+		_, err := req.serviceClient.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+			SecretId: aws.String(req.name),
+		})
+		return err
+	})
+}
+
+// RotateSecret starts the rotation of the secret at the path with its
+// configured rotation function. The keys of the rotated secret are not
+// known in advance, therefore the change lists none.
+func (c *client) RotateSecret(ctx context.Context, path string) (*Change, error) {
+	req, err := c.newWriteRequest("rotate", path)
+	if err != nil {
+		return nil, err
+	}
+	return c.apply(req, &Change{Op: "rotate", Path: path}, func() error {
+		_, err := req.serviceClient.RotateSecret(ctx, &secretsmanager.RotateSecretInput{
+			SecretId: aws.String(req.name),
+		})
+		return err
+	})
+}
+
+// Sync makes the secrets at the paths match the key-value maps. It creates
+// the missing secrets and updates the ones that differ. The secrets
+// already matching are left intact and have no changes reported.
+func (c *client) Sync(ctx context.Context, secrets map[string]map[string]interface{}) ([]*Change, error) {
+	paths := make([]string, 0, len(secrets))
+	for path := range secrets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var changes []*Change
+	for _, path := range paths {
+		req, err := c.newWriteRequest("sync", path)
+		if err != nil {
+			return changes, err
+		}
+		current, err := req.getCurrentSecret(ctx)
+		if err != nil {
+			return changes, err
+		}
+		var ch *Change
+		switch {
+		case current == nil:
+			ch, err = c.createSecret(ctx, req, secrets[path])
+		case newChange("put", path, withoutKey(current, req.checksumKey), withoutKey(secrets[path], req.checksumKey)).Empty():
+			continue
+		default:
+			ch, err = c.putSecret(ctx, req, current, secrets[path])
+		}
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, ch)
+	}
+	return changes, nil
+}
@@ -0,0 +1,276 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/greenpau/go-authcrunch-secrets-aws-secrets-manager/secretsmock"
+)
+
+// newWritesMockClient returns HTTP client backed by the store of secret
+// strings. It records the targets and the secret ids of the mutating
+// requests.
+func newWritesMockClient(t *testing.T, store map[string]string, requests *[]string) smithyhttp.ClientDoFunc {
+	return smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return mockFailure(t, "failed reading request: %v", err)
+		}
+		var input struct {
+			Name         string
+			SecretId     string
+			SecretString string
+			Filters      []struct {
+				Key    string
+				Values []string
+			}
+		}
+		if err := json.Unmarshal(b, &input); err != nil {
+			return mockFailure(t, "failed parsing request: %v", err)
+		}
+		target := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "secretsmanager.")
+		response := `{}`
+		switch target {
+		case "GetSecretValue":
+			if v, exists := store[input.SecretId]; exists {
+				response = packMapToJSON(t, map[string]interface{}{"SecretString": v})
+			} else {
+				return secretsmock.NotFoundResponse("524b9962-6854-4b5c-aa53-81759ef610dd"), nil
+			}
+		case "CreateSecret":
+			*requests = append(*requests, target+" "+input.Name)
+			store[input.Name] = input.SecretString
+		case "PutSecretValue":
+			*requests = append(*requests, target+" "+input.SecretId)
+			store[input.SecretId] = input.SecretString
+		case "DeleteSecret":
+			*requests = append(*requests, target+" "+input.SecretId)
+			delete(store, input.SecretId)
+		case "RotateSecret":
+			*requests = append(*requests, target+" "+input.SecretId)
+		case "ListSecrets":
+			var entries []map[string]interface{}
+			for name := range store {
+				if len(input.Filters) == 0 || strings.HasPrefix(name, input.Filters[0].Values[0]) {
+					entries = append(entries, map[string]interface{}{"Name": name})
+				}
+			}
+			response = packMapToJSON(t, map[string]interface{}{"SecretList": entries})
+		default:
+			return mockFailure(t, "unexpected %q target", target)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(response)),
+		}, nil
+	})
+}
+
+func newWritesTestClient(t *testing.T, store map[string]string, requests *[]string, opts ...Option) Client {
+	opts = append([]Option{
+		WithID("foo"),
+		WithRegion("us-east-1"),
+		WithHTTPClient(newWritesMockClient(t, store, requests)),
+		WithCredentialsProvider(MockCredentialsProvider{}),
+	}, opts...)
+	c, err := NewClient(context.TODO(), opts...)
+	if err != nil {
+		t.Fatalf("unexpected error during client initialization: %v", err)
+	}
+	return c
+}
+
+func TestWrites(t *testing.T) {
+	testcases := []struct {
+		name         string
+		opts         []Option
+		want         []*Change
+		wantRequests []string
+		wantStore    map[string]string
+		shouldErr    bool
+		err          error
+	}{
+		{
+			name: "test writes",
+			opts: []Option{WithBasePrefix("authcrunch/")},
+			want: []*Change{
+				{Op: "create", Path: "users/jsmith", AddedKeys: []string{"password", "username"}},
+				{Op: "put", Path: "users/jsmith", AddedKeys: []string{"email"}, RemovedKeys: []string{"password"}, ModifiedKeys: []string{"username"}},
+				{Op: "rotate", Path: "users/jsmith"},
+				{Op: "delete", Path: "users/jsmith", RemovedKeys: []string{"email", "username"}},
+			},
+			wantRequests: []string{
+				"CreateSecret authcrunch/users/jsmith",
+				"PutSecretValue authcrunch/users/jsmith",
+				"RotateSecret authcrunch/users/jsmith",
+				"DeleteSecret authcrunch/users/jsmith",
+			},
+			wantStore: map[string]string{},
+		},
+		{
+			name: "test writes in dry-run mode",
+			opts: []Option{WithDryRun()},
+			want: []*Change{
+				{Op: "create", Path: "users/jsmith", AddedKeys: []string{"password", "username"}, DryRun: true},
+				{Op: "rotate", Path: "users/jsmith", DryRun: true},
+			},
+			wantStore: map[string]string{},
+			shouldErr: true,
+			err:       fmt.Errorf("secret %q not found", "users/jsmith"),
+		},
+		{
+			name:      "test writes with read-only client",
+			opts:      []Option{WithReadOnly(), WithDryRun()},
+			wantStore: map[string]string{},
+			shouldErr: true,
+			err:       fmt.Errorf("%w: create %q", ErrReadOnly, "users/jsmith"),
+		},
+		{
+			name:      "test writes outside path policy",
+			opts:      []Option{WithPathPolicy(&PathPolicyConfig{Deny: []string{"users/*"}})},
+			wantStore: map[string]string{},
+			shouldErr: true,
+			err:       fmt.Errorf("%w: %q matches %q deny pattern", ErrPathNotAllowed, "users/jsmith", "users/*"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := make(map[string]string)
+			var requests []string
+			c := newWritesTestClient(t, store, &requests, tc.opts...)
+			ops := []func() (*Change, error){
+				func() (*Change, error) {
+					return c.CreateSecret(context.TODO(), "users/jsmith", map[string]interface{}{"username": "jsmith", "password": "foobar"})
+				},
+				func() (*Change, error) {
+					return c.PutSecret(context.TODO(), "users/jsmith", map[string]interface{}{"username": "jsmith1", "email": "jsmith@localhost"})
+				},
+				func() (*Change, error) { return c.RotateSecret(context.TODO(), "users/jsmith") },
+				func() (*Change, error) { return c.DeleteSecret(context.TODO(), "users/jsmith") },
+			}
+			var got []*Change
+			var firstErr error
+			for _, op := range ops {
+				ch, err := op()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					continue
+				}
+				got = append(got, ch)
+			}
+			if firstErr != nil {
+				if !tc.shouldErr {
+					t.Fatalf("expected success, got: %v", firstErr)
+				}
+				if diff := cmp.Diff(tc.err.Error(), firstErr.Error()); diff != "" {
+					t.Fatalf("unexpected error: %v, want: %v", firstErr, tc.err)
+				}
+			} else if tc.shouldErr {
+				t.Fatalf("unexpected success, want: %v", tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("changes mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantRequests, requests); diff != "" {
+				t.Errorf("requests mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantStore, store); diff != "" {
+				t.Errorf("store mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	secrets := map[string]map[string]interface{}{
+		"users/jsmith": {"username": "jsmith", "email": "jsmith@localhost"},
+		"users/mjones": {"username": "mjones"},
+		"users/rstone": {"username": "rstone"},
+	}
+	testcases := []struct {
+		name         string
+		opts         []Option
+		want         []*Change
+		wantRequests []string
+	}{
+		{
+			name: "test sync",
+			want: []*Change{
+				{Op: "put", Path: "users/jsmith", AddedKeys: []string{"email"}},
+				{Op: "create", Path: "users/rstone", AddedKeys: []string{"username"}},
+			},
+			wantRequests: []string{
+				"PutSecretValue users/jsmith",
+				"CreateSecret users/rstone",
+			},
+		},
+		{
+			name: "test sync in dry-run mode",
+			opts: []Option{WithDryRun()},
+			want: []*Change{
+				{Op: "put", Path: "users/jsmith", AddedKeys: []string{"email"}, DryRun: true},
+				{Op: "create", Path: "users/rstone", AddedKeys: []string{"username"}, DryRun: true},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := map[string]string{
+				"users/jsmith": `{"username":"jsmith"}`,
+				"users/mjones": `{"username":"mjones"}`,
+			}
+			var requests []string
+			c := newWritesTestClient(t, store, &requests, tc.opts...)
+			got, err := c.Sync(context.TODO(), secrets)
+			if err != nil {
+				t.Fatalf("expected success, got: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("changes mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantRequests, requests); diff != "" {
+				t.Errorf("requests mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadOnlyClientReadsSecrets(t *testing.T) {
+	store := map[string]string{"users/jsmith": `{"username":"jsmith"}`}
+	var requests []string
+	c := newWritesTestClient(t, store, &requests, WithReadOnly())
+	if _, err := c.GetSecret(context.TODO(), "users/jsmith"); err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if _, err := c.DeleteSecret(context.TODO(), "users/jsmith"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got: %v", err)
+	}
+	if _, err := c.Sync(context.TODO(), map[string]map[string]interface{}{"users/jsmith": {}}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got: %v", err)
+	}
+}